@@ -9,8 +9,16 @@ import (
 	"pinn-connect-service/internal/config"
 	"pinn-connect-service/internal/db"
 	"pinn-connect-service/internal/docker"
+	"pinn-connect-service/internal/domain"
+	"pinn-connect-service/internal/envelope"
 	"pinn-connect-service/internal/gc"
+	"pinn-connect-service/internal/leader"
+	"pinn-connect-service/internal/node"
+	"pinn-connect-service/internal/notify"
+	"pinn-connect-service/internal/reconcile"
 	"pinn-connect-service/internal/repository"
+	"pinn-connect-service/internal/retention"
+	"pinn-connect-service/internal/scheduler"
 	"pinn-connect-service/internal/server"
 	"pinn-connect-service/internal/service"
 	"pinn-connect-service/internal/storage"
@@ -18,11 +26,6 @@ import (
 	"pinn-connect-service/internal/workspace"
 	"sync"
 	"syscall"
-	"time"
-
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -35,6 +38,14 @@ import (
 func main() {
 	slog.SetDefault(slog.Default())
 
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		if err := runReconcile(os.Args[2:]); err != nil {
+			slog.Error("reconcile failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := run(); err != nil {
 		slog.Error("application stopped with error", "error", err)
 		os.Exit(1)
@@ -50,23 +61,35 @@ func run() error {
 		return fmt.Errorf("error loading config: %w", err)
 	}
 
-	manager, err := docker.NewManager(ctx)
+	manager, err := docker.NewPool(ctx, cfg.Docker)
 	if err != nil {
-		return fmt.Errorf("error while initializing Docker client: %w", err)
+		return fmt.Errorf("error while initializing Docker clients: %w", err)
 	}
 	defer func() {
-		slog.Info("closing Docker client...")
-		if err := manager.Client.Close(); err != nil {
-			slog.Error("failed to close Docker client", "error", err)
+		slog.Info("closing Docker clients...")
+		if err := manager.Close(); err != nil {
+			slog.Error("failed to close Docker clients", "error", err)
 		}
 	}()
 
-	storage, err := storage.NewMinIOStorage(ctx, cfg)
+	artifactStorage, err := storage.New(ctx, cfg)
 	if err != nil {
-		return fmt.Errorf("error while initializing minio storage: %w", err)
+		return fmt.Errorf("error while initializing %s storage: %w", cfg.Storage.Backend, err)
 	}
 
-	if err := runMigrations(cfg.DB.URL); err != nil {
+	// the local backend has no server of its own and encrypted objects can only
+	// be decrypted by the service, downloads go through the service
+	var fileStore server.SignedFileStore
+	switch s := artifactStorage.(type) {
+	case *storage.LocalStorage:
+		fileStore = s
+	case *storage.MinIOStorage:
+		if s.Encrypted() {
+			fileStore = s
+		}
+	}
+
+	if err := db.Migrate(cfg.DB.URL); err != nil {
 		return fmt.Errorf("migrations failed: %w", err)
 	}
 
@@ -78,57 +101,93 @@ func run() error {
 
 	taskRepo := repository.NewTaskRepository(pool)
 	modelRepo := repository.NewModelRepository(pool)
+	nodeRepo := repository.NewNodeRepository(pool)
 
 	workspace := workspace.NewLocalWorkspace(cfg)
 
 	modelService := service.NewModelService(modelRepo, manager)
-	taskService := service.NewTaskService(manager, storage, cfg, taskRepo, workspace, modelService)
-	healthService := service.NewHealthService(manager, storage, &db.PostgresDatabasePinger{Pool: pool})
+	var secretCipher service.SecretCipher
+	if cfg.Secrets.Keyring != "" {
+		keyring, err := envelope.LoadKeyring(cfg.Secrets.Keyring)
+		if err != nil {
+			return fmt.Errorf("error while loading secrets keyring: %w", err)
+		}
+		secretCipher = keyring
+	}
+	secretService := service.NewSecretService(repository.NewSecretRepository(pool), secretCipher)
+	taskService := service.NewTaskService(manager, artifactStorage, cfg, taskRepo, workspace, modelService, secretService)
+	elector := leader.NewElector(pool, cfg.DB, cfg.Leader, cfg.InstanceID)
+	healthService := service.NewHealthService(manager, artifactStorage, &db.PostgresDatabasePinger{Pool: pool}, elector, manager)
+
+	janitor := retention.NewJanitor(taskRepo, artifactStorage, workspace, cfg.Retention)
+	reconciler := reconcile.NewReconciler(taskRepo, artifactStorage, cfg.Reconcile)
+	scheduler := scheduler.NewScheduler(taskRepo, cfg.Scheduler)
+
+	// the garbage collector only cleans up after the tasks of this node
+	var collector service.GCJob
+	var garbageCollector *gc.GarbageCollector
+	if cfg.Server.ExecuteTasks {
+		garbageCollector = gc.NewGarbageCollector(taskRepo, workspace, manager, artifactStorage, taskService, cfg.GC, cfg.Worker.LeaseDuration, cfg.InstanceID)
+		collector = garbageCollector
+	}
+	adminService := service.NewAdminService(janitor, collector, reconciler, scheduler, elector, nodeRepo, cfg.Node.Timeout)
 
-	gcCtx, gcCancel := context.WithTimeout(ctx, 20*time.Second)
-	gc := gc.NewGarbageCollector(taskRepo, workspace, manager, storage, taskService)
-	gc.Cleanup(gcCtx)
-	gcCancel()
+	listener := notify.NewListener(cfg.DB)
+	scheduled := listener.Subscribe(domain.TaskScheduled)
+
+	// singleton jobs run only on the leader among the instances sharing the database
+	elector.Run(func(ctx context.Context, wg *sync.WaitGroup) {
+		scheduler.Start(ctx, wg, scheduled)
+	})
+	if cfg.Retention.Enabled {
+		elector.Run(janitor.Start)
+	}
 
 	var wg sync.WaitGroup
-	taskService.StartWorker(ctx, &wg)
-	taskService.StartScheduler(ctx, &wg)
 
-	sysstats.StartCPULoadFetcher(ctx, cfg.SysstatsCPUInterval, &wg)
+	// the node is marked stopped only after its workers have drained
+	nodeCtx, nodeCancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer nodeCancel()
+	var nodeWG sync.WaitGroup
 
-	// blocking Run() call
-	if err := server.New(taskService, modelService, healthService, cfg).Run(ctx, cfg.Server.Port); err != nil {
-		return fmt.Errorf("server stopped with error: %w", err)
+	if cfg.Server.ExecuteTasks {
+		self, err := node.Describe(cfg)
+		if err != nil {
+			return fmt.Errorf("describing node: %w", err)
+		}
+		agent := node.NewAgent(nodeRepo, self, cfg.Node)
+		if err := agent.Register(ctx); err != nil {
+			return err
+		}
+		agent.Start(nodeCtx, &nodeWG)
+
+		queued := listener.Subscribe(domain.TaskQueued)
+		stopped := listener.Subscribe(domain.TaskStopped)
+
+		wg.Go(func() {
+			gcCtx, gcCancel := context.WithTimeout(ctx, cfg.GC.Timeout)
+			defer gcCancel()
+			garbageCollector.Cleanup(gcCtx)
+		})
+		garbageCollector.Start(ctx, &wg)
+		taskService.StartWorker(ctx, &wg, queued)
+		taskService.StartStopWatcher(ctx, &wg, stopped)
 	}
 
-	wg.Wait()
+	listener.Start(ctx, &wg)
+	elector.Start(ctx, &wg)
+	manager.Start(ctx, &wg)
 
-	return nil
-}
+	sysstats.StartCPULoadFetcher(ctx, cfg.SysstatsCPUInterval, &wg)
 
-func runMigrations(dbURL string) error {
-	m, err := migrate.New(
-		"file://migrations",
-		dbURL,
-	)
-	if err != nil {
-		return fmt.Errorf("creating migrate instance: %w", err)
+	// blocking Run() call
+	if err := server.New(taskService, modelService, healthService, adminService, secretService, fileStore, cfg).Run(ctx, cfg.Server.Port); err != nil {
+		return fmt.Errorf("server stopped with error: %w", err)
 	}
 
-	defer func() {
-		sourceErr, dbErr := m.Close()
-		if sourceErr != nil {
-			slog.Error("failed to close migrate source", "error", sourceErr)
-		}
-		if dbErr != nil {
-			slog.Error("failed to close migrate db connection", "error", dbErr)
-		}
-	}()
-
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return fmt.Errorf("running migrations: %w", err)
-	}
+	wg.Wait()
+	nodeCancel()
+	nodeWG.Wait()
 
-	slog.Info("Migrations applied successfully")
 	return nil
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/db"
+	"pinn-connect-service/internal/reconcile"
+	"pinn-connect-service/internal/repository"
+	"pinn-connect-service/internal/storage"
+	"syscall"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// runReconcile implements the "reconcile" subcommand: it reconciles the bucket
+// with the tasks table once and prints the report as JSON.
+func runReconcile(args []string) error {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only report orphan objects and missing results, don't change anything")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	artifactStorage, err := storage.New(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("error while initializing %s storage: %w", cfg.Storage.Backend, err)
+	}
+
+	if err := db.Migrate(cfg.DB.URL); err != nil {
+		return fmt.Errorf("migrations failed: %w", err)
+	}
+
+	pool, err := pgxpool.New(ctx, cfg.DB.URL)
+	if err != nil {
+		return fmt.Errorf("error while creating new pgxpool: %w", err)
+	}
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Reconcile.Timeout)
+	defer cancel()
+
+	reconciler := reconcile.NewReconciler(repository.NewTaskRepository(pool), artifactStorage, cfg.Reconcile)
+	report := reconciler.Run(ctx, *dryRun)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("encoding report: %w", err)
+	}
+
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("reconcile finished with %d errors", len(report.Errors))
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/db"
+	"pinn-connect-service/internal/docker"
+	"pinn-connect-service/internal/domain"
+	"pinn-connect-service/internal/envelope"
+	"pinn-connect-service/internal/gc"
+	"pinn-connect-service/internal/node"
+	"pinn-connect-service/internal/notify"
+	"pinn-connect-service/internal/repository"
+	"pinn-connect-service/internal/service"
+	"pinn-connect-service/internal/storage"
+	"pinn-connect-service/internal/workspace"
+	"sync"
+	"syscall"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// The worker executes queued tasks without serving the API. It shares the
+// database, the storage and TMP_DIR with the server and the other workers.
+func main() {
+	slog.SetDefault(slog.Default())
+
+	if err := run(); err != nil {
+		slog.Error("worker stopped with error", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	manager, err := docker.NewPool(ctx, cfg.Docker)
+	if err != nil {
+		return fmt.Errorf("error while initializing Docker clients: %w", err)
+	}
+	defer func() {
+		slog.Info("closing Docker clients...")
+		if err := manager.Close(); err != nil {
+			slog.Error("failed to close Docker clients", "error", err)
+		}
+	}()
+
+	artifactStorage, err := storage.New(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("error while initializing %s storage: %w", cfg.Storage.Backend, err)
+	}
+
+	if err := db.Migrate(cfg.DB.URL); err != nil {
+		return fmt.Errorf("migrations failed: %w", err)
+	}
+
+	pool, err := pgxpool.New(ctx, cfg.DB.URL)
+	if err != nil {
+		return fmt.Errorf("error while creating new pgxpool: %w", err)
+	}
+	defer pool.Close()
+
+	taskRepo := repository.NewTaskRepository(pool)
+	modelRepo := repository.NewModelRepository(pool)
+	nodeRepo := repository.NewNodeRepository(pool)
+
+	workspace := workspace.NewLocalWorkspace(cfg)
+
+	modelService := service.NewModelService(modelRepo, manager)
+	var secretCipher service.SecretCipher
+	if cfg.Secrets.Keyring != "" {
+		keyring, err := envelope.LoadKeyring(cfg.Secrets.Keyring)
+		if err != nil {
+			return fmt.Errorf("error while loading secrets keyring: %w", err)
+		}
+		secretCipher = keyring
+	}
+	secretService := service.NewSecretService(repository.NewSecretRepository(pool), secretCipher)
+	taskService := service.NewTaskService(manager, artifactStorage, cfg, taskRepo, workspace, modelService, secretService)
+	garbageCollector := gc.NewGarbageCollector(taskRepo, workspace, manager, artifactStorage, taskService, cfg.GC, cfg.Worker.LeaseDuration, cfg.InstanceID)
+
+	self, err := node.Describe(cfg)
+	if err != nil {
+		return fmt.Errorf("describing node: %w", err)
+	}
+	agent := node.NewAgent(nodeRepo, self, cfg.Node)
+	if err := agent.Register(ctx); err != nil {
+		return err
+	}
+
+	// the node is marked stopped only after its workers have drained
+	nodeCtx, nodeCancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer nodeCancel()
+	var nodeWG sync.WaitGroup
+	agent.Start(nodeCtx, &nodeWG)
+
+	listener := notify.NewListener(cfg.DB)
+	queued := listener.Subscribe(domain.TaskQueued)
+	stopped := listener.Subscribe(domain.TaskStopped)
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		gcCtx, gcCancel := context.WithTimeout(ctx, cfg.GC.Timeout)
+		defer gcCancel()
+		garbageCollector.Cleanup(gcCtx)
+	})
+	garbageCollector.Start(ctx, &wg)
+	taskService.StartWorker(ctx, &wg, queued)
+	taskService.StartStopWatcher(ctx, &wg, stopped)
+	listener.Start(ctx, &wg)
+	manager.Start(ctx, &wg)
+
+	slog.Info("worker started", "node", self.ID, "capacity", self.Capacity)
+	<-ctx.Done()
+	slog.Info("worker shutting down, waiting for tasks...")
+
+	wg.Wait()
+	nodeCancel()
+	nodeWG.Wait()
+
+	return nil
+}
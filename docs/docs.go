@@ -15,9 +15,208 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/gc": {
+            "get": {
+                "description": "Returns the report of the last garbage collector run",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get last garbage collector report",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.GCReport"
+                        }
+                    },
+                    "404": {
+                        "description": "Garbage collector has not run yet",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/gc/run": {
+            "post": {
+                "description": "Runs the garbage collector immediately and returns the report",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Run garbage collector",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.GCReport"
+                        }
+                    },
+                    "409": {
+                        "description": "Instance does not execute tasks",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/nodes": {
+            "get": {
+                "description": "Returns the registered nodes executing tasks with their capacity, labels, last heartbeat and the number of running tasks. A node is alive if its last heartbeat is within NODE_TIMEOUT.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List nodes",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Node"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reconcile": {
+            "post": {
+                "description": "Finds objects under tasks/ that are not referenced by any task and tasks whose result object is missing. Orphan objects are deleted and tasks are marked with result_missing unless dry_run is set.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reconcile storage with the database",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Only report, don't delete or mark anything",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ReconcileReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid dry_run",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Instance is not the leader",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/retention": {
+            "get": {
+                "description": "Returns the report of the last retention run",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get last retention report",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.RetentionReport"
+                        }
+                    },
+                    "404": {
+                        "description": "Retention has not run yet",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/retention/run": {
+            "post": {
+                "description": "Applies the retention policy immediately and returns the report",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Run retention",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.RetentionReport"
+                        }
+                    },
+                    "409": {
+                        "description": "Instance is not the leader",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/scheduler": {
+            "get": {
+                "description": "Returns the state of the scheduler: the last run, the next scheduled firing, the number of queued tasks and the most recent misfires",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get scheduler report",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.SchedulerReport"
+                        }
+                    },
+                    "404": {
+                        "description": "Scheduler has not run yet",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/health": {
             "get": {
-                "description": "Returns the health status of the application and its dependencies",
+                "description": "Returns the health status of the application and its dependencies, and the instance running the singleton jobs (scheduler, garbage collector, retention)",
                 "produces": [
                     "application/json"
                 ],
@@ -248,66 +447,93 @@ const docTemplate = `{
                 }
             }
         },
-        "/stats": {
-            "get": {
-                "description": "Returns CPU utilization and available memory of the host",
-                "produces": [
+        "/model/{id}/network": {
+            "put": {
+                "description": "Sets the network mode of the tasks of a model created from now on, an empty mode falls back to the default of the service",
+                "consumes": [
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "models"
+                ],
+                "summary": "Set model network mode",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Model ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Set Model Network Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.SetModelNetworkRequest"
+                        }
+                    }
                 ],
-                "summary": "Get host resources statistics",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid JSON or network mode not allowed",
                         "schema": {
-                            "$ref": "#/definitions/domain.StatsResponse"
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Model not found",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/task/run": {
-            "post": {
-                "description": "Accepts multipart/form-data with task metadata and optional input file",
+        "/model/{id}/security": {
+            "put": {
+                "description": "Overrides the security profile of the service for the tasks of a model started from now on, an empty object falls back to the profile of the service",
                 "consumes": [
-                    "multipart/form-data"
-                ],
-                "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tasks"
+                    "models"
                 ],
-                "summary": "Create and run a new task",
+                "summary": "Set model security overrides",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Task metadata (JSON)",
-                        "name": "task",
-                        "in": "formData",
+                        "description": "Model ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "type": "file",
-                        "description": "Input file for the task",
-                        "name": "file",
-                        "in": "formData"
+                        "description": "Security overrides",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.ModelSecurity"
+                        }
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Task accepted",
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid JSON or overrides",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "string"
                         }
                     },
-                    "400": {
-                        "description": "Invalid request or missing fields",
+                    "404": {
+                        "description": "Model not found",
                         "schema": {
                             "type": "string"
                         }
@@ -315,114 +541,1531 @@ const docTemplate = `{
                 }
             }
         },
-        "/task/{id}/status": {
+        "/secret": {
             "get": {
-                "description": "Returns the current status and metadata of a task",
+                "description": "Returns the names of the stored secrets, their values are never returned",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tasks"
-                ],
-                "summary": "Get task status",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Task UUID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "secrets"
                 ],
+                "summary": "List secrets",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.TaskStatusResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Secret"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "Task not found",
+                    "503": {
+                        "description": "Secrets are disabled",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
-            }
-        },
-        "/task/{id}/stop": {
+            },
             "post": {
-                "description": "Sends a stop signal to a running container task",
+                "description": "Stores the value encrypted. Tasks reference the secret by name in their secrets field",
+                "consumes": [
+                    "application/json"
+                ],
                 "tags": [
-                    "tasks"
+                    "secrets"
                 ],
-                "summary": "Stop a running task",
+                "summary": "Create a secret",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Task UUID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Stop timeout (seconds or duration string)",
-                        "name": "timeout",
+                        "description": "Create Secret Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.CreateSecretRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "Invalid JSON or name",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Secret already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Secrets are disabled",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/secret/{name}": {
+            "put": {
+                "description": "Replaces the value of a secret. Running containers keep the old value",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "secrets"
+                ],
+                "summary": "Update a secret",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Secret name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Update Secret Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.UpdateSecretRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid JSON or name",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Secret not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Secrets are disabled",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes a secret. Queued tasks referencing it fail when they start",
+                "tags": [
+                    "secrets"
+                ],
+                "summary": "Delete a secret",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Secret name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Secret not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Secrets are disabled",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats": {
+            "get": {
+                "description": "Returns CPU utilization and available memory of the host, and the health and load of the Docker hosts",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "Get host resources statistics",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.StatsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/storage/{key}": {
+            "get": {
+                "description": "Serves objects of the local storage backend and client-side encrypted objects of the s3 backend, decrypting them on the fly. URLs are produced by /task/{id}/result and expire. Supports Range requests.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Download a stored object through a signed URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Object key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Expiry as unix time",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "URL signature",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Partial Content",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Invalid or expired signature",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "File not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/task/list": {
+            "get": {
+                "description": "Returns a paginated list of all tasks with their statuses and metadata",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get all tasks (paginated)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page number (default: 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items per page (default: 10)",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "oom_killed",
+                            "timeout",
+                            "nonzero_exit",
+                            "image_pull_failed",
+                            "container_start_failed",
+                            "upload_failed",
+                            "user_cancelled",
+                            "infrastructure",
+                            "disk_quota_exceeded",
+                            "attempts_exhausted"
+                        ],
+                        "type": "string",
+                        "description": "Only tasks that failed or were stopped for the reason",
+                        "name": "failure_reason",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.GetAllTasksResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown failure reason",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/task/run": {
+            "post": {
+                "description": "Accepts multipart/form-data with task metadata and an optional input file, or the task metadata as application/json. A task needs an input file, params or both.",
+                "consumes": [
+                    "multipart/form-data",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Create and run a new task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task metadata (JSON)",
+                        "name": "task",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Input file for the task",
+                        "name": "file",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Task accepted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or missing fields",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "507": {
+                        "description": "Not enough free disk space",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/task/usage": {
+            "get": {
+                "description": "Aggregates the resources used by the tasks per model and per day (UTC). A task is counted on the day its container exited",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get resource usage report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start of the period, RFC 3339 or YYYY-MM-DD (default: 30 days before to)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the period, exclusive, RFC 3339 or YYYY-MM-DD (default: now)",
+                        "name": "to",
                         "in": "query"
                     }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Task stopped",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "400": {
-                        "description": "Invalid ID or timeout format",
-                        "schema": {
-                            "type": "string"
-                        }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.UsageReportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid period",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/task/{id}": {
+            "delete": {
+                "description": "Removes task from database, deletes its artifacts from storage and cleans up its workspace",
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Delete a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/task/{id}/archive": {
+            "get": {
+                "description": "Streams the whole result set as a zip or tar.gz archive generated on the fly",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Download all task result files as an archive",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Archive format: zip (default) or tar.gz",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID or format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found or result not ready",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/task/{id}/events": {
+            "get": {
+                "description": "Returns the status transitions of the task with the component that made each of them, oldest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get task status history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TaskEventsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/task/{id}/files": {
+            "get": {
+                "description": "Returns all result objects of a completed task",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "List task result files",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TaskFilesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found or result not ready",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/task/{id}/files/{path}": {
+            "get": {
+                "description": "Streams one result object through the service. Supports Range requests.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Download a single task result file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File path relative to the result set",
+                        "name": "path",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Partial Content",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Task, result or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/task/{id}/pin": {
+            "post": {
+                "description": "Excludes the task and its result from retention cleanup",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Pin a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TaskStatusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Makes the task subject to retention cleanup again",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Unpin a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TaskStatusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/task/{id}/result": {
+            "get": {
+                "description": "Returns a pre-signed URL to download the task result artifact",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get task result download URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "URL expiry in seconds or as a duration string (default: STORAGE_PRESIGN_EXPIRY)",
+                        "name": "expires",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Download URL",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID or expiry",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found or result not ready",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/task/{id}/stats": {
+            "get": {
+                "description": "Streams Server-Sent Events with the CPU, memory and IO usage of the task container about every second. Each ` + "`" + `stats` + "`" + ` event carries a domain.TaskStats, an ` + "`" + `end` + "`" + ` event is sent once the container exits",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Stream live resource stats of a running task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TaskStats"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Task is not running",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/task/{id}/status": {
+            "get": {
+                "description": "Returns the current status and metadata of a task",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get task status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TaskStatusResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/task/{id}/stop": {
+            "post": {
+                "description": "Sends a stop signal to a running container task. A queued or scheduled task is cancelled.",
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Stop a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Stop timeout (seconds or duration string)",
+                        "name": "timeout",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Task stopped",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID or timeout format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Task can't be stopped in its status or changed concurrently",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/task/{id}/upload/retry": {
+            "post": {
+                "description": "Uploads again the result of a task whose container succeeded but whose upload failed. Parts already uploaded are reused, the container is not run again. The upload runs in the background, its progress is shown in the task status",
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Retry the result upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted"
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Upload has not failed or is already in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/task/{id}/verify": {
+            "post": {
+                "description": "Re-reads every stored result object and compares it with the sha256 recorded on upload. A corrupted result is flagged and no longer reused by the cache.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Verify task result integrity",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.VerifyReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found or result not ready",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "domain.ChecksumStatus": {
+            "type": "string",
+            "enum": [
+                "ok",
+                "mismatch",
+                "missing",
+                "unrecorded"
+            ],
+            "x-enum-varnames": [
+                "ChecksumOK",
+                "ChecksumMismatch",
+                "ChecksumMissing",
+                "ChecksumUnrecorded"
+            ]
+        },
+        "domain.CreateModelRequest": {
+            "type": "object",
+            "properties": {
+                "container_image": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.CreateSecretRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.DockerHost": {
+            "type": "object",
+            "properties": {
+                "checked_at": {
+                    "type": "string"
+                },
+                "cpu": {
+                    "description": "CPU and MemoryMB are the resources available to tasks, zero is unlimited.\nCPU uses the units of the task cpu limit, 100 is one thread.",
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "gpu": {
+                    "type": "boolean"
+                },
+                "healthy": {
+                    "type": "boolean"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "memory_mb": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "running_containers": {
+                    "description": "UsedCPU and UsedMemoryMB are the limits of the running task containers.",
+                    "type": "integer"
+                },
+                "used_cpu": {
+                    "type": "integer"
+                },
+                "used_memory_mb": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.FileVerification": {
+            "type": "object",
+            "properties": {
+                "actual_sha256": {
+                    "type": "string"
+                },
+                "expected_sha256": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "status": {
+                    "$ref": "#/definitions/domain.ChecksumStatus"
+                }
+            }
+        },
+        "domain.GCReport": {
+            "type": "object",
+            "properties": {
+                "completed_tasks": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "failed_tasks": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "reclaimed_tasks": {
+                    "description": "ReclaimedTasks were taken over from nodes whose lease expired.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "recovered_tasks": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "removed_containers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "removed_workspaces": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "requeued_tasks": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "started_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.GetAllTasksResponse": {
+            "type": "object",
+            "properties": {
+                "page": {
+                    "type": "integer"
+                },
+                "page_size": {
+                    "type": "integer"
+                },
+                "tasks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.TaskStatusResponse"
+                    }
+                },
+                "total_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "leader": {
+                    "$ref": "#/definitions/domain.LeaderInfo"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.LeaderInfo": {
+            "type": "object",
+            "properties": {
+                "instance_id": {
+                    "type": "string"
+                },
+                "is_leader": {
+                    "type": "boolean"
+                },
+                "leader_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.Misfire": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "detected_at": {
+                    "type": "string"
+                },
+                "scheduled_at": {
+                    "type": "string"
+                },
+                "task_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.Model": {
+            "type": "object",
+            "properties": {
+                "containerImage": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "networkMode": {
+                    "description": "NetworkMode is the network of the tasks of the model, empty for the\ndefault of the service.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.NetworkMode"
+                        }
+                    ]
+                },
+                "security": {
+                    "description": "Security overrides the security profile of the tasks of the model, nil\nfor the profile of the service.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.ModelSecurity"
+                        }
+                    ]
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.ModelSecurity": {
+            "type": "object",
+            "properties": {
+                "cap_add": {
+                    "description": "CapAdd are kept in addition to the capabilities of the service.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "no_new_privileges": {
+                    "type": "boolean"
+                },
+                "pids_limit": {
+                    "type": "integer"
+                },
+                "read_only_rootfs": {
+                    "type": "boolean"
+                },
+                "seccomp_unconfined": {
+                    "type": "boolean"
+                },
+                "user": {
+                    "description": "User runs the tasks as user[:group], empty keeps the user of the image.",
+                    "type": "string"
+                }
+            }
+        },
+        "domain.NetworkMode": {
+            "type": "string",
+            "enum": [
+                "none",
+                "internal",
+                "egress",
+                "bridge"
+            ],
+            "x-enum-varnames": [
+                "NetworkNone",
+                "NetworkInternal",
+                "NetworkEgress",
+                "NetworkBridge"
+            ]
+        },
+        "domain.Node": {
+            "type": "object",
+            "properties": {
+                "alive": {
+                    "type": "boolean"
+                },
+                "capacity": {
+                    "type": "integer"
+                },
+                "heartbeat_at": {
+                    "type": "string"
+                },
+                "hostname": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "running_tasks": {
+                    "description": "RunningTasks is the number of running tasks claimed by the node.",
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "stopped_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.ReconcileReport": {
+            "type": "object",
+            "properties": {
+                "deleted_objects": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "missing_results": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "orphan_bytes": {
+                    "type": "integer"
+                },
+                "orphan_objects": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
                     }
+                },
+                "restored_results": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "scanned_objects": {
+                    "type": "integer"
+                },
+                "scanned_tasks": {
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
                 }
             }
-        }
-    },
-    "definitions": {
-        "domain.CreateModelRequest": {
+        },
+        "domain.ResourceUsage": {
             "type": "object",
             "properties": {
-                "container_image": {
-                    "type": "string"
+                "block_read_bytes": {
+                    "type": "integer"
                 },
-                "id": {
+                "block_write_bytes": {
+                    "type": "integer"
+                },
+                "cpu_seconds": {
+                    "type": "number"
+                },
+                "mem_avg_bytes": {
+                    "type": "integer"
+                },
+                "mem_peak_bytes": {
+                    "type": "integer"
+                },
+                "net_rx_bytes": {
+                    "type": "integer"
+                },
+                "net_tx_bytes": {
+                    "type": "integer"
+                },
+                "queue_seconds": {
+                    "description": "QueueSeconds is the time from the task becoming runnable to its start,\nRunSeconds the time from its start to the exit of the container.",
+                    "type": "number"
+                },
+                "recorded_at": {
                     "type": "string"
+                },
+                "run_seconds": {
+                    "type": "number"
+                },
+                "samples": {
+                    "type": "integer"
                 }
             }
         },
-        "domain.HealthResponse": {
+        "domain.RetentionDeletion": {
             "type": "object",
             "properties": {
+                "reason": {
+                    "$ref": "#/definitions/domain.RetentionReason"
+                },
                 "status": {
+                    "$ref": "#/definitions/domain.TaskStatus"
+                },
+                "task_id": {
                     "type": "string"
                 }
             }
         },
-        "domain.Model": {
+        "domain.RetentionReason": {
+            "type": "string",
+            "enum": [
+                "expired",
+                "size_limit"
+            ],
+            "x-enum-varnames": [
+                "RetentionExpired",
+                "RetentionSizeLimit"
+            ]
+        },
+        "domain.RetentionReport": {
             "type": "object",
             "properties": {
-                "containerImage": {
+                "deleted_prefixes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "deleted_tasks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.RetentionDeletion"
+                    }
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "finished_at": {
                     "type": "string"
                 },
-                "createdAt": {
+                "freed_bytes": {
+                    "type": "integer"
+                },
+                "started_at": {
                     "type": "string"
                 },
-                "id": {
+                "total_bytes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.SchedulerReport": {
+            "type": "object",
+            "properties": {
+                "last_error": {
                     "type": "string"
                 },
-                "updatedAt": {
+                "last_run_at": {
+                    "type": "string"
+                },
+                "misfires": {
+                    "description": "Misfires lists the most recent misfires, newest first.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Misfire"
+                    }
+                },
+                "next_firing_at": {
+                    "description": "NextFiringAt is the earliest scheduled time of a task still waiting.",
+                    "type": "string"
+                },
+                "queued_tasks": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Secret": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "name": {
                     "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.SetModelNetworkRequest": {
+            "type": "object",
+            "properties": {
+                "network_mode": {
+                    "type": "string",
+                    "enum": [
+                        "none",
+                        "internal",
+                        "egress",
+                        "bridge"
+                    ]
                 }
             }
         },
@@ -434,24 +2077,236 @@ const docTemplate = `{
                 },
                 "cpu_utilization": {
                     "type": "number"
+                },
+                "docker_hosts": {
+                    "description": "DockerHosts is empty when the instance doesn't execute tasks.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.DockerHost"
+                    }
+                }
+            }
+        },
+        "domain.TaskActor": {
+            "type": "string",
+            "enum": [
+                "api",
+                "worker",
+                "scheduler",
+                "gc"
+            ],
+            "x-enum-varnames": [
+                "ActorAPI",
+                "ActorWorker",
+                "ActorScheduler",
+                "ActorGC"
+            ]
+        },
+        "domain.TaskEvent": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "$ref": "#/definitions/domain.TaskActor"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "from": {
+                    "$ref": "#/definitions/domain.TaskStatus"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "node_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "task_id": {
+                    "type": "string"
+                },
+                "to": {
+                    "$ref": "#/definitions/domain.TaskStatus"
+                }
+            }
+        },
+        "domain.TaskEventsResponse": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.TaskEvent"
+                    }
+                },
+                "task_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.TaskFileResponse": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string"
+                },
+                "last_modified": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "sha256": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.TaskFilesResponse": {
+            "type": "object",
+            "properties": {
+                "files": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.TaskFileResponse"
+                    }
+                }
+            }
+        },
+        "domain.TaskStats": {
+            "type": "object",
+            "properties": {
+                "block_read_bytes": {
+                    "type": "integer"
+                },
+                "block_write_bytes": {
+                    "type": "integer"
+                },
+                "cpu_limit_percent": {
+                    "type": "integer"
+                },
+                "cpu_percent": {
+                    "type": "number"
+                },
+                "memory_bytes": {
+                    "type": "integer"
+                },
+                "memory_limit_bytes": {
+                    "type": "integer"
+                },
+                "memory_percent": {
+                    "type": "number"
+                },
+                "net_rx_bytes": {
+                    "type": "integer"
+                },
+                "net_tx_bytes": {
+                    "type": "integer"
+                },
+                "read_at": {
+                    "type": "string"
                 }
             }
         },
+        "domain.TaskStatus": {
+            "type": "string",
+            "enum": [
+                "initializing",
+                "scheduled",
+                "running",
+                "completed",
+                "failed",
+                "queued",
+                "stopped",
+                "skipped"
+            ],
+            "x-enum-varnames": [
+                "TaskInitializing",
+                "TaskScheduled",
+                "TaskRunning",
+                "TaskCompleted",
+                "TaskFailed",
+                "TaskQueued",
+                "TaskStopped",
+                "TaskSkipped"
+            ]
+        },
         "domain.TaskStatusResponse": {
             "type": "object",
             "properties": {
+                "attempts": {
+                    "description": "Attempts is how many times the run of the task was lost and requeued.",
+                    "type": "integer"
+                },
+                "constraints": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
                 "created_at": {
                     "type": "string"
                 },
+                "disk_limit": {
+                    "description": "DiskLimit is the size in MB the task may write to its result.",
+                    "type": "integer"
+                },
                 "err_log": {
                     "type": "string"
                 },
+                "exit_code": {
+                    "description": "ExitCode is the exit code of the container, absent if it never exited.",
+                    "type": "integer"
+                },
+                "failure_reason": {
+                    "description": "FailureReason tells why a failed or stopped task didn't complete.",
+                    "type": "string",
+                    "enum": [
+                        "oom_killed",
+                        "timeout",
+                        "nonzero_exit",
+                        "image_pull_failed",
+                        "container_start_failed",
+                        "upload_failed",
+                        "user_cancelled",
+                        "infrastructure",
+                        "disk_quota_exceeded",
+                        "attempts_exhausted"
+                    ]
+                },
                 "finished_at": {
                     "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
+                "input_sha256": {
+                    "type": "string"
+                },
+                "keep_for_sec": {
+                    "type": "integer"
+                },
+                "model_id": {
+                    "type": "string"
+                },
+                "network_mode": {
+                    "description": "NetworkMode is the network access the container of the task got.",
+                    "type": "string"
+                },
+                "pinned": {
+                    "type": "boolean"
+                },
+                "result_corrupted": {
+                    "description": "ResultCorrupted is set when the stored result doesn't match its checksums.",
+                    "type": "boolean"
+                },
+                "result_missing": {
+                    "description": "ResultMissing is set when the stored result object was not found in the bucket.",
+                    "type": "boolean"
+                },
                 "result_path": {
                     "type": "string"
                 },
@@ -463,6 +2318,25 @@ const docTemplate = `{
                 },
                 "status": {
                     "type": "string"
+                },
+                "upload_failed": {
+                    "description": "UploadFailed is set for failed tasks whose result upload can be retried.",
+                    "type": "boolean"
+                },
+                "upload_total_bytes": {
+                    "description": "UploadTotalBytes and UploadedBytes show the progress of the result upload.",
+                    "type": "integer"
+                },
+                "uploaded_bytes": {
+                    "type": "integer"
+                },
+                "usage": {
+                    "description": "Usage is the resources the task consumed, absent until its container exits.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.ResourceUsage"
+                        }
+                    ]
                 }
             }
         },
@@ -476,6 +2350,92 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "domain.UpdateSecretRequest": {
+            "type": "object",
+            "properties": {
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.UsageReportResponse": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.UsageReportRow"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.UsageReportRow": {
+            "type": "object",
+            "properties": {
+                "avg_queue_seconds": {
+                    "type": "number"
+                },
+                "block_read_bytes": {
+                    "type": "integer"
+                },
+                "block_write_bytes": {
+                    "type": "integer"
+                },
+                "cpu_seconds": {
+                    "type": "number"
+                },
+                "day": {
+                    "type": "string"
+                },
+                "mem_avg_bytes": {
+                    "type": "integer"
+                },
+                "mem_peak_bytes": {
+                    "type": "integer"
+                },
+                "model_id": {
+                    "type": "string"
+                },
+                "net_rx_bytes": {
+                    "type": "integer"
+                },
+                "net_tx_bytes": {
+                    "type": "integer"
+                },
+                "run_seconds": {
+                    "type": "number"
+                },
+                "tasks": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.VerifyReport": {
+            "type": "object",
+            "properties": {
+                "checked_at": {
+                    "type": "string"
+                },
+                "files": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.FileVerification"
+                    }
+                },
+                "ok": {
+                    "type": "boolean"
+                },
+                "task_id": {
+                    "type": "string"
+                }
+            }
         }
     }
 }`
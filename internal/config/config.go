@@ -3,48 +3,279 @@ package config
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v11"
 )
 
+// DatabaseConfig configures the connection pool and the dedicated connection
+// listening for task changes, which is reopened after ListenReconnectDelay.
 type DatabaseConfig struct {
-	URL string `env:"URL,required"`
+	URL                  string        `env:"URL,required"`
+	ListenReconnectDelay time.Duration `env:"LISTEN_RECONNECT_DELAY" envDefault:"5s"`
 }
 
+const (
+	StorageS3    = "s3"
+	StorageLocal = "local"
+)
+
+// StorageConfig selects the storage backend for task results. Download URLs
+// are valid for PresignExpiry unless a request asks for another expiry, which
+// can't exceed MaxPresignExpiry. With VerifyCached a cached result is checked
+// against its recorded checksums before it is reused. That re-reads the whole
+// result on every cache hit, so it's off by default.
+type StorageConfig struct {
+	Backend          string             `env:"BACKEND" envDefault:"s3"`
+	PresignExpiry    time.Duration      `env:"PRESIGN_EXPIRY" envDefault:"10m"`
+	MaxPresignExpiry time.Duration      `env:"MAX_PRESIGN_EXPIRY" envDefault:"24h"`
+	VerifyCached     bool               `env:"VERIFY_CACHED" envDefault:"false"`
+	Local            LocalStorageConfig `envPrefix:"LOCAL_"`
+}
+
+// LocalStorageConfig configures the filesystem backend. Downloads are served
+// by the service itself through URLs signed with SigningKey and rooted at PublicURL.
+type LocalStorageConfig struct {
+	Dir        string `env:"DIR" envDefault:"./storage"`
+	PublicURL  string `env:"PUBLIC_URL" envDefault:"http://localhost:8080"`
+	SigningKey string `env:"SIGNING_KEY"`
+}
+
+// MinIOConfig configures the S3 backend. It works with MinIO as well as with
+// any S3 compatible service. Without AccessKey credentials are taken from the
+// AWS environment variables, the shared credentials file or the instance role.
 type MinIOConfig struct {
-	Endpoint         string `env:"ENDPOINT,required"`
-	ExternalEndpoint string `env:"EXTERNAL_ENDPOINT,required" envDefault:"localhost:9000"`
-	AccessKey        string `env:"ACCESS_KEY,required"`
-	SecretKey        string `env:"SECRET_KEY,required"`
+	Endpoint         string `env:"ENDPOINT"`
+	ExternalEndpoint string `env:"EXTERNAL_ENDPOINT" envDefault:"localhost:9000"`
+	AccessKey        string `env:"ACCESS_KEY"`
+	SecretKey        string `env:"SECRET_KEY"`
 	SSLUse           bool   `env:"USE_SSL" envDefault:"false"`
 	Bucket           string `env:"BUCKET" envDefault:"tasks"`
+	Region           string `env:"REGION" envDefault:"us-east-1"`
+	// Addressing is one of auto, path or virtual.
+	Addressing   string `env:"ADDRESSING" envDefault:"auto"`
+	CreateBucket bool   `env:"CREATE_BUCKET" envDefault:"true"`
+	// SSE is the server-side encryption of uploaded objects: none, s3 or kms.
+	SSE         string `env:"SSE" envDefault:"none"`
+	SSEKMSKeyID string `env:"SSE_KMS_KEY_ID"`
+	// EncryptionKeyring is the path of the master keyring. When set objects
+	// are encrypted before they leave the service and downloads are served
+	// by the service like the ones of the local backend.
+	EncryptionKeyring string `env:"ENCRYPTION_KEYRING"`
 }
 
+// ServerConfig configures the API server. With ExecuteTasks the server also
+// runs as a node executing tasks, otherwise tasks are left to cmd/worker.
 type ServerConfig struct {
 	Port                   string        `env:"PORT" envDefault:":8080"`
+	ExecuteTasks           bool          `env:"EXECUTE_TASKS" envDefault:"true"`
 	DefaultTaskStopTimeout time.Duration `env:"DEFAULT_TASK_STOP_TIMEOUT" envDefault:"5s"`
 	APIToken               string        `env:"API_TOKEN"` // e.g. SERVER_API_TOKEN=secret
 }
 
+// Misfire policies of the scheduler.
+const (
+	MisfireRun  = "run"
+	MisfireSkip = "skip"
+	MisfireFail = "fail"
+)
+
+// SchedulerConfig controls the promotion of scheduled tasks to the queue.
+// The scheduler wakes up at the next scheduled time or when a task is
+// scheduled, and polls every Interval as a fallback. A task promoted more
+// than MisfireThreshold after its scheduled time is handled by MisfirePolicy:
+// run it anyway, skip it or fail it.
 type SchedulerConfig struct {
-	Interval    time.Duration `env:"INTERVAL" envDefault:"20s"`
-	TaskExpires time.Duration `env:"TASK_EXPIRES" envDefault:"30s"`
+	Interval         time.Duration `env:"INTERVAL" envDefault:"1m"`
+	MisfirePolicy    string        `env:"MISFIRE_POLICY" envDefault:"run"`
+	MisfireThreshold time.Duration `env:"MISFIRE_THRESHOLD" envDefault:"5m"`
 }
 
+// WorkerConfig controls the task workers. Workers are woken up when a task is
+// queued and poll the queue every Interval as a fallback. A worker leases the
+// task it processes for LeaseDuration and renews the lease every third of it.
+// The resource usage of a running task is sampled every StatsInterval.
 type WorkerConfig struct {
 	MaxWorkers                int           `env:"MAX_WORKERS" envDefault:"5"`
-	Interval                  time.Duration `env:"WORKER_INTERVAL" envDefault:"2s"`
+	Interval                  time.Duration `env:"WORKER_INTERVAL" envDefault:"30s"`
+	LeaseDuration             time.Duration `env:"WORKER_LEASE_DURATION" envDefault:"1m"`
 	ProcessTaskCleanupTimeout time.Duration `env:"PROCESS_TASK_CLEANUP_TIMEOUT" envDefault:"10s"`
+	StatsInterval             time.Duration `env:"WORKER_STATS_INTERVAL" envDefault:"5s"`
+}
+
+// NodeConfig describes the node to the other instances. The node renews its
+// heartbeat every HeartbeatInterval and is considered dead once its heartbeat
+// is older than Timeout. Labels are given as NODE_LABELS=gpu=true,arch=amd64.
+type NodeConfig struct {
+	HeartbeatInterval time.Duration     `env:"HEARTBEAT_INTERVAL" envDefault:"10s"`
+	Timeout           time.Duration     `env:"TIMEOUT" envDefault:"30s"`
+	Labels            map[string]string `env:"LABELS" envKeyValSeparator:"="`
+}
+
+// DockerConfig configures the pool of Docker hosts running task containers.
+// The hosts are read from the JSON HostsFile, without it the pool has a
+// single host taken from the DOCKER_HOST environment variables. The hosts are
+// checked every HealthInterval. The exits of task containers are taken from
+// the events of the hosts, a broken events stream is reopened after
+// EventsReconnectDelay.
+//
+// Task containers get the network NetworkMode unless their task or model
+// chooses another one of NetworkModes. The internal mode uses InternalNetwork,
+// created on each host on first use; the egress mode uses EgressNetwork, set
+// up by the operator to let through allowlisted destinations only. With
+// EgressProxy the egress containers get it in their proxy variables.
+//
+// Task containers are hardened with Security, models may override it.
+type DockerConfig struct {
+	HostsFile            string         `env:"HOSTS_FILE"`
+	HealthInterval       time.Duration  `env:"HEALTH_INTERVAL" envDefault:"15s"`
+	EventsReconnectDelay time.Duration  `env:"EVENTS_RECONNECT_DELAY" envDefault:"5s"`
+	NetworkMode          string         `env:"NETWORK_MODE" envDefault:"none"`
+	NetworkModes         []string       `env:"NETWORK_MODES" envDefault:"none,internal"`
+	InternalNetwork      string         `env:"INTERNAL_NETWORK" envDefault:"pinn-internal"`
+	EgressNetwork        string         `env:"EGRESS_NETWORK"`
+	EgressProxy          string         `env:"EGRESS_PROXY"`
+	Security             SecurityConfig `envPrefix:"SECURITY_"`
+}
+
+// AllowsNetworkMode tells whether task containers may use the network mode.
+func (c DockerConfig) AllowsNetworkMode(mode string) bool {
+	return slices.Contains(c.NetworkModes, mode)
+}
+
+// SecurityConfig is the hardening of task containers. An empty User keeps the
+// user of the image. Ulimits are given as
+// DOCKER_SECURITY_ULIMITS=nofile=1024:4096,core=0, a single value sets both
+// the soft and the hard limit. User and Ulimits get their defaults only when
+// unset, so that they can be set empty. SeccompProfile is the path of a
+// seccomp profile on the node, empty for the default profile of Docker.
+// WorkspaceGID owns the result directories of the tasks, task containers run
+// with it as a supplementary group to write their results.
+type SecurityConfig struct {
+	User             string            `env:"USER"`
+	DropCapabilities bool              `env:"DROP_CAPABILITIES" envDefault:"true"`
+	CapAdd           []string          `env:"CAP_ADD"`
+	NoNewPrivileges  bool              `env:"NO_NEW_PRIVILEGES" envDefault:"true"`
+	ReadOnlyRootfs   bool              `env:"READ_ONLY_ROOTFS" envDefault:"true"`
+	TmpfsSizeMB      int               `env:"TMPFS_SIZE_MB" envDefault:"64"`
+	PidsLimit        int64             `env:"PIDS_LIMIT" envDefault:"256"`
+	Ulimits          map[string]string `env:"ULIMITS" envKeyValSeparator:"="`
+	SeccompProfile   string            `env:"SECCOMP_PROFILE"`
+	WorkspaceGID     int               `env:"WORKSPACE_GID" envDefault:"65534"`
+}
+
+// ParseUlimit parses a ulimit given as soft[:hard].
+func ParseUlimit(v string) (soft, hard int64, err error) {
+	softStr, hardStr, found := strings.Cut(v, ":")
+	if soft, err = strconv.ParseInt(softStr, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("parsing soft limit: %w", err)
+	}
+	hard = soft
+	if found {
+		if hard, err = strconv.ParseInt(hardStr, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("parsing hard limit: %w", err)
+		}
+	}
+	if soft < 0 || hard < soft {
+		return 0, 0, fmt.Errorf("soft limit must be between 0 and the hard limit")
+	}
+	return soft, hard, nil
+}
+
+// LeaderConfig controls the leader election. Followers try to become the
+// leader every Interval and the leader checks its connection just as often.
+type LeaderConfig struct {
+	Interval time.Duration `env:"INTERVAL" envDefault:"5s"`
+}
+
+// GCConfig controls the periodic garbage collector. Tasks stuck longer than
+// the thresholds are failed or requeued, workspaces without an active task
+// are removed once they are older than WorkspaceMinAge. Running tasks with an
+// expired lease are reclaimed every ReclaimInterval. A task whose run is lost
+// MaxAttempts times is failed instead of being requeued again.
+type GCConfig struct {
+	Interval            time.Duration `env:"INTERVAL" envDefault:"5m"`
+	ReclaimInterval     time.Duration `env:"RECLAIM_INTERVAL" envDefault:"30s"`
+	Timeout             time.Duration `env:"TIMEOUT" envDefault:"1m"`
+	InitializingTimeout time.Duration `env:"INITIALIZING_TIMEOUT" envDefault:"30m"`
+	StartTimeout        time.Duration `env:"START_TIMEOUT" envDefault:"10m"`
+	MaxAttempts         int           `env:"MAX_ATTEMPTS" envDefault:"3"`
+	WorkspaceMinAge     time.Duration `env:"WORKSPACE_MIN_AGE" envDefault:"1h"`
+}
+
+// RetentionConfig controls how long finished tasks and their results are kept.
+// A zero max age keeps tasks with that status forever, a zero MaxTotalBytes
+// disables the size limit.
+type RetentionConfig struct {
+	Enabled         bool          `env:"ENABLED" envDefault:"false"`
+	Interval        time.Duration `env:"INTERVAL" envDefault:"1h"`
+	MaxAgeCompleted time.Duration `env:"MAX_AGE_COMPLETED" envDefault:"720h"`
+	MaxAgeFailed    time.Duration `env:"MAX_AGE_FAILED" envDefault:"168h"`
+	MaxAgeStopped   time.Duration `env:"MAX_AGE_STOPPED" envDefault:"168h"`
+	MaxTotalBytes   int64         `env:"MAX_TOTAL_BYTES" envDefault:"0"`
+}
+
+// ReconcileConfig controls the reconciliation between the tasks table and the
+// bucket. Objects younger than ObjectMinAge are never reported as orphans, so
+// results being uploaded right now are not deleted.
+type ReconcileConfig struct {
+	Timeout      time.Duration `env:"TIMEOUT" envDefault:"10m"`
+	ObjectMinAge time.Duration `env:"OBJECT_MIN_AGE" envDefault:"1h"`
+}
+
+// UploadConfig controls how results are uploaded to the s3 backend. Files
+// larger than PartSize are uploaded in parts, Concurrency parts at a time.
+// A failed part is retried PartRetries times before the upload fails, the
+// parts already uploaded are reused when the upload is retried.
+type UploadConfig struct {
+	PartSize         int64         `env:"PART_SIZE" envDefault:"67108864"`
+	Concurrency      int           `env:"CONCURRENCY" envDefault:"4"`
+	PartRetries      int           `env:"PART_RETRIES" envDefault:"3"`
+	RetryBackoff     time.Duration `env:"RETRY_BACKOFF" envDefault:"1s"`
+	ProgressInterval time.Duration `env:"PROGRESS_INTERVAL" envDefault:"2s"`
+}
+
+// SecretsConfig configures the secrets store. Keyring is the path of the
+// master keyring encrypting the stored values, in the same format as the
+// keyring of the s3 backend. Without it secrets are disabled.
+type SecretsConfig struct {
+	Keyring string `env:"KEYRING"`
+}
+
+// DiskConfig limits the disk used by the task workspaces in TMP_DIR. The
+// result of a task may take up to MaxByTaskMB, checked every CheckInterval;
+// zero is unlimited. New tasks are rejected once less than MinFreeMB or
+// MinFreePercent of the disk is free, zero disables the check.
+type DiskConfig struct {
+	MaxByTaskMB    int           `env:"MAX_BY_TASK_MB" envDefault:"10240"`
+	CheckInterval  time.Duration `env:"CHECK_INTERVAL" envDefault:"10s"`
+	MinFreeMB      int64         `env:"MIN_FREE_MB" envDefault:"1024"`
+	MinFreePercent float64       `env:"MIN_FREE_PERCENT" envDefault:"5"`
 }
 
 type Config struct {
 	DB        DatabaseConfig  `envPrefix:"DB_"`
+	Storage   StorageConfig   `envPrefix:"STORAGE_"`
 	MinIO     MinIOConfig     `envPrefix:"MINIO_"`
 	Server    ServerConfig    `envPrefix:"SERVER_"`
 	Scheduler SchedulerConfig `envPrefix:"SCHEDULER_"`
 	Worker    WorkerConfig
+	Leader    LeaderConfig    `envPrefix:"LEADER_"`
+	Node      NodeConfig      `envPrefix:"NODE_"`
+	Docker    DockerConfig    `envPrefix:"DOCKER_"`
+	GC        GCConfig        `envPrefix:"GC_"`
+	Retention RetentionConfig `envPrefix:"RETENTION_"`
+	Reconcile ReconcileConfig `envPrefix:"RECONCILE_"`
+	Upload    UploadConfig    `envPrefix:"UPLOAD_"`
+	Secrets   SecretsConfig   `envPrefix:"SECRETS_"`
+	Disk      DiskConfig      `envPrefix:"DISK_"`
+
+	// InstanceID tells the instances sharing the database apart, the host name
+	// by default. It is also the ID of the node.
+	InstanceID string `env:"INSTANCE_ID"`
 
 	TmpDir  string `env:"TMP_DIR" envDefault:"./tmp"`
 	MockDir string `env:"MOCK_DIR" envDefault:"./mock"`
@@ -70,12 +301,98 @@ func (c *Config) Validate() error {
 	if c.MaxCPUByTask <= 0 {
 		return fmt.Errorf("MAX_CPU_BY_TASK must be greater than 0, got: %d", c.MaxCPUByTask)
 	}
+	if c.DB.ListenReconnectDelay <= 0 {
+		return fmt.Errorf("DB_LISTEN_RECONNECT_DELAY must be positive")
+	}
 	if c.Worker.Interval <= 0 {
 		return fmt.Errorf("WORKER_INTERVAL must be positive")
 	}
+	if c.Worker.LeaseDuration <= 0 {
+		return fmt.Errorf("WORKER_LEASE_DURATION must be positive")
+	}
+	if c.Worker.StatsInterval <= 0 {
+		return fmt.Errorf("WORKER_STATS_INTERVAL must be positive")
+	}
 	if c.Scheduler.Interval <= 0 {
 		return fmt.Errorf("SCHEDULER_INTERVAL must be positive")
 	}
+	switch c.Scheduler.MisfirePolicy {
+	case MisfireRun, MisfireSkip, MisfireFail:
+	default:
+		return fmt.Errorf("SCHEDULER_MISFIRE_POLICY must be run, skip or fail, got: %q", c.Scheduler.MisfirePolicy)
+	}
+	if c.Scheduler.MisfireThreshold < 0 {
+		return fmt.Errorf("SCHEDULER_MISFIRE_THRESHOLD must not be negative")
+	}
+
+	if c.Node.HeartbeatInterval <= 0 {
+		return fmt.Errorf("NODE_HEARTBEAT_INTERVAL must be positive")
+	}
+	if c.Node.Timeout <= c.Node.HeartbeatInterval {
+		return fmt.Errorf("NODE_TIMEOUT must be greater than NODE_HEARTBEAT_INTERVAL")
+	}
+	if c.Docker.HealthInterval <= 0 {
+		return fmt.Errorf("DOCKER_HEALTH_INTERVAL must be positive")
+	}
+	if c.Docker.EventsReconnectDelay <= 0 {
+		return fmt.Errorf("DOCKER_EVENTS_RECONNECT_DELAY must be positive")
+	}
+	if err := c.validateNetwork(); err != nil {
+		return err
+	}
+	if err := c.validateSecurity(); err != nil {
+		return err
+	}
+	if c.Disk.MaxByTaskMB < 0 {
+		return fmt.Errorf("DISK_MAX_BY_TASK_MB must not be negative")
+	}
+	if c.Disk.MaxByTaskMB > 0 && c.Disk.CheckInterval <= 0 {
+		return fmt.Errorf("DISK_CHECK_INTERVAL must be positive")
+	}
+	if c.Disk.MinFreeMB < 0 || c.Disk.MinFreePercent < 0 || c.Disk.MinFreePercent >= 100 {
+		return fmt.Errorf("DISK_MIN_FREE_MB and DISK_MIN_FREE_PERCENT must be between 0 and the size of the disk")
+	}
+	if c.Leader.Interval <= 0 {
+		return fmt.Errorf("LEADER_INTERVAL must be positive")
+	}
+	if c.InstanceID == "" {
+		return fmt.Errorf("INSTANCE_ID must not be empty")
+	}
+
+	if c.GC.Interval <= 0 {
+		return fmt.Errorf("GC_INTERVAL must be positive")
+	}
+	if c.GC.Timeout <= 0 {
+		return fmt.Errorf("GC_TIMEOUT must be positive")
+	}
+	if c.GC.ReclaimInterval <= 0 {
+		return fmt.Errorf("GC_RECLAIM_INTERVAL must be positive")
+	}
+	if c.GC.MaxAttempts <= 0 {
+		return fmt.Errorf("GC_MAX_ATTEMPTS must be greater than 0, got: %d", c.GC.MaxAttempts)
+	}
+
+	if c.Retention.Enabled && c.Retention.Interval <= 0 {
+		return fmt.Errorf("RETENTION_INTERVAL must be positive")
+	}
+	if c.Retention.MaxTotalBytes < 0 {
+		return fmt.Errorf("RETENTION_MAX_TOTAL_BYTES must not be negative, got: %d", c.Retention.MaxTotalBytes)
+	}
+
+	if err := c.validateStorage(); err != nil {
+		return err
+	}
+
+	if c.Reconcile.Timeout <= 0 {
+		return fmt.Errorf("RECONCILE_TIMEOUT must be positive")
+	}
+	if c.Reconcile.ObjectMinAge < 0 {
+		return fmt.Errorf("RECONCILE_OBJECT_MIN_AGE must not be negative")
+	}
+
+	if err := c.validateUpload(); err != nil {
+		return err
+	}
 
 	if c.Server.Port == "" || c.Server.Port[0] != ':' {
 		return fmt.Errorf("SERVER_PORT must start with colon (e.g. ':8080')")
@@ -84,6 +401,138 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// maxS3PresignExpiry is the longest expiry S3 accepts for presigned URLs.
+const maxS3PresignExpiry = 7 * 24 * time.Hour
+
+func (c *Config) validateStorage() error {
+	if c.Storage.PresignExpiry <= 0 {
+		return fmt.Errorf("STORAGE_PRESIGN_EXPIRY must be positive")
+	}
+	if c.Storage.MaxPresignExpiry < c.Storage.PresignExpiry {
+		return fmt.Errorf("STORAGE_MAX_PRESIGN_EXPIRY must not be less than STORAGE_PRESIGN_EXPIRY")
+	}
+
+	switch c.Storage.Backend {
+	case StorageLocal:
+		if c.Storage.Local.Dir == "" {
+			return fmt.Errorf("STORAGE_LOCAL_DIR is required for the local storage backend")
+		}
+		if c.Storage.Local.PublicURL == "" {
+			return fmt.Errorf("STORAGE_LOCAL_PUBLIC_URL is required for the local storage backend")
+		}
+		if c.Storage.Local.SigningKey == "" {
+			return fmt.Errorf("STORAGE_LOCAL_SIGNING_KEY is required for the local storage backend")
+		}
+	case StorageS3:
+		if c.MinIO.Endpoint == "" {
+			return fmt.Errorf("MINIO_ENDPOINT is required for the s3 storage backend")
+		}
+		if (c.MinIO.AccessKey == "") != (c.MinIO.SecretKey == "") {
+			return fmt.Errorf("MINIO_ACCESS_KEY and MINIO_SECRET_KEY must be set together")
+		}
+		if c.Storage.MaxPresignExpiry > maxS3PresignExpiry {
+			return fmt.Errorf("STORAGE_MAX_PRESIGN_EXPIRY must not exceed %s for the s3 storage backend", maxS3PresignExpiry)
+		}
+		switch c.MinIO.Addressing {
+		case "auto", "path", "virtual":
+		default:
+			return fmt.Errorf("MINIO_ADDRESSING must be auto, path or virtual, got: %q", c.MinIO.Addressing)
+		}
+		switch c.MinIO.SSE {
+		case "none", "s3":
+		case "kms":
+			if c.MinIO.SSEKMSKeyID == "" {
+				return fmt.Errorf("MINIO_SSE_KMS_KEY_ID is required for kms encryption")
+			}
+		default:
+			return fmt.Errorf("MINIO_SSE must be none, s3 or kms, got: %q", c.MinIO.SSE)
+		}
+		if c.MinIO.EncryptionKeyring != "" && c.Storage.Local.PublicURL == "" {
+			return fmt.Errorf("STORAGE_LOCAL_PUBLIC_URL is required for client-side encryption")
+		}
+		if c.MinIO.EncryptionKeyring != "" && c.Storage.Local.SigningKey == "" {
+			return fmt.Errorf("STORAGE_LOCAL_SIGNING_KEY is required for client-side encryption")
+		}
+	default:
+		return fmt.Errorf("STORAGE_BACKEND must be s3 or local, got: %q", c.Storage.Backend)
+	}
+
+	return nil
+}
+
+func (c *Config) validateNetwork() error {
+	for _, mode := range c.Docker.NetworkModes {
+		switch mode {
+		case "none", "internal", "egress", "bridge":
+		default:
+			return fmt.Errorf("DOCKER_NETWORK_MODES must contain none, internal, egress or bridge, got: %q", mode)
+		}
+	}
+	if !c.Docker.AllowsNetworkMode(c.Docker.NetworkMode) {
+		return fmt.Errorf("DOCKER_NETWORK_MODE must be one of DOCKER_NETWORK_MODES, got: %q", c.Docker.NetworkMode)
+	}
+	if c.Docker.AllowsNetworkMode("internal") && c.Docker.InternalNetwork == "" {
+		return fmt.Errorf("DOCKER_INTERNAL_NETWORK is required for the internal network mode")
+	}
+	if c.Docker.AllowsNetworkMode("egress") && c.Docker.EgressNetwork == "" {
+		return fmt.Errorf("DOCKER_EGRESS_NETWORK is required for the egress network mode")
+	}
+
+	return nil
+}
+
+func (c *Config) validateSecurity() error {
+	sec := c.Docker.Security
+	if sec.ReadOnlyRootfs && sec.TmpfsSizeMB <= 0 {
+		return fmt.Errorf("DOCKER_SECURITY_TMPFS_SIZE_MB must be positive with a read-only rootfs")
+	}
+	if sec.PidsLimit < 0 {
+		return fmt.Errorf("DOCKER_SECURITY_PIDS_LIMIT must not be negative")
+	}
+	if sec.WorkspaceGID < 0 {
+		return fmt.Errorf("DOCKER_SECURITY_WORKSPACE_GID must not be negative")
+	}
+	for name, v := range sec.Ulimits {
+		if _, _, err := ParseUlimit(v); err != nil {
+			return fmt.Errorf("invalid DOCKER_SECURITY_ULIMITS %s=%s: %w", name, v, err)
+		}
+	}
+	if sec.SeccompProfile != "" {
+		if _, err := os.Stat(sec.SeccompProfile); err != nil {
+			return fmt.Errorf("DOCKER_SECURITY_SECCOMP_PROFILE: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// S3 limits of multipart uploads.
+const (
+	minUploadPartSize = 5 << 20
+	maxUploadPartSize = 5 << 30
+)
+
+func (c *Config) validateUpload() error {
+	if c.Upload.PartSize < minUploadPartSize || c.Upload.PartSize > maxUploadPartSize {
+		return fmt.Errorf("UPLOAD_PART_SIZE must be within %d and %d bytes, got: %d",
+			minUploadPartSize, maxUploadPartSize, c.Upload.PartSize)
+	}
+	if c.Upload.Concurrency <= 0 {
+		return fmt.Errorf("UPLOAD_CONCURRENCY must be greater than 0, got: %d", c.Upload.Concurrency)
+	}
+	if c.Upload.PartRetries < 0 {
+		return fmt.Errorf("UPLOAD_PART_RETRIES must not be negative, got: %d", c.Upload.PartRetries)
+	}
+	if c.Upload.RetryBackoff < 0 {
+		return fmt.Errorf("UPLOAD_RETRY_BACKOFF must not be negative")
+	}
+	if c.Upload.ProgressInterval <= 0 {
+		return fmt.Errorf("UPLOAD_PROGRESS_INTERVAL must be positive")
+	}
+
+	return nil
+}
+
 func Load() (*Config, error) {
 	cfg := &Config{}
 	if err := env.Parse(cfg); err != nil {
@@ -96,6 +545,19 @@ func Load() (*Config, error) {
 	}
 	cfg.WorkspaceDirsPerm = os.FileMode(perm)
 
+	if _, ok := os.LookupEnv("DOCKER_SECURITY_USER"); !ok {
+		cfg.Docker.Security.User = "65534:65534"
+	}
+	if _, ok := os.LookupEnv("DOCKER_SECURITY_ULIMITS"); !ok {
+		cfg.Docker.Security.Ulimits = map[string]string{"nofile": "1024:4096", "core": "0"}
+	}
+
+	if cfg.InstanceID == "" {
+		if cfg.InstanceID, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("getting host name for INSTANCE_ID: %w", err)
+		}
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// Migrate applies the migrations from the migrations directory. It is run by
+// every process on start, concurrent runs are serialized by golang-migrate.
+func Migrate(dbURL string) error {
+	m, err := migrate.New(
+		"file://migrations",
+		dbURL,
+	)
+	if err != nil {
+		return fmt.Errorf("creating migrate instance: %w", err)
+	}
+
+	defer func() {
+		sourceErr, dbErr := m.Close()
+		if sourceErr != nil {
+			slog.Error("failed to close migrate source", "error", sourceErr)
+		}
+		if dbErr != nil {
+			slog.Error("failed to close migrate db connection", "error", dbErr)
+		}
+	}()
+
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		return fmt.Errorf("running migrations: %w", err)
+	}
+
+	slog.Info("Migrations applied successfully")
+	return nil
+}
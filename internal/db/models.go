@@ -11,6 +11,100 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type FailureReason string
+
+const (
+	FailureReasonOomKilled            FailureReason = "oom_killed"
+	FailureReasonTimeout              FailureReason = "timeout"
+	FailureReasonNonzeroExit          FailureReason = "nonzero_exit"
+	FailureReasonImagePullFailed      FailureReason = "image_pull_failed"
+	FailureReasonContainerStartFailed FailureReason = "container_start_failed"
+	FailureReasonUploadFailed         FailureReason = "upload_failed"
+	FailureReasonUserCancelled        FailureReason = "user_cancelled"
+	FailureReasonInfrastructure       FailureReason = "infrastructure"
+	FailureReasonDiskQuotaExceeded    FailureReason = "disk_quota_exceeded"
+	FailureReasonAttemptsExhausted    FailureReason = "attempts_exhausted"
+)
+
+func (e *FailureReason) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = FailureReason(s)
+	case string:
+		*e = FailureReason(s)
+	default:
+		return fmt.Errorf("unsupported scan type for FailureReason: %T", src)
+	}
+	return nil
+}
+
+type NullFailureReason struct {
+	FailureReason FailureReason
+	Valid         bool // Valid is true if FailureReason is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullFailureReason) Scan(value interface{}) error {
+	if value == nil {
+		ns.FailureReason, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.FailureReason.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullFailureReason) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.FailureReason), nil
+}
+
+type NetworkMode string
+
+const (
+	NetworkModeNone     NetworkMode = "none"
+	NetworkModeInternal NetworkMode = "internal"
+	NetworkModeEgress   NetworkMode = "egress"
+	NetworkModeBridge   NetworkMode = "bridge"
+)
+
+func (e *NetworkMode) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = NetworkMode(s)
+	case string:
+		*e = NetworkMode(s)
+	default:
+		return fmt.Errorf("unsupported scan type for NetworkMode: %T", src)
+	}
+	return nil
+}
+
+type NullNetworkMode struct {
+	NetworkMode NetworkMode
+	Valid       bool // Valid is true if NetworkMode is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullNetworkMode) Scan(value interface{}) error {
+	if value == nil {
+		ns.NetworkMode, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.NetworkMode.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullNetworkMode) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.NetworkMode), nil
+}
+
 type TaskStatus string
 
 const (
@@ -21,6 +115,7 @@ const (
 	TaskStatusCompleted    TaskStatus = "completed"
 	TaskStatusFailed       TaskStatus = "failed"
 	TaskStatusStopped      TaskStatus = "stopped"
+	TaskStatusSkipped      TaskStatus = "skipped"
 )
 
 func (e *TaskStatus) Scan(src interface{}) error {
@@ -58,32 +153,101 @@ func (ns NullTaskStatus) Value() (driver.Value, error) {
 	return string(ns.TaskStatus), nil
 }
 
+type ArtifactChecksum struct {
+	ObjectKey string
+	TaskID    pgtype.UUID
+	Size      int64
+	Sha256    string
+	CreatedAt pgtype.Timestamptz
+}
+
 type Model struct {
 	ID             string
 	ContainerImage string
 	CreatedAt      pgtype.Timestamptz
 	UpdatedAt      pgtype.Timestamptz
+	NetworkMode    NullNetworkMode
+	Security       []byte
+}
+
+type Node struct {
+	ID          string
+	Hostname    string
+	Capacity    int32
+	Labels      []byte
+	StartedAt   pgtype.Timestamptz
+	HeartbeatAt pgtype.Timestamptz
+	StoppedAt   pgtype.Timestamptz
+}
+
+type Secret struct {
+	Name      string
+	KeyID     string
+	Value     []byte
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
 }
 
 type Task struct {
-	ID             pgtype.UUID
-	ModelID        string
-	InputFilename  string
-	ResultPath     pgtype.Text
-	Signature      string
-	Status         TaskStatus
-	ContainerID    pgtype.Text
-	ContainerImage pgtype.Text
-	ContainerEnvs  []string
-	ContainerCmd   []string
-	ErrorLog       pgtype.Text
-	ScheduledAt    pgtype.Timestamptz
-	StartedAt      pgtype.Timestamptz
-	FinishedAt     pgtype.Timestamptz
-	CreatedAt      pgtype.Timestamptz
-	UpdatedAt      pgtype.Timestamptz
-	MemLim         pgtype.Int4
-	CpuLim         pgtype.Int4
-	GpuEnable      pgtype.Bool
-	TimeoutSec     int32
+	ID               pgtype.UUID
+	ModelID          string
+	InputFilename    string
+	ResultPath       pgtype.Text
+	Signature        string
+	Status           TaskStatus
+	ContainerID      pgtype.Text
+	ContainerImage   pgtype.Text
+	ContainerEnvs    []string
+	ContainerCmd     []string
+	ErrorLog         pgtype.Text
+	ScheduledAt      pgtype.Timestamptz
+	StartedAt        pgtype.Timestamptz
+	FinishedAt       pgtype.Timestamptz
+	CreatedAt        pgtype.Timestamptz
+	UpdatedAt        pgtype.Timestamptz
+	MemLim           pgtype.Int4
+	CpuLim           pgtype.Int4
+	GpuEnable        pgtype.Bool
+	TimeoutSec       int32
+	Pinned           bool
+	KeepForSec       int32
+	ResultMissing    bool
+	UploadTotalBytes int64
+	UploadDoneBytes  int64
+	UploadFailed     bool
+	InputSha256      string
+	ResultCorrupted  bool
+	NodeID           pgtype.Text
+	LeaseExpiresAt   pgtype.Timestamptz
+	Constraints      []byte
+	Version          int32
+	ExitCode         pgtype.Int4
+	FailureReason    NullFailureReason
+	MemPeakBytes     int64
+	MemAvgBytes      int64
+	CpuSeconds       float64
+	BlockReadBytes   int64
+	BlockWriteBytes  int64
+	NetRxBytes       int64
+	NetTxBytes       int64
+	UsageSamples     int32
+	QueueSeconds     float64
+	RunSeconds       float64
+	UsageRecordedAt  pgtype.Timestamptz
+	SecretEnvs       []byte
+	NetworkMode      NetworkMode
+	DiskLim          int32
+	Attempts         int32
+	RetryAt          pgtype.Timestamptz
+}
+
+type TaskEvent struct {
+	ID         int64
+	TaskID     pgtype.UUID
+	FromStatus NullTaskStatus
+	ToStatus   TaskStatus
+	Actor      string
+	NodeID     pgtype.Text
+	Reason     string
+	CreatedAt  pgtype.Timestamptz
 }
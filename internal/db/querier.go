@@ -11,29 +11,59 @@ import (
 )
 
 type Querier interface {
+	CountResultRefs(ctx context.Context, arg CountResultRefsParams) (int64, error)
 	CreateModel(ctx context.Context, arg CreateModelParams) (Model, error)
+	CreateSecret(ctx context.Context, arg CreateSecretParams) (int64, error)
 	CreateTask(ctx context.Context, arg CreateTaskParams) (Task, error)
+	CreateTaskEvent(ctx context.Context, arg CreateTaskEventParams) error
+	DeleteArtifactChecksums(ctx context.Context, prefix string) error
 	DeleteModel(ctx context.Context, id string) error
+	DeleteSecret(ctx context.Context, name string) (int64, error)
 	DeleteTask(ctx context.Context, id pgtype.UUID) error
 	ExistsModelByID(ctx context.Context, id string) (bool, error)
 	FindCachedTask(ctx context.Context, signature string) (pgtype.Text, error)
 	GetActiveTasks(ctx context.Context) ([]Task, error)
+	GetFinishedTasks(ctx context.Context) ([]Task, error)
 	GetModelByID(ctx context.Context, id string) (Model, error)
-	GetNextQueuedTask(ctx context.Context) (Task, error)
+	GetNextQueuedTask(ctx context.Context, arg GetNextQueuedTaskParams) (Task, error)
+	GetNextScheduledAt(ctx context.Context) (pgtype.Timestamptz, error)
 	GetRunningTasksContainers(ctx context.Context) ([]Task, error)
+	GetSecrets(ctx context.Context, names []string) ([]Secret, error)
+	GetStaleTasks(ctx context.Context, arg GetStaleTasksParams) ([]Task, error)
 	GetTaskByID(ctx context.Context, id pgtype.UUID) (Task, error)
-	GetTasksCount(ctx context.Context) (int64, error)
+	GetTasksCount(ctx context.Context, failureReason NullFailureReason) (int64, error)
 	GetTasksPaginated(ctx context.Context, arg GetTasksPaginatedParams) ([]Task, error)
-	GetUpcomingScheduledTasks(ctx context.Context, scheduledAt pgtype.Timestamptz) ([]Task, error)
+	GetUploadFailedTasks(ctx context.Context) ([]Task, error)
+	GetUsageReport(ctx context.Context, arg GetUsageReportParams) ([]GetUsageReportRow, error)
+	HeartbeatNode(ctx context.Context, id string) (int64, error)
+	ListArtifactChecksums(ctx context.Context, prefix string) ([]ListArtifactChecksumsRow, error)
 	ListModels(ctx context.Context) ([]Model, error)
+	ListNodes(ctx context.Context, timeoutSec float64) ([]ListNodesRow, error)
+	ListSecrets(ctx context.Context) ([]ListSecretsRow, error)
+	ListTaskEvents(ctx context.Context, taskID pgtype.UUID) ([]TaskEvent, error)
+	ListTaskResultRefs(ctx context.Context) ([]ListTaskResultRefsRow, error)
 	MarkTaskCompleted(ctx context.Context, arg MarkTaskCompletedParams) (Task, error)
 	MarkTaskFailed(ctx context.Context, arg MarkTaskFailedParams) (Task, error)
-	MarkTaskInitializing(ctx context.Context, id pgtype.UUID) (Task, error)
-	MarkTaskQueued(ctx context.Context, id pgtype.UUID) (Task, error)
+	MarkTaskInitializing(ctx context.Context, arg MarkTaskInitializingParams) (Task, error)
+	MarkTaskQueued(ctx context.Context, arg MarkTaskQueuedParams) (Task, error)
 	MarkTaskRunning(ctx context.Context, arg MarkTaskRunningParams) (Task, error)
 	MarkTaskScheduled(ctx context.Context, arg MarkTaskScheduledParams) (Task, error)
-	MarkTaskStopped(ctx context.Context, id pgtype.UUID) (Task, error)
+	MarkTaskStopped(ctx context.Context, arg MarkTaskStoppedParams) (Task, error)
+	PromoteScheduledTasks(ctx context.Context, arg PromoteScheduledTasksParams) ([]Task, error)
+	ReclaimExpiredTasks(ctx context.Context, arg ReclaimExpiredTasksParams) ([]Task, error)
+	RegisterNode(ctx context.Context, arg RegisterNodeParams) error
+	RenewTaskLease(ctx context.Context, arg RenewTaskLeaseParams) (int64, error)
+	SaveTaskUsage(ctx context.Context, arg SaveTaskUsageParams) error
+	SetModelNetworkMode(ctx context.Context, arg SetModelNetworkModeParams) (int64, error)
+	SetModelSecurity(ctx context.Context, arg SetModelSecurityParams) (int64, error)
+	SetResultCorrupted(ctx context.Context, arg SetResultCorruptedParams) error
+	SetTaskPinned(ctx context.Context, arg SetTaskPinnedParams) (Task, error)
+	SetTaskResultMissing(ctx context.Context, arg SetTaskResultMissingParams) error
+	SetTaskUploadProgress(ctx context.Context, arg SetTaskUploadProgressParams) error
+	StopNode(ctx context.Context, id string) error
 	UpdateModel(ctx context.Context, arg UpdateModelParams) error
+	UpdateSecret(ctx context.Context, arg UpdateSecretParams) (int64, error)
+	UpsertArtifactChecksum(ctx context.Context, arg UpsertArtifactChecksumParams) error
 }
 
 var _ Querier = (*Queries)(nil)
@@ -11,8 +11,25 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countResultRefs = `-- name: CountResultRefs :one
+SELECT COUNT(*) FROM tasks
+WHERE id != $1 AND starts_with(result_path, $2::text)
+`
+
+type CountResultRefsParams struct {
+	ID     pgtype.UUID
+	Prefix string
+}
+
+func (q *Queries) CountResultRefs(ctx context.Context, arg CountResultRefsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countResultRefs, arg.ID, arg.Prefix)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createModel = `-- name: CreateModel :one
-INSERT INTO models (id, container_image) VALUES ($1, $2) RETURNING id, container_image, created_at, updated_at
+INSERT INTO models (id, container_image) VALUES ($1, $2) RETURNING id, container_image, created_at, updated_at, network_mode, security
 `
 
 type CreateModelParams struct {
@@ -28,19 +45,41 @@ func (q *Queries) CreateModel(ctx context.Context, arg CreateModelParams) (Model
 		&i.ContainerImage,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.NetworkMode,
+		&i.Security,
 	)
 	return i, err
 }
 
+const createSecret = `-- name: CreateSecret :execrows
+INSERT INTO secrets (name, key_id, value)
+VALUES ($1, $2, $3)
+ON CONFLICT (name) DO NOTHING
+`
+
+type CreateSecretParams struct {
+	Name  string
+	KeyID string
+	Value []byte
+}
+
+func (q *Queries) CreateSecret(ctx context.Context, arg CreateSecretParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createSecret, arg.Name, arg.KeyID, arg.Value)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const createTask = `-- name: CreateTask :one
 INSERT INTO tasks (
     id, model_id, input_filename, signature, status, scheduled_at,
      container_image, container_envs, container_cmd, error_log, mem_lim,
-      cpu_lim, gpu_enable, result_path, timeout_sec
+      cpu_lim, gpu_enable, result_path, timeout_sec, keep_for_sec, input_sha256, constraints, secret_envs, network_mode, disk_lim
 ) VALUES (
-    $1, $2, $3, $4, $15::task_status, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+    $1, $2, $3, $4, $21::task_status, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20
 )
-RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec
+RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec, pinned, keep_for_sec, result_missing, upload_total_bytes, upload_done_bytes, upload_failed, input_sha256, result_corrupted, node_id, lease_expires_at, constraints, version, exit_code, failure_reason, mem_peak_bytes, mem_avg_bytes, cpu_seconds, block_read_bytes, block_write_bytes, net_rx_bytes, net_tx_bytes, usage_samples, queue_seconds, run_seconds, usage_recorded_at, secret_envs, network_mode, disk_lim, attempts, retry_at
 `
 
 type CreateTaskParams struct {
@@ -58,6 +97,12 @@ type CreateTaskParams struct {
 	GpuEnable      pgtype.Bool
 	ResultPath     pgtype.Text
 	TimeoutSec     int32
+	KeepForSec     int32
+	InputSha256    string
+	Constraints    []byte
+	SecretEnvs     []byte
+	NetworkMode    NetworkMode
+	DiskLim        int32
 	Status         TaskStatus
 }
 
@@ -77,6 +122,12 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, e
 		arg.GpuEnable,
 		arg.ResultPath,
 		arg.TimeoutSec,
+		arg.KeepForSec,
+		arg.InputSha256,
+		arg.Constraints,
+		arg.SecretEnvs,
+		arg.NetworkMode,
+		arg.DiskLim,
 		arg.Status,
 	)
 	var i Task
@@ -101,10 +152,69 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, e
 		&i.CpuLim,
 		&i.GpuEnable,
 		&i.TimeoutSec,
+		&i.Pinned,
+		&i.KeepForSec,
+		&i.ResultMissing,
+		&i.UploadTotalBytes,
+		&i.UploadDoneBytes,
+		&i.UploadFailed,
+		&i.InputSha256,
+		&i.ResultCorrupted,
+		&i.NodeID,
+		&i.LeaseExpiresAt,
+		&i.Constraints,
+		&i.Version,
+		&i.ExitCode,
+		&i.FailureReason,
+		&i.MemPeakBytes,
+		&i.MemAvgBytes,
+		&i.CpuSeconds,
+		&i.BlockReadBytes,
+		&i.BlockWriteBytes,
+		&i.NetRxBytes,
+		&i.NetTxBytes,
+		&i.UsageSamples,
+		&i.QueueSeconds,
+		&i.RunSeconds,
+		&i.UsageRecordedAt,
+		&i.SecretEnvs,
+		&i.NetworkMode,
+		&i.DiskLim,
+		&i.Attempts,
+		&i.RetryAt,
 	)
 	return i, err
 }
 
+const createTaskEvent = `-- name: CreateTaskEvent :exec
+INSERT INTO task_events (task_id, from_status, to_status, actor, node_id, reason)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type CreateTaskEventParams struct {
+	TaskID     pgtype.UUID
+	FromStatus NullTaskStatus
+	ToStatus   TaskStatus
+	Actor      string
+	NodeID     pgtype.Text
+	Reason     string
+}
+
+func (q *Queries) CreateTaskEvent(ctx context.Context, arg CreateTaskEventParams) error {
+	_, err := q.db.Exec(ctx, createTaskEvent, arg.TaskID, arg.FromStatus, arg.ToStatus, arg.Actor, arg.NodeID, arg.Reason)
+	return err
+}
+
+const deleteArtifactChecksums = `-- name: DeleteArtifactChecksums :exec
+DELETE FROM artifact_checksums
+WHERE starts_with(object_key, $1::text)
+`
+
+func (q *Queries) DeleteArtifactChecksums(ctx context.Context, prefix string) error {
+	_, err := q.db.Exec(ctx, deleteArtifactChecksums, prefix)
+	return err
+}
+
 const deleteModel = `-- name: DeleteModel :exec
 DELETE FROM models WHERE id = $1
 `
@@ -114,6 +224,18 @@ func (q *Queries) DeleteModel(ctx context.Context, id string) error {
 	return err
 }
 
+const deleteSecret = `-- name: DeleteSecret :execrows
+DELETE FROM secrets WHERE name = $1
+`
+
+func (q *Queries) DeleteSecret(ctx context.Context, name string) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSecret, name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteTask = `-- name: DeleteTask :exec
 DELETE FROM tasks WHERE id = $1
 `
@@ -140,6 +262,8 @@ WHERE signature = $1
     AND status = 'completed'::task_status
     AND result_path IS NOT NULL
     AND result_path != ''
+    AND NOT result_missing
+    AND NOT result_corrupted
 ORDER BY created_at DESC
 LIMIT 1
 `
@@ -152,7 +276,7 @@ func (q *Queries) FindCachedTask(ctx context.Context, signature string) (pgtype.
 }
 
 const getActiveTasks = `-- name: GetActiveTasks :many
-SELECT id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec FROM tasks
+SELECT id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec, pinned, keep_for_sec, result_missing, upload_total_bytes, upload_done_bytes, upload_failed, input_sha256, result_corrupted, node_id, lease_expires_at, constraints, version, exit_code, failure_reason, mem_peak_bytes, mem_avg_bytes, cpu_seconds, block_read_bytes, block_write_bytes, net_rx_bytes, net_tx_bytes, usage_samples, queue_seconds, run_seconds, usage_recorded_at, secret_envs, network_mode, disk_lim, attempts, retry_at FROM tasks
 WHERE status = 'running' 
     OR status = 'scheduled' 
     OR status = 'queued' 
@@ -189,6 +313,113 @@ func (q *Queries) GetActiveTasks(ctx context.Context) ([]Task, error) {
 			&i.CpuLim,
 			&i.GpuEnable,
 			&i.TimeoutSec,
+			&i.Pinned,
+			&i.KeepForSec,
+			&i.ResultMissing,
+			&i.UploadTotalBytes,
+			&i.UploadDoneBytes,
+			&i.UploadFailed,
+			&i.InputSha256,
+			&i.ResultCorrupted,
+			&i.NodeID,
+			&i.LeaseExpiresAt,
+			&i.Constraints,
+			&i.Version,
+			&i.ExitCode,
+			&i.FailureReason,
+			&i.MemPeakBytes,
+			&i.MemAvgBytes,
+			&i.CpuSeconds,
+			&i.BlockReadBytes,
+			&i.BlockWriteBytes,
+			&i.NetRxBytes,
+			&i.NetTxBytes,
+			&i.UsageSamples,
+			&i.QueueSeconds,
+			&i.RunSeconds,
+			&i.UsageRecordedAt,
+			&i.SecretEnvs,
+			&i.NetworkMode,
+			&i.DiskLim,
+			&i.Attempts,
+			&i.RetryAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFinishedTasks = `-- name: GetFinishedTasks :many
+SELECT id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec, pinned, keep_for_sec, result_missing, upload_total_bytes, upload_done_bytes, upload_failed, input_sha256, result_corrupted, node_id, lease_expires_at, constraints, version, exit_code, failure_reason, mem_peak_bytes, mem_avg_bytes, cpu_seconds, block_read_bytes, block_write_bytes, net_rx_bytes, net_tx_bytes, usage_samples, queue_seconds, run_seconds, usage_recorded_at, secret_envs, network_mode, disk_lim, attempts, retry_at FROM tasks
+WHERE status IN ('completed', 'failed', 'stopped', 'skipped')
+ORDER BY finished_at ASC NULLS FIRST
+`
+
+func (q *Queries) GetFinishedTasks(ctx context.Context) ([]Task, error) {
+	rows, err := q.db.Query(ctx, getFinishedTasks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.ModelID,
+			&i.InputFilename,
+			&i.ResultPath,
+			&i.Signature,
+			&i.Status,
+			&i.ContainerID,
+			&i.ContainerImage,
+			&i.ContainerEnvs,
+			&i.ContainerCmd,
+			&i.ErrorLog,
+			&i.ScheduledAt,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.MemLim,
+			&i.CpuLim,
+			&i.GpuEnable,
+			&i.TimeoutSec,
+			&i.Pinned,
+			&i.KeepForSec,
+			&i.ResultMissing,
+			&i.UploadTotalBytes,
+			&i.UploadDoneBytes,
+			&i.UploadFailed,
+			&i.InputSha256,
+			&i.ResultCorrupted,
+			&i.NodeID,
+			&i.LeaseExpiresAt,
+			&i.Constraints,
+			&i.Version,
+			&i.ExitCode,
+			&i.FailureReason,
+			&i.MemPeakBytes,
+			&i.MemAvgBytes,
+			&i.CpuSeconds,
+			&i.BlockReadBytes,
+			&i.BlockWriteBytes,
+			&i.NetRxBytes,
+			&i.NetTxBytes,
+			&i.UsageSamples,
+			&i.QueueSeconds,
+			&i.RunSeconds,
+			&i.UsageRecordedAt,
+			&i.SecretEnvs,
+			&i.NetworkMode,
+			&i.DiskLim,
+			&i.Attempts,
+			&i.RetryAt,
 		); err != nil {
 			return nil, err
 		}
@@ -201,7 +432,7 @@ func (q *Queries) GetActiveTasks(ctx context.Context) ([]Task, error) {
 }
 
 const getModelByID = `-- name: GetModelByID :one
-SELECT id, container_image, created_at, updated_at FROM models WHERE id = $1 LIMIT 1
+SELECT id, container_image, created_at, updated_at, network_mode, security FROM models WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetModelByID(ctx context.Context, id string) (Model, error) {
@@ -212,27 +443,36 @@ func (q *Queries) GetModelByID(ctx context.Context, id string) (Model, error) {
 		&i.ContainerImage,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.NetworkMode,
+		&i.Security,
 	)
 	return i, err
 }
 
 const getNextQueuedTask = `-- name: GetNextQueuedTask :one
 UPDATE tasks
-SET status = 'running', updated_at = NOW()
+SET status = 'running', node_id = $1,
+    lease_expires_at = NOW() + make_interval(secs => $2::float8), updated_at = NOW(), version = version + 1
 WHERE id = (
     SELECT id
     FROM tasks
     WHERE status = 'queued'
     AND (scheduled_at IS NULL OR scheduled_at <= NOW())
+    AND (retry_at IS NULL OR retry_at <= NOW())
     ORDER BY scheduled_at ASC
 LIMIT 1
 FOR UPDATE SKIP LOCKED
 )
-RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec
+RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec, pinned, keep_for_sec, result_missing, upload_total_bytes, upload_done_bytes, upload_failed, input_sha256, result_corrupted, node_id, lease_expires_at, constraints, version, exit_code, failure_reason, mem_peak_bytes, mem_avg_bytes, cpu_seconds, block_read_bytes, block_write_bytes, net_rx_bytes, net_tx_bytes, usage_samples, queue_seconds, run_seconds, usage_recorded_at, secret_envs, network_mode, disk_lim, attempts, retry_at
 `
 
-func (q *Queries) GetNextQueuedTask(ctx context.Context) (Task, error) {
-	row := q.db.QueryRow(ctx, getNextQueuedTask)
+type GetNextQueuedTaskParams struct {
+	NodeID   pgtype.Text
+	LeaseSec float64
+}
+
+func (q *Queries) GetNextQueuedTask(ctx context.Context, arg GetNextQueuedTaskParams) (Task, error) {
+	row := q.db.QueryRow(ctx, getNextQueuedTask, arg.NodeID, arg.LeaseSec)
 	var i Task
 	err := row.Scan(
 		&i.ID,
@@ -255,12 +495,54 @@ func (q *Queries) GetNextQueuedTask(ctx context.Context) (Task, error) {
 		&i.CpuLim,
 		&i.GpuEnable,
 		&i.TimeoutSec,
+		&i.Pinned,
+		&i.KeepForSec,
+		&i.ResultMissing,
+		&i.UploadTotalBytes,
+		&i.UploadDoneBytes,
+		&i.UploadFailed,
+		&i.InputSha256,
+		&i.ResultCorrupted,
+		&i.NodeID,
+		&i.LeaseExpiresAt,
+		&i.Constraints,
+		&i.Version,
+		&i.ExitCode,
+		&i.FailureReason,
+		&i.MemPeakBytes,
+		&i.MemAvgBytes,
+		&i.CpuSeconds,
+		&i.BlockReadBytes,
+		&i.BlockWriteBytes,
+		&i.NetRxBytes,
+		&i.NetTxBytes,
+		&i.UsageSamples,
+		&i.QueueSeconds,
+		&i.RunSeconds,
+		&i.UsageRecordedAt,
+		&i.SecretEnvs,
+		&i.NetworkMode,
+		&i.DiskLim,
+		&i.Attempts,
+		&i.RetryAt,
 	)
 	return i, err
 }
 
+const getNextScheduledAt = `-- name: GetNextScheduledAt :one
+SELECT MIN(scheduled_at)::timestamptz FROM tasks
+WHERE status = 'scheduled'
+`
+
+func (q *Queries) GetNextScheduledAt(ctx context.Context) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, getNextScheduledAt)
+	var column_1 pgtype.Timestamptz
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
 const getRunningTasksContainers = `-- name: GetRunningTasksContainers :many
-SELECT id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec FROM tasks
+SELECT id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec, pinned, keep_for_sec, result_missing, upload_total_bytes, upload_done_bytes, upload_failed, input_sha256, result_corrupted, node_id, lease_expires_at, constraints, version, exit_code, failure_reason, mem_peak_bytes, mem_avg_bytes, cpu_seconds, block_read_bytes, block_write_bytes, net_rx_bytes, net_tx_bytes, usage_samples, queue_seconds, run_seconds, usage_recorded_at, secret_envs, network_mode, disk_lim, attempts, retry_at FROM tasks
 WHERE status = 'running' AND container_id IS NOT NULL
 `
 
@@ -294,6 +576,150 @@ func (q *Queries) GetRunningTasksContainers(ctx context.Context) ([]Task, error)
 			&i.CpuLim,
 			&i.GpuEnable,
 			&i.TimeoutSec,
+			&i.Pinned,
+			&i.KeepForSec,
+			&i.ResultMissing,
+			&i.UploadTotalBytes,
+			&i.UploadDoneBytes,
+			&i.UploadFailed,
+			&i.InputSha256,
+			&i.ResultCorrupted,
+			&i.NodeID,
+			&i.LeaseExpiresAt,
+			&i.Constraints,
+			&i.Version,
+			&i.ExitCode,
+			&i.FailureReason,
+			&i.MemPeakBytes,
+			&i.MemAvgBytes,
+			&i.CpuSeconds,
+			&i.BlockReadBytes,
+			&i.BlockWriteBytes,
+			&i.NetRxBytes,
+			&i.NetTxBytes,
+			&i.UsageSamples,
+			&i.QueueSeconds,
+			&i.RunSeconds,
+			&i.UsageRecordedAt,
+			&i.SecretEnvs,
+			&i.NetworkMode,
+			&i.DiskLim,
+			&i.Attempts,
+			&i.RetryAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSecrets = `-- name: GetSecrets :many
+SELECT name, key_id, value, created_at, updated_at FROM secrets
+WHERE name = ANY($1::text[])
+`
+
+func (q *Queries) GetSecrets(ctx context.Context, names []string) ([]Secret, error) {
+	rows, err := q.db.Query(ctx, getSecrets, names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Secret
+	for rows.Next() {
+		var i Secret
+		if err := rows.Scan(
+			&i.Name,
+			&i.KeyID,
+			&i.Value,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStaleTasks = `-- name: GetStaleTasks :many
+SELECT id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec, pinned, keep_for_sec, result_missing, upload_total_bytes, upload_done_bytes, upload_failed, input_sha256, result_corrupted, node_id, lease_expires_at, constraints, version, exit_code, failure_reason, mem_peak_bytes, mem_avg_bytes, cpu_seconds, block_read_bytes, block_write_bytes, net_rx_bytes, net_tx_bytes, usage_samples, queue_seconds, run_seconds, usage_recorded_at, secret_envs, network_mode, disk_lim, attempts, retry_at FROM tasks
+WHERE status = $1::task_status
+    AND updated_at < $2
+ORDER BY updated_at ASC
+`
+
+type GetStaleTasksParams struct {
+	Status        TaskStatus
+	UpdatedBefore pgtype.Timestamptz
+}
+
+func (q *Queries) GetStaleTasks(ctx context.Context, arg GetStaleTasksParams) ([]Task, error) {
+	rows, err := q.db.Query(ctx, getStaleTasks, arg.Status, arg.UpdatedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.ModelID,
+			&i.InputFilename,
+			&i.ResultPath,
+			&i.Signature,
+			&i.Status,
+			&i.ContainerID,
+			&i.ContainerImage,
+			&i.ContainerEnvs,
+			&i.ContainerCmd,
+			&i.ErrorLog,
+			&i.ScheduledAt,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.MemLim,
+			&i.CpuLim,
+			&i.GpuEnable,
+			&i.TimeoutSec,
+			&i.Pinned,
+			&i.KeepForSec,
+			&i.ResultMissing,
+			&i.UploadTotalBytes,
+			&i.UploadDoneBytes,
+			&i.UploadFailed,
+			&i.InputSha256,
+			&i.ResultCorrupted,
+			&i.NodeID,
+			&i.LeaseExpiresAt,
+			&i.Constraints,
+			&i.Version,
+			&i.ExitCode,
+			&i.FailureReason,
+			&i.MemPeakBytes,
+			&i.MemAvgBytes,
+			&i.CpuSeconds,
+			&i.BlockReadBytes,
+			&i.BlockWriteBytes,
+			&i.NetRxBytes,
+			&i.NetTxBytes,
+			&i.UsageSamples,
+			&i.QueueSeconds,
+			&i.RunSeconds,
+			&i.UsageRecordedAt,
+			&i.SecretEnvs,
+			&i.NetworkMode,
+			&i.DiskLim,
+			&i.Attempts,
+			&i.RetryAt,
 		); err != nil {
 			return nil, err
 		}
@@ -306,7 +732,7 @@ func (q *Queries) GetRunningTasksContainers(ctx context.Context) ([]Task, error)
 }
 
 const getTaskByID = `-- name: GetTaskByID :one
-SELECT id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec FROM tasks
+SELECT id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec, pinned, keep_for_sec, result_missing, upload_total_bytes, upload_done_bytes, upload_failed, input_sha256, result_corrupted, node_id, lease_expires_at, constraints, version, exit_code, failure_reason, mem_peak_bytes, mem_avg_bytes, cpu_seconds, block_read_bytes, block_write_bytes, net_rx_bytes, net_tx_bytes, usage_samples, queue_seconds, run_seconds, usage_recorded_at, secret_envs, network_mode, disk_lim, attempts, retry_at FROM tasks
 WHERE id = $1 LIMIT 1
 `
 
@@ -334,34 +760,67 @@ func (q *Queries) GetTaskByID(ctx context.Context, id pgtype.UUID) (Task, error)
 		&i.CpuLim,
 		&i.GpuEnable,
 		&i.TimeoutSec,
+		&i.Pinned,
+		&i.KeepForSec,
+		&i.ResultMissing,
+		&i.UploadTotalBytes,
+		&i.UploadDoneBytes,
+		&i.UploadFailed,
+		&i.InputSha256,
+		&i.ResultCorrupted,
+		&i.NodeID,
+		&i.LeaseExpiresAt,
+		&i.Constraints,
+		&i.Version,
+		&i.ExitCode,
+		&i.FailureReason,
+		&i.MemPeakBytes,
+		&i.MemAvgBytes,
+		&i.CpuSeconds,
+		&i.BlockReadBytes,
+		&i.BlockWriteBytes,
+		&i.NetRxBytes,
+		&i.NetTxBytes,
+		&i.UsageSamples,
+		&i.QueueSeconds,
+		&i.RunSeconds,
+		&i.UsageRecordedAt,
+		&i.SecretEnvs,
+		&i.NetworkMode,
+		&i.DiskLim,
+		&i.Attempts,
+		&i.RetryAt,
 	)
 	return i, err
 }
 
 const getTasksCount = `-- name: GetTasksCount :one
 SELECT COUNT(*) FROM tasks
+WHERE $1::failure_reason IS NULL OR failure_reason = $1
 `
 
-func (q *Queries) GetTasksCount(ctx context.Context) (int64, error) {
-	row := q.db.QueryRow(ctx, getTasksCount)
+func (q *Queries) GetTasksCount(ctx context.Context, failureReason NullFailureReason) (int64, error) {
+	row := q.db.QueryRow(ctx, getTasksCount, failureReason)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
 const getTasksPaginated = `-- name: GetTasksPaginated :many
-SELECT id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec FROM tasks
+SELECT id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec, pinned, keep_for_sec, result_missing, upload_total_bytes, upload_done_bytes, upload_failed, input_sha256, result_corrupted, node_id, lease_expires_at, constraints, version, exit_code, failure_reason, mem_peak_bytes, mem_avg_bytes, cpu_seconds, block_read_bytes, block_write_bytes, net_rx_bytes, net_tx_bytes, usage_samples, queue_seconds, run_seconds, usage_recorded_at, secret_envs, network_mode, disk_lim, attempts, retry_at FROM tasks
+WHERE $3::failure_reason IS NULL OR failure_reason = $3
 ORDER BY created_at DESC
 LIMIT $1 OFFSET $2
 `
 
 type GetTasksPaginatedParams struct {
-	Limit  int32
-	Offset int32
+	Limit         int32
+	Offset        int32
+	FailureReason NullFailureReason
 }
 
 func (q *Queries) GetTasksPaginated(ctx context.Context, arg GetTasksPaginatedParams) ([]Task, error) {
-	rows, err := q.db.Query(ctx, getTasksPaginated, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, getTasksPaginated, arg.Limit, arg.Offset, arg.FailureReason)
 	if err != nil {
 		return nil, err
 	}
@@ -390,6 +849,36 @@ func (q *Queries) GetTasksPaginated(ctx context.Context, arg GetTasksPaginatedPa
 			&i.CpuLim,
 			&i.GpuEnable,
 			&i.TimeoutSec,
+			&i.Pinned,
+			&i.KeepForSec,
+			&i.ResultMissing,
+			&i.UploadTotalBytes,
+			&i.UploadDoneBytes,
+			&i.UploadFailed,
+			&i.InputSha256,
+			&i.ResultCorrupted,
+			&i.NodeID,
+			&i.LeaseExpiresAt,
+			&i.Constraints,
+			&i.Version,
+			&i.ExitCode,
+			&i.FailureReason,
+			&i.MemPeakBytes,
+			&i.MemAvgBytes,
+			&i.CpuSeconds,
+			&i.BlockReadBytes,
+			&i.BlockWriteBytes,
+			&i.NetRxBytes,
+			&i.NetTxBytes,
+			&i.UsageSamples,
+			&i.QueueSeconds,
+			&i.RunSeconds,
+			&i.UsageRecordedAt,
+			&i.SecretEnvs,
+			&i.NetworkMode,
+			&i.DiskLim,
+			&i.Attempts,
+			&i.RetryAt,
 		); err != nil {
 			return nil, err
 		}
@@ -401,15 +890,13 @@ func (q *Queries) GetTasksPaginated(ctx context.Context, arg GetTasksPaginatedPa
 	return items, nil
 }
 
-const getUpcomingScheduledTasks = `-- name: GetUpcomingScheduledTasks :many
-SELECT id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec FROM tasks
-WHERE status = 'scheduled'
-AND scheduled_at <= $1
-ORDER BY scheduled_at ASC
+const getUploadFailedTasks = `-- name: GetUploadFailedTasks :many
+SELECT id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec, pinned, keep_for_sec, result_missing, upload_total_bytes, upload_done_bytes, upload_failed, input_sha256, result_corrupted, node_id, lease_expires_at, constraints, version, exit_code, failure_reason, mem_peak_bytes, mem_avg_bytes, cpu_seconds, block_read_bytes, block_write_bytes, net_rx_bytes, net_tx_bytes, usage_samples, queue_seconds, run_seconds, usage_recorded_at, secret_envs, network_mode, disk_lim, attempts, retry_at FROM tasks
+WHERE status = 'failed' AND upload_failed
 `
 
-func (q *Queries) GetUpcomingScheduledTasks(ctx context.Context, scheduledAt pgtype.Timestamptz) ([]Task, error) {
-	rows, err := q.db.Query(ctx, getUpcomingScheduledTasks, scheduledAt)
+func (q *Queries) GetUploadFailedTasks(ctx context.Context) ([]Task, error) {
+	rows, err := q.db.Query(ctx, getUploadFailedTasks)
 	if err != nil {
 		return nil, err
 	}
@@ -438,6 +925,158 @@ func (q *Queries) GetUpcomingScheduledTasks(ctx context.Context, scheduledAt pgt
 			&i.CpuLim,
 			&i.GpuEnable,
 			&i.TimeoutSec,
+			&i.Pinned,
+			&i.KeepForSec,
+			&i.ResultMissing,
+			&i.UploadTotalBytes,
+			&i.UploadDoneBytes,
+			&i.UploadFailed,
+			&i.InputSha256,
+			&i.ResultCorrupted,
+			&i.NodeID,
+			&i.LeaseExpiresAt,
+			&i.Constraints,
+			&i.Version,
+			&i.ExitCode,
+			&i.FailureReason,
+			&i.MemPeakBytes,
+			&i.MemAvgBytes,
+			&i.CpuSeconds,
+			&i.BlockReadBytes,
+			&i.BlockWriteBytes,
+			&i.NetRxBytes,
+			&i.NetTxBytes,
+			&i.UsageSamples,
+			&i.QueueSeconds,
+			&i.RunSeconds,
+			&i.UsageRecordedAt,
+			&i.SecretEnvs,
+			&i.NetworkMode,
+			&i.DiskLim,
+			&i.Attempts,
+			&i.RetryAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUsageReport = `-- name: GetUsageReport :many
+SELECT model_id,
+    date_trunc('day', usage_recorded_at, 'UTC')::timestamptz AS day,
+    COUNT(*) AS tasks,
+    MAX(mem_peak_bytes)::bigint AS mem_peak_bytes,
+    AVG(mem_avg_bytes)::bigint AS mem_avg_bytes,
+    SUM(cpu_seconds)::float8 AS cpu_seconds,
+    SUM(block_read_bytes)::bigint AS block_read_bytes,
+    SUM(block_write_bytes)::bigint AS block_write_bytes,
+    SUM(net_rx_bytes)::bigint AS net_rx_bytes,
+    SUM(net_tx_bytes)::bigint AS net_tx_bytes,
+    AVG(queue_seconds)::float8 AS avg_queue_seconds,
+    SUM(run_seconds)::float8 AS run_seconds
+FROM tasks
+WHERE usage_recorded_at >= $1 AND usage_recorded_at < $2
+GROUP BY model_id, day
+ORDER BY day, model_id
+`
+
+type GetUsageReportParams struct {
+	Since pgtype.Timestamptz
+	Until pgtype.Timestamptz
+}
+
+type GetUsageReportRow struct {
+	ModelID         string
+	Day             pgtype.Timestamptz
+	Tasks           int64
+	MemPeakBytes    int64
+	MemAvgBytes     int64
+	CpuSeconds      float64
+	BlockReadBytes  int64
+	BlockWriteBytes int64
+	NetRxBytes      int64
+	NetTxBytes      int64
+	AvgQueueSeconds float64
+	RunSeconds      float64
+}
+
+func (q *Queries) GetUsageReport(ctx context.Context, arg GetUsageReportParams) ([]GetUsageReportRow, error) {
+	rows, err := q.db.Query(ctx, getUsageReport, arg.Since, arg.Until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUsageReportRow
+	for rows.Next() {
+		var i GetUsageReportRow
+		if err := rows.Scan(
+			&i.ModelID,
+			&i.Day,
+			&i.Tasks,
+			&i.MemPeakBytes,
+			&i.MemAvgBytes,
+			&i.CpuSeconds,
+			&i.BlockReadBytes,
+			&i.BlockWriteBytes,
+			&i.NetRxBytes,
+			&i.NetTxBytes,
+			&i.AvgQueueSeconds,
+			&i.RunSeconds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const heartbeatNode = `-- name: HeartbeatNode :execrows
+UPDATE nodes
+SET heartbeat_at = NOW()
+WHERE id = $1 AND stopped_at IS NULL
+`
+
+func (q *Queries) HeartbeatNode(ctx context.Context, id string) (int64, error) {
+	result, err := q.db.Exec(ctx, heartbeatNode, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const listArtifactChecksums = `-- name: ListArtifactChecksums :many
+SELECT object_key, size, sha256 FROM artifact_checksums
+WHERE starts_with(object_key, $1::text)
+ORDER BY object_key
+`
+
+type ListArtifactChecksumsRow struct {
+	ObjectKey string
+	Size      int64
+	Sha256    string
+}
+
+func (q *Queries) ListArtifactChecksums(ctx context.Context, prefix string) ([]ListArtifactChecksumsRow, error) {
+	rows, err := q.db.Query(ctx, listArtifactChecksums, prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListArtifactChecksumsRow
+	for rows.Next() {
+		var i ListArtifactChecksumsRow
+		if err := rows.Scan(
+			&i.ObjectKey,
+			&i.Size,
+			&i.Sha256,
 		); err != nil {
 			return nil, err
 		}
@@ -450,7 +1089,7 @@ func (q *Queries) GetUpcomingScheduledTasks(ctx context.Context, scheduledAt pgt
 }
 
 const listModels = `-- name: ListModels :many
-SELECT id, container_image, created_at, updated_at FROM models ORDER BY id
+SELECT id, container_image, created_at, updated_at, network_mode, security FROM models ORDER BY id
 `
 
 func (q *Queries) ListModels(ctx context.Context) ([]Model, error) {
@@ -467,6 +1106,8 @@ func (q *Queries) ListModels(ctx context.Context) ([]Model, error) {
 			&i.ContainerImage,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.NetworkMode,
+			&i.Security,
 		); err != nil {
 			return nil, err
 		}
@@ -478,68 +1119,271 @@ func (q *Queries) ListModels(ctx context.Context) ([]Model, error) {
 	return items, nil
 }
 
-const markTaskCompleted = `-- name: MarkTaskCompleted :one
-UPDATE tasks
-SET 
-    status = 'completed',
-    result_path = $2,
-    finished_at = NOW(),
-    updated_at = NOW()
-WHERE id = $1
-RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec
+const listNodes = `-- name: ListNodes :many
+SELECT n.id, n.hostname, n.capacity, n.labels, n.started_at, n.heartbeat_at, n.stopped_at,
+    (SELECT COUNT(*) FROM tasks t WHERE t.node_id = n.id AND t.status = 'running') AS running_tasks,
+    (n.stopped_at IS NULL AND n.heartbeat_at > NOW() - make_interval(secs => $1::float8))::bool AS alive
+FROM nodes n
+ORDER BY n.id
 `
 
-type MarkTaskCompletedParams struct {
-	ID         pgtype.UUID
-	ResultPath pgtype.Text
+type ListNodesRow struct {
+	ID           string
+	Hostname     string
+	Capacity     int32
+	Labels       []byte
+	StartedAt    pgtype.Timestamptz
+	HeartbeatAt  pgtype.Timestamptz
+	StoppedAt    pgtype.Timestamptz
+	RunningTasks int64
+	Alive        bool
 }
 
-func (q *Queries) MarkTaskCompleted(ctx context.Context, arg MarkTaskCompletedParams) (Task, error) {
-	row := q.db.QueryRow(ctx, markTaskCompleted, arg.ID, arg.ResultPath)
-	var i Task
-	err := row.Scan(
-		&i.ID,
-		&i.ModelID,
-		&i.InputFilename,
-		&i.ResultPath,
-		&i.Signature,
-		&i.Status,
-		&i.ContainerID,
-		&i.ContainerImage,
-		&i.ContainerEnvs,
-		&i.ContainerCmd,
-		&i.ErrorLog,
-		&i.ScheduledAt,
-		&i.StartedAt,
-		&i.FinishedAt,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.MemLim,
-		&i.CpuLim,
-		&i.GpuEnable,
-		&i.TimeoutSec,
-	)
-	return i, err
+func (q *Queries) ListNodes(ctx context.Context, timeoutSec float64) ([]ListNodesRow, error) {
+	rows, err := q.db.Query(ctx, listNodes, timeoutSec)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListNodesRow
+	for rows.Next() {
+		var i ListNodesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Hostname,
+			&i.Capacity,
+			&i.Labels,
+			&i.StartedAt,
+			&i.HeartbeatAt,
+			&i.StoppedAt,
+			&i.RunningTasks,
+			&i.Alive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const markTaskFailed = `-- name: MarkTaskFailed :one
-UPDATE tasks
-SET 
+const listSecrets = `-- name: ListSecrets :many
+SELECT name, created_at, updated_at FROM secrets
+ORDER BY name
+`
+
+type ListSecretsRow struct {
+	Name      string
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+}
+
+func (q *Queries) ListSecrets(ctx context.Context) ([]ListSecretsRow, error) {
+	rows, err := q.db.Query(ctx, listSecrets)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSecretsRow
+	for rows.Next() {
+		var i ListSecretsRow
+		if err := rows.Scan(
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTaskEvents = `-- name: ListTaskEvents :many
+SELECT id, task_id, from_status, to_status, actor, node_id, reason, created_at FROM task_events
+WHERE task_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListTaskEvents(ctx context.Context, taskID pgtype.UUID) ([]TaskEvent, error) {
+	rows, err := q.db.Query(ctx, listTaskEvents, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TaskEvent
+	for rows.Next() {
+		var i TaskEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.TaskID,
+			&i.FromStatus,
+			&i.ToStatus,
+			&i.Actor,
+			&i.NodeID,
+			&i.Reason,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTaskResultRefs = `-- name: ListTaskResultRefs :many
+SELECT id, status, result_path, result_missing FROM tasks
+WHERE result_path IS NOT NULL AND result_path != ''
+`
+
+type ListTaskResultRefsRow struct {
+	ID            pgtype.UUID
+	Status        TaskStatus
+	ResultPath    pgtype.Text
+	ResultMissing bool
+}
+
+func (q *Queries) ListTaskResultRefs(ctx context.Context) ([]ListTaskResultRefsRow, error) {
+	rows, err := q.db.Query(ctx, listTaskResultRefs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTaskResultRefsRow
+	for rows.Next() {
+		var i ListTaskResultRefsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Status,
+			&i.ResultPath,
+			&i.ResultMissing,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markTaskCompleted = `-- name: MarkTaskCompleted :one
+UPDATE tasks
+SET 
+    status = 'completed',
+    result_path = $2,
+    upload_failed = FALSE,
+    exit_code = $3,
+    failure_reason = NULL,
+    finished_at = NOW(),
+    updated_at = NOW(),
+    version = version + 1
+WHERE id = $1 AND status = $4 AND version = $5
+RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec, pinned, keep_for_sec, result_missing, upload_total_bytes, upload_done_bytes, upload_failed, input_sha256, result_corrupted, node_id, lease_expires_at, constraints, version, exit_code, failure_reason, mem_peak_bytes, mem_avg_bytes, cpu_seconds, block_read_bytes, block_write_bytes, net_rx_bytes, net_tx_bytes, usage_samples, queue_seconds, run_seconds, usage_recorded_at, secret_envs, network_mode, disk_lim, attempts, retry_at
+`
+
+type MarkTaskCompletedParams struct {
+	ID         pgtype.UUID
+	ResultPath pgtype.Text
+	ExitCode   pgtype.Int4
+	FromStatus TaskStatus
+	Version    int32
+}
+
+func (q *Queries) MarkTaskCompleted(ctx context.Context, arg MarkTaskCompletedParams) (Task, error) {
+	row := q.db.QueryRow(ctx, markTaskCompleted, arg.ID, arg.ResultPath, arg.ExitCode, arg.FromStatus, arg.Version)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.ModelID,
+		&i.InputFilename,
+		&i.ResultPath,
+		&i.Signature,
+		&i.Status,
+		&i.ContainerID,
+		&i.ContainerImage,
+		&i.ContainerEnvs,
+		&i.ContainerCmd,
+		&i.ErrorLog,
+		&i.ScheduledAt,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.MemLim,
+		&i.CpuLim,
+		&i.GpuEnable,
+		&i.TimeoutSec,
+		&i.Pinned,
+		&i.KeepForSec,
+		&i.ResultMissing,
+		&i.UploadTotalBytes,
+		&i.UploadDoneBytes,
+		&i.UploadFailed,
+		&i.InputSha256,
+		&i.ResultCorrupted,
+		&i.NodeID,
+		&i.LeaseExpiresAt,
+		&i.Constraints,
+		&i.Version,
+		&i.ExitCode,
+		&i.FailureReason,
+		&i.MemPeakBytes,
+		&i.MemAvgBytes,
+		&i.CpuSeconds,
+		&i.BlockReadBytes,
+		&i.BlockWriteBytes,
+		&i.NetRxBytes,
+		&i.NetTxBytes,
+		&i.UsageSamples,
+		&i.QueueSeconds,
+		&i.RunSeconds,
+		&i.UsageRecordedAt,
+		&i.SecretEnvs,
+		&i.NetworkMode,
+		&i.DiskLim,
+		&i.Attempts,
+		&i.RetryAt,
+	)
+	return i, err
+}
+
+const markTaskFailed = `-- name: MarkTaskFailed :one
+UPDATE tasks
+SET 
     status = 'failed',
     error_log = $2,
+    upload_failed = $3,
+    exit_code = $4,
+    failure_reason = $5,
     finished_at = NOW(),
-    updated_at = NOW()
-WHERE id = $1 AND status != 'stopped'
-RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec
+    updated_at = NOW(),
+    version = version + 1
+WHERE id = $1 AND status = $6 AND version = $7
+RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec, pinned, keep_for_sec, result_missing, upload_total_bytes, upload_done_bytes, upload_failed, input_sha256, result_corrupted, node_id, lease_expires_at, constraints, version, exit_code, failure_reason, mem_peak_bytes, mem_avg_bytes, cpu_seconds, block_read_bytes, block_write_bytes, net_rx_bytes, net_tx_bytes, usage_samples, queue_seconds, run_seconds, usage_recorded_at, secret_envs, network_mode, disk_lim, attempts, retry_at
 `
 
 type MarkTaskFailedParams struct {
-	ID       pgtype.UUID
-	ErrorLog pgtype.Text
+	ID            pgtype.UUID
+	ErrorLog      pgtype.Text
+	UploadFailed  bool
+	ExitCode      pgtype.Int4
+	FailureReason NullFailureReason
+	FromStatus    TaskStatus
+	Version       int32
 }
 
 func (q *Queries) MarkTaskFailed(ctx context.Context, arg MarkTaskFailedParams) (Task, error) {
-	row := q.db.QueryRow(ctx, markTaskFailed, arg.ID, arg.ErrorLog)
+	row := q.db.QueryRow(ctx, markTaskFailed, arg.ID, arg.ErrorLog, arg.UploadFailed, arg.ExitCode, arg.FailureReason, arg.FromStatus, arg.Version)
 	var i Task
 	err := row.Scan(
 		&i.ID,
@@ -562,6 +1406,36 @@ func (q *Queries) MarkTaskFailed(ctx context.Context, arg MarkTaskFailedParams)
 		&i.CpuLim,
 		&i.GpuEnable,
 		&i.TimeoutSec,
+		&i.Pinned,
+		&i.KeepForSec,
+		&i.ResultMissing,
+		&i.UploadTotalBytes,
+		&i.UploadDoneBytes,
+		&i.UploadFailed,
+		&i.InputSha256,
+		&i.ResultCorrupted,
+		&i.NodeID,
+		&i.LeaseExpiresAt,
+		&i.Constraints,
+		&i.Version,
+		&i.ExitCode,
+		&i.FailureReason,
+		&i.MemPeakBytes,
+		&i.MemAvgBytes,
+		&i.CpuSeconds,
+		&i.BlockReadBytes,
+		&i.BlockWriteBytes,
+		&i.NetRxBytes,
+		&i.NetTxBytes,
+		&i.UsageSamples,
+		&i.QueueSeconds,
+		&i.RunSeconds,
+		&i.UsageRecordedAt,
+		&i.SecretEnvs,
+		&i.NetworkMode,
+		&i.DiskLim,
+		&i.Attempts,
+		&i.RetryAt,
 	)
 	return i, err
 }
@@ -570,13 +1444,20 @@ const markTaskInitializing = `-- name: MarkTaskInitializing :one
 UPDATE tasks
 SET 
     status = 'initializing',
-    updated_at = NOW()
-WHERE id = $1
-RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec
+    updated_at = NOW(),
+    version = version + 1
+WHERE id = $1 AND status = $2 AND version = $3
+RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec, pinned, keep_for_sec, result_missing, upload_total_bytes, upload_done_bytes, upload_failed, input_sha256, result_corrupted, node_id, lease_expires_at, constraints, version, exit_code, failure_reason, mem_peak_bytes, mem_avg_bytes, cpu_seconds, block_read_bytes, block_write_bytes, net_rx_bytes, net_tx_bytes, usage_samples, queue_seconds, run_seconds, usage_recorded_at, secret_envs, network_mode, disk_lim, attempts, retry_at
 `
 
-func (q *Queries) MarkTaskInitializing(ctx context.Context, id pgtype.UUID) (Task, error) {
-	row := q.db.QueryRow(ctx, markTaskInitializing, id)
+type MarkTaskInitializingParams struct {
+	ID         pgtype.UUID
+	FromStatus TaskStatus
+	Version    int32
+}
+
+func (q *Queries) MarkTaskInitializing(ctx context.Context, arg MarkTaskInitializingParams) (Task, error) {
+	row := q.db.QueryRow(ctx, markTaskInitializing, arg.ID, arg.FromStatus, arg.Version)
 	var i Task
 	err := row.Scan(
 		&i.ID,
@@ -599,6 +1480,36 @@ func (q *Queries) MarkTaskInitializing(ctx context.Context, id pgtype.UUID) (Tas
 		&i.CpuLim,
 		&i.GpuEnable,
 		&i.TimeoutSec,
+		&i.Pinned,
+		&i.KeepForSec,
+		&i.ResultMissing,
+		&i.UploadTotalBytes,
+		&i.UploadDoneBytes,
+		&i.UploadFailed,
+		&i.InputSha256,
+		&i.ResultCorrupted,
+		&i.NodeID,
+		&i.LeaseExpiresAt,
+		&i.Constraints,
+		&i.Version,
+		&i.ExitCode,
+		&i.FailureReason,
+		&i.MemPeakBytes,
+		&i.MemAvgBytes,
+		&i.CpuSeconds,
+		&i.BlockReadBytes,
+		&i.BlockWriteBytes,
+		&i.NetRxBytes,
+		&i.NetTxBytes,
+		&i.UsageSamples,
+		&i.QueueSeconds,
+		&i.RunSeconds,
+		&i.UsageRecordedAt,
+		&i.SecretEnvs,
+		&i.NetworkMode,
+		&i.DiskLim,
+		&i.Attempts,
+		&i.RetryAt,
 	)
 	return i, err
 }
@@ -607,13 +1518,30 @@ const markTaskQueued = `-- name: MarkTaskQueued :one
 UPDATE tasks
 SET 
     status = 'queued',
-    updated_at = NOW()
-WHERE id = $1
-RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec
+    attempts = $2,
+    retry_at = $3,
+    updated_at = NOW(),
+    version = version + 1
+WHERE id = $1 AND status = $4 AND version = $5
+RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec, pinned, keep_for_sec, result_missing, upload_total_bytes, upload_done_bytes, upload_failed, input_sha256, result_corrupted, node_id, lease_expires_at, constraints, version, exit_code, failure_reason, mem_peak_bytes, mem_avg_bytes, cpu_seconds, block_read_bytes, block_write_bytes, net_rx_bytes, net_tx_bytes, usage_samples, queue_seconds, run_seconds, usage_recorded_at, secret_envs, network_mode, disk_lim, attempts, retry_at
 `
 
-func (q *Queries) MarkTaskQueued(ctx context.Context, id pgtype.UUID) (Task, error) {
-	row := q.db.QueryRow(ctx, markTaskQueued, id)
+type MarkTaskQueuedParams struct {
+	ID         pgtype.UUID
+	Attempts   int32
+	RetryAt    pgtype.Timestamptz
+	FromStatus TaskStatus
+	Version    int32
+}
+
+func (q *Queries) MarkTaskQueued(ctx context.Context, arg MarkTaskQueuedParams) (Task, error) {
+	row := q.db.QueryRow(ctx, markTaskQueued,
+		arg.ID,
+		arg.Attempts,
+		arg.RetryAt,
+		arg.FromStatus,
+		arg.Version,
+	)
 	var i Task
 	err := row.Scan(
 		&i.ID,
@@ -636,6 +1564,36 @@ func (q *Queries) MarkTaskQueued(ctx context.Context, id pgtype.UUID) (Task, err
 		&i.CpuLim,
 		&i.GpuEnable,
 		&i.TimeoutSec,
+		&i.Pinned,
+		&i.KeepForSec,
+		&i.ResultMissing,
+		&i.UploadTotalBytes,
+		&i.UploadDoneBytes,
+		&i.UploadFailed,
+		&i.InputSha256,
+		&i.ResultCorrupted,
+		&i.NodeID,
+		&i.LeaseExpiresAt,
+		&i.Constraints,
+		&i.Version,
+		&i.ExitCode,
+		&i.FailureReason,
+		&i.MemPeakBytes,
+		&i.MemAvgBytes,
+		&i.CpuSeconds,
+		&i.BlockReadBytes,
+		&i.BlockWriteBytes,
+		&i.NetRxBytes,
+		&i.NetTxBytes,
+		&i.UsageSamples,
+		&i.QueueSeconds,
+		&i.RunSeconds,
+		&i.UsageRecordedAt,
+		&i.SecretEnvs,
+		&i.NetworkMode,
+		&i.DiskLim,
+		&i.Attempts,
+		&i.RetryAt,
 	)
 	return i, err
 }
@@ -646,18 +1604,21 @@ SET
     status = 'running',
     container_id = $2,
     started_at = NOW(),
-    updated_at = NOW()
-WHERE id = $1
-RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec
+    updated_at = NOW(),
+    version = version + 1
+WHERE id = $1 AND status = $3 AND version = $4
+RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec, pinned, keep_for_sec, result_missing, upload_total_bytes, upload_done_bytes, upload_failed, input_sha256, result_corrupted, node_id, lease_expires_at, constraints, version, exit_code, failure_reason, mem_peak_bytes, mem_avg_bytes, cpu_seconds, block_read_bytes, block_write_bytes, net_rx_bytes, net_tx_bytes, usage_samples, queue_seconds, run_seconds, usage_recorded_at, secret_envs, network_mode, disk_lim, attempts, retry_at
 `
 
 type MarkTaskRunningParams struct {
 	ID          pgtype.UUID
 	ContainerID pgtype.Text
+	FromStatus  TaskStatus
+	Version     int32
 }
 
 func (q *Queries) MarkTaskRunning(ctx context.Context, arg MarkTaskRunningParams) (Task, error) {
-	row := q.db.QueryRow(ctx, markTaskRunning, arg.ID, arg.ContainerID)
+	row := q.db.QueryRow(ctx, markTaskRunning, arg.ID, arg.ContainerID, arg.FromStatus, arg.Version)
 	var i Task
 	err := row.Scan(
 		&i.ID,
@@ -680,6 +1641,36 @@ func (q *Queries) MarkTaskRunning(ctx context.Context, arg MarkTaskRunningParams
 		&i.CpuLim,
 		&i.GpuEnable,
 		&i.TimeoutSec,
+		&i.Pinned,
+		&i.KeepForSec,
+		&i.ResultMissing,
+		&i.UploadTotalBytes,
+		&i.UploadDoneBytes,
+		&i.UploadFailed,
+		&i.InputSha256,
+		&i.ResultCorrupted,
+		&i.NodeID,
+		&i.LeaseExpiresAt,
+		&i.Constraints,
+		&i.Version,
+		&i.ExitCode,
+		&i.FailureReason,
+		&i.MemPeakBytes,
+		&i.MemAvgBytes,
+		&i.CpuSeconds,
+		&i.BlockReadBytes,
+		&i.BlockWriteBytes,
+		&i.NetRxBytes,
+		&i.NetTxBytes,
+		&i.UsageSamples,
+		&i.QueueSeconds,
+		&i.RunSeconds,
+		&i.UsageRecordedAt,
+		&i.SecretEnvs,
+		&i.NetworkMode,
+		&i.DiskLim,
+		&i.Attempts,
+		&i.RetryAt,
 	)
 	return i, err
 }
@@ -689,18 +1680,21 @@ UPDATE tasks
 SET 
     status = 'scheduled',
     updated_at = NOW(),
-    scheduled_at = $2
-WHERE id = $1
-RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec
+    scheduled_at = $2,
+    version = version + 1
+WHERE id = $1 AND status = $3 AND version = $4
+RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec, pinned, keep_for_sec, result_missing, upload_total_bytes, upload_done_bytes, upload_failed, input_sha256, result_corrupted, node_id, lease_expires_at, constraints, version, exit_code, failure_reason, mem_peak_bytes, mem_avg_bytes, cpu_seconds, block_read_bytes, block_write_bytes, net_rx_bytes, net_tx_bytes, usage_samples, queue_seconds, run_seconds, usage_recorded_at, secret_envs, network_mode, disk_lim, attempts, retry_at
 `
 
 type MarkTaskScheduledParams struct {
 	ID          pgtype.UUID
 	ScheduledAt pgtype.Timestamptz
+	FromStatus  TaskStatus
+	Version     int32
 }
 
 func (q *Queries) MarkTaskScheduled(ctx context.Context, arg MarkTaskScheduledParams) (Task, error) {
-	row := q.db.QueryRow(ctx, markTaskScheduled, arg.ID, arg.ScheduledAt)
+	row := q.db.QueryRow(ctx, markTaskScheduled, arg.ID, arg.ScheduledAt, arg.FromStatus, arg.Version)
 	var i Task
 	err := row.Scan(
 		&i.ID,
@@ -723,6 +1717,36 @@ func (q *Queries) MarkTaskScheduled(ctx context.Context, arg MarkTaskScheduledPa
 		&i.CpuLim,
 		&i.GpuEnable,
 		&i.TimeoutSec,
+		&i.Pinned,
+		&i.KeepForSec,
+		&i.ResultMissing,
+		&i.UploadTotalBytes,
+		&i.UploadDoneBytes,
+		&i.UploadFailed,
+		&i.InputSha256,
+		&i.ResultCorrupted,
+		&i.NodeID,
+		&i.LeaseExpiresAt,
+		&i.Constraints,
+		&i.Version,
+		&i.ExitCode,
+		&i.FailureReason,
+		&i.MemPeakBytes,
+		&i.MemAvgBytes,
+		&i.CpuSeconds,
+		&i.BlockReadBytes,
+		&i.BlockWriteBytes,
+		&i.NetRxBytes,
+		&i.NetTxBytes,
+		&i.UsageSamples,
+		&i.QueueSeconds,
+		&i.RunSeconds,
+		&i.UsageRecordedAt,
+		&i.SecretEnvs,
+		&i.NetworkMode,
+		&i.DiskLim,
+		&i.Attempts,
+		&i.RetryAt,
 	)
 	return i, err
 }
@@ -731,14 +1755,420 @@ const markTaskStopped = `-- name: MarkTaskStopped :one
 UPDATE tasks
 SET 
     status = 'stopped',
+    exit_code = $2,
+    failure_reason = $3,
     finished_at = NOW(),
+    updated_at = NOW(),
+    version = version + 1
+WHERE id = $1 AND status = $4 AND version = $5
+RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec, pinned, keep_for_sec, result_missing, upload_total_bytes, upload_done_bytes, upload_failed, input_sha256, result_corrupted, node_id, lease_expires_at, constraints, version, exit_code, failure_reason, mem_peak_bytes, mem_avg_bytes, cpu_seconds, block_read_bytes, block_write_bytes, net_rx_bytes, net_tx_bytes, usage_samples, queue_seconds, run_seconds, usage_recorded_at, secret_envs, network_mode, disk_lim, attempts, retry_at
+`
+
+type MarkTaskStoppedParams struct {
+	ID            pgtype.UUID
+	ExitCode      pgtype.Int4
+	FailureReason NullFailureReason
+	FromStatus    TaskStatus
+	Version       int32
+}
+
+func (q *Queries) MarkTaskStopped(ctx context.Context, arg MarkTaskStoppedParams) (Task, error) {
+	row := q.db.QueryRow(ctx, markTaskStopped, arg.ID, arg.ExitCode, arg.FailureReason, arg.FromStatus, arg.Version)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.ModelID,
+		&i.InputFilename,
+		&i.ResultPath,
+		&i.Signature,
+		&i.Status,
+		&i.ContainerID,
+		&i.ContainerImage,
+		&i.ContainerEnvs,
+		&i.ContainerCmd,
+		&i.ErrorLog,
+		&i.ScheduledAt,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.MemLim,
+		&i.CpuLim,
+		&i.GpuEnable,
+		&i.TimeoutSec,
+		&i.Pinned,
+		&i.KeepForSec,
+		&i.ResultMissing,
+		&i.UploadTotalBytes,
+		&i.UploadDoneBytes,
+		&i.UploadFailed,
+		&i.InputSha256,
+		&i.ResultCorrupted,
+		&i.NodeID,
+		&i.LeaseExpiresAt,
+		&i.Constraints,
+		&i.Version,
+		&i.ExitCode,
+		&i.FailureReason,
+		&i.MemPeakBytes,
+		&i.MemAvgBytes,
+		&i.CpuSeconds,
+		&i.BlockReadBytes,
+		&i.BlockWriteBytes,
+		&i.NetRxBytes,
+		&i.NetTxBytes,
+		&i.UsageSamples,
+		&i.QueueSeconds,
+		&i.RunSeconds,
+		&i.UsageRecordedAt,
+		&i.SecretEnvs,
+		&i.NetworkMode,
+		&i.DiskLim,
+		&i.Attempts,
+		&i.RetryAt,
+	)
+	return i, err
+}
+
+const promoteScheduledTasks = `-- name: PromoteScheduledTasks :many
+WITH due AS (
+    SELECT id,
+        $1::text <> 'run'
+            AND scheduled_at < NOW() - make_interval(secs => $2::float8) AS misfired
+    FROM tasks
+    WHERE status = 'scheduled' AND scheduled_at <= NOW()
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE tasks t
+SET
+    status = CASE
+        WHEN NOT due.misfired THEN 'queued'::task_status
+        WHEN $1::text = 'skip' THEN 'skipped'::task_status
+        ELSE 'failed'::task_status
+    END,
+    error_log = CASE
+        WHEN due.misfired THEN format('missed its schedule by %s', date_trunc('second', NOW() - t.scheduled_at))
+        ELSE t.error_log
+    END,
+    finished_at = CASE WHEN due.misfired THEN NOW() ELSE t.finished_at END,
+    updated_at = NOW(),
+    version = t.version + 1
+FROM due
+WHERE t.id = due.id
+RETURNING t.id, t.model_id, t.input_filename, t.result_path, t.signature, t.status, t.container_id, t.container_image, t.container_envs, t.container_cmd, t.error_log, t.scheduled_at, t.started_at, t.finished_at, t.created_at, t.updated_at, t.mem_lim, t.cpu_lim, t.gpu_enable, t.timeout_sec, t.pinned, t.keep_for_sec, t.result_missing, t.upload_total_bytes, t.upload_done_bytes, t.upload_failed, t.input_sha256, t.result_corrupted, t.node_id, t.lease_expires_at, t.constraints, t.version, t.exit_code, t.failure_reason, t.mem_peak_bytes, t.mem_avg_bytes, t.cpu_seconds, t.block_read_bytes, t.block_write_bytes, t.net_rx_bytes, t.net_tx_bytes, t.usage_samples, t.queue_seconds, t.run_seconds, t.usage_recorded_at, t.secret_envs, t.network_mode, t.disk_lim, t.attempts, t.retry_at
+`
+
+type PromoteScheduledTasksParams struct {
+	Policy              string
+	MisfireThresholdSec float64
+}
+
+func (q *Queries) PromoteScheduledTasks(ctx context.Context, arg PromoteScheduledTasksParams) ([]Task, error) {
+	rows, err := q.db.Query(ctx, promoteScheduledTasks, arg.Policy, arg.MisfireThresholdSec)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.ModelID,
+			&i.InputFilename,
+			&i.ResultPath,
+			&i.Signature,
+			&i.Status,
+			&i.ContainerID,
+			&i.ContainerImage,
+			&i.ContainerEnvs,
+			&i.ContainerCmd,
+			&i.ErrorLog,
+			&i.ScheduledAt,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.MemLim,
+			&i.CpuLim,
+			&i.GpuEnable,
+			&i.TimeoutSec,
+			&i.Pinned,
+			&i.KeepForSec,
+			&i.ResultMissing,
+			&i.UploadTotalBytes,
+			&i.UploadDoneBytes,
+			&i.UploadFailed,
+			&i.InputSha256,
+			&i.ResultCorrupted,
+			&i.NodeID,
+			&i.LeaseExpiresAt,
+			&i.Constraints,
+			&i.Version,
+			&i.ExitCode,
+			&i.FailureReason,
+			&i.MemPeakBytes,
+			&i.MemAvgBytes,
+			&i.CpuSeconds,
+			&i.BlockReadBytes,
+			&i.BlockWriteBytes,
+			&i.NetRxBytes,
+			&i.NetTxBytes,
+			&i.UsageSamples,
+			&i.QueueSeconds,
+			&i.RunSeconds,
+			&i.UsageRecordedAt,
+			&i.SecretEnvs,
+			&i.NetworkMode,
+			&i.DiskLim,
+			&i.Attempts,
+			&i.RetryAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reclaimExpiredTasks = `-- name: ReclaimExpiredTasks :many
+UPDATE tasks
+SET node_id = $1, lease_expires_at = NOW() + make_interval(secs => $2::float8), updated_at = NOW(), version = version + 1
+WHERE id IN (
+    SELECT id
+    FROM tasks
+    WHERE status = 'running' AND lease_expires_at < NOW()
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec, pinned, keep_for_sec, result_missing, upload_total_bytes, upload_done_bytes, upload_failed, input_sha256, result_corrupted, node_id, lease_expires_at, constraints, version, exit_code, failure_reason, mem_peak_bytes, mem_avg_bytes, cpu_seconds, block_read_bytes, block_write_bytes, net_rx_bytes, net_tx_bytes, usage_samples, queue_seconds, run_seconds, usage_recorded_at, secret_envs, network_mode, disk_lim, attempts, retry_at
+`
+
+type ReclaimExpiredTasksParams struct {
+	NodeID   pgtype.Text
+	LeaseSec float64
+}
+
+func (q *Queries) ReclaimExpiredTasks(ctx context.Context, arg ReclaimExpiredTasksParams) ([]Task, error) {
+	rows, err := q.db.Query(ctx, reclaimExpiredTasks, arg.NodeID, arg.LeaseSec)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.ModelID,
+			&i.InputFilename,
+			&i.ResultPath,
+			&i.Signature,
+			&i.Status,
+			&i.ContainerID,
+			&i.ContainerImage,
+			&i.ContainerEnvs,
+			&i.ContainerCmd,
+			&i.ErrorLog,
+			&i.ScheduledAt,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.MemLim,
+			&i.CpuLim,
+			&i.GpuEnable,
+			&i.TimeoutSec,
+			&i.Pinned,
+			&i.KeepForSec,
+			&i.ResultMissing,
+			&i.UploadTotalBytes,
+			&i.UploadDoneBytes,
+			&i.UploadFailed,
+			&i.InputSha256,
+			&i.ResultCorrupted,
+			&i.NodeID,
+			&i.LeaseExpiresAt,
+			&i.Constraints,
+			&i.Version,
+			&i.ExitCode,
+			&i.FailureReason,
+			&i.MemPeakBytes,
+			&i.MemAvgBytes,
+			&i.CpuSeconds,
+			&i.BlockReadBytes,
+			&i.BlockWriteBytes,
+			&i.NetRxBytes,
+			&i.NetTxBytes,
+			&i.UsageSamples,
+			&i.QueueSeconds,
+			&i.RunSeconds,
+			&i.UsageRecordedAt,
+			&i.SecretEnvs,
+			&i.NetworkMode,
+			&i.DiskLim,
+			&i.Attempts,
+			&i.RetryAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const registerNode = `-- name: RegisterNode :exec
+INSERT INTO nodes (id, hostname, capacity, labels)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (id) DO UPDATE
+SET hostname = EXCLUDED.hostname,
+    capacity = EXCLUDED.capacity,
+    labels = EXCLUDED.labels,
+    started_at = NOW(),
+    heartbeat_at = NOW(),
+    stopped_at = NULL
+`
+
+type RegisterNodeParams struct {
+	ID       string
+	Hostname string
+	Capacity int32
+	Labels   []byte
+}
+
+func (q *Queries) RegisterNode(ctx context.Context, arg RegisterNodeParams) error {
+	_, err := q.db.Exec(ctx, registerNode, arg.ID, arg.Hostname, arg.Capacity, arg.Labels)
+	return err
+}
+
+const renewTaskLease = `-- name: RenewTaskLease :execrows
+UPDATE tasks
+SET node_id = $2, lease_expires_at = NOW() + make_interval(secs => $3::float8)
+WHERE id = $1 AND (node_id = $2 OR node_id IS NULL)
+`
+
+type RenewTaskLeaseParams struct {
+	ID       pgtype.UUID
+	NodeID   pgtype.Text
+	LeaseSec float64
+}
+
+func (q *Queries) RenewTaskLease(ctx context.Context, arg RenewTaskLeaseParams) (int64, error) {
+	result, err := q.db.Exec(ctx, renewTaskLease, arg.ID, arg.NodeID, arg.LeaseSec)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const saveTaskUsage = `-- name: SaveTaskUsage :exec
+UPDATE tasks
+SET mem_peak_bytes = $2,
+    mem_avg_bytes = $3,
+    cpu_seconds = $4,
+    block_read_bytes = $5,
+    block_write_bytes = $6,
+    net_rx_bytes = $7,
+    net_tx_bytes = $8,
+    usage_samples = $9,
+    queue_seconds = COALESCE(EXTRACT(EPOCH FROM started_at - GREATEST(created_at, scheduled_at))::float8, 0),
+    run_seconds = COALESCE(EXTRACT(EPOCH FROM NOW() - started_at)::float8, 0),
+    usage_recorded_at = NOW()
+WHERE id = $1
+`
+
+type SaveTaskUsageParams struct {
+	ID              pgtype.UUID
+	MemPeakBytes    int64
+	MemAvgBytes     int64
+	CpuSeconds      float64
+	BlockReadBytes  int64
+	BlockWriteBytes int64
+	NetRxBytes      int64
+	NetTxBytes      int64
+	UsageSamples    int32
+}
+
+func (q *Queries) SaveTaskUsage(ctx context.Context, arg SaveTaskUsageParams) error {
+	_, err := q.db.Exec(ctx, saveTaskUsage, arg.ID, arg.MemPeakBytes, arg.MemAvgBytes, arg.CpuSeconds, arg.BlockReadBytes, arg.BlockWriteBytes, arg.NetRxBytes, arg.NetTxBytes, arg.UsageSamples)
+	return err
+}
+
+const setModelNetworkMode = `-- name: SetModelNetworkMode :execrows
+UPDATE models
+SET network_mode = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetModelNetworkModeParams struct {
+	ID          string
+	NetworkMode NullNetworkMode
+}
+
+func (q *Queries) SetModelNetworkMode(ctx context.Context, arg SetModelNetworkModeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setModelNetworkMode, arg.ID, arg.NetworkMode)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setModelSecurity = `-- name: SetModelSecurity :execrows
+UPDATE models
+SET security = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetModelSecurityParams struct {
+	ID       string
+	Security []byte
+}
+
+func (q *Queries) SetModelSecurity(ctx context.Context, arg SetModelSecurityParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setModelSecurity, arg.ID, arg.Security)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setResultCorrupted = `-- name: SetResultCorrupted :exec
+UPDATE tasks
+SET result_corrupted = $1, updated_at = NOW()
+WHERE starts_with(result_path, $2::text)
+`
+
+type SetResultCorruptedParams struct {
+	Corrupted bool
+	Prefix    string
+}
+
+func (q *Queries) SetResultCorrupted(ctx context.Context, arg SetResultCorruptedParams) error {
+	_, err := q.db.Exec(ctx, setResultCorrupted, arg.Corrupted, arg.Prefix)
+	return err
+}
+
+const setTaskPinned = `-- name: SetTaskPinned :one
+UPDATE tasks
+SET
+    pinned = $2,
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec
+RETURNING id, model_id, input_filename, result_path, signature, status, container_id, container_image, container_envs, container_cmd, error_log, scheduled_at, started_at, finished_at, created_at, updated_at, mem_lim, cpu_lim, gpu_enable, timeout_sec, pinned, keep_for_sec, result_missing, upload_total_bytes, upload_done_bytes, upload_failed, input_sha256, result_corrupted, node_id, lease_expires_at, constraints, version, exit_code, failure_reason, mem_peak_bytes, mem_avg_bytes, cpu_seconds, block_read_bytes, block_write_bytes, net_rx_bytes, net_tx_bytes, usage_samples, queue_seconds, run_seconds, usage_recorded_at, secret_envs, network_mode, disk_lim, attempts, retry_at
 `
 
-func (q *Queries) MarkTaskStopped(ctx context.Context, id pgtype.UUID) (Task, error) {
-	row := q.db.QueryRow(ctx, markTaskStopped, id)
+type SetTaskPinnedParams struct {
+	ID     pgtype.UUID
+	Pinned bool
+}
+
+func (q *Queries) SetTaskPinned(ctx context.Context, arg SetTaskPinnedParams) (Task, error) {
+	row := q.db.QueryRow(ctx, setTaskPinned, arg.ID, arg.Pinned)
 	var i Task
 	err := row.Scan(
 		&i.ID,
@@ -761,10 +2191,84 @@ func (q *Queries) MarkTaskStopped(ctx context.Context, id pgtype.UUID) (Task, er
 		&i.CpuLim,
 		&i.GpuEnable,
 		&i.TimeoutSec,
+		&i.Pinned,
+		&i.KeepForSec,
+		&i.ResultMissing,
+		&i.UploadTotalBytes,
+		&i.UploadDoneBytes,
+		&i.UploadFailed,
+		&i.InputSha256,
+		&i.ResultCorrupted,
+		&i.NodeID,
+		&i.LeaseExpiresAt,
+		&i.Constraints,
+		&i.Version,
+		&i.ExitCode,
+		&i.FailureReason,
+		&i.MemPeakBytes,
+		&i.MemAvgBytes,
+		&i.CpuSeconds,
+		&i.BlockReadBytes,
+		&i.BlockWriteBytes,
+		&i.NetRxBytes,
+		&i.NetTxBytes,
+		&i.UsageSamples,
+		&i.QueueSeconds,
+		&i.RunSeconds,
+		&i.UsageRecordedAt,
+		&i.SecretEnvs,
+		&i.NetworkMode,
+		&i.DiskLim,
+		&i.Attempts,
+		&i.RetryAt,
 	)
 	return i, err
 }
 
+const setTaskResultMissing = `-- name: SetTaskResultMissing :exec
+UPDATE tasks
+SET result_missing = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetTaskResultMissingParams struct {
+	ID            pgtype.UUID
+	ResultMissing bool
+}
+
+func (q *Queries) SetTaskResultMissing(ctx context.Context, arg SetTaskResultMissingParams) error {
+	_, err := q.db.Exec(ctx, setTaskResultMissing, arg.ID, arg.ResultMissing)
+	return err
+}
+
+const setTaskUploadProgress = `-- name: SetTaskUploadProgress :exec
+UPDATE tasks
+SET upload_total_bytes = $2, upload_done_bytes = $3
+WHERE id = $1
+`
+
+type SetTaskUploadProgressParams struct {
+	ID               pgtype.UUID
+	UploadTotalBytes int64
+	UploadDoneBytes  int64
+}
+
+func (q *Queries) SetTaskUploadProgress(ctx context.Context, arg SetTaskUploadProgressParams) error {
+	_, err := q.db.Exec(ctx, setTaskUploadProgress, arg.ID, arg.UploadTotalBytes, arg.UploadDoneBytes)
+	return err
+}
+
+const stopNode = `-- name: StopNode :exec
+UPDATE nodes
+SET stopped_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) StopNode(ctx context.Context, id string) error {
+	_, err := q.db.Exec(ctx, stopNode, id)
+	return err
+}
+
 const updateModel = `-- name: UpdateModel :exec
 UPDATE models
 SET
@@ -782,3 +2286,42 @@ func (q *Queries) UpdateModel(ctx context.Context, arg UpdateModelParams) error
 	_, err := q.db.Exec(ctx, updateModel, arg.ID, arg.ContainerImage)
 	return err
 }
+
+const updateSecret = `-- name: UpdateSecret :execrows
+UPDATE secrets
+SET key_id = $2, value = $3, updated_at = NOW()
+WHERE name = $1
+`
+
+type UpdateSecretParams struct {
+	Name  string
+	KeyID string
+	Value []byte
+}
+
+func (q *Queries) UpdateSecret(ctx context.Context, arg UpdateSecretParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateSecret, arg.Name, arg.KeyID, arg.Value)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const upsertArtifactChecksum = `-- name: UpsertArtifactChecksum :exec
+INSERT INTO artifact_checksums (object_key, task_id, size, sha256)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (object_key) DO UPDATE
+SET task_id = EXCLUDED.task_id, size = EXCLUDED.size, sha256 = EXCLUDED.sha256, created_at = NOW()
+`
+
+type UpsertArtifactChecksumParams struct {
+	ObjectKey string
+	TaskID    pgtype.UUID
+	Size      int64
+	Sha256    string
+}
+
+func (q *Queries) UpsertArtifactChecksum(ctx context.Context, arg UpsertArtifactChecksumParams) error {
+	_, err := q.db.Exec(ctx, upsertArtifactChecksum, arg.ObjectKey, arg.TaskID, arg.Size, arg.Sha256)
+	return err
+}
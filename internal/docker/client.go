@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
 	"pinn-connect-service/internal/domain"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,12 +21,16 @@ import (
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 )
 
+// Manager runs containers on a single Docker host.
 type Manager struct {
 	Client *client.Client
 	hasGPU bool
+	// name is the host of the pool the manager connects to.
+	name string
 }
 
 type buildLine struct {
@@ -35,29 +41,26 @@ type buildLine struct {
 	} `json:"errorDetail"`
 }
 
-// NewManager create and return new docker manager.
-func NewManager(ctx context.Context) (*Manager, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-
+// newManager connects to the host. GPU support is detected by the pool once
+// the host is reachable.
+func newManager(host HostConfig) (*Manager, error) {
+	cli, err := newClient(host)
 	if err != nil {
 		return nil, err
 	}
 
-	manager := &Manager{
+	return &Manager{
 		Client: cli,
 		hasGPU: false,
-	}
-
-	manager.setGPUSupport(ctx)
-
-	return manager, nil
+		name:   host.Name,
+	}, nil
 }
 
 // StartContainer starts container with given options.
 // It returns the container id and an error if the process fails.
 func (m *Manager) StartContainer(ctx context.Context, cfg *domain.ContainerConfig) (string, error) {
 	if err := m.pullImage(ctx, cfg.Image); err != nil {
-		return "", fmt.Errorf("pre-pulling image: %w", err)
+		return "", fmt.Errorf("pre-pulling image: %w: %w", domain.ErrImagePull, err)
 	}
 
 	config := &container.Config{
@@ -67,17 +70,32 @@ func (m *Manager) StartContainer(ctx context.Context, cfg *domain.ContainerConfi
 		Labels: map[string]string{
 			"pinn.managed": "true",
 			"pinn.task_id": cfg.TaskID.String(),
+			"pinn.node":    cfg.NodeID,
+			"pinn.host":    m.name,
+			// the pool sums the limits of running containers
+			labelCPU:    strconv.Itoa(cfg.CPULimit),
+			labelMemory: strconv.Itoa(cfg.MemoryLimit),
 		},
 	}
 
+	networkMode, err := m.containerNetwork(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+
 	hostConfig := &container.HostConfig{
-		Mounts: domainToDockerMounts(cfg),
+		NetworkMode: networkMode,
+		Mounts:      domainToDockerMounts(cfg),
 		Resources: container.Resources{
 			Memory:   int64(cfg.MemoryLimit) * 1024 * 1024,
 			NanoCPUs: int64(float64(cfg.CPULimit) * 1e7),
 		},
 	}
 
+	if err := applySecurity(config, hostConfig, cfg.Security); err != nil {
+		return "", err
+	}
+
 	if cfg.GPU {
 		if m.hasGPU {
 			hostConfig.DeviceRequests = []container.DeviceRequest{
@@ -215,30 +233,6 @@ func (m *Manager) RemoveImage(ctx context.Context, img string) error {
 	return nil
 }
 
-// WaitContainer blocks goroutine and waiting for container finished his job.
-// Returns status code and an error if occurs.
-func (m *Manager) WaitContainer(ctx context.Context, containerID string) (int64, error) {
-	statusCh, errorCh := m.Client.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
-
-	select {
-	case err := <-errorCh:
-		if err != nil {
-			return 0, fmt.Errorf("waiting container: %w", err)
-		}
-
-	case res := <-statusCh:
-		if res.Error != nil {
-			return 0, fmt.Errorf("waiting container: container exit error: %s", res.Error.Message)
-		}
-		return res.StatusCode, nil
-
-	case <-ctx.Done():
-		return 0, ctx.Err()
-	}
-
-	return 0, nil
-}
-
 // InspectContainer returns the container information.
 func (m *Manager) GetContainerState(ctx context.Context, containerID string) (*domain.ContainerState, error) {
 	result, err := m.Client.ContainerInspect(ctx, containerID)
@@ -259,6 +253,98 @@ func (m *Manager) GetContainerState(ctx context.Context, containerID string) (*d
 	}, nil
 }
 
+// ContainerStats returns a single sample of the container resource usage.
+func (m *Manager) ContainerStats(ctx context.Context, containerID string) (*domain.ContainerStats, error) {
+	resp, err := m.Client.ContainerStatsOneShot(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("getting container stats: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var stats container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("decoding container stats: %w", err)
+	}
+
+	return toContainerStats(&stats), nil
+}
+
+// StreamContainerStats calls fn with a sample of the container resource
+// usage about every second, until the container exits, ctx is done or fn
+// returns an error.
+func (m *Manager) StreamContainerStats(ctx context.Context, containerID string, fn func(*domain.ContainerStats) error) error {
+	resp, err := m.Client.ContainerStats(ctx, containerID, true)
+	if err != nil {
+		return fmt.Errorf("streaming container stats: %w", err)
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var stats container.StatsResponse
+		if err := dec.Decode(&stats); err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("decoding container stats: %w", err)
+		}
+		// an exited container reports empty stats
+		if stats.Read.IsZero() {
+			return nil
+		}
+
+		if err := fn(toContainerStats(&stats)); err != nil {
+			return err
+		}
+	}
+}
+
+// toContainerStats sums the per-device and per-interface counters. Memory
+// excludes the inactive page cache and the CPU percent is computed the same
+// way `docker stats` does.
+func toContainerStats(s *container.StatsResponse) *domain.ContainerStats {
+	out := &domain.ContainerStats{
+		ReadAt:           s.Read,
+		MemoryBytes:      s.MemoryStats.Usage,
+		MemoryLimitBytes: s.MemoryStats.Limit,
+		CPUNanos:         s.CPUStats.CPUUsage.TotalUsage,
+	}
+
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	cpus := float64(s.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		out.CPUPercent = cpuDelta / systemDelta * cpus * 100
+	}
+
+	// cgroup v1 reports total_inactive_file, v2 inactive_file
+	inactive, ok := s.MemoryStats.Stats["total_inactive_file"]
+	if !ok {
+		inactive = s.MemoryStats.Stats["inactive_file"]
+	}
+	if inactive < out.MemoryBytes {
+		out.MemoryBytes -= inactive
+	}
+
+	for _, e := range s.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			out.BlockReadBytes += e.Value
+		case "write":
+			out.BlockWriteBytes += e.Value
+		}
+	}
+	for _, n := range s.Networks {
+		out.NetRxBytes += n.RxBytes
+		out.NetTxBytes += n.TxBytes
+	}
+
+	return out
+}
+
 func (m *Manager) CheckStatus(ctx context.Context) error {
 	_, err := m.Client.Ping(ctx)
 	return err
@@ -429,6 +515,105 @@ func isGPUDriverError(err error) bool {
 	return strings.Contains(err.Error(), "could not select device driver")
 }
 
+// applySecurity hardens a task container with the security profile.
+func applySecurity(config *container.Config, hostConfig *container.HostConfig, p domain.SecurityProfile) error {
+	config.User = p.User
+	hostConfig.GroupAdd = p.GroupAdd
+
+	if p.DropCapabilities {
+		hostConfig.CapDrop = []string{"ALL"}
+	}
+	hostConfig.CapAdd = p.CapAdd
+
+	if p.NoNewPrivileges {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "no-new-privileges:true")
+	}
+	switch p.Seccomp {
+	case "":
+	case domain.SeccompUnconfined:
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "seccomp=unconfined")
+	default:
+		// the API takes the profile itself, not its path
+		profile, err := os.ReadFile(p.Seccomp)
+		if err != nil {
+			return fmt.Errorf("reading seccomp profile: %w", err)
+		}
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "seccomp="+string(profile))
+	}
+
+	if p.ReadOnlyRootfs {
+		hostConfig.ReadonlyRootfs = true
+		hostConfig.Tmpfs = map[string]string{"/tmp": fmt.Sprintf("rw,nosuid,nodev,size=%dm", p.TmpfsSizeMB)}
+	}
+
+	if p.PidsLimit > 0 {
+		hostConfig.PidsLimit = &p.PidsLimit
+	}
+	for _, u := range p.Ulimits {
+		hostConfig.Ulimits = append(hostConfig.Ulimits, &container.Ulimit{Name: u.Name, Soft: u.Soft, Hard: u.Hard})
+	}
+
+	return nil
+}
+
+// containerNetwork returns the Docker network of a task container. The
+// internal network is created on first use, the egress network is set up by
+// the operator and must exist.
+func (m *Manager) containerNetwork(ctx context.Context, cfg *domain.ContainerConfig) (container.NetworkMode, error) {
+	switch cfg.NetworkMode {
+	case "":
+		return "", nil
+	case domain.NetworkNone:
+		return network.NetworkNone, nil
+	case domain.NetworkBridge:
+		return network.NetworkBridge, nil
+	case domain.NetworkInternal:
+		if err := m.ensureInternalNetwork(ctx, cfg.Network); err != nil {
+			return "", err
+		}
+		return container.NetworkMode(cfg.Network), nil
+	case domain.NetworkEgress:
+		if _, err := m.Client.NetworkInspect(ctx, cfg.Network, network.InspectOptions{}); err != nil {
+			return "", fmt.Errorf("inspecting egress network %s: %w", cfg.Network, err)
+		}
+		return container.NetworkMode(cfg.Network), nil
+	default:
+		return "", fmt.Errorf("%w: %q", domain.ErrUnknownNetworkMode, cfg.NetworkMode)
+	}
+}
+
+// ensureInternalNetwork creates the internal network unless it exists. An
+// existing network with a route outside of the host is refused, it would give
+// the containers full network access.
+func (m *Manager) ensureInternalNetwork(ctx context.Context, name string) error {
+	info, err := m.Client.NetworkInspect(ctx, name, network.InspectOptions{})
+	if err == nil {
+		if !info.Internal {
+			return fmt.Errorf("network %s is not internal", name)
+		}
+		return nil
+	}
+	if !errdefs.IsNotFound(err) {
+		return fmt.Errorf("inspecting internal network %s: %w", name, err)
+	}
+
+	_, err = m.Client.NetworkCreate(ctx, name, network.CreateOptions{
+		Driver:   "bridge",
+		Internal: true,
+		Labels:   map[string]string{"pinn.managed": "true"},
+	})
+	if errdefs.IsConflict(err) {
+		// another worker of the host created it in the meantime
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("creating internal network %s: %w", name, err)
+	}
+
+	slog.Info("internal network created", "host", m.name, "network", name)
+	return nil
+}
+
 func domainToDockerMounts(cfg *domain.ContainerConfig) []mount.Mount {
 	var dockerMounts []mount.Mount
 	for _, mnt := range cfg.Mounts {
@@ -14,7 +14,10 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"pinn-connect-service/internal/domain"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -352,6 +355,135 @@ func TestGetContainerState_Error(t *testing.T) {
 	}
 }
 
+// ─────────────────────────────────────────────
+// ContainerStats
+// ─────────────────────────────────────────────
+
+func TestContainerStats_Success(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/ctr-1/stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("one-shot") != "1" || r.URL.Query().Get("stream") != "0" {
+			t.Errorf("expected a one-shot sample, got %q", r.URL.RawQuery)
+		}
+		jsonResp(w, http.StatusOK, map[string]any{
+			"read": "2024-01-01T00:00:00Z",
+			"cpu_stats": map[string]any{
+				"cpu_usage": map[string]any{"total_usage": 3_000_000_000},
+			},
+			"memory_stats": map[string]any{
+				"usage": 500,
+				"stats": map[string]any{"inactive_file": 100},
+			},
+			"blkio_stats": map[string]any{
+				"io_service_bytes_recursive": []map[string]any{
+					{"major": 8, "minor": 0, "op": "read", "value": 10},
+					{"major": 8, "minor": 16, "op": "Read", "value": 5},
+					{"major": 8, "minor": 0, "op": "write", "value": 7},
+					{"major": 8, "minor": 0, "op": "total", "value": 22},
+				},
+			},
+			"networks": map[string]any{
+				"eth0": map[string]any{"rx_bytes": 100, "tx_bytes": 200},
+				"eth1": map[string]any{"rx_bytes": 1, "tx_bytes": 2},
+			},
+		})
+	})
+	m := newTestManager(t, mux)
+
+	stats, err := m.ContainerStats(context.Background(), "ctr-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := domain.ContainerStats{
+		ReadAt:          time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		MemoryBytes:     400,
+		CPUNanos:        3_000_000_000,
+		BlockReadBytes:  15,
+		BlockWriteBytes: 7,
+		NetRxBytes:      101,
+		NetTxBytes:      202,
+	}
+	if !stats.ReadAt.Equal(want.ReadAt) {
+		t.Errorf("ReadAt = %v, want %v", stats.ReadAt, want.ReadAt)
+	}
+	stats.ReadAt = want.ReadAt
+	if *stats != want {
+		t.Errorf("stats = %+v, want %+v", *stats, want)
+	}
+}
+
+func TestContainerStats_Error(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/bad/stats", func(w http.ResponseWriter, _ *http.Request) {
+		errResp(w, http.StatusNotFound, "No such container")
+	})
+	m := newTestManager(t, mux)
+	if _, err := m.ContainerStats(context.Background(), "bad"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestStreamContainerStats(t *testing.T) {
+	frame := func(read string, total, preTotal, system, preSystem uint64) map[string]any {
+		return map[string]any{
+			"read":         read,
+			"cpu_stats":    map[string]any{"cpu_usage": map[string]any{"total_usage": total}, "system_cpu_usage": system, "online_cpus": 4},
+			"precpu_stats": map[string]any{"cpu_usage": map[string]any{"total_usage": preTotal}, "system_cpu_usage": preSystem},
+			"memory_stats": map[string]any{"usage": 300, "limit": 1000},
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/ctr-1/stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("stream") != "1" {
+			t.Errorf("expected a stream, got %q", r.URL.RawQuery)
+		}
+		enc := json.NewEncoder(w)
+		enc.Encode(frame("2024-01-01T00:00:01Z", 100, 0, 1000, 0))
+		enc.Encode(frame("2024-01-01T00:00:02Z", 300, 100, 2000, 1000))
+		// the container exited
+		enc.Encode(frame("0001-01-01T00:00:00Z", 0, 0, 0, 0))
+		enc.Encode(frame("2024-01-01T00:00:03Z", 300, 100, 2000, 1000))
+	})
+	m := newTestManager(t, mux)
+
+	var got []domain.ContainerStats
+	err := m.StreamContainerStats(context.Background(), "ctr-1", func(s *domain.ContainerStats) error {
+		got = append(got, *s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 samples before the exit, got %d", len(got))
+	}
+	// 200 of 1000 system nanoseconds on 4 cpus
+	if got[1].CPUPercent != 80 || got[1].MemoryBytes != 300 || got[1].MemoryLimitBytes != 1000 {
+		t.Errorf("unexpected sample %+v", got[1])
+	}
+}
+
+func TestStreamContainerStats_StopsOnCallbackError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/ctr-1/stats", func(w http.ResponseWriter, _ *http.Request) {
+		enc := json.NewEncoder(w)
+		for range 3 {
+			enc.Encode(map[string]any{"read": "2024-01-01T00:00:01Z"})
+		}
+	})
+	m := newTestManager(t, mux)
+
+	calls := 0
+	stop := errors.New("client gone")
+	err := m.StreamContainerStats(context.Background(), "ctr-1", func(*domain.ContainerStats) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("expected to stop after the first sample, got %d calls, err %v", calls, err)
+	}
+}
+
 // ─────────────────────────────────────────────
 // GetContainerLogs
 // ─────────────────────────────────────────────
@@ -666,79 +798,6 @@ func TestSetGPUSupport_ErrorLogsAndSetsToFalse(t *testing.T) {
 	}
 }
 
-// ─────────────────────────────────────────────
-// WaitContainer
-// ─────────────────────────────────────────────
-
-func TestWaitContainer_Success_ExitCode0(t *testing.T) {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/containers/ctr-1/wait", func(w http.ResponseWriter, _ *http.Request) {
-		jsonResp(w, http.StatusOK, map[string]any{"StatusCode": 0})
-	})
-	m := newTestManager(t, mux)
-	code, err := m.WaitContainer(context.Background(), "ctr-1")
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if code != 0 {
-		t.Errorf("expected exit code 0, got %d", code)
-	}
-}
-
-func TestWaitContainer_Success_NonZeroExitCode(t *testing.T) {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/containers/ctr-1/wait", func(w http.ResponseWriter, _ *http.Request) {
-		jsonResp(w, http.StatusOK, map[string]any{"StatusCode": 1})
-	})
-	m := newTestManager(t, mux)
-	code, err := m.WaitContainer(context.Background(), "ctr-1")
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if code != 1 {
-		t.Errorf("expected exit code 1, got %d", code)
-	}
-}
-
-func TestWaitContainer_ContainerExitError(t *testing.T) {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/containers/ctr-err/wait", func(w http.ResponseWriter, _ *http.Request) {
-		jsonResp(w, http.StatusOK, map[string]any{
-			"StatusCode": 0,
-			"Error":      map[string]string{"Message": "container crashed"},
-		})
-	})
-	m := newTestManager(t, mux)
-	if _, err := m.WaitContainer(context.Background(), "ctr-err"); err == nil {
-		t.Fatal("expected error from container exit error, got nil")
-	}
-}
-
-func TestWaitContainer_APIError(t *testing.T) {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/containers/bad/wait", func(w http.ResponseWriter, _ *http.Request) {
-		errResp(w, http.StatusNotFound, "No such container")
-	})
-	m := newTestManager(t, mux)
-	if _, err := m.WaitContainer(context.Background(), "bad"); err == nil {
-		t.Fatal("expected error from API 404, got nil")
-	}
-}
-
-func TestWaitContainer_ContextCanceled(t *testing.T) {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/containers/ctr-1/wait", func(w http.ResponseWriter, r *http.Request) {
-		// stall until client disconnects
-		<-r.Context().Done()
-	})
-	m := newTestManager(t, mux)
-	ctx, cancel := context.WithCancel(context.Background())
-	cancel()
-	if _, err := m.WaitContainer(ctx, "ctr-1"); err == nil {
-		t.Fatal("expected error from cancelled context, got nil")
-	}
-}
-
 // ─────────────────────────────────────────────
 // pullImage
 // ─────────────────────────────────────────────
@@ -909,8 +968,8 @@ func TestStartContainer_PullImageFails(t *testing.T) {
 		errResp(w, http.StatusInternalServerError, "daemon error")
 	})
 	m := newTestManager(t, mux)
-	if _, err := m.StartContainer(context.Background(), makeContainerConfig()); err == nil {
-		t.Fatal("expected error from pullImage, got nil")
+	if _, err := m.StartContainer(context.Background(), makeContainerConfig()); !errors.Is(err, domain.ErrImagePull) {
+		t.Fatalf("expected ErrImagePull, got %v", err)
 	}
 }
 
@@ -972,6 +1031,210 @@ func TestStartContainer_GPU_NotAvailable_SkipsDeviceRequest(t *testing.T) {
 	}
 }
 
+// networkMux serves the image, the container and the network endpoints and
+// records the network mode of the created container.
+func networkMux(networkMode *string) *http.ServeMux {
+	mux := imageExistsMux(http.NewServeMux())
+	mux.HandleFunc("/containers/create", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			HostConfig struct{ NetworkMode string }
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		*networkMode = body.HostConfig.NetworkMode
+		jsonResp(w, http.StatusCreated, map[string]any{"Id": "ctr-1", "Warnings": []string{}})
+	})
+	mux.HandleFunc("/containers/ctr-1/start", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+func TestStartContainer_NetworkNone(t *testing.T) {
+	var networkMode string
+	m := newTestManager(t, networkMux(&networkMode))
+
+	cfg := makeContainerConfig()
+	cfg.NetworkMode = domain.NetworkNone
+	if _, err := m.StartContainer(context.Background(), cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if networkMode != "none" {
+		t.Errorf("expected network mode none, got %q", networkMode)
+	}
+}
+
+func TestStartContainer_InternalNetwork_CreatedOnFirstUse(t *testing.T) {
+	var networkMode string
+	var created struct {
+		Name     string
+		Internal bool
+	}
+	mux := networkMux(&networkMode)
+	mux.HandleFunc("/networks/pinn-internal", func(w http.ResponseWriter, _ *http.Request) {
+		errResp(w, http.StatusNotFound, "network pinn-internal not found")
+	})
+	mux.HandleFunc("/networks/create", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&created)
+		jsonResp(w, http.StatusCreated, map[string]any{"Id": "net-1"})
+	})
+	m := newTestManager(t, mux)
+
+	cfg := makeContainerConfig()
+	cfg.NetworkMode = domain.NetworkInternal
+	cfg.Network = "pinn-internal"
+	if _, err := m.StartContainer(context.Background(), cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.Name != "pinn-internal" || !created.Internal {
+		t.Errorf("expected an internal network pinn-internal to be created, got %+v", created)
+	}
+	if networkMode != "pinn-internal" {
+		t.Errorf("expected network mode pinn-internal, got %q", networkMode)
+	}
+}
+
+// An existing network with a route outside of the host must not be used for
+// the internal mode.
+func TestStartContainer_InternalNetwork_NotInternal(t *testing.T) {
+	var networkMode string
+	mux := networkMux(&networkMode)
+	mux.HandleFunc("/networks/pinn-internal", func(w http.ResponseWriter, _ *http.Request) {
+		jsonResp(w, http.StatusOK, map[string]any{"Name": "pinn-internal", "Internal": false})
+	})
+	m := newTestManager(t, mux)
+
+	cfg := makeContainerConfig()
+	cfg.NetworkMode = domain.NetworkInternal
+	cfg.Network = "pinn-internal"
+	if _, err := m.StartContainer(context.Background(), cfg); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if networkMode != "" {
+		t.Error("container must not be created")
+	}
+}
+
+func TestStartContainer_EgressNetwork_Missing(t *testing.T) {
+	var networkMode string
+	mux := networkMux(&networkMode)
+	mux.HandleFunc("/networks/egress", func(w http.ResponseWriter, _ *http.Request) {
+		errResp(w, http.StatusNotFound, "network egress not found")
+	})
+	m := newTestManager(t, mux)
+
+	cfg := makeContainerConfig()
+	cfg.NetworkMode = domain.NetworkEgress
+	cfg.Network = "egress"
+	if _, err := m.StartContainer(context.Background(), cfg); err == nil {
+		t.Fatal("expected error for a missing egress network, got nil")
+	}
+}
+
+// securityMux records the container create request.
+func securityMux(body *containerCreateBody) *http.ServeMux {
+	mux := imageExistsMux(http.NewServeMux())
+	mux.HandleFunc("/containers/create", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(body)
+		jsonResp(w, http.StatusCreated, map[string]any{"Id": "ctr-1", "Warnings": []string{}})
+	})
+	mux.HandleFunc("/containers/ctr-1/start", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+type containerCreateBody struct {
+	User       string
+	HostConfig struct {
+		GroupAdd       []string
+		CapAdd         []string
+		CapDrop        []string
+		SecurityOpt    []string
+		ReadonlyRootfs bool
+		Tmpfs          map[string]string
+		PidsLimit      *int64
+		Ulimits        []struct {
+			Name string
+			Soft int64
+			Hard int64
+		}
+	}
+}
+
+func TestStartContainer_SecurityProfile(t *testing.T) {
+	var body containerCreateBody
+	m := newTestManager(t, securityMux(&body))
+
+	seccomp := filepath.Join(t.TempDir(), "seccomp.json")
+	os.WriteFile(seccomp, []byte(`{"defaultAction":"SCMP_ACT_ERRNO"}`), 0o600)
+
+	cfg := makeContainerConfig()
+	cfg.Security = domain.SecurityProfile{
+		User:             "65534:65534",
+		DropCapabilities: true,
+		CapAdd:           []string{"NET_BIND_SERVICE"},
+		NoNewPrivileges:  true,
+		ReadOnlyRootfs:   true,
+		TmpfsSizeMB:      64,
+		PidsLimit:        256,
+		Ulimits:          []domain.Ulimit{{Name: "nofile", Soft: 1024, Hard: 4096}},
+		Seccomp:          seccomp,
+		GroupAdd:         []string{"65534"},
+	}
+	if _, err := m.StartContainer(context.Background(), cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hc := body.HostConfig
+	if body.User != "65534:65534" {
+		t.Errorf("expected user 65534:65534, got %q", body.User)
+	}
+	if !slices.Equal(hc.GroupAdd, []string{"65534"}) {
+		t.Errorf("expected the workspace group 65534, got %v", hc.GroupAdd)
+	}
+	if !slices.Equal(hc.CapDrop, []string{"ALL"}) || !slices.Equal(hc.CapAdd, []string{"CAP_NET_BIND_SERVICE"}) {
+		t.Errorf("expected all capabilities but NET_BIND_SERVICE dropped, got drop %v add %v", hc.CapDrop, hc.CapAdd)
+	}
+	if !slices.Contains(hc.SecurityOpt, "no-new-privileges:true") ||
+		!slices.Contains(hc.SecurityOpt, `seccomp={"defaultAction":"SCMP_ACT_ERRNO"}`) {
+		t.Errorf("expected no-new-privileges and the seccomp profile, got %v", hc.SecurityOpt)
+	}
+	if !hc.ReadonlyRootfs || hc.Tmpfs["/tmp"] != "rw,nosuid,nodev,size=64m" {
+		t.Errorf("expected a read-only rootfs with a tmpfs /tmp, got %v %v", hc.ReadonlyRootfs, hc.Tmpfs)
+	}
+	if hc.PidsLimit == nil || *hc.PidsLimit != 256 {
+		t.Errorf("expected pids limit 256, got %v", hc.PidsLimit)
+	}
+	if len(hc.Ulimits) != 1 || hc.Ulimits[0].Name != "nofile" || hc.Ulimits[0].Soft != 1024 || hc.Ulimits[0].Hard != 4096 {
+		t.Errorf("expected the nofile ulimit, got %+v", hc.Ulimits)
+	}
+}
+
+func TestStartContainer_NoSecurityProfile(t *testing.T) {
+	var body containerCreateBody
+	m := newTestManager(t, securityMux(&body))
+
+	if _, err := m.StartContainer(context.Background(), makeContainerConfig()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hc := body.HostConfig
+	if body.User != "" || len(hc.CapDrop) != 0 || len(hc.SecurityOpt) != 0 || hc.ReadonlyRootfs || hc.PidsLimit != nil {
+		t.Errorf("expected the Docker defaults, got %+v", body)
+	}
+}
+
+func TestStartContainer_SeccompProfileMissing(t *testing.T) {
+	var body containerCreateBody
+	m := newTestManager(t, securityMux(&body))
+
+	cfg := makeContainerConfig()
+	cfg.Security.Seccomp = filepath.Join(t.TempDir(), "missing.json")
+	if _, err := m.StartContainer(context.Background(), cfg); err == nil {
+		t.Fatal("expected an error for a missing seccomp profile")
+	}
+}
+
 func TestStartContainer_GPUFallback_Success(t *testing.T) {
 	// GPU enabled + GPU driver error on first start → remove + retry without GPU → success.
 	var mu sync.Mutex
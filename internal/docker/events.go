@@ -0,0 +1,243 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"pinn-connect-service/internal/domain"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// exitWaiter is a task waiting for its container to exit.
+type exitWaiter struct {
+	host *poolHost
+	done chan struct{}
+	exit domain.ContainerExit
+	// inspect is set when the die event had no readable exit code
+	inspect bool
+}
+
+// WaitContainer waits for the die event of the container. Exits the watcher
+// may have missed are caught by inspecting the container once the waiter is
+// registered.
+func (p *Pool) WaitContainer(ctx context.Context, containerID string) (*domain.ContainerExit, error) {
+	h, err := p.hostOf(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	w := p.addWaiter(containerID, h)
+	defer p.removeWaiter(containerID, w)
+
+	exit, err := h.manager.containerExit(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("waiting container: %w", err)
+	}
+	if exit != nil {
+		return exit, nil
+	}
+
+	select {
+	case <-w.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if w.inspect {
+		exit, err := h.manager.containerExit(ctx, containerID)
+		if err != nil || exit == nil {
+			// an exit that can't be told apart from a success counts as a failure
+			slog.Warn("inspecting container without exit code", "container", containerID, "error", err)
+			return &w.exit, nil
+		}
+		exit.OOMKilled = exit.OOMKilled || w.exit.OOMKilled
+		exit.Signal = w.exit.Signal
+		return exit, nil
+	}
+
+	return &w.exit, nil
+}
+
+func (p *Pool) addWaiter(containerID string, h *poolHost) *exitWaiter {
+	w := &exitWaiter{host: h, done: make(chan struct{})}
+
+	p.mu.Lock()
+	p.waiters[containerID] = append(p.waiters[containerID], w)
+	p.mu.Unlock()
+
+	return w
+}
+
+func (p *Pool) removeWaiter(containerID string, w *exitWaiter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	waiters := p.waiters[containerID]
+	for i, other := range waiters {
+		if other == w {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(p.waiters, containerID)
+	} else {
+		p.waiters[containerID] = waiters
+	}
+}
+
+// watch follows the container events of the host until ctx is done. A broken
+// stream is reopened after the configured delay and replays the events
+// since the last one received.
+func (p *Pool) watch(ctx context.Context, h *poolHost) {
+	since := time.Now()
+
+	for {
+		last, err := p.followEvents(ctx, h, since)
+		if !last.IsZero() {
+			since = last
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("docker events stream broken, reconnecting", "host", h.config.Name, "error", err,
+			"delay", p.config.EventsReconnectDelay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.config.EventsReconnectDelay):
+		}
+	}
+}
+
+// followEvents dispatches the die, oom and kill events of task containers
+// until the stream breaks. It returns the time of the last event.
+func (p *Pool) followEvents(ctx context.Context, h *poolHost, since time.Time) (time.Time, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	args := filters.NewArgs()
+	args.Add("type", string(events.ContainerEventType))
+	args.Add("label", "pinn.managed=true")
+	args.Add("event", string(events.ActionDie))
+	args.Add("event", string(events.ActionOOM))
+	args.Add("event", string(events.ActionKill))
+
+	messages, errs := h.manager.Client.Events(ctx, events.ListOptions{
+		Since:   fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond()),
+		Filters: args,
+	})
+
+	// the daemon forgets its events when it restarts
+	p.resyncWaiters(ctx, h)
+
+	var last time.Time
+	for {
+		select {
+		case msg := <-messages:
+			if msg.TimeNano != 0 {
+				last = time.Unix(0, msg.TimeNano)
+			}
+			p.dispatch(msg)
+		case err := <-errs:
+			if err == nil {
+				err = errors.New("events stream closed")
+			}
+			return last, err
+		}
+	}
+}
+
+// dispatch passes the event to the tasks waiting for the container.
+func (p *Pool) dispatch(msg events.Message) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	waiters := p.waiters[msg.Actor.ID]
+	if len(waiters) == 0 {
+		return
+	}
+
+	switch msg.Action {
+	case events.ActionOOM:
+		for _, w := range waiters {
+			w.exit.OOMKilled = true
+		}
+	case events.ActionKill:
+		for _, w := range waiters {
+			w.exit.Signal = msg.Actor.Attributes["signal"]
+		}
+	case events.ActionDie:
+		code, err := strconv.ParseInt(msg.Actor.Attributes["exitCode"], 10, 64)
+		if err != nil {
+			slog.Warn("die event without exit code", "container", msg.Actor.ID, "error", err)
+			code = -1
+		}
+		for _, w := range waiters {
+			w.exit.ExitCode = code
+			w.inspect = err != nil
+			close(w.done)
+		}
+		delete(p.waiters, msg.Actor.ID)
+	}
+}
+
+// resyncWaiters inspects the containers waited for on the host and releases
+// the waiters of exited ones.
+func (p *Pool) resyncWaiters(ctx context.Context, h *poolHost) {
+	p.mu.Lock()
+	ids := make([]string, 0)
+	for id, waiters := range p.waiters {
+		if len(waiters) > 0 && waiters[0].host == h {
+			ids = append(ids, id)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, id := range ids {
+		exit, err := h.manager.containerExit(ctx, id)
+		if err != nil {
+			slog.Warn("inspecting waited container", "host", h.config.Name, "container", id, "error", err)
+			continue
+		}
+		if exit == nil {
+			continue
+		}
+
+		p.mu.Lock()
+		for _, w := range p.waiters[id] {
+			w.exit = *exit
+			close(w.done)
+		}
+		delete(p.waiters, id)
+		p.mu.Unlock()
+	}
+}
+
+// containerExit returns the exit of the container, or nil if it is still running.
+func (m *Manager) containerExit(ctx context.Context, containerID string) (*domain.ContainerExit, error) {
+	res, err := m.Client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("inspecting container: %w", err)
+	}
+
+	state := res.State
+	if state == nil || state.Running || state.Restarting || state.Status == "created" {
+		return nil, nil
+	}
+
+	if state.Error != "" {
+		return nil, fmt.Errorf("container exit error: %s", state.Error)
+	}
+
+	return &domain.ContainerExit{
+		ExitCode:  int64(state.ExitCode),
+		OOMKilled: state.OOMKilled,
+	}, nil
+}
@@ -0,0 +1,238 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+)
+
+// inspectMux serves the inspection of ctr-1 with the given state.
+func inspectMux(state map[string]any) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/ctr-1/json", func(w http.ResponseWriter, _ *http.Request) {
+		jsonResp(w, http.StatusOK, map[string]any{"Id": "ctr-1", "State": state})
+	})
+	return mux
+}
+
+var runningState = map[string]any{"Status": "running", "Running": true}
+
+// waitForWaiter blocks until a task waits for the container.
+func waitForWaiter(t *testing.T, pool *Pool, containerID string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		pool.mu.Lock()
+		n := len(pool.waiters[containerID])
+		pool.mu.Unlock()
+		if n > 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected a waiter for the container")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func dieEvent(id, exitCode string, at time.Time) events.Message {
+	return events.Message{
+		Type:     events.ContainerEventType,
+		Action:   events.ActionDie,
+		Actor:    events.Actor{ID: id, Attributes: map[string]string{"exitCode": exitCode}},
+		TimeNano: at.UnixNano(),
+	}
+}
+
+func TestPool_WaitContainer_AlreadyExited(t *testing.T) {
+	pool := newTestPool(testHost(t, "a", nil, inspectMux(map[string]any{"Status": "exited", "ExitCode": 3, "OOMKilled": true})))
+
+	exit, err := pool.WaitContainer(context.Background(), "ctr-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exit.ExitCode != 3 || !exit.OOMKilled {
+		t.Errorf("unexpected exit: %+v", exit)
+	}
+	if len(pool.waiters) != 0 {
+		t.Errorf("expected the waiter to be removed, got %v", pool.waiters)
+	}
+}
+
+func TestPool_WaitContainer_Events(t *testing.T) {
+	pool := newTestPool(testHost(t, "a", nil, inspectMux(runningState)))
+
+	type result struct {
+		code int64
+		oom  bool
+		sig  string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		exit, err := pool.WaitContainer(context.Background(), "ctr-1")
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		done <- result{code: exit.ExitCode, oom: exit.OOMKilled, sig: exit.Signal}
+	}()
+	waitForWaiter(t, pool, "ctr-1")
+
+	// events of other containers are ignored
+	pool.dispatch(dieEvent("ctr-2", "0", time.Now()))
+	pool.dispatch(events.Message{Action: events.ActionOOM, Actor: events.Actor{ID: "ctr-1"}})
+	pool.dispatch(events.Message{Action: events.ActionKill, Actor: events.Actor{ID: "ctr-1", Attributes: map[string]string{"signal": "9"}}})
+	pool.dispatch(dieEvent("ctr-1", "137", time.Now()))
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("unexpected error: %v", r.err)
+		}
+		if r.code != 137 || !r.oom || r.sig != "9" {
+			t.Errorf("unexpected exit: %+v", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected WaitContainer to return after the die event")
+	}
+}
+
+// A die event without a readable exit code must not pass for a success: the
+// container is inspected, and the exit counts as a failure if that fails too.
+func TestPool_WaitContainer_DieWithoutExitCode(t *testing.T) {
+	exited := map[string]any{"Status": "exited", "ExitCode": 2}
+	cases := map[string]struct {
+		state map[string]any
+		want  int64
+	}{
+		"inspected":   {state: exited, want: 2},
+		"uninspected": {state: runningState, want: -1},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			calls := 0
+			mux := http.NewServeMux()
+			mux.HandleFunc("/containers/ctr-1/json", func(w http.ResponseWriter, _ *http.Request) {
+				mu.Lock()
+				calls++
+				state := runningState
+				if calls > 1 {
+					state = tc.state
+				}
+				mu.Unlock()
+				jsonResp(w, http.StatusOK, map[string]any{"Id": "ctr-1", "State": state})
+			})
+			pool := newTestPool(testHost(t, "a", nil, mux))
+
+			done := make(chan int64, 1)
+			go func() {
+				exit, err := pool.WaitContainer(context.Background(), "ctr-1")
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					done <- 0
+					return
+				}
+				done <- exit.ExitCode
+			}()
+			waitForWaiter(t, pool, "ctr-1")
+
+			pool.dispatch(dieEvent("ctr-1", "", time.Now()))
+
+			select {
+			case code := <-done:
+				if code != tc.want {
+					t.Errorf("expected exit code %d, got %d", tc.want, code)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("expected WaitContainer to return after the die event")
+			}
+		})
+	}
+}
+
+func TestPool_WaitContainer_ContextCanceled(t *testing.T) {
+	pool := newTestPool(testHost(t, "a", nil, inspectMux(runningState)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := pool.WaitContainer(ctx, "ctr-1"); err == nil {
+		t.Fatal("expected error from cancelled context, got nil")
+	}
+	if len(pool.waiters) != 0 {
+		t.Errorf("expected the waiter to be removed, got %v", pool.waiters)
+	}
+}
+
+// A broken events stream is reopened with the time of the last event, so the
+// events sent meanwhile are replayed.
+func TestPool_Watch_ReconnectsSinceLastEvent(t *testing.T) {
+	at := time.Unix(1700000000, 500)
+	var pool *Pool
+
+	var mu sync.Mutex
+	var sinces []string
+	mux := inspectMux(runningState)
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sinces = append(sinces, r.URL.Query().Get("since"))
+		first := len(sinces) == 1
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		if !first {
+			<-r.Context().Done()
+			return
+		}
+
+		// the stream breaks after the die event
+		waitForWaiter(t, pool, "ctr-1")
+		fmt.Fprintf(w, `{"Type":"container","Action":"die","Actor":{"ID":"ctr-1","Attributes":{"exitCode":"2"}},"timeNano":%d}`+"\n", at.UnixNano())
+	})
+	h := testHost(t, "a", nil, mux)
+	pool = newTestPool(h)
+	pool.config.EventsReconnectDelay = 5 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Go(func() { pool.watch(ctx, h) })
+
+	exit, err := pool.WaitContainer(context.Background(), "ctr-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exit.ExitCode != 2 {
+		t.Errorf("expected exit code 2, got %d", exit.ExitCode)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		n := len(sinces)
+		mu.Unlock()
+		if n >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the events stream to be reopened")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !strings.HasPrefix(sinces[1], "1700000000.") {
+		t.Errorf("expected the stream to be reopened since the last event, got %q", sinces[1])
+	}
+}
@@ -0,0 +1,133 @@
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/docker/docker/client"
+)
+
+// LocalHost is the name of the host configured by the DOCKER_HOST
+// environment variables.
+const LocalHost = "local"
+
+// HostConfig describes a Docker daemon of the pool. Host is a tcp://,
+// unix:// or ssh:// address, TLS is used for tcp:// hosts. CPU and MemoryMB
+// limit the resources given to task containers, zero is unlimited. CPU uses
+// the units of the task cpu limit, 100 is one thread.
+type HostConfig struct {
+	Name     string            `json:"name"`
+	Host     string            `json:"host"`
+	TLS      *TLSConfig        `json:"tls,omitempty"`
+	Labels   map[string]string `json:"labels"`
+	CPU      int               `json:"cpu"`
+	MemoryMB int               `json:"memory_mb"`
+}
+
+// TLSConfig holds the paths of the certificates used to connect to a host.
+type TLSConfig struct {
+	CACert string `json:"ca_cert"`
+	Cert   string `json:"cert"`
+	Key    string `json:"key"`
+}
+
+type hostsFile struct {
+	Hosts []HostConfig `json:"hosts"`
+}
+
+// LoadHosts reads the hosts file. Without a path the pool has the single
+// local host.
+func LoadHosts(path string) ([]HostConfig, error) {
+	if path == "" {
+		return []HostConfig{{Name: LocalHost}}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading docker hosts file: %w", err)
+	}
+
+	var file hostsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("decoding docker hosts file: %w", err)
+	}
+
+	if err := validateHosts(file.Hosts); err != nil {
+		return nil, fmt.Errorf("invalid docker hosts file %s: %w", path, err)
+	}
+
+	return file.Hosts, nil
+}
+
+func validateHosts(hosts []HostConfig) error {
+	if len(hosts) == 0 {
+		return errors.New("no hosts")
+	}
+
+	names := make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		if h.Name == "" {
+			return errors.New("host without name")
+		}
+		if names[h.Name] {
+			return fmt.Errorf("duplicate host %q", h.Name)
+		}
+		names[h.Name] = true
+
+		if h.CPU < 0 || h.MemoryMB < 0 {
+			return fmt.Errorf("host %q: cpu and memory_mb must not be negative", h.Name)
+		}
+		if h.Host == "" {
+			continue
+		}
+
+		u, err := url.Parse(h.Host)
+		if err != nil {
+			return fmt.Errorf("host %q: %w", h.Name, err)
+		}
+		switch u.Scheme {
+		case "tcp", "unix", "ssh":
+		default:
+			return fmt.Errorf("host %q: unsupported scheme %q", h.Name, u.Scheme)
+		}
+		if h.TLS != nil && u.Scheme != "tcp" {
+			return fmt.Errorf("host %q: tls requires a tcp:// host", h.Name)
+		}
+	}
+
+	return nil
+}
+
+// newClient connects to the host. A host without an address is configured
+// by the DOCKER_HOST environment variables.
+func newClient(h HostConfig) (*client.Client, error) {
+	opts := []client.Opt{client.WithAPIVersionNegotiation()}
+
+	switch {
+	case h.Host == "":
+		opts = append(opts, client.FromEnv)
+	case h.TLS != nil:
+		opts = append(opts, client.WithHost(h.Host), client.WithTLSClientConfig(h.TLS.CACert, h.TLS.Cert, h.TLS.Key))
+	default:
+		u, err := url.Parse(h.Host)
+		if err != nil {
+			return nil, fmt.Errorf("parsing host: %w", err)
+		}
+		if u.Scheme == "ssh" {
+			// the daemon is reached through "docker system dial-stdio" on the remote host
+			opts = append(opts, client.WithHost("http://docker.example.com"), client.WithDialContext(sshDialer(u)))
+		} else {
+			opts = append(opts, client.WithHost(h.Host))
+		}
+	}
+
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating docker client for host %s: %w", h.Name, err)
+	}
+
+	return cli, nil
+}
@@ -0,0 +1,558 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/domain"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// Labels with the limits of a task container.
+const (
+	labelCPU    = "pinn.cpu"
+	labelMemory = "pinn.memory_mb"
+)
+
+// Pool runs task containers on a set of Docker hosts. Every container is
+// placed on a host picked by the placement policy, the other calls are routed
+// to the host running the container.
+type Pool struct {
+	hosts  []*poolHost
+	config config.DockerConfig
+
+	// mu guards the status of the hosts and containers
+	mu sync.Mutex
+	// containers maps the IDs of known containers to their hosts
+	containers map[string]*poolHost
+	// waiters are the tasks waiting for their containers to exit
+	waiters map[string][]*exitWaiter
+}
+
+type poolHost struct {
+	config  HostConfig
+	manager *Manager
+
+	status domain.DockerHost
+	// reserved are the limits of the containers being started
+	reservedCPU    int
+	reservedMemory int
+	reservedCount  int
+}
+
+// NewPool connects to the hosts of the hosts file and checks them.
+func NewPool(ctx context.Context, cfg config.DockerConfig) (*Pool, error) {
+	hosts, err := LoadHosts(cfg.HostsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	poolHosts := make([]*poolHost, 0, len(hosts))
+	for _, h := range hosts {
+		manager, err := newManager(h)
+		if err != nil {
+			for _, ph := range poolHosts {
+				_ = ph.manager.Client.Close()
+			}
+			return nil, err
+		}
+		poolHosts = append(poolHosts, &poolHost{config: h, manager: manager})
+	}
+
+	pool := newPool(poolHosts, cfg)
+	pool.checkAll(ctx)
+
+	return pool, nil
+}
+
+func newPool(hosts []*poolHost, cfg config.DockerConfig) *Pool {
+	for _, h := range hosts {
+		h.status = domain.DockerHost{
+			Name:     h.config.Name,
+			Host:     h.address(),
+			Labels:   h.config.Labels,
+			CPU:      h.config.CPU,
+			MemoryMB: h.config.MemoryMB,
+			Error:    "not checked yet",
+		}
+	}
+
+	return &Pool{
+		hosts:      hosts,
+		config:     cfg,
+		containers: make(map[string]*poolHost),
+		waiters:    make(map[string][]*exitWaiter),
+	}
+}
+
+// Start checks the hosts every configured interval and watches their
+// container events until ctx is done.
+func (p *Pool) Start(ctx context.Context, wg *sync.WaitGroup) {
+	for _, h := range p.hosts {
+		wg.Go(func() { p.watch(ctx, h) })
+	}
+
+	ticker := time.NewTicker(p.config.HealthInterval)
+
+	wg.Go(func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.checkAll(ctx)
+			}
+		}
+	})
+}
+
+// Close closes the connections to the hosts.
+func (p *Pool) Close() error {
+	var errs []error
+	for _, h := range p.hosts {
+		if err := h.manager.Client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing client of host %s: %w", h.config.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Hosts returns the status of the hosts as of their last check.
+func (p *Pool) Hosts() []domain.DockerHost {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	hosts := make([]domain.DockerHost, 0, len(p.hosts))
+	for _, h := range p.hosts {
+		hosts = append(hosts, h.status)
+	}
+	return hosts
+}
+
+func (p *Pool) checkAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, h := range p.hosts {
+		wg.Go(func() { p.check(ctx, h) })
+	}
+	wg.Wait()
+}
+
+// check pings the host and counts the limits of its running task containers.
+func (p *Pool) check(ctx context.Context, h *poolHost) {
+	ctx, cancel := context.WithTimeout(ctx, p.config.HealthInterval)
+	defer cancel()
+
+	status := domain.DockerHost{
+		Name:      h.config.Name,
+		Host:      h.address(),
+		Labels:    h.config.Labels,
+		CPU:       h.config.CPU,
+		MemoryMB:  h.config.MemoryMB,
+		CheckedAt: time.Now(),
+	}
+
+	err := h.manager.CheckStatus(ctx)
+	if err == nil {
+		status.RunningContainers, status.UsedCPU, status.UsedMemoryMB, err = h.manager.runningUsage(ctx)
+	}
+
+	p.mu.Lock()
+	wasHealthy := h.status.Healthy
+	p.mu.Unlock()
+
+	if err != nil {
+		status.Error = err.Error()
+		if wasHealthy {
+			slog.Warn("docker host is unavailable", "host", h.config.Name, "error", err)
+		}
+	} else {
+		status.Healthy = true
+		if !wasHealthy {
+			// the host takes no containers until it is marked healthy
+			h.manager.setGPUSupport(ctx)
+			slog.Info("docker host is available", "host", h.config.Name, "gpu", h.manager.hasGPU)
+		}
+	}
+	status.GPU = h.manager.hasGPU
+
+	p.mu.Lock()
+	h.status = status
+	p.mu.Unlock()
+}
+
+// StartContainer starts the container on the host picked for it. It returns
+// domain.ErrNoMatchingHost if no host can ever run the container and
+// domain.ErrHostsBusy if the matching hosts can't run it right now.
+func (p *Pool) StartContainer(ctx context.Context, cfg *domain.ContainerConfig) (string, error) {
+	h, err := p.place(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	containerID, err := h.manager.StartContainer(ctx, cfg)
+
+	p.mu.Lock()
+	h.reservedCPU -= cfg.CPULimit
+	h.reservedMemory -= cfg.MemoryLimit
+	h.reservedCount--
+	if err == nil {
+		h.status.RunningContainers++
+		h.status.UsedCPU += cfg.CPULimit
+		h.status.UsedMemoryMB += cfg.MemoryLimit
+		p.containers[containerID] = h
+	} else if client.IsErrConnectionFailed(err) {
+		h.status.Healthy = false
+		h.status.Error = err.Error()
+	}
+	p.mu.Unlock()
+
+	if err != nil {
+		if client.IsErrConnectionFailed(err) {
+			return "", fmt.Errorf("host %s: %w: %w", h.config.Name, domain.ErrHostsBusy, err)
+		}
+		return "", fmt.Errorf("host %s: %w", h.config.Name, err)
+	}
+
+	slog.Info("container placed", "task_id", cfg.TaskID, "host", h.config.Name)
+	return containerID, nil
+}
+
+// place picks a healthy host having the labels of the constraints and enough
+// free resources. GPU containers go to hosts with GPU support first, then
+// the host running the fewest containers is preferred. The resources of the
+// container are reserved on the picked host.
+func (p *Pool) place(cfg *domain.ContainerConfig) (*poolHost, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var best *poolHost
+	matching := false
+	for _, h := range p.hosts {
+		if !h.matches(cfg) {
+			continue
+		}
+		matching = true
+
+		if !h.status.Healthy || !h.fits(cfg) {
+			continue
+		}
+		if best == nil || h.preferredTo(best, cfg) {
+			best = h
+		}
+	}
+
+	if !matching {
+		return nil, fmt.Errorf("%w: constraints %v, cpu %d, memory %d MB",
+			domain.ErrNoMatchingHost, cfg.Constraints, cfg.CPULimit, cfg.MemoryLimit)
+	}
+	if best == nil {
+		return nil, domain.ErrHostsBusy
+	}
+
+	best.reservedCPU += cfg.CPULimit
+	best.reservedMemory += cfg.MemoryLimit
+	best.reservedCount++
+
+	return best, nil
+}
+
+// matches reports whether the host has the labels of the constraints and
+// enough resources for the container when it runs nothing else.
+func (h *poolHost) matches(cfg *domain.ContainerConfig) bool {
+	for k, v := range cfg.Constraints {
+		if label, ok := h.config.Labels[k]; !ok || label != v {
+			return false
+		}
+	}
+	if h.config.CPU > 0 && cfg.CPULimit > h.config.CPU {
+		return false
+	}
+	if h.config.MemoryMB > 0 && cfg.MemoryLimit > h.config.MemoryMB {
+		return false
+	}
+	return true
+}
+
+// fits reports whether the host has free resources for the container.
+func (h *poolHost) fits(cfg *domain.ContainerConfig) bool {
+	if h.config.CPU > 0 && h.status.UsedCPU+h.reservedCPU+cfg.CPULimit > h.config.CPU {
+		return false
+	}
+	if h.config.MemoryMB > 0 && h.status.UsedMemoryMB+h.reservedMemory+cfg.MemoryLimit > h.config.MemoryMB {
+		return false
+	}
+	return true
+}
+
+func (h *poolHost) preferredTo(other *poolHost, cfg *domain.ContainerConfig) bool {
+	if cfg.GPU && h.status.GPU != other.status.GPU {
+		return h.status.GPU
+	}
+	if load, otherLoad := h.load(), other.load(); load != otherLoad {
+		return load < otherLoad
+	}
+	return h.config.Name < other.config.Name
+}
+
+func (h *poolHost) load() int {
+	return h.status.RunningContainers + h.reservedCount
+}
+
+// address is the configured address of the host or the one taken from the
+// environment.
+func (h *poolHost) address() string {
+	if h.config.Host != "" {
+		return h.config.Host
+	}
+	return h.manager.Client.DaemonHost()
+}
+
+// hostOf returns the host running the container. Unknown containers are
+// looked up on every host.
+func (p *Pool) hostOf(ctx context.Context, containerID string) (*poolHost, error) {
+	p.mu.Lock()
+	h, ok := p.containers[containerID]
+	p.mu.Unlock()
+	if ok {
+		return h, nil
+	}
+
+	if len(p.hosts) == 1 {
+		return p.hosts[0], nil
+	}
+
+	var errs []error
+	for _, h := range p.hosts {
+		exists, err := h.manager.IsContainerExists(ctx, containerID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("host %s: %w", h.config.Name, err))
+			continue
+		}
+		if exists {
+			p.mu.Lock()
+			p.containers[containerID] = h
+			p.mu.Unlock()
+			return h, nil
+		}
+	}
+
+	// the container may be on an unavailable host
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("finding host of container %s: %w", containerID, errors.Join(errs...))
+	}
+
+	return nil, fmt.Errorf("container %s: %w", containerID, errdefs.ErrNotFound)
+}
+
+func (p *Pool) forget(containerID string) {
+	p.mu.Lock()
+	delete(p.containers, containerID)
+	p.mu.Unlock()
+}
+
+// ListManagedContainers lists the task containers of all hosts. Unavailable
+// hosts are skipped unless every host is unavailable.
+func (p *Pool) ListManagedContainers(ctx context.Context) ([]*domain.Container, error) {
+	var containers []*domain.Container
+	var errs []error
+
+	for _, h := range p.hosts {
+		hostContainers, err := h.manager.ListManagedContainers(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("host %s: %w", h.config.Name, err))
+			continue
+		}
+
+		p.mu.Lock()
+		for _, c := range hostContainers {
+			p.containers[c.ID] = h
+		}
+		p.mu.Unlock()
+
+		containers = append(containers, hostContainers...)
+	}
+
+	if len(errs) == len(p.hosts) {
+		return nil, errors.Join(errs...)
+	}
+	for _, err := range errs {
+		slog.Warn("skipping unavailable docker host", "error", err)
+	}
+
+	return containers, nil
+}
+
+func (p *Pool) GetContainerLogs(ctx context.Context, containerID string, follow bool) (io.ReadCloser, error) {
+	h, err := p.hostOf(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	return h.manager.GetContainerLogs(ctx, containerID, follow)
+}
+
+func (p *Pool) StopContainer(ctx context.Context, containerID string, timeout time.Duration) error {
+	h, err := p.hostOf(ctx, containerID)
+	if err != nil {
+		return err
+	}
+	return h.manager.StopContainer(ctx, containerID, timeout)
+}
+
+func (p *Pool) RemoveContainer(ctx context.Context, containerID string) error {
+	h, err := p.hostOf(ctx, containerID)
+	if err != nil {
+		return err
+	}
+	if err := h.manager.RemoveContainer(ctx, containerID); err != nil {
+		return err
+	}
+	p.forget(containerID)
+	return nil
+}
+
+func (p *Pool) GetContainerState(ctx context.Context, containerID string) (*domain.ContainerState, error) {
+	h, err := p.hostOf(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	return h.manager.GetContainerState(ctx, containerID)
+}
+
+func (p *Pool) ContainerStats(ctx context.Context, containerID string) (*domain.ContainerStats, error) {
+	h, err := p.hostOf(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	return h.manager.ContainerStats(ctx, containerID)
+}
+
+func (p *Pool) StreamContainerStats(ctx context.Context, containerID string, fn func(*domain.ContainerStats) error) error {
+	h, err := p.hostOf(ctx, containerID)
+	if err == nil {
+		err = h.manager.StreamContainerStats(ctx, containerID, fn)
+	}
+	if errdefs.IsNotFound(err) {
+		return fmt.Errorf("%w: %w", domain.ErrContainerNotFound, err)
+	}
+	return err
+}
+
+func (p *Pool) IsContainerExists(ctx context.Context, containerID string) (bool, error) {
+	if containerID == "" {
+		return false, errors.New("container id requires")
+	}
+
+	h, err := p.hostOf(ctx, containerID)
+	if errdefs.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	exists, err := h.manager.IsContainerExists(ctx, containerID)
+	if err == nil && !exists {
+		p.forget(containerID)
+	}
+	return exists, err
+}
+
+// CheckStatus succeeds if any host is available.
+func (p *Pool) CheckStatus(ctx context.Context) error {
+	var errs []error
+	for _, h := range p.hosts {
+		err := h.manager.CheckStatus(ctx)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("host %s: %w", h.config.Name, err))
+	}
+	return errors.Join(errs...)
+}
+
+// BuildImage builds the image on every available host. Hosts unavailable
+// during the build don't get the image until the model is updated.
+func (p *Pool) BuildImage(ctx context.Context, archive io.Reader, tag string, logWriter io.Writer) error {
+	if len(p.hosts) == 1 {
+		return p.hosts[0].manager.BuildImage(ctx, archive, tag, logWriter)
+	}
+
+	data, err := io.ReadAll(archive)
+	if err != nil {
+		return fmt.Errorf("reading build context: %w", err)
+	}
+
+	built := 0
+	for _, h := range p.hosts {
+		if err := h.manager.CheckStatus(ctx); err != nil {
+			slog.Warn("skipping image build on unavailable docker host", "host", h.config.Name, "tag", tag, "error", err)
+			continue
+		}
+
+		if logWriter != nil {
+			fmt.Fprintf(logWriter, "building on host %s\n", h.config.Name)
+		}
+		if err := h.manager.BuildImage(ctx, bytes.NewReader(data), tag, logWriter); err != nil {
+			return fmt.Errorf("host %s: %w", h.config.Name, err)
+		}
+		built++
+	}
+
+	if built == 0 {
+		return errors.New("no docker host is available")
+	}
+	return nil
+}
+
+// RemoveImage removes the image from every host having it.
+func (p *Pool) RemoveImage(ctx context.Context, img string) error {
+	if len(p.hosts) == 1 {
+		return p.hosts[0].manager.RemoveImage(ctx, img)
+	}
+
+	var errs []error
+	for _, h := range p.hosts {
+		if err := h.manager.RemoveImage(ctx, img); err != nil && !errdefs.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("host %s: %w", h.config.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runningUsage returns the number of running task containers and the sum of
+// their limits.
+func (m *Manager) runningUsage(ctx context.Context) (count, cpu, memoryMB int, err error) {
+	args := filters.NewArgs()
+	args.Add("label", "pinn.managed=true")
+	args.Add("status", "running")
+
+	res, err := m.Client.ContainerList(ctx, container.ListOptions{Filters: args})
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("listing running containers: %w", err)
+	}
+
+	for _, c := range res {
+		// containers without the labels don't count against the limits
+		if v, err := strconv.Atoi(c.Labels[labelCPU]); err == nil {
+			cpu += v
+		}
+		if v, err := strconv.Atoi(c.Labels[labelMemory]); err == nil {
+			memoryMB += v
+		}
+	}
+
+	return len(res), cpu, memoryMB, nil
+}
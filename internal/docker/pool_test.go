@@ -0,0 +1,355 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/domain"
+	"testing"
+	"time"
+)
+
+// ─────────────────────────────────────────────
+// TEST INFRASTRUCTURE
+// ─────────────────────────────────────────────
+
+// testHost returns a healthy host of the pool, its manager talks to mux.
+func testHost(t *testing.T, name string, labels map[string]string, mux *http.ServeMux) *poolHost {
+	t.Helper()
+	if mux == nil {
+		mux = http.NewServeMux()
+	}
+	return &poolHost{
+		config:  HostConfig{Name: name, Host: "tcp://" + name, Labels: labels},
+		manager: newTestManager(t, mux),
+	}
+}
+
+func newTestPool(hosts ...*poolHost) *Pool {
+	pool := newPool(hosts, config.DockerConfig{HealthInterval: time.Second})
+	for _, h := range hosts {
+		h.status.Healthy = true
+		h.status.Error = ""
+	}
+	return pool
+}
+
+// startMux serves the calls of a successful StartContainer.
+func startMux(id string) *http.ServeMux {
+	mux := http.NewServeMux()
+	imageExistsMux(mux)
+	mux.HandleFunc("/containers/create", func(w http.ResponseWriter, _ *http.Request) {
+		jsonResp(w, http.StatusCreated, map[string]any{"Id": id, "Warnings": []string{}})
+	})
+	mux.HandleFunc("/containers/"+id+"/start", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// ─────────────────────────────────────────────
+// PLACEMENT
+// ─────────────────────────────────────────────
+
+func TestPlace_MatchesConstraints(t *testing.T) {
+	pool := newTestPool(
+		testHost(t, "cpu-1", map[string]string{"arch": "amd64"}, nil),
+		testHost(t, "arm-1", map[string]string{"arch": "arm64"}, nil),
+	)
+
+	h, err := pool.place(&domain.ContainerConfig{Constraints: map[string]string{"arch": "arm64"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h.config.Name != "arm-1" {
+		t.Errorf("expected arm-1, got %s", h.config.Name)
+	}
+}
+
+func TestPlace_NoMatchingHost(t *testing.T) {
+	small := testHost(t, "small", nil, nil)
+	small.config.MemoryMB = 512
+	amd := testHost(t, "amd-1", map[string]string{"arch": "amd64"}, nil)
+	amd.config.MemoryMB = 512
+	pool := newTestPool(small, amd)
+
+	tests := []struct {
+		name string
+		cfg  *domain.ContainerConfig
+	}{
+		{"unknown label", &domain.ContainerConfig{Constraints: map[string]string{"gpu": "true"}}},
+		{"label value", &domain.ContainerConfig{Constraints: map[string]string{"arch": "arm64"}}},
+		{"too large", &domain.ContainerConfig{MemoryLimit: 1024}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := pool.place(tt.cfg); !errors.Is(err, domain.ErrNoMatchingHost) {
+				t.Errorf("expected ErrNoMatchingHost, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPlace_HostsBusy(t *testing.T) {
+	full := testHost(t, "full", nil, nil)
+	full.config.CPU = 100
+	down := testHost(t, "down", nil, nil)
+	pool := newTestPool(full, down)
+	full.status.UsedCPU = 80
+	down.status.Healthy = false
+
+	if _, err := pool.place(&domain.ContainerConfig{CPULimit: 50}); !errors.Is(err, domain.ErrHostsBusy) {
+		t.Errorf("expected ErrHostsBusy, got %v", err)
+	}
+}
+
+func TestPlace_ReservesResources(t *testing.T) {
+	h := testHost(t, "host", nil, nil)
+	h.config.CPU = 100
+	pool := newTestPool(h)
+
+	if _, err := pool.place(&domain.ContainerConfig{CPULimit: 60}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the first container is still being started
+	if _, err := pool.place(&domain.ContainerConfig{CPULimit: 60}); !errors.Is(err, domain.ErrHostsBusy) {
+		t.Errorf("expected ErrHostsBusy, got %v", err)
+	}
+}
+
+func TestPlace_PrefersLeastLoaded(t *testing.T) {
+	a := testHost(t, "a", nil, nil)
+	b := testHost(t, "b", nil, nil)
+	pool := newTestPool(a, b)
+	a.status.RunningContainers = 3
+	b.status.RunningContainers = 1
+
+	h, err := pool.place(&domain.ContainerConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h != b {
+		t.Errorf("expected the least loaded host b, got %s", h.config.Name)
+	}
+}
+
+func TestPlace_PrefersGPUHosts(t *testing.T) {
+	cpu := testHost(t, "cpu", nil, nil)
+	gpu := testHost(t, "gpu", nil, nil)
+	pool := newTestPool(cpu, gpu)
+	gpu.status.GPU = true
+	gpu.status.RunningContainers = 5
+
+	h, err := pool.place(&domain.ContainerConfig{GPU: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h != gpu {
+		t.Errorf("expected the GPU host, got %s", h.config.Name)
+	}
+
+	h, err = pool.place(&domain.ContainerConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h != cpu {
+		t.Errorf("expected the idle host for a CPU container, got %s", h.config.Name)
+	}
+}
+
+// ─────────────────────────────────────────────
+// ROUTING
+// ─────────────────────────────────────────────
+
+func TestPool_StartContainer_RoutesLaterCalls(t *testing.T) {
+	mux := startMux("ctr-1")
+	mux.HandleFunc("/containers/ctr-1/json", func(w http.ResponseWriter, _ *http.Request) {
+		jsonResp(w, http.StatusOK, map[string]any{"Id": "ctr-1", "State": map[string]any{"Status": "running", "Running": true}})
+	})
+	arm := testHost(t, "arm-1", map[string]string{"arch": "arm64"}, mux)
+	pool := newTestPool(testHost(t, "cpu-1", nil, nil), arm)
+
+	cfg := makeContainerConfig()
+	cfg.CPULimit = 50
+	cfg.Constraints = map[string]string{"arch": "arm64"}
+	id, err := pool.StartContainer(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	state, err := pool.GetContainerState(context.Background(), id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !state.Running {
+		t.Errorf("expected the state from arm-1, got %+v", state)
+	}
+
+	hosts := pool.Hosts()
+	if hosts[1].RunningContainers != 1 || hosts[1].UsedCPU != 50 {
+		t.Errorf("expected the started container to be counted, got %+v", hosts[1])
+	}
+}
+
+func TestPool_IsContainerExists_LooksUpHosts(t *testing.T) {
+	notFound := http.NewServeMux()
+	notFound.HandleFunc("/containers/ctr-9/json", func(w http.ResponseWriter, _ *http.Request) {
+		errResp(w, http.StatusNotFound, "No such container: ctr-9")
+	})
+	found := http.NewServeMux()
+	found.HandleFunc("/containers/ctr-9/json", func(w http.ResponseWriter, _ *http.Request) {
+		jsonResp(w, http.StatusOK, map[string]any{"Id": "ctr-9"})
+	})
+	pool := newTestPool(testHost(t, "a", nil, notFound), testHost(t, "b", nil, found))
+
+	exists, err := pool.IsContainerExists(context.Background(), "ctr-9")
+	if err != nil || !exists {
+		t.Fatalf("expected the container on b, got exists=%v err=%v", exists, err)
+	}
+	if pool.containers["ctr-9"] != pool.hosts[1] {
+		t.Error("expected the host of the container to be remembered")
+	}
+}
+
+func TestPool_IsContainerExists_NotFound(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/ctr-9/json", func(w http.ResponseWriter, _ *http.Request) {
+		errResp(w, http.StatusNotFound, "No such container: ctr-9")
+	})
+	pool := newTestPool(testHost(t, "a", nil, mux), testHost(t, "b", nil, mux))
+
+	exists, err := pool.IsContainerExists(context.Background(), "ctr-9")
+	if err != nil || exists {
+		t.Errorf("expected a missing container, got exists=%v err=%v", exists, err)
+	}
+}
+
+// A container can't be reported missing while a host is unavailable.
+func TestPool_IsContainerExists_HostUnavailable(t *testing.T) {
+	notFound := http.NewServeMux()
+	notFound.HandleFunc("/containers/ctr-9/json", func(w http.ResponseWriter, _ *http.Request) {
+		errResp(w, http.StatusNotFound, "No such container: ctr-9")
+	})
+	broken := http.NewServeMux()
+	broken.HandleFunc("/containers/ctr-9/json", func(w http.ResponseWriter, _ *http.Request) {
+		errResp(w, http.StatusInternalServerError, "daemon error")
+	})
+	pool := newTestPool(testHost(t, "a", nil, notFound), testHost(t, "b", nil, broken))
+
+	if _, err := pool.IsContainerExists(context.Background(), "ctr-9"); err == nil {
+		t.Error("expected an error, got nil")
+	}
+}
+
+func TestPool_StreamContainerStats_NotFound(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/ctr-9/json", func(w http.ResponseWriter, _ *http.Request) {
+		errResp(w, http.StatusNotFound, "No such container: ctr-9")
+	})
+	pool := newTestPool(testHost(t, "a", nil, mux), testHost(t, "b", nil, mux))
+
+	err := pool.StreamContainerStats(context.Background(), "ctr-9", func(*domain.ContainerStats) error { return nil })
+	if !errors.Is(err, domain.ErrContainerNotFound) {
+		t.Errorf("expected ErrContainerNotFound, got %v", err)
+	}
+}
+
+// ─────────────────────────────────────────────
+// HEALTH
+// ─────────────────────────────────────────────
+
+func TestPool_Check_CountsRunningContainers(t *testing.T) {
+	mux := pingMux()
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, _ *http.Request) {
+		jsonResp(w, http.StatusOK, []map[string]any{
+			{"Id": "a", "Labels": map[string]string{labelCPU: "50", labelMemory: "512"}},
+			{"Id": "b", "Labels": map[string]string{labelCPU: "100", labelMemory: "1024"}},
+		})
+	})
+	mux.HandleFunc("/info", func(w http.ResponseWriter, _ *http.Request) {
+		jsonResp(w, http.StatusOK, map[string]any{"Runtimes": map[string]any{"nvidia": map[string]any{}}})
+	})
+	h := testHost(t, "gpu-1", nil, mux)
+	pool := newPool([]*poolHost{h}, config.DockerConfig{HealthInterval: time.Second})
+
+	pool.check(context.Background(), h)
+
+	got := pool.Hosts()[0]
+	if !got.Healthy || got.Error != "" {
+		t.Fatalf("expected a healthy host, got %+v", got)
+	}
+	if got.RunningContainers != 2 || got.UsedCPU != 150 || got.UsedMemoryMB != 1536 {
+		t.Errorf("unexpected usage: %+v", got)
+	}
+	if !got.GPU {
+		t.Error("expected GPU support to be detected")
+	}
+}
+
+func TestPool_Check_Unavailable(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/_ping", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	h := testHost(t, "down", nil, mux)
+	pool := newTestPool(h)
+
+	pool.check(context.Background(), h)
+
+	got := pool.Hosts()[0]
+	if got.Healthy || got.Error == "" {
+		t.Errorf("expected an unhealthy host, got %+v", got)
+	}
+	if _, err := pool.place(&domain.ContainerConfig{}); !errors.Is(err, domain.ErrHostsBusy) {
+		t.Errorf("expected ErrHostsBusy, got %v", err)
+	}
+}
+
+// ─────────────────────────────────────────────
+// HOSTS FILE
+// ─────────────────────────────────────────────
+
+func TestLoadHosts_WithoutFile(t *testing.T) {
+	hosts, err := LoadHosts("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hosts) != 1 || hosts[0].Name != LocalHost || hosts[0].Host != "" {
+		t.Errorf("expected the single local host, got %+v", hosts)
+	}
+}
+
+func TestLoadHosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"valid", `{"hosts":[{"name":"gpu-1","host":"tcp://10.0.0.2:2376","tls":{"ca_cert":"ca.pem","cert":"cert.pem","key":"key.pem"},"labels":{"gpu":"true"}},{"name":"arm-1","host":"ssh://deploy@10.0.0.3"}]}`, false},
+		{"no hosts", `{"hosts":[]}`, true},
+		{"no name", `{"hosts":[{"host":"tcp://10.0.0.2:2376"}]}`, true},
+		{"duplicate", `{"hosts":[{"name":"a"},{"name":"a"}]}`, true},
+		{"scheme", `{"hosts":[{"name":"a","host":"http://10.0.0.2"}]}`, true},
+		{"tls over ssh", `{"hosts":[{"name":"a","host":"ssh://10.0.0.2","tls":{}}]}`, true},
+		{"negative memory", `{"hosts":[{"name":"a","memory_mb":-1}]}`, true},
+		{"invalid json", `{`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "hosts.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := LoadHosts(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("wantErr %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
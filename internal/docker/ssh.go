@@ -0,0 +1,85 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os/exec"
+	"time"
+)
+
+// sshDialer connects to the Docker daemon of a remote host through the ssh
+// client, the same way the docker CLI does. Authentication is left to the ssh
+// configuration of the user running the service.
+func sshDialer(u *url.URL) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	args := []string{"-o", "ConnectTimeout=30", "-o", "BatchMode=yes"}
+	if u.User != nil {
+		args = append(args, "-l", u.User.Username())
+	}
+	if port := u.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+	args = append(args, "--", u.Hostname(), "docker", "system", "dial-stdio")
+
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// the connection outlives the dial context
+		cmd := exec.Command("ssh", args...)
+
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, fmt.Errorf("opening ssh stdin: %w", err)
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, fmt.Errorf("opening ssh stdout: %w", err)
+		}
+
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("starting ssh: %w", err)
+		}
+
+		return &cmdConn{cmd: cmd, stdin: stdin, stdout: stdout, host: u.Host}, nil
+	}
+}
+
+// cmdConn is a connection over the standard streams of a command.
+type cmdConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	host   string
+}
+
+func (c *cmdConn) Read(p []byte) (int, error)  { return c.stdout.Read(p) }
+func (c *cmdConn) Write(p []byte) (int, error) { return c.stdin.Write(p) }
+
+func (c *cmdConn) Close() error {
+	err := c.stdin.Close()
+	if c.cmd.Process != nil {
+		_ = c.cmd.Process.Kill()
+	}
+	// Wait closes stdout
+	_ = c.cmd.Wait()
+	return err
+}
+
+func (c *cmdConn) LocalAddr() net.Addr  { return cmdAddr("local") }
+func (c *cmdConn) RemoteAddr() net.Addr { return cmdAddr(c.host) }
+
+// deadlines are not supported by pipes, the http client relies on contexts
+func (c *cmdConn) SetDeadline(time.Time) error      { return nil }
+func (c *cmdConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *cmdConn) SetWriteDeadline(time.Time) error { return nil }
+
+type cmdAddr string
+
+func (a cmdAddr) Network() string { return "ssh" }
+func (a cmdAddr) String() string  { return string(a) }
+
+var _ net.Conn = (*cmdConn)(nil)
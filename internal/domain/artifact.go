@@ -0,0 +1,43 @@
+package domain
+
+import "time"
+
+type ArchiveFormat string
+
+const (
+	ArchiveZip   ArchiveFormat = "zip"
+	ArchiveTarGz ArchiveFormat = "tar.gz"
+)
+
+// Artifact describes a single object kept in the artifact storage. Size is
+// the size of the content, except in listings, which report the stored size.
+// The two differ for client-side encrypted objects.
+type Artifact struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+	// SHA256 is the hex encoded checksum of the content, empty if unknown.
+	SHA256 string
+}
+
+// UploadResult describes the objects stored by an upload of a result.
+type UploadResult struct {
+	// PrimaryKey is the key of the primary result file.
+	PrimaryKey string
+	Objects    []Artifact
+}
+
+// ResultFile is an artifact addressed relative to the result set of a task.
+type ResultFile struct {
+	Path         string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+	SHA256       string
+}
+
+// UploadProgressFunc is called while the result of a task is uploaded with
+// the number of bytes uploaded so far and the total size of the result.
+// It is called from several goroutines, but never concurrently.
+type UploadProgressFunc func(uploaded, total int64)
@@ -1,6 +1,10 @@
 package domain
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Mount struct {
 	Source   string
@@ -16,8 +20,42 @@ type ContainerConfig struct {
 	MemoryLimit int
 	CPULimit    int
 	GPU         bool
+	// Constraints are the labels the Docker host must have.
+	Constraints map[string]string
+	// NetworkMode is the network access of the container, Network is the
+	// Docker network of the internal and egress modes. An empty mode leaves
+	// the container on the default network of the host.
+	NetworkMode NetworkMode
+	Network     string
+	Security    SecurityProfile
 
 	TaskID uuid.UUID
+	NodeID string
+}
+
+// ContainerExit describes how a task container exited.
+type ContainerExit struct {
+	ExitCode  int64
+	OOMKilled bool
+	// Signal is the signal the container was killed with, e.g. when it was stopped.
+	Signal string
+}
+
+// ContainerStats is a sample of the resources used by a running container.
+// The CPU, block and network counters are cumulative since the container start.
+type ContainerStats struct {
+	ReadAt time.Time
+	// MemoryBytes is the memory in use without the reclaimable page cache.
+	MemoryBytes      uint64
+	MemoryLimitBytes uint64
+	CPUNanos         uint64
+	// CPUPercent is the CPU used since the previous sample of a stream, 100 is
+	// one thread. It is zero for a single sample.
+	CPUPercent      float64
+	BlockReadBytes  uint64
+	BlockWriteBytes uint64
+	NetRxBytes      uint64
+	NetTxBytes      uint64
 }
 
 type ContainerState struct {
@@ -41,3 +79,22 @@ type Container struct {
 	Status  string
 	Mounts  []Mount
 }
+
+// DockerHost is a Docker daemon of the execution pool.
+type DockerHost struct {
+	Name    string            `json:"name"`
+	Host    string            `json:"host"`
+	Labels  map[string]string `json:"labels"`
+	Healthy bool              `json:"healthy"`
+	Error   string            `json:"error,omitempty"`
+	GPU     bool              `json:"gpu"`
+	// CPU and MemoryMB are the resources available to tasks, zero is unlimited.
+	// CPU uses the units of the task cpu limit, 100 is one thread.
+	CPU      int `json:"cpu"`
+	MemoryMB int `json:"memory_mb"`
+	// UsedCPU and UsedMemoryMB are the limits of the running task containers.
+	RunningContainers int       `json:"running_containers"`
+	UsedCPU           int       `json:"used_cpu"`
+	UsedMemoryMB      int       `json:"used_memory_mb"`
+	CheckedAt         time.Time `json:"checked_at"`
+}
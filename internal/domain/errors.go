@@ -3,5 +3,58 @@ package domain
 import "errors"
 
 var (
-	ErrModelNotFound = errors.New("model not found")
+	ErrModelNotFound    = errors.New("model not found")
+	ErrTaskNotFound     = errors.New("task not found")
+	ErrResultNotReady   = errors.New("task result is not ready")
+	ErrArtifactNotFound = errors.New("artifact not found")
+	// ErrArtifactCorrupted is returned when an encrypted object fails authentication.
+	ErrArtifactCorrupted = errors.New("artifact is corrupted")
+	ErrInvalidSignature  = errors.New("invalid or expired signature")
+	ErrInvalidExpiry     = errors.New("invalid download url expiry")
+	ErrUploadNotFailed   = errors.New("task result upload has not failed")
+	ErrTaskInProgress    = errors.New("task is being processed")
+	// ErrNotLeader is returned when a singleton job is requested from a follower.
+	ErrNotLeader = errors.New("instance is not the leader")
+	// ErrNotExecuting is returned when a node job is requested from an instance
+	// that doesn't execute tasks.
+	ErrNotExecuting = errors.New("instance does not execute tasks")
+	// ErrNoMatchingHost is returned when no Docker host satisfies the
+	// constraints and the resource limits of a task.
+	ErrNoMatchingHost = errors.New("no docker host matches the task")
+	// ErrHostsBusy is returned when the matching Docker hosts are unavailable
+	// or have no free resources. The task is queued again.
+	ErrHostsBusy = errors.New("matching docker hosts are busy")
+	// ErrInvalidTransition is returned when a task can't move from its
+	// status to the requested one, e.g. a stopped task to completed.
+	ErrInvalidTransition = errors.New("invalid task status transition")
+	// ErrTaskConflict is returned when a task changed since it was read.
+	ErrTaskConflict = errors.New("task was changed concurrently")
+	// ErrImagePull is returned when the image of a task can't be pulled.
+	ErrImagePull = errors.New("pulling image failed")
+	// ErrUnknownFailureReason is returned when a string names no known
+	// failure reason.
+	ErrUnknownFailureReason = errors.New("unknown failure reason")
+	// ErrContainerNotFound is returned when no Docker host has the container.
+	ErrContainerNotFound = errors.New("container not found")
+	// ErrTaskNotRunning is returned for live data of a task without a running container.
+	ErrTaskNotRunning = errors.New("task is not running")
+	// ErrSecretsDisabled is returned when no keyring is configured for secrets.
+	ErrSecretsDisabled   = errors.New("secrets are disabled")
+	ErrSecretNotFound    = errors.New("secret not found")
+	ErrSecretExists      = errors.New("secret already exists")
+	ErrInvalidSecretName = errors.New("invalid secret name")
+	// ErrInvalidSecretRef is returned when a task references a secret with an
+	// invalid name or under an invalid environment variable.
+	ErrInvalidSecretRef = errors.New("invalid secret reference")
+	// ErrNetworkModeNotAllowed is returned for a network mode the
+	// administrator hasn't allowed in DOCKER_NETWORK_MODES.
+	ErrNetworkModeNotAllowed = errors.New("network mode is not allowed")
+	ErrUnknownNetworkMode    = errors.New("unknown network mode")
+	// ErrDiskFull is returned when the workspace disk is too full to accept
+	// new tasks.
+	ErrDiskFull = errors.New("not enough free disk space")
+	// ErrInvalidSecurity is returned for invalid security overrides of a model.
+	ErrInvalidSecurity = errors.New("invalid security profile")
+	// ErrInvalidParams is returned when the params of a task are not a JSON object.
+	ErrInvalidParams = errors.New("params must be a JSON object")
 )
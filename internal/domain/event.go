@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaskActor is the component that changed the status of a task.
+type TaskActor string
+
+const (
+	ActorAPI       TaskActor = "api"
+	ActorWorker    TaskActor = "worker"
+	ActorScheduler TaskActor = "scheduler"
+	ActorGC        TaskActor = "gc"
+)
+
+// StatusChange tells who moves a task to another status and why. It is
+// recorded in the event log of the task.
+type StatusChange struct {
+	Actor TaskActor
+	// NodeID is the instance making the change.
+	NodeID string
+	Reason string
+}
+
+// TaskEvent is a recorded status transition of a task. From is empty for the
+// creation of the task.
+type TaskEvent struct {
+	ID        int64      `json:"id"`
+	TaskID    uuid.UUID  `json:"task_id"`
+	From      TaskStatus `json:"from,omitempty"`
+	To        TaskStatus `json:"to"`
+	Actor     TaskActor  `json:"actor"`
+	NodeID    string     `json:"node_id,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GCReport describes a single garbage collector run.
+type GCReport struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// ReclaimedTasks were taken over from nodes whose lease expired.
+	ReclaimedTasks    []uuid.UUID `json:"reclaimed_tasks"`
+	RequeuedTasks     []uuid.UUID `json:"requeued_tasks"`
+	RecoveredTasks    []uuid.UUID `json:"recovered_tasks"`
+	CompletedTasks    []uuid.UUID `json:"completed_tasks"`
+	FailedTasks       []uuid.UUID `json:"failed_tasks"`
+	RemovedContainers []string    `json:"removed_containers"`
+	RemovedWorkspaces []uuid.UUID `json:"removed_workspaces"`
+	Errors            []string    `json:"errors,omitempty"`
+}
+
+// WorkspaceInfo describes a task workspace directory.
+type WorkspaceInfo struct {
+	TaskID     uuid.UUID
+	ModifiedAt time.Time
+}
@@ -7,4 +7,10 @@ type Model struct {
 	ContainerImage string
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
+	// NetworkMode is the network of the tasks of the model, empty for the
+	// default of the service.
+	NetworkMode NetworkMode
+	// Security overrides the security profile of the tasks of the model, nil
+	// for the profile of the service.
+	Security *ModelSecurity
 }
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+)
+
+// NetworkMode is the network access of a task container.
+type NetworkMode string
+
+const (
+	// NetworkNone leaves the container without a network.
+	NetworkNone NetworkMode = "none"
+	// NetworkInternal attaches the container to a network without a route
+	// outside of the Docker host.
+	NetworkInternal NetworkMode = "internal"
+	// NetworkEgress attaches the container to the network whose outgoing
+	// traffic is limited to an allowlist by the operator.
+	NetworkEgress NetworkMode = "egress"
+	// NetworkBridge is the default Docker bridge with full network access.
+	NetworkBridge NetworkMode = "bridge"
+)
+
+var networkModes = []NetworkMode{NetworkNone, NetworkInternal, NetworkEgress, NetworkBridge}
+
+// ParseNetworkMode checks that s is a known network mode.
+func ParseNetworkMode(s string) (NetworkMode, error) {
+	if !slices.Contains(networkModes, NetworkMode(s)) {
+		return "", fmt.Errorf("%w: %q", ErrUnknownNetworkMode, s)
+	}
+	return NetworkMode(s), nil
+}
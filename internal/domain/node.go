@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// Node is a process executing tasks. Nodes register on start and send
+// heartbeats, a node whose heartbeat is older than the configured timeout is
+// considered dead.
+type Node struct {
+	ID          string            `json:"id"`
+	Hostname    string            `json:"hostname"`
+	Capacity    int               `json:"capacity"`
+	Labels      map[string]string `json:"labels"`
+	StartedAt   time.Time         `json:"started_at"`
+	HeartbeatAt time.Time         `json:"heartbeat_at"`
+	StoppedAt   *time.Time        `json:"stopped_at,omitempty"`
+	// RunningTasks is the number of running tasks claimed by the node.
+	RunningTasks int64 `json:"running_tasks"`
+	Alive        bool  `json:"alive"`
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ResultRef is a reference from a task to its stored result object.
+type ResultRef struct {
+	TaskID        uuid.UUID
+	Status        TaskStatus
+	ResultPath    string
+	ResultMissing bool
+}
+
+// ReconcileReport describes a single reconciliation between the tasks table
+// and the bucket. In a dry run nothing is deleted or marked.
+type ReconcileReport struct {
+	StartedAt       time.Time   `json:"started_at"`
+	FinishedAt      time.Time   `json:"finished_at"`
+	DryRun          bool        `json:"dry_run"`
+	ScannedObjects  int         `json:"scanned_objects"`
+	ScannedTasks    int         `json:"scanned_tasks"`
+	OrphanObjects   []string    `json:"orphan_objects"`
+	OrphanBytes     int64       `json:"orphan_bytes"`
+	DeletedObjects  []string    `json:"deleted_objects"`
+	MissingResults  []uuid.UUID `json:"missing_results"`
+	RestoredResults []uuid.UUID `json:"restored_results"`
+	Errors          []string    `json:"errors,omitempty"`
+}
@@ -1,16 +1,31 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type CreateTaskRequest struct {
-	ModelID       string     `json:"model_id"`
-	ContainerCmd  []string   `json:"container_cmd"`
-	ContainerEnvs []string   `json:"container_envs"`
-	CPULimit      int        `json:"cpu_limit"`
-	MemoryLimit   int        `json:"memory_limit"`
-	GPUEnabled    bool       `json:"gpu_enabled"`
-	ScheduledAt   *time.Time `json:"scheduled_at"`
-	TimeoutSec    int        `json:"timeout_sec"`
+	ModelID       string   `json:"model_id"`
+	ContainerCmd  []string `json:"container_cmd"`
+	ContainerEnvs []string `json:"container_envs"`
+	CPULimit      int      `json:"cpu_limit"`
+	MemoryLimit   int      `json:"memory_limit"`
+	// DiskLimit is the size in MB the task may write to its result.
+	DiskLimit   int        `json:"disk_limit"`
+	GPUEnabled  bool       `json:"gpu_enabled"`
+	ScheduledAt *time.Time `json:"scheduled_at"`
+	TimeoutSec  int        `json:"timeout_sec"`
+	KeepForSec  int        `json:"keep_for_sec"`
+	// Constraints are the labels a Docker host must have to run the task.
+	Constraints map[string]string `json:"constraints"`
+	// Secrets maps environment variables to the names of stored secrets. The
+	// values are set only in the container and never stored with the task.
+	Secrets map[string]string `json:"secrets"`
+	// NetworkMode overrides the network mode of the model.
+	NetworkMode string `json:"network_mode" enums:"none,internal,egress,bridge"`
+	// Params is a JSON object written to /app/input/params.json of the task.
+	Params json.RawMessage `json:"params,omitempty" swaggertype:"object"`
 }
 
 type CreateModelRequest struct {
@@ -22,3 +37,18 @@ type UpdateModelRequest struct {
 	ID             string `json:"id"`
 	ContainerImage string `json:"container_image"`
 }
+
+type CreateSecretRequest struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type UpdateSecretRequest struct {
+	Value string `json:"value"`
+}
+
+// SetModelNetworkRequest sets the network mode of the tasks of a model, an
+// empty mode falls back to the default of the service.
+type SetModelNetworkRequest struct {
+	NetworkMode string `json:"network_mode" enums:"none,internal,egress,bridge"`
+}
@@ -3,7 +3,16 @@ package domain
 import "time"
 
 type HealthResponse struct {
-	Status string `json:"status"`
+	Status string      `json:"status"`
+	Leader *LeaderInfo `json:"leader,omitempty"`
+}
+
+// LeaderInfo tells which of the instances sharing the database runs the
+// singleton jobs. LeaderID is empty while there is no leader.
+type LeaderInfo struct {
+	InstanceID string `json:"instance_id"`
+	LeaderID   string `json:"leader_id"`
+	IsLeader   bool   `json:"is_leader"`
 }
 
 type RunMockResponse struct {
@@ -20,11 +29,38 @@ type TaskStatusResponse struct {
 	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
 	ResultPath  string     `json:"result_path,omitempty"`
 	ErrLog      string     `json:"err_log,omitempty"`
+	Pinned      bool       `json:"pinned"`
+	KeepForSec  int        `json:"keep_for_sec,omitempty"`
+	// ResultMissing is set when the stored result object was not found in the bucket.
+	ResultMissing bool `json:"result_missing,omitempty"`
+	// UploadTotalBytes and UploadedBytes show the progress of the result upload.
+	UploadTotalBytes int64 `json:"upload_total_bytes,omitempty"`
+	UploadedBytes    int64 `json:"uploaded_bytes,omitempty"`
+	// UploadFailed is set for failed tasks whose result upload can be retried.
+	UploadFailed bool   `json:"upload_failed,omitempty"`
+	InputSHA256  string `json:"input_sha256,omitempty"`
+	// ResultCorrupted is set when the stored result doesn't match its checksums.
+	ResultCorrupted bool              `json:"result_corrupted,omitempty"`
+	Constraints     map[string]string `json:"constraints,omitempty"`
+	// ExitCode is the exit code of the container, absent if it never exited.
+	ExitCode *int `json:"exit_code,omitempty"`
+	// FailureReason tells why a failed or stopped task didn't complete.
+	FailureReason string `json:"failure_reason,omitempty" enums:"oom_killed,timeout,nonzero_exit,image_pull_failed,container_start_failed,upload_failed,user_cancelled,infrastructure,disk_quota_exceeded,attempts_exhausted"`
+	// Usage is the resources the task consumed, absent until its container exits.
+	Usage *ResourceUsage `json:"usage,omitempty"`
+	// NetworkMode is the network access the container of the task got.
+	NetworkMode string `json:"network_mode,omitempty"`
+	// DiskLimit is the size in MB the task may write to its result.
+	DiskLimit int `json:"disk_limit,omitempty"`
+	// Attempts is how many times the run of the task was lost and requeued.
+	Attempts int `json:"attempts,omitempty"`
 }
 
 type StatsResponse struct {
 	AvailableMemoryBytes uint64  `json:"available_memory_bytes"`
 	CPUUtilization       float64 `json:"cpu_utilization"`
+	// DockerHosts is empty when the instance doesn't execute tasks.
+	DockerHosts []DockerHost `json:"docker_hosts,omitempty"`
 }
 
 type GetAllTasksResponse struct {
@@ -33,3 +69,26 @@ type GetAllTasksResponse struct {
 	Page       int                  `json:"page"`
 	PageSize   int                  `json:"page_size"`
 }
+
+type TaskFileResponse struct {
+	Path         string    `json:"path"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"content_type"`
+	LastModified time.Time `json:"last_modified"`
+	SHA256       string    `json:"sha256,omitempty"`
+}
+
+type TaskFilesResponse struct {
+	Files []TaskFileResponse `json:"files"`
+}
+
+type TaskEventsResponse struct {
+	TaskID string      `json:"task_id"`
+	Events []TaskEvent `json:"events"`
+}
+
+type UsageReportResponse struct {
+	From time.Time        `json:"from"`
+	To   time.Time        `json:"to"`
+	Rows []UsageReportRow `json:"rows"`
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RetentionReason string
+
+const (
+	// RetentionExpired is used when a task outlived its keep_for or the max age for its status.
+	RetentionExpired RetentionReason = "expired"
+	// RetentionSizeLimit is used when a task was evicted to bring storage under the size limit.
+	RetentionSizeLimit RetentionReason = "size_limit"
+)
+
+type RetentionDeletion struct {
+	TaskID uuid.UUID       `json:"task_id"`
+	Status TaskStatus      `json:"status"`
+	Reason RetentionReason `json:"reason"`
+}
+
+// RetentionReport describes a single retention run.
+type RetentionReport struct {
+	StartedAt       time.Time           `json:"started_at"`
+	FinishedAt      time.Time           `json:"finished_at"`
+	DeletedTasks    []RetentionDeletion `json:"deleted_tasks"`
+	DeletedPrefixes []string            `json:"deleted_prefixes"`
+	FreedBytes      int64               `json:"freed_bytes"`
+	TotalBytes      int64               `json:"total_bytes"`
+	Errors          []string            `json:"errors,omitempty"`
+}
@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Misfire is a scheduled task promoted later than the misfire threshold.
+// Action is the misfire policy applied to it: run, skip or fail.
+type Misfire struct {
+	TaskID      uuid.UUID `json:"task_id"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	DetectedAt  time.Time `json:"detected_at"`
+	Action      string    `json:"action"`
+}
+
+// SchedulerReport describes the scheduler since the service started.
+type SchedulerReport struct {
+	LastRunAt time.Time `json:"last_run_at"`
+	// NextFiringAt is the earliest scheduled time of a task still waiting.
+	NextFiringAt *time.Time `json:"next_firing_at,omitempty"`
+	QueuedTasks  int64      `json:"queued_tasks"`
+	// Misfires lists the most recent misfires, newest first.
+	Misfires  []Misfire `json:"misfires"`
+	LastError string    `json:"last_error,omitempty"`
+}
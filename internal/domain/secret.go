@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var (
+	secretNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,127}$`)
+	envNameRe    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Secret is a stored secret. Its value is kept encrypted and is never
+// returned by the API.
+type Secret struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ValidateSecretName checks the name of a secret.
+func ValidateSecretName(name string) error {
+	if !secretNameRe.MatchString(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidSecretName, name)
+	}
+	return nil
+}
+
+// ValidateSecretRefs checks the secrets of a task, a map from the name of an
+// environment variable to the name of the secret it is set to.
+func ValidateSecretRefs(refs map[string]string) error {
+	for env, name := range refs {
+		if !envNameRe.MatchString(env) {
+			return fmt.Errorf("%w: invalid environment variable %q", ErrInvalidSecretRef, env)
+		}
+		if err := ValidateSecretName(name); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidSecretRef, err)
+		}
+	}
+	return nil
+}
+
+// SealedSecret is the value of a secret encrypted by the master key KeyID.
+type SealedSecret struct {
+	Name  string
+	KeyID string
+	Value []byte
+}
@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	capabilityRe    = regexp.MustCompile(`^(CAP_)?[A-Z_]+$`)
+	containerUserRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+(:[A-Za-z0-9_.-]+)?$`)
+)
+
+// SeccompUnconfined is the seccomp profile that disables seccomp.
+const SeccompUnconfined = "unconfined"
+
+// Ulimit is a resource limit of the processes of a container.
+type Ulimit struct {
+	Name string
+	Soft int64
+	Hard int64
+}
+
+// SecurityProfile is the hardening of a task container.
+type SecurityProfile struct {
+	// User runs the processes as user[:group], empty keeps the user of the image.
+	User string
+	// DropCapabilities drops all Linux capabilities but CapAdd.
+	DropCapabilities bool
+	CapAdd           []string
+	NoNewPrivileges  bool
+	// ReadOnlyRootfs mounts the root filesystem read-only with a tmpfs of
+	// TmpfsSizeMB on /tmp.
+	ReadOnlyRootfs bool
+	TmpfsSizeMB    int
+	// PidsLimit is the maximum number of processes, zero is unlimited.
+	PidsLimit int64
+	Ulimits   []Ulimit
+	// Seccomp is the path of a seccomp profile on the node, empty for the
+	// default profile of Docker or SeccompUnconfined.
+	Seccomp string
+	// GroupAdd are supplementary groups of the processes, the group of the
+	// workspace among them.
+	GroupAdd []string
+}
+
+// ModelSecurity overrides the security profile for the tasks of a model that
+// legitimately need more. Nil fields keep the profile of the service.
+type ModelSecurity struct {
+	// User runs the tasks as user[:group], empty keeps the user of the image.
+	User *string `json:"user,omitempty"`
+	// CapAdd are kept in addition to the capabilities of the service.
+	CapAdd            []string `json:"cap_add,omitempty"`
+	NoNewPrivileges   *bool    `json:"no_new_privileges,omitempty"`
+	ReadOnlyRootfs    *bool    `json:"read_only_rootfs,omitempty"`
+	PidsLimit         *int64   `json:"pids_limit,omitempty"`
+	SeccompUnconfined *bool    `json:"seccomp_unconfined,omitempty"`
+}
+
+// IsZero tells whether the model overrides nothing.
+func (m *ModelSecurity) IsZero() bool {
+	return m == nil || (m.User == nil && len(m.CapAdd) == 0 && m.NoNewPrivileges == nil &&
+		m.ReadOnlyRootfs == nil && m.PidsLimit == nil && m.SeccompUnconfined == nil)
+}
+
+// Validate checks the overrides of a model.
+func (m *ModelSecurity) Validate() error {
+	if m == nil {
+		return nil
+	}
+	if m.User != nil && *m.User != "" && !containerUserRe.MatchString(*m.User) {
+		return fmt.Errorf("%w: invalid user %q", ErrInvalidSecurity, *m.User)
+	}
+	for _, c := range m.CapAdd {
+		if !capabilityRe.MatchString(c) || strings.TrimPrefix(c, "CAP_") == "ALL" {
+			return fmt.Errorf("%w: invalid capability %q", ErrInvalidSecurity, c)
+		}
+	}
+	if m.PidsLimit != nil && *m.PidsLimit < 0 {
+		return fmt.Errorf("%w: negative pids limit", ErrInvalidSecurity)
+	}
+	return nil
+}
+
+// Apply returns the profile with the overrides of the model.
+func (p SecurityProfile) Apply(m *ModelSecurity) SecurityProfile {
+	if m == nil {
+		return p
+	}
+	if m.User != nil {
+		p.User = *m.User
+	}
+	if len(m.CapAdd) > 0 {
+		caps := slices.Clone(p.CapAdd)
+		for _, c := range m.CapAdd {
+			if !slices.Contains(caps, c) {
+				caps = append(caps, c)
+			}
+		}
+		p.CapAdd = caps
+	}
+	if m.NoNewPrivileges != nil {
+		p.NoNewPrivileges = *m.NoNewPrivileges
+	}
+	if m.ReadOnlyRootfs != nil {
+		p.ReadOnlyRootfs = *m.ReadOnlyRootfs
+	}
+	if m.PidsLimit != nil {
+		p.PidsLimit = *m.PidsLimit
+	}
+	if m.SeccompUnconfined != nil && *m.SeccompUnconfined {
+		p.Seccomp = SeccompUnconfined
+	}
+	return p
+}
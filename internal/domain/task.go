@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,8 +18,89 @@ const (
 	TaskFailed       TaskStatus = "failed"
 	TaskQueued       TaskStatus = "queued"
 	TaskStopped      TaskStatus = "stopped"
+	// TaskSkipped is a scheduled task that missed its schedule and wasn't run.
+	TaskSkipped TaskStatus = "skipped"
 )
 
+// ParamsFilename is the input file the params of a task are written to.
+const ParamsFilename = "params.json"
+
+// FailureReason tells why a task failed or was stopped.
+type FailureReason string
+
+const (
+	FailureOOMKilled      FailureReason = "oom_killed"
+	FailureTimeout        FailureReason = "timeout"
+	FailureNonzeroExit    FailureReason = "nonzero_exit"
+	FailureImagePull      FailureReason = "image_pull_failed"
+	FailureContainerStart FailureReason = "container_start_failed"
+	FailureUpload         FailureReason = "upload_failed"
+	FailureUserCancelled  FailureReason = "user_cancelled"
+	// FailureDiskQuota is a task whose result outgrew its disk limit.
+	FailureDiskQuota FailureReason = "disk_quota_exceeded"
+	// FailureAttemptsExhausted is a task whose run was lost and requeued too
+	// many times, e.g. because it keeps crashing its node.
+	FailureAttemptsExhausted FailureReason = "attempts_exhausted"
+	// FailureInfrastructure is a failure of the service itself: the database,
+	// the Docker daemon or a shutdown of the node.
+	FailureInfrastructure FailureReason = "infrastructure"
+)
+
+var failureReasons = []FailureReason{
+	FailureOOMKilled, FailureTimeout, FailureNonzeroExit, FailureImagePull,
+	FailureContainerStart, FailureUpload, FailureUserCancelled, FailureInfrastructure,
+	FailureDiskQuota, FailureAttemptsExhausted,
+}
+
+// ParseFailureReason checks that s is a known failure reason.
+func ParseFailureReason(s string) (FailureReason, error) {
+	if !slices.Contains(failureReasons, FailureReason(s)) {
+		return "", fmt.Errorf("%w: %q", ErrUnknownFailureReason, s)
+	}
+	return FailureReason(s), nil
+}
+
+// TaskFilter narrows a task listing. Zero fields don't filter.
+type TaskFilter struct {
+	FailureReason FailureReason
+}
+
+// taskTransitions lists the statuses a task may move to from each status.
+// Completed, stopped and skipped tasks are final.
+var taskTransitions = map[TaskStatus][]TaskStatus{
+	TaskInitializing: {TaskScheduled, TaskQueued, TaskCompleted, TaskFailed},
+	TaskScheduled:    {TaskQueued, TaskSkipped, TaskFailed, TaskStopped},
+	TaskQueued:       {TaskRunning, TaskFailed, TaskStopped},
+	// a running task is marked running again once its container is started,
+	// and queued again when the Docker hosts are busy or its node died
+	TaskRunning: {TaskRunning, TaskQueued, TaskCompleted, TaskFailed, TaskStopped},
+	// the kept result of an upload failed task is uploaded again
+	TaskFailed: {TaskCompleted, TaskFailed},
+}
+
+// CanTransition reports whether a task may move from one status to another.
+func CanTransition(from, to TaskStatus) bool {
+	return slices.Contains(taskTransitions[from], to)
+}
+
+// TransitionError is returned when a task can't be moved to a status. Err is
+// ErrInvalidTransition or ErrTaskConflict.
+type TransitionError struct {
+	TaskID uuid.UUID
+	// From is the status of the task when the transition was rejected.
+	From TaskStatus
+	To   TaskStatus
+	Err  error
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("task %s: %s -> %s: %v", e.TaskID, e.From, e.To, e.Err)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return e.Err
+}
+
 type Task struct {
 	ID             uuid.UUID
 	ModelID        string
@@ -39,6 +122,54 @@ type Task struct {
 	GPUEnabled     bool
 	CPULim         int
 	MemLim         int
+	Pinned         bool
+	KeepForSec     int
+	ResultMissing  bool
+	// UploadTotalBytes and UploadDoneBytes report the progress of the result upload.
+	UploadTotalBytes int64
+	UploadDoneBytes  int64
+	// UploadFailed is set when the container succeeded but its result couldn't
+	// be uploaded. The workspace is kept so the upload can be retried.
+	UploadFailed bool
+	// InputSHA256 is the hex encoded sha256 of the input file.
+	InputSHA256 string
+	// Params is the canonical JSON of the parameters of the task, written to
+	// ParamsFilename in its input dir. It is not stored with the task.
+	Params []byte
+	// ResultCorrupted is set when the stored result doesn't match its recorded
+	// checksums. Corrupted results are not reused by the cache.
+	ResultCorrupted bool
+	// NodeID is the node that picked the task from the queue.
+	NodeID string
+	// LeaseExpiresAt is renewed by the node while it processes the task. A
+	// running task with an expired lease is reclaimed by another node.
+	LeaseExpiresAt *time.Time
+	// Constraints are the labels a Docker host must have to run the task.
+	Constraints map[string]string
+	// Secrets maps environment variables to the names of the secrets they are
+	// set to when the container starts.
+	Secrets map[string]string
+	// NetworkMode is the network access the container of the task gets. It
+	// is chosen when the task is created and kept for auditing.
+	NetworkMode NetworkMode
+	// DiskLim is the size in MB the result of the task may take in its
+	// workspace, zero is unlimited.
+	DiskLim int
+	// Attempts is how many times the run of the task was lost and the task
+	// was queued again.
+	Attempts int
+	// RetryAt keeps a task queued again because its hosts were busy from
+	// being taken before that time.
+	RetryAt *time.Time
+	// ExitCode is the exit code of the container, nil if it never exited.
+	ExitCode *int
+	// FailureReason is set for failed and stopped tasks.
+	FailureReason FailureReason
+	// Usage is the resources the task consumed, nil until its container exits.
+	Usage *ResourceUsage
+	// Version is incremented on every status change. A status change is
+	// applied only if the task still has the version it was read with.
+	Version int
 }
 
 type RunningTasksContainer struct {
@@ -0,0 +1,56 @@
+package domain
+
+import "time"
+
+// ResourceUsage is what a task consumed, it is recorded once its container
+// exits. The memory figures are taken from Samples samples of the container
+// stats, the CPU, block and network totals from the last of them.
+type ResourceUsage struct {
+	MemPeakBytes    int64   `json:"mem_peak_bytes"`
+	MemAvgBytes     int64   `json:"mem_avg_bytes"`
+	CPUSeconds      float64 `json:"cpu_seconds"`
+	BlockReadBytes  int64   `json:"block_read_bytes"`
+	BlockWriteBytes int64   `json:"block_write_bytes"`
+	NetRxBytes      int64   `json:"net_rx_bytes"`
+	NetTxBytes      int64   `json:"net_tx_bytes"`
+	Samples         int     `json:"samples"`
+	// QueueSeconds is the time from the task becoming runnable to its start,
+	// RunSeconds the time from its start to the exit of the container.
+	QueueSeconds float64   `json:"queue_seconds"`
+	RunSeconds   float64   `json:"run_seconds"`
+	RecordedAt   time.Time `json:"recorded_at"`
+}
+
+// UsageReportRow aggregates the usage of the tasks of a model recorded in a
+// day (UTC). MemPeakBytes is the highest peak of the tasks, MemAvgBytes and
+// AvgQueueSeconds are averaged over the tasks, the rest are totals.
+type UsageReportRow struct {
+	ModelID         string    `json:"model_id"`
+	Day             time.Time `json:"day"`
+	Tasks           int64     `json:"tasks"`
+	MemPeakBytes    int64     `json:"mem_peak_bytes"`
+	MemAvgBytes     int64     `json:"mem_avg_bytes"`
+	CPUSeconds      float64   `json:"cpu_seconds"`
+	BlockReadBytes  int64     `json:"block_read_bytes"`
+	BlockWriteBytes int64     `json:"block_write_bytes"`
+	NetRxBytes      int64     `json:"net_rx_bytes"`
+	NetTxBytes      int64     `json:"net_tx_bytes"`
+	AvgQueueSeconds float64   `json:"avg_queue_seconds"`
+	RunSeconds      float64   `json:"run_seconds"`
+}
+
+// TaskStats is a live sample of the resources used by a running task. The CPU
+// percents use the units of the task cpu limit, 100 is one thread, a zero
+// limit is unlimited. The block and network counters are cumulative.
+type TaskStats struct {
+	ReadAt           time.Time `json:"read_at"`
+	CPUPercent       float64   `json:"cpu_percent"`
+	CPULimitPercent  int       `json:"cpu_limit_percent"`
+	MemoryBytes      uint64    `json:"memory_bytes"`
+	MemoryLimitBytes uint64    `json:"memory_limit_bytes"`
+	MemoryPercent    float64   `json:"memory_percent"`
+	BlockReadBytes   uint64    `json:"block_read_bytes"`
+	BlockWriteBytes  uint64    `json:"block_write_bytes"`
+	NetRxBytes       uint64    `json:"net_rx_bytes"`
+	NetTxBytes       uint64    `json:"net_tx_bytes"`
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ChecksumStatus string
+
+const (
+	// ChecksumOK means the stored object matches its recorded checksum.
+	ChecksumOK ChecksumStatus = "ok"
+	// ChecksumMismatch means the stored object differs from what was uploaded.
+	ChecksumMismatch ChecksumStatus = "mismatch"
+	// ChecksumMissing means the object has a recorded checksum but is gone.
+	ChecksumMissing ChecksumStatus = "missing"
+	// ChecksumUnrecorded means the object was uploaded before checksums were
+	// recorded, so it can't be verified.
+	ChecksumUnrecorded ChecksumStatus = "unrecorded"
+)
+
+// FileVerification is the result of checking a single result object.
+type FileVerification struct {
+	Path     string         `json:"path"`
+	Size     int64          `json:"size"`
+	Expected string         `json:"expected_sha256,omitempty"`
+	Actual   string         `json:"actual_sha256,omitempty"`
+	Status   ChecksumStatus `json:"status"`
+}
+
+// VerifyReport is the result of re-reading the stored result of a task and
+// comparing it against the checksums recorded on upload. OK is false if any
+// object is corrupted or missing.
+type VerifyReport struct {
+	TaskID    uuid.UUID          `json:"task_id"`
+	CheckedAt time.Time          `json:"checked_at"`
+	OK        bool               `json:"ok"`
+	Files     []FileVerification `json:"files"`
+}
@@ -0,0 +1,331 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// ─────────────────────────────────────────────
+// HELPERS
+// ─────────────────────────────────────────────
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func newTestKeyring(t *testing.T) *Keyring {
+	t.Helper()
+	k, err := NewKeyring("k1", map[string][]byte{"k1": testKey(1)})
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	return k
+}
+
+func newTestDataKey(t *testing.T) *DataKey {
+	t.Helper()
+	dk, err := newTestKeyring(t).NewDataKey()
+	if err != nil {
+		t.Fatalf("NewDataKey: %v", err)
+	}
+	return dk
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// seal returns the whole ciphertext of plain and its nonce.
+func seal(t *testing.T, dk *DataKey, key string, plain []byte) ([]byte, []byte) {
+	t.Helper()
+	enc, err := dk.NewEncryptor(key, bytes.NewReader(plain), int64(len(plain)))
+	if err != nil {
+		t.Fatalf("NewEncryptor: %v", err)
+	}
+	sealed, err := io.ReadAll(io.NewSectionReader(enc, 0, enc.Size()))
+	if err != nil {
+		t.Fatalf("reading ciphertext: %v", err)
+	}
+	if int64(len(sealed)) != CiphertextSize(int64(len(plain))) {
+		t.Fatalf("expected %d ciphertext bytes, got %d", CiphertextSize(int64(len(plain))), len(sealed))
+	}
+	return sealed, enc.Nonce()
+}
+
+// ─────────────────────────────────────────────
+// Keyring
+// ─────────────────────────────────────────────
+
+func TestLoadKeyring(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	content := `{"active":"new","keys":{"old":"` + base64.StdEncoding.EncodeToString(testKey(1)) +
+		`","new":"` + base64.StdEncoding.EncodeToString(testKey(2)) + `"}}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := LoadKeyring(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if k.ActiveKeyID() != "new" {
+		t.Errorf("expected active key new, got %q", k.ActiveKeyID())
+	}
+}
+
+func TestLoadKeyring_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"not json":       `{`,
+		"missing active": `{"active":"x","keys":{"k":"` + base64.StdEncoding.EncodeToString(testKey(1)) + `"}}`,
+		"short key":      `{"active":"k","keys":{"k":"` + base64.StdEncoding.EncodeToString([]byte("short")) + `"}}`,
+		"not base64":     `{"active":"k","keys":{"k":"!!"}}`,
+	}
+	for name, content := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keyring.json")
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadKeyring(path); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestKeyring_UnwrapAfterRotation(t *testing.T) {
+	old, _ := NewKeyring("k1", map[string][]byte{"k1": testKey(1)})
+	dk, err := old.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, _ := NewKeyring("k2", map[string][]byte{"k1": testKey(1), "k2": testKey(2)})
+	unwrapped, err := rotated.Unwrap(dk.KeyID, dk.Wrapped)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(unwrapped.key, dk.key) {
+		t.Error("unwrapped key differs")
+	}
+
+	fresh, _ := rotated.NewDataKey()
+	if fresh.KeyID != "k2" {
+		t.Errorf("expected new data keys wrapped by k2, got %q", fresh.KeyID)
+	}
+}
+
+func TestKeyring_UnwrapErrors(t *testing.T) {
+	k := newTestKeyring(t)
+	dk, _ := k.NewDataKey()
+
+	if _, err := k.Unwrap("k9", dk.Wrapped); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+
+	tampered := bytes.Clone(dk.Wrapped)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := k.Unwrap("k1", tampered); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+
+	other, _ := NewKeyring("k1", map[string][]byte{"k1": testKey(7)})
+	if _, err := other.Unwrap("k1", dk.Wrapped); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey for another master key, got %v", err)
+	}
+}
+
+func TestKeyring_Fingerprint(t *testing.T) {
+	k := newTestKeyring(t)
+
+	a, _ := k.Fingerprint("k1", "abc")
+	b, _ := k.Fingerprint("k1", "abc")
+	c, _ := k.Fingerprint("k1", "abd")
+	if a != b || a == c || a == "abc" {
+		t.Errorf("unexpected fingerprints %q %q %q", a, b, c)
+	}
+}
+
+// ─────────────────────────────────────────────
+// Stream
+// ─────────────────────────────────────────────
+
+func TestKeyring_SealOpen(t *testing.T) {
+	k := newTestKeyring(t)
+
+	keyID, sealed, err := k.Seal([]byte("s3cr3t"), []byte("db-password"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if keyID != "k1" {
+		t.Errorf("expected key k1, got %q", keyID)
+	}
+	if bytes.Contains(sealed, []byte("s3cr3t")) {
+		t.Error("sealed value contains the plaintext")
+	}
+
+	rotated, err := NewKeyring("k2", map[string][]byte{"k1": testKey(1), "k2": testKey(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := rotated.Open(keyID, sealed, []byte("db-password"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if string(plain) != "s3cr3t" {
+		t.Errorf("expected s3cr3t, got %q", plain)
+	}
+}
+
+func TestKeyring_OpenErrors(t *testing.T) {
+	k := newTestKeyring(t)
+	_, sealed, err := k.Seal([]byte("s3cr3t"), []byte("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := k.Open("k9", sealed, []byte("a")); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+	// a value moved to another owner must not open
+	if _, err := k.Open("k1", sealed, []byte("b")); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("expected ErrAuthFailed for another ad, got %v", err)
+	}
+	if _, err := k.Open("k1", sealed[:10], []byte("a")); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("expected ErrInvalidSize, got %v", err)
+	}
+}
+
+func TestSizes(t *testing.T) {
+	for _, plain := range []int64{0, 1, SegmentSize - 1, SegmentSize, SegmentSize + 1, 5*SegmentSize + 3} {
+		got, err := PlaintextSize(CiphertextSize(plain))
+		if err != nil || got != plain {
+			t.Errorf("size %d: got %d, %v", plain, got, err)
+		}
+	}
+
+	if _, err := PlaintextSize(Overhead - 1); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("expected ErrInvalidSize, got %v", err)
+	}
+	if _, err := PlaintextSize(sealedSegmentSize + 3); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("expected ErrInvalidSize, got %v", err)
+	}
+}
+
+func TestStream_RoundTrip(t *testing.T) {
+	dk := newTestDataKey(t)
+
+	for _, size := range []int{0, 1, SegmentSize - 1, SegmentSize, SegmentSize + 1, 3*SegmentSize + 5} {
+		plain := randomBytes(t, size)
+		sealed, nonce := seal(t, dk, "tasks/x/result.bin", plain)
+
+		dec, err := dk.NewDecryptor("tasks/x/result.bin", nonce, bytes.NewReader(sealed), int64(len(sealed)))
+		if err != nil {
+			t.Fatalf("size %d: NewDecryptor: %v", size, err)
+		}
+		if dec.Size() != int64(size) {
+			t.Errorf("size %d: decryptor reports %d", size, dec.Size())
+		}
+
+		got, err := io.ReadAll(dec)
+		if err != nil {
+			t.Fatalf("size %d: reading plaintext: %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Errorf("size %d: plaintext differs", size)
+		}
+	}
+}
+
+func TestDecryptor_SeekAndReadAt(t *testing.T) {
+	dk := newTestDataKey(t)
+	plain := randomBytes(t, 2*SegmentSize+100)
+	sealed, nonce := seal(t, dk, "k", plain)
+
+	dec, err := dk.NewDecryptor("k", nonce, bytes.NewReader(sealed), int64(len(sealed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a range spanning a segment boundary
+	buf := make([]byte, 200)
+	if _, err := dec.ReadAt(buf, SegmentSize-100); err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	if !bytes.Equal(buf, plain[SegmentSize-100:SegmentSize+100]) {
+		t.Error("ReadAt returned wrong bytes")
+	}
+
+	if _, err := dec.Seek(-50, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	tail, err := io.ReadAll(dec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tail, plain[len(plain)-50:]) {
+		t.Error("read after seek returned wrong bytes")
+	}
+
+	if n, err := dec.ReadAt(buf, int64(len(plain))-10); n != 10 || err != io.EOF {
+		t.Errorf("expected 10 bytes and EOF, got %d, %v", n, err)
+	}
+}
+
+func TestDecryptor_DetectsTampering(t *testing.T) {
+	dk := newTestDataKey(t)
+	plain := randomBytes(t, 2*SegmentSize+10)
+	sealed, nonce := seal(t, dk, "k", plain)
+
+	read := func(key string, nonce, data []byte) error {
+		dec, err := dk.NewDecryptor(key, nonce, bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return err
+		}
+		_, err = io.ReadAll(dec)
+		return err
+	}
+
+	flipped := bytes.Clone(sealed)
+	flipped[SegmentSize+5] ^= 1
+	if err := read("k", nonce, flipped); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("flipped bit: expected ErrAuthFailed, got %v", err)
+	}
+
+	// dropping the last segment leaves a valid size
+	truncated := sealed[:2*sealedSegmentSize]
+	if err := read("k", nonce, truncated); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("truncated: expected ErrAuthFailed, got %v", err)
+	}
+
+	swapped := append(bytes.Clone(sealed[sealedSegmentSize:2*sealedSegmentSize]), sealed[:sealedSegmentSize]...)
+	swapped = append(swapped, sealed[2*sealedSegmentSize:]...)
+	if err := read("k", nonce, swapped); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("swapped segments: expected ErrAuthFailed, got %v", err)
+	}
+
+	if err := read("other", nonce, sealed); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("moved object: expected ErrAuthFailed, got %v", err)
+	}
+}
+
+func TestDecryptor_EmptyPlaintextIsAuthenticated(t *testing.T) {
+	dk := newTestDataKey(t)
+	sealed, nonce := seal(t, dk, "k", nil)
+
+	forged := make([]byte, len(sealed))
+	if _, err := dk.NewDecryptor("k", nonce, bytes.NewReader(forged), int64(len(forged))); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("expected ErrAuthFailed, got %v", err)
+	}
+}
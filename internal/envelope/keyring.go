@@ -0,0 +1,210 @@
+// Package envelope implements envelope encryption of stored objects. Every
+// object is encrypted with AES-256-GCM under a data key, the data key is
+// wrapped by a master key from a local keyring and kept next to the object.
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// KeySize is the size of master and data keys, AES-256 is used.
+const KeySize = 32
+
+var (
+	ErrUnknownKey  = errors.New("unknown master key")
+	ErrInvalidKey  = errors.New("invalid wrapped data key")
+	ErrAuthFailed  = errors.New("message authentication failed")
+	ErrInvalidSize = errors.New("invalid ciphertext size")
+)
+
+// keyringFile is the format of the keyring file. Keys are base64 encoded,
+// new data keys are wrapped by the active one. Old keys are kept to unwrap
+// the data keys of objects written before a rotation.
+type keyringFile struct {
+	Active string            `json:"active"`
+	Keys   map[string]string `json:"keys"`
+}
+
+// Keyring holds the master keys.
+type Keyring struct {
+	active string
+	keys   map[string][]byte
+}
+
+// LoadKeyring reads the keyring from a JSON file.
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading keyring: %w", err)
+	}
+
+	var file keyringFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing keyring: %w", err)
+	}
+
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("decoding key %q: %w", id, err)
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("key %q must be %d bytes, got %d", id, KeySize, len(key))
+		}
+		keys[id] = key
+	}
+
+	return NewKeyring(file.Active, keys)
+}
+
+// NewKeyring creates a keyring from raw master keys.
+func NewKeyring(active string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("active key %q is not in the keyring", active)
+	}
+	return &Keyring{active: active, keys: keys}, nil
+}
+
+// DataKey is a key encrypting the objects of one task. Wrapped is the key
+// encrypted by the master key KeyID and is safe to store with the objects.
+type DataKey struct {
+	KeyID   string
+	Wrapped []byte
+	key     []byte
+}
+
+// NewDataKey generates a data key wrapped by the active master key.
+func (k *Keyring) NewDataKey() (*DataKey, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generating data key: %w", err)
+	}
+
+	aead, err := newGCM(k.keys[k.active])
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+
+	wrapped := aead.Seal(nonce, nonce, key, wrapAAD(k.active))
+
+	return &DataKey{KeyID: k.active, Wrapped: wrapped, key: key}, nil
+}
+
+// Unwrap decrypts a data key wrapped by the master key keyID.
+func (k *Keyring) Unwrap(keyID string, wrapped []byte) (*DataKey, error) {
+	master, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+
+	aead, err := newGCM(master)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, ErrInvalidKey
+	}
+
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	key, err := aead.Open(nil, nonce, sealed, wrapAAD(keyID))
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	return &DataKey{KeyID: keyID, Wrapped: wrapped, key: key}, nil
+}
+
+// Fingerprint returns a keyed hash of the checksum of a plaintext, so equal
+// content can be recognized without storing its plain checksum next to the
+// ciphertext.
+func (k *Keyring) Fingerprint(keyID, checksum string) (string, error) {
+	master, ok := k.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+
+	mac := hmac.New(sha256.New, master)
+	mac.Write([]byte("pinn-fingerprint/" + checksum))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Seal encrypts a small value, e.g. a secret, directly with the active
+// master key. The additional data ad binds the ciphertext to its owner, the
+// same ad must be passed to Open.
+func (k *Keyring) Seal(plaintext, ad []byte) (keyID string, sealed []byte, err error) {
+	aead, err := newGCM(k.keys[k.active])
+	if err != nil {
+		return "", nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, fmt.Errorf("generating nonce: %w", err)
+	}
+
+	return k.active, aead.Seal(nonce, nonce, plaintext, sealAAD(ad)), nil
+}
+
+// Open decrypts a value sealed by Seal with the master key keyID.
+func (k *Keyring) Open(keyID string, sealed, ad []byte) ([]byte, error) {
+	master, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+
+	aead, err := newGCM(master)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrInvalidSize
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, sealAAD(ad))
+	if err != nil {
+		return nil, ErrAuthFailed
+	}
+	return plaintext, nil
+}
+
+// ActiveKeyID returns the ID of the master key wrapping new data keys.
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+func wrapAAD(keyID string) []byte {
+	return []byte("pinn-data-key/" + keyID)
+}
+
+func sealAAD(ad []byte) []byte {
+	return append([]byte("pinn-sealed/"), ad...)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("creating gcm: %w", err)
+	}
+	return aead, nil
+}
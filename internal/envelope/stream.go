@@ -0,0 +1,299 @@
+package envelope
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	// SegmentSize is the size of the plaintext sealed at once. Objects are
+	// split into segments, so any range can be read without reading the
+	// whole object.
+	SegmentSize = 64 << 10
+	// Overhead is the number of bytes every segment grows by.
+	Overhead = 16
+
+	// NonceSize is the size of the per object nonce prefix.
+	NonceSize = 8
+
+	sealedSegmentSize = SegmentSize + Overhead
+)
+
+// CiphertextSize returns the stored size of a plaintext of the given size.
+// An empty plaintext is stored as a single empty segment.
+func CiphertextSize(plain int64) int64 {
+	return plain + segmentsCount(plain)*Overhead
+}
+
+// PlaintextSize returns the size of the plaintext stored as a ciphertext of
+// the given size.
+func PlaintextSize(cipher int64) (int64, error) {
+	segments := (cipher + sealedSegmentSize - 1) / sealedSegmentSize
+	plain := cipher - segments*Overhead
+	if plain < 0 || CiphertextSize(plain) != cipher {
+		return 0, ErrInvalidSize
+	}
+	return plain, nil
+}
+
+func segmentsCount(plain int64) int64 {
+	return max(1, (plain+SegmentSize-1)/SegmentSize)
+}
+
+// stream seals the segments of one object. Every segment has its own nonce
+// and is bound to the object key, its position and whether it is the last
+// one, so segments can't be reordered, moved to another object or dropped
+// from the end.
+type stream struct {
+	aead      cipher.AEAD
+	prefix    []byte
+	objectKey string
+	segments  int64
+}
+
+func (d *DataKey) newStream(objectKey string, prefix []byte, plainSize int64) (*stream, error) {
+	if len(prefix) != NonceSize {
+		return nil, fmt.Errorf("nonce must be %d bytes, got %d", NonceSize, len(prefix))
+	}
+
+	aead, err := newGCM(d.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stream{
+		aead:      aead,
+		prefix:    prefix,
+		objectKey: objectKey,
+		segments:  segmentsCount(plainSize),
+	}, nil
+}
+
+func (s *stream) nonce(i int64) []byte {
+	nonce := make([]byte, 0, s.aead.NonceSize())
+	nonce = append(nonce, s.prefix...)
+	return binary.BigEndian.AppendUint32(nonce, uint32(i))
+}
+
+func (s *stream) aad(i int64) []byte {
+	aad := make([]byte, 0, len(s.objectKey)+10)
+	aad = append(aad, s.objectKey...)
+	aad = append(aad, 0)
+	aad = binary.BigEndian.AppendUint64(aad, uint64(i))
+	if i == s.segments-1 {
+		return append(aad, 1)
+	}
+	return append(aad, 0)
+}
+
+// segmentCache keeps the last produced segment, readers usually read a
+// segment in several calls.
+type segmentCache struct {
+	mu    sync.Mutex
+	index int64
+	data  []byte
+}
+
+// get returns the segment i, calling load on a miss. Returned slices are
+// never modified, so they can be used after the lock is released.
+func (c *segmentCache) get(i int64, load func(int64) ([]byte, error)) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.data != nil && c.index == i {
+		return c.data, nil
+	}
+
+	data, err := load(i)
+	if err != nil {
+		return nil, err
+	}
+	c.index, c.data = i, data
+
+	return data, nil
+}
+
+// readAt fills p from the content split into segments of segmentLen bytes.
+func readAt(p []byte, off, size, segmentLen int64, segment func(int64) ([]byte, error)) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	n := 0
+	for n < len(p) {
+		if off >= size {
+			return n, io.EOF
+		}
+
+		i := off / segmentLen
+		data, err := segment(i)
+		if err != nil {
+			return n, err
+		}
+
+		copied := copy(p[n:], data[off-i*segmentLen:])
+		n += copied
+		off += int64(copied)
+	}
+
+	return n, nil
+}
+
+// readFull reads exactly n bytes at off, tolerating io.EOF on the last byte.
+func readFull(src io.ReaderAt, off, n int64) ([]byte, error) {
+	buf := make([]byte, n)
+	read, err := src.ReadAt(buf, off)
+	if int64(read) == n {
+		return buf, nil
+	}
+	if err == nil || errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return nil, err
+}
+
+// Encryptor exposes the ciphertext of a plaintext as an io.ReaderAt.
+// Segments are sealed on demand, nothing is buffered besides the last one.
+// It is safe for concurrent use.
+type Encryptor struct {
+	stream *stream
+	src    io.ReaderAt
+	size   int64
+	cache  segmentCache
+}
+
+// NewEncryptor encrypts size bytes of src stored under objectKey with a
+// fresh nonce.
+func (d *DataKey) NewEncryptor(objectKey string, src io.ReaderAt, size int64) (*Encryptor, error) {
+	prefix := make([]byte, NonceSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+
+	s, err := d.newStream(objectKey, prefix, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Encryptor{stream: s, src: src, size: size}, nil
+}
+
+// Nonce returns the nonce prefix that has to be stored with the object.
+func (e *Encryptor) Nonce() []byte {
+	return e.stream.prefix
+}
+
+// Size returns the size of the ciphertext.
+func (e *Encryptor) Size() int64 {
+	return CiphertextSize(e.size)
+}
+
+func (e *Encryptor) ReadAt(p []byte, off int64) (int, error) {
+	return readAt(p, off, e.Size(), sealedSegmentSize, func(i int64) ([]byte, error) {
+		return e.cache.get(i, e.seal)
+	})
+}
+
+func (e *Encryptor) seal(i int64) ([]byte, error) {
+	offset := i * SegmentSize
+	plain, err := readFull(e.src, offset, min(SegmentSize, e.size-offset))
+	if err != nil {
+		return nil, fmt.Errorf("reading plaintext: %w", err)
+	}
+
+	return e.stream.aead.Seal(nil, e.stream.nonce(i), plain, e.stream.aad(i)), nil
+}
+
+// Decryptor reads the plaintext of a stored ciphertext. Every segment is
+// authenticated before it is returned, a modified ciphertext fails with
+// ErrAuthFailed. ReadAt is safe for concurrent use, Read and Seek are not.
+type Decryptor struct {
+	stream *stream
+	src    io.ReaderAt
+	size   int64
+	pos    int64
+	cache  segmentCache
+}
+
+// NewDecryptor decrypts the ciphertext of size bytes read from src, stored
+// under objectKey with the given nonce.
+func (d *DataKey) NewDecryptor(objectKey string, nonce []byte, src io.ReaderAt, size int64) (*Decryptor, error) {
+	plain, err := PlaintextSize(size)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := d.newStream(objectKey, nonce, plain)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := &Decryptor{stream: s, src: src, size: plain}
+
+	// an empty plaintext is never read, its only segment is checked here
+	if plain == 0 {
+		if _, err := dec.open(0); err != nil {
+			return nil, err
+		}
+	}
+
+	return dec, nil
+}
+
+// Size returns the size of the plaintext.
+func (d *Decryptor) Size() int64 {
+	return d.size
+}
+
+func (d *Decryptor) ReadAt(p []byte, off int64) (int, error) {
+	return readAt(p, off, d.size, SegmentSize, func(i int64) ([]byte, error) {
+		return d.cache.get(i, d.open)
+	})
+}
+
+func (d *Decryptor) Read(p []byte) (int, error) {
+	n, err := d.ReadAt(p, d.pos)
+	d.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (d *Decryptor) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += d.pos
+	case io.SeekEnd:
+		offset += d.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	d.pos = offset
+	return offset, nil
+}
+
+func (d *Decryptor) open(i int64) ([]byte, error) {
+	offset := i * sealedSegmentSize
+	sealed, err := readFull(d.src, offset, min(sealedSegmentSize, CiphertextSize(d.size)-offset))
+	if err != nil {
+		return nil, fmt.Errorf("reading ciphertext: %w", err)
+	}
+
+	plain, err := d.stream.aead.Open(nil, d.stream.nonce(i), sealed, d.stream.aad(i))
+	if err != nil {
+		return nil, ErrAuthFailed
+	}
+
+	return plain, nil
+}
@@ -2,9 +2,13 @@ package gc
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"pinn-connect-service/internal/config"
 	"pinn-connect-service/internal/domain"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -12,12 +16,17 @@ import (
 type Repository interface {
 	GetRunningTasks(context.Context) ([]*domain.Task, error)
 	GetActiveTasks(context.Context) ([]*domain.Task, error)
-	Mark(context.Context, *domain.Task, domain.TaskStatus) error
+	GetStaleTasks(ctx context.Context, status domain.TaskStatus, before time.Time) ([]*domain.Task, error)
+	GetUploadFailedTasks(context.Context) ([]*domain.Task, error)
+	Mark(context.Context, *domain.Task, domain.TaskStatus, domain.StatusChange) error
+	SaveArtifactChecksums(ctx context.Context, taskID uuid.UUID, objects []domain.Artifact) error
+	ReclaimExpiredTasks(ctx context.Context, nodeID string, lease time.Duration) ([]*domain.Task, error)
 }
 
 type Workspace interface {
 	Cleanup(taskID uuid.UUID) error
 	ResultDir(taskID uuid.UUID) string
+	List() ([]domain.WorkspaceInfo, error)
 }
 
 type ContainerManager interface {
@@ -28,114 +37,383 @@ type ContainerManager interface {
 }
 
 type Storage interface {
-	UploadToStorage(ctx context.Context, taskID uuid.UUID, resultDir string) (string, error)
+	UploadToStorage(ctx context.Context, taskID uuid.UUID, resultDir string, progress domain.UploadProgressFunc) (*domain.UploadResult, error)
 }
 
 type TaskService interface {
 	RecoverTask(*domain.Task)
+	// IsProcessing reports whether the task is handled by a worker of this instance.
+	IsProcessing(uuid.UUID) bool
 }
 
+// GarbageCollector cleans up after the node it runs on: it only handles
+// running tasks claimed by the node and containers started by it. Stuck
+// initializing tasks and workspaces are shared by all nodes, running tasks
+// whose lease expired are reclaimed by the first node to find them.
 type GarbageCollector struct {
 	repo             Repository
 	workspace        Workspace
 	containerManager ContainerManager
 	storage          Storage
 	taskService      TaskService
+	config           config.GCConfig
+	// lease is how long a reclaimed task is leased, the same as the lease of
+	// a task taken by a worker
+	lease  time.Duration
+	nodeID string
+
+	runMu    sync.Mutex
+	reportMu sync.RWMutex
+	last     *domain.GCReport
+
+	now func() time.Time
 }
 
 func NewGarbageCollector(repo Repository, workspace Workspace, containerManager ContainerManager,
-	storage Storage, taskService TaskService) *GarbageCollector {
+	storage Storage, taskService TaskService, cfg config.GCConfig, lease time.Duration, nodeID string) *GarbageCollector {
 	return &GarbageCollector{
 		repo:             repo,
 		workspace:        workspace,
 		containerManager: containerManager,
 		storage:          storage,
 		taskService:      taskService,
+		config:           cfg,
+		lease:            lease,
+		nodeID:           nodeID,
+		now:              time.Now,
 	}
 }
 
-// Cleanup starts a cleanup for dead running tasks.
-// It is a blocking call, not async.
-func (gc *GarbageCollector) Cleanup(ctx context.Context) {
+// run collects the results of a single Cleanup call. Tasks are cleaned up
+// concurrently, so every report update goes through the mutex.
+type run struct {
+	mu     sync.Mutex
+	report *domain.GCReport
+}
+
+func (r *run) add(f func(*domain.GCReport)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f(r.report)
+}
+
+func (r *run) fail(msg string, err error) {
+	slog.Error("gc: "+msg, "error", err)
+	r.add(func(rep *domain.GCReport) { rep.Errors = append(rep.Errors, fmt.Sprintf("%s: %v", msg, err)) })
+}
+
+// failMark reports a failed status change of the task. A task changed or
+// deleted since it was read is left to whoever changed it.
+func (r *run) failMark(msg string, task *domain.Task, err error) {
+	var terr *domain.TransitionError
+	if errors.As(err, &terr) || errors.Is(err, domain.ErrTaskNotFound) {
+		slog.Info("gc: task changed meanwhile, skipping", "id", task.ID, "reason", err)
+		return
+	}
+	r.fail(msg, err)
+}
+
+// Start runs Cleanup every configured interval and Reclaim every reclaim
+// interval until ctx is done.
+func (gc *GarbageCollector) Start(ctx context.Context, wg *sync.WaitGroup) {
+	ticker := time.NewTicker(gc.config.Interval)
+	reclaimTicker := time.NewTicker(gc.config.ReclaimInterval)
+
+	wg.Go(func() {
+		defer ticker.Stop()
+		defer reclaimTicker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-reclaimTicker.C:
+				runCtx, cancel := context.WithTimeout(ctx, gc.config.Timeout)
+				report := gc.Reclaim(runCtx)
+				cancel()
+
+				if len(report.ReclaimedTasks) > 0 || len(report.Errors) > 0 {
+					slog.Info("gc: reclaim finished",
+						"reclaimed", len(report.ReclaimedTasks),
+						"requeued", len(report.RequeuedTasks),
+						"recovered", len(report.RecoveredTasks),
+						"errors", len(report.Errors),
+					)
+				}
+			case <-ticker.C:
+				runCtx, cancel := context.WithTimeout(ctx, gc.config.Timeout)
+				report := gc.Cleanup(runCtx)
+				cancel()
+
+				slog.Info("gc: run finished",
+					"requeued", len(report.RequeuedTasks),
+					"recovered", len(report.RecoveredTasks),
+					"failed", len(report.FailedTasks),
+					"removed_containers", len(report.RemovedContainers),
+					"removed_workspaces", len(report.RemovedWorkspaces),
+					"errors", len(report.Errors),
+				)
+			}
+		}
+	})
+}
+
+// LastReport returns the report of the last finished run or nil if there was none.
+func (gc *GarbageCollector) LastReport() *domain.GCReport {
+	gc.reportMu.RLock()
+	defer gc.reportMu.RUnlock()
+	return gc.last
+}
+
+// Cleanup starts a cleanup for dead running tasks, stuck tasks, orphan
+// containers and orphan workspaces. Tasks processed by this instance are
+// skipped. It is a blocking call, not async.
+func (gc *GarbageCollector) Cleanup(ctx context.Context) *domain.GCReport {
+	gc.runMu.Lock()
+	defer gc.runMu.Unlock()
+
+	r := gc.newRun()
+	defer func() {
+		r.report.FinishedAt = gc.now()
+		gc.reportMu.Lock()
+		gc.last = r.report
+		gc.reportMu.Unlock()
+	}()
+
+	gc.reclaim(ctx, r)
+
 	var wg sync.WaitGroup
 
 	runningTasks, err := gc.repo.GetRunningTasks(ctx)
 	if err != nil {
-		slog.Error("gc: getting running task", "error", err)
-		return
+		r.fail("getting running task", err)
+		return r.report
 	}
 
 	for _, task := range runningTasks {
-		wg.Go(func() { gc.cleanupTask(ctx, task) })
+		if !gc.owns(task.NodeID) || gc.taskService.IsProcessing(task.ID) {
+			continue
+		}
+		wg.Go(func() { gc.cleanupTask(ctx, r, task) })
 	}
 
-	gc.cleanupOrphans(ctx)
+	gc.cleanupStuck(ctx, r)
+	gc.cleanupOrphans(ctx, r)
 
 	wg.Wait()
+
+	gc.cleanupWorkspaces(ctx, r)
+
+	return r.report
 }
 
-func (gc *GarbageCollector) cleanupTask(ctx context.Context, task *domain.Task) {
+// Reclaim takes over the running tasks whose lease expired because their node
+// died or lost the database. Unlike Cleanup its report is not kept as the last
+// report.
+func (gc *GarbageCollector) Reclaim(ctx context.Context) *domain.GCReport {
+	gc.runMu.Lock()
+	defer gc.runMu.Unlock()
+
+	r := gc.newRun()
+	gc.reclaim(ctx, r)
+	r.report.FinishedAt = gc.now()
+
+	return r.report
+}
+
+func (gc *GarbageCollector) newRun() *run {
+	return &run{report: &domain.GCReport{
+		StartedAt:         gc.now(),
+		ReclaimedTasks:    []uuid.UUID{},
+		RequeuedTasks:     []uuid.UUID{},
+		RecoveredTasks:    []uuid.UUID{},
+		CompletedTasks:    []uuid.UUID{},
+		FailedTasks:       []uuid.UUID{},
+		RemovedContainers: []string{},
+		RemovedWorkspaces: []uuid.UUID{},
+	}}
+}
+
+// reclaim moves the tasks with an expired lease to this node, leased like a
+// task taken by a worker. A task whose container exists on this node is recovered
+// or completed like a task of the node, the others are requeued.
+func (gc *GarbageCollector) reclaim(ctx context.Context, r *run) {
+	tasks, err := gc.repo.ReclaimExpiredTasks(ctx, gc.nodeID, gc.lease)
+	if err != nil {
+		r.fail("reclaiming tasks with an expired lease", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, task := range tasks {
+		// the lease of a task processed here lapsed, the worker keeps renewing it
+		if gc.taskService.IsProcessing(task.ID) {
+			continue
+		}
+
+		slog.Warn("gc: reclaiming task with an expired lease", "id", task.ID)
+		r.add(func(rep *domain.GCReport) { rep.ReclaimedTasks = append(rep.ReclaimedTasks, task.ID) })
+
+		if task.ContainerID == "" {
+			gc.requeue(ctx, r, task, "reclaimed task has no container")
+			continue
+		}
+		wg.Go(func() { gc.cleanupTask(ctx, r, task) })
+	}
+	wg.Wait()
+}
+
+func (gc *GarbageCollector) cleanupTask(ctx context.Context, r *run, task *domain.Task) {
 	exists, err := gc.containerManager.IsContainerExists(ctx, task.ContainerID)
 	if err != nil {
-		slog.Error("gc: checking container exists", "error", err)
+		r.fail("checking container exists", err)
 		return
 	}
 
 	if !exists {
-		if err := gc.repo.Mark(ctx, task, domain.TaskQueued); err != nil {
-			slog.Error("gc: marking task queued", "error", err)
-		}
+		gc.requeue(ctx, r, task, "container not found")
 		return
 	}
 
 	status, err := gc.containerManager.GetContainerState(ctx, task.ContainerID)
 	if err != nil {
-		slog.Error("gc: checking container status", "error", err)
+		r.fail("checking container status", err)
 		return
 	}
 
 	if status.Error != "" || status.ExitCode != 0 || status.OOMKilled {
 		if err := gc.containerManager.RemoveContainer(ctx, task.ContainerID); err != nil {
-			slog.Error("gc: removing container", "error", err)
+			r.fail("removing container", err)
 			return
 		}
+		r.add(func(rep *domain.GCReport) { rep.RemovedContainers = append(rep.RemovedContainers, task.ContainerID) })
 
-		if err := gc.repo.Mark(ctx, task, domain.TaskQueued); err != nil {
-			slog.Error("gc: marking task queued", "error", err)
-			return
-		}
+		gc.requeue(ctx, r, task, containerFailure(status))
+		return
 	} else if status.Running {
 		gc.taskService.RecoverTask(task)
+		r.add(func(rep *domain.GCReport) { rep.RecoveredTasks = append(rep.RecoveredTasks, task.ID) })
 		return
 	}
 
-	resultPath, err := gc.storage.UploadToStorage(ctx, task.ID, gc.workspace.ResultDir(task.ID))
+	res, err := gc.storage.UploadToStorage(ctx, task.ID, gc.workspace.ResultDir(task.ID), nil)
 	if err != nil {
-		slog.Error("gc: uploading to storage", "error", err)
+		r.fail("uploading to storage", err)
 		return
 	}
 
-	task.ResultPath = resultPath
-	if err := gc.repo.Mark(ctx, task, domain.TaskCompleted); err != nil {
-		slog.Error("gc: marking task completed", "error", err)
+	if err := gc.repo.SaveArtifactChecksums(ctx, task.ID, res.Objects); err != nil {
+		r.fail("saving result checksums", err)
 		return
 	}
+
+	task.ResultPath = res.PrimaryKey
+	task.ExitCode = &status.ExitCode
+	if err := gc.repo.Mark(ctx, task, domain.TaskCompleted, gc.change("result of the exited container uploaded")); err != nil {
+		r.failMark("marking task completed", task, err)
+		return
+	}
+	r.add(func(rep *domain.GCReport) { rep.CompletedTasks = append(rep.CompletedTasks, task.ID) })
+
 	if err := gc.workspace.Cleanup(task.ID); err != nil {
-		slog.Error("gc: removing workspace", "error", err)
+		r.fail("removing workspace", err)
 		return
 	}
 }
 
-func (gc *GarbageCollector) cleanupOrphans(ctx context.Context) {
+// cleanupStuck fails tasks stuck in initializing and requeues running tasks
+// whose container was never started.
+func (gc *GarbageCollector) cleanupStuck(ctx context.Context, r *run) {
+	now := gc.now()
+
+	initializing, err := gc.repo.GetStaleTasks(ctx, domain.TaskInitializing, now.Add(-gc.config.InitializingTimeout))
+	if err != nil {
+		r.fail("getting stuck initializing tasks", err)
+	}
+	for _, task := range initializing {
+		if gc.taskService.IsProcessing(task.ID) {
+			continue
+		}
+
+		task.ErrorLog = fmt.Sprintf("task stuck in initializing for more than %s", gc.config.InitializingTimeout)
+		task.FailureReason = domain.FailureInfrastructure
+		if err := gc.repo.Mark(ctx, task, domain.TaskFailed, gc.change(task.ErrorLog)); err != nil {
+			r.failMark("marking stuck task failed", task, err)
+			continue
+		}
+		r.add(func(rep *domain.GCReport) { rep.FailedTasks = append(rep.FailedTasks, task.ID) })
+
+		if err := gc.workspace.Cleanup(task.ID); err != nil {
+			r.fail("removing workspace", err)
+		}
+	}
+
+	running, err := gc.repo.GetStaleTasks(ctx, domain.TaskRunning, now.Add(-gc.config.StartTimeout))
+	if err != nil {
+		r.fail("getting stuck running tasks", err)
+	}
+	for _, task := range running {
+		if task.ContainerID != "" || !gc.owns(task.NodeID) || gc.taskService.IsProcessing(task.ID) {
+			continue
+		}
+		gc.requeue(ctx, r, task, "container was never started")
+	}
+}
+
+// change describes a status change made by the GC of this node.
+func (gc *GarbageCollector) change(reason string) domain.StatusChange {
+	return domain.StatusChange{Actor: domain.ActorGC, NodeID: gc.nodeID, Reason: reason}
+}
+
+// containerFailure explains why an exited container failed.
+func containerFailure(state *domain.ContainerState) string {
+	switch {
+	case state.Error != "":
+		return "container failed: " + state.Error
+	case state.OOMKilled:
+		return "container ran out of memory"
+	default:
+		return fmt.Sprintf("container exited with code %d", state.ExitCode)
+	}
+}
+
+// owns reports whether a task or container belongs to this node. Those
+// without a node predate nodes and are handled by any node.
+func (gc *GarbageCollector) owns(nodeID string) bool {
+	return nodeID == "" || nodeID == gc.nodeID
+}
+
+// requeue queues a task whose run was lost again. A task that ran out of
+// attempts is failed instead, it would most likely be lost again.
+func (gc *GarbageCollector) requeue(ctx context.Context, r *run, task *domain.Task, reason string) {
+	if task.Attempts+1 >= gc.config.MaxAttempts {
+		task.ErrorLog = fmt.Sprintf("%s, giving up after %d attempts", reason, task.Attempts+1)
+		task.FailureReason = domain.FailureAttemptsExhausted
+		if err := gc.repo.Mark(ctx, task, domain.TaskFailed, gc.change(task.ErrorLog)); err != nil {
+			r.failMark("marking task failed", task, err)
+			return
+		}
+		r.add(func(rep *domain.GCReport) { rep.FailedTasks = append(rep.FailedTasks, task.ID) })
+		return
+	}
+
+	task.Attempts++
+	if err := gc.repo.Mark(ctx, task, domain.TaskQueued, gc.change(reason)); err != nil {
+		r.failMark("marking task queued", task, err)
+		return
+	}
+	r.add(func(rep *domain.GCReport) { rep.RequeuedTasks = append(rep.RequeuedTasks, task.ID) })
+}
+
+func (gc *GarbageCollector) cleanupOrphans(ctx context.Context, r *run) {
 	dockerContainers, err := gc.containerManager.ListManagedContainers(ctx)
 	if err != nil {
-		slog.Error("gc: error getting list of managed containers", "error", err)
+		r.fail("error getting list of managed containers", err)
 		return
 	}
 
 	activeTasks, err := gc.repo.GetActiveTasks(ctx)
 	if err != nil {
-		slog.Error("gc: error getting active tasks", "error", err)
+		r.fail("error getting active tasks", err)
 		return
 	}
 
@@ -146,22 +424,71 @@ func (gc *GarbageCollector) cleanupOrphans(ctx context.Context) {
 
 	for _, dockerCont := range dockerContainers {
 		taskID := dockerCont.Labels["pinn.task_id"]
-		if !activeMap[taskID] {
+		if !activeMap[taskID] && gc.owns(dockerCont.Labels["pinn.node"]) {
 			err := gc.containerManager.RemoveContainer(ctx, dockerCont.ID)
 			if err != nil {
-				slog.Error("gc: removing container", "error", err)
+				r.fail("removing container", err)
+			} else {
+				r.add(func(rep *domain.GCReport) { rep.RemovedContainers = append(rep.RemovedContainers, dockerCont.ID) })
 			}
 
 			uuID, err := uuid.Parse(taskID)
 			if err != nil {
-				slog.Error("gc: parsing uuid", "error", err)
+				r.fail("parsing uuid", err)
 				return
 			}
 
 			err = gc.workspace.Cleanup(uuID)
 			if err != nil {
-				slog.Error("gc: cleaning up workspace", "error", err)
+				r.fail("cleaning up workspace", err)
 			}
 		}
 	}
 }
+
+// cleanupWorkspaces removes workspace dirs that don't belong to any active task.
+// Fresh dirs are kept: a workspace is created before its task is saved. Dirs of
+// tasks whose upload failed are kept too, the upload can still be retried.
+func (gc *GarbageCollector) cleanupWorkspaces(ctx context.Context, r *run) {
+	workspaces, err := gc.workspace.List()
+	if err != nil {
+		r.fail("listing workspaces", err)
+		return
+	}
+	if len(workspaces) == 0 {
+		return
+	}
+
+	activeTasks, err := gc.repo.GetActiveTasks(ctx)
+	if err != nil {
+		r.fail("error getting active tasks", err)
+		return
+	}
+
+	uploadFailed, err := gc.repo.GetUploadFailedTasks(ctx)
+	if err != nil {
+		r.fail("error getting upload failed tasks", err)
+		return
+	}
+
+	active := make(map[uuid.UUID]bool, len(activeTasks)+len(uploadFailed))
+	for _, task := range activeTasks {
+		active[task.ID] = true
+	}
+	for _, task := range uploadFailed {
+		active[task.ID] = true
+	}
+
+	threshold := gc.now().Add(-gc.config.WorkspaceMinAge)
+	for _, ws := range workspaces {
+		if active[ws.TaskID] || gc.taskService.IsProcessing(ws.TaskID) || ws.ModifiedAt.After(threshold) {
+			continue
+		}
+
+		if err := gc.workspace.Cleanup(ws.TaskID); err != nil {
+			r.fail("removing orphan workspace", err)
+			continue
+		}
+		r.add(func(rep *domain.GCReport) { rep.RemovedWorkspaces = append(rep.RemovedWorkspaces, ws.TaskID) })
+	}
+}
@@ -2,8 +2,12 @@ package gc
 
 import (
 	"context"
+	"errors"
+	"pinn-connect-service/internal/config"
 	"pinn-connect-service/internal/domain"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -14,6 +18,11 @@ type mockRepo struct {
 	getRunningTasksFunc func(ctx context.Context) ([]*domain.Task, error)
 	getActiveTasksFunc  func(ctx context.Context) ([]*domain.Task, error)
 	markFunc            func(ctx context.Context, task *domain.Task, status domain.TaskStatus) error
+	changeFunc          func(task *domain.Task, change domain.StatusChange)
+	getStaleTasksFunc   func(ctx context.Context, status domain.TaskStatus, before time.Time) ([]*domain.Task, error)
+	uploadFailedFunc    func(ctx context.Context) ([]*domain.Task, error)
+	saveSumsFunc        func(ctx context.Context, taskID uuid.UUID, objects []domain.Artifact) error
+	reclaimFunc         func(ctx context.Context, nodeID string, lease time.Duration) ([]*domain.Task, error)
 }
 
 func (m *mockRepo) GetRunningTasks(ctx context.Context) ([]*domain.Task, error) {
@@ -28,7 +37,34 @@ func (m *mockRepo) GetActiveTasks(ctx context.Context) ([]*domain.Task, error) {
 	}
 	return nil, nil
 }
-func (m *mockRepo) Mark(ctx context.Context, task *domain.Task, status domain.TaskStatus) error {
+func (m *mockRepo) GetStaleTasks(ctx context.Context, status domain.TaskStatus, before time.Time) ([]*domain.Task, error) {
+	if m.getStaleTasksFunc != nil {
+		return m.getStaleTasksFunc(ctx, status, before)
+	}
+	return nil, nil
+}
+func (m *mockRepo) GetUploadFailedTasks(ctx context.Context) ([]*domain.Task, error) {
+	if m.uploadFailedFunc != nil {
+		return m.uploadFailedFunc(ctx)
+	}
+	return nil, nil
+}
+func (m *mockRepo) SaveArtifactChecksums(ctx context.Context, taskID uuid.UUID, objects []domain.Artifact) error {
+	if m.saveSumsFunc != nil {
+		return m.saveSumsFunc(ctx, taskID, objects)
+	}
+	return nil
+}
+func (m *mockRepo) ReclaimExpiredTasks(ctx context.Context, nodeID string, lease time.Duration) ([]*domain.Task, error) {
+	if m.reclaimFunc != nil {
+		return m.reclaimFunc(ctx, nodeID, lease)
+	}
+	return nil, nil
+}
+func (m *mockRepo) Mark(ctx context.Context, task *domain.Task, status domain.TaskStatus, change domain.StatusChange) error {
+	if m.changeFunc != nil {
+		m.changeFunc(task, change)
+	}
 	if m.markFunc != nil {
 		return m.markFunc(ctx, task, status)
 	}
@@ -38,6 +74,7 @@ func (m *mockRepo) Mark(ctx context.Context, task *domain.Task, status domain.Ta
 type mockWorkspace struct {
 	cleanupFunc   func(taskID uuid.UUID) error
 	resultDirFunc func(taskID uuid.UUID) string
+	listFunc      func() ([]domain.WorkspaceInfo, error)
 }
 
 func (m *mockWorkspace) Cleanup(taskID uuid.UUID) error {
@@ -46,6 +83,12 @@ func (m *mockWorkspace) Cleanup(taskID uuid.UUID) error {
 	}
 	return nil
 }
+func (m *mockWorkspace) List() ([]domain.WorkspaceInfo, error) {
+	if m.listFunc != nil {
+		return m.listFunc()
+	}
+	return nil, nil
+}
 func (m *mockWorkspace) ResultDir(taskID uuid.UUID) string {
 	if m.resultDirFunc != nil {
 		return m.resultDirFunc(taskID)
@@ -86,18 +129,19 @@ func (m *mockContainerManager) ListManagedContainers(ctx context.Context) ([]*do
 }
 
 type mockStorage struct {
-	uploadToStorageFunc func(ctx context.Context, taskID uuid.UUID, resultDir string) (string, error)
+	uploadToStorageFunc func(ctx context.Context, taskID uuid.UUID, resultDir string) (*domain.UploadResult, error)
 }
 
-func (m *mockStorage) UploadToStorage(ctx context.Context, taskID uuid.UUID, resultDir string) (string, error) {
+func (m *mockStorage) UploadToStorage(ctx context.Context, taskID uuid.UUID, resultDir string, _ domain.UploadProgressFunc) (*domain.UploadResult, error) {
 	if m.uploadToStorageFunc != nil {
 		return m.uploadToStorageFunc(ctx, taskID, resultDir)
 	}
-	return "", nil
+	return &domain.UploadResult{}, nil
 }
 
 type mockTaskService struct {
 	recoverTaskFunc func(task *domain.Task)
+	processing      map[uuid.UUID]bool
 }
 
 func (m *mockTaskService) RecoverTask(task *domain.Task) {
@@ -106,6 +150,10 @@ func (m *mockTaskService) RecoverTask(task *domain.Task) {
 	}
 }
 
+func (m *mockTaskService) IsProcessing(id uuid.UUID) bool {
+	return m.processing[id]
+}
+
 // --- TESTS ---
 
 func TestGarbageCollector_Cleanup_DeadTask(t *testing.T) {
@@ -126,7 +174,7 @@ func TestGarbageCollector_Cleanup_DeadTask(t *testing.T) {
 		isContainerExistsFunc: func(ctx context.Context, id string) (bool, error) { return false, nil },
 	}
 
-	gc := NewGarbageCollector(repo, &mockWorkspace{}, cm, &mockStorage{}, &mockTaskService{})
+	gc := NewGarbageCollector(repo, &mockWorkspace{}, cm, &mockStorage{}, &mockTaskService{}, config.GCConfig{MaxAttempts: 3}, time.Minute, "node-1")
 	gc.Cleanup(context.Background())
 }
 
@@ -154,7 +202,7 @@ func TestGarbageCollector_Cleanup_RecoverTask(t *testing.T) {
 		},
 	}
 
-	gc := NewGarbageCollector(repo, &mockWorkspace{}, cm, &mockStorage{}, ts)
+	gc := NewGarbageCollector(repo, &mockWorkspace{}, cm, &mockStorage{}, ts, config.GCConfig{MaxAttempts: 3}, time.Minute, "node-1")
 	gc.Cleanup(context.Background())
 
 	if !recovered {
@@ -194,7 +242,7 @@ func TestGarbageCollector_Cleanup_Orphans(t *testing.T) {
 		},
 	}
 
-	gc := NewGarbageCollector(repo, ws, cm, &mockStorage{}, &mockTaskService{})
+	gc := NewGarbageCollector(repo, ws, cm, &mockStorage{}, &mockTaskService{}, config.GCConfig{MaxAttempts: 3}, time.Minute, "node-1")
 	gc.Cleanup(context.Background())
 
 	if !containerRemoved {
@@ -204,3 +252,292 @@ func TestGarbageCollector_Cleanup_Orphans(t *testing.T) {
 		t.Error("orphan workspace was not cleaned up")
 	}
 }
+
+func TestGarbageCollector_Cleanup_SkipsProcessingTasks(t *testing.T) {
+	task := &domain.Task{ID: uuid.New(), ContainerID: "live-cont", Status: domain.TaskRunning}
+
+	repo := &mockRepo{
+		getRunningTasksFunc: func(ctx context.Context) ([]*domain.Task, error) { return []*domain.Task{task}, nil },
+		markFunc: func(ctx context.Context, task *domain.Task, status domain.TaskStatus) error {
+			t.Errorf("task processed by this instance must not be marked, got %v", status)
+			return nil
+		},
+	}
+	cm := &mockContainerManager{
+		isContainerExistsFunc: func(ctx context.Context, id string) (bool, error) {
+			t.Error("container of a processing task must not be inspected")
+			return false, nil
+		},
+	}
+	ts := &mockTaskService{processing: map[uuid.UUID]bool{task.ID: true}}
+
+	gc := NewGarbageCollector(repo, &mockWorkspace{}, cm, &mockStorage{}, ts, config.GCConfig{MaxAttempts: 3}, time.Minute, "node-1")
+	gc.Cleanup(context.Background())
+}
+
+func TestGarbageCollector_Cleanup_StuckTasks(t *testing.T) {
+	initializing := &domain.Task{ID: uuid.New(), Status: domain.TaskInitializing}
+	notStarted := &domain.Task{ID: uuid.New(), Status: domain.TaskRunning}
+	started := &domain.Task{ID: uuid.New(), Status: domain.TaskRunning, ContainerID: "cont"}
+
+	marked := map[uuid.UUID]domain.TaskStatus{}
+	changes := map[uuid.UUID]domain.StatusChange{}
+	repo := &mockRepo{
+		getStaleTasksFunc: func(ctx context.Context, status domain.TaskStatus, before time.Time) ([]*domain.Task, error) {
+			if status == domain.TaskInitializing {
+				return []*domain.Task{initializing}, nil
+			}
+			return []*domain.Task{notStarted, started}, nil
+		},
+		markFunc: func(ctx context.Context, task *domain.Task, status domain.TaskStatus) error {
+			marked[task.ID] = status
+			return nil
+		},
+		changeFunc: func(task *domain.Task, change domain.StatusChange) {
+			changes[task.ID] = change
+		},
+	}
+
+	gc := NewGarbageCollector(repo, &mockWorkspace{}, &mockContainerManager{}, &mockStorage{}, &mockTaskService{},
+		config.GCConfig{InitializingTimeout: time.Minute, StartTimeout: time.Minute, MaxAttempts: 3}, time.Minute, "node-1")
+	report := gc.Cleanup(context.Background())
+
+	if marked[initializing.ID] != domain.TaskFailed || initializing.ErrorLog == "" {
+		t.Errorf("expected stuck initializing task to be failed with a reason, got %v", marked[initializing.ID])
+	}
+	if initializing.FailureReason != domain.FailureInfrastructure {
+		t.Errorf("expected stuck initializing task to be an infrastructure failure, got %q", initializing.FailureReason)
+	}
+	if marked[notStarted.ID] != domain.TaskQueued {
+		t.Errorf("expected running task without container to be requeued, got %v", marked[notStarted.ID])
+	}
+	if _, ok := marked[started.ID]; ok {
+		t.Error("running task with container must be handled by the container check only")
+	}
+	want := domain.StatusChange{Actor: domain.ActorGC, NodeID: "node-1", Reason: "container was never started"}
+	if changes[notStarted.ID] != want {
+		t.Errorf("expected the requeue to be recorded as %+v, got %+v", want, changes[notStarted.ID])
+	}
+	if changes[initializing.ID].Reason != initializing.ErrorLog {
+		t.Errorf("expected the failure reason to be recorded, got %q", changes[initializing.ID].Reason)
+	}
+	if len(report.FailedTasks) != 1 || len(report.RequeuedTasks) != 1 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestGarbageCollector_Cleanup_SkipsChangedTasks(t *testing.T) {
+	initializing := &domain.Task{ID: uuid.New(), Status: domain.TaskInitializing}
+	notStarted := &domain.Task{ID: uuid.New(), Status: domain.TaskRunning}
+
+	repo := &mockRepo{
+		getStaleTasksFunc: func(ctx context.Context, status domain.TaskStatus, before time.Time) ([]*domain.Task, error) {
+			if status == domain.TaskInitializing {
+				return []*domain.Task{initializing}, nil
+			}
+			return []*domain.Task{notStarted}, nil
+		},
+		markFunc: func(ctx context.Context, task *domain.Task, status domain.TaskStatus) error {
+			// both tasks were stopped through the API meanwhile
+			return &domain.TransitionError{TaskID: task.ID, From: domain.TaskStopped, To: status, Err: domain.ErrInvalidTransition}
+		},
+	}
+	ws := &mockWorkspace{
+		cleanupFunc: func(id uuid.UUID) error {
+			t.Errorf("workspace of a task that wasn't failed must be kept, got %v", id)
+			return nil
+		},
+	}
+
+	gc := NewGarbageCollector(repo, ws, &mockContainerManager{}, &mockStorage{}, &mockTaskService{},
+		config.GCConfig{InitializingTimeout: time.Minute, StartTimeout: time.Minute, MaxAttempts: 3}, time.Minute, "node-1")
+	report := gc.Cleanup(context.Background())
+
+	if len(report.FailedTasks) != 0 || len(report.RequeuedTasks) != 0 {
+		t.Errorf("expected changed tasks to be skipped, got %+v", report)
+	}
+	if len(report.Errors) != 0 {
+		t.Errorf("expected rejected transitions not to be reported as errors, got %v", report.Errors)
+	}
+}
+
+func TestGarbageCollector_Cleanup_OrphanWorkspaces(t *testing.T) {
+	now := time.Now()
+	active := uuid.New()
+	orphan := uuid.New()
+	fresh := uuid.New()
+	uploadFailed := uuid.New()
+
+	repo := &mockRepo{
+		getActiveTasksFunc: func(ctx context.Context) ([]*domain.Task, error) {
+			return []*domain.Task{{ID: active}}, nil
+		},
+		uploadFailedFunc: func(ctx context.Context) ([]*domain.Task, error) {
+			return []*domain.Task{{ID: uploadFailed}}, nil
+		},
+	}
+
+	var cleaned []uuid.UUID
+	ws := &mockWorkspace{
+		listFunc: func() ([]domain.WorkspaceInfo, error) {
+			return []domain.WorkspaceInfo{
+				{TaskID: active, ModifiedAt: now.Add(-2 * time.Hour)},
+				{TaskID: orphan, ModifiedAt: now.Add(-2 * time.Hour)},
+				{TaskID: fresh, ModifiedAt: now},
+				{TaskID: uploadFailed, ModifiedAt: now.Add(-2 * time.Hour)},
+			}, nil
+		},
+		cleanupFunc: func(id uuid.UUID) error {
+			cleaned = append(cleaned, id)
+			return nil
+		},
+	}
+
+	gc := NewGarbageCollector(repo, ws, &mockContainerManager{}, &mockStorage{}, &mockTaskService{},
+		config.GCConfig{WorkspaceMinAge: time.Hour}, time.Minute, "node-1")
+	report := gc.Cleanup(context.Background())
+
+	if len(cleaned) != 1 || cleaned[0] != orphan {
+		t.Fatalf("expected only the old orphan workspace to be removed, got %v", cleaned)
+	}
+	if len(report.RemovedWorkspaces) != 1 {
+		t.Errorf("expected 1 removed workspace in report, got %v", report.RemovedWorkspaces)
+	}
+}
+
+func TestGarbageCollector_LastReport(t *testing.T) {
+	repo := &mockRepo{
+		getRunningTasksFunc: func(ctx context.Context) ([]*domain.Task, error) { return nil, errors.New("db down") },
+	}
+	gc := NewGarbageCollector(repo, &mockWorkspace{}, &mockContainerManager{}, &mockStorage{}, &mockTaskService{}, config.GCConfig{MaxAttempts: 3}, time.Minute, "node-1")
+
+	if gc.LastReport() != nil {
+		t.Fatal("expected no report before the first run")
+	}
+
+	report := gc.Cleanup(context.Background())
+	if gc.LastReport() != report || len(report.Errors) != 1 {
+		t.Errorf("expected last report with an error, got %+v", gc.LastReport())
+	}
+}
+
+func TestGarbageCollector_Cleanup_SkipsOtherNodes(t *testing.T) {
+	foreign := &domain.Task{ID: uuid.New(), ContainerID: "foreign-cont", Status: domain.TaskRunning, NodeID: "node-2"}
+	repo := &mockRepo{
+		getRunningTasksFunc: func(ctx context.Context) ([]*domain.Task, error) { return []*domain.Task{foreign}, nil },
+		markFunc: func(ctx context.Context, task *domain.Task, status domain.TaskStatus) error {
+			t.Errorf("task of another node must not be marked %s", status)
+			return nil
+		},
+	}
+
+	cm := &mockContainerManager{
+		isContainerExistsFunc: func(ctx context.Context, id string) (bool, error) {
+			t.Error("container of another node must not be inspected")
+			return false, nil
+		},
+		listManagedContainersFunc: func(ctx context.Context) ([]*domain.Container, error) {
+			return []*domain.Container{
+				{ID: "foreign-orphan", Labels: map[string]string{"pinn.task_id": uuid.NewString(), "pinn.node": "node-2"}},
+			}, nil
+		},
+		removeContainerFunc: func(ctx context.Context, id string) error {
+			t.Errorf("container %s of another node must not be removed", id)
+			return nil
+		},
+	}
+
+	gc := NewGarbageCollector(repo, &mockWorkspace{}, cm, &mockStorage{}, &mockTaskService{}, config.GCConfig{MaxAttempts: 3}, time.Minute, "node-1")
+	gc.Cleanup(context.Background())
+}
+
+func TestGarbageCollector_Reclaim(t *testing.T) {
+	unstarted := &domain.Task{ID: uuid.New(), Status: domain.TaskRunning}
+	gone := &domain.Task{ID: uuid.New(), ContainerID: "gone-cont", Status: domain.TaskRunning}
+	live := &domain.Task{ID: uuid.New(), ContainerID: "live-cont", Status: domain.TaskRunning}
+	processing := &domain.Task{ID: uuid.New(), ContainerID: "own-cont", Status: domain.TaskRunning}
+
+	var gotNode string
+	var gotLease time.Duration
+	var mu sync.Mutex
+	requeued := map[uuid.UUID]bool{}
+	repo := &mockRepo{
+		reclaimFunc: func(_ context.Context, nodeID string, lease time.Duration) ([]*domain.Task, error) {
+			gotNode, gotLease = nodeID, lease
+			return []*domain.Task{unstarted, gone, live, processing}, nil
+		},
+		markFunc: func(_ context.Context, task *domain.Task, status domain.TaskStatus) error {
+			if status != domain.TaskQueued {
+				t.Errorf("expected task to be requeued, got %v", status)
+			}
+			mu.Lock()
+			requeued[task.ID] = true
+			mu.Unlock()
+			return nil
+		},
+	}
+	cm := &mockContainerManager{
+		isContainerExistsFunc: func(_ context.Context, id string) (bool, error) { return id == "live-cont", nil },
+		getContainerStateFunc: func(context.Context, string) (*domain.ContainerState, error) {
+			return &domain.ContainerState{Running: true}, nil
+		},
+	}
+	var recovered []uuid.UUID
+	ts := &mockTaskService{
+		recoverTaskFunc: func(task *domain.Task) { recovered = append(recovered, task.ID) },
+		processing:      map[uuid.UUID]bool{processing.ID: true},
+	}
+
+	gc := NewGarbageCollector(repo, &mockWorkspace{}, cm, &mockStorage{}, ts, config.GCConfig{Timeout: time.Second, MaxAttempts: 3}, time.Minute, "node-1")
+	report := gc.Reclaim(context.Background())
+
+	if gotNode != "node-1" || gotLease != time.Minute {
+		t.Errorf("expected tasks leased to node-1 for the worker lease, got %q for %v", gotNode, gotLease)
+	}
+	if len(report.ReclaimedTasks) != 3 {
+		t.Errorf("expected 3 reclaimed tasks, got %v", report.ReclaimedTasks)
+	}
+	if !requeued[unstarted.ID] || !requeued[gone.ID] || len(requeued) != 2 {
+		t.Errorf("expected the tasks without a container to be requeued, got %v", requeued)
+	}
+	if len(recovered) != 1 || recovered[0] != live.ID {
+		t.Errorf("expected the task with a live container to be recovered, got %v", recovered)
+	}
+	if gc.LastReport() != nil {
+		t.Error("reclaim must not replace the last cleanup report")
+	}
+}
+
+// A task whose run keeps being lost is failed once it runs out of attempts.
+func TestGarbageCollector_Reclaim_AttemptsExhausted(t *testing.T) {
+	retried := &domain.Task{ID: uuid.New(), Status: domain.TaskRunning, Attempts: 1}
+	exhausted := &domain.Task{ID: uuid.New(), Status: domain.TaskRunning, Attempts: 2}
+
+	var mu sync.Mutex
+	marked := map[uuid.UUID]domain.TaskStatus{}
+	repo := &mockRepo{
+		reclaimFunc: func(context.Context, string, time.Duration) ([]*domain.Task, error) {
+			return []*domain.Task{retried, exhausted}, nil
+		},
+		markFunc: func(_ context.Context, task *domain.Task, status domain.TaskStatus) error {
+			mu.Lock()
+			marked[task.ID] = status
+			mu.Unlock()
+			return nil
+		},
+	}
+
+	gc := NewGarbageCollector(repo, &mockWorkspace{}, &mockContainerManager{}, &mockStorage{}, &mockTaskService{},
+		config.GCConfig{MaxAttempts: 3}, time.Minute, "node-1")
+	report := gc.Reclaim(context.Background())
+
+	if marked[retried.ID] != domain.TaskQueued || retried.Attempts != 2 {
+		t.Errorf("expected task to be requeued with 2 attempts, got %s with %d", marked[retried.ID], retried.Attempts)
+	}
+	if marked[exhausted.ID] != domain.TaskFailed || exhausted.FailureReason != domain.FailureAttemptsExhausted {
+		t.Errorf("expected task to fail with attempts_exhausted, got %s with %q", marked[exhausted.ID], exhausted.FailureReason)
+	}
+	if len(report.RequeuedTasks) != 1 || len(report.FailedTasks) != 1 {
+		t.Errorf("expected 1 requeued and 1 failed task, got %+v", report)
+	}
+}
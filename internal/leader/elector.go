@@ -0,0 +1,192 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"pinn-connect-service/internal/config"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// lockKey identifies the advisory lock held by the leader. It fits into 32
+// bits, so the lock shows up in pg_locks with classid 0 and objid lockKey.
+const lockKey = 0x70696e6e // "pinn"
+
+const closeTimeout = 5 * time.Second
+
+const leaderQuery = `
+SELECT a.application_name
+FROM pg_locks l
+JOIN pg_stat_activity a ON a.pid = l.pid
+WHERE l.locktype = 'advisory'
+  AND l.granted
+  AND l.database = (SELECT oid FROM pg_database WHERE datname = current_database())
+  AND l.classid = 0 AND l.objid::bigint = $1 AND l.objsubid = 1`
+
+type Conn interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Close(context.Context) error
+}
+
+type Querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Job is started when the instance becomes the leader. Its ctx is cancelled
+// when the leadership is lost, and the leadership is only given up after the
+// goroutines it added to wg are done.
+type Job func(ctx context.Context, wg *sync.WaitGroup)
+
+// Elector elects one leader among the instances sharing the database. The
+// leader holds a session advisory lock on a dedicated connection, so the lock
+// is released by Postgres as soon as the leader stops or loses its connection
+// and another instance takes over on its next attempt.
+type Elector struct {
+	instanceID string
+	connect    func(context.Context) (Conn, error)
+	querier    Querier
+	interval   time.Duration
+
+	jobs    []Job
+	leading atomic.Bool
+}
+
+// NewElector returns an elector for the instance. The dedicated connection
+// reports instanceID as its application_name, which is how the other
+// instances find out who the leader is.
+func NewElector(querier Querier, dbCfg config.DatabaseConfig, cfg config.LeaderConfig, instanceID string) *Elector {
+	return &Elector{
+		instanceID: instanceID,
+		connect: func(ctx context.Context) (Conn, error) {
+			connCfg, err := pgx.ParseConfig(dbCfg.URL)
+			if err != nil {
+				return nil, err
+			}
+			connCfg.RuntimeParams["application_name"] = instanceID
+			return pgx.ConnectConfig(ctx, connCfg)
+		},
+		querier:  querier,
+		interval: cfg.Interval,
+	}
+}
+
+// Run registers a job to run while the instance is the leader. Jobs must be
+// registered before Start.
+func (e *Elector) Run(job Job) {
+	e.jobs = append(e.jobs, job)
+}
+
+func (e *Elector) InstanceID() string {
+	return e.instanceID
+}
+
+func (e *Elector) IsLeader() bool {
+	return e.leading.Load()
+}
+
+// Leader returns the ID of the current leader or an empty string if there is
+// none at the moment.
+func (e *Elector) Leader(ctx context.Context) (string, error) {
+	var id string
+	err := e.querier.QueryRow(ctx, leaderQuery, lockKey).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("looking up leader: %w", err)
+	}
+	return id, nil
+}
+
+// Start campaigns for the leadership until ctx is done. Followers try to take
+// the lock every interval, the leader checks its connection just as often.
+func (e *Elector) Start(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Go(func() {
+		for {
+			err := e.campaign(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Error("leader: election", "error", err, "retry_in", e.interval)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(e.interval):
+			}
+		}
+	})
+}
+
+func (e *Elector) campaign(ctx context.Context) error {
+	conn, err := e.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("connecting: %w", err)
+	}
+	defer func() {
+		// closing the session releases the lock
+		closeCtx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+		defer cancel()
+		if err := conn.Close(closeCtx); err != nil {
+			slog.Warn("leader: closing connection", "error", err)
+		}
+	}()
+
+	for {
+		var acquired bool
+		if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", lockKey).Scan(&acquired); err != nil {
+			return fmt.Errorf("acquiring lock: %w", err)
+		}
+		if acquired {
+			return e.lead(ctx, conn)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(e.interval):
+		}
+	}
+}
+
+func (e *Elector) lead(ctx context.Context, conn Conn) error {
+	slog.Info("leader: elected", "instance_id", e.instanceID)
+	e.leading.Store(true)
+
+	jobsCtx, cancel := context.WithCancel(ctx)
+	var jobs sync.WaitGroup
+	defer func() {
+		e.leading.Store(false)
+		cancel()
+		jobs.Wait()
+		slog.Info("leader: stepped down", "instance_id", e.instanceID)
+	}()
+
+	for _, job := range e.jobs {
+		job(jobsCtx, &jobs)
+	}
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			pingCtx, pingCancel := context.WithTimeout(ctx, e.interval)
+			_, err := conn.Exec(pingCtx, "SELECT 1")
+			pingCancel()
+			if err != nil {
+				return fmt.Errorf("checking connection: %w", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// --- MOCKS ---
+
+type fakeRow struct {
+	value any
+	err   error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	switch d := dest[0].(type) {
+	case *bool:
+		*d = r.value.(bool)
+	case *string:
+		*d = r.value.(string)
+	}
+	return nil
+}
+
+// fakeLock is the advisory lock shared by the fake connections.
+type fakeLock struct {
+	mu     sync.Mutex
+	holder *fakeConn
+}
+
+type fakeConn struct {
+	lock *fakeLock
+
+	mu     sync.Mutex
+	broken bool
+	closed bool
+}
+
+func (c *fakeConn) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.broken {
+		return pgconn.CommandTag{}, errors.New("connection lost")
+	}
+	return pgconn.CommandTag{}, nil
+}
+
+func (c *fakeConn) QueryRow(context.Context, string, ...any) pgx.Row {
+	c.mu.Lock()
+	broken := c.broken
+	c.mu.Unlock()
+	if broken {
+		return fakeRow{err: errors.New("connection lost")}
+	}
+
+	c.lock.mu.Lock()
+	defer c.lock.mu.Unlock()
+	if c.lock.holder == nil {
+		c.lock.holder = c
+	}
+	return fakeRow{value: c.lock.holder == c}
+}
+
+func (c *fakeConn) Close(context.Context) error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	c.lock.mu.Lock()
+	defer c.lock.mu.Unlock()
+	if c.lock.holder == c {
+		c.lock.holder = nil
+	}
+	return nil
+}
+
+func (c *fakeConn) breakConn() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.broken = true
+}
+
+type fakeQuerier struct {
+	row fakeRow
+}
+
+func (q *fakeQuerier) QueryRow(context.Context, string, ...any) pgx.Row {
+	return q.row
+}
+
+// --- HELPERS ---
+
+func newTestElector(lock *fakeLock, id string) (*Elector, *[]*fakeConn) {
+	var mu sync.Mutex
+	conns := &[]*fakeConn{}
+	e := &Elector{
+		instanceID: id,
+		connect: func(context.Context) (Conn, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			conn := &fakeConn{lock: lock}
+			*conns = append(*conns, conn)
+			return conn, nil
+		},
+		interval: 5 * time.Millisecond,
+	}
+	return e, conns
+}
+
+func waitFor(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// --- TESTS ---
+
+func TestElector_SingleLeaderAndFailover(t *testing.T) {
+	lock := &fakeLock{}
+	first, _ := newTestElector(lock, "first")
+	second, _ := newTestElector(lock, "second")
+
+	var firstJobs, secondJobs atomic.Int32
+	first.Run(func(ctx context.Context, wg *sync.WaitGroup) { firstJobs.Add(1) })
+	second.Run(func(ctx context.Context, wg *sync.WaitGroup) { secondJobs.Add(1) })
+
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	var firstWg sync.WaitGroup
+	first.Start(firstCtx, &firstWg)
+	waitFor(t, first.IsLeader, "expected the first instance to become the leader")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	second.Start(ctx, &wg)
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	if second.IsLeader() || secondJobs.Load() != 0 {
+		t.Fatal("expected a single leader")
+	}
+
+	stopFirst()
+	firstWg.Wait()
+	if first.IsLeader() {
+		t.Error("expected the stopped instance to step down")
+	}
+
+	waitFor(t, second.IsLeader, "expected the second instance to take over")
+	if firstJobs.Load() != 1 || secondJobs.Load() != 1 {
+		t.Errorf("expected each leader to start its jobs once, got %d and %d", firstJobs.Load(), secondJobs.Load())
+	}
+}
+
+func TestElector_StepsDownOnLostConnection(t *testing.T) {
+	lock := &fakeLock{}
+	e, conns := newTestElector(lock, "first")
+
+	var stopped atomic.Bool
+	e.Run(func(ctx context.Context, wg *sync.WaitGroup) {
+		wg.Go(func() {
+			<-ctx.Done()
+			stopped.Store(true)
+		})
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	e.Start(ctx, &wg)
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	waitFor(t, e.IsLeader, "expected the instance to become the leader")
+	conn := (*conns)[0]
+	conn.breakConn()
+
+	waitFor(t, stopped.Load, "expected the jobs to be stopped")
+	waitFor(t, func() bool {
+		conn.mu.Lock()
+		defer conn.mu.Unlock()
+		return conn.closed
+	}, "expected the lost connection to be closed")
+
+	// the lock was released with the session, so the instance is elected again
+	waitFor(t, e.IsLeader, "expected the instance to be elected again after reconnecting")
+}
+
+func TestElector_Leader(t *testing.T) {
+	e := &Elector{querier: &fakeQuerier{row: fakeRow{value: "api-2"}}}
+	if id, err := e.Leader(context.Background()); err != nil || id != "api-2" {
+		t.Errorf("Leader() = %q, %v", id, err)
+	}
+
+	e = &Elector{querier: &fakeQuerier{row: fakeRow{err: pgx.ErrNoRows}}}
+	if id, err := e.Leader(context.Background()); err != nil || id != "" {
+		t.Errorf("expected no leader, got %q, %v", id, err)
+	}
+
+	e = &Elector{querier: &fakeQuerier{row: fakeRow{err: errors.New("db down")}}}
+	if _, err := e.Leader(context.Background()); err == nil {
+		t.Error("expected an error")
+	}
+}
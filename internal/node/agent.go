@@ -0,0 +1,95 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/domain"
+	"sync"
+	"time"
+)
+
+const stopTimeout = 5 * time.Second
+
+type Repository interface {
+	Register(context.Context, *domain.Node) error
+	Heartbeat(ctx context.Context, id string) (bool, error)
+	Stop(ctx context.Context, id string) error
+}
+
+// Agent keeps the registration of the node executing tasks up to date.
+type Agent struct {
+	repo   Repository
+	node   *domain.Node
+	config config.NodeConfig
+}
+
+func NewAgent(repo Repository, node *domain.Node, cfg config.NodeConfig) *Agent {
+	return &Agent{repo: repo, node: node, config: cfg}
+}
+
+// Register registers the node. It must succeed before the node claims tasks,
+// as tasks reference their node.
+func (a *Agent) Register(ctx context.Context) error {
+	if err := a.repo.Register(ctx, a.node); err != nil {
+		return fmt.Errorf("registering node %s: %w", a.node.ID, err)
+	}
+	slog.Info("node: registered", "id", a.node.ID, "capacity", a.node.Capacity, "labels", a.node.Labels)
+	return nil
+}
+
+// Start sends heartbeats until ctx is done and then marks the node stopped.
+func (a *Agent) Start(ctx context.Context, wg *sync.WaitGroup) {
+	ticker := time.NewTicker(a.config.HeartbeatInterval)
+
+	wg.Go(func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				stopCtx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+				defer cancel()
+				if err := a.repo.Stop(stopCtx, a.node.ID); err != nil {
+					slog.Error("node: marking stopped", "id", a.node.ID, "error", err)
+				}
+				return
+			case <-ticker.C:
+				a.heartbeat(ctx)
+			}
+		}
+	})
+}
+
+func (a *Agent) heartbeat(ctx context.Context) {
+	ok, err := a.repo.Heartbeat(ctx, a.node.ID)
+	if err != nil {
+		slog.Error("node: heartbeat", "id", a.node.ID, "error", err)
+		return
+	}
+	if ok {
+		return
+	}
+
+	// the row was removed or marked stopped while the node kept running
+	slog.Warn("node: not registered, registering again", "id", a.node.ID)
+	if err := a.Register(ctx); err != nil {
+		slog.Error("node: heartbeat", "error", err)
+	}
+}
+
+// Describe describes the node of this process from the configuration.
+func Describe(cfg *config.Config) (*domain.Node, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("getting hostname: %w", err)
+	}
+
+	return &domain.Node{
+		ID:       cfg.InstanceID,
+		Hostname: hostname,
+		Capacity: cfg.Worker.MaxWorkers,
+		Labels:   cfg.Node.Labels,
+	}, nil
+}
@@ -0,0 +1,86 @@
+package node
+
+import (
+	"context"
+	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/domain"
+	"sync"
+	"testing"
+	"time"
+)
+
+// --- MOCKS ---
+
+type mockRepository struct {
+	mu         sync.Mutex
+	registered int
+	stopped    bool
+	// known is false once the node row is gone
+	known bool
+}
+
+func (m *mockRepository) Register(context.Context, *domain.Node) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.registered++
+	m.known = true
+	return nil
+}
+
+func (m *mockRepository) Heartbeat(context.Context, string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.known, nil
+}
+
+func (m *mockRepository) Stop(context.Context, string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopped = true
+	return nil
+}
+
+func (m *mockRepository) forget() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.known = false
+}
+
+func (m *mockRepository) registrations() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.registered
+}
+
+// --- TESTS ---
+
+func TestAgent_RegistersAgainWhenForgotten(t *testing.T) {
+	repo := &mockRepository{}
+	agent := NewAgent(repo, &domain.Node{ID: "node-1"}, config.NodeConfig{HeartbeatInterval: 10 * time.Millisecond})
+	if err := agent.Register(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	repo.forget()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	agent.Start(ctx, &wg)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if repo.registrations() >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the node to register again after a missed heartbeat")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	wg.Wait()
+
+	if !repo.stopped {
+		t.Error("expected the node to be marked stopped on shutdown")
+	}
+}
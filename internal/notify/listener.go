@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/domain"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Channel is the Postgres channel the tasks trigger announces inserted tasks
+// and status changes on.
+const Channel = "task_changes"
+
+const closeTimeout = 5 * time.Second
+
+type Conn interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	WaitForNotification(context.Context) (*pgconn.Notification, error)
+	Close(context.Context) error
+}
+
+// Listener keeps a dedicated connection listening on Channel and wakes up the
+// subscribers of the announced status. A wake-up is only a hint to look into
+// the database, several changes may be folded into one.
+type Listener struct {
+	connect        func(context.Context) (Conn, error)
+	reconnectDelay time.Duration
+
+	mu   sync.Mutex
+	subs []subscription
+}
+
+type subscription struct {
+	statuses []domain.TaskStatus
+	ch       chan struct{}
+}
+
+// change is the payload of a notification.
+type change struct {
+	ID     uuid.UUID         `json:"id"`
+	Status domain.TaskStatus `json:"status"`
+}
+
+func NewListener(cfg config.DatabaseConfig) *Listener {
+	return &Listener{
+		connect: func(ctx context.Context) (Conn, error) {
+			return pgx.Connect(ctx, cfg.URL)
+		},
+		reconnectDelay: cfg.ListenReconnectDelay,
+	}
+}
+
+// Subscribe returns a channel that receives a value whenever a task enters one
+// of the statuses, or on any change if none are given.
+func (l *Listener) Subscribe(statuses ...domain.TaskStatus) <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ch := make(chan struct{}, 1)
+	l.subs = append(l.subs, subscription{statuses: statuses, ch: ch})
+	return ch
+}
+
+// Start listens until ctx is done, reconnecting after ReconnectDelay when the
+// connection is lost.
+func (l *Listener) Start(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Go(func() {
+		for {
+			err := l.listen(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Error("notify: listening for task changes", "error", err, "retry_in", l.reconnectDelay)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(l.reconnectDelay):
+			}
+		}
+	})
+}
+
+func (l *Listener) listen(ctx context.Context) error {
+	conn, err := l.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("connecting: %w", err)
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+		defer cancel()
+		if err := conn.Close(closeCtx); err != nil {
+			slog.Warn("notify: closing connection", "error", err)
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return fmt.Errorf("listening on %s: %w", Channel, err)
+	}
+	slog.Info("notify: listening for task changes", "channel", Channel)
+
+	// changes made while the connection was down are lost
+	l.wakeAll()
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("waiting for notification: %w", err)
+		}
+		l.dispatch(n.Payload)
+	}
+}
+
+func (l *Listener) dispatch(payload string) {
+	var c change
+	if err := json.Unmarshal([]byte(payload), &c); err != nil {
+		slog.Warn("notify: malformed payload", "payload", payload, "error", err)
+		l.wakeAll()
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, sub := range l.subs {
+		if len(sub.statuses) == 0 || slices.Contains(sub.statuses, c.Status) {
+			wake(sub.ch)
+		}
+	}
+}
+
+func (l *Listener) wakeAll() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, sub := range l.subs {
+		wake(sub.ch)
+	}
+}
+
+// wake signals ch without blocking, a pending signal already covers this one.
+func wake(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
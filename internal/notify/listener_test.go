@@ -0,0 +1,211 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"pinn-connect-service/internal/domain"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// --- MOCKS ---
+
+type fakeConn struct {
+	notifications chan *pgconn.Notification
+	execErr       error
+
+	mu     sync.Mutex
+	execs  []string
+	closed bool
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{notifications: make(chan *pgconn.Notification)}
+}
+
+func (c *fakeConn) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.execs = append(c.execs, sql)
+	return pgconn.CommandTag{}, c.execErr
+}
+
+func (c *fakeConn) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case n, ok := <-c.notifications:
+		if !ok {
+			return nil, errors.New("connection lost")
+		}
+		return n, nil
+	}
+}
+
+func (c *fakeConn) Close(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+// --- HELPERS ---
+
+func newTestListener(conns ...*fakeConn) *Listener {
+	var mu sync.Mutex
+	return &Listener{
+		connect: func(context.Context) (Conn, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if len(conns) == 0 {
+				return nil, errors.New("database is down")
+			}
+			conn := conns[0]
+			conns = conns[1:]
+			return conn, nil
+		},
+		reconnectDelay: 10 * time.Millisecond,
+	}
+}
+
+func received(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func drained(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return false
+	default:
+		return true
+	}
+}
+
+func notification(status domain.TaskStatus) *pgconn.Notification {
+	return &pgconn.Notification{
+		Channel: Channel,
+		Payload: `{"id":"9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d","status":"` + string(status) + `"}`,
+	}
+}
+
+// --- TESTS ---
+
+func TestDispatch_WakesMatchingSubscribers(t *testing.T) {
+	l := newTestListener()
+	queued := l.Subscribe(domain.TaskQueued)
+	scheduled := l.Subscribe(domain.TaskScheduled)
+	all := l.Subscribe()
+
+	l.dispatch(notification(domain.TaskQueued).Payload)
+
+	if drained(queued) {
+		t.Error("expected the queued subscriber to be woken up")
+	}
+	if !drained(scheduled) {
+		t.Error("expected the scheduled subscriber not to be woken up")
+	}
+	if drained(all) {
+		t.Error("expected the subscriber to all changes to be woken up")
+	}
+}
+
+func TestDispatch_FoldsWakeUps(t *testing.T) {
+	l := newTestListener()
+	queued := l.Subscribe(domain.TaskQueued)
+
+	l.dispatch(notification(domain.TaskQueued).Payload)
+	l.dispatch(notification(domain.TaskQueued).Payload)
+
+	if drained(queued) {
+		t.Fatal("expected a wake-up")
+	}
+	if !drained(queued) {
+		t.Error("expected pending wake-ups to be folded into one")
+	}
+}
+
+func TestDispatch_MalformedPayloadWakesEveryone(t *testing.T) {
+	l := newTestListener()
+	queued := l.Subscribe(domain.TaskQueued)
+	scheduled := l.Subscribe(domain.TaskScheduled)
+
+	l.dispatch("not json")
+
+	if drained(queued) || drained(scheduled) {
+		t.Error("expected all subscribers to be woken up")
+	}
+}
+
+func TestStart_ListensAndDispatches(t *testing.T) {
+	conn := newFakeConn()
+	l := newTestListener(conn)
+	queued := l.Subscribe(domain.TaskQueued)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	l.Start(ctx, &wg)
+
+	// the initial wake-up covers changes made before listening
+	if !received(queued) {
+		t.Fatal("expected a wake-up once listening")
+	}
+
+	conn.notifications <- notification(domain.TaskQueued)
+	if !received(queued) {
+		t.Error("expected a wake-up on notification")
+	}
+
+	cancel()
+	wg.Wait()
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if len(conn.execs) != 1 || conn.execs[0] != "LISTEN "+Channel {
+		t.Errorf("unexpected statements: %v", conn.execs)
+	}
+	if !conn.closed {
+		t.Error("expected the connection to be closed")
+	}
+}
+
+func TestStart_Reconnects(t *testing.T) {
+	first, second := newFakeConn(), newFakeConn()
+	l := newTestListener(first, second)
+	queued := l.Subscribe(domain.TaskQueued)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	l.Start(ctx, &wg)
+
+	if !received(queued) {
+		t.Fatal("expected a wake-up once listening")
+	}
+
+	close(first.notifications)
+	// missed changes are announced again after reconnecting
+	if !received(queued) {
+		t.Fatal("expected a wake-up after reconnecting")
+	}
+
+	second.notifications <- notification(domain.TaskQueued)
+	if !received(queued) {
+		t.Error("expected notifications from the new connection")
+	}
+
+	cancel()
+	wg.Wait()
+
+	first.mu.Lock()
+	defer first.mu.Unlock()
+	if !first.closed {
+		t.Error("expected the lost connection to be closed")
+	}
+}
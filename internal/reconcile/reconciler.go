@@ -0,0 +1,196 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/domain"
+	"pinn-connect-service/internal/storage"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Repository interface {
+	ListResultRefs(context.Context) ([]domain.ResultRef, error)
+	GetActiveTasks(context.Context) ([]*domain.Task, error)
+	SetResultMissing(ctx context.Context, id uuid.UUID, missing bool) error
+}
+
+type Storage interface {
+	ListArtifacts(ctx context.Context, prefix string) ([]domain.Artifact, error)
+	OpenArtifact(ctx context.Context, key string) (io.ReadSeekCloser, *domain.Artifact, error)
+	DeleteArtifact(ctx context.Context, key string) error
+}
+
+// Reconciler compares the objects stored under "tasks/" with the result paths
+// of the tasks table. Task deletion removes the stored result before the row,
+// so a partial failure leaves either an orphan object or a row pointing to a
+// result that no longer exists.
+type Reconciler struct {
+	repo    Repository
+	storage Storage
+	config  config.ReconcileConfig
+
+	runMu sync.Mutex
+
+	now func() time.Time
+}
+
+func NewReconciler(repo Repository, storage Storage, cfg config.ReconcileConfig) *Reconciler {
+	return &Reconciler{
+		repo:    repo,
+		storage: storage,
+		config:  cfg,
+		now:     time.Now,
+	}
+}
+
+// Run reconciles the bucket with the database once. Orphan objects are
+// deleted and tasks with a missing result are marked, unless dryRun is set.
+// It is a blocking call, concurrent calls are serialised.
+func (rc *Reconciler) Run(ctx context.Context, dryRun bool) *domain.ReconcileReport {
+	rc.runMu.Lock()
+	defer rc.runMu.Unlock()
+
+	report := &domain.ReconcileReport{
+		StartedAt:       rc.now(),
+		DryRun:          dryRun,
+		OrphanObjects:   []string{},
+		DeletedObjects:  []string{},
+		MissingResults:  []uuid.UUID{},
+		RestoredResults: []uuid.UUID{},
+	}
+	defer func() {
+		report.FinishedAt = rc.now()
+		slog.Info("reconcile: run finished",
+			"dry_run", report.DryRun,
+			"orphan_objects", len(report.OrphanObjects),
+			"orphan_bytes", report.OrphanBytes,
+			"deleted_objects", len(report.DeletedObjects),
+			"missing_results", len(report.MissingResults),
+			"restored_results", len(report.RestoredResults),
+			"errors", len(report.Errors),
+		)
+	}()
+
+	// objects are listed before the rows are read: a result uploaded in
+	// between is then referenced by a row and can't be taken for an orphan
+	objects, err := rc.storage.ListArtifacts(ctx, storage.TasksPrefix)
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("listing artifacts: %v", err))
+		return report
+	}
+	report.ScannedObjects = len(objects)
+
+	refs, err := rc.repo.ListResultRefs(ctx)
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("listing task results: %v", err))
+		return report
+	}
+	report.ScannedTasks = len(refs)
+
+	activeTasks, err := rc.repo.GetActiveTasks(ctx)
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("getting active tasks: %v", err))
+		return report
+	}
+
+	// results taken from cache point into the prefix of another task, so the
+	// whole prefix is kept while any row references it
+	referenced := make(map[string]bool, len(refs)+len(activeTasks))
+	for _, ref := range refs {
+		referenced[storage.ResultPrefix(ref.ResultPath)] = true
+	}
+	for _, task := range activeTasks {
+		referenced[storage.TaskPrefix(task.ID)] = true
+	}
+
+	rc.collectOrphans(ctx, report, objects, referenced, dryRun)
+	rc.checkResults(ctx, report, objects, refs, dryRun)
+
+	return report
+}
+
+func (rc *Reconciler) collectOrphans(ctx context.Context, report *domain.ReconcileReport,
+	objects []domain.Artifact, referenced map[string]bool, dryRun bool) {
+	threshold := rc.now().Add(-rc.config.ObjectMinAge)
+
+	for _, obj := range objects {
+		if referenced[storage.ResultPrefix(obj.Key)] || obj.LastModified.After(threshold) {
+			continue
+		}
+
+		report.OrphanObjects = append(report.OrphanObjects, obj.Key)
+		report.OrphanBytes += obj.Size
+		if dryRun {
+			continue
+		}
+
+		if err := rc.storage.DeleteArtifact(ctx, obj.Key); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("deleting object %s: %v", obj.Key, err))
+			continue
+		}
+		report.DeletedObjects = append(report.DeletedObjects, obj.Key)
+	}
+}
+
+func (rc *Reconciler) checkResults(ctx context.Context, report *domain.ReconcileReport,
+	objects []domain.Artifact, refs []domain.ResultRef, dryRun bool) {
+	stored := make(map[string]bool, len(objects))
+	for _, obj := range objects {
+		stored[obj.Key] = true
+	}
+
+	for _, ref := range refs {
+		exists := stored[ref.ResultPath]
+		if !exists {
+			// the result may have been uploaded after the listing
+			var err error
+			if exists, err = rc.exists(ctx, ref.ResultPath); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("checking result of task %s: %v", ref.TaskID, err))
+				continue
+			}
+		}
+
+		switch {
+		case !exists:
+			report.MissingResults = append(report.MissingResults, ref.TaskID)
+			if ref.ResultMissing || dryRun {
+				continue
+			}
+			if err := rc.repo.SetResultMissing(ctx, ref.TaskID, true); err != nil {
+				report.Errors = append(report.Errors, err.Error())
+			}
+		case ref.ResultMissing:
+			report.RestoredResults = append(report.RestoredResults, ref.TaskID)
+			if dryRun {
+				continue
+			}
+			if err := rc.repo.SetResultMissing(ctx, ref.TaskID, false); err != nil {
+				report.Errors = append(report.Errors, err.Error())
+			}
+		}
+	}
+}
+
+func (rc *Reconciler) exists(ctx context.Context, key string) (bool, error) {
+	r, _, err := rc.storage.OpenArtifact(ctx, key)
+	if errors.Is(err, domain.ErrArtifactNotFound) {
+		return false, nil
+	}
+	// a corrupted object is still there, verification deals with it
+	if errors.Is(err, domain.ErrArtifactCorrupted) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	r.Close()
+
+	return true, nil
+}
@@ -0,0 +1,177 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"io"
+	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/domain"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// --- MOCKS ---
+
+type mockRepo struct {
+	refs    []domain.ResultRef
+	active  []*domain.Task
+	refsErr error
+	marked  map[uuid.UUID]bool
+}
+
+func (m *mockRepo) ListResultRefs(context.Context) ([]domain.ResultRef, error) {
+	return m.refs, m.refsErr
+}
+func (m *mockRepo) GetActiveTasks(context.Context) ([]*domain.Task, error) {
+	return m.active, nil
+}
+func (m *mockRepo) SetResultMissing(_ context.Context, id uuid.UUID, missing bool) error {
+	if m.marked == nil {
+		m.marked = make(map[uuid.UUID]bool)
+	}
+	m.marked[id] = missing
+	return nil
+}
+
+type nopSeekCloser struct{ io.ReadSeeker }
+
+func (nopSeekCloser) Close() error { return nil }
+
+type mockStorage struct {
+	objects []domain.Artifact
+	// late objects appear after the listing
+	late    map[string]bool
+	deleted []string
+}
+
+func (m *mockStorage) ListArtifacts(_ context.Context, prefix string) ([]domain.Artifact, error) {
+	var res []domain.Artifact
+	for _, obj := range m.objects {
+		if strings.HasPrefix(obj.Key, prefix) {
+			res = append(res, obj)
+		}
+	}
+	return res, nil
+}
+func (m *mockStorage) OpenArtifact(_ context.Context, key string) (io.ReadSeekCloser, *domain.Artifact, error) {
+	if m.late[key] {
+		return nopSeekCloser{strings.NewReader("")}, &domain.Artifact{Key: key}, nil
+	}
+	return nil, nil, domain.ErrArtifactNotFound
+}
+func (m *mockStorage) DeleteArtifact(_ context.Context, key string) error {
+	m.deleted = append(m.deleted, key)
+	return nil
+}
+
+// --- HELPERS ---
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func object(owner uuid.UUID, name string, age time.Duration) domain.Artifact {
+	return domain.Artifact{Key: "tasks/" + owner.String() + "/" + name, Size: 10, LastModified: now.Add(-age)}
+}
+
+func ref(owner uuid.UUID) domain.ResultRef {
+	return domain.ResultRef{TaskID: uuid.New(), Status: domain.TaskCompleted, ResultPath: "tasks/" + owner.String() + "/result.txt"}
+}
+
+func newTestReconciler(repo *mockRepo, storage *mockStorage) *Reconciler {
+	rc := NewReconciler(repo, storage, config.ReconcileConfig{ObjectMinAge: time.Hour})
+	rc.now = func() time.Time { return now }
+	return rc
+}
+
+// --- TESTS ---
+
+func TestRun_DeletesOrphanObjects(t *testing.T) {
+	owner, orphan, active, fresh := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	r := ref(owner)
+	repo := &mockRepo{refs: []domain.ResultRef{r}, active: []*domain.Task{{ID: active}}}
+	storage := &mockStorage{objects: []domain.Artifact{
+		object(owner, "result.txt", 2*time.Hour),
+		object(owner, "sub/log.txt", 2*time.Hour),
+		object(orphan, "result.txt", 2*time.Hour),
+		object(active, "result.txt", 2*time.Hour),
+		object(fresh, "result.txt", time.Minute),
+	}}
+
+	report := newTestReconciler(repo, storage).Run(context.Background(), false)
+
+	want := "tasks/" + orphan.String() + "/result.txt"
+	if len(report.OrphanObjects) != 1 || report.OrphanObjects[0] != want {
+		t.Fatalf("expected only %s to be orphan, got %v", want, report.OrphanObjects)
+	}
+	if len(storage.deleted) != 1 || storage.deleted[0] != want || report.OrphanBytes != 10 {
+		t.Errorf("unexpected deletion: %v (%d bytes)", storage.deleted, report.OrphanBytes)
+	}
+	if len(report.MissingResults) != 0 || len(repo.marked) != 0 {
+		t.Errorf("expected no missing results, got %v", report.MissingResults)
+	}
+}
+
+func TestRun_MarksMissingAndRestoredResults(t *testing.T) {
+	owner := uuid.New()
+	missing := ref(uuid.New())
+	known := ref(uuid.New())
+	known.ResultMissing = true
+	restored := ref(owner)
+	restored.ResultMissing = true
+	repo := &mockRepo{refs: []domain.ResultRef{missing, known, restored}}
+	storage := &mockStorage{objects: []domain.Artifact{object(owner, "result.txt", 2*time.Hour)}}
+
+	report := newTestReconciler(repo, storage).Run(context.Background(), false)
+
+	if len(report.MissingResults) != 2 {
+		t.Fatalf("expected 2 missing results, got %v", report.MissingResults)
+	}
+	if len(report.RestoredResults) != 1 || report.RestoredResults[0] != restored.TaskID {
+		t.Errorf("expected restored %s, got %v", restored.TaskID, report.RestoredResults)
+	}
+	if len(repo.marked) != 2 || !repo.marked[missing.TaskID] || repo.marked[restored.TaskID] {
+		t.Errorf("unexpected marks: %v", repo.marked)
+	}
+}
+
+// A result uploaded between the listing and the read of the rows is not missing.
+func TestRun_RechecksResultUploadedAfterListing(t *testing.T) {
+	r := ref(uuid.New())
+	repo := &mockRepo{refs: []domain.ResultRef{r}}
+	storage := &mockStorage{late: map[string]bool{r.ResultPath: true}}
+
+	report := newTestReconciler(repo, storage).Run(context.Background(), false)
+
+	if len(report.MissingResults) != 0 || len(repo.marked) != 0 {
+		t.Errorf("expected no missing results, got %v", report.MissingResults)
+	}
+}
+
+func TestRun_DryRunChangesNothing(t *testing.T) {
+	orphan := uuid.New()
+	missing := ref(uuid.New())
+	repo := &mockRepo{refs: []domain.ResultRef{missing}}
+	storage := &mockStorage{objects: []domain.Artifact{object(orphan, "result.txt", 2*time.Hour)}}
+
+	report := newTestReconciler(repo, storage).Run(context.Background(), true)
+
+	if !report.DryRun || len(report.OrphanObjects) != 1 || len(report.MissingResults) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if len(storage.deleted) != 0 || len(report.DeletedObjects) != 0 || len(repo.marked) != 0 {
+		t.Errorf("dry run must not change anything, deleted %v, marked %v", storage.deleted, repo.marked)
+	}
+}
+
+func TestRun_ListError(t *testing.T) {
+	repo := &mockRepo{refsErr: errors.New("db down")}
+	storage := &mockStorage{objects: []domain.Artifact{object(uuid.New(), "result.txt", 2*time.Hour)}}
+
+	report := newTestReconciler(repo, storage).Run(context.Background(), false)
+
+	if len(report.Errors) != 1 || len(storage.deleted) != 0 {
+		t.Errorf("expected an error and no deletions, got %v / %v", report.Errors, storage.deleted)
+	}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"pinn-connect-service/internal/db"
+	"pinn-connect-service/internal/domain"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxEventReason bounds the reason of an event, failure reasons may carry
+// the logs of the container.
+const maxEventReason = 1024
+
+// recordEvent saves a status transition of the task. It runs in the
+// transaction of the transition, which fails along with it.
+func recordEvent(ctx context.Context, q *db.Queries, taskID uuid.UUID, from, to domain.TaskStatus, change domain.StatusChange) error {
+	reason := change.Reason
+	if len(reason) > maxEventReason {
+		// drop a rune cut in half
+		reason = strings.ToValidUTF8(reason[:maxEventReason], "") + "..."
+	}
+
+	err := q.CreateTaskEvent(ctx, db.CreateTaskEventParams{
+		TaskID:     pgtype.UUID{Bytes: taskID, Valid: true},
+		FromStatus: db.NullTaskStatus{TaskStatus: db.TaskStatus(from), Valid: from != ""},
+		ToStatus:   db.TaskStatus(to),
+		Actor:      string(change.Actor),
+		NodeID:     pgtype.Text{String: change.NodeID, Valid: change.NodeID != ""},
+		Reason:     reason,
+	})
+	if err != nil {
+		return fmt.Errorf("recording task event: %w", err)
+	}
+
+	return nil
+}
+
+// ListTaskEvents returns the status transitions of the task, oldest first.
+func (r *TaskRepository) ListTaskEvents(ctx context.Context, taskID uuid.UUID) ([]domain.TaskEvent, error) {
+	rows, err := r.queries.ListTaskEvents(ctx, pgtype.UUID{Bytes: taskID, Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("listing task events: %w", err)
+	}
+
+	events := make([]domain.TaskEvent, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, domain.TaskEvent{
+			ID:        row.ID,
+			TaskID:    uuid.UUID(row.TaskID.Bytes),
+			From:      domain.TaskStatus(row.FromStatus.TaskStatus),
+			To:        domain.TaskStatus(row.ToStatus),
+			Actor:     domain.TaskActor(row.Actor),
+			NodeID:    row.NodeID.String,
+			Reason:    row.Reason,
+			CreatedAt: row.CreatedAt.Time,
+		})
+	}
+
+	return events, nil
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"pinn-connect-service/internal/db"
+	"pinn-connect-service/internal/domain"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var taskEventColumns = []string{"id", "task_id", "from_status", "to_status", "actor", "node_id", "reason", "created_at"}
+
+func TestTaskRepository_Mark_RecordsEvent(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE tasks`).
+		WithArgs(anyArgs(7)...).
+		WillReturnRows(pgxmock.NewRows(taskColumns).AddRow(taskRow(id, db.TaskStatusFailed)...))
+	mock.ExpectExec(`INSERT INTO task_events`).
+		WithArgs(
+			pgtype.UUID{Bytes: id, Valid: true},
+			db.NullTaskStatus{TaskStatus: db.TaskStatusRunning, Valid: true},
+			db.TaskStatusFailed,
+			"worker",
+			pgtype.Text{String: "node-1", Valid: true},
+			"exit code 1",
+		).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	task := &domain.Task{ID: id, Status: domain.TaskRunning, ErrorLog: "exit code 1"}
+	change := domain.StatusChange{Actor: domain.ActorWorker, NodeID: "node-1", Reason: "exit code 1"}
+	if err := repo.Mark(context.Background(), task, domain.TaskFailed, change); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// The event is written in the transaction of the transition, a failed insert
+// rolls the status change back.
+func TestTaskRepository_Mark_EventError_RollsBack(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE tasks`).
+		WithArgs(anyArgs(5)...).
+		WillReturnRows(pgxmock.NewRows(taskColumns).AddRow(taskRow(id, db.TaskStatusQueued)...))
+	mock.ExpectExec(`INSERT INTO task_events`).
+		WithArgs(anyArgs(6)...).
+		WillReturnError(errors.New("db error"))
+	mock.ExpectRollback()
+
+	task := &domain.Task{ID: id, Status: domain.TaskRunning}
+	if err := repo.Mark(context.Background(), task, domain.TaskQueued, domain.StatusChange{}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if task.Status != domain.TaskRunning {
+		t.Errorf("expected TaskRunning, got %v", task.Status)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestTaskRepository_RecordEvent_TruncatesReason(t *testing.T) {
+	_, mock := newTaskRepoMock(t)
+
+	// the cut falls into the middle of a two-byte rune
+	reason := "a" + strings.Repeat("я", maxEventReason)
+	want := "a" + strings.Repeat("я", (maxEventReason-1)/2) + "..."
+	mock.ExpectExec(`INSERT INTO task_events`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), want).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	if err := recordEvent(context.Background(), db.New(mock), uuid.New(), domain.TaskRunning, domain.TaskFailed, domain.StatusChange{Reason: reason}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestTaskRepository_ListTaskEvents_Success(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+	id := uuid.New()
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT .* FROM task_events`).
+		WithArgs(pgtype.UUID{Bytes: id, Valid: true}).
+		WillReturnRows(pgxmock.NewRows(taskEventColumns).
+			AddRow(int64(1), pgtype.UUID{Bytes: id, Valid: true}, db.NullTaskStatus{}, db.TaskStatusQueued,
+				"api", pgtype.Text{String: "api-1", Valid: true}, "", pgtype.Timestamptz{Time: now, Valid: true}).
+			AddRow(int64(2), pgtype.UUID{Bytes: id, Valid: true}, db.NullTaskStatus{TaskStatus: db.TaskStatusQueued, Valid: true}, db.TaskStatusRunning,
+				"worker", pgtype.Text{String: "node-1", Valid: true}, "", pgtype.Timestamptz{Time: now, Valid: true}))
+
+	events, err := repo.ListTaskEvents(context.Background(), id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].From != "" || events[0].To != domain.TaskQueued || events[0].Actor != domain.ActorAPI {
+		t.Errorf("unexpected creation event %+v", events[0])
+	}
+	if events[1].From != domain.TaskQueued || events[1].To != domain.TaskRunning || events[1].NodeID != "node-1" {
+		t.Errorf("unexpected claim event %+v", events[1])
+	}
+}
+
+func TestTaskRepository_ListTaskEvents_DBError(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+
+	mock.ExpectQuery(`SELECT .* FROM task_events`).
+		WithArgs(pgxmock.AnyArg()).
+		WillReturnError(errors.New("db error"))
+
+	if _, err := repo.ListTaskEvents(context.Background(), uuid.New()); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -2,8 +2,10 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"pinn-connect-service/internal/db"
 	"pinn-connect-service/internal/domain"
 
@@ -86,11 +88,57 @@ func (r *ModelRepository) DeleteModel(ctx context.Context, modelID string) error
 	return nil
 }
 
+// SetNetworkMode sets the network mode of the tasks of a model, an empty mode
+// falls back to the default of the service.
+func (r *ModelRepository) SetNetworkMode(ctx context.Context, modelID string, mode domain.NetworkMode) error {
+	n, err := r.queries.SetModelNetworkMode(ctx, db.SetModelNetworkModeParams{
+		ID:          modelID,
+		NetworkMode: db.NullNetworkMode{NetworkMode: db.NetworkMode(mode), Valid: mode != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("setting model network mode: %w", err)
+	}
+	if n == 0 {
+		return domain.ErrModelNotFound
+	}
+	return nil
+}
+
+// SetSecurity sets the security overrides of the tasks of a model, nil or
+// empty overrides fall back to the profile of the service.
+func (r *ModelRepository) SetSecurity(ctx context.Context, modelID string, security *domain.ModelSecurity) error {
+	var raw []byte
+	if !security.IsZero() {
+		var err error
+		if raw, err = json.Marshal(security); err != nil {
+			return fmt.Errorf("encoding model security: %w", err)
+		}
+	}
+
+	n, err := r.queries.SetModelSecurity(ctx, db.SetModelSecurityParams{ID: modelID, Security: raw})
+	if err != nil {
+		return fmt.Errorf("setting model security: %w", err)
+	}
+	if n == 0 {
+		return domain.ErrModelNotFound
+	}
+	return nil
+}
+
 func dbModelToDomainModel(dbm *db.Model) *domain.Model {
-	return &domain.Model{
+	m := &domain.Model{
 		ID:             dbm.ID,
 		ContainerImage: dbm.ContainerImage,
 		CreatedAt:      dbm.CreatedAt.Time,
 		UpdatedAt:      dbm.UpdatedAt.Time,
+		NetworkMode:    domain.NetworkMode(dbm.NetworkMode.NetworkMode),
+	}
+	// a broken override falls back to the hardened profile of the service
+	if len(dbm.Security) > 0 {
+		if err := json.Unmarshal(dbm.Security, &m.Security); err != nil {
+			slog.Warn("decoding model security", "id", dbm.ID, "error", err)
+			m.Security = nil
+		}
 	}
+	return m
 }
@@ -26,11 +26,11 @@ func newModelRepoMock(t *testing.T) (*ModelRepository, pgxmock.PgxPoolIface) {
 	return &ModelRepository{queries: db.New(mock)}, mock
 }
 
-var modelColumns = []string{"id", "container_image", "created_at", "updated_at"}
+var modelColumns = []string{"id", "container_image", "created_at", "updated_at", "network_mode", "security"}
 
 func modelRow(id string) []any {
 	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
-	return []any{id, "pinn-model-" + id + ":latest", now, now}
+	return []any{id, "pinn-model-" + id + ":latest", now, now, db.NullNetworkMode{}, []byte(nil)}
 }
 
 // ─────────────────────────────────────────────
@@ -97,7 +97,8 @@ func TestModelRepository_GetModelByID_FieldMapping(t *testing.T) {
 	mock.ExpectQuery(`SELECT`).
 		WithArgs(pgxmock.AnyArg()).
 		WillReturnRows(pgxmock.NewRows(modelColumns).
-			AddRow("m42", "custom-img:v2", now, now))
+			AddRow("m42", "custom-img:v2", now, now, db.NullNetworkMode{NetworkMode: db.NetworkModeInternal, Valid: true},
+				[]byte(`{"user":"","cap_add":["NET_ADMIN"]}`)))
 
 	model, err := repo.GetModelByID(context.Background(), "m42")
 	if err != nil {
@@ -109,6 +110,13 @@ func TestModelRepository_GetModelByID_FieldMapping(t *testing.T) {
 	if model.CreatedAt.IsZero() {
 		t.Error("expected CreatedAt to be populated")
 	}
+	if model.NetworkMode != domain.NetworkInternal {
+		t.Errorf("expected network mode internal, got %q", model.NetworkMode)
+	}
+	if model.Security == nil || model.Security.User == nil || *model.Security.User != "" ||
+		len(model.Security.CapAdd) != 1 || model.Security.CapAdd[0] != "NET_ADMIN" {
+		t.Errorf("expected the security overrides, got %+v", model.Security)
+	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("unmet expectations: %v", err)
 	}
@@ -312,7 +320,7 @@ func TestModelRepository_CreateModel_FieldMapping(t *testing.T) {
 	mock.ExpectQuery(`INSERT INTO`).
 		WithArgs(anyArgs(2)...).
 		WillReturnRows(pgxmock.NewRows(modelColumns).
-			AddRow("m1", "img:v1", now, now))
+			AddRow("m1", "img:v1", now, now, db.NullNetworkMode{}, []byte(nil)))
 
 	model, err := repo.CreateModel(context.Background(), "m1", "img:v1")
 	if err != nil {
@@ -421,3 +429,87 @@ func TestDbModelToDomainModel_ReturnType(t *testing.T) {
 		t.Error("expected *domain.Model return type")
 	}
 }
+
+// ─────────────────────────────────────────────
+// SetNetworkMode
+// ─────────────────────────────────────────────
+
+func TestModelRepository_SetNetworkMode(t *testing.T) {
+	repo, mock := newModelRepoMock(t)
+
+	mock.ExpectExec(`UPDATE models`).
+		WithArgs("m1", db.NullNetworkMode{NetworkMode: db.NetworkModeEgress, Valid: true}).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	// an empty mode clears the override
+	mock.ExpectExec(`UPDATE models`).
+		WithArgs("m1", db.NullNetworkMode{}).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	if err := repo.SetNetworkMode(context.Background(), "m1", domain.NetworkEgress); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.SetNetworkMode(context.Background(), "m1", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestModelRepository_SetNetworkMode_NotFound(t *testing.T) {
+	repo, mock := newModelRepoMock(t)
+
+	mock.ExpectExec(`UPDATE models`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	if err := repo.SetNetworkMode(context.Background(), "missing", domain.NetworkNone); !errors.Is(err, domain.ErrModelNotFound) {
+		t.Errorf("expected ErrModelNotFound, got %v", err)
+	}
+}
+
+// ─────────────────────────────────────────────
+// SetSecurity
+// ─────────────────────────────────────────────
+
+func TestModelRepository_SetSecurity(t *testing.T) {
+	repo, mock := newModelRepoMock(t)
+	readOnly := false
+
+	mock.ExpectExec(`UPDATE models`).
+		WithArgs("m1", []byte(`{"read_only_rootfs":false}`)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	// empty overrides are cleared
+	mock.ExpectExec(`UPDATE models`).
+		WithArgs("m1", []byte(nil)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	if err := repo.SetSecurity(context.Background(), "m1", &domain.ModelSecurity{ReadOnlyRootfs: &readOnly}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.SetSecurity(context.Background(), "m1", &domain.ModelSecurity{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestModelRepository_SetSecurity_NotFound(t *testing.T) {
+	repo, mock := newModelRepoMock(t)
+
+	mock.ExpectExec(`UPDATE models`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	if err := repo.SetSecurity(context.Background(), "missing", nil); !errors.Is(err, domain.ErrModelNotFound) {
+		t.Errorf("expected ErrModelNotFound, got %v", err)
+	}
+}
+
+func TestDbModelToDomainModel_BrokenSecurity(t *testing.T) {
+	got := dbModelToDomainModel(&db.Model{ID: "m1", Security: []byte("{broken")})
+	if got.Security != nil {
+		t.Errorf("expected a broken override to be dropped, got %+v", got.Security)
+	}
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"pinn-connect-service/internal/db"
+	"pinn-connect-service/internal/domain"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type NodeRepository struct {
+	queries *db.Queries
+}
+
+func NewNodeRepository(pool *pgxpool.Pool) *NodeRepository {
+	return &NodeRepository{queries: db.New(pool)}
+}
+
+// Register creates the node or, for a restarted node, resets its start time.
+func (r *NodeRepository) Register(ctx context.Context, node *domain.Node) error {
+	labels := []byte("{}")
+	if len(node.Labels) > 0 {
+		var err error
+		if labels, err = json.Marshal(node.Labels); err != nil {
+			return fmt.Errorf("encoding node labels: %w", err)
+		}
+	}
+
+	if err := r.queries.RegisterNode(ctx, db.RegisterNodeParams{
+		ID:       node.ID,
+		Hostname: node.Hostname,
+		Capacity: int32(node.Capacity),
+		Labels:   labels,
+	}); err != nil {
+		return fmt.Errorf("registering node: %w", err)
+	}
+	return nil
+}
+
+// Heartbeat renews the heartbeat of the node. It reports false if the node is
+// not registered or was stopped.
+func (r *NodeRepository) Heartbeat(ctx context.Context, id string) (bool, error) {
+	n, err := r.queries.HeartbeatNode(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("sending heartbeat: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *NodeRepository) Stop(ctx context.Context, id string) error {
+	if err := r.queries.StopNode(ctx, id); err != nil {
+		return fmt.Errorf("stopping node: %w", err)
+	}
+	return nil
+}
+
+// List returns all nodes. A node is alive unless it was stopped or its last
+// heartbeat is older than timeout.
+func (r *NodeRepository) List(ctx context.Context, timeout time.Duration) ([]domain.Node, error) {
+	rows, err := r.queries.ListNodes(ctx, timeout.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("listing nodes: %w", err)
+	}
+
+	nodes := make([]domain.Node, 0, len(rows))
+	for _, row := range rows {
+		node := domain.Node{
+			ID:           row.ID,
+			Hostname:     row.Hostname,
+			Capacity:     int(row.Capacity),
+			StartedAt:    row.StartedAt.Time,
+			HeartbeatAt:  row.HeartbeatAt.Time,
+			RunningTasks: row.RunningTasks,
+			Alive:        row.Alive,
+		}
+		if row.StoppedAt.Valid {
+			node.StoppedAt = &row.StoppedAt.Time
+		}
+		if err := json.Unmarshal(row.Labels, &node.Labels); err != nil {
+			return nil, fmt.Errorf("decoding labels of node %s: %w", row.ID, err)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
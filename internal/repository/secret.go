@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"pinn-connect-service/internal/db"
+	"pinn-connect-service/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SecretRepository struct {
+	queries *db.Queries
+}
+
+func NewSecretRepository(pool *pgxpool.Pool) *SecretRepository {
+	return &SecretRepository{queries: db.New(pool)}
+}
+
+// ListSecrets returns the names of the stored secrets without their values.
+func (r *SecretRepository) ListSecrets(ctx context.Context) ([]domain.Secret, error) {
+	rows, err := r.queries.ListSecrets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing secrets: %w", err)
+	}
+
+	result := []domain.Secret{}
+	for _, row := range rows {
+		result = append(result, domain.Secret{
+			Name:      row.Name,
+			CreatedAt: row.CreatedAt.Time,
+			UpdatedAt: row.UpdatedAt.Time,
+		})
+	}
+
+	return result, nil
+}
+
+// GetSecrets returns the sealed values of the named secrets. Missing secrets
+// are skipped.
+func (r *SecretRepository) GetSecrets(ctx context.Context, names []string) ([]domain.SealedSecret, error) {
+	rows, err := r.queries.GetSecrets(ctx, names)
+	if err != nil {
+		return nil, fmt.Errorf("getting secrets: %w", err)
+	}
+
+	result := make([]domain.SealedSecret, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, domain.SealedSecret{Name: row.Name, KeyID: row.KeyID, Value: row.Value})
+	}
+
+	return result, nil
+}
+
+func (r *SecretRepository) CreateSecret(ctx context.Context, secret domain.SealedSecret) error {
+	n, err := r.queries.CreateSecret(ctx, db.CreateSecretParams{
+		Name:  secret.Name,
+		KeyID: secret.KeyID,
+		Value: secret.Value,
+	})
+	if err != nil {
+		return fmt.Errorf("creating secret: %w", err)
+	}
+	if n == 0 {
+		return domain.ErrSecretExists
+	}
+	return nil
+}
+
+func (r *SecretRepository) UpdateSecret(ctx context.Context, secret domain.SealedSecret) error {
+	n, err := r.queries.UpdateSecret(ctx, db.UpdateSecretParams{
+		Name:  secret.Name,
+		KeyID: secret.KeyID,
+		Value: secret.Value,
+	})
+	if err != nil {
+		return fmt.Errorf("updating secret: %w", err)
+	}
+	if n == 0 {
+		return domain.ErrSecretNotFound
+	}
+	return nil
+}
+
+func (r *SecretRepository) DeleteSecret(ctx context.Context, name string) error {
+	n, err := r.queries.DeleteSecret(ctx, name)
+	if err != nil {
+		return fmt.Errorf("deleting secret: %w", err)
+	}
+	if n == 0 {
+		return domain.ErrSecretNotFound
+	}
+	return nil
+}
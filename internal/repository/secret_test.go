@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"pinn-connect-service/internal/db"
+	"pinn-connect-service/internal/domain"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func newSecretRepoMock(t *testing.T) (*SecretRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("pgxmock.NewPool: %v", err)
+	}
+	return &SecretRepository{queries: db.New(mock)}, mock
+}
+
+func TestSecretRepository_ListSecrets(t *testing.T) {
+	repo, mock := newSecretRepoMock(t)
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	mock.ExpectQuery(`SELECT name, created_at, updated_at FROM secrets`).
+		WillReturnRows(pgxmock.NewRows([]string{"name", "created_at", "updated_at"}).
+			AddRow("api-key", now, now).
+			AddRow("db-password", now, now))
+
+	secrets, err := repo.ListSecrets(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(secrets) != 2 || secrets[0].Name != "api-key" || secrets[1].Name != "db-password" {
+		t.Errorf("unexpected secrets %+v", secrets)
+	}
+}
+
+func TestSecretRepository_GetSecrets(t *testing.T) {
+	repo, mock := newSecretRepoMock(t)
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	mock.ExpectQuery(`FROM secrets`).
+		WithArgs([]string{"api-key", "missing"}).
+		WillReturnRows(pgxmock.NewRows([]string{"name", "key_id", "value", "created_at", "updated_at"}).
+			AddRow("api-key", "k1", []byte("sealed"), now, now))
+
+	secrets, err := repo.GetSecrets(context.Background(), []string{"api-key", "missing"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(secrets) != 1 || secrets[0].KeyID != "k1" || string(secrets[0].Value) != "sealed" {
+		t.Errorf("unexpected secrets %+v", secrets)
+	}
+}
+
+func TestSecretRepository_CreateSecret_Exists(t *testing.T) {
+	repo, mock := newSecretRepoMock(t)
+
+	mock.ExpectExec(`INSERT INTO secrets`).
+		WithArgs("api-key", "k1", []byte("sealed")).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+
+	err := repo.CreateSecret(context.Background(), domain.SealedSecret{Name: "api-key", KeyID: "k1", Value: []byte("sealed")})
+	if !errors.Is(err, domain.ErrSecretExists) {
+		t.Errorf("expected ErrSecretExists, got %v", err)
+	}
+}
+
+func TestSecretRepository_UpdateSecret_NotFound(t *testing.T) {
+	repo, mock := newSecretRepoMock(t)
+
+	mock.ExpectExec(`UPDATE secrets`).
+		WithArgs("api-key", "k1", []byte("sealed")).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	err := repo.UpdateSecret(context.Background(), domain.SealedSecret{Name: "api-key", KeyID: "k1", Value: []byte("sealed")})
+	if !errors.Is(err, domain.ErrSecretNotFound) {
+		t.Errorf("expected ErrSecretNotFound, got %v", err)
+	}
+}
+
+func TestSecretRepository_DeleteSecret(t *testing.T) {
+	repo, mock := newSecretRepoMock(t)
+
+	mock.ExpectExec(`DELETE FROM secrets`).
+		WithArgs("api-key").
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectExec(`DELETE FROM secrets`).
+		WithArgs("api-key").
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	if err := repo.DeleteSecret(context.Background(), "api-key"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.DeleteSecret(context.Background(), "api-key"); !errors.Is(err, domain.ErrSecretNotFound) {
+		t.Errorf("expected ErrSecretNotFound, got %v", err)
+	}
+}
@@ -2,8 +2,10 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"pinn-connect-service/internal/db"
 	"pinn-connect-service/internal/domain"
 	"time"
@@ -15,14 +17,44 @@ import (
 )
 
 type TaskRepository struct {
+	pool    txBeginner
 	queries *db.Queries
 }
 
+// txBeginner starts the transactions a status change and its event are
+// written in.
+type txBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
 func NewTaskRepository(pool *pgxpool.Pool) *TaskRepository {
-	return &TaskRepository{queries: db.New(pool)}
+	return &TaskRepository{pool: pool, queries: db.New(pool)}
+}
+
+// inTx runs fn with queries bound to a transaction, which is committed if fn
+// succeeds and rolled back otherwise.
+func (r *TaskRepository) inTx(ctx context.Context, fn func(q *db.Queries) error) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+
+	if err := fn(r.queries.WithTx(tx)); err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			slog.Warn("rolling back transaction", "error", rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return nil
 }
 
-func (r *TaskRepository) Create(ctx context.Context, task *domain.Task) error {
+// Create inserts the task and records its creation in the event log.
+func (r *TaskRepository) Create(ctx context.Context, task *domain.Task, change domain.StatusChange) error {
 	var pgScheduledAt pgtype.Timestamptz
 	if task.ScheduledAt != nil {
 		pgScheduledAt = pgtype.Timestamptz{Time: *task.ScheduledAt, Valid: true}
@@ -33,22 +65,58 @@ func (r *TaskRepository) Create(ctx context.Context, task *domain.Task) error {
 		pgstatus = domain.TaskInitializing
 	}
 
-	dbtask, err := r.queries.CreateTask(ctx, db.CreateTaskParams{
-		ID:             pgtype.UUID{Bytes: task.ID, Valid: true},
-		ModelID:        task.ModelID,
-		InputFilename:  task.InputFilename,
-		Signature:      task.Signature,
-		Status:         db.TaskStatus(pgstatus),
-		ScheduledAt:    pgScheduledAt,
-		ContainerImage: pgtype.Text{String: task.ContainerImage, Valid: task.ContainerImage != ""},
-		ContainerEnvs:  task.ContainerEnvs,
-		ContainerCmd:   task.ContainerCmd,
-		ErrorLog:       pgtype.Text{String: task.ErrorLog, Valid: true},
-		MemLim:         pgtype.Int4{Int32: int32(task.MemLim), Valid: true},
-		CpuLim:         pgtype.Int4{Int32: int32(task.CPULim), Valid: true},
-		GpuEnable:      pgtype.Bool{Bool: task.GPUEnabled, Valid: true},
-		ResultPath:     pgtype.Text{String: task.ResultPath, Valid: true},
-		TimeoutSec:     int32(task.TimeoutSec),
+	constraints := []byte("{}")
+	if len(task.Constraints) > 0 {
+		var err error
+		if constraints, err = json.Marshal(task.Constraints); err != nil {
+			return fmt.Errorf("encoding task constraints: %w", err)
+		}
+	}
+
+	secrets := []byte("{}")
+	if len(task.Secrets) > 0 {
+		var err error
+		if secrets, err = json.Marshal(task.Secrets); err != nil {
+			return fmt.Errorf("encoding task secrets: %w", err)
+		}
+	}
+
+	networkMode := task.NetworkMode
+	if networkMode == "" {
+		networkMode = domain.NetworkNone
+	}
+
+	var dbtask db.Task
+	err := r.inTx(ctx, func(q *db.Queries) error {
+		var err error
+		dbtask, err = q.CreateTask(ctx, db.CreateTaskParams{
+			ID:             pgtype.UUID{Bytes: task.ID, Valid: true},
+			ModelID:        task.ModelID,
+			InputFilename:  task.InputFilename,
+			Signature:      task.Signature,
+			Status:         db.TaskStatus(pgstatus),
+			ScheduledAt:    pgScheduledAt,
+			ContainerImage: pgtype.Text{String: task.ContainerImage, Valid: task.ContainerImage != ""},
+			ContainerEnvs:  task.ContainerEnvs,
+			ContainerCmd:   task.ContainerCmd,
+			ErrorLog:       pgtype.Text{String: task.ErrorLog, Valid: true},
+			MemLim:         pgtype.Int4{Int32: int32(task.MemLim), Valid: true},
+			CpuLim:         pgtype.Int4{Int32: int32(task.CPULim), Valid: true},
+			GpuEnable:      pgtype.Bool{Bool: task.GPUEnabled, Valid: true},
+			ResultPath:     pgtype.Text{String: task.ResultPath, Valid: true},
+			TimeoutSec:     int32(task.TimeoutSec),
+			KeepForSec:     int32(task.KeepForSec),
+			InputSha256:    task.InputSHA256,
+			Constraints:    constraints,
+			SecretEnvs:     secrets,
+			NetworkMode:    db.NetworkMode(networkMode),
+			DiskLim:        int32(task.DiskLim),
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, q, task.ID, "", domain.TaskStatus(dbtask.Status), change)
 	})
 	if err != nil {
 		return fmt.Errorf("creating task: %w", err)
@@ -57,6 +125,8 @@ func (r *TaskRepository) Create(ctx context.Context, task *domain.Task) error {
 	task.CreatedAt = dbtask.CreatedAt.Time
 	task.UpdatedAt = dbtask.UpdatedAt.Time
 	task.Status = domain.TaskStatus(dbtask.Status)
+	task.Version = int(dbtask.Version)
+	task.NetworkMode = domain.NetworkMode(dbtask.NetworkMode)
 
 	return nil
 }
@@ -73,8 +143,23 @@ func (r *TaskRepository) GetTaskById(ctx context.Context, uuid uuid.UUID) (*doma
 	return dbTaskToDomainTask(&dbtask), nil
 }
 
-func (r *TaskRepository) GetNextQueuedTask(ctx context.Context) (*domain.Task, error) {
-	dbtask, err := r.queries.GetNextQueuedTask(ctx)
+// GetNextQueuedTask claims the next queued task for the node with a lease of
+// the given duration.
+func (r *TaskRepository) GetNextQueuedTask(ctx context.Context, nodeID string, lease time.Duration) (*domain.Task, error) {
+	var dbtask db.Task
+	err := r.inTx(ctx, func(q *db.Queries) error {
+		var err error
+		dbtask, err = q.GetNextQueuedTask(ctx, db.GetNextQueuedTaskParams{
+			NodeID:   pgtype.Text{String: nodeID, Valid: true},
+			LeaseSec: lease.Seconds(),
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, q, dbtask.ID.Bytes, domain.TaskQueued, domain.TaskStatus(dbtask.Status),
+			domain.StatusChange{Actor: domain.ActorWorker, NodeID: nodeID})
+	})
 	if err != nil && errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
 	}
@@ -85,6 +170,56 @@ func (r *TaskRepository) GetNextQueuedTask(ctx context.Context) (*domain.Task, e
 	return dbTaskToDomainTask(&dbtask), nil
 }
 
+// RenewLease extends the lease of the task held by the node. Tasks without a
+// node are taken over. It reports false if the task was deleted or reclaimed
+// by another node.
+func (r *TaskRepository) RenewLease(ctx context.Context, id uuid.UUID, nodeID string, lease time.Duration) (bool, error) {
+	n, err := r.queries.RenewTaskLease(ctx, db.RenewTaskLeaseParams{
+		ID:       pgtype.UUID{Bytes: id, Valid: true},
+		NodeID:   pgtype.Text{String: nodeID, Valid: true},
+		LeaseSec: lease.Seconds(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("renewing task lease: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+// ReclaimExpiredTasks moves the running tasks whose lease expired to the node
+// and leases them for the given duration.
+func (r *TaskRepository) ReclaimExpiredTasks(ctx context.Context, nodeID string, lease time.Duration) ([]*domain.Task, error) {
+	var resp []db.Task
+	err := r.inTx(ctx, func(q *db.Queries) error {
+		var err error
+		resp, err = q.ReclaimExpiredTasks(ctx, db.ReclaimExpiredTasksParams{
+			NodeID:   pgtype.Text{String: nodeID, Valid: true},
+			LeaseSec: lease.Seconds(),
+		})
+		if err != nil {
+			return err
+		}
+
+		change := domain.StatusChange{Actor: domain.ActorGC, NodeID: nodeID, Reason: "lease expired, task reclaimed"}
+		for _, row := range resp {
+			if err := recordEvent(ctx, q, row.ID.Bytes, domain.TaskRunning, domain.TaskStatus(row.Status), change); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reclaiming expired tasks: %w", err)
+	}
+
+	result := make([]*domain.Task, 0, len(resp))
+	for _, row := range resp {
+		result = append(result, dbTaskToDomainTask(&row))
+	}
+
+	return result, nil
+}
+
 func (r *TaskRepository) FindCachedTask(ctx context.Context, signature string) (string, error) {
 	resultPath, err := r.queries.FindCachedTask(ctx, signature)
 	if err != nil && errors.Is(err, pgx.ErrNoRows) {
@@ -97,66 +232,132 @@ func (r *TaskRepository) FindCachedTask(ctx context.Context, signature string) (
 	return resultPath.String, nil
 }
 
-func (r *TaskRepository) GetScheduledTasks(ctx context.Context, time time.Time) ([]domain.Task, error) {
-	resp, err := r.queries.GetUpcomingScheduledTasks(ctx, pgtype.Timestamptz{Time: time, Valid: true})
+// PromoteScheduledTasks moves every due scheduled task to the queue in a
+// single update. Tasks more than misfireThreshold late are skipped or failed
+// instead, unless the policy is run. The updated tasks are returned.
+func (r *TaskRepository) PromoteScheduledTasks(ctx context.Context, policy string, misfireThreshold time.Duration) ([]*domain.Task, error) {
+	var result []*domain.Task
+	err := r.inTx(ctx, func(q *db.Queries) error {
+		resp, err := q.PromoteScheduledTasks(ctx, db.PromoteScheduledTasksParams{
+			Policy:              policy,
+			MisfireThresholdSec: misfireThreshold.Seconds(),
+		})
+		if err != nil {
+			return err
+		}
+
+		result = make([]*domain.Task, 0, len(resp))
+		for _, row := range resp {
+			task := dbTaskToDomainTask(&row)
+			change := domain.StatusChange{Actor: domain.ActorScheduler}
+			if task.Status != domain.TaskQueued {
+				change.Reason = task.ErrorLog
+			}
+			if err := recordEvent(ctx, q, task.ID, domain.TaskScheduled, task.Status, change); err != nil {
+				return err
+			}
+			result = append(result, task)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("getting scheduled tasks: %w", err)
+		return nil, fmt.Errorf("promoting scheduled tasks: %w", err)
 	}
 
-	result := []domain.Task{}
-	for _, res := range resp {
-		result = append(result, *dbTaskToDomainTask(&res))
+	return result, nil
+}
+
+// GetNextScheduledAt returns the earliest scheduled time of a waiting task,
+// nil if there is none.
+func (r *TaskRepository) GetNextScheduledAt(ctx context.Context) (*time.Time, error) {
+	next, err := r.queries.GetNextScheduledAt(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting next scheduled time: %w", err)
+	}
+	if !next.Valid {
+		return nil, nil
 	}
 
-	return result, nil
+	return &next.Time, nil
 }
 
-func (r *TaskRepository) Mark(ctx context.Context, task *domain.Task, status domain.TaskStatus) error {
-	switch status {
-	case domain.TaskInitializing:
-		if err := r.markTaskInitializing(ctx, task); err != nil {
-			return fmt.Errorf("marking task initializing: %w", err)
-		}
-		return nil
-	case domain.TaskScheduled:
-		if err := r.markTaskScheduled(ctx, task); err != nil {
-			return fmt.Errorf("marking task scheduled: %w", err)
-		}
-		return nil
-	case domain.TaskQueued:
-		if err := r.markTaskQueued(ctx, task); err != nil {
-			return fmt.Errorf("marking task queued: %w", err)
-		}
-		return nil
-	case domain.TaskRunning:
-		if err := r.markTaskRunning(ctx, task); err != nil {
-			return fmt.Errorf("marking task running: %w", err)
-		}
-		return nil
-	case domain.TaskFailed:
-		if err := r.markTaskFailed(ctx, task); err != nil {
-			return fmt.Errorf("marking task failed: %w", err)
+// Mark moves the task to the status and records the change in the event log.
+// The transition must be allowed from the status of the task, and the task
+// must not have changed since it was read, otherwise a
+// *domain.TransitionError is returned.
+func (r *TaskRepository) Mark(ctx context.Context, task *domain.Task, status domain.TaskStatus, change domain.StatusChange) error {
+	from := task.Status
+	if !domain.CanTransition(from, status) {
+		return &domain.TransitionError{TaskID: task.ID, From: from, To: status, Err: domain.ErrInvalidTransition}
+	}
+
+	// the task is left as it was if the transaction is rolled back
+	saved := *task
+	err := r.inTx(ctx, func(q *db.Queries) error {
+		var err error
+		switch status {
+		case domain.TaskInitializing:
+			err = r.markTaskInitializing(ctx, q, task)
+		case domain.TaskScheduled:
+			err = r.markTaskScheduled(ctx, q, task)
+		case domain.TaskQueued:
+			err = r.markTaskQueued(ctx, q, task)
+		case domain.TaskRunning:
+			err = r.markTaskRunning(ctx, q, task)
+		case domain.TaskFailed:
+			err = r.markTaskFailed(ctx, q, task)
+		case domain.TaskCompleted:
+			err = r.markTaskCompleted(ctx, q, task)
+		case domain.TaskStopped:
+			err = r.markTaskStopped(ctx, q, task)
+		default:
+			return errors.New("unsupported task status")
 		}
-		return nil
-	case domain.TaskCompleted:
-		if err := r.markTaskCompleted(ctx, task); err != nil {
-			return fmt.Errorf("marking task completed: %w", err)
+		if err != nil {
+			return err
 		}
-		return nil
-	case domain.TaskStopped:
-		if err := r.markTaskStopped(ctx, task); err != nil {
-			return fmt.Errorf("marking task stopped: %w", err)
-		}
-		return nil
-	default:
-		return errors.New("unsupported task status")
+
+		return recordEvent(ctx, q, task.ID, from, status, change)
+	})
+	if err != nil {
+		*task = saved
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r.rejectedTransition(ctx, task, status)
 	}
+	if err != nil {
+		return fmt.Errorf("marking task %s: %w", status, err)
+	}
+
+	return nil
 }
 
-func (r *TaskRepository) markTaskRunning(ctx context.Context, task *domain.Task) error {
-	dbtask, err := r.queries.MarkTaskRunning(ctx, db.MarkTaskRunningParams{
+// rejectedTransition explains why the task wasn't moved to the status: it was
+// deleted, moved to a status the transition isn't allowed from, or changed
+// concurrently.
+func (r *TaskRepository) rejectedTransition(ctx context.Context, task *domain.Task, status domain.TaskStatus) error {
+	current, err := r.GetTaskById(ctx, task.ID)
+	if err != nil {
+		return fmt.Errorf("getting task of rejected transition: %w", err)
+	}
+	if current == nil {
+		return domain.ErrTaskNotFound
+	}
+
+	reason := domain.ErrTaskConflict
+	if !domain.CanTransition(current.Status, status) {
+		reason = domain.ErrInvalidTransition
+	}
+
+	return &domain.TransitionError{TaskID: task.ID, From: current.Status, To: status, Err: reason}
+}
+
+func (r *TaskRepository) markTaskRunning(ctx context.Context, q *db.Queries, task *domain.Task) error {
+	dbtask, err := q.MarkTaskRunning(ctx, db.MarkTaskRunningParams{
 		ID:          pgtype.UUID{Bytes: task.ID, Valid: true},
 		ContainerID: pgtype.Text{String: task.ContainerID, Valid: true},
+		FromStatus:  db.TaskStatus(task.Status),
+		Version:     int32(task.Version),
 	})
 	if err != nil {
 		return fmt.Errorf("db query for marking task running: %w", err)
@@ -164,42 +365,60 @@ func (r *TaskRepository) markTaskRunning(ctx context.Context, task *domain.Task)
 
 	task.UpdatedAt = dbtask.UpdatedAt.Time
 	task.Status = domain.TaskStatus(dbtask.Status)
+	task.Version = int(dbtask.Version)
 
 	return nil
 }
 
-func (r *TaskRepository) markTaskStopped(ctx context.Context, task *domain.Task) error {
-	dbtask, err := r.queries.MarkTaskStopped(ctx, pgtype.UUID{Bytes: task.ID, Valid: true})
+func (r *TaskRepository) markTaskStopped(ctx context.Context, q *db.Queries, task *domain.Task) error {
+	dbtask, err := q.MarkTaskStopped(ctx, db.MarkTaskStoppedParams{
+		ID:            pgtype.UUID{Bytes: task.ID, Valid: true},
+		ExitCode:      exitCodeParam(task.ExitCode),
+		FailureReason: failureReasonParam(task.FailureReason),
+		FromStatus:    db.TaskStatus(task.Status),
+		Version:       int32(task.Version),
+	})
 	if err != nil {
-		return fmt.Errorf("db query for marking task running: %w", err)
+		return fmt.Errorf("db query for marking task stopped: %w", err)
 	}
 
 	task.UpdatedAt = dbtask.UpdatedAt.Time
 	task.FinishedAt = &dbtask.FinishedAt.Time
 	task.Status = domain.TaskStatus(dbtask.Status)
+	task.Version = int(dbtask.Version)
 
 	return nil
 }
 
-func (r *TaskRepository) markTaskCompleted(ctx context.Context, task *domain.Task) error {
-	dbtask, err := r.queries.MarkTaskCompleted(ctx, db.MarkTaskCompletedParams{
+func (r *TaskRepository) markTaskCompleted(ctx context.Context, q *db.Queries, task *domain.Task) error {
+	dbtask, err := q.MarkTaskCompleted(ctx, db.MarkTaskCompletedParams{
 		ID:         pgtype.UUID{Bytes: task.ID, Valid: true},
 		ResultPath: pgtype.Text{String: task.ResultPath, Valid: true},
+		ExitCode:   exitCodeParam(task.ExitCode),
+		FromStatus: db.TaskStatus(task.Status),
+		Version:    int32(task.Version),
 	})
 	if err != nil {
 		return fmt.Errorf("db query for marking task completed: %w", err)
 	}
 
+	task.FailureReason = ""
 	task.UpdatedAt = dbtask.UpdatedAt.Time
 	task.Status = domain.TaskStatus(dbtask.Status)
+	task.Version = int(dbtask.Version)
 
 	return nil
 }
 
-func (r *TaskRepository) markTaskFailed(ctx context.Context, task *domain.Task) error {
-	dbtask, err := r.queries.MarkTaskFailed(ctx, db.MarkTaskFailedParams{
-		ID:       pgtype.UUID{Bytes: task.ID, Valid: true},
-		ErrorLog: pgtype.Text{String: task.ErrorLog, Valid: true},
+func (r *TaskRepository) markTaskFailed(ctx context.Context, q *db.Queries, task *domain.Task) error {
+	dbtask, err := q.MarkTaskFailed(ctx, db.MarkTaskFailedParams{
+		ID:            pgtype.UUID{Bytes: task.ID, Valid: true},
+		ErrorLog:      pgtype.Text{String: task.ErrorLog, Valid: true},
+		UploadFailed:  task.UploadFailed,
+		ExitCode:      exitCodeParam(task.ExitCode),
+		FailureReason: failureReasonParam(task.FailureReason),
+		FromStatus:    db.TaskStatus(task.Status),
+		Version:       int32(task.Version),
 	})
 	if err != nil {
 		return fmt.Errorf("db query for marking task failed: %w", err)
@@ -207,42 +426,62 @@ func (r *TaskRepository) markTaskFailed(ctx context.Context, task *domain.Task)
 
 	task.UpdatedAt = dbtask.UpdatedAt.Time
 	task.Status = domain.TaskStatus(dbtask.Status)
+	task.Version = int(dbtask.Version)
 
 	return nil
 }
 
-func (r *TaskRepository) markTaskQueued(ctx context.Context, task *domain.Task) error {
-	dbtask, err := r.queries.MarkTaskQueued(ctx, pgtype.UUID{Bytes: task.ID, Valid: true})
+func (r *TaskRepository) markTaskQueued(ctx context.Context, q *db.Queries, task *domain.Task) error {
+	var retryAt pgtype.Timestamptz
+	if task.RetryAt != nil {
+		retryAt = pgtype.Timestamptz{Time: *task.RetryAt, Valid: true}
+	}
+
+	dbtask, err := q.MarkTaskQueued(ctx, db.MarkTaskQueuedParams{
+		ID:         pgtype.UUID{Bytes: task.ID, Valid: true},
+		Attempts:   int32(task.Attempts),
+		RetryAt:    retryAt,
+		FromStatus: db.TaskStatus(task.Status),
+		Version:    int32(task.Version),
+	})
 	if err != nil {
 		return fmt.Errorf("db query for marking task queued: %w", err)
 	}
 
 	task.UpdatedAt = dbtask.UpdatedAt.Time
 	task.Status = domain.TaskStatus(dbtask.Status)
+	task.Version = int(dbtask.Version)
 
 	return nil
 }
 
-func (r *TaskRepository) markTaskInitializing(ctx context.Context, task *domain.Task) error {
-	dbtask, err := r.queries.MarkTaskInitializing(ctx, pgtype.UUID{Bytes: task.ID, Valid: true})
+func (r *TaskRepository) markTaskInitializing(ctx context.Context, q *db.Queries, task *domain.Task) error {
+	dbtask, err := q.MarkTaskInitializing(ctx, db.MarkTaskInitializingParams{
+		ID:         pgtype.UUID{Bytes: task.ID, Valid: true},
+		FromStatus: db.TaskStatus(task.Status),
+		Version:    int32(task.Version),
+	})
 	if err != nil {
 		return fmt.Errorf("db query for marking task initializing: %w", err)
 	}
 
 	task.UpdatedAt = dbtask.UpdatedAt.Time
 	task.Status = domain.TaskStatus(dbtask.Status)
+	task.Version = int(dbtask.Version)
 
 	return nil
 }
 
-func (r *TaskRepository) markTaskScheduled(ctx context.Context, task *domain.Task) error {
+func (r *TaskRepository) markTaskScheduled(ctx context.Context, q *db.Queries, task *domain.Task) error {
 	if task.ScheduledAt == nil {
 		return errors.New("scheduledAt field must be not null")
 	}
 
-	dbtask, err := r.queries.MarkTaskScheduled(ctx, db.MarkTaskScheduledParams{
+	dbtask, err := q.MarkTaskScheduled(ctx, db.MarkTaskScheduledParams{
 		ID:          pgtype.UUID{Bytes: task.ID, Valid: true},
 		ScheduledAt: pgtype.Timestamptz{Time: *task.ScheduledAt, Valid: true},
+		FromStatus:  db.TaskStatus(task.Status),
+		Version:     int32(task.Version),
 	})
 	if err != nil {
 		return fmt.Errorf("db query for marking task scheduled: %w", err)
@@ -250,6 +489,7 @@ func (r *TaskRepository) markTaskScheduled(ctx context.Context, task *domain.Tas
 
 	task.UpdatedAt = dbtask.UpdatedAt.Time
 	task.Status = domain.TaskStatus(dbtask.Status)
+	task.Version = int(dbtask.Version)
 
 	return nil
 }
@@ -282,10 +522,11 @@ func (r *TaskRepository) GetActiveTasks(ctx context.Context) ([]*domain.Task, er
 	return result, nil
 }
 
-func (r *TaskRepository) GetTasksPaginated(ctx context.Context, limit, offset int32) (result []domain.Task, err error) {
+func (r *TaskRepository) GetTasksPaginated(ctx context.Context, limit, offset int32, filter domain.TaskFilter) (result []domain.Task, err error) {
 	dbtasks, err := r.queries.GetTasksPaginated(ctx, db.GetTasksPaginatedParams{
-		Limit:  limit,
-		Offset: offset,
+		Limit:         limit,
+		Offset:        offset,
+		FailureReason: failureReasonParam(filter.FailureReason),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("getting paginated tasks: %w", err)
@@ -298,8 +539,8 @@ func (r *TaskRepository) GetTasksPaginated(ctx context.Context, limit, offset in
 	return
 }
 
-func (r *TaskRepository) GetTasksCount(ctx context.Context) (int64, error) {
-	count, err := r.queries.GetTasksCount(ctx)
+func (r *TaskRepository) GetTasksCount(ctx context.Context, filter domain.TaskFilter) (int64, error) {
+	count, err := r.queries.GetTasksCount(ctx, failureReasonParam(filter.FailureReason))
 	if err != nil {
 		return 0, fmt.Errorf("getting tasks count: %w", err)
 	}
@@ -307,6 +548,53 @@ func (r *TaskRepository) GetTasksCount(ctx context.Context) (int64, error) {
 	return count, nil
 }
 
+// GetStaleTasks returns tasks with the given status that were not updated since before.
+func (r *TaskRepository) GetStaleTasks(ctx context.Context, status domain.TaskStatus, before time.Time) ([]*domain.Task, error) {
+	resp, err := r.queries.GetStaleTasks(ctx, db.GetStaleTasksParams{
+		Status:        db.TaskStatus(status),
+		UpdatedBefore: pgtype.Timestamptz{Time: before, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting stale tasks: %w", err)
+	}
+
+	result := make([]*domain.Task, 0, len(resp))
+	for _, row := range resp {
+		result = append(result, dbTaskToDomainTask(&row))
+	}
+
+	return result, nil
+}
+
+func (r *TaskRepository) GetFinishedTasks(ctx context.Context) ([]*domain.Task, error) {
+	resp, err := r.queries.GetFinishedTasks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting finished tasks: %w", err)
+	}
+
+	result := make([]*domain.Task, 0, len(resp))
+	for _, row := range resp {
+		result = append(result, dbTaskToDomainTask(&row))
+	}
+
+	return result, nil
+}
+
+func (r *TaskRepository) SetPinned(ctx context.Context, id uuid.UUID, pinned bool) (*domain.Task, error) {
+	dbtask, err := r.queries.SetTaskPinned(ctx, db.SetTaskPinnedParams{
+		ID:     pgtype.UUID{Bytes: id, Valid: true},
+		Pinned: pinned,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("setting task pinned: %w", err)
+	}
+
+	return dbTaskToDomainTask(&dbtask), nil
+}
+
 func (r *TaskRepository) DeleteTask(ctx context.Context, id uuid.UUID) error {
 	if err := r.queries.DeleteTask(ctx, pgtype.UUID{Bytes: id, Valid: true}); err != nil {
 		return fmt.Errorf("deleting task from db: %w", err)
@@ -315,36 +603,228 @@ func (r *TaskRepository) DeleteTask(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+// ListResultRefs returns the result paths of all tasks that have a result.
+func (r *TaskRepository) ListResultRefs(ctx context.Context) ([]domain.ResultRef, error) {
+	resp, err := r.queries.ListTaskResultRefs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing task result refs: %w", err)
+	}
+
+	result := make([]domain.ResultRef, 0, len(resp))
+	for _, row := range resp {
+		result = append(result, domain.ResultRef{
+			TaskID:        uuid.UUID(row.ID.Bytes),
+			Status:        domain.TaskStatus(row.Status),
+			ResultPath:    row.ResultPath.String,
+			ResultMissing: row.ResultMissing,
+		})
+	}
+
+	return result, nil
+}
+
+// CountResultRefs returns the number of tasks other than the given one whose
+// result is stored under the prefix.
+func (r *TaskRepository) CountResultRefs(ctx context.Context, id uuid.UUID, prefix string) (int64, error) {
+	count, err := r.queries.CountResultRefs(ctx, db.CountResultRefsParams{
+		ID:     pgtype.UUID{Bytes: id, Valid: true},
+		Prefix: prefix,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("counting result refs: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *TaskRepository) SetResultMissing(ctx context.Context, id uuid.UUID, missing bool) error {
+	err := r.queries.SetTaskResultMissing(ctx, db.SetTaskResultMissingParams{
+		ID:            pgtype.UUID{Bytes: id, Valid: true},
+		ResultMissing: missing,
+	})
+	if err != nil {
+		return fmt.Errorf("setting task result missing: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TaskRepository) SetUploadProgress(ctx context.Context, id uuid.UUID, total, done int64) error {
+	err := r.queries.SetTaskUploadProgress(ctx, db.SetTaskUploadProgressParams{
+		ID:               pgtype.UUID{Bytes: id, Valid: true},
+		UploadTotalBytes: total,
+		UploadDoneBytes:  done,
+	})
+	if err != nil {
+		return fmt.Errorf("setting task upload progress: %w", err)
+	}
+
+	return nil
+}
+
+// GetUploadFailedTasks returns failed tasks whose result upload can be retried.
+func (r *TaskRepository) GetUploadFailedTasks(ctx context.Context) ([]*domain.Task, error) {
+	resp, err := r.queries.GetUploadFailedTasks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting upload failed tasks: %w", err)
+	}
+
+	result := make([]*domain.Task, 0, len(resp))
+	for _, row := range resp {
+		result = append(result, dbTaskToDomainTask(&row))
+	}
+
+	return result, nil
+}
+
+// SaveArtifactChecksums records the checksums of the objects uploaded by the task.
+func (r *TaskRepository) SaveArtifactChecksums(ctx context.Context, taskID uuid.UUID, objects []domain.Artifact) error {
+	for _, obj := range objects {
+		err := r.queries.UpsertArtifactChecksum(ctx, db.UpsertArtifactChecksumParams{
+			ObjectKey: obj.Key,
+			TaskID:    pgtype.UUID{Bytes: taskID, Valid: true},
+			Size:      obj.Size,
+			Sha256:    obj.SHA256,
+		})
+		if err != nil {
+			return fmt.Errorf("saving checksum of %s: %w", obj.Key, err)
+		}
+	}
+
+	return nil
+}
+
+// ListArtifactChecksums returns the recorded checksums of the objects under
+// the prefix. Only Key, Size and SHA256 of the artifacts are set.
+func (r *TaskRepository) ListArtifactChecksums(ctx context.Context, prefix string) ([]domain.Artifact, error) {
+	rows, err := r.queries.ListArtifactChecksums(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("listing artifact checksums: %w", err)
+	}
+
+	result := make([]domain.Artifact, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, domain.Artifact{Key: row.ObjectKey, Size: row.Size, SHA256: row.Sha256})
+	}
+
+	return result, nil
+}
+
+// DeleteArtifactChecksums removes the checksums of the objects under the
+// prefix once the prefix itself is deleted from storage.
+func (r *TaskRepository) DeleteArtifactChecksums(ctx context.Context, prefix string) error {
+	if err := r.queries.DeleteArtifactChecksums(ctx, prefix); err != nil {
+		return fmt.Errorf("deleting artifact checksums: %w", err)
+	}
+
+	return nil
+}
+
+// SetResultCorrupted flags every task whose result lives under the prefix,
+// the task that produced it as well as the tasks reusing it from the cache.
+func (r *TaskRepository) SetResultCorrupted(ctx context.Context, prefix string, corrupted bool) error {
+	err := r.queries.SetResultCorrupted(ctx, db.SetResultCorruptedParams{
+		Corrupted: corrupted,
+		Prefix:    prefix,
+	})
+	if err != nil {
+		return fmt.Errorf("setting task result corrupted: %w", err)
+	}
+
+	return nil
+}
+
+func exitCodeParam(code *int) pgtype.Int4 {
+	if code == nil {
+		return pgtype.Int4{}
+	}
+	return pgtype.Int4{Int32: int32(*code), Valid: true}
+}
+
+func failureReasonParam(reason domain.FailureReason) db.NullFailureReason {
+	return db.NullFailureReason{FailureReason: db.FailureReason(reason), Valid: reason != ""}
+}
+
 func dbTaskToDomainTask(task *db.Task) *domain.Task {
 	d := &domain.Task{
-		ID:             uuid.UUID(task.ID.Bytes),
-		ModelID:        task.ModelID,
-		InputFilename:  task.InputFilename,
-		ResultPath:     task.ResultPath.String,
-		Signature:      task.Signature,
-		Status:         domain.TaskStatus(task.Status),
-		ContainerID:    task.ContainerID.String,
-		ContainerImage: task.ContainerImage.String,
-		ContainerEnvs:  task.ContainerEnvs,
-		ContainerCmd:   task.ContainerCmd,
-		ErrorLog:       task.ErrorLog.String,
-		CreatedAt:      task.CreatedAt.Time,
-		UpdatedAt:      task.UpdatedAt.Time,
-		GPUEnabled:     task.GpuEnable.Bool,
-		CPULim:         int(task.CpuLim.Int32),
-		MemLim:         int(task.MemLim.Int32),
-		TimeoutSec:     int(task.TimeoutSec),
+		ID:               uuid.UUID(task.ID.Bytes),
+		ModelID:          task.ModelID,
+		InputFilename:    task.InputFilename,
+		ResultPath:       task.ResultPath.String,
+		Signature:        task.Signature,
+		Status:           domain.TaskStatus(task.Status),
+		ContainerID:      task.ContainerID.String,
+		ContainerImage:   task.ContainerImage.String,
+		ContainerEnvs:    task.ContainerEnvs,
+		ContainerCmd:     task.ContainerCmd,
+		ErrorLog:         task.ErrorLog.String,
+		CreatedAt:        task.CreatedAt.Time,
+		UpdatedAt:        task.UpdatedAt.Time,
+		GPUEnabled:       task.GpuEnable.Bool,
+		CPULim:           int(task.CpuLim.Int32),
+		MemLim:           int(task.MemLim.Int32),
+		DiskLim:          int(task.DiskLim),
+		Attempts:         int(task.Attempts),
+		TimeoutSec:       int(task.TimeoutSec),
+		Pinned:           task.Pinned,
+		KeepForSec:       int(task.KeepForSec),
+		ResultMissing:    task.ResultMissing,
+		UploadTotalBytes: task.UploadTotalBytes,
+		UploadDoneBytes:  task.UploadDoneBytes,
+		UploadFailed:     task.UploadFailed,
+		InputSHA256:      task.InputSha256,
+		ResultCorrupted:  task.ResultCorrupted,
+		NodeID:           task.NodeID.String,
+		Version:          int(task.Version),
+		FailureReason:    domain.FailureReason(task.FailureReason.FailureReason),
+		NetworkMode:      domain.NetworkMode(task.NetworkMode),
+	}
+
+	if task.ExitCode.Valid {
+		code := int(task.ExitCode.Int32)
+		d.ExitCode = &code
 	}
 
 	if task.ScheduledAt.Valid {
 		d.ScheduledAt = &task.ScheduledAt.Time
 	}
+	if task.LeaseExpiresAt.Valid {
+		d.LeaseExpiresAt = &task.LeaseExpiresAt.Time
+	}
+	if task.RetryAt.Valid {
+		d.RetryAt = &task.RetryAt.Time
+	}
+	if len(task.Constraints) > 0 {
+		if err := json.Unmarshal(task.Constraints, &d.Constraints); err != nil {
+			slog.Warn("decoding task constraints", "id", d.ID, "error", err)
+		}
+	}
+	if len(task.SecretEnvs) > 0 {
+		if err := json.Unmarshal(task.SecretEnvs, &d.Secrets); err != nil {
+			slog.Warn("decoding task secrets", "id", d.ID, "error", err)
+		}
+	}
 	if task.StartedAt.Valid {
 		d.StartedAt = &task.StartedAt.Time
 	}
 	if task.FinishedAt.Valid {
 		d.FinishedAt = &task.FinishedAt.Time
 	}
+	if task.UsageRecordedAt.Valid {
+		d.Usage = &domain.ResourceUsage{
+			MemPeakBytes:    task.MemPeakBytes,
+			MemAvgBytes:     task.MemAvgBytes,
+			CPUSeconds:      task.CpuSeconds,
+			BlockReadBytes:  task.BlockReadBytes,
+			BlockWriteBytes: task.BlockWriteBytes,
+			NetRxBytes:      task.NetRxBytes,
+			NetTxBytes:      task.NetTxBytes,
+			Samples:         int(task.UsageSamples),
+			QueueSeconds:    task.QueueSeconds,
+			RunSeconds:      task.RunSeconds,
+			RecordedAt:      task.UsageRecordedAt.Time,
+		}
+	}
 
 	return d
 }
@@ -24,7 +24,7 @@ func newTaskRepoMock(t *testing.T) (*TaskRepository, pgxmock.PgxPoolIface) {
 	if err != nil {
 		t.Fatalf("pgxmock.NewPool: %v", err)
 	}
-	return &TaskRepository{queries: db.New(mock)}, mock
+	return &TaskRepository{pool: mock, queries: db.New(mock)}, mock
 }
 
 // anyArgs returns n AnyArg() values for use with WithArgs.
@@ -51,6 +51,14 @@ var taskColumns = []string{
 	"error_log", "scheduled_at", "started_at", "finished_at",
 	"created_at", "updated_at",
 	"mem_lim", "cpu_lim", "gpu_enable", "timeout_sec",
+	"pinned", "keep_for_sec", "result_missing", "upload_total_bytes",
+	"upload_done_bytes", "upload_failed", "input_sha256",
+	"result_corrupted", "node_id", "lease_expires_at", "constraints",
+	"version", "exit_code", "failure_reason", "mem_peak_bytes",
+	"mem_avg_bytes", "cpu_seconds", "block_read_bytes",
+	"block_write_bytes", "net_rx_bytes", "net_tx_bytes", "usage_samples",
+	"queue_seconds", "run_seconds", "usage_recorded_at", "secret_envs",
+	"network_mode", "disk_lim", "attempts", "retry_at",
 }
 
 // taskRow returns column values in taskColumns order.
@@ -78,14 +86,54 @@ func taskRow(id uuid.UUID, status db.TaskStatus) []any {
 		pgtype.Int4{Int32: 2, Valid: true},             // 17 cpu_lim
 		pgtype.Bool{Bool: false, Valid: true},          // 18 gpu_enable
 		int32(30),                                      // 19 timeout_sec
+		false,                                          // 20 pinned
+		int32(0),                                       // 21 keep_for_sec
+		false,                                          // 22 result_missing
+		int64(0),                                       // 23 upload_total_bytes
+		int64(0),                                       // 24 upload_done_bytes
+		false,                                          // 25 upload_failed
+		"",                                             // 26 input_sha256
+		false,                                          // 27 result_corrupted
+		pgtype.Text{},                                  // 28 node_id
+		pgtype.Timestamptz{},                           // 29 lease_expires_at
+		[]byte("{}"),                                   // 30 constraints
+		int32(0),                                       // 31 version
+		pgtype.Int4{},                                  // 32 exit_code
+		db.NullFailureReason{},                         // 33 failure_reason
+		int64(0),                                       // 34 mem_peak_bytes
+		int64(0),                                       // 35 mem_avg_bytes
+		float64(0),                                     // 36 cpu_seconds
+		int64(0),                                       // 37 block_read_bytes
+		int64(0),                                       // 38 block_write_bytes
+		int64(0),                                       // 39 net_rx_bytes
+		int64(0),                                       // 40 net_tx_bytes
+		int32(0),                                       // 41 usage_samples
+		float64(0),                                     // 42 queue_seconds
+		float64(0),                                     // 43 run_seconds
+		pgtype.Timestamptz{},                           // 44 usage_recorded_at
+		[]byte("{}"),                                   // 45 secret_envs
+		db.NetworkModeNone,                             // 46 network_mode
+		int32(0),                                       // 47 disk_lim
+		int32(0),                                       // 48 attempts
+		pgtype.Timestamptz{},                           // 49 retry_at
 	}
 }
 
 // expectMarkQuery sets up UPDATE expectation with n args returning the given status.
 func expectMarkQuery(mock pgxmock.PgxPoolIface, id uuid.UUID, status db.TaskStatus, argCount int) {
+	mock.ExpectBegin()
 	mock.ExpectQuery(`UPDATE tasks`).
 		WithArgs(anyArgs(argCount)...).
 		WillReturnRows(pgxmock.NewRows(taskColumns).AddRow(taskRow(id, status)...))
+	expectEvent(mock)
+	mock.ExpectCommit()
+}
+
+// expectEvent sets up the INSERT of a status transition into the event log.
+func expectEvent(mock pgxmock.PgxPoolIface) {
+	mock.ExpectExec(`INSERT INTO task_events`).
+		WithArgs(anyArgs(6)...).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 }
 
 // ─────────────────────────────────────────────
@@ -96,13 +144,16 @@ func TestTaskRepository_Create_Success(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 	id := uuid.New()
 
-	// CreateTaskParams has 15 fields
+	// CreateTaskParams has 21 fields
+	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO tasks`).
-		WithArgs(anyArgs(15)...).
+		WithArgs(anyArgs(21)...).
 		WillReturnRows(pgxmock.NewRows(taskColumns).AddRow(taskRow(id, db.TaskStatusQueued)...))
+	expectEvent(mock)
+	mock.ExpectCommit()
 
 	task := &domain.Task{ID: id, ModelID: "m1", Status: domain.TaskQueued}
-	if err := repo.Create(context.Background(), task); err != nil {
+	if err := repo.Create(context.Background(), task, domain.StatusChange{}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if task.CreatedAt.IsZero() {
@@ -118,12 +169,15 @@ func TestTaskRepository_Create_WithScheduledAt(t *testing.T) {
 	id := uuid.New()
 	future := time.Now().Add(time.Hour)
 
+	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO tasks`).
-		WithArgs(anyArgs(15)...).
+		WithArgs(anyArgs(21)...).
 		WillReturnRows(pgxmock.NewRows(taskColumns).AddRow(taskRow(id, db.TaskStatusScheduled)...))
+	expectEvent(mock)
+	mock.ExpectCommit()
 
 	task := &domain.Task{ID: id, ModelID: "m1", Status: domain.TaskScheduled, ScheduledAt: &future}
-	if err := repo.Create(context.Background(), task); err != nil {
+	if err := repo.Create(context.Background(), task, domain.StatusChange{}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -135,13 +189,16 @@ func TestTaskRepository_Create_EmptyStatus_DefaultsToInitializing(t *testing.T)
 	repo, mock := newTaskRepoMock(t)
 	id := uuid.New()
 
+	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO tasks`).
-		WithArgs(anyArgs(15)...).
+		WithArgs(anyArgs(21)...).
 		WillReturnRows(pgxmock.NewRows(taskColumns).AddRow(taskRow(id, db.TaskStatusInitializing)...))
+	expectEvent(mock)
+	mock.ExpectCommit()
 
 	// Empty Status → repository must substitute TaskInitializing
 	task := &domain.Task{ID: id, ModelID: "m1"}
-	if err := repo.Create(context.Background(), task); err != nil {
+	if err := repo.Create(context.Background(), task, domain.StatusChange{}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -152,11 +209,13 @@ func TestTaskRepository_Create_EmptyStatus_DefaultsToInitializing(t *testing.T)
 func TestTaskRepository_Create_DBError(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 
+	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO tasks`).
-		WithArgs(anyArgs(15)...).
+		WithArgs(anyArgs(21)...).
 		WillReturnError(errors.New("unique violation"))
+	mock.ExpectRollback()
 
-	if err := repo.Create(context.Background(), &domain.Task{ID: uuid.New(), ModelID: "m1"}); err == nil {
+	if err := repo.Create(context.Background(), &domain.Task{ID: uuid.New(), ModelID: "m1"}, domain.StatusChange{}); err == nil {
 		t.Fatal("expected error, got nil")
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -255,11 +314,15 @@ func TestTaskRepository_GetTaskById_PopulatesOptionalTimes(t *testing.T) {
 func TestTaskRepository_GetNextQueuedTask_Success(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 
-	// No args — GetNextQueuedTask takes no parameters
+	// the claiming node and its lease are recorded on the task
+	mock.ExpectBegin()
 	mock.ExpectQuery(`SELECT`).
+		WithArgs(pgtype.Text{String: "node-1", Valid: true}, float64(60)).
 		WillReturnRows(pgxmock.NewRows(taskColumns).AddRow(taskRow(uuid.New(), db.TaskStatusQueued)...))
+	expectEvent(mock)
+	mock.ExpectCommit()
 
-	task, err := repo.GetNextQueuedTask(context.Background())
+	task, err := repo.GetNextQueuedTask(context.Background(), "node-1", time.Minute)
 	if err != nil || task == nil {
 		t.Fatalf("expected task, got task=%v err=%v", task, err)
 	}
@@ -271,9 +334,11 @@ func TestTaskRepository_GetNextQueuedTask_Success(t *testing.T) {
 func TestTaskRepository_GetNextQueuedTask_EmptyQueue_ReturnsNil(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 
-	mock.ExpectQuery(`SELECT`).WillReturnError(pgx.ErrNoRows)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT`).WithArgs(anyArgs(2)...).WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
 
-	task, err := repo.GetNextQueuedTask(context.Background())
+	task, err := repo.GetNextQueuedTask(context.Background(), "node-1", time.Minute)
 	if err != nil || task != nil {
 		t.Errorf("expected nil/nil, got task=%v err=%v", task, err)
 	}
@@ -285,9 +350,11 @@ func TestTaskRepository_GetNextQueuedTask_EmptyQueue_ReturnsNil(t *testing.T) {
 func TestTaskRepository_GetNextQueuedTask_DBError(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 
-	mock.ExpectQuery(`SELECT`).WillReturnError(errors.New("db error"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT`).WithArgs(anyArgs(2)...).WillReturnError(errors.New("db error"))
+	mock.ExpectRollback()
 
-	if _, err := repo.GetNextQueuedTask(context.Background()); err == nil {
+	if _, err := repo.GetNextQueuedTask(context.Background(), "node-1", time.Minute); err == nil {
 		t.Fatal("expected error, got nil")
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -295,6 +362,55 @@ func TestTaskRepository_GetNextQueuedTask_DBError(t *testing.T) {
 	}
 }
 
+// ─────────────────────────────────────────────
+// RenewLease / ReclaimExpiredTasks
+// ─────────────────────────────────────────────
+
+func TestTaskRepository_RenewLease(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+
+	mock.ExpectExec(`UPDATE tasks`).
+		WithArgs(pgxmock.AnyArg(), pgtype.Text{String: "node-1", Valid: true}, float64(60)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	// a task reclaimed by another node is not renewed
+	mock.ExpectExec(`UPDATE tasks`).
+		WithArgs(anyArgs(3)...).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	if ok, err := repo.RenewLease(context.Background(), uuid.New(), "node-1", time.Minute); err != nil || !ok {
+		t.Errorf("expected the lease to be renewed, got ok=%v err=%v", ok, err)
+	}
+	if ok, err := repo.RenewLease(context.Background(), uuid.New(), "node-1", time.Minute); err != nil || ok {
+		t.Errorf("expected the lease to be lost, got ok=%v err=%v", ok, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestTaskRepository_ReclaimExpiredTasks(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE tasks`).
+		WithArgs(pgtype.Text{String: "node-1", Valid: true}, float64(30)).
+		WillReturnRows(pgxmock.NewRows(taskColumns).AddRow(taskRow(id, db.TaskStatusRunning)...))
+	expectEvent(mock)
+	mock.ExpectCommit()
+
+	tasks, err := repo.ReclaimExpiredTasks(context.Background(), "node-1", 30*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != id {
+		t.Errorf("unexpected tasks: %+v", tasks)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
 // ─────────────────────────────────────────────
 // FindCachedTask
 // ─────────────────────────────────────────────
@@ -348,51 +464,67 @@ func TestTaskRepository_FindCachedTask_DBError(t *testing.T) {
 }
 
 // ─────────────────────────────────────────────
-// GetScheduledTasks
+// PromoteScheduledTasks / GetNextScheduledAt
 // ─────────────────────────────────────────────
 
-func TestTaskRepository_GetScheduledTasks_Success(t *testing.T) {
+func TestTaskRepository_PromoteScheduledTasks_Success(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 
-	mock.ExpectQuery(`SELECT`).
-		WithArgs(pgxmock.AnyArg()).
+	mock.ExpectBegin()
+	mock.ExpectQuery(`WITH due AS`).
+		WithArgs("skip", float64(300)).
 		WillReturnRows(pgxmock.NewRows(taskColumns).
-			AddRow(taskRow(uuid.New(), db.TaskStatusScheduled)...))
+			AddRow(taskRow(uuid.New(), db.TaskStatusQueued)...).
+			AddRow(taskRow(uuid.New(), db.TaskStatusSkipped)...))
+	expectEvent(mock)
+	expectEvent(mock)
+	mock.ExpectCommit()
 
-	tasks, err := repo.GetScheduledTasks(context.Background(), time.Now())
-	if err != nil || len(tasks) == 0 {
-		t.Fatalf("expected tasks, got %v / %v", tasks, err)
+	tasks, err := repo.PromoteScheduledTasks(context.Background(), "skip", 5*time.Minute)
+	if err != nil || len(tasks) != 2 {
+		t.Fatalf("expected 2 tasks, got %v / %v", tasks, err)
+	}
+	if tasks[1].Status != domain.TaskSkipped {
+		t.Errorf("expected the second task to be skipped, got %s", tasks[1].Status)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("unmet expectations: %v", err)
 	}
 }
 
-func TestTaskRepository_GetScheduledTasks_Empty(t *testing.T) {
+func TestTaskRepository_PromoteScheduledTasks_DBError(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 
-	mock.ExpectQuery(`SELECT`).
-		WithArgs(pgxmock.AnyArg()).
-		WillReturnRows(pgxmock.NewRows(taskColumns))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`WITH due AS`).
+		WithArgs(anyArgs(2)...).
+		WillReturnError(errors.New("db error"))
+	mock.ExpectRollback()
 
-	tasks, err := repo.GetScheduledTasks(context.Background(), time.Now())
-	if err != nil || len(tasks) != 0 {
-		t.Errorf("expected empty/nil, got %v / %v", tasks, err)
+	if _, err := repo.PromoteScheduledTasks(context.Background(), "run", time.Minute); err == nil {
+		t.Fatal("expected error, got nil")
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("unmet expectations: %v", err)
 	}
 }
 
-func TestTaskRepository_GetScheduledTasks_DBError(t *testing.T) {
+func TestTaskRepository_GetNextScheduledAt(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
+	at := time.Now().Add(time.Hour)
 
-	mock.ExpectQuery(`SELECT`).
-		WithArgs(pgxmock.AnyArg()).
-		WillReturnError(errors.New("db error"))
+	mock.ExpectQuery(`SELECT MIN`).
+		WillReturnRows(pgxmock.NewRows([]string{"column_1"}).AddRow(pgtype.Timestamptz{Time: at, Valid: true}))
+	mock.ExpectQuery(`SELECT MIN`).
+		WillReturnRows(pgxmock.NewRows([]string{"column_1"}).AddRow(pgtype.Timestamptz{}))
 
-	if _, err := repo.GetScheduledTasks(context.Background(), time.Now()); err == nil {
-		t.Fatal("expected error, got nil")
+	next, err := repo.GetNextScheduledAt(context.Background())
+	if err != nil || next == nil || !next.Equal(at) {
+		t.Errorf("expected %v, got %v / %v", at, next, err)
+	}
+	next, err = repo.GetNextScheduledAt(context.Background())
+	if err != nil || next != nil {
+		t.Errorf("expected nil without scheduled tasks, got %v / %v", next, err)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("unmet expectations: %v", err)
@@ -400,29 +532,16 @@ func TestTaskRepository_GetScheduledTasks_DBError(t *testing.T) {
 }
 
 // ─────────────────────────────────────────────
-// Mark — all 7 status branches
+// Mark — status branches and rejected transitions
 // ─────────────────────────────────────────────
 
-func TestTaskRepository_Mark_Initializing_Success(t *testing.T) {
+// initializing is the status tasks are created with, no task moves back to it
+func TestTaskRepository_Mark_Initializing_Rejected(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
-	id := uuid.New()
-	expectMarkQuery(mock, id, db.TaskStatusInitializing, 1)
 
-	if err := repo.Mark(context.Background(), &domain.Task{ID: id}, domain.TaskInitializing); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Errorf("unmet expectations: %v", err)
-	}
-}
-
-func TestTaskRepository_Mark_Initializing_DBError(t *testing.T) {
-	repo, mock := newTaskRepoMock(t)
-
-	mock.ExpectQuery(`UPDATE tasks`).WithArgs(pgxmock.AnyArg()).WillReturnError(errors.New("db error"))
-
-	if err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New()}, domain.TaskInitializing); err == nil {
-		t.Fatal("expected error, got nil")
+	err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New(), Status: domain.TaskQueued}, domain.TaskInitializing, domain.StatusChange{})
+	if !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Fatalf("expected invalid transition error, got %v", err)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("unmet expectations: %v", err)
@@ -433,9 +552,9 @@ func TestTaskRepository_Mark_Scheduled_Success(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 	id := uuid.New()
 	future := time.Now().Add(time.Hour)
-	expectMarkQuery(mock, id, db.TaskStatusScheduled, 2)
+	expectMarkQuery(mock, id, db.TaskStatusScheduled, 4)
 
-	if err := repo.Mark(context.Background(), &domain.Task{ID: id, ScheduledAt: &future}, domain.TaskScheduled); err != nil {
+	if err := repo.Mark(context.Background(), &domain.Task{ID: id, Status: domain.TaskInitializing, ScheduledAt: &future}, domain.TaskScheduled, domain.StatusChange{}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -446,7 +565,7 @@ func TestTaskRepository_Mark_Scheduled_Success(t *testing.T) {
 func TestTaskRepository_Mark_Scheduled_NilScheduledAt_Error(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 
-	if err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New(), ScheduledAt: nil}, domain.TaskScheduled); err == nil {
+	if err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New(), Status: domain.TaskInitializing, ScheduledAt: nil}, domain.TaskScheduled, domain.StatusChange{}); err == nil {
 		t.Fatal("expected error for nil ScheduledAt, got nil")
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -458,9 +577,11 @@ func TestTaskRepository_Mark_Scheduled_DBError(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 	future := time.Now().Add(time.Hour)
 
-	mock.ExpectQuery(`UPDATE tasks`).WithArgs(anyArgs(2)...).WillReturnError(errors.New("db error"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE tasks`).WithArgs(anyArgs(4)...).WillReturnError(errors.New("db error"))
+	mock.ExpectRollback()
 
-	if err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New(), ScheduledAt: &future}, domain.TaskScheduled); err == nil {
+	if err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New(), Status: domain.TaskInitializing, ScheduledAt: &future}, domain.TaskScheduled, domain.StatusChange{}); err == nil {
 		t.Fatal("expected error, got nil")
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -471,10 +592,10 @@ func TestTaskRepository_Mark_Scheduled_DBError(t *testing.T) {
 func TestTaskRepository_Mark_Queued_Success(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 	id := uuid.New()
-	expectMarkQuery(mock, id, db.TaskStatusQueued, 1)
+	expectMarkQuery(mock, id, db.TaskStatusQueued, 5)
 
-	task := &domain.Task{ID: id}
-	if err := repo.Mark(context.Background(), task, domain.TaskQueued); err != nil {
+	task := &domain.Task{ID: id, Status: domain.TaskRunning}
+	if err := repo.Mark(context.Background(), task, domain.TaskQueued, domain.StatusChange{}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if task.Status != domain.TaskQueued {
@@ -488,9 +609,11 @@ func TestTaskRepository_Mark_Queued_Success(t *testing.T) {
 func TestTaskRepository_Mark_Queued_DBError(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 
-	mock.ExpectQuery(`UPDATE tasks`).WithArgs(pgxmock.AnyArg()).WillReturnError(errors.New("db error"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE tasks`).WithArgs(anyArgs(5)...).WillReturnError(errors.New("db error"))
+	mock.ExpectRollback()
 
-	if err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New()}, domain.TaskQueued); err == nil {
+	if err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New(), Status: domain.TaskRunning}, domain.TaskQueued, domain.StatusChange{}); err == nil {
 		t.Fatal("expected error, got nil")
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -501,10 +624,10 @@ func TestTaskRepository_Mark_Queued_DBError(t *testing.T) {
 func TestTaskRepository_Mark_Running_Success(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 	id := uuid.New()
-	expectMarkQuery(mock, id, db.TaskStatusRunning, 2)
+	expectMarkQuery(mock, id, db.TaskStatusRunning, 4)
 
-	task := &domain.Task{ID: id, ContainerID: "ctr-1"}
-	if err := repo.Mark(context.Background(), task, domain.TaskRunning); err != nil {
+	task := &domain.Task{ID: id, Status: domain.TaskRunning, ContainerID: "ctr-1"}
+	if err := repo.Mark(context.Background(), task, domain.TaskRunning, domain.StatusChange{}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if task.Status != domain.TaskRunning {
@@ -518,9 +641,11 @@ func TestTaskRepository_Mark_Running_Success(t *testing.T) {
 func TestTaskRepository_Mark_Running_DBError(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 
-	mock.ExpectQuery(`UPDATE tasks`).WithArgs(anyArgs(2)...).WillReturnError(errors.New("db error"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE tasks`).WithArgs(anyArgs(4)...).WillReturnError(errors.New("db error"))
+	mock.ExpectRollback()
 
-	if err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New()}, domain.TaskRunning); err == nil {
+	if err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New(), Status: domain.TaskRunning}, domain.TaskRunning, domain.StatusChange{}); err == nil {
 		t.Fatal("expected error, got nil")
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -531,9 +656,42 @@ func TestTaskRepository_Mark_Running_DBError(t *testing.T) {
 func TestTaskRepository_Mark_Failed_Success(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 	id := uuid.New()
-	expectMarkQuery(mock, id, db.TaskStatusFailed, 2)
+	expectMarkQuery(mock, id, db.TaskStatusFailed, 7)
+
+	if err := repo.Mark(context.Background(), &domain.Task{ID: id, Status: domain.TaskRunning, ErrorLog: "oom"}, domain.TaskFailed, domain.StatusChange{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// The failure reason and the exit code are saved with the failed status.
+func TestTaskRepository_Mark_Failed_SavesReason(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+	id := uuid.New()
+	exitCode := 137
 
-	if err := repo.Mark(context.Background(), &domain.Task{ID: id, ErrorLog: "oom"}, domain.TaskFailed); err != nil {
+	row := taskRow(id, db.TaskStatusFailed)
+	row[32] = pgtype.Int4{Int32: 137, Valid: true}
+	row[33] = db.NullFailureReason{FailureReason: db.FailureReasonOomKilled, Valid: true}
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE tasks`).
+		WithArgs(
+			pgtype.UUID{Bytes: id, Valid: true},
+			pgtype.Text{String: "oom", Valid: true},
+			false,
+			pgtype.Int4{Int32: 137, Valid: true},
+			db.NullFailureReason{FailureReason: db.FailureReasonOomKilled, Valid: true},
+			db.TaskStatusRunning,
+			int32(0),
+		).
+		WillReturnRows(pgxmock.NewRows(taskColumns).AddRow(row...))
+	expectEvent(mock)
+	mock.ExpectCommit()
+
+	task := &domain.Task{ID: id, Status: domain.TaskRunning, ErrorLog: "oom", ExitCode: &exitCode, FailureReason: domain.FailureOOMKilled}
+	if err := repo.Mark(context.Background(), task, domain.TaskFailed, domain.StatusChange{}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -544,9 +702,11 @@ func TestTaskRepository_Mark_Failed_Success(t *testing.T) {
 func TestTaskRepository_Mark_Failed_DBError(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 
-	mock.ExpectQuery(`UPDATE tasks`).WithArgs(anyArgs(2)...).WillReturnError(errors.New("db error"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE tasks`).WithArgs(anyArgs(7)...).WillReturnError(errors.New("db error"))
+	mock.ExpectRollback()
 
-	if err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New()}, domain.TaskFailed); err == nil {
+	if err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New(), Status: domain.TaskRunning}, domain.TaskFailed, domain.StatusChange{}); err == nil {
 		t.Fatal("expected error, got nil")
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -557,9 +717,9 @@ func TestTaskRepository_Mark_Failed_DBError(t *testing.T) {
 func TestTaskRepository_Mark_Completed_Success(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 	id := uuid.New()
-	expectMarkQuery(mock, id, db.TaskStatusCompleted, 2)
+	expectMarkQuery(mock, id, db.TaskStatusCompleted, 5)
 
-	if err := repo.Mark(context.Background(), &domain.Task{ID: id, ResultPath: "s3://key"}, domain.TaskCompleted); err != nil {
+	if err := repo.Mark(context.Background(), &domain.Task{ID: id, Status: domain.TaskRunning, ResultPath: "s3://key"}, domain.TaskCompleted, domain.StatusChange{}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -570,9 +730,11 @@ func TestTaskRepository_Mark_Completed_Success(t *testing.T) {
 func TestTaskRepository_Mark_Completed_DBError(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 
-	mock.ExpectQuery(`UPDATE tasks`).WithArgs(anyArgs(2)...).WillReturnError(errors.New("db error"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE tasks`).WithArgs(anyArgs(5)...).WillReturnError(errors.New("db error"))
+	mock.ExpectRollback()
 
-	if err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New()}, domain.TaskCompleted); err == nil {
+	if err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New(), Status: domain.TaskRunning}, domain.TaskCompleted, domain.StatusChange{}); err == nil {
 		t.Fatal("expected error, got nil")
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -586,12 +748,15 @@ func TestTaskRepository_Mark_Stopped_Success(t *testing.T) {
 
 	row := taskRow(id, db.TaskStatusStopped)
 	row[13] = pgtype.Timestamptz{Time: time.Now(), Valid: true} // finished_at at index 13
+	mock.ExpectBegin()
 	mock.ExpectQuery(`UPDATE tasks`).
-		WithArgs(pgxmock.AnyArg()).
+		WithArgs(anyArgs(5)...).
 		WillReturnRows(pgxmock.NewRows(taskColumns).AddRow(row...))
+	expectEvent(mock)
+	mock.ExpectCommit()
 
-	task := &domain.Task{ID: id}
-	if err := repo.Mark(context.Background(), task, domain.TaskStopped); err != nil {
+	task := &domain.Task{ID: id, Status: domain.TaskRunning}
+	if err := repo.Mark(context.Background(), task, domain.TaskStopped, domain.StatusChange{}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if task.FinishedAt == nil {
@@ -605,9 +770,11 @@ func TestTaskRepository_Mark_Stopped_Success(t *testing.T) {
 func TestTaskRepository_Mark_Stopped_DBError(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 
-	mock.ExpectQuery(`UPDATE tasks`).WithArgs(pgxmock.AnyArg()).WillReturnError(errors.New("db error"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE tasks`).WithArgs(anyArgs(5)...).WillReturnError(errors.New("db error"))
+	mock.ExpectRollback()
 
-	if err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New()}, domain.TaskStopped); err == nil {
+	if err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New(), Status: domain.TaskRunning}, domain.TaskStopped, domain.StatusChange{}); err == nil {
 		t.Fatal("expected error, got nil")
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -615,10 +782,71 @@ func TestTaskRepository_Mark_Stopped_DBError(t *testing.T) {
 	}
 }
 
+func TestTaskRepository_Mark_InvalidTransition(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+
+	err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New(), Status: domain.TaskStopped}, domain.TaskCompleted, domain.StatusChange{})
+	var terr *domain.TransitionError
+	if !errors.As(err, &terr) || !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Fatalf("expected invalid transition error, got %v", err)
+	}
+	if terr.From != domain.TaskStopped || terr.To != domain.TaskCompleted {
+		t.Errorf("unexpected transition %s -> %s", terr.From, terr.To)
+	}
+	// rejected without a query
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestTaskRepository_Mark_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		current []any
+		want    error
+		from    domain.TaskStatus
+	}{
+		{"stopped meanwhile", taskRow(uuid.Nil, db.TaskStatusStopped), domain.ErrInvalidTransition, domain.TaskStopped},
+		{"changed meanwhile", taskRow(uuid.Nil, db.TaskStatusRunning), domain.ErrTaskConflict, domain.TaskRunning},
+		{"deleted meanwhile", nil, domain.ErrTaskNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, mock := newTaskRepoMock(t)
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(`UPDATE tasks`).WithArgs(anyArgs(5)...).WillReturnError(pgx.ErrNoRows)
+			mock.ExpectRollback()
+			reread := mock.ExpectQuery(`SELECT`).WithArgs(pgxmock.AnyArg())
+			if tt.current == nil {
+				reread.WillReturnError(pgx.ErrNoRows)
+			} else {
+				reread.WillReturnRows(pgxmock.NewRows(taskColumns).AddRow(tt.current...))
+			}
+
+			task := &domain.Task{ID: uuid.New(), Status: domain.TaskRunning, Version: 1}
+			err := repo.Mark(context.Background(), task, domain.TaskCompleted, domain.StatusChange{})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+			var terr *domain.TransitionError
+			if errors.As(err, &terr) && terr.From != tt.from {
+				t.Errorf("expected rejection from %s, got %s", tt.from, terr.From)
+			}
+			if task.Status != domain.TaskRunning {
+				t.Errorf("expected the task to be unchanged, got %s", task.Status)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
 func TestTaskRepository_Mark_UnknownStatus_Error(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 
-	if err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New()}, domain.TaskStatus("bogus")); err == nil {
+	if err := repo.Mark(context.Background(), &domain.Task{ID: uuid.New()}, domain.TaskStatus("bogus"), domain.StatusChange{}); err == nil {
 		t.Fatal("expected error for unknown status, got nil")
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -714,11 +942,11 @@ func TestTaskRepository_GetTasksPaginated_Success(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 
 	mock.ExpectQuery(`SELECT`).
-		WithArgs(anyArgs(2)...).
+		WithArgs(anyArgs(3)...).
 		WillReturnRows(pgxmock.NewRows(taskColumns).
 			AddRow(taskRow(uuid.New(), db.TaskStatusQueued)...))
 
-	tasks, err := repo.GetTasksPaginated(context.Background(), 10, 0)
+	tasks, err := repo.GetTasksPaginated(context.Background(), 10, 0, domain.TaskFilter{})
 	if err != nil || len(tasks) == 0 {
 		t.Fatalf("expected tasks, got %v / %v", tasks, err)
 	}
@@ -727,14 +955,35 @@ func TestTaskRepository_GetTasksPaginated_Success(t *testing.T) {
 	}
 }
 
+func TestTaskRepository_GetTasksPaginated_FilterByFailureReason(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+
+	row := taskRow(uuid.New(), db.TaskStatusStopped)
+	row[33] = db.NullFailureReason{FailureReason: db.FailureReasonTimeout, Valid: true}
+	mock.ExpectQuery(`SELECT`).
+		WithArgs(int32(10), int32(0), db.NullFailureReason{FailureReason: db.FailureReasonTimeout, Valid: true}).
+		WillReturnRows(pgxmock.NewRows(taskColumns).AddRow(row...))
+
+	tasks, err := repo.GetTasksPaginated(context.Background(), 10, 0, domain.TaskFilter{FailureReason: domain.FailureTimeout})
+	if err != nil || len(tasks) != 1 {
+		t.Fatalf("expected 1 task, got %v / %v", tasks, err)
+	}
+	if tasks[0].FailureReason != domain.FailureTimeout || tasks[0].ExitCode != nil {
+		t.Errorf("unexpected failure of the task: %q, exit code %v", tasks[0].FailureReason, tasks[0].ExitCode)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
 func TestTaskRepository_GetTasksPaginated_DBError(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 
 	mock.ExpectQuery(`SELECT`).
-		WithArgs(anyArgs(2)...).
+		WithArgs(anyArgs(3)...).
 		WillReturnError(errors.New("db error"))
 
-	if _, err := repo.GetTasksPaginated(context.Background(), 10, 0); err == nil {
+	if _, err := repo.GetTasksPaginated(context.Background(), 10, 0, domain.TaskFilter{}); err == nil {
 		t.Fatal("expected error, got nil")
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -750,9 +999,10 @@ func TestTaskRepository_GetTasksCount_Success(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 
 	mock.ExpectQuery(`SELECT`).
+		WithArgs(db.NullFailureReason{}).
 		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(42)))
 
-	count, err := repo.GetTasksCount(context.Background())
+	count, err := repo.GetTasksCount(context.Background(), domain.TaskFilter{})
 	if err != nil || count != 42 {
 		t.Fatalf("expected 42/nil, got %d/%v", count, err)
 	}
@@ -764,9 +1014,9 @@ func TestTaskRepository_GetTasksCount_Success(t *testing.T) {
 func TestTaskRepository_GetTasksCount_DBError(t *testing.T) {
 	repo, mock := newTaskRepoMock(t)
 
-	mock.ExpectQuery(`SELECT`).WillReturnError(errors.New("db error"))
+	mock.ExpectQuery(`SELECT`).WithArgs(pgxmock.AnyArg()).WillReturnError(errors.New("db error"))
 
-	if _, err := repo.GetTasksCount(context.Background()); err == nil {
+	if _, err := repo.GetTasksCount(context.Background(), domain.TaskFilter{}); err == nil {
 		t.Fatal("expected error, got nil")
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -808,6 +1058,124 @@ func TestTaskRepository_DeleteTask_DBError(t *testing.T) {
 	}
 }
 
+// ─────────────────────────────────────────────
+// GetStaleTasks
+// ─────────────────────────────────────────────
+
+func TestTaskRepository_GetStaleTasks_Success(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+
+	mock.ExpectQuery(`SELECT`).
+		WithArgs(db.TaskStatusInitializing, pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows(taskColumns).
+			AddRow(taskRow(uuid.New(), db.TaskStatusInitializing)...))
+
+	tasks, err := repo.GetStaleTasks(context.Background(), domain.TaskInitializing, time.Now())
+	if err != nil || len(tasks) != 1 {
+		t.Fatalf("expected 1 task, got %v / %v", tasks, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestTaskRepository_GetStaleTasks_DBError(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+
+	mock.ExpectQuery(`SELECT`).
+		WithArgs(anyArgs(2)...).
+		WillReturnError(errors.New("db error"))
+
+	if _, err := repo.GetStaleTasks(context.Background(), domain.TaskRunning, time.Now()); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+// ─────────────────────────────────────────────
+// GetFinishedTasks
+// ─────────────────────────────────────────────
+
+func TestTaskRepository_GetFinishedTasks_Success(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+
+	mock.ExpectQuery(`SELECT`).
+		WillReturnRows(pgxmock.NewRows(taskColumns).
+			AddRow(taskRow(uuid.New(), db.TaskStatusCompleted)...).
+			AddRow(taskRow(uuid.New(), db.TaskStatusFailed)...))
+
+	tasks, err := repo.GetFinishedTasks(context.Background())
+	if err != nil || len(tasks) != 2 {
+		t.Fatalf("expected 2 tasks, got %v / %v", tasks, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestTaskRepository_GetFinishedTasks_DBError(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+
+	mock.ExpectQuery(`SELECT`).WillReturnError(errors.New("db error"))
+
+	if _, err := repo.GetFinishedTasks(context.Background()); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// ─────────────────────────────────────────────
+// SetPinned
+// ─────────────────────────────────────────────
+
+func TestTaskRepository_SetPinned_Success(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+	id := uuid.New()
+	row := taskRow(id, db.TaskStatusCompleted)
+	row[20] = true
+
+	mock.ExpectQuery(`UPDATE tasks`).
+		WithArgs(anyArgs(2)...).
+		WillReturnRows(pgxmock.NewRows(taskColumns).AddRow(row...))
+
+	task, err := repo.SetPinned(context.Background(), id, true)
+	if err != nil || task == nil {
+		t.Fatalf("expected task, got %v / %v", task, err)
+	}
+	if !task.Pinned {
+		t.Error("expected task to be pinned")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestTaskRepository_SetPinned_NotFound(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+
+	mock.ExpectQuery(`UPDATE tasks`).
+		WithArgs(anyArgs(2)...).
+		WillReturnError(pgx.ErrNoRows)
+
+	task, err := repo.SetPinned(context.Background(), uuid.New(), true)
+	if err != nil || task != nil {
+		t.Fatalf("expected nil/nil, got %v / %v", task, err)
+	}
+}
+
+func TestTaskRepository_SetPinned_DBError(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+
+	mock.ExpectQuery(`UPDATE tasks`).
+		WithArgs(anyArgs(2)...).
+		WillReturnError(errors.New("db error"))
+
+	if _, err := repo.SetPinned(context.Background(), uuid.New(), false); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
 // ─────────────────────────────────────────────
 // dbTaskToDomainTask — pure unit, no DB
 // ─────────────────────────────────────────────
@@ -878,4 +1246,187 @@ func TestDbTaskToDomainTask_NullOptionalTimes(t *testing.T) {
 	if got.FinishedAt != nil {
 		t.Error("FinishedAt should be nil when Valid=false")
 	}
+	if got.Usage != nil {
+		t.Error("Usage should be nil until it is recorded")
+	}
+}
+
+// ─────────────────────────────────────────────
+// ListResultRefs / SetResultMissing
+// ─────────────────────────────────────────────
+
+func TestTaskRepository_ListResultRefs_Success(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+	id := uuid.New()
+
+	mock.ExpectQuery(`SELECT id, status, result_path, result_missing`).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "status", "result_path", "result_missing"}).
+			AddRow(pgtype.UUID{Bytes: id, Valid: true}, db.TaskStatusCompleted,
+				pgtype.Text{String: "tasks/" + id.String() + "/result.txt", Valid: true}, true))
+
+	refs, err := repo.ListResultRefs(context.Background())
+	if err != nil || len(refs) != 1 {
+		t.Fatalf("expected 1 ref, got %v / %v", refs, err)
+	}
+	if refs[0].TaskID != id || !refs[0].ResultMissing || refs[0].ResultPath != "tasks/"+id.String()+"/result.txt" {
+		t.Errorf("unexpected ref: %+v", refs[0])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestTaskRepository_CountResultRefs(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+	id := uuid.New()
+	prefix := "tasks/" + id.String() + "/"
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM tasks`).
+		WithArgs(pgtype.UUID{Bytes: id, Valid: true}, prefix).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(2)))
+
+	count, err := repo.CountResultRefs(context.Background(), id, prefix)
+	if err != nil || count != 2 {
+		t.Fatalf("expected 2 refs, got %d / %v", count, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestTaskRepository_SetResultMissing_Success(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+
+	mock.ExpectExec(`UPDATE tasks`).
+		WithArgs(pgxmock.AnyArg(), true).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	if err := repo.SetResultMissing(context.Background(), uuid.New(), true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// ─────────────────────────────────────────────
+// SetUploadProgress / GetUploadFailedTasks
+// ─────────────────────────────────────────────
+
+func TestTaskRepository_SetUploadProgress_Success(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+
+	mock.ExpectExec(`UPDATE tasks`).
+		WithArgs(pgxmock.AnyArg(), int64(100), int64(40)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	if err := repo.SetUploadProgress(context.Background(), uuid.New(), 100, 40); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestTaskRepository_GetUploadFailedTasks_Success(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+	id := uuid.New()
+
+	mock.ExpectQuery(`SELECT .+ FROM tasks`).
+		WillReturnRows(pgxmock.NewRows(taskColumns).AddRow(taskRow(id, db.TaskStatusFailed)...))
+
+	tasks, err := repo.GetUploadFailedTasks(context.Background())
+	if err != nil || len(tasks) != 1 || tasks[0].ID != id {
+		t.Fatalf("unexpected result: %v / %v", tasks, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// ─────────────────────────────────────────────
+// Artifact checksums
+// ─────────────────────────────────────────────
+
+func TestTaskRepository_SaveArtifactChecksums_Success(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+	id := uuid.New()
+
+	mock.ExpectExec(`INSERT INTO artifact_checksums`).
+		WithArgs("tasks/a/result.txt", pgxmock.AnyArg(), int64(4), "abc").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO artifact_checksums`).
+		WithArgs("tasks/a/log.txt", pgxmock.AnyArg(), int64(2), "def").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	err := repo.SaveArtifactChecksums(context.Background(), id, []domain.Artifact{
+		{Key: "tasks/a/result.txt", Size: 4, SHA256: "abc"},
+		{Key: "tasks/a/log.txt", Size: 2, SHA256: "def"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestTaskRepository_SaveArtifactChecksums_DBError(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+
+	mock.ExpectExec(`INSERT INTO artifact_checksums`).
+		WithArgs(anyArgs(4)...).
+		WillReturnError(errors.New("db error"))
+
+	err := repo.SaveArtifactChecksums(context.Background(), uuid.New(), []domain.Artifact{{Key: "tasks/a/result.txt"}})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestTaskRepository_ListArtifactChecksums_Success(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+
+	mock.ExpectQuery(`SELECT object_key, size, sha256 FROM artifact_checksums`).
+		WithArgs("tasks/a/").
+		WillReturnRows(pgxmock.NewRows([]string{"object_key", "size", "sha256"}).
+			AddRow("tasks/a/result.txt", int64(4), "abc"))
+
+	objects, err := repo.ListArtifactChecksums(context.Background(), "tasks/a/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Key != "tasks/a/result.txt" || objects[0].Size != 4 || objects[0].SHA256 != "abc" {
+		t.Errorf("unexpected checksums %+v", objects)
+	}
+}
+
+func TestTaskRepository_DeleteArtifactChecksums_Success(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+
+	mock.ExpectExec(`DELETE FROM artifact_checksums`).
+		WithArgs("tasks/a/").
+		WillReturnResult(pgxmock.NewResult("DELETE", 2))
+
+	if err := repo.DeleteArtifactChecksums(context.Background(), "tasks/a/"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestTaskRepository_SetResultCorrupted_Success(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+
+	mock.ExpectExec(`UPDATE tasks`).
+		WithArgs(true, "tasks/a/").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+
+	if err := repo.SetResultCorrupted(context.Background(), "tasks/a/", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
 }
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"pinn-connect-service/internal/db"
+	"pinn-connect-service/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// SaveTaskUsage records the resources used by the task. The queue and run
+// times are computed by the database from the timestamps of the task.
+func (r *TaskRepository) SaveTaskUsage(ctx context.Context, id uuid.UUID, usage domain.ResourceUsage) error {
+	err := r.queries.SaveTaskUsage(ctx, db.SaveTaskUsageParams{
+		ID:              pgtype.UUID{Bytes: id, Valid: true},
+		MemPeakBytes:    usage.MemPeakBytes,
+		MemAvgBytes:     usage.MemAvgBytes,
+		CpuSeconds:      usage.CPUSeconds,
+		BlockReadBytes:  usage.BlockReadBytes,
+		BlockWriteBytes: usage.BlockWriteBytes,
+		NetRxBytes:      usage.NetRxBytes,
+		NetTxBytes:      usage.NetTxBytes,
+		UsageSamples:    int32(usage.Samples),
+	})
+	if err != nil {
+		return fmt.Errorf("saving task usage: %w", err)
+	}
+
+	return nil
+}
+
+// UsageReport aggregates the usage recorded in [from, to) per model and day.
+func (r *TaskRepository) UsageReport(ctx context.Context, from, to time.Time) ([]domain.UsageReportRow, error) {
+	rows, err := r.queries.GetUsageReport(ctx, db.GetUsageReportParams{
+		Since: pgtype.Timestamptz{Time: from, Valid: true},
+		Until: pgtype.Timestamptz{Time: to, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting usage report: %w", err)
+	}
+
+	report := make([]domain.UsageReportRow, 0, len(rows))
+	for _, row := range rows {
+		report = append(report, domain.UsageReportRow{
+			ModelID:         row.ModelID,
+			Day:             row.Day.Time.UTC(),
+			Tasks:           row.Tasks,
+			MemPeakBytes:    row.MemPeakBytes,
+			MemAvgBytes:     row.MemAvgBytes,
+			CPUSeconds:      row.CpuSeconds,
+			BlockReadBytes:  row.BlockReadBytes,
+			BlockWriteBytes: row.BlockWriteBytes,
+			NetRxBytes:      row.NetRxBytes,
+			NetTxBytes:      row.NetTxBytes,
+			AvgQueueSeconds: row.AvgQueueSeconds,
+			RunSeconds:      row.RunSeconds,
+		})
+	}
+
+	return report, nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"pinn-connect-service/internal/db"
+	"pinn-connect-service/internal/domain"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var usageReportColumns = []string{"model_id", "day", "tasks", "mem_peak_bytes", "mem_avg_bytes", "cpu_seconds",
+	"block_read_bytes", "block_write_bytes", "net_rx_bytes", "net_tx_bytes", "avg_queue_seconds", "run_seconds"}
+
+func TestTaskRepository_SaveTaskUsage(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+	id := uuid.New()
+
+	mock.ExpectExec(`UPDATE tasks\s+SET mem_peak_bytes`).
+		WithArgs(pgtype.UUID{Bytes: id, Valid: true}, int64(200), int64(150), 2.5,
+			int64(10), int64(20), int64(30), int64(40), int32(3)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	usage := domain.ResourceUsage{
+		MemPeakBytes:    200,
+		MemAvgBytes:     150,
+		CPUSeconds:      2.5,
+		BlockReadBytes:  10,
+		BlockWriteBytes: 20,
+		NetRxBytes:      30,
+		NetTxBytes:      40,
+		Samples:         3,
+	}
+	if err := repo.SaveTaskUsage(context.Background(), id, usage); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestTaskRepository_SaveTaskUsage_DBError(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+
+	mock.ExpectExec(`UPDATE tasks\s+SET mem_peak_bytes`).
+		WithArgs(anyArgs(9)...).
+		WillReturnError(errors.New("db error"))
+
+	if err := repo.SaveTaskUsage(context.Background(), uuid.New(), domain.ResourceUsage{}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestTaskRepository_UsageReport_Success(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 2)
+	day := pgtype.Timestamptz{Time: from.In(time.FixedZone("MSK", 3*3600)), Valid: true}
+
+	mock.ExpectQuery(`GROUP BY model_id, day`).
+		WithArgs(pgtype.Timestamptz{Time: from, Valid: true}, pgtype.Timestamptz{Time: to, Valid: true}).
+		WillReturnRows(pgxmock.NewRows(usageReportColumns).
+			AddRow("m1", day, int64(2), int64(300), int64(150), 4.5, int64(1), int64(2), int64(3), int64(4), 0.5, 60.0))
+
+	report, err := repo.UsageReport(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(report))
+	}
+	row := report[0]
+	if row.ModelID != "m1" || row.Tasks != 2 || row.MemPeakBytes != 300 || row.CPUSeconds != 4.5 || row.RunSeconds != 60 {
+		t.Errorf("unexpected row %+v", row)
+	}
+	if row.Day.Location() != time.UTC || !row.Day.Equal(from) {
+		t.Errorf("expected the day in UTC, got %v", row.Day)
+	}
+}
+
+func TestTaskRepository_UsageReport_DBError(t *testing.T) {
+	repo, mock := newTaskRepoMock(t)
+
+	mock.ExpectQuery(`GROUP BY model_id, day`).
+		WithArgs(anyArgs(2)...).
+		WillReturnError(errors.New("db error"))
+
+	if _, err := repo.UsageReport(context.Background(), time.Now().Add(-time.Hour), time.Now()); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestDbTaskToDomainTask_Usage(t *testing.T) {
+	recorded := time.Now()
+	src := db.Task{
+		ID:              pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		MemPeakBytes:    300,
+		MemAvgBytes:     200,
+		CpuSeconds:      1.5,
+		UsageSamples:    4,
+		QueueSeconds:    2,
+		RunSeconds:      20,
+		UsageRecordedAt: pgtype.Timestamptz{Time: recorded, Valid: true},
+	}
+
+	got := dbTaskToDomainTask(&src)
+	if got.Usage == nil {
+		t.Fatal("expected the usage to be mapped")
+	}
+	want := domain.ResourceUsage{
+		MemPeakBytes: 300,
+		MemAvgBytes:  200,
+		CPUSeconds:   1.5,
+		Samples:      4,
+		QueueSeconds: 2,
+		RunSeconds:   20,
+		RecordedAt:   recorded,
+	}
+	if *got.Usage != want {
+		t.Errorf("usage = %+v, want %+v", *got.Usage, want)
+	}
+}
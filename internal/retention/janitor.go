@@ -0,0 +1,338 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/domain"
+	"pinn-connect-service/internal/storage"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Repository interface {
+	GetFinishedTasks(context.Context) ([]*domain.Task, error)
+	DeleteTask(context.Context, uuid.UUID) error
+	DeleteArtifactChecksums(ctx context.Context, prefix string) error
+}
+
+type Storage interface {
+	ListArtifacts(ctx context.Context, prefix string) ([]domain.Artifact, error)
+	DeleteArtifacts(ctx context.Context, taskID uuid.UUID) error
+}
+
+type Workspace interface {
+	Cleanup(taskID uuid.UUID) error
+}
+
+// Janitor deletes finished tasks, their workspaces and stored results
+// according to the retention policy.
+type Janitor struct {
+	repo      Repository
+	storage   Storage
+	workspace Workspace
+	config    config.RetentionConfig
+
+	runMu    sync.Mutex
+	reportMu sync.RWMutex
+	last     *domain.RetentionReport
+
+	now func() time.Time
+}
+
+func NewJanitor(repo Repository, storage Storage, workspace Workspace, cfg config.RetentionConfig) *Janitor {
+	return &Janitor{
+		repo:      repo,
+		storage:   storage,
+		workspace: workspace,
+		config:    cfg,
+		now:       time.Now,
+	}
+}
+
+// Start runs the retention policy every configured interval until ctx is done.
+func (j *Janitor) Start(ctx context.Context, wg *sync.WaitGroup) {
+	ticker := time.NewTicker(j.config.Interval)
+
+	wg.Go(func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report := j.Run(ctx)
+				slog.Info("retention: run finished",
+					"deleted_tasks", len(report.DeletedTasks),
+					"deleted_prefixes", len(report.DeletedPrefixes),
+					"freed_bytes", report.FreedBytes,
+					"errors", len(report.Errors),
+				)
+			}
+		}
+	})
+}
+
+// LastReport returns the report of the last finished run or nil if there was none.
+func (j *Janitor) LastReport() *domain.RetentionReport {
+	j.reportMu.RLock()
+	defer j.reportMu.RUnlock()
+	return j.last
+}
+
+// Run applies the retention policy once. It is a blocking call, concurrent
+// calls are serialised.
+func (j *Janitor) Run(ctx context.Context) *domain.RetentionReport {
+	j.runMu.Lock()
+	defer j.runMu.Unlock()
+
+	report := &domain.RetentionReport{
+		StartedAt:       j.now(),
+		DeletedTasks:    []domain.RetentionDeletion{},
+		DeletedPrefixes: []string{},
+	}
+	defer func() {
+		report.FinishedAt = j.now()
+		j.reportMu.Lock()
+		j.last = report
+		j.reportMu.Unlock()
+	}()
+
+	tasks, err := j.repo.GetFinishedTasks(ctx)
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("getting finished tasks: %v", err))
+		return report
+	}
+
+	sizes, err := j.prefixSizes(ctx)
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("listing artifacts: %v", err))
+		return report
+	}
+	for _, size := range sizes {
+		report.TotalBytes += size
+	}
+
+	// several tasks may share a result prefix when results were taken from cache
+	refs := make(map[string][]*domain.Task)
+	for _, task := range tasks {
+		if prefix := storage.ResultPrefix(task.ResultPath); prefix != "" {
+			refs[prefix] = append(refs[prefix], task)
+		}
+	}
+
+	reasons := j.selectExpired(tasks)
+	j.selectOverLimit(tasks, refs, sizes, reasons, report.TotalBytes)
+
+	deleted := make(map[uuid.UUID]bool, len(reasons))
+	for _, task := range tasks {
+		reason, ok := reasons[task.ID]
+		if !ok {
+			continue
+		}
+		if err := j.deleteTask(ctx, task); err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		deleted[task.ID] = true
+		report.DeletedTasks = append(report.DeletedTasks, domain.RetentionDeletion{
+			TaskID: task.ID,
+			Status: task.Status,
+			Reason: reason,
+		})
+	}
+
+	for _, prefix := range orphanedPrefixes(tasks, refs, deleted) {
+		owner, ok := storage.PrefixOwner(prefix)
+		if !ok {
+			continue
+		}
+		if err := j.storage.DeleteArtifacts(ctx, owner); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("deleting artifacts %s: %v", prefix, err))
+			continue
+		}
+		report.DeletedPrefixes = append(report.DeletedPrefixes, prefix)
+		report.FreedBytes += sizes[prefix]
+
+		if err := j.repo.DeleteArtifactChecksums(ctx, prefix); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("deleting checksums %s: %v", prefix, err))
+		}
+	}
+	report.TotalBytes -= report.FreedBytes
+
+	return report
+}
+
+// selectExpired returns the tasks that outlived their retention period.
+func (j *Janitor) selectExpired(tasks []*domain.Task) map[uuid.UUID]domain.RetentionReason {
+	now := j.now()
+	reasons := make(map[uuid.UUID]domain.RetentionReason)
+
+	for _, task := range tasks {
+		if task.Pinned {
+			continue
+		}
+
+		maxAge := j.maxAge(task)
+		if maxAge <= 0 {
+			continue
+		}
+
+		if now.Sub(finishedAt(task)) > maxAge {
+			reasons[task.ID] = domain.RetentionExpired
+		}
+	}
+
+	return reasons
+}
+
+// selectOverLimit evicts the oldest results until the stored size fits into
+// MaxTotalBytes. A result is evicted together with every task referencing it,
+// so results referenced by a pinned task or a task with keep_for are kept.
+func (j *Janitor) selectOverLimit(tasks []*domain.Task, refs map[string][]*domain.Task,
+	sizes map[string]int64, reasons map[uuid.UUID]domain.RetentionReason, total int64) {
+	if j.config.MaxTotalBytes <= 0 {
+		return
+	}
+
+	visited := make(map[string]bool)
+	for prefix, holders := range refs {
+		if allSelected(holders, reasons) {
+			total -= sizes[prefix]
+			visited[prefix] = true
+		}
+	}
+
+	// tasks are ordered by finished_at, so the oldest results go first
+	for _, task := range tasks {
+		if total <= j.config.MaxTotalBytes {
+			return
+		}
+
+		prefix := storage.ResultPrefix(task.ResultPath)
+		if prefix == "" || visited[prefix] {
+			continue
+		}
+		visited[prefix] = true
+
+		holders := refs[prefix]
+		if !evictable(holders) {
+			continue
+		}
+
+		for _, holder := range holders {
+			if _, ok := reasons[holder.ID]; !ok {
+				reasons[holder.ID] = domain.RetentionSizeLimit
+			}
+		}
+		total -= sizes[prefix]
+	}
+}
+
+func (j *Janitor) maxAge(task *domain.Task) time.Duration {
+	if task.KeepForSec > 0 {
+		return time.Duration(task.KeepForSec) * time.Second
+	}
+
+	switch task.Status {
+	case domain.TaskCompleted:
+		return j.config.MaxAgeCompleted
+	case domain.TaskFailed:
+		return j.config.MaxAgeFailed
+	case domain.TaskStopped, domain.TaskSkipped:
+		return j.config.MaxAgeStopped
+	default:
+		return 0
+	}
+}
+
+func (j *Janitor) deleteTask(ctx context.Context, task *domain.Task) error {
+	if err := j.workspace.Cleanup(task.ID); err != nil {
+		return fmt.Errorf("cleaning up workspace %s: %w", task.ID, err)
+	}
+
+	if err := j.repo.DeleteTask(ctx, task.ID); err != nil {
+		return fmt.Errorf("deleting task %s: %w", task.ID, err)
+	}
+
+	return nil
+}
+
+func (j *Janitor) prefixSizes(ctx context.Context) (map[string]int64, error) {
+	artifacts, err := j.storage.ListArtifacts(ctx, storage.TasksPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make(map[string]int64)
+	for _, a := range artifacts {
+		if prefix := storage.ResultPrefix(a.Key); prefix != "" {
+			sizes[prefix] += a.Size
+		}
+	}
+
+	return sizes, nil
+}
+
+// orphanedPrefixes returns the prefixes of deleted tasks that are no longer
+// referenced by any surviving task.
+func orphanedPrefixes(tasks []*domain.Task, refs map[string][]*domain.Task, deleted map[uuid.UUID]bool) []string {
+	seen := make(map[string]bool)
+	var result []string
+
+	for _, task := range tasks {
+		if !deleted[task.ID] {
+			continue
+		}
+
+		for _, prefix := range []string{storage.ResultPrefix(task.ResultPath), storage.TaskPrefix(task.ID)} {
+			if prefix == "" || seen[prefix] {
+				continue
+			}
+			seen[prefix] = true
+
+			if allDeleted(refs[prefix], deleted) {
+				result = append(result, prefix)
+			}
+		}
+	}
+
+	return result
+}
+
+func evictable(tasks []*domain.Task) bool {
+	for _, task := range tasks {
+		if task.Pinned || task.KeepForSec > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func allSelected(tasks []*domain.Task, reasons map[uuid.UUID]domain.RetentionReason) bool {
+	for _, task := range tasks {
+		if _, ok := reasons[task.ID]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func allDeleted(tasks []*domain.Task, deleted map[uuid.UUID]bool) bool {
+	for _, task := range tasks {
+		if !deleted[task.ID] {
+			return false
+		}
+	}
+	return true
+}
+
+func finishedAt(task *domain.Task) time.Time {
+	if task.FinishedAt != nil {
+		return *task.FinishedAt
+	}
+	return task.UpdatedAt
+}
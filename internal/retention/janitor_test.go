@@ -0,0 +1,261 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/domain"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// --- MOCKS ---
+
+type mockRepo struct {
+	tasks      []*domain.Task
+	getErr     error
+	deleteFunc func(ctx context.Context, id uuid.UUID) error
+	deleted    []uuid.UUID
+	sumsPurged []string
+}
+
+func (m *mockRepo) GetFinishedTasks(ctx context.Context) ([]*domain.Task, error) {
+	return m.tasks, m.getErr
+}
+func (m *mockRepo) DeleteTask(ctx context.Context, id uuid.UUID) error {
+	if m.deleteFunc != nil {
+		if err := m.deleteFunc(ctx, id); err != nil {
+			return err
+		}
+	}
+	m.deleted = append(m.deleted, id)
+	return nil
+}
+func (m *mockRepo) DeleteArtifactChecksums(ctx context.Context, prefix string) error {
+	m.sumsPurged = append(m.sumsPurged, prefix)
+	return nil
+}
+
+type mockStorage struct {
+	objects map[string]int64
+	deleted []uuid.UUID
+}
+
+func (m *mockStorage) ListArtifacts(ctx context.Context, prefix string) ([]domain.Artifact, error) {
+	var res []domain.Artifact
+	for key, size := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			res = append(res, domain.Artifact{Key: key, Size: size})
+		}
+	}
+	return res, nil
+}
+func (m *mockStorage) DeleteArtifacts(ctx context.Context, taskID uuid.UUID) error {
+	m.deleted = append(m.deleted, taskID)
+	return nil
+}
+
+type mockWorkspace struct {
+	cleaned []uuid.UUID
+}
+
+func (m *mockWorkspace) Cleanup(taskID uuid.UUID) error {
+	m.cleaned = append(m.cleaned, taskID)
+	return nil
+}
+
+// --- HELPERS ---
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func finished(status domain.TaskStatus, age time.Duration, owner uuid.UUID) *domain.Task {
+	at := now.Add(-age)
+	task := &domain.Task{ID: uuid.New(), Status: status, FinishedAt: &at}
+	if owner != uuid.Nil {
+		task.ResultPath = "tasks/" + owner.String() + "/result.txt"
+	}
+	return task
+}
+
+func newTestJanitor(tasks []*domain.Task, objects map[string]int64, cfg config.RetentionConfig) (*Janitor, *mockRepo, *mockStorage) {
+	repo := &mockRepo{tasks: tasks}
+	storage := &mockStorage{objects: objects}
+	j := NewJanitor(repo, storage, &mockWorkspace{}, cfg)
+	j.now = func() time.Time { return now }
+	return j, repo, storage
+}
+
+func contains(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// --- TESTS ---
+
+func TestRun_DeletesExpiredByStatus(t *testing.T) {
+	oldFailed := finished(domain.TaskFailed, 3*time.Hour, uuid.Nil)
+	freshFailed := finished(domain.TaskFailed, 30*time.Minute, uuid.Nil)
+	oldCompleted := finished(domain.TaskCompleted, 3*time.Hour, uuid.Nil)
+	oldCompleted.ResultPath = "tasks/" + oldCompleted.ID.String() + "/result.txt"
+
+	j, repo, _ := newTestJanitor(
+		[]*domain.Task{oldFailed, freshFailed, oldCompleted},
+		map[string]int64{oldCompleted.ResultPath: 10},
+		config.RetentionConfig{MaxAgeFailed: time.Hour},
+	)
+
+	report := j.Run(context.Background())
+
+	if len(repo.deleted) != 1 || repo.deleted[0] != oldFailed.ID {
+		t.Fatalf("expected only the old failed task to be deleted, got %v", repo.deleted)
+	}
+	if report.DeletedTasks[0].Reason != domain.RetentionExpired {
+		t.Errorf("expected reason expired, got %q", report.DeletedTasks[0].Reason)
+	}
+	if report.TotalBytes != 10 {
+		t.Errorf("expected total bytes 10, got %d", report.TotalBytes)
+	}
+}
+
+func TestRun_PinnedAndKeepFor(t *testing.T) {
+	pinned := finished(domain.TaskFailed, 48*time.Hour, uuid.Nil)
+	pinned.Pinned = true
+	keep := finished(domain.TaskFailed, 2*time.Hour, uuid.Nil)
+	keep.KeepForSec = int((24 * time.Hour).Seconds())
+	shortKeep := finished(domain.TaskCompleted, 2*time.Hour, uuid.Nil)
+	shortKeep.KeepForSec = 60
+
+	j, repo, _ := newTestJanitor(
+		[]*domain.Task{pinned, keep, shortKeep},
+		nil,
+		config.RetentionConfig{MaxAgeFailed: time.Hour},
+	)
+
+	j.Run(context.Background())
+
+	if len(repo.deleted) != 1 || repo.deleted[0] != shortKeep.ID {
+		t.Fatalf("expected only task with expired keep_for to be deleted, got %v", repo.deleted)
+	}
+}
+
+// A result shared through the cache must survive until its last reference is deleted.
+func TestRun_KeepsResultReferencedByCachedTask(t *testing.T) {
+	owner := finished(domain.TaskCompleted, 3*time.Hour, uuid.Nil)
+	owner.ResultPath = "tasks/" + owner.ID.String() + "/result.txt"
+	cached := finished(domain.TaskCompleted, 10*time.Minute, owner.ID)
+
+	j, repo, storage := newTestJanitor(
+		[]*domain.Task{owner, cached},
+		map[string]int64{owner.ResultPath: 100},
+		config.RetentionConfig{MaxAgeCompleted: time.Hour},
+	)
+
+	report := j.Run(context.Background())
+
+	if !contains(repo.deleted, owner.ID) {
+		t.Fatal("expected expired owner task row to be deleted")
+	}
+	if len(storage.deleted) != 0 {
+		t.Fatalf("expected shared result to be kept, deleted %v", storage.deleted)
+	}
+	if len(repo.sumsPurged) != 0 {
+		t.Fatalf("expected checksums of shared result to be kept, deleted %v", repo.sumsPurged)
+	}
+	if report.FreedBytes != 0 {
+		t.Errorf("expected 0 freed bytes, got %d", report.FreedBytes)
+	}
+}
+
+func TestRun_DeletesResultWithLastReference(t *testing.T) {
+	owner := uuid.New() // row already deleted by a previous run
+	cached := finished(domain.TaskCompleted, 3*time.Hour, owner)
+
+	j, repo, storage := newTestJanitor(
+		[]*domain.Task{cached},
+		map[string]int64{cached.ResultPath: 100},
+		config.RetentionConfig{MaxAgeCompleted: time.Hour},
+	)
+
+	report := j.Run(context.Background())
+
+	if !contains(storage.deleted, owner) {
+		t.Fatalf("expected owner prefix to be deleted, got %v", storage.deleted)
+	}
+	if prefix := "tasks/" + owner.String() + "/"; !slices.Contains(repo.sumsPurged, prefix) {
+		t.Errorf("expected checksums of %s to be deleted, got %v", prefix, repo.sumsPurged)
+	}
+	if report.FreedBytes != 100 || report.TotalBytes != 0 {
+		t.Errorf("unexpected bytes: freed %d, total %d", report.FreedBytes, report.TotalBytes)
+	}
+}
+
+func TestRun_EvictsOldestOverSizeLimit(t *testing.T) {
+	oldest := finished(domain.TaskCompleted, 3*time.Hour, uuid.Nil)
+	oldest.ResultPath = "tasks/" + oldest.ID.String() + "/r"
+	pinned := finished(domain.TaskCompleted, 2*time.Hour, uuid.Nil)
+	pinned.ResultPath = "tasks/" + pinned.ID.String() + "/r"
+	pinned.Pinned = true
+	newest := finished(domain.TaskCompleted, time.Hour, uuid.Nil)
+	newest.ResultPath = "tasks/" + newest.ID.String() + "/r"
+
+	j, repo, _ := newTestJanitor(
+		[]*domain.Task{oldest, pinned, newest},
+		map[string]int64{oldest.ResultPath: 50, pinned.ResultPath: 50, newest.ResultPath: 50},
+		config.RetentionConfig{MaxTotalBytes: 100},
+	)
+
+	report := j.Run(context.Background())
+
+	if len(repo.deleted) != 1 || repo.deleted[0] != oldest.ID {
+		t.Fatalf("expected only the oldest task to be evicted, got %v", repo.deleted)
+	}
+	if report.DeletedTasks[0].Reason != domain.RetentionSizeLimit {
+		t.Errorf("expected reason size_limit, got %q", report.DeletedTasks[0].Reason)
+	}
+	if report.TotalBytes != 100 {
+		t.Errorf("expected total bytes 100, got %d", report.TotalBytes)
+	}
+}
+
+func TestRun_DeleteErrorKeepsResult(t *testing.T) {
+	task := finished(domain.TaskCompleted, 3*time.Hour, uuid.Nil)
+	task.ResultPath = "tasks/" + task.ID.String() + "/result.txt"
+
+	j, repo, storage := newTestJanitor(
+		[]*domain.Task{task},
+		map[string]int64{task.ResultPath: 1},
+		config.RetentionConfig{MaxAgeCompleted: time.Hour},
+	)
+	repo.deleteFunc = func(context.Context, uuid.UUID) error { return errors.New("db down") }
+
+	report := j.Run(context.Background())
+
+	if len(report.Errors) != 1 {
+		t.Fatalf("expected 1 error, got %v", report.Errors)
+	}
+	if len(storage.deleted) != 0 {
+		t.Error("result must not be deleted when the task row was kept")
+	}
+}
+
+func TestRun_StoresLastReport(t *testing.T) {
+	j, repo, _ := newTestJanitor(nil, nil, config.RetentionConfig{})
+	repo.getErr = errors.New("db down")
+
+	if j.LastReport() != nil {
+		t.Fatal("expected no report before the first run")
+	}
+
+	report := j.Run(context.Background())
+	if j.LastReport() != report || len(report.Errors) != 1 {
+		t.Errorf("expected last report with an error, got %+v", j.LastReport())
+	}
+}
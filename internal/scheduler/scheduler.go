@@ -0,0 +1,144 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/domain"
+	"sync"
+	"time"
+)
+
+// maxMisfires is the number of recent misfires kept in the report.
+const maxMisfires = 100
+
+// minWait keeps the scheduler from spinning when a due task is locked by
+// another instance.
+const minWait = 100 * time.Millisecond
+
+type Repository interface {
+	PromoteScheduledTasks(ctx context.Context, policy string, misfireThreshold time.Duration) ([]*domain.Task, error)
+	GetNextScheduledAt(context.Context) (*time.Time, error)
+}
+
+// Scheduler moves scheduled tasks to the queue once they are due. All state
+// lives in the database, so firings missed while the service was down are
+// found on the next run and handled by the misfire policy.
+type Scheduler struct {
+	repo   Repository
+	config config.SchedulerConfig
+
+	runMu    sync.Mutex
+	reportMu sync.RWMutex
+	report   *domain.SchedulerReport
+
+	now func() time.Time
+}
+
+func NewScheduler(repo Repository, cfg config.SchedulerConfig) *Scheduler {
+	return &Scheduler{
+		repo:   repo,
+		config: cfg,
+		now:    time.Now,
+	}
+}
+
+// Start promotes due tasks until ctx is done. The scheduler sleeps until the
+// next scheduled task is due or until scheduled receives, as a task scheduled
+// in the meantime may be due earlier. It never sleeps longer than the
+// configured interval in case a notification is lost.
+func (s *Scheduler) Start(ctx context.Context, wg *sync.WaitGroup, scheduled <-chan struct{}) {
+	wg.Go(func() {
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-scheduled:
+				timer.Reset(s.wait(s.Run(ctx)))
+			case <-timer.C:
+				timer.Reset(s.wait(s.Run(ctx)))
+			}
+		}
+	})
+}
+
+func (s *Scheduler) wait(report *domain.SchedulerReport) time.Duration {
+	if report.LastError != "" || report.NextFiringAt == nil {
+		return s.config.Interval
+	}
+	return min(max(report.NextFiringAt.Sub(s.now()), minWait), s.config.Interval)
+}
+
+// Report returns the state of the scheduler or nil if it has not run yet.
+func (s *Scheduler) Report() *domain.SchedulerReport {
+	s.reportMu.RLock()
+	defer s.reportMu.RUnlock()
+	return s.report
+}
+
+// Run promotes the due tasks once and returns the updated report.
+// Concurrent calls are serialised.
+func (s *Scheduler) Run(ctx context.Context) *domain.SchedulerReport {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	report := domain.SchedulerReport{Misfires: []domain.Misfire{}}
+	if last := s.Report(); last != nil {
+		report = *last
+	}
+	report.LastRunAt = s.now()
+	report.LastError = ""
+
+	defer func() {
+		s.reportMu.Lock()
+		s.report = &report
+		s.reportMu.Unlock()
+	}()
+
+	tasks, err := s.repo.PromoteScheduledTasks(ctx, s.config.MisfirePolicy, s.config.MisfireThreshold)
+	if err != nil {
+		slog.Error("scheduler: promoting tasks", "error", err)
+		report.LastError = err.Error()
+		return &report
+	}
+
+	var misfires []domain.Misfire
+	for _, task := range tasks {
+		if task.Status == domain.TaskQueued {
+			report.QueuedTasks++
+		}
+
+		late := report.LastRunAt.Sub(*task.ScheduledAt)
+		if task.Status == domain.TaskQueued && late <= s.config.MisfireThreshold {
+			continue
+		}
+
+		slog.Warn("scheduler: task missed its schedule",
+			"task_id", task.ID,
+			"scheduled_at", task.ScheduledAt,
+			"late", late.Round(time.Second),
+			"policy", s.config.MisfirePolicy,
+		)
+		misfires = append(misfires, domain.Misfire{
+			TaskID:      task.ID,
+			ScheduledAt: *task.ScheduledAt,
+			DetectedAt:  report.LastRunAt,
+			Action:      s.config.MisfirePolicy,
+		})
+	}
+	if len(misfires) > 0 {
+		report.Misfires = append(misfires, report.Misfires...)[:min(len(misfires)+len(report.Misfires), maxMisfires)]
+	}
+
+	next, err := s.repo.GetNextScheduledAt(ctx)
+	if err != nil {
+		slog.Error("scheduler: getting next scheduled time", "error", err)
+		report.LastError = err.Error()
+	}
+	report.NextFiringAt = next
+
+	return &report
+}
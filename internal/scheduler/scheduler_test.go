@@ -0,0 +1,212 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/domain"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// --- MOCKS ---
+
+type mockRepo struct {
+	mu       sync.Mutex
+	tasks    []*domain.Task
+	err      error
+	next     *time.Time
+	policies []string
+}
+
+func (m *mockRepo) PromoteScheduledTasks(ctx context.Context, policy string, threshold time.Duration) ([]*domain.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policies = append(m.policies, policy)
+	tasks := m.tasks
+	m.tasks = nil
+	return tasks, m.err
+}
+
+func (m *mockRepo) GetNextScheduledAt(ctx context.Context) (*time.Time, error) {
+	return m.next, nil
+}
+
+func (m *mockRepo) calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.policies)
+}
+
+// --- HELPERS ---
+
+var testNow = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestScheduler(repo *mockRepo, policy string) *Scheduler {
+	s := NewScheduler(repo, config.SchedulerConfig{
+		Interval:         time.Minute,
+		MisfirePolicy:    policy,
+		MisfireThreshold: 5 * time.Minute,
+	})
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+func scheduledTask(status domain.TaskStatus, late time.Duration) *domain.Task {
+	at := testNow.Add(-late)
+	return &domain.Task{ID: uuid.New(), Status: status, ScheduledAt: &at}
+}
+
+// --- TESTS ---
+
+func TestRun_QueuesDueTasks(t *testing.T) {
+	repo := &mockRepo{tasks: []*domain.Task{
+		scheduledTask(domain.TaskQueued, time.Second),
+		scheduledTask(domain.TaskQueued, time.Minute),
+	}}
+	s := newTestScheduler(repo, config.MisfireSkip)
+
+	report := s.Run(context.Background())
+
+	if report.QueuedTasks != 2 || len(report.Misfires) != 0 {
+		t.Errorf("expected 2 queued tasks without misfires, got %+v", report)
+	}
+	if repo.policies[0] != config.MisfireSkip {
+		t.Errorf("expected the configured policy, got %q", repo.policies[0])
+	}
+	if s.Report() != report {
+		t.Error("expected the report to be kept")
+	}
+}
+
+func TestRun_ReportsMisfires(t *testing.T) {
+	cases := map[string]domain.TaskStatus{
+		config.MisfireRun:  domain.TaskQueued,
+		config.MisfireSkip: domain.TaskSkipped,
+		config.MisfireFail: domain.TaskFailed,
+	}
+	for policy, status := range cases {
+		t.Run(policy, func(t *testing.T) {
+			late := scheduledTask(status, time.Hour)
+			repo := &mockRepo{tasks: []*domain.Task{late}}
+			s := newTestScheduler(repo, policy)
+
+			report := s.Run(context.Background())
+
+			if len(report.Misfires) != 1 || report.Misfires[0].TaskID != late.ID || report.Misfires[0].Action != policy {
+				t.Fatalf("expected a %s misfire of %s, got %+v", policy, late.ID, report.Misfires)
+			}
+			wantQueued := int64(0)
+			if policy == config.MisfireRun {
+				wantQueued = 1
+			}
+			if report.QueuedTasks != wantQueued {
+				t.Errorf("expected %d queued tasks, got %d", wantQueued, report.QueuedTasks)
+			}
+		})
+	}
+}
+
+// Misfires and counters accumulate over runs, newest misfires first.
+func TestRun_AccumulatesReport(t *testing.T) {
+	repo := &mockRepo{}
+	s := newTestScheduler(repo, config.MisfireSkip)
+
+	first := scheduledTask(domain.TaskSkipped, time.Hour)
+	repo.tasks = []*domain.Task{first, scheduledTask(domain.TaskQueued, 0)}
+	s.Run(context.Background())
+
+	second := scheduledTask(domain.TaskSkipped, time.Hour)
+	repo.tasks = []*domain.Task{second}
+	report := s.Run(context.Background())
+
+	if report.QueuedTasks != 1 || len(report.Misfires) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if report.Misfires[0].TaskID != second.ID || report.Misfires[1].TaskID != first.ID {
+		t.Errorf("expected the newest misfire first, got %+v", report.Misfires)
+	}
+}
+
+func TestRun_Error(t *testing.T) {
+	repo := &mockRepo{err: errors.New("db down")}
+	s := newTestScheduler(repo, config.MisfireRun)
+
+	report := s.Run(context.Background())
+
+	if report.LastError == "" {
+		t.Error("expected the error to be reported")
+	}
+	if got := s.wait(report); got != time.Minute {
+		t.Errorf("expected to retry after the interval, got %s", got)
+	}
+}
+
+func TestWait(t *testing.T) {
+	s := newTestScheduler(&mockRepo{}, config.MisfireRun)
+	at := func(d time.Duration) *domain.SchedulerReport {
+		next := testNow.Add(d)
+		return &domain.SchedulerReport{NextFiringAt: &next}
+	}
+
+	if got := s.wait(&domain.SchedulerReport{}); got != time.Minute {
+		t.Errorf("expected the interval without scheduled tasks, got %s", got)
+	}
+	if got := s.wait(at(10 * time.Second)); got != 10*time.Second {
+		t.Errorf("expected to wake up at the next firing, got %s", got)
+	}
+	if got := s.wait(at(time.Hour)); got != time.Minute {
+		t.Errorf("expected the wait to be capped by the interval, got %s", got)
+	}
+	if got := s.wait(at(-time.Second)); got != minWait {
+		t.Errorf("expected the minimal wait for an overdue task, got %s", got)
+	}
+}
+
+func TestStart_RunsImmediately(t *testing.T) {
+	repo := &mockRepo{}
+	s := newTestScheduler(repo, config.MisfireRun)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	s.Start(ctx, &wg, nil)
+
+	deadline := time.Now().Add(time.Second)
+	for repo.calls() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+
+	if repo.calls() == 0 {
+		t.Error("expected the scheduler to run on start")
+	}
+}
+
+func TestStart_RunsOnNotification(t *testing.T) {
+	repo := &mockRepo{}
+	s := newTestScheduler(repo, config.MisfireRun)
+
+	scheduled := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	s.Start(ctx, &wg, scheduled)
+
+	deadline := time.Now().Add(time.Second)
+	for repo.calls() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	scheduled <- struct{}{}
+	for repo.calls() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+
+	if repo.calls() < 2 {
+		t.Errorf("expected a run per notification, got %d runs", repo.calls())
+	}
+}
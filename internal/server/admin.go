@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"pinn-connect-service/internal/domain"
+	"strconv"
+)
+
+// HandleRetentionReport godoc
+// @Summary      Get last retention report
+// @Description  Returns the report of the last retention run
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  domain.RetentionReport
+// @Failure      404  {string}  string "Retention has not run yet"
+// @Router       /admin/retention [get]
+func (s *Server) HandleRetentionReport(w http.ResponseWriter, r *http.Request) {
+	report := s.adminService.LastRetentionReport()
+	if report == nil {
+		http.Error(w, "retention has not run yet", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, report)
+}
+
+// HandleRetentionRun godoc
+// @Summary      Run retention
+// @Description  Applies the retention policy immediately and returns the report
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  domain.RetentionReport
+// @Failure      409  {string}  string "Instance is not the leader"
+// @Router       /admin/retention/run [post]
+func (s *Server) HandleRetentionRun(w http.ResponseWriter, r *http.Request) {
+	report, err := s.adminService.RunRetention(r.Context())
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	writeJSON(w, report)
+}
+
+// HandleGCReport godoc
+// @Summary      Get last garbage collector report
+// @Description  Returns the report of the last garbage collector run
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  domain.GCReport
+// @Failure      404  {string}  string "Garbage collector has not run yet"
+// @Router       /admin/gc [get]
+func (s *Server) HandleGCReport(w http.ResponseWriter, r *http.Request) {
+	report := s.adminService.LastGCReport()
+	if report == nil {
+		http.Error(w, "garbage collector has not run yet", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, report)
+}
+
+// HandleGCRun godoc
+// @Summary      Run garbage collector
+// @Description  Runs the garbage collector immediately and returns the report
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  domain.GCReport
+// @Failure      409  {string}  string "Instance does not execute tasks"
+// @Router       /admin/gc/run [post]
+func (s *Server) HandleGCRun(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), s.config.GC.Timeout)
+	defer cancel()
+
+	report, err := s.adminService.RunGC(ctx)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	writeJSON(w, report)
+}
+
+// HandleReconcile godoc
+// @Summary      Reconcile storage with the database
+// @Description  Finds objects under tasks/ that are not referenced by any task and tasks whose result object is missing. Orphan objects are deleted and tasks are marked with result_missing unless dry_run is set.
+// @Tags         admin
+// @Produce      json
+// @Param        dry_run  query     bool  false  "Only report, don't delete or mark anything"
+// @Success      200      {object}  domain.ReconcileReport
+// @Failure      400      {string}  string "Invalid dry_run"
+// @Failure      409      {string}  string "Instance is not the leader"
+// @Router       /admin/reconcile [post]
+func (s *Server) HandleReconcile(w http.ResponseWriter, r *http.Request) {
+	var dryRun bool
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "invalid dry_run, expected true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.config.Reconcile.Timeout)
+	defer cancel()
+
+	report, err := s.adminService.RunReconcile(ctx, dryRun)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	writeJSON(w, report)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("encoding response", "error", err)
+	}
+}
+
+func writeAdminError(w http.ResponseWriter, err error) {
+	if errors.Is(err, domain.ErrNotLeader) {
+		http.Error(w, "instance is not the leader, see /health for the current leader", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, domain.ErrNotExecuting) {
+		http.Error(w, "instance does not execute tasks, run the job on a worker", http.StatusConflict)
+		return
+	}
+
+	slog.Error("running admin job", "error", err)
+	http.Error(w, "internal server error", http.StatusInternalServerError)
+}
+
+// HandleSchedulerReport godoc
+// @Summary      Get scheduler report
+// @Description  Returns the state of the scheduler: the last run, the next scheduled firing, the number of queued tasks and the most recent misfires
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  domain.SchedulerReport
+// @Failure      404  {string}  string "Scheduler has not run yet"
+// @Router       /admin/scheduler [get]
+func (s *Server) HandleSchedulerReport(w http.ResponseWriter, r *http.Request) {
+	report := s.adminService.SchedulerReport()
+	if report == nil {
+		http.Error(w, "scheduler has not run yet", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, report)
+}
+
+// HandleNodes godoc
+// @Summary      List nodes
+// @Description  Returns the registered nodes executing tasks with their capacity, labels, last heartbeat and the number of running tasks. A node is alive if its last heartbeat is within NODE_TIMEOUT.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {array}   domain.Node
+// @Failure      500  {string}  string "Internal server error"
+// @Router       /admin/nodes [get]
+func (s *Server) HandleNodes(w http.ResponseWriter, r *http.Request) {
+	nodes, err := s.adminService.Nodes(r.Context())
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	writeJSON(w, nodes)
+}
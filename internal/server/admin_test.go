@@ -0,0 +1,321 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pinn-connect-service/internal/domain"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// ─────────────────────────────────────────────
+// HandleTaskPin / HandleTaskUnpin
+// ─────────────────────────────────────────────
+
+func TestHandleTaskPin_Success(t *testing.T) {
+	var gotPinned *bool
+	ts := &mockTaskSvc{
+		setPinnedFunc: func(_ context.Context, id uuid.UUID, pinned bool) (*domain.Task, error) {
+			gotPinned = &pinned
+			return &domain.Task{ID: id, Status: domain.TaskCompleted, Pinned: pinned}, nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
+	id := uuid.New()
+
+	req := httptest.NewRequest(http.MethodPost, "/task/"+id.String()+"/pin", nil)
+	req = withChiParam(req, "id", id.String())
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskPin(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if gotPinned == nil || !*gotPinned {
+		t.Error("expected SetPinned to be called with true")
+	}
+	var resp domain.TaskStatusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !resp.Pinned {
+		t.Error("expected pinned in response")
+	}
+}
+
+func TestHandleTaskUnpin_NotFound(t *testing.T) {
+	ts := &mockTaskSvc{
+		setPinnedFunc: func(context.Context, uuid.UUID, bool) (*domain.Task, error) {
+			return nil, domain.ErrTaskNotFound
+		},
+	}
+	srv := testServer(ts, nil, nil)
+	id := uuid.New()
+
+	req := httptest.NewRequest(http.MethodDelete, "/task/"+id.String()+"/pin", nil)
+	req = withChiParam(req, "id", id.String())
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskUnpin(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestHandleTaskPin_InvalidUUID(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/task/bad/pin", nil)
+	req = withChiParam(req, "id", "bad")
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskPin(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
+// ─────────────────────────────────────────────
+// HandleRetentionReport / HandleRetentionRun
+// ─────────────────────────────────────────────
+
+func TestHandleRetentionReport_NoRunYet(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+
+	rec := httptest.NewRecorder()
+	srv.HandleRetentionReport(rec, httptest.NewRequest(http.MethodGet, "/admin/retention", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestHandleRetentionReport_Success(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+	srv.adminService = &mockAdminSvc{
+		lastRetentionFunc: func() *domain.RetentionReport {
+			return &domain.RetentionReport{FreedBytes: 42}
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	srv.HandleRetentionReport(rec, httptest.NewRequest(http.MethodGet, "/admin/retention", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var report domain.RetentionReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if report.FreedBytes != 42 {
+		t.Errorf("expected freed_bytes 42, got %d", report.FreedBytes)
+	}
+}
+
+func TestHandleRetentionRun(t *testing.T) {
+	var called bool
+	srv := testServer(nil, nil, nil)
+	srv.adminService = &mockAdminSvc{
+		runRetentionFunc: func(context.Context) (*domain.RetentionReport, error) {
+			called = true
+			return &domain.RetentionReport{}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	srv.HandleRetentionRun(rec, httptest.NewRequest(http.MethodPost, "/admin/retention/run", nil))
+
+	if rec.Code != http.StatusOK || !called {
+		t.Errorf("expected 200 and a retention run, got %d (called=%v)", rec.Code, called)
+	}
+}
+
+// ─────────────────────────────────────────────
+// HandleGCReport / HandleGCRun
+// ─────────────────────────────────────────────
+
+func TestHandleGCReport_NoRunYet(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+
+	rec := httptest.NewRecorder()
+	srv.HandleGCReport(rec, httptest.NewRequest(http.MethodGet, "/admin/gc", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestHandleGCReport_Success(t *testing.T) {
+	id := uuid.New()
+	srv := testServer(nil, nil, nil)
+	srv.adminService = &mockAdminSvc{
+		lastGCFunc: func() *domain.GCReport {
+			return &domain.GCReport{RequeuedTasks: []uuid.UUID{id}}
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	srv.HandleGCReport(rec, httptest.NewRequest(http.MethodGet, "/admin/gc", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var report domain.GCReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(report.RequeuedTasks) != 1 || report.RequeuedTasks[0] != id {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestHandleGCRun_UsesTimeout(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+	srv.adminService = &mockAdminSvc{
+		runGCFunc: func(ctx context.Context) (*domain.GCReport, error) {
+			if _, ok := ctx.Deadline(); !ok {
+				t.Error("expected gc run to be bounded by GC_TIMEOUT")
+			}
+			return &domain.GCReport{}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	srv.HandleGCRun(rec, httptest.NewRequest(http.MethodPost, "/admin/gc/run", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rec.Code)
+	}
+}
+
+func TestHandleGCRun_NotExecuting(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+	srv.adminService = &mockAdminSvc{
+		runGCFunc: func(context.Context) (*domain.GCReport, error) {
+			return nil, domain.ErrNotExecuting
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	srv.HandleGCRun(rec, httptest.NewRequest(http.MethodPost, "/admin/gc/run", nil))
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d", rec.Code)
+	}
+}
+
+// ─────────────────────────────────────────────
+// HandleReconcile
+// ─────────────────────────────────────────────
+
+func TestHandleReconcile_DryRun(t *testing.T) {
+	var gotDryRun bool
+	srv := testServer(nil, nil, nil)
+	srv.adminService = &mockAdminSvc{
+		runReconcileFunc: func(ctx context.Context, dryRun bool) (*domain.ReconcileReport, error) {
+			if _, ok := ctx.Deadline(); !ok {
+				t.Error("expected reconcile run to be bounded by RECONCILE_TIMEOUT")
+			}
+			gotDryRun = dryRun
+			return &domain.ReconcileReport{DryRun: dryRun, OrphanObjects: []string{"tasks/x/result.txt"}}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	srv.HandleReconcile(rec, httptest.NewRequest(http.MethodPost, "/admin/reconcile?dry_run=true", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if !gotDryRun {
+		t.Error("expected dry run")
+	}
+	var report domain.ReconcileReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !report.DryRun || len(report.OrphanObjects) != 1 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestHandleReconcile_InvalidDryRun(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+
+	rec := httptest.NewRecorder()
+	srv.HandleReconcile(rec, httptest.NewRequest(http.MethodPost, "/admin/reconcile?dry_run=maybe", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestHandleSchedulerReport_NoRunYet(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+
+	rec := httptest.NewRecorder()
+	srv.HandleSchedulerReport(rec, httptest.NewRequest(http.MethodGet, "/admin/scheduler", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestHandleSchedulerReport_Success(t *testing.T) {
+	id := uuid.New()
+	srv := testServer(nil, nil, nil)
+	srv.adminService = &mockAdminSvc{
+		schedulerFunc: func() *domain.SchedulerReport {
+			return &domain.SchedulerReport{QueuedTasks: 3, Misfires: []domain.Misfire{{TaskID: id, Action: "skip"}}}
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	srv.HandleSchedulerReport(rec, httptest.NewRequest(http.MethodGet, "/admin/scheduler", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var report domain.SchedulerReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if report.QueuedTasks != 3 || len(report.Misfires) != 1 || report.Misfires[0].TaskID != id {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+// ─────────────────────────────────────────────
+// HandleNodes
+// ─────────────────────────────────────────────
+
+func TestHandleNodes_Success(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+	srv.adminService = &mockAdminSvc{
+		nodesFunc: func(context.Context) ([]domain.Node, error) {
+			return []domain.Node{
+				{ID: "node-1", Capacity: 2, RunningTasks: 1, Alive: true},
+				{ID: "node-2", Capacity: 4},
+			}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	srv.HandleNodes(rec, httptest.NewRequest(http.MethodGet, "/admin/nodes", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var nodes []domain.Node
+	if err := json.NewDecoder(rec.Body).Decode(&nodes); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(nodes) != 2 || !nodes[0].Alive || nodes[1].Alive || nodes[0].RunningTasks != 1 {
+		t.Errorf("unexpected nodes: %+v", nodes)
+	}
+}
@@ -10,7 +10,7 @@ import (
 
 // HandleHealth godoc
 // @Summary      Check service health
-// @Description  Returns the health status of the application and its dependencies
+// @Description  Returns the health status of the application and its dependencies, and the instance running the singleton jobs (scheduler, garbage collector, retention)
 // @Tags         system
 // @Produce      json
 // @Success      200  {object}  domain.HealthResponse
@@ -23,11 +23,15 @@ func (s *Server) HandleHealth(w http.ResponseWriter, r *http.Request) {
 		slog.Error(err.Error())
 
 		w.WriteHeader(http.StatusServiceUnavailable)
-		json.NewEncoder(w).Encode(domain.HealthResponse{Status: fmt.Sprintf("error: %v", err.Error())})
+		json.NewEncoder(w).Encode(domain.HealthResponse{
+			Status: fmt.Sprintf("error: %v", err.Error()),
+			Leader: s.healthService.LeaderInfo(r.Context()),
+		})
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(domain.HealthResponse{Status: "ok"}); err != nil {
+	resp := domain.HealthResponse{Status: "ok", Leader: s.healthService.LeaderInfo(r.Context())}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		slog.Error("failed to encode health response", "error", err)
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
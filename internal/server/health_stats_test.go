@@ -31,6 +31,23 @@ func TestHandleHealth_OK(t *testing.T) {
 	}
 }
 
+func TestHandleHealth_ShowsLeader(t *testing.T) {
+	srv := testServer(nil, nil, &mockHealthSvc{
+		leaderInfo: &domain.LeaderInfo{InstanceID: "api-1", LeaderID: "api-2"},
+	})
+
+	rec := httptest.NewRecorder()
+	srv.HandleHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	var resp domain.HealthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Leader == nil || resp.Leader.LeaderID != "api-2" || resp.Leader.IsLeader {
+		t.Errorf("unexpected leader: %+v", resp.Leader)
+	}
+}
+
 func TestHandleHealth_ServiceError_Returns503(t *testing.T) {
 	srv := testServer(nil, nil, &mockHealthSvc{
 		checkFunc: func(_ context.Context) error { return errors.New("db unreachable") },
@@ -105,3 +122,32 @@ func TestHandleStats_ContentTypeJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestHandleStats_DockerHosts(t *testing.T) {
+	srv := testServer(nil, nil, &mockHealthSvc{
+		hosts: []domain.DockerHost{
+			{Name: "gpu-1", Healthy: true, GPU: true, RunningContainers: 2},
+			{Name: "cpu-1", Error: "connection refused"},
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	srv.HandleStats(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	if rec.Code != http.StatusOK {
+		t.Skipf("host stats are unavailable: %d", rec.Code)
+	}
+
+	var resp domain.StatsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode stats response: %v", err)
+	}
+	if len(resp.DockerHosts) != 2 {
+		t.Fatalf("expected 2 docker hosts, got %+v", resp.DockerHosts)
+	}
+	if !resp.DockerHosts[0].Healthy || resp.DockerHosts[0].RunningContainers != 2 {
+		t.Errorf("unexpected first host: %+v", resp.DockerHosts[0])
+	}
+	if resp.DockerHosts[1].Healthy || resp.DockerHosts[1].Error == "" {
+		t.Errorf("expected the second host to be unhealthy: %+v", resp.DockerHosts[1])
+	}
+}
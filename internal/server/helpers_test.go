@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"pinn-connect-service/internal/config"
 	"pinn-connect-service/internal/domain"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -21,12 +22,23 @@ import (
 
 type mockTaskSvc struct {
 	saveInputFunc    func(uuid.UUID, string, io.Reader) ([]byte, error)
+	saveParamsFunc   func(uuid.UUID, []byte) ([]byte, error)
+	discardInputFunc func(uuid.UUID) error
 	getTaskFunc      func(context.Context, uuid.UUID) (*domain.Task, error)
-	getResultURLFunc func(context.Context, uuid.UUID) (string, error)
+	getResultURLFunc func(context.Context, uuid.UUID, time.Duration) (string, error)
 	createTaskFunc   func(context.Context, *domain.Task, []byte) error
 	stopTaskFunc     func(context.Context, uuid.UUID, time.Duration) error
-	listTasksFunc    func(context.Context, int, int) ([]domain.Task, int64, error)
+	listTasksFunc    func(context.Context, int, int, domain.TaskFilter) ([]domain.Task, int64, error)
 	deleteTaskFunc   func(context.Context, uuid.UUID) error
+	listFilesFunc    func(context.Context, uuid.UUID) ([]domain.ResultFile, error)
+	openFileFunc     func(context.Context, uuid.UUID, string) (io.ReadSeekCloser, *domain.ResultFile, error)
+	openArchiveFunc  func(context.Context, uuid.UUID, domain.ArchiveFormat) (func(io.Writer) error, error)
+	setPinnedFunc    func(context.Context, uuid.UUID, bool) (*domain.Task, error)
+	retryUploadFunc  func(context.Context, uuid.UUID) error
+	verifyFunc       func(context.Context, uuid.UUID) (*domain.VerifyReport, error)
+	listEventsFunc   func(context.Context, uuid.UUID) ([]domain.TaskEvent, error)
+	usageReportFunc  func(context.Context, time.Time, time.Time) ([]domain.UsageReportRow, error)
+	streamStatsFunc  func(context.Context, uuid.UUID, func(domain.TaskStats) error) error
 }
 
 func (m *mockTaskSvc) SaveInput(id uuid.UUID, filename string, r io.Reader) ([]byte, error) {
@@ -36,15 +48,27 @@ func (m *mockTaskSvc) SaveInput(id uuid.UUID, filename string, r io.Reader) ([]b
 	io.Copy(io.Discard, r)
 	return []byte("hash"), nil
 }
+func (m *mockTaskSvc) SaveParams(id uuid.UUID, params []byte) ([]byte, error) {
+	if m.saveParamsFunc != nil {
+		return m.saveParamsFunc(id, params)
+	}
+	return params, nil
+}
+func (m *mockTaskSvc) DiscardInput(id uuid.UUID) error {
+	if m.discardInputFunc != nil {
+		return m.discardInputFunc(id)
+	}
+	return nil
+}
 func (m *mockTaskSvc) GetTask(ctx context.Context, id uuid.UUID) (*domain.Task, error) {
 	if m.getTaskFunc != nil {
 		return m.getTaskFunc(ctx, id)
 	}
 	return &domain.Task{ID: id, Status: domain.TaskQueued}, nil
 }
-func (m *mockTaskSvc) GetResultURL(ctx context.Context, id uuid.UUID) (string, error) {
+func (m *mockTaskSvc) GetResultURL(ctx context.Context, id uuid.UUID, expiry time.Duration) (string, error) {
 	if m.getResultURLFunc != nil {
-		return m.getResultURLFunc(ctx, id)
+		return m.getResultURLFunc(ctx, id, expiry)
 	}
 	return "https://example.com/result", nil
 }
@@ -60,9 +84,9 @@ func (m *mockTaskSvc) StopTask(ctx context.Context, id uuid.UUID, timeout time.D
 	}
 	return nil
 }
-func (m *mockTaskSvc) ListTasks(ctx context.Context, page, pageSize int) ([]domain.Task, int64, error) {
+func (m *mockTaskSvc) ListTasks(ctx context.Context, page, pageSize int, filter domain.TaskFilter) ([]domain.Task, int64, error) {
 	if m.listTasksFunc != nil {
-		return m.listTasksFunc(ctx, page, pageSize)
+		return m.listTasksFunc(ctx, page, pageSize, filter)
 	}
 	return []domain.Task{{ID: uuid.New(), Status: domain.TaskQueued}}, 1, nil
 }
@@ -73,6 +97,74 @@ func (m *mockTaskSvc) DeleteTask(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+func (m *mockTaskSvc) ListResultFiles(ctx context.Context, id uuid.UUID) ([]domain.ResultFile, error) {
+	if m.listFilesFunc != nil {
+		return m.listFilesFunc(ctx, id)
+	}
+	return []domain.ResultFile{{Path: "result.txt", Size: 6, ContentType: "text/plain"}}, nil
+}
+func (m *mockTaskSvc) OpenResultFile(ctx context.Context, id uuid.UUID, name string) (io.ReadSeekCloser, *domain.ResultFile, error) {
+	if m.openFileFunc != nil {
+		return m.openFileFunc(ctx, id, name)
+	}
+	return nopSeekCloser{strings.NewReader("result")}, &domain.ResultFile{Path: name, Size: 6, ContentType: "text/plain"}, nil
+}
+func (m *mockTaskSvc) OpenResultArchive(ctx context.Context, id uuid.UUID, f domain.ArchiveFormat) (func(io.Writer) error, error) {
+	if m.openArchiveFunc != nil {
+		return m.openArchiveFunc(ctx, id, f)
+	}
+	return func(w io.Writer) error {
+		_, err := w.Write([]byte("archive"))
+		return err
+	}, nil
+}
+
+func (m *mockTaskSvc) SetPinned(ctx context.Context, id uuid.UUID, pinned bool) (*domain.Task, error) {
+	if m.setPinnedFunc != nil {
+		return m.setPinnedFunc(ctx, id, pinned)
+	}
+	return &domain.Task{ID: id, Status: domain.TaskCompleted, Pinned: pinned}, nil
+}
+
+func (m *mockTaskSvc) RetryUpload(ctx context.Context, id uuid.UUID) error {
+	if m.retryUploadFunc != nil {
+		return m.retryUploadFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *mockTaskSvc) VerifyResult(ctx context.Context, id uuid.UUID) (*domain.VerifyReport, error) {
+	if m.verifyFunc != nil {
+		return m.verifyFunc(ctx, id)
+	}
+	return &domain.VerifyReport{TaskID: id, OK: true, Files: []domain.FileVerification{
+		{Path: "result.txt", Size: 1, Expected: "abc", Actual: "abc", Status: domain.ChecksumOK},
+	}}, nil
+}
+
+func (m *mockTaskSvc) ListTaskEvents(ctx context.Context, id uuid.UUID) ([]domain.TaskEvent, error) {
+	if m.listEventsFunc != nil {
+		return m.listEventsFunc(ctx, id)
+	}
+	return []domain.TaskEvent{{TaskID: id, To: domain.TaskQueued, Actor: domain.ActorAPI}}, nil
+}
+func (m *mockTaskSvc) UsageReport(ctx context.Context, from, to time.Time) ([]domain.UsageReportRow, error) {
+	if m.usageReportFunc != nil {
+		return m.usageReportFunc(ctx, from, to)
+	}
+	return []domain.UsageReportRow{}, nil
+}
+func (m *mockTaskSvc) StreamTaskStats(ctx context.Context, id uuid.UUID, send func(domain.TaskStats) error) error {
+	if m.streamStatsFunc != nil {
+		return m.streamStatsFunc(ctx, id, send)
+	}
+	return nil
+}
+
+type nopSeekCloser struct{ io.ReadSeeker }
+
+func (nopSeekCloser) Close() error { return nil }
+
 // ─────────────────────────────────────────────
 // MOCK: ModelService
 // ─────────────────────────────────────────────
@@ -87,6 +179,22 @@ type mockModelSvc struct {
 	buildModelFunc         func(context.Context, string, io.Reader, io.Writer) error
 	rebuildModelFunc       func(context.Context, string, io.Reader, io.Writer) error
 	deleteImageByModelFunc func(context.Context, string) error
+	setNetworkModeFunc     func(context.Context, string, domain.NetworkMode) error
+	setSecurityFunc        func(context.Context, string, *domain.ModelSecurity) error
+}
+
+func (m *mockModelSvc) SetSecurity(ctx context.Context, id string, security *domain.ModelSecurity) error {
+	if m.setSecurityFunc != nil {
+		return m.setSecurityFunc(ctx, id, security)
+	}
+	return nil
+}
+
+func (m *mockModelSvc) SetNetworkMode(ctx context.Context, id string, mode domain.NetworkMode) error {
+	if m.setNetworkModeFunc != nil {
+		return m.setNetworkModeFunc(ctx, id, mode)
+	}
+	return nil
 }
 
 func (m *mockModelSvc) GetImageByID(ctx context.Context, id string) (string, error) {
@@ -149,7 +257,9 @@ func (m *mockModelSvc) DeleteImageByModelId(ctx context.Context, id string) erro
 // ─────────────────────────────────────────────
 
 type mockHealthSvc struct {
-	checkFunc func(context.Context) error
+	checkFunc  func(context.Context) error
+	leaderInfo *domain.LeaderInfo
+	hosts      []domain.DockerHost
 }
 
 func (m *mockHealthSvc) CheckStatus(ctx context.Context) error {
@@ -159,6 +269,114 @@ func (m *mockHealthSvc) CheckStatus(ctx context.Context) error {
 	return nil
 }
 
+func (m *mockHealthSvc) LeaderInfo(context.Context) *domain.LeaderInfo {
+	return m.leaderInfo
+}
+
+func (m *mockHealthSvc) DockerHosts() []domain.DockerHost {
+	return m.hosts
+}
+
+// ─────────────────────────────────────────────
+// MOCK: AdminService
+// ─────────────────────────────────────────────
+
+type mockAdminSvc struct {
+	runRetentionFunc  func(context.Context) (*domain.RetentionReport, error)
+	lastRetentionFunc func() *domain.RetentionReport
+	runGCFunc         func(context.Context) (*domain.GCReport, error)
+	lastGCFunc        func() *domain.GCReport
+	runReconcileFunc  func(context.Context, bool) (*domain.ReconcileReport, error)
+	schedulerFunc     func() *domain.SchedulerReport
+	nodesFunc         func(context.Context) ([]domain.Node, error)
+}
+
+func (m *mockAdminSvc) RunRetention(ctx context.Context) (*domain.RetentionReport, error) {
+	if m.runRetentionFunc != nil {
+		return m.runRetentionFunc(ctx)
+	}
+	return &domain.RetentionReport{}, nil
+}
+func (m *mockAdminSvc) LastRetentionReport() *domain.RetentionReport {
+	if m.lastRetentionFunc != nil {
+		return m.lastRetentionFunc()
+	}
+	return nil
+}
+
+func (m *mockAdminSvc) RunGC(ctx context.Context) (*domain.GCReport, error) {
+	if m.runGCFunc != nil {
+		return m.runGCFunc(ctx)
+	}
+	return &domain.GCReport{}, nil
+}
+func (m *mockAdminSvc) LastGCReport() *domain.GCReport {
+	if m.lastGCFunc != nil {
+		return m.lastGCFunc()
+	}
+	return nil
+}
+
+func (m *mockAdminSvc) RunReconcile(ctx context.Context, dryRun bool) (*domain.ReconcileReport, error) {
+	if m.runReconcileFunc != nil {
+		return m.runReconcileFunc(ctx, dryRun)
+	}
+	return &domain.ReconcileReport{DryRun: dryRun}, nil
+}
+
+func (m *mockAdminSvc) SchedulerReport() *domain.SchedulerReport {
+	if m.schedulerFunc != nil {
+		return m.schedulerFunc()
+	}
+	return nil
+}
+
+func (m *mockAdminSvc) Nodes(ctx context.Context) ([]domain.Node, error) {
+	if m.nodesFunc != nil {
+		return m.nodesFunc(ctx)
+	}
+	return []domain.Node{}, nil
+}
+
+// ─────────────────────────────────────────────
+// MOCK: SecretService
+// ─────────────────────────────────────────────
+
+type mockSecretSvc struct {
+	listFunc   func(context.Context) ([]domain.Secret, error)
+	createFunc func(ctx context.Context, name, value string) error
+	updateFunc func(ctx context.Context, name, value string) error
+	deleteFunc func(ctx context.Context, name string) error
+}
+
+func (m *mockSecretSvc) ListSecrets(ctx context.Context) ([]domain.Secret, error) {
+	if m.listFunc != nil {
+		return m.listFunc(ctx)
+	}
+	return []domain.Secret{}, nil
+}
+
+func (m *mockSecretSvc) CreateSecret(ctx context.Context, name, value string) error {
+	if m.createFunc != nil {
+		return m.createFunc(ctx, name, value)
+	}
+	return nil
+}
+
+func (m *mockSecretSvc) UpdateSecret(ctx context.Context, name, value string) error {
+	if m.updateFunc != nil {
+		return m.updateFunc(ctx, name, value)
+	}
+	return nil
+}
+
+func (m *mockSecretSvc) DeleteSecret(ctx context.Context, name string) error {
+	if m.deleteFunc != nil {
+		return m.deleteFunc(ctx, name)
+	}
+	return nil
+}
+
 // ─────────────────────────────────────────────
 // SERVER FACTORY
 // ─────────────────────────────────────────────
@@ -167,11 +385,18 @@ func testServer(ts TaskService, ms ModelService, hs HealthService) *Server {
 	cfg := &config.Config{
 		MaxCPUByTask:          4,
 		MaxMemByTask:          2048,
+		Disk:                  config.DiskConfig{MaxByTaskMB: 1024},
 		DefaultTaskTimeoutSec: 60,
 		Server: config.ServerConfig{
 			DefaultTaskStopTimeout: 30 * time.Second,
 			APIToken:               "",
 		},
+		GC:        config.GCConfig{Timeout: time.Minute},
+		Reconcile: config.ReconcileConfig{Timeout: time.Minute},
+		Docker: config.DockerConfig{
+			NetworkMode:  "none",
+			NetworkModes: []string{"none", "internal"},
+		},
 	}
 	if ts == nil {
 		ts = &mockTaskSvc{}
@@ -187,6 +412,8 @@ func testServer(ts TaskService, ms ModelService, hs HealthService) *Server {
 		taskService:   ts,
 		modelService:  ms,
 		healthService: hs,
+		adminService:  &mockAdminSvc{},
+		secretService: &mockSecretSvc{},
 		config:        cfg,
 	}
 }
@@ -202,6 +429,15 @@ func withChiParam(r *http.Request, key, value string) *http.Request {
 	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
 }
 
+// withChiParams injects several chi URL params into the request context.
+func withChiParams(r *http.Request, kv ...string) *http.Request {
+	rctx := chi.NewRouteContext()
+	for i := 0; i+1 < len(kv); i += 2 {
+		rctx.URLParams.Add(kv[i], kv[i+1])
+	}
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
 // buildMultipartTask creates a multipart body with a "task" JSON part and a "file" part.
 func buildMultipartTask(taskJSON, fileContent string) (body *bytes.Buffer, contentType string) {
 	body = &bytes.Buffer{}
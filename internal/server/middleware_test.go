@@ -98,7 +98,7 @@ func TestAuthMiddleware_EmptyTokenHeader_Returns401(t *testing.T) {
 // ─────────────────────────────────────────────
 
 func TestNew_RoutesRegistered(t *testing.T) {
-	srv := New(&mockTaskSvc{}, &mockModelSvc{}, &mockHealthSvc{}, testServer(nil, nil, nil).config)
+	srv := New(&mockTaskSvc{}, &mockModelSvc{}, &mockHealthSvc{}, &mockAdminSvc{}, &mockSecretSvc{}, nil, testServer(nil, nil, nil).config)
 
 	// Health is public — no token required.
 	rec := httptest.NewRecorder()
@@ -114,7 +114,7 @@ func TestNew_ProtectedRouteRequiresToken(t *testing.T) {
 	cfg := testServer(nil, nil, nil).config
 	cfg.Server.APIToken = "tok"
 
-	srv := New(&mockTaskSvc{}, &mockModelSvc{}, &mockHealthSvc{}, cfg)
+	srv := New(&mockTaskSvc{}, &mockModelSvc{}, &mockHealthSvc{}, &mockAdminSvc{}, &mockSecretSvc{}, nil, cfg)
 
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/task/list", nil)
@@ -130,7 +130,7 @@ func TestNew_ProtectedRouteWithToken(t *testing.T) {
 	cfg := testServer(nil, nil, nil).config
 	cfg.Server.APIToken = "tok"
 
-	srv := New(&mockTaskSvc{}, &mockModelSvc{}, &mockHealthSvc{}, cfg)
+	srv := New(&mockTaskSvc{}, &mockModelSvc{}, &mockHealthSvc{}, &mockAdminSvc{}, &mockSecretSvc{}, nil, cfg)
 
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/task/list", nil)
@@ -115,6 +115,88 @@ func (s *Server) HandleModelUpdate(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// HandleModelNetwork godoc
+// @Summary      Set model network mode
+// @Description  Sets the network mode of the tasks of a model created from now on, an empty mode falls back to the default of the service
+// @Tags         models
+// @Accept       json
+// @Param        id      path  string                         true  "Model ID"
+// @Param        request body  domain.SetModelNetworkRequest  true  "Set Model Network Request"
+// @Success      204  "No Content"
+// @Failure      400  {string}  string "Invalid JSON or network mode not allowed"
+// @Failure      404  {string}  string "Model not found"
+// @Router       /model/{id}/network [put]
+func (s *Server) HandleModelNetwork(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req domain.SetModelNetworkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	var mode domain.NetworkMode
+	if req.NetworkMode != "" {
+		var err error
+		if mode, err = domain.ParseNetworkMode(req.NetworkMode); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !s.config.Docker.AllowsNetworkMode(req.NetworkMode) {
+			http.Error(w, fmt.Sprintf("%s: %s", domain.ErrNetworkModeNotAllowed, mode), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := s.modelService.SetNetworkMode(r.Context(), id, mode); err != nil {
+		if errors.Is(err, domain.ErrModelNotFound) {
+			http.Error(w, "model not found", http.StatusNotFound)
+			return
+		}
+		slog.Error("error setting model network mode", "model_id", id, "error", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleModelSecurity godoc
+// @Summary      Set model security overrides
+// @Description  Overrides the security profile of the service for the tasks of a model started from now on, an empty object falls back to the profile of the service
+// @Tags         models
+// @Accept       json
+// @Param        id      path  string                true  "Model ID"
+// @Param        request body  domain.ModelSecurity  true  "Security overrides"
+// @Success      204  "No Content"
+// @Failure      400  {string}  string "Invalid JSON or overrides"
+// @Failure      404  {string}  string "Model not found"
+// @Router       /model/{id}/security [put]
+func (s *Server) HandleModelSecurity(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req domain.ModelSecurity
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.modelService.SetSecurity(r.Context(), id, &req); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidSecurity):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, domain.ErrModelNotFound):
+			http.Error(w, "model not found", http.StatusNotFound)
+		default:
+			slog.Error("error setting model security", "model_id", id, "error", err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // HandleModelList godoc
 // @Summary      List all models
 // @Description  Returns a list of all registered models
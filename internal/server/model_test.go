@@ -148,6 +148,136 @@ func TestHandleModelDelete_ServiceError(t *testing.T) {
 	}
 }
 
+// ─────────────────────────────────────────────
+// HandleModelNetwork
+// ─────────────────────────────────────────────
+
+func TestHandleModelNetwork_Success(t *testing.T) {
+	var got domain.NetworkMode
+	ms := &mockModelSvc{
+		setNetworkModeFunc: func(_ context.Context, _ string, mode domain.NetworkMode) error {
+			got = mode
+			return nil
+		},
+	}
+	srv := testServer(nil, ms, nil)
+
+	req := httptest.NewRequest(http.MethodPut, "/model/m1/network", strings.NewReader(`{"network_mode":"internal"}`))
+	req = withChiParam(req, "id", "m1")
+	rec := httptest.NewRecorder()
+
+	srv.HandleModelNetwork(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got != domain.NetworkInternal {
+		t.Errorf("expected network mode internal, got %q", got)
+	}
+}
+
+func TestHandleModelNetwork_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid json", "{bad json"},
+		{"unknown mode", `{"network_mode":"host"}`},
+		{"mode not allowed", `{"network_mode":"bridge"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := testServer(nil, nil, nil)
+
+			req := httptest.NewRequest(http.MethodPut, "/model/m1/network", strings.NewReader(tt.body))
+			req = withChiParam(req, "id", "m1")
+			rec := httptest.NewRecorder()
+
+			srv.HandleModelNetwork(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestHandleModelNetwork_NotFound(t *testing.T) {
+	ms := &mockModelSvc{
+		setNetworkModeFunc: func(context.Context, string, domain.NetworkMode) error {
+			return domain.ErrModelNotFound
+		},
+	}
+	srv := testServer(nil, ms, nil)
+
+	req := httptest.NewRequest(http.MethodPut, "/model/m1/network", strings.NewReader(`{"network_mode":""}`))
+	req = withChiParam(req, "id", "m1")
+	rec := httptest.NewRecorder()
+
+	srv.HandleModelNetwork(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+// ─────────────────────────────────────────────
+// HandleModelSecurity
+// ─────────────────────────────────────────────
+
+func TestHandleModelSecurity_Success(t *testing.T) {
+	var got *domain.ModelSecurity
+	ms := &mockModelSvc{
+		setSecurityFunc: func(_ context.Context, _ string, sec *domain.ModelSecurity) error {
+			got = sec
+			return nil
+		},
+	}
+	srv := testServer(nil, ms, nil)
+
+	req := httptest.NewRequest(http.MethodPut, "/model/m1/security", strings.NewReader(`{"read_only_rootfs":false,"cap_add":["NET_ADMIN"]}`))
+	req = withChiParam(req, "id", "m1")
+	rec := httptest.NewRecorder()
+
+	srv.HandleModelSecurity(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got == nil || got.ReadOnlyRootfs == nil || *got.ReadOnlyRootfs || len(got.CapAdd) != 1 {
+		t.Errorf("expected the overrides to be passed, got %+v", got)
+	}
+}
+
+func TestHandleModelSecurity_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+		want int
+	}{
+		{"invalid json", "{bad json", nil, http.StatusBadRequest},
+		{"invalid overrides", `{"cap_add":["ALL"]}`, domain.ErrInvalidSecurity, http.StatusBadRequest},
+		{"model not found", `{}`, domain.ErrModelNotFound, http.StatusNotFound},
+		{"service error", `{}`, errors.New("db error"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := &mockModelSvc{
+				setSecurityFunc: func(context.Context, string, *domain.ModelSecurity) error {
+					return tt.err
+				},
+			}
+			srv := testServer(nil, ms, nil)
+
+			req := httptest.NewRequest(http.MethodPut, "/model/m1/security", strings.NewReader(tt.body))
+			req = withChiParam(req, "id", "m1")
+			rec := httptest.NewRecorder()
+
+			srv.HandleModelSecurity(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
 // ─────────────────────────────────────────────
 // HandleModelUpdate
 // ─────────────────────────────────────────────
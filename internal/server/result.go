@@ -0,0 +1,232 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"pinn-connect-service/internal/domain"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// HandleTaskFiles godoc
+// @Summary      List task result files
+// @Description  Returns all result objects of a completed task
+// @Tags         tasks
+// @Produce      json
+// @Param        id   path      string  true  "Task UUID"
+// @Success      200  {object}  domain.TaskFilesResponse
+// @Failure      400  {string}  string "Invalid ID"
+// @Failure      404  {string}  string "Task not found or result not ready"
+// @Router       /task/{id}/files [get]
+func (s *Server) HandleTaskFiles(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "missing id", http.StatusBadRequest)
+		return
+	}
+
+	uuID, err := uuid.Parse(id)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	files, err := s.taskService.ListResultFiles(r.Context(), uuID)
+	if err != nil {
+		writeResultError(w, err)
+		return
+	}
+
+	resp := domain.TaskFilesResponse{Files: make([]domain.TaskFileResponse, 0, len(files))}
+	for _, f := range files {
+		resp.Files = append(resp.Files, domain.TaskFileResponse{
+			Path:         f.Path,
+			Size:         f.Size,
+			ContentType:  f.ContentType,
+			LastModified: f.LastModified,
+			SHA256:       f.SHA256,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("encoding task files", "error", err)
+	}
+}
+
+// HandleTaskFile godoc
+// @Summary      Download a single task result file
+// @Description  Streams one result object through the service. Supports Range requests.
+// @Tags         tasks
+// @Produce      octet-stream
+// @Param        id    path      string  true  "Task UUID"
+// @Param        path  path      string  true  "File path relative to the result set"
+// @Success      200  {file}    file
+// @Success      206  {file}    file
+// @Failure      400  {string}  string "Invalid ID"
+// @Failure      404  {string}  string "Task, result or file not found"
+// @Router       /task/{id}/files/{path} [get]
+func (s *Server) HandleTaskFile(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "missing id", http.StatusBadRequest)
+		return
+	}
+
+	uuID, err := uuid.Parse(id)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	rc, file, err := s.taskService.OpenResultFile(r.Context(), uuID, chi.URLParam(r, "*"))
+	if err != nil {
+		writeResultError(w, err)
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(file.Path)))
+
+	http.ServeContent(w, r, file.Path, file.LastModified, rc)
+}
+
+// HandleTaskArchive godoc
+// @Summary      Download all task result files as an archive
+// @Description  Streams the whole result set as a zip or tar.gz archive generated on the fly
+// @Tags         tasks
+// @Produce      octet-stream
+// @Param        id      path      string  true   "Task UUID"
+// @Param        format  query     string  false  "Archive format: zip (default) or tar.gz"
+// @Success      200  {file}    file
+// @Failure      400  {string}  string "Invalid ID or format"
+// @Failure      404  {string}  string "Task not found or result not ready"
+// @Router       /task/{id}/archive [get]
+func (s *Server) HandleTaskArchive(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "missing id", http.StatusBadRequest)
+		return
+	}
+
+	uuID, err := uuid.Parse(id)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	format := domain.ArchiveFormat(r.URL.Query().Get("format"))
+	var contentType string
+
+	switch format {
+	case "", domain.ArchiveZip:
+		format = domain.ArchiveZip
+		contentType = "application/zip"
+	case domain.ArchiveTarGz, "tgz":
+		format = domain.ArchiveTarGz
+		contentType = "application/gzip"
+	default:
+		http.Error(w, "invalid format, expected zip or tar.gz", http.StatusBadRequest)
+		return
+	}
+
+	// the result is checked before sending headers, archive errors after that can only be logged
+	write, err := s.taskService.OpenResultArchive(r.Context(), uuID, format)
+	if err != nil {
+		writeResultError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", uuID.String()+"."+string(format)))
+
+	if err := write(w); err != nil {
+		slog.Error("streaming result archive", "task_id", uuID, "error", err)
+	}
+}
+
+// HandleStorageFile godoc
+// @Summary      Download a stored object through a signed URL
+// @Description  Serves objects of the local storage backend and client-side encrypted objects of the s3 backend, decrypting them on the fly. URLs are produced by /task/{id}/result and expire. Supports Range requests.
+// @Tags         tasks
+// @Produce      octet-stream
+// @Param        key        path      string  true  "Object key"
+// @Param        expires    query     int     true  "Expiry as unix time"
+// @Param        signature  query     string  true  "URL signature"
+// @Success      200  {file}    file
+// @Success      206  {file}    file
+// @Failure      403  {string}  string "Invalid or expired signature"
+// @Failure      404  {string}  string "File not found"
+// @Router       /storage/{key} [get]
+func (s *Server) HandleStorageFile(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "*")
+
+	if err := s.fileStore.VerifySignedURL(key, r.URL.Query()); err != nil {
+		http.Error(w, "invalid or expired signature", http.StatusForbidden)
+		return
+	}
+
+	rc, artifact, err := s.fileStore.OpenArtifact(r.Context(), key)
+	if err != nil {
+		writeResultError(w, err)
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", artifact.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(key)))
+
+	http.ServeContent(w, r, path.Base(key), artifact.LastModified, rc)
+}
+
+// HandleTaskVerify godoc
+// @Summary      Verify task result integrity
+// @Description  Re-reads every stored result object and compares it with the sha256 recorded on upload. A corrupted result is flagged and no longer reused by the cache.
+// @Tags         tasks
+// @Produce      json
+// @Param        id   path      string  true  "Task UUID"
+// @Success      200  {object}  domain.VerifyReport
+// @Failure      400  {string}  string "Invalid ID"
+// @Failure      404  {string}  string "Task not found or result not ready"
+// @Router       /task/{id}/verify [post]
+func (s *Server) HandleTaskVerify(w http.ResponseWriter, r *http.Request) {
+	uuID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	report, err := s.taskService.VerifyResult(r.Context(), uuID)
+	if err != nil {
+		writeResultError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.Error("encoding verify report", "error", err)
+	}
+}
+
+func writeResultError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrTaskNotFound):
+		http.Error(w, "task not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrResultNotReady):
+		http.Error(w, "result not found or task not completed", http.StatusNotFound)
+	case errors.Is(err, domain.ErrArtifactNotFound):
+		http.Error(w, "file not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrArtifactCorrupted):
+		slog.Error("stored result is corrupted", "error", err)
+		http.Error(w, "stored file is corrupted", http.StatusInternalServerError)
+	default:
+		slog.Error("getting task result", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
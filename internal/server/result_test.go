@@ -0,0 +1,365 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"pinn-connect-service/internal/domain"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// ─────────────────────────────────────────────
+// HandleTaskFiles
+// ─────────────────────────────────────────────
+
+func TestHandleTaskFiles_Success(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+	id := uuid.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/task/"+id.String()+"/files", nil)
+	req = withChiParam(req, "id", id.String())
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskFiles(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var resp domain.TaskFilesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(resp.Files) != 1 || resp.Files[0].Path != "result.txt" {
+		t.Errorf("unexpected files: %+v", resp.Files)
+	}
+}
+
+func TestHandleTaskFiles_InvalidUUID(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/task/bad/files", nil)
+	req = withChiParam(req, "id", "bad")
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskFiles(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestHandleTaskFiles_ErrorMapping(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"not found", domain.ErrTaskNotFound, http.StatusNotFound},
+		{"not ready", domain.ErrResultNotReady, http.StatusNotFound},
+		{"internal", errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := &mockTaskSvc{
+				listFilesFunc: func(context.Context, uuid.UUID) ([]domain.ResultFile, error) { return nil, tc.err },
+			}
+			srv := testServer(ts, nil, nil)
+			id := uuid.New()
+
+			req := httptest.NewRequest(http.MethodGet, "/task/"+id.String()+"/files", nil)
+			req = withChiParam(req, "id", id.String())
+			rec := httptest.NewRecorder()
+
+			srv.HandleTaskFiles(rec, req)
+			if rec.Code != tc.code {
+				t.Errorf("expected %d, got %d", tc.code, rec.Code)
+			}
+		})
+	}
+}
+
+// ─────────────────────────────────────────────
+// HandleTaskFile
+// ─────────────────────────────────────────────
+
+func TestHandleTaskFile_Success(t *testing.T) {
+	var gotName string
+	ts := &mockTaskSvc{
+		openFileFunc: func(_ context.Context, _ uuid.UUID, name string) (io.ReadSeekCloser, *domain.ResultFile, error) {
+			gotName = name
+			return nopSeekCloser{strings.NewReader("result")}, &domain.ResultFile{Path: name, ContentType: "text/plain"}, nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
+	id := uuid.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/task/"+id.String()+"/files/sub/out.txt", nil)
+	req = withChiParams(req, "id", id.String(), "*", "sub/out.txt")
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskFile(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if gotName != "sub/out.txt" {
+		t.Errorf("expected file path 'sub/out.txt', got %q", gotName)
+	}
+	if rec.Body.String() != "result" {
+		t.Errorf("unexpected body %q", rec.Body.String())
+	}
+	if !strings.Contains(rec.Header().Get("Content-Disposition"), "out.txt") {
+		t.Errorf("expected Content-Disposition with file name, got %q", rec.Header().Get("Content-Disposition"))
+	}
+}
+
+// Range requests must be answered with 206 and only the requested bytes.
+func TestHandleTaskFile_Range(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+	id := uuid.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/task/"+id.String()+"/files/result.txt", nil)
+	req.Header.Set("Range", "bytes=1-3")
+	req = withChiParams(req, "id", id.String(), "*", "result.txt")
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskFile(rec, req)
+
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("expected 206, got %d", rec.Code)
+	}
+	if rec.Body.String() != "esu" {
+		t.Errorf("expected 'esu', got %q", rec.Body.String())
+	}
+}
+
+func TestHandleTaskFile_NotFound(t *testing.T) {
+	ts := &mockTaskSvc{
+		openFileFunc: func(context.Context, uuid.UUID, string) (io.ReadSeekCloser, *domain.ResultFile, error) {
+			return nil, nil, domain.ErrArtifactNotFound
+		},
+	}
+	srv := testServer(ts, nil, nil)
+	id := uuid.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/task/"+id.String()+"/files/missing", nil)
+	req = withChiParams(req, "id", id.String(), "*", "missing")
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskFile(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestHandleTaskFile_Corrupted(t *testing.T) {
+	ts := &mockTaskSvc{
+		openFileFunc: func(context.Context, uuid.UUID, string) (io.ReadSeekCloser, *domain.ResultFile, error) {
+			return nil, nil, domain.ErrArtifactCorrupted
+		},
+	}
+	srv := testServer(ts, nil, nil)
+	id := uuid.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/task/"+id.String()+"/files/result.txt", nil)
+	req = withChiParams(req, "id", id.String(), "*", "result.txt")
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskFile(rec, req)
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "corrupted") {
+		t.Errorf("expected 500 reporting corruption, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+// ─────────────────────────────────────────────
+// HandleTaskArchive
+// ─────────────────────────────────────────────
+
+func TestHandleTaskArchive_DefaultZip(t *testing.T) {
+	var gotFormat domain.ArchiveFormat
+	ts := &mockTaskSvc{
+		openArchiveFunc: func(_ context.Context, _ uuid.UUID, f domain.ArchiveFormat) (func(io.Writer) error, error) {
+			gotFormat = f
+			return func(io.Writer) error { return nil }, nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
+	id := uuid.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/task/"+id.String()+"/archive", nil)
+	req = withChiParam(req, "id", id.String())
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskArchive(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if gotFormat != domain.ArchiveZip {
+		t.Errorf("expected zip format, got %q", gotFormat)
+	}
+	if rec.Header().Get("Content-Type") != "application/zip" {
+		t.Errorf("unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestHandleTaskArchive_TarGz(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+	id := uuid.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/task/"+id.String()+"/archive?format=tar.gz", nil)
+	req = withChiParam(req, "id", id.String())
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskArchive(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec.Header().Get("Content-Type") != "application/gzip" {
+		t.Errorf("unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestHandleTaskArchive_InvalidFormat(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+	id := uuid.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/task/"+id.String()+"/archive?format=rar", nil)
+	req = withChiParam(req, "id", id.String())
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskArchive(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
+// When the result is not ready the handler must answer 404 before any
+// archive bytes are written.
+func TestHandleTaskArchive_NotReady(t *testing.T) {
+	ts := &mockTaskSvc{
+		openArchiveFunc: func(context.Context, uuid.UUID, domain.ArchiveFormat) (func(io.Writer) error, error) {
+			return nil, domain.ErrResultNotReady
+		},
+	}
+	srv := testServer(ts, nil, nil)
+	id := uuid.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/task/"+id.String()+"/archive", nil)
+	req = withChiParam(req, "id", id.String())
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskArchive(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+// ─────────────────────────────────────────────
+// HandleStorageFile
+// ─────────────────────────────────────────────
+
+type mockFileStore struct {
+	verifyErr error
+}
+
+func (m *mockFileStore) VerifySignedURL(string, url.Values) error { return m.verifyErr }
+func (m *mockFileStore) OpenArtifact(_ context.Context, key string) (io.ReadSeekCloser, *domain.Artifact, error) {
+	return nopSeekCloser{strings.NewReader("result")}, &domain.Artifact{Key: key, Size: 6, ContentType: "text/plain"}, nil
+}
+
+func TestHandleStorageFile_Success(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+	srv.fileStore = &mockFileStore{}
+
+	req := httptest.NewRequest(http.MethodGet, "/storage/tasks/abc/result.txt?expires=1&signature=aa", nil)
+	req = withChiParam(req, "*", "tasks/abc/result.txt")
+	rec := httptest.NewRecorder()
+
+	srv.HandleStorageFile(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "result" {
+		t.Fatalf("expected 200 with the object, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleStorageFile_InvalidSignature(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+	srv.fileStore = &mockFileStore{verifyErr: domain.ErrInvalidSignature}
+
+	req := httptest.NewRequest(http.MethodGet, "/storage/tasks/abc/result.txt", nil)
+	req = withChiParam(req, "*", "tasks/abc/result.txt")
+	rec := httptest.NewRecorder()
+
+	srv.HandleStorageFile(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", rec.Code)
+	}
+}
+
+// ─────────────────────────────────────────────
+// HandleTaskVerify
+// ─────────────────────────────────────────────
+
+func TestHandleTaskVerify_Success(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+	id := uuid.New()
+
+	req := httptest.NewRequest(http.MethodPost, "/task/"+id.String()+"/verify", nil)
+	req = withChiParam(req, "id", id.String())
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskVerify(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var report domain.VerifyReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !report.OK || report.TaskID != id || len(report.Files) != 1 || report.Files[0].Status != domain.ChecksumOK {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestHandleTaskVerify_ErrorMapping(t *testing.T) {
+	cases := []struct {
+		name string
+		id   string
+		err  error
+		code int
+	}{
+		{"invalid id", "bad", nil, http.StatusBadRequest},
+		{"not found", uuid.NewString(), domain.ErrTaskNotFound, http.StatusNotFound},
+		{"not ready", uuid.NewString(), domain.ErrResultNotReady, http.StatusNotFound},
+		{"internal", uuid.NewString(), errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := &mockTaskSvc{
+				verifyFunc: func(context.Context, uuid.UUID) (*domain.VerifyReport, error) { return nil, tc.err },
+			}
+			srv := testServer(ts, nil, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/task/"+tc.id+"/verify", nil)
+			req = withChiParam(req, "id", tc.id)
+			rec := httptest.NewRecorder()
+
+			srv.HandleTaskVerify(rec, req)
+			if rec.Code != tc.code {
+				t.Errorf("expected %d, got %d", tc.code, rec.Code)
+			}
+		})
+	}
+}
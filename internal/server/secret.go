@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"pinn-connect-service/internal/domain"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// HandleSecretList godoc
+// @Summary      List secrets
+// @Description  Returns the names of the stored secrets, their values are never returned
+// @Tags         secrets
+// @Produce      json
+// @Success      200  {array}   domain.Secret
+// @Failure      503  {string}  string "Secrets are disabled"
+// @Router       /secret [get]
+func (s *Server) HandleSecretList(w http.ResponseWriter, r *http.Request) {
+	secrets, err := s.secretService.ListSecrets(r.Context())
+	if err != nil {
+		writeSecretError(w, err)
+		return
+	}
+
+	writeJSON(w, secrets)
+}
+
+// HandleSecretAdd godoc
+// @Summary      Create a secret
+// @Description  Stores the value encrypted. Tasks reference the secret by name in their secrets field
+// @Tags         secrets
+// @Accept       json
+// @Param        request body domain.CreateSecretRequest true "Create Secret Request"
+// @Success      201  "Created"
+// @Failure      400  {string}  string "Invalid JSON or name"
+// @Failure      409  {string}  string "Secret already exists"
+// @Failure      503  {string}  string "Secrets are disabled"
+// @Router       /secret [post]
+func (s *Server) HandleSecretAdd(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateSecretRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.secretService.CreateSecret(r.Context(), req.Name, req.Value); err != nil {
+		writeSecretError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// HandleSecretUpdate godoc
+// @Summary      Update a secret
+// @Description  Replaces the value of a secret. Running containers keep the old value
+// @Tags         secrets
+// @Accept       json
+// @Param        name    path  string                      true  "Secret name"
+// @Param        request body  domain.UpdateSecretRequest  true  "Update Secret Request"
+// @Success      204  "No Content"
+// @Failure      400  {string}  string "Invalid JSON or name"
+// @Failure      404  {string}  string "Secret not found"
+// @Failure      503  {string}  string "Secrets are disabled"
+// @Router       /secret/{name} [put]
+func (s *Server) HandleSecretUpdate(w http.ResponseWriter, r *http.Request) {
+	var req domain.UpdateSecretRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.secretService.UpdateSecret(r.Context(), chi.URLParam(r, "name"), req.Value); err != nil {
+		writeSecretError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleSecretDelete godoc
+// @Summary      Delete a secret
+// @Description  Removes a secret. Queued tasks referencing it fail when they start
+// @Tags         secrets
+// @Param        name  path  string  true  "Secret name"
+// @Success      204  "No Content"
+// @Failure      404  {string}  string "Secret not found"
+// @Failure      503  {string}  string "Secrets are disabled"
+// @Router       /secret/{name} [delete]
+func (s *Server) HandleSecretDelete(w http.ResponseWriter, r *http.Request) {
+	if err := s.secretService.DeleteSecret(r.Context(), chi.URLParam(r, "name")); err != nil {
+		writeSecretError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeSecretError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrSecretsDisabled):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, domain.ErrInvalidSecretName):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, domain.ErrSecretExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, domain.ErrSecretNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		slog.Error("managing secrets", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"pinn-connect-service/internal/domain"
+	"strings"
+	"testing"
+)
+
+func TestHandleSecretList(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+	srv.secretService = &mockSecretSvc{
+		listFunc: func(context.Context) ([]domain.Secret, error) {
+			return []domain.Secret{{Name: "api-key"}}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	srv.HandleSecretList(rec, httptest.NewRequest(http.MethodGet, "/secret", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var secrets []map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&secrets); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(secrets) != 1 || secrets[0]["name"] != "api-key" {
+		t.Errorf("unexpected secrets %v", secrets)
+	}
+	if _, ok := secrets[0]["value"]; ok {
+		t.Error("the value of a secret must not be returned")
+	}
+}
+
+func TestHandleSecretAdd(t *testing.T) {
+	var gotName, gotValue string
+	srv := testServer(nil, nil, nil)
+	srv.secretService = &mockSecretSvc{
+		createFunc: func(_ context.Context, name, value string) error {
+			gotName, gotValue = name, value
+			return nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	body := strings.NewReader(`{"name":"api-key","value":"s3cr3t"}`)
+	srv.HandleSecretAdd(rec, httptest.NewRequest(http.MethodPost, "/secret", body))
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rec.Code)
+	}
+	if gotName != "api-key" || gotValue != "s3cr3t" {
+		t.Errorf("unexpected secret %q=%q", gotName, gotValue)
+	}
+}
+
+func TestHandleSecret_Errors(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{domain.ErrSecretsDisabled, http.StatusServiceUnavailable},
+		{fmt.Errorf("%w: %q", domain.ErrInvalidSecretName, "a b"), http.StatusBadRequest},
+		{domain.ErrSecretExists, http.StatusConflict},
+		{domain.ErrSecretNotFound, http.StatusNotFound},
+		{errors.New("db error"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			fail := func(context.Context, string, string) error { return tt.err }
+			srv := testServer(nil, nil, nil)
+			srv.secretService = &mockSecretSvc{createFunc: fail, updateFunc: fail}
+
+			rec := httptest.NewRecorder()
+			srv.HandleSecretAdd(rec, httptest.NewRequest(http.MethodPost, "/secret", strings.NewReader(`{"name":"a"}`)))
+			if rec.Code != tt.want {
+				t.Errorf("create: expected %d, got %d", tt.want, rec.Code)
+			}
+
+			rec = httptest.NewRecorder()
+			srv.HandleSecretUpdate(rec, httptest.NewRequest(http.MethodPut, "/secret/a", strings.NewReader(`{"value":"x"}`)))
+			if rec.Code != tt.want {
+				t.Errorf("update: expected %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestHandleSecretDelete(t *testing.T) {
+	var deleted string
+	srv := testServer(nil, nil, nil)
+	srv.secretService = &mockSecretSvc{
+		deleteFunc: func(_ context.Context, name string) error {
+			deleted = name
+			return nil
+		},
+	}
+	srv.setRoutes()
+
+	rec := httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/secret/api-key", nil))
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	if deleted != "api-key" {
+		t.Errorf("expected api-key to be deleted, got %q", deleted)
+	}
+}
+
+func TestHandleTaskRun_SecretNotFound(t *testing.T) {
+	var got *domain.Task
+	ts := &mockTaskSvc{
+		createTaskFunc: func(_ context.Context, task *domain.Task, _ []byte) error {
+			got = task
+			return fmt.Errorf("checking task secrets: %w: missing", domain.ErrSecretNotFound)
+		},
+	}
+	srv := testServer(ts, nil, nil)
+
+	body, ct := buildMultipartTask(`{"model_id":"m1","secrets":{"API_KEY":"missing"}}`, "data")
+	req := httptest.NewRequest(http.MethodPost, "/task/run", body)
+	req.Header.Set("Content-Type", ct)
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskRun(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a missing secret, got %d", rec.Code)
+	}
+	if got == nil || got.Secrets["API_KEY"] != "missing" {
+		t.Errorf("expected the secrets of the request on the task, got %+v", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"pinn-connect-service/internal/config"
 	"pinn-connect-service/internal/domain"
 	"time"
@@ -21,12 +22,23 @@ import (
 
 type TaskService interface {
 	SaveInput(taskID uuid.UUID, filename string, r io.Reader) ([]byte, error)
+	SaveParams(taskID uuid.UUID, params []byte) ([]byte, error)
+	DiscardInput(taskID uuid.UUID) error
 	GetTask(context.Context, uuid.UUID) (*domain.Task, error)
-	GetResultURL(ctx context.Context, id uuid.UUID) (string, error)
+	GetResultURL(ctx context.Context, id uuid.UUID, expiry time.Duration) (string, error)
 	CreateTask(ctx context.Context, task *domain.Task, fileHash []byte) error
 	StopTask(ctx context.Context, taskID uuid.UUID, timeout time.Duration) error
-	ListTasks(ctx context.Context, page, pageSize int) ([]domain.Task, int64, error)
+	ListTaskEvents(ctx context.Context, id uuid.UUID) ([]domain.TaskEvent, error)
+	ListTasks(ctx context.Context, page, pageSize int, filter domain.TaskFilter) ([]domain.Task, int64, error)
 	DeleteTask(context.Context, uuid.UUID) error
+	ListResultFiles(ctx context.Context, id uuid.UUID) ([]domain.ResultFile, error)
+	OpenResultFile(ctx context.Context, id uuid.UUID, name string) (io.ReadSeekCloser, *domain.ResultFile, error)
+	OpenResultArchive(ctx context.Context, id uuid.UUID, format domain.ArchiveFormat) (func(io.Writer) error, error)
+	SetPinned(ctx context.Context, id uuid.UUID, pinned bool) (*domain.Task, error)
+	RetryUpload(ctx context.Context, id uuid.UUID) error
+	VerifyResult(ctx context.Context, id uuid.UUID) (*domain.VerifyReport, error)
+	UsageReport(ctx context.Context, from, to time.Time) ([]domain.UsageReportRow, error)
+	StreamTaskStats(ctx context.Context, id uuid.UUID, send func(domain.TaskStats) error) error
 }
 
 type ModelService interface {
@@ -39,10 +51,38 @@ type ModelService interface {
 	BuildModel(ctx context.Context, modelID string, archive io.Reader, logWriter io.Writer) error
 	RebuildModel(ctx context.Context, modelID string, archive io.Reader, logWriter io.Writer) error
 	DeleteImageByModelId(context.Context, string) error
+	SetNetworkMode(ctx context.Context, modelID string, mode domain.NetworkMode) error
+	SetSecurity(ctx context.Context, modelID string, security *domain.ModelSecurity) error
 }
 
 type HealthService interface {
 	CheckStatus(ctx context.Context) error
+	LeaderInfo(ctx context.Context) *domain.LeaderInfo
+	DockerHosts() []domain.DockerHost
+}
+
+type AdminService interface {
+	RunRetention(context.Context) (*domain.RetentionReport, error)
+	LastRetentionReport() *domain.RetentionReport
+	RunGC(context.Context) (*domain.GCReport, error)
+	LastGCReport() *domain.GCReport
+	RunReconcile(ctx context.Context, dryRun bool) (*domain.ReconcileReport, error)
+	SchedulerReport() *domain.SchedulerReport
+	Nodes(context.Context) ([]domain.Node, error)
+}
+
+type SecretService interface {
+	ListSecrets(ctx context.Context) ([]domain.Secret, error)
+	CreateSecret(ctx context.Context, name, value string) error
+	UpdateSecret(ctx context.Context, name, value string) error
+	DeleteSecret(ctx context.Context, name string) error
+}
+
+// SignedFileStore serves stored objects through signed download URLs. It is
+// only set for storage backends that can't serve downloads themselves.
+type SignedFileStore interface {
+	VerifySignedURL(objectKey string, query url.Values) error
+	OpenArtifact(ctx context.Context, objectKey string) (io.ReadSeekCloser, *domain.Artifact, error)
 }
 
 type Server struct {
@@ -50,15 +90,23 @@ type Server struct {
 	taskService   TaskService
 	modelService  ModelService
 	healthService HealthService
+	adminService  AdminService
+	secretService SecretService
+	fileStore     SignedFileStore
 	config        *config.Config
 }
 
-func New(taskService TaskService, modelService ModelService, healthService HealthService, config *config.Config) *Server {
+// New creates the server. fileStore may be nil, then the /storage endpoint is disabled.
+func New(taskService TaskService, modelService ModelService, healthService HealthService,
+	adminService AdminService, secretService SecretService, fileStore SignedFileStore, config *config.Config) *Server {
 	s := &Server{
 		router:        chi.NewRouter(),
 		taskService:   taskService,
 		modelService:  modelService,
 		healthService: healthService,
+		adminService:  adminService,
+		secretService: secretService,
+		fileStore:     fileStore,
 		config:        config,
 	}
 
@@ -116,6 +164,11 @@ func (s *Server) setRoutes() {
 
 	s.router.Get("/health", s.HandleHealth)
 
+	// signed download urls carry their own authorization
+	if s.fileStore != nil {
+		s.router.Get("/storage/*", s.HandleStorageFile)
+	}
+
 	// Protected routes group
 	s.router.Group(func(r chi.Router) {
 		r.Use(s.AuthMiddleware)
@@ -124,10 +177,20 @@ func (s *Server) setRoutes() {
 
 		r.Route("/task", func(r chi.Router) {
 			r.Get("/list", s.HandleGetAllTasks)
+			r.Get("/usage", s.HandleTaskUsage)
 			r.Post("/run", s.HandleTaskRun)
 			r.Get("/{id}/status", s.HandleTaskStatus)
 			r.Post("/{id}/stop", s.HandleTaskStop)
+			r.Get("/{id}/events", s.HandleTaskEvents)
+			r.Get("/{id}/stats", s.HandleTaskStats)
 			r.Get("/{id}/result", s.HandleTaskResult)
+			r.Get("/{id}/files", s.HandleTaskFiles)
+			r.Get("/{id}/files/*", s.HandleTaskFile)
+			r.Get("/{id}/archive", s.HandleTaskArchive)
+			r.Post("/{id}/verify", s.HandleTaskVerify)
+			r.Post("/{id}/pin", s.HandleTaskPin)
+			r.Delete("/{id}/pin", s.HandleTaskUnpin)
+			r.Post("/{id}/upload/retry", s.HandleTaskUploadRetry)
 			r.Delete("/{id}", s.HandleTaskDelete)
 		})
 
@@ -136,9 +199,28 @@ func (s *Server) setRoutes() {
 			r.Post("/", s.HandleModelAdd)
 			r.Put("/", s.HandleModelUpdate)
 			r.Delete("/{id}", s.HandleModelDelete)
+			r.Put("/{id}/network", s.HandleModelNetwork)
+			r.Put("/{id}/security", s.HandleModelSecurity)
 			r.Post("/build", s.HandleModelBuild)
 			r.Put("/build", s.HandleModelBuildUpdate)
 		})
+
+		r.Route("/secret", func(r chi.Router) {
+			r.Get("/", s.HandleSecretList)
+			r.Post("/", s.HandleSecretAdd)
+			r.Put("/{name}", s.HandleSecretUpdate)
+			r.Delete("/{name}", s.HandleSecretDelete)
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Get("/retention", s.HandleRetentionReport)
+			r.Post("/retention/run", s.HandleRetentionRun)
+			r.Get("/gc", s.HandleGCReport)
+			r.Post("/gc/run", s.HandleGCRun)
+			r.Post("/reconcile", s.HandleReconcile)
+			r.Get("/scheduler", s.HandleSchedulerReport)
+			r.Get("/nodes", s.HandleNodes)
+		})
 	})
 }
 
@@ -2,15 +2,20 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"pinn-connect-service/internal/domain"
 	"pinn-connect-service/internal/sysstats"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 // HandleStats godoc
 // @Summary      Get host resources statistics
-// @Description  Returns CPU utilization and available memory of the host
+// @Description  Returns CPU utilization and available memory of the host, and the health and load of the Docker hosts
 // @Tags         system
 // @Produce      json
 // @Success      200  {object}  domain.StatsResponse
@@ -26,6 +31,7 @@ func (s *Server) HandleStats(w http.ResponseWriter, r *http.Request) {
 	resp := domain.StatsResponse{
 		AvailableMemoryBytes: resources.AvailableMemoryBytes,
 		CPUUtilization:       resources.CPUUtilization,
+		DockerHosts:          s.healthService.DockerHosts(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -36,3 +42,64 @@ func (s *Server) HandleStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// HandleTaskStats godoc
+// @Summary      Stream live resource stats of a running task
+// @Description  Streams Server-Sent Events with the CPU, memory and IO usage of the task container about every second. Each `stats` event carries a domain.TaskStats, an `end` event is sent once the container exits
+// @Tags         tasks
+// @Produce      text/event-stream
+// @Param        id   path      string  true  "Task UUID"
+// @Success      200  {object}  domain.TaskStats
+// @Failure      400  {string}  string "Invalid ID"
+// @Failure      404  {string}  string "Task not found"
+// @Failure      409  {string}  string "Task is not running"
+// @Failure      500  {string}  string "Internal server error"
+// @Router       /task/{id}/stats [get]
+func (s *Server) HandleTaskStats(w http.ResponseWriter, r *http.Request) {
+	uuID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// the status is sent with the first event, until then errors are reported with their code
+	streaming := false
+	send := func(event string, v any) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("encoding %s event: %w", event, err)
+		}
+		if !streaming {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("X-Accel-Buffering", "no")
+			w.WriteHeader(http.StatusOK)
+			streaming = true
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	err = s.taskService.StreamTaskStats(r.Context(), uuID, func(stats domain.TaskStats) error {
+		return send("stats", stats)
+	})
+	switch {
+	case err == nil:
+		if err := send("end", struct{}{}); err != nil {
+			slog.Debug("sending task stats end", "task_id", uuID, "error", err)
+		}
+	case streaming:
+		// mostly a client that went away
+		slog.Debug("task stats stream ended", "task_id", uuID, "error", err)
+	case errors.Is(err, domain.ErrTaskNotFound):
+		http.Error(w, "task not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrTaskNotRunning):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		slog.Error("streaming task stats", "task_id", uuID, "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
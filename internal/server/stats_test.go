@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"pinn-connect-service/internal/domain"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// ─────────────────────────────────────────────
+// HandleTaskStats
+// ─────────────────────────────────────────────
+
+func TestHandleTaskStats_Stream(t *testing.T) {
+	ts := &mockTaskSvc{
+		streamStatsFunc: func(_ context.Context, _ uuid.UUID, send func(domain.TaskStats) error) error {
+			for _, cpu := range []float64{50, 75} {
+				if err := send(domain.TaskStats{CPUPercent: cpu, MemoryBytes: 10}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
+	id := uuid.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/task/"+id.String()+"/stats", nil)
+	req = withChiParam(req, "id", id.String())
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskStats(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected an event stream, got %q", ct)
+	}
+	if !rec.Flushed {
+		t.Error("expected the events to be flushed")
+	}
+
+	frames := strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n")
+	if len(frames) != 3 {
+		t.Fatalf("expected 2 stats and an end event, got %q", rec.Body.String())
+	}
+	var stats domain.TaskStats
+	data, ok := strings.CutPrefix(frames[1], "event: stats\ndata: ")
+	if !ok {
+		t.Fatalf("unexpected frame %q", frames[1])
+	}
+	if err := json.Unmarshal([]byte(data), &stats); err != nil || stats.CPUPercent != 75 {
+		t.Errorf("unexpected stats %+v, err %v", stats, err)
+	}
+	if !strings.HasPrefix(frames[2], "event: end\n") {
+		t.Errorf("expected an end event, got %q", frames[2])
+	}
+}
+
+func TestHandleTaskStats_Errors(t *testing.T) {
+	cases := []struct {
+		err  error
+		code int
+	}{
+		{domain.ErrTaskNotFound, http.StatusNotFound},
+		{domain.ErrTaskNotRunning, http.StatusConflict},
+		{errors.New("docker down"), http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		ts := &mockTaskSvc{
+			streamStatsFunc: func(context.Context, uuid.UUID, func(domain.TaskStats) error) error { return tc.err },
+		}
+		srv := testServer(ts, nil, nil)
+		id := uuid.New()
+
+		req := httptest.NewRequest(http.MethodGet, "/task/"+id.String()+"/stats", nil)
+		req = withChiParam(req, "id", id.String())
+		rec := httptest.NewRecorder()
+
+		srv.HandleTaskStats(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("%v: expected %d, got %d", tc.err, tc.code, rec.Code)
+		}
+	}
+}
+
+func TestHandleTaskStats_InvalidID(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+	req := httptest.NewRequest(http.MethodGet, "/task/bad/stats", nil)
+	req = withChiParam(req, "id", "bad")
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskStats(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
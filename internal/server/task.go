@@ -6,6 +6,7 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
 	"pinn-connect-service/internal/domain"
 	"strconv"
@@ -17,95 +18,141 @@ import (
 
 // HandleTaskRun godoc
 // @Summary      Create and run a new task
-// @Description  Accepts multipart/form-data with task metadata and optional input file
+// @Description  Accepts multipart/form-data with task metadata and an optional input file, or the task metadata as application/json. A task needs an input file, params or both.
 // @Tags         tasks
-// @Accept       multipart/form-data
+// @Accept       multipart/form-data,json
 // @Produce      json
 // @Param        task  formData  string  true  "Task metadata (JSON)"
 // @Param        file  formData  file    false "Input file for the task"
 // @Success      202  {object}  map[string]string "Task accepted"
 // @Failure      400  {string}  string "Invalid request or missing fields"
+// @Failure      507  {string}  string "Not enough free disk space"
 // @Router       /task/run [post]
 func (s *Server) HandleTaskRun(w http.ResponseWriter, r *http.Request) {
-	mr, err := r.MultipartReader()
-	if err != nil {
-		http.Error(w, "invalid multipart request", http.StatusBadRequest)
-		return
-	}
-
 	task := domain.Task{ID: uuid.New()}
-	var fileProcessed, taskProcessed bool
 	var fileHashBytes []byte
+	var fileProcessed bool
+
+	// the input of a task rejected here is removed, CreateTask removes it
+	// on its own failures
+	var inputSaved, submitted bool
+	defer func() {
+		if inputSaved && !submitted {
+			if err := s.taskService.DiscardInput(task.ID); err != nil {
+				slog.Error("failed to discard task input", "task_id", task.ID, "error", err)
+			}
+		}
+	}()
 
-	for {
-		part, err := mr.NextPart()
-		if errors.Is(err, io.EOF) {
-			break
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		req := domain.CreateTaskRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
 		}
+		if err := s.checkTaskRequest(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mapReqToTask(&req, &task)
+	} else {
+		mr, err := r.MultipartReader()
 		if err != nil {
-			http.Error(w, "error reading multipart", http.StatusBadRequest)
+			http.Error(w, "invalid multipart request", http.StatusBadRequest)
 			return
 		}
 
-		switch part.FormName() {
-		case "task":
-			req := domain.CreateTaskRequest{}
-			if err := json.NewDecoder(part).Decode(&req); err != nil {
-				http.Error(w, "invalid json metadata", http.StatusBadRequest)
-				return
-			}
-
-			if req.CPULimit < 0 || req.CPULimit > s.config.MaxCPUByTask {
-				http.Error(w, "invalid cpu limit", http.StatusBadRequest)
-				return
-			}
-			if req.CPULimit == 0 {
-				req.CPULimit = s.config.MaxCPUByTask
+		var taskProcessed bool
+		for {
+			part, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
 			}
-
-			if req.MemoryLimit < 0 || req.MemoryLimit > s.config.MaxMemByTask {
-				http.Error(w, "invalid memory limit", http.StatusBadRequest)
-				return
-			}
-			if req.MemoryLimit == 0 {
-				req.MemoryLimit = s.config.MaxMemByTask
-			}
-
-			if req.TimeoutSec < 0 {
-				http.Error(w, "invalid timeout", http.StatusBadRequest)
+			if err != nil {
+				http.Error(w, "error reading multipart", http.StatusBadRequest)
 				return
 			}
-			if req.TimeoutSec == 0 {
-				req.TimeoutSec = s.config.DefaultTaskTimeoutSec
-			}
-
-			mapReqToTask(&req, &task)
-			taskProcessed = true
 
-		case "file":
-			task.InputFilename = part.FileName()
-
-			fileHashBytes, err = s.taskService.SaveInput(task.ID, task.InputFilename, part)
-			if err != nil {
-				slog.Error("save input failed", "task_id", task.ID, "error", err)
-				http.Error(w, "internal error", http.StatusInternalServerError)
-				return
+			switch part.FormName() {
+			case "task":
+				req := domain.CreateTaskRequest{}
+				if err := json.NewDecoder(part).Decode(&req); err != nil {
+					http.Error(w, "invalid json metadata", http.StatusBadRequest)
+					return
+				}
+				if err := s.checkTaskRequest(&req); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				mapReqToTask(&req, &task)
+				taskProcessed = true
+
+			case "file":
+				task.InputFilename = part.FileName()
+
+				inputSaved = true
+				fileHashBytes, err = s.taskService.SaveInput(task.ID, task.InputFilename, part)
+				if errors.Is(err, domain.ErrDiskFull) {
+					slog.Warn("rejecting task, disk is nearly full", "task_id", task.ID, "error", err)
+					http.Error(w, "insufficient disk space", http.StatusInsufficientStorage)
+					return
+				}
+				if err != nil {
+					slog.Error("save input failed", "task_id", task.ID, "error", err)
+					http.Error(w, "internal error", http.StatusInternalServerError)
+					return
+				}
+
+				fileProcessed = true
 			}
+		}
 
-			fileProcessed = true
+		if !taskProcessed {
+			http.Error(w, "'task' part is required", http.StatusBadRequest)
+			return
 		}
 	}
 
-	if !fileProcessed || !taskProcessed {
-		http.Error(w, "both 'file' and 'task' parts are required", http.StatusBadRequest)
+	if !fileProcessed && len(task.Params) == 0 {
+		http.Error(w, "an input file or params are required", http.StatusBadRequest)
 		return
 	}
 
-	err = s.taskService.CreateTask(r.Context(), &task, fileHashBytes)
+	if len(task.Params) > 0 {
+		if task.InputFilename == domain.ParamsFilename {
+			http.Error(w, "input file "+domain.ParamsFilename+" conflicts with params", http.StatusBadRequest)
+			return
+		}
+
+		inputSaved = true
+		params, err := s.taskService.SaveParams(task.ID, task.Params)
+		switch {
+		case errors.Is(err, domain.ErrInvalidParams):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, domain.ErrDiskFull):
+			slog.Warn("rejecting task, disk is nearly full", "task_id", task.ID, "error", err)
+			http.Error(w, "insufficient disk space", http.StatusInsufficientStorage)
+			return
+		case err != nil:
+			slog.Error("save params failed", "task_id", task.ID, "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		task.Params = params
+	}
+
+	submitted = true
+	err := s.taskService.CreateTask(r.Context(), &task, fileHashBytes)
 	if err != nil {
 		switch {
 		case errors.Is(err, domain.ErrModelNotFound):
 			http.Error(w, "model not found", http.StatusBadRequest)
+		case errors.Is(err, domain.ErrSecretNotFound), errors.Is(err, domain.ErrInvalidSecretRef),
+			errors.Is(err, domain.ErrSecretsDisabled), errors.Is(err, domain.ErrNetworkModeNotAllowed):
+			http.Error(w, err.Error(), http.StatusBadRequest)
 		default:
 			slog.Error("error creating task", "error", err)
 			http.Error(w, "internal error", http.StatusInternalServerError)
@@ -120,15 +167,73 @@ func (s *Server) HandleTaskRun(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// checkTaskRequest validates the limits of a new task and fills the ones left
+// empty with the defaults.
+func (s *Server) checkTaskRequest(req *domain.CreateTaskRequest) error {
+	if req.CPULimit < 0 || req.CPULimit > s.config.MaxCPUByTask {
+		return errors.New("invalid cpu limit")
+	}
+	if req.CPULimit == 0 {
+		req.CPULimit = s.config.MaxCPUByTask
+	}
+
+	if req.MemoryLimit < 0 || req.MemoryLimit > s.config.MaxMemByTask {
+		return errors.New("invalid memory limit")
+	}
+	if req.MemoryLimit == 0 {
+		req.MemoryLimit = s.config.MaxMemByTask
+	}
+
+	maxDisk := s.config.Disk.MaxByTaskMB
+	if req.DiskLimit < 0 || (maxDisk > 0 && req.DiskLimit > maxDisk) {
+		return errors.New("invalid disk limit")
+	}
+	if req.DiskLimit == 0 {
+		req.DiskLimit = maxDisk
+	}
+
+	if _, ok := req.Constraints[""]; ok {
+		return errors.New("invalid constraints")
+	}
+
+	if req.TimeoutSec < 0 {
+		return errors.New("invalid timeout")
+	}
+	if req.TimeoutSec == 0 {
+		req.TimeoutSec = s.config.DefaultTaskTimeoutSec
+	}
+
+	if req.KeepForSec < 0 {
+		return errors.New("invalid keep_for_sec")
+	}
+
+	if req.NetworkMode != "" {
+		if _, err := domain.ParseNetworkMode(req.NetworkMode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func mapReqToTask(req *domain.CreateTaskRequest, task *domain.Task) {
 	task.ModelID = req.ModelID
 	task.ContainerCmd = req.ContainerCmd
 	task.ContainerEnvs = req.ContainerEnvs
 	task.CPULim = req.CPULimit
 	task.MemLim = req.MemoryLimit
+	task.DiskLim = req.DiskLimit
 	task.GPUEnabled = req.GPUEnabled
 	task.ScheduledAt = req.ScheduledAt
 	task.TimeoutSec = req.TimeoutSec
+	task.KeepForSec = req.KeepForSec
+	task.Constraints = req.Constraints
+	task.Secrets = req.Secrets
+	task.NetworkMode = domain.NetworkMode(req.NetworkMode)
+	// a literal null is no params
+	if string(req.Params) != "null" {
+		task.Params = req.Params
+	}
 }
 
 // HandleTaskStatus godoc
@@ -224,10 +329,25 @@ func (s *Server) HandleTaskDelete(w http.ResponseWriter, r *http.Request) {
 
 func mapTaskToResp(task *domain.Task) *domain.TaskStatusResponse {
 	resp := domain.TaskStatusResponse{
-		ID:        task.ID.String(),
-		ModelID:   task.ModelID,
-		Status:    string(task.Status),
-		CreatedAt: task.CreatedAt,
+		ID:               task.ID.String(),
+		ModelID:          task.ModelID,
+		Status:           string(task.Status),
+		CreatedAt:        task.CreatedAt,
+		Pinned:           task.Pinned,
+		KeepForSec:       task.KeepForSec,
+		ResultMissing:    task.ResultMissing,
+		UploadTotalBytes: task.UploadTotalBytes,
+		UploadedBytes:    task.UploadDoneBytes,
+		UploadFailed:     task.UploadFailed,
+		InputSHA256:      task.InputSHA256,
+		ResultCorrupted:  task.ResultCorrupted,
+		Constraints:      task.Constraints,
+		ExitCode:         task.ExitCode,
+		FailureReason:    string(task.FailureReason),
+		Usage:            task.Usage,
+		NetworkMode:      string(task.NetworkMode),
+		DiskLimit:        task.DiskLim,
+		Attempts:         task.Attempts,
 	}
 
 	if task.Status == domain.TaskScheduled {
@@ -253,13 +373,15 @@ func mapTaskToResp(task *domain.Task) *domain.TaskStatusResponse {
 }
 
 // HandleTaskStop godoc
-// @Summary      Stop a running task
-// @Description  Sends a stop signal to a running container task
+// @Summary      Stop a task
+// @Description  Sends a stop signal to a running container task. A queued or scheduled task is cancelled.
 // @Tags         tasks
 // @Param        id       path      string  true  "Task UUID"
 // @Param        timeout  query     string  false "Stop timeout (seconds or duration string)"
 // @Success      200  {string}  string "Task stopped"
 // @Failure      400  {string}  string "Invalid ID or timeout format"
+// @Failure      404  {string}  string "Task not found"
+// @Failure      409  {string}  string "Task can't be stopped in its status or changed concurrently"
 // @Router       /task/{id}/stop [post]
 func (s *Server) HandleTaskStop(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -293,22 +415,65 @@ func (s *Server) HandleTaskStop(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	if err := s.taskService.StopTask(ctx, uuID, timeout); err != nil {
-		slog.Error("failed to stop task", "error", err)
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, domain.ErrTaskNotFound):
+			http.Error(w, "task not found", http.StatusNotFound)
+		case errors.Is(err, domain.ErrInvalidTransition), errors.Is(err, domain.ErrTaskConflict):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			slog.Error("failed to stop task", "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
+// HandleTaskEvents godoc
+// @Summary      Get task status history
+// @Description  Returns the status transitions of the task with the component that made each of them, oldest first
+// @Tags         tasks
+// @Produce      json
+// @Param        id   path      string  true  "Task UUID"
+// @Success      200  {object}  domain.TaskEventsResponse
+// @Failure      400  {string}  string "Invalid ID"
+// @Failure      404  {string}  string "Task not found"
+// @Failure      500  {string}  string "Internal server error"
+// @Router       /task/{id}/events [get]
+func (s *Server) HandleTaskEvents(w http.ResponseWriter, r *http.Request) {
+	uuID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	events, err := s.taskService.ListTaskEvents(r.Context(), uuID)
+	if errors.Is(err, domain.ErrTaskNotFound) {
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("listing task events", "task_id", uuID, "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(domain.TaskEventsResponse{TaskID: uuID.String(), Events: events}); err != nil {
+		slog.Error("encoding task events", "error", err)
+	}
+}
+
 // HandleTaskResult godoc
 // @Summary      Get task result download URL
 // @Description  Returns a pre-signed URL to download the task result artifact
 // @Tags         tasks
 // @Produce      json
-// @Param        id   path      string  true  "Task UUID"
+// @Param        id       path      string  true   "Task UUID"
+// @Param        expires  query     string  false  "URL expiry in seconds or as a duration string (default: STORAGE_PRESIGN_EXPIRY)"
 // @Success      200  {object}  map[string]string "Download URL"
-// @Failure      400  {string}  string "Invalid ID"
+// @Failure      400  {string}  string "Invalid ID or expiry"
 // @Failure      404  {string}  string "Task not found or result not ready"
 // @Router       /task/{id}/result [get]
 func (s *Server) HandleTaskResult(w http.ResponseWriter, r *http.Request) {
@@ -324,7 +489,29 @@ func (s *Server) HandleTaskResult(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	url, err := s.taskService.GetResultURL(r.Context(), uuID)
+	var expiry time.Duration
+	if expiresStr := r.URL.Query().Get("expires"); expiresStr != "" {
+		if sec, err := strconv.Atoi(expiresStr); err == nil {
+			expiry = time.Duration(sec) * time.Second
+		} else if expiry, err = time.ParseDuration(expiresStr); err != nil {
+			http.Error(w, "invalid expires format, expected seconds or duration string", http.StatusBadRequest)
+			return
+		}
+		if expiry <= 0 {
+			http.Error(w, "expires must be positive", http.StatusBadRequest)
+			return
+		}
+	}
+
+	url, err := s.taskService.GetResultURL(r.Context(), uuID, expiry)
+	if errors.Is(err, domain.ErrInvalidExpiry) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, domain.ErrTaskNotFound) {
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		slog.Error("getting task result url", "error", err)
 		http.Error(w, "internal error", http.StatusInternalServerError)
@@ -348,8 +535,10 @@ func (s *Server) HandleTaskResult(w http.ResponseWriter, r *http.Request) {
 // @Tags         tasks
 // @Produce      json
 // @Param        page       query     int  false  "Page number (default: 1)"
-// @Param        page_size  query     int  false  "Number of items per page (default: 10)"
+// @Param        page_size       query     int     false  "Number of items per page (default: 10)"
+// @Param        failure_reason  query     string  false  "Only tasks that failed or were stopped for the reason"  Enums(oom_killed, timeout, nonzero_exit, image_pull_failed, container_start_failed, upload_failed, user_cancelled, infrastructure, disk_quota_exceeded, attempts_exhausted)
 // @Success      200  {object}  domain.GetAllTasksResponse
+// @Failure      400  {string}  string "Unknown failure reason"
 // @Failure      500  {string}  string "Internal server error"
 // @Router       /task/list [get]
 func (s *Server) HandleGetAllTasks(w http.ResponseWriter, r *http.Request) {
@@ -366,7 +555,17 @@ func (s *Server) HandleGetAllTasks(w http.ResponseWriter, r *http.Request) {
 		pageSize = 10
 	}
 
-	tasks, total, err := s.taskService.ListTasks(r.Context(), page, pageSize)
+	var filter domain.TaskFilter
+	if reason := r.URL.Query().Get("failure_reason"); reason != "" {
+		failureReason, err := domain.ParseFailureReason(reason)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.FailureReason = failureReason
+	}
+
+	tasks, total, err := s.taskService.ListTasks(r.Context(), page, pageSize, filter)
 	if err != nil {
 		slog.Error("listing tasks", "error", err)
 		http.Error(w, "internal error", http.StatusInternalServerError)
@@ -391,3 +590,89 @@ func (s *Server) HandleGetAllTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// HandleTaskPin godoc
+// @Summary      Pin a task
+// @Description  Excludes the task and its result from retention cleanup
+// @Tags         tasks
+// @Produce      json
+// @Param        id   path      string  true  "Task UUID"
+// @Success      200  {object}  domain.TaskStatusResponse
+// @Failure      400  {string}  string "Invalid ID"
+// @Failure      404  {string}  string "Task not found"
+// @Router       /task/{id}/pin [post]
+func (s *Server) HandleTaskPin(w http.ResponseWriter, r *http.Request) {
+	s.handleSetPinned(w, r, true)
+}
+
+// HandleTaskUnpin godoc
+// @Summary      Unpin a task
+// @Description  Makes the task subject to retention cleanup again
+// @Tags         tasks
+// @Produce      json
+// @Param        id   path      string  true  "Task UUID"
+// @Success      200  {object}  domain.TaskStatusResponse
+// @Failure      400  {string}  string "Invalid ID"
+// @Failure      404  {string}  string "Task not found"
+// @Router       /task/{id}/pin [delete]
+func (s *Server) HandleTaskUnpin(w http.ResponseWriter, r *http.Request) {
+	s.handleSetPinned(w, r, false)
+}
+
+func (s *Server) handleSetPinned(w http.ResponseWriter, r *http.Request, pinned bool) {
+	uuID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.taskService.SetPinned(r.Context(), uuID, pinned)
+	if err != nil {
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			http.Error(w, "task not found", http.StatusNotFound)
+			return
+		}
+		slog.Error("setting task pinned", "task_id", uuID, "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(mapTaskToResp(task)); err != nil {
+		slog.Error("encoding task status", "error", err)
+	}
+}
+
+// HandleTaskUploadRetry godoc
+// @Summary      Retry the result upload
+// @Description  Uploads again the result of a task whose container succeeded but whose upload failed. Parts already uploaded are reused, the container is not run again. The upload runs in the background, its progress is shown in the task status
+// @Tags         tasks
+// @Param        id   path      string  true  "Task UUID"
+// @Success      202  "Accepted"
+// @Failure      400  {string}  string "Invalid ID"
+// @Failure      404  {string}  string "Task not found"
+// @Failure      409  {string}  string "Upload has not failed or is already in progress"
+// @Failure      500  {string}  string "Internal server error"
+// @Router       /task/{id}/upload/retry [post]
+func (s *Server) HandleTaskUploadRetry(w http.ResponseWriter, r *http.Request) {
+	uuID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.taskService.RetryUpload(r.Context(), uuID); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrTaskNotFound):
+			http.Error(w, "task not found", http.StatusNotFound)
+		case errors.Is(err, domain.ErrUploadNotFailed), errors.Is(err, domain.ErrTaskInProgress):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			slog.Error("retrying task upload", "task_id", uuID, "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -21,7 +22,13 @@ import (
 // ─────────────────────────────────────────────
 
 func TestHandleTaskRun_Success(t *testing.T) {
-	srv := testServer(nil, nil, nil)
+	ts := &mockTaskSvc{
+		discardInputFunc: func(uuid.UUID) error {
+			t.Error("expected the input of a created task to be kept")
+			return nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
 
 	body, ct := buildMultipartTask(validTaskJSON(), "payload")
 	req := httptest.NewRequest(http.MethodPost, "/task/run", body)
@@ -42,6 +49,185 @@ func TestHandleTaskRun_Success(t *testing.T) {
 	}
 }
 
+func TestHandleTaskRun_JSONParams(t *testing.T) {
+	var captured *domain.Task
+	var capturedHash []byte
+	var savedParams string
+	ts := &mockTaskSvc{
+		saveInputFunc: func(uuid.UUID, string, io.Reader) ([]byte, error) {
+			t.Fatal("expected no input file to be saved")
+			return nil, nil
+		},
+		saveParamsFunc: func(_ uuid.UUID, params []byte) ([]byte, error) {
+			savedParams = string(params)
+			return []byte(`{"epochs":1000}`), nil
+		},
+		createTaskFunc: func(_ context.Context, t *domain.Task, fileHash []byte) error {
+			captured, capturedHash = t, fileHash
+			return nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/task/run",
+		bytes.NewBufferString(`{"model_id":"m1","params":{ "epochs": 1000 }}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskRun(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if savedParams != `{ "epochs": 1000 }` {
+		t.Errorf("expected params to be saved, got %q", savedParams)
+	}
+	if string(captured.Params) != `{"epochs":1000}` || capturedHash != nil {
+		t.Errorf("expected the task with canonical params and no input, got %q / %x", captured.Params, capturedHash)
+	}
+	if captured.CPULim != srv.config.MaxCPUByTask {
+		t.Errorf("expected default limits to be applied, got CPULim=%v", captured.CPULim)
+	}
+}
+
+func TestHandleTaskRun_JSONInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"malformed json", `{"model_id":`},
+		{"no params", `{"model_id":"m1"}`},
+		{"null params", `{"model_id":"m1","params":null}`},
+		{"invalid limits", `{"model_id":"m1","cpu_limit":-1,"params":{"a":1}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := testServer(nil, nil, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/task/run", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			srv.HandleTaskRun(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestHandleTaskRun_InvalidParams(t *testing.T) {
+	ts := &mockTaskSvc{
+		saveParamsFunc: func(uuid.UUID, []byte) ([]byte, error) {
+			return nil, domain.ErrInvalidParams
+		},
+	}
+	srv := testServer(ts, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/task/run", bytes.NewBufferString(`{"model_id":"m1","params":[1]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskRun(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestHandleTaskRun_MultipartParamsOnly(t *testing.T) {
+	var captured *domain.Task
+	ts := &mockTaskSvc{
+		createTaskFunc: func(_ context.Context, t *domain.Task, _ []byte) error {
+			captured = t
+			return nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	tw, _ := w.CreateFormField("task")
+	tw.Write([]byte(`{"model_id":"m1","params":{"epochs":1000}}`))
+	w.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/task/run", body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskRun(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if string(captured.Params) != `{"epochs":1000}` {
+		t.Errorf("expected params on the task, got %q", captured.Params)
+	}
+}
+
+// A literal null is no params, the task runs on its input file.
+func TestHandleTaskRun_NullParams(t *testing.T) {
+	var captured *domain.Task
+	ts := &mockTaskSvc{
+		saveParamsFunc: func(uuid.UUID, []byte) ([]byte, error) {
+			t.Fatal("expected no params to be saved")
+			return nil, nil
+		},
+		createTaskFunc: func(_ context.Context, t *domain.Task, _ []byte) error {
+			captured = t
+			return nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
+
+	body, ct := buildMultipartTask(`{"model_id":"m1","params":null}`, "data")
+	req := httptest.NewRequest(http.MethodPost, "/task/run", body)
+	req.Header.Set("Content-Type", ct)
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskRun(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if captured.Params != nil {
+		t.Errorf("expected no params on the task, got %q", captured.Params)
+	}
+}
+
+func TestHandleTaskRun_ParamsFileConflict(t *testing.T) {
+	var savedID, discardedID uuid.UUID
+	ts := &mockTaskSvc{
+		saveInputFunc: func(id uuid.UUID, _ string, r io.Reader) ([]byte, error) {
+			savedID = id
+			io.Copy(io.Discard, r)
+			return []byte("hash"), nil
+		},
+		discardInputFunc: func(id uuid.UUID) error {
+			discardedID = id
+			return nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	tw, _ := w.CreateFormField("task")
+	tw.Write([]byte(`{"model_id":"m1","params":{"epochs":1000}}`))
+	fw, _ := w.CreateFormFile("file", domain.ParamsFilename)
+	fw.Write([]byte("{}"))
+	w.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/task/run", body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskRun(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a file named %s next to params, got %d", domain.ParamsFilename, rec.Code)
+	}
+	if savedID == uuid.Nil || discardedID != savedID {
+		t.Errorf("expected the saved input %s to be discarded, got %s", savedID, discardedID)
+	}
+}
+
 func TestHandleTaskRun_InvalidMultipart(t *testing.T) {
 	srv := testServer(nil, nil, nil)
 
@@ -96,7 +282,14 @@ func TestHandleTaskRun_MissingFilePart(t *testing.T) {
 }
 
 func TestHandleTaskRun_MissingTaskPart(t *testing.T) {
-	srv := testServer(nil, nil, nil)
+	var discarded bool
+	ts := &mockTaskSvc{
+		discardInputFunc: func(uuid.UUID) error {
+			discarded = true
+			return nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
 
 	// Only "file" part, no "task"
 	body := &bytes.Buffer{}
@@ -113,6 +306,9 @@ func TestHandleTaskRun_MissingTaskPart(t *testing.T) {
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("expected 400, got %d", rec.Code)
 	}
+	if !discarded {
+		t.Error("expected the saved input to be discarded")
+	}
 }
 
 func TestHandleTaskRun_CPULimit_Negative(t *testing.T) {
@@ -171,6 +367,22 @@ func TestHandleTaskRun_MemoryLimit_ExceedsMax(t *testing.T) {
 	}
 }
 
+func TestHandleTaskRun_DiskLimit_Invalid(t *testing.T) {
+	for _, limit := range []string{"-1", "4096"} {
+		srv := testServer(nil, nil, nil)
+
+		body, ct := buildMultipartTask(`{"model_id":"m1","disk_limit":`+limit+`}`, "data")
+		req := httptest.NewRequest(http.MethodPost, "/task/run", body)
+		req.Header.Set("Content-Type", ct)
+		rec := httptest.NewRecorder()
+
+		srv.HandleTaskRun(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for disk_limit=%s, got %d", limit, rec.Code)
+		}
+	}
+}
+
 func TestHandleTaskRun_TimeoutSec_Negative(t *testing.T) {
 	srv := testServer(nil, nil, nil)
 
@@ -185,6 +397,59 @@ func TestHandleTaskRun_TimeoutSec_Negative(t *testing.T) {
 	}
 }
 
+func TestHandleTaskRun_KeepForSec_Negative(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+
+	body, ct := buildMultipartTask(`{"model_id":"m1","keep_for_sec":-1}`, "data")
+	req := httptest.NewRequest(http.MethodPost, "/task/run", body)
+	req.Header.Set("Content-Type", ct)
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskRun(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for negative keep_for_sec, got %d", rec.Code)
+	}
+}
+
+func TestHandleTaskRun_Constraints(t *testing.T) {
+	var captured *domain.Task
+	ts := &mockTaskSvc{
+		createTaskFunc: func(_ context.Context, t *domain.Task, _ []byte) error {
+			captured = t
+			return nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
+
+	body, ct := buildMultipartTask(`{"model_id":"m1","constraints":{"gpu":"true","arch":"amd64"}}`, "data")
+	req := httptest.NewRequest(http.MethodPost, "/task/run", body)
+	req.Header.Set("Content-Type", ct)
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskRun(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rec.Code)
+	}
+	if captured.Constraints["gpu"] != "true" || captured.Constraints["arch"] != "amd64" {
+		t.Errorf("unexpected constraints: %v", captured.Constraints)
+	}
+}
+
+func TestHandleTaskRun_Constraints_EmptyLabel(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+
+	body, ct := buildMultipartTask(`{"model_id":"m1","constraints":{"":"x"}}`, "data")
+	req := httptest.NewRequest(http.MethodPost, "/task/run", body)
+	req.Header.Set("Content-Type", ct)
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskRun(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an empty constraint label, got %d", rec.Code)
+	}
+}
+
 func TestHandleTaskRun_DefaultLimitsApplied(t *testing.T) {
 	var captured *domain.Task
 	ts := &mockTaskSvc{
@@ -212,6 +477,9 @@ func TestHandleTaskRun_DefaultLimitsApplied(t *testing.T) {
 	if captured.MemLim != srv.config.MaxMemByTask {
 		t.Errorf("expected MemLim=%v, got %v", srv.config.MaxMemByTask, captured.MemLim)
 	}
+	if captured.DiskLim != srv.config.Disk.MaxByTaskMB {
+		t.Errorf("expected DiskLim=%v, got %v", srv.config.Disk.MaxByTaskMB, captured.DiskLim)
+	}
 	if captured.TimeoutSec != srv.config.DefaultTaskTimeoutSec {
 		t.Errorf("expected TimeoutSec=%v, got %v", srv.config.DefaultTaskTimeoutSec, captured.TimeoutSec)
 	}
@@ -237,6 +505,26 @@ func TestHandleTaskRun_SaveInputError(t *testing.T) {
 	}
 }
 
+func TestHandleTaskRun_SaveInput_DiskFull(t *testing.T) {
+	ts := &mockTaskSvc{
+		saveInputFunc: func(_ uuid.UUID, _ string, r io.Reader) ([]byte, error) {
+			io.Copy(io.Discard, r)
+			return nil, fmt.Errorf("%w: 100 MB of 10000 MB free", domain.ErrDiskFull)
+		},
+	}
+	srv := testServer(ts, nil, nil)
+
+	body, ct := buildMultipartTask(validTaskJSON(), "data")
+	req := httptest.NewRequest(http.MethodPost, "/task/run", body)
+	req.Header.Set("Content-Type", ct)
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskRun(rec, req)
+	if rec.Code != http.StatusInsufficientStorage {
+		t.Errorf("expected 507, got %d", rec.Code)
+	}
+}
+
 func TestHandleTaskRun_CreateTask_ModelNotFound(t *testing.T) {
 	ts := &mockTaskSvc{
 		createTaskFunc: func(_ context.Context, _ *domain.Task, _ []byte) error {
@@ -256,6 +544,63 @@ func TestHandleTaskRun_CreateTask_ModelNotFound(t *testing.T) {
 	}
 }
 
+func TestHandleTaskRun_NetworkMode(t *testing.T) {
+	var got domain.NetworkMode
+	ts := &mockTaskSvc{
+		createTaskFunc: func(_ context.Context, task *domain.Task, _ []byte) error {
+			got = task.NetworkMode
+			return nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
+
+	body, ct := buildMultipartTask(`{"model_id":"m1","network_mode":"internal"}`, "data")
+	req := httptest.NewRequest(http.MethodPost, "/task/run", body)
+	req.Header.Set("Content-Type", ct)
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskRun(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got != domain.NetworkInternal {
+		t.Errorf("expected network mode internal, got %q", got)
+	}
+}
+
+func TestHandleTaskRun_NetworkMode_Unknown(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+
+	body, ct := buildMultipartTask(`{"model_id":"m1","network_mode":"host"}`, "data")
+	req := httptest.NewRequest(http.MethodPost, "/task/run", body)
+	req.Header.Set("Content-Type", ct)
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskRun(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown network mode, got %d", rec.Code)
+	}
+}
+
+func TestHandleTaskRun_CreateTask_NetworkModeNotAllowed(t *testing.T) {
+	ts := &mockTaskSvc{
+		createTaskFunc: func(_ context.Context, _ *domain.Task, _ []byte) error {
+			return domain.ErrNetworkModeNotAllowed
+		},
+	}
+	srv := testServer(ts, nil, nil)
+
+	body, ct := buildMultipartTask(`{"model_id":"m1","network_mode":"bridge"}`, "data")
+	req := httptest.NewRequest(http.MethodPost, "/task/run", body)
+	req.Header.Set("Content-Type", ct)
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskRun(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a network mode not allowed, got %d", rec.Code)
+	}
+}
+
 func TestHandleTaskRun_CreateTask_InternalError(t *testing.T) {
 	ts := &mockTaskSvc{
 		createTaskFunc: func(_ context.Context, _ *domain.Task, _ []byte) error {
@@ -712,6 +1057,119 @@ func TestHandleTaskStop_ServiceError(t *testing.T) {
 	}
 }
 
+func TestHandleTaskStop_RejectedTransition(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"not found", domain.ErrTaskNotFound, http.StatusNotFound},
+		{"finished", &domain.TransitionError{From: domain.TaskCompleted, To: domain.TaskStopped, Err: domain.ErrInvalidTransition}, http.StatusConflict},
+		{"changed", &domain.TransitionError{From: domain.TaskRunning, To: domain.TaskStopped, Err: domain.ErrTaskConflict}, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := &mockTaskSvc{
+				stopTaskFunc: func(_ context.Context, _ uuid.UUID, _ time.Duration) error {
+					return fmt.Errorf("marking task stopped: %w", tt.err)
+				},
+			}
+			srv := testServer(ts, nil, nil)
+			id := uuid.New()
+
+			req := httptest.NewRequest(http.MethodPost, "/task/"+id.String()+"/stop", nil)
+			req = withChiParam(req, "id", id.String())
+			rec := httptest.NewRecorder()
+
+			srv.HandleTaskStop(rec, req)
+			if rec.Code != tt.code {
+				t.Errorf("expected %d, got %d", tt.code, rec.Code)
+			}
+		})
+	}
+}
+
+// ─────────────────────────────────────────────
+// HandleTaskEvents
+// ─────────────────────────────────────────────
+
+func TestHandleTaskEvents_Success(t *testing.T) {
+	ts := &mockTaskSvc{
+		listEventsFunc: func(_ context.Context, id uuid.UUID) ([]domain.TaskEvent, error) {
+			return []domain.TaskEvent{
+				{ID: 1, TaskID: id, To: domain.TaskQueued, Actor: domain.ActorAPI},
+				{ID: 2, TaskID: id, From: domain.TaskQueued, To: domain.TaskRunning, Actor: domain.ActorWorker, NodeID: "node-1"},
+			}, nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
+	id := uuid.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/task/"+id.String()+"/events", nil)
+	req = withChiParam(req, "id", id.String())
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskEvents(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var resp domain.TaskEventsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.TaskID != id.String() || len(resp.Events) != 2 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if resp.Events[1].From != domain.TaskQueued || resp.Events[1].NodeID != "node-1" {
+		t.Errorf("unexpected event %+v", resp.Events[1])
+	}
+}
+
+func TestHandleTaskEvents_InvalidID(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/task/bad-uuid/events", nil)
+	req = withChiParam(req, "id", "bad-uuid")
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskEvents(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestHandleTaskEvents_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"not found", domain.ErrTaskNotFound, http.StatusNotFound},
+		{"db error", errors.New("db error"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := &mockTaskSvc{
+				listEventsFunc: func(_ context.Context, _ uuid.UUID) ([]domain.TaskEvent, error) {
+					return nil, tt.err
+				},
+			}
+			srv := testServer(ts, nil, nil)
+			id := uuid.New()
+
+			req := httptest.NewRequest(http.MethodGet, "/task/"+id.String()+"/events", nil)
+			req = withChiParam(req, "id", id.String())
+			rec := httptest.NewRecorder()
+
+			srv.HandleTaskEvents(rec, req)
+			if rec.Code != tt.code {
+				t.Errorf("expected %d, got %d", tt.code, rec.Code)
+			}
+		})
+	}
+}
+
 // ─────────────────────────────────────────────
 // HandleTaskResult
 // ─────────────────────────────────────────────
@@ -766,7 +1224,7 @@ func TestHandleTaskResult_InvalidUUID(t *testing.T) {
 
 func TestHandleTaskResult_ServiceError(t *testing.T) {
 	ts := &mockTaskSvc{
-		getResultURLFunc: func(_ context.Context, _ uuid.UUID) (string, error) {
+		getResultURLFunc: func(_ context.Context, _ uuid.UUID, _ time.Duration) (string, error) {
 			return "", errors.New("storage error")
 		},
 	}
@@ -785,7 +1243,7 @@ func TestHandleTaskResult_ServiceError(t *testing.T) {
 
 func TestHandleTaskResult_EmptyURL_NotReady(t *testing.T) {
 	ts := &mockTaskSvc{
-		getResultURLFunc: func(_ context.Context, _ uuid.UUID) (string, error) { return "", nil },
+		getResultURLFunc: func(_ context.Context, _ uuid.UUID, _ time.Duration) (string, error) { return "", nil },
 	}
 	srv := testServer(ts, nil, nil)
 	id := uuid.New()
@@ -800,6 +1258,69 @@ func TestHandleTaskResult_EmptyURL_NotReady(t *testing.T) {
 	}
 }
 
+func TestHandleTaskResult_TaskNotFound(t *testing.T) {
+	ts := &mockTaskSvc{
+		getResultURLFunc: func(context.Context, uuid.UUID, time.Duration) (string, error) {
+			return "", domain.ErrTaskNotFound
+		},
+	}
+	srv := testServer(ts, nil, nil)
+	id := uuid.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/task/"+id.String()+"/result", nil)
+	req = withChiParam(req, "id", id.String())
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskResult(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestHandleTaskResult_Expires(t *testing.T) {
+	var gotExpiry time.Duration
+	ts := &mockTaskSvc{
+		getResultURLFunc: func(_ context.Context, _ uuid.UUID, expiry time.Duration) (string, error) {
+			gotExpiry = expiry
+			return "https://example.com/result", nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
+	id := uuid.New()
+
+	for in, want := range map[string]time.Duration{"": 0, "90": 90 * time.Second, "2h": 2 * time.Hour} {
+		req := httptest.NewRequest(http.MethodGet, "/task/"+id.String()+"/result?expires="+in, nil)
+		req = withChiParam(req, "id", id.String())
+		rec := httptest.NewRecorder()
+
+		srv.HandleTaskResult(rec, req)
+		if rec.Code != http.StatusOK || gotExpiry != want {
+			t.Errorf("expires=%q: expected 200 with %s, got %d with %s", in, want, rec.Code, gotExpiry)
+		}
+	}
+}
+
+func TestHandleTaskResult_InvalidExpires(t *testing.T) {
+	ts := &mockTaskSvc{
+		getResultURLFunc: func(context.Context, uuid.UUID, time.Duration) (string, error) {
+			return "", domain.ErrInvalidExpiry
+		},
+	}
+	srv := testServer(ts, nil, nil)
+	id := uuid.New()
+
+	for _, in := range []string{"soon", "-5", "999h"} {
+		req := httptest.NewRequest(http.MethodGet, "/task/"+id.String()+"/result?expires="+in, nil)
+		req = withChiParam(req, "id", id.String())
+		rec := httptest.NewRecorder()
+
+		srv.HandleTaskResult(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expires=%q: expected 400, got %d", in, rec.Code)
+		}
+	}
+}
+
 // ─────────────────────────────────────────────
 // HandleGetAllTasks
 // ─────────────────────────────────────────────
@@ -827,7 +1348,7 @@ func TestHandleGetAllTasks_Success(t *testing.T) {
 func TestHandleGetAllTasks_DefaultPagination(t *testing.T) {
 	var capturedPage, capturedSize int
 	ts := &mockTaskSvc{
-		listTasksFunc: func(_ context.Context, page, size int) ([]domain.Task, int64, error) {
+		listTasksFunc: func(_ context.Context, page, size int, _ domain.TaskFilter) ([]domain.Task, int64, error) {
 			capturedPage = page
 			capturedSize = size
 			return nil, 0, nil
@@ -850,7 +1371,7 @@ func TestHandleGetAllTasks_DefaultPagination(t *testing.T) {
 func TestHandleGetAllTasks_NegativePaginationDefaults(t *testing.T) {
 	var capturedPage, capturedSize int
 	ts := &mockTaskSvc{
-		listTasksFunc: func(_ context.Context, page, size int) ([]domain.Task, int64, error) {
+		listTasksFunc: func(_ context.Context, page, size int, _ domain.TaskFilter) ([]domain.Task, int64, error) {
 			capturedPage = page
 			capturedSize = size
 			return nil, 0, nil
@@ -869,7 +1390,7 @@ func TestHandleGetAllTasks_NegativePaginationDefaults(t *testing.T) {
 
 func TestHandleGetAllTasks_ServiceError(t *testing.T) {
 	ts := &mockTaskSvc{
-		listTasksFunc: func(_ context.Context, _, _ int) ([]domain.Task, int64, error) {
+		listTasksFunc: func(_ context.Context, _, _ int, _ domain.TaskFilter) ([]domain.Task, int64, error) {
 			return nil, 0, errors.New("db error")
 		},
 	}
@@ -886,7 +1407,7 @@ func TestHandleGetAllTasks_ServiceError(t *testing.T) {
 
 func TestHandleGetAllTasks_EmptyList(t *testing.T) {
 	ts := &mockTaskSvc{
-		listTasksFunc: func(_ context.Context, _, _ int) ([]domain.Task, int64, error) {
+		listTasksFunc: func(_ context.Context, _, _ int, _ domain.TaskFilter) ([]domain.Task, int64, error) {
 			return []domain.Task{}, 0, nil
 		},
 	}
@@ -900,3 +1421,116 @@ func TestHandleGetAllTasks_EmptyList(t *testing.T) {
 		t.Errorf("expected 200, got %d", rec.Code)
 	}
 }
+
+func TestHandleGetAllTasks_FailureReasonFilter(t *testing.T) {
+	var got domain.TaskFilter
+	code := 137
+	ts := &mockTaskSvc{
+		listTasksFunc: func(_ context.Context, _, _ int, f domain.TaskFilter) ([]domain.Task, int64, error) {
+			got = f
+			return []domain.Task{{ID: uuid.New(), Status: domain.TaskFailed, ExitCode: &code, FailureReason: domain.FailureOOMKilled}}, 1, nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/task/list?failure_reason=oom_killed", nil)
+	rec := httptest.NewRecorder()
+	srv.HandleGetAllTasks(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if got.FailureReason != domain.FailureOOMKilled {
+		t.Errorf("expected the oom_killed filter, got %+v", got)
+	}
+	var resp domain.GetAllTasksResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Tasks) != 1 || resp.Tasks[0].FailureReason != "oom_killed" || resp.Tasks[0].ExitCode == nil || *resp.Tasks[0].ExitCode != 137 {
+		t.Errorf("unexpected tasks %+v", resp.Tasks)
+	}
+}
+
+func TestHandleGetAllTasks_UnknownFailureReason(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/task/list?failure_reason=bogus", nil)
+	rec := httptest.NewRecorder()
+	srv.HandleGetAllTasks(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
+// ─────────────────────────────────────────────
+// HandleTaskUploadRetry
+// ─────────────────────────────────────────────
+
+func TestHandleTaskUploadRetry_Accepted(t *testing.T) {
+	var got uuid.UUID
+	ts := &mockTaskSvc{
+		retryUploadFunc: func(_ context.Context, id uuid.UUID) error {
+			got = id
+			return nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
+	id := uuid.New()
+
+	req := httptest.NewRequest(http.MethodPost, "/task/"+id.String()+"/upload/retry", nil)
+	req = withChiParam(req, "id", id.String())
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskUploadRetry(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rec.Code)
+	}
+	if got != id {
+		t.Errorf("expected retry of %s, got %s", id, got)
+	}
+}
+
+func TestHandleTaskUploadRetry_Errors(t *testing.T) {
+	cases := []struct {
+		err  error
+		code int
+	}{
+		{domain.ErrTaskNotFound, http.StatusNotFound},
+		{domain.ErrUploadNotFailed, http.StatusConflict},
+		{domain.ErrTaskInProgress, http.StatusConflict},
+		{errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		ts := &mockTaskSvc{
+			retryUploadFunc: func(context.Context, uuid.UUID) error { return tc.err },
+		}
+		srv := testServer(ts, nil, nil)
+		id := uuid.New()
+
+		req := httptest.NewRequest(http.MethodPost, "/task/"+id.String()+"/upload/retry", nil)
+		req = withChiParam(req, "id", id.String())
+		rec := httptest.NewRecorder()
+
+		srv.HandleTaskUploadRetry(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("%v: expected %d, got %d", tc.err, tc.code, rec.Code)
+		}
+	}
+}
+
+func TestHandleTaskUploadRetry_InvalidUUID(t *testing.T) {
+	srv := testServer(nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/task/bad/upload/retry", nil)
+	req = withChiParam(req, "id", "bad")
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskUploadRetry(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
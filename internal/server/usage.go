@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"pinn-connect-service/internal/domain"
+	"time"
+)
+
+// defaultUsagePeriod is the period of the usage report without `from`.
+const defaultUsagePeriod = 30 * 24 * time.Hour
+
+// HandleTaskUsage godoc
+// @Summary      Get resource usage report
+// @Description  Aggregates the resources used by the tasks per model and per day (UTC). A task is counted on the day its container exited
+// @Tags         tasks
+// @Produce      json
+// @Param        from  query     string  false  "Start of the period, RFC 3339 or YYYY-MM-DD (default: 30 days before to)"
+// @Param        to    query     string  false  "End of the period, exclusive, RFC 3339 or YYYY-MM-DD (default: now)"
+// @Success      200  {object}  domain.UsageReportResponse
+// @Failure      400  {string}  string "Invalid period"
+// @Failure      500  {string}  string "Internal server error"
+// @Router       /task/usage [get]
+func (s *Server) HandleTaskUsage(w http.ResponseWriter, r *http.Request) {
+	to := time.Now()
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := parseReportTime(v)
+		if err != nil {
+			http.Error(w, "invalid to: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		to = t
+	}
+
+	from := to.Add(-defaultUsagePeriod)
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := parseReportTime(v)
+		if err != nil {
+			http.Error(w, "invalid from: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		from = t
+	}
+
+	if !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := s.taskService.UsageReport(r.Context(), from, to)
+	if err != nil {
+		slog.Error("getting usage report", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(domain.UsageReportResponse{From: from, To: to, Rows: rows}); err != nil {
+		slog.Error("encoding usage report", "error", err)
+	}
+}
+
+// parseReportTime accepts a RFC 3339 timestamp or a date, a date is the
+// start of the day in UTC.
+func parseReportTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC 3339 or YYYY-MM-DD, got %q", v)
+	}
+	return t, nil
+}
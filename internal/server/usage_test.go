@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"pinn-connect-service/internal/domain"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ─────────────────────────────────────────────
+// HandleTaskUsage
+// ─────────────────────────────────────────────
+
+func TestHandleTaskUsage_Success(t *testing.T) {
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	var gotFrom, gotTo time.Time
+	ts := &mockTaskSvc{
+		usageReportFunc: func(_ context.Context, from, to time.Time) ([]domain.UsageReportRow, error) {
+			gotFrom, gotTo = from, to
+			return []domain.UsageReportRow{{ModelID: "m1", Day: day, Tasks: 2, CPUSeconds: 1.5}}, nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/task/usage?from=2024-01-01&to=2024-01-08T12:00:00Z", nil)
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskUsage(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !gotFrom.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !gotTo.Equal(time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected period %v - %v", gotFrom, gotTo)
+	}
+
+	var resp domain.UsageReportResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Rows) != 1 || resp.Rows[0].ModelID != "m1" || !resp.Rows[0].Day.Equal(day) || resp.Rows[0].CPUSeconds != 1.5 {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestHandleTaskUsage_DefaultPeriod(t *testing.T) {
+	var gotFrom, gotTo time.Time
+	ts := &mockTaskSvc{
+		usageReportFunc: func(_ context.Context, from, to time.Time) ([]domain.UsageReportRow, error) {
+			gotFrom, gotTo = from, to
+			return nil, nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
+
+	rec := httptest.NewRecorder()
+	srv.HandleTaskUsage(rec, httptest.NewRequest(http.MethodGet, "/task/usage", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if time.Since(gotTo) > time.Minute || gotTo.Sub(gotFrom) != defaultUsagePeriod {
+		t.Errorf("unexpected default period %v - %v", gotFrom, gotTo)
+	}
+}
+
+func TestHandleTaskUsage_BadPeriod(t *testing.T) {
+	cases := map[string]string{
+		"invalid from": "/task/usage?from=yesterday",
+		"invalid to":   "/task/usage?to=2024-13-01",
+		"empty period": "/task/usage?from=2024-01-02&to=2024-01-02",
+		"reversed":     "/task/usage?from=2024-01-03&to=2024-01-02",
+	}
+	for name, url := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := testServer(nil, nil, nil)
+			rec := httptest.NewRecorder()
+			srv.HandleTaskUsage(rec, httptest.NewRequest(http.MethodGet, url, nil))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestHandleTaskUsage_ServiceError(t *testing.T) {
+	ts := &mockTaskSvc{
+		usageReportFunc: func(context.Context, time.Time, time.Time) ([]domain.UsageReportRow, error) {
+			return nil, errors.New("db error")
+		},
+	}
+	srv := testServer(ts, nil, nil)
+
+	rec := httptest.NewRecorder()
+	srv.HandleTaskUsage(rec, httptest.NewRequest(http.MethodGet, "/task/usage", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", rec.Code)
+	}
+}
+
+func TestHandleTaskStatus_Usage(t *testing.T) {
+	id := uuid.New()
+	ts := &mockTaskSvc{
+		getTaskFunc: func(context.Context, uuid.UUID) (*domain.Task, error) {
+			return &domain.Task{ID: id, Status: domain.TaskCompleted, Usage: &domain.ResourceUsage{MemPeakBytes: 1 << 20, RunSeconds: 12}}, nil
+		},
+	}
+	srv := testServer(ts, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/task/"+id.String()+"/status", nil)
+	req = withChiParam(req, "id", id.String())
+	rec := httptest.NewRecorder()
+
+	srv.HandleTaskStatus(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var resp domain.TaskStatusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Usage == nil || resp.Usage.MemPeakBytes != 1<<20 || resp.Usage.RunSeconds != 12 {
+		t.Errorf("unexpected usage %+v", resp.Usage)
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"pinn-connect-service/internal/domain"
+	"time"
+)
+
+type RetentionJob interface {
+	Run(context.Context) *domain.RetentionReport
+	LastReport() *domain.RetentionReport
+}
+
+type GCJob interface {
+	Cleanup(context.Context) *domain.GCReport
+	LastReport() *domain.GCReport
+}
+
+type ReconcileJob interface {
+	Run(ctx context.Context, dryRun bool) *domain.ReconcileReport
+}
+
+type SchedulerJob interface {
+	Report() *domain.SchedulerReport
+}
+
+// Leader reports whether this instance runs the singleton jobs.
+type Leader interface {
+	IsLeader() bool
+}
+
+type NodeLister interface {
+	List(ctx context.Context, timeout time.Duration) ([]domain.Node, error)
+}
+
+// AdminService exposes maintenance jobs to the API. Jobs that change shared
+// state only run on the leader, without a leader they run anywhere. The
+// garbage collector cleans up after the node, it is nil if the instance
+// doesn't execute tasks.
+type AdminService struct {
+	retention   RetentionJob
+	gc          GCJob
+	reconcile   ReconcileJob
+	scheduler   SchedulerJob
+	leader      Leader
+	nodes       NodeLister
+	nodeTimeout time.Duration
+}
+
+func NewAdminService(retention RetentionJob, gc GCJob, reconcile ReconcileJob, scheduler SchedulerJob,
+	leader Leader, nodes NodeLister, nodeTimeout time.Duration) *AdminService {
+	return &AdminService{
+		retention:   retention,
+		gc:          gc,
+		reconcile:   reconcile,
+		scheduler:   scheduler,
+		leader:      leader,
+		nodes:       nodes,
+		nodeTimeout: nodeTimeout,
+	}
+}
+
+func (s *AdminService) requireLeader() error {
+	if s.leader != nil && !s.leader.IsLeader() {
+		return domain.ErrNotLeader
+	}
+	return nil
+}
+
+func (s *AdminService) RunRetention(ctx context.Context) (*domain.RetentionReport, error) {
+	if err := s.requireLeader(); err != nil {
+		return nil, err
+	}
+	return s.retention.Run(ctx), nil
+}
+
+func (s *AdminService) LastRetentionReport() *domain.RetentionReport {
+	return s.retention.LastReport()
+}
+
+func (s *AdminService) RunGC(ctx context.Context) (*domain.GCReport, error) {
+	if s.gc == nil {
+		return nil, domain.ErrNotExecuting
+	}
+	return s.gc.Cleanup(ctx), nil
+}
+
+func (s *AdminService) LastGCReport() *domain.GCReport {
+	if s.gc == nil {
+		return nil
+	}
+	return s.gc.LastReport()
+}
+
+// RunReconcile runs the reconciliation. A dry run changes nothing, so it is
+// allowed on any instance.
+func (s *AdminService) RunReconcile(ctx context.Context, dryRun bool) (*domain.ReconcileReport, error) {
+	if !dryRun {
+		if err := s.requireLeader(); err != nil {
+			return nil, err
+		}
+	}
+	return s.reconcile.Run(ctx, dryRun), nil
+}
+
+func (s *AdminService) SchedulerReport() *domain.SchedulerReport {
+	return s.scheduler.Report()
+}
+
+// Nodes lists the nodes executing tasks with their liveness.
+func (s *AdminService) Nodes(ctx context.Context) ([]domain.Node, error) {
+	return s.nodes.List(ctx, s.nodeTimeout)
+}
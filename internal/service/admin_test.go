@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"pinn-connect-service/internal/domain"
+	"testing"
+	"time"
+)
+
+// --- MOCKS ---
+
+type mockGCJob struct {
+	runs int
+}
+
+func (m *mockGCJob) Cleanup(context.Context) *domain.GCReport {
+	m.runs++
+	return &domain.GCReport{}
+}
+
+func (m *mockGCJob) LastReport() *domain.GCReport { return nil }
+
+type mockReconcileJob struct {
+	runs int
+}
+
+func (m *mockReconcileJob) Run(_ context.Context, dryRun bool) *domain.ReconcileReport {
+	m.runs++
+	return &domain.ReconcileReport{DryRun: dryRun}
+}
+
+type mockNodeLister struct {
+	nodes   []domain.Node
+	timeout time.Duration
+}
+
+func (m *mockNodeLister) List(_ context.Context, timeout time.Duration) ([]domain.Node, error) {
+	m.timeout = timeout
+	return m.nodes, nil
+}
+
+type staticLeader bool
+
+func (l staticLeader) IsLeader() bool { return bool(l) }
+
+// --- TESTS ---
+
+func TestAdminService_RunGC_RunsOnEveryNode(t *testing.T) {
+	gc := &mockGCJob{}
+
+	follower := NewAdminService(nil, gc, nil, nil, staticLeader(false), nil, 0)
+	if _, err := follower.RunGC(context.Background()); err != nil || gc.runs != 1 {
+		t.Errorf("expected gc to run on a follower, err=%v runs=%d", err, gc.runs)
+	}
+
+	apiOnly := NewAdminService(nil, nil, nil, nil, staticLeader(true), nil, 0)
+	if _, err := apiOnly.RunGC(context.Background()); !errors.Is(err, domain.ErrNotExecuting) {
+		t.Errorf("expected ErrNotExecuting, got %v", err)
+	}
+	if apiOnly.LastGCReport() != nil {
+		t.Error("expected no gc report on an instance that doesn't execute tasks")
+	}
+}
+
+func TestAdminService_Nodes_UsesTimeout(t *testing.T) {
+	nodes := &mockNodeLister{nodes: []domain.Node{{ID: "node-1", Alive: true}}}
+	svc := NewAdminService(nil, nil, nil, nil, nil, nodes, 30*time.Second)
+
+	got, err := svc.Nodes(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "node-1" {
+		t.Errorf("unexpected nodes: %+v", got)
+	}
+	if nodes.timeout != 30*time.Second {
+		t.Errorf("expected the node timeout to be passed, got %v", nodes.timeout)
+	}
+}
+
+func TestAdminService_RunReconcile_DryRunOnFollower(t *testing.T) {
+	reconcile := &mockReconcileJob{}
+	svc := NewAdminService(nil, nil, reconcile, nil, staticLeader(false), nil, 0)
+
+	if _, err := svc.RunReconcile(context.Background(), true); err != nil {
+		t.Errorf("expected a dry run to be allowed on a follower, got %v", err)
+	}
+	if _, err := svc.RunReconcile(context.Background(), false); !errors.Is(err, domain.ErrNotLeader) {
+		t.Errorf("expected ErrNotLeader, got %v", err)
+	}
+	if reconcile.runs != 1 {
+		t.Errorf("expected a single run, got %d", reconcile.runs)
+	}
+}
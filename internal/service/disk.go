@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"pinn-connect-service/internal/domain"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkFreeSpace rejects new tasks once the disk of the workspaces is nearly
+// full, a running task could otherwise take it and every other task down.
+func (s *TaskService) checkFreeSpace() error {
+	cfg := s.config.Disk
+	if cfg.MinFreeMB == 0 && cfg.MinFreePercent == 0 {
+		return nil
+	}
+
+	free, total, err := s.workspace.FreeSpace()
+	if err != nil {
+		return fmt.Errorf("checking free disk space: %w", err)
+	}
+
+	if free < uint64(cfg.MinFreeMB)<<20 || float64(free) < float64(total)*cfg.MinFreePercent/100 {
+		return fmt.Errorf("%w: %d MB of %d MB free", domain.ErrDiskFull, free>>20, total>>20)
+	}
+
+	return nil
+}
+
+// watchDisk checks the size of the result of the task every
+// DISK_CHECK_INTERVAL and kills its container once the result outgrows the disk
+// limit of the task. The returned func stops the checks and tells whether the
+// result exceeded the limit, checking it once more after the container exited.
+func (s *TaskService) watchDisk(ctx context.Context, task *domain.Task) func() bool {
+	if task.DiskLim <= 0 {
+		return func() bool { return false }
+	}
+
+	limit := int64(task.DiskLim) << 20
+	watchCtx, cancel := context.WithCancel(ctx)
+	ticker := time.NewTicker(s.config.Disk.CheckInterval)
+
+	var exceeded atomic.Bool
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-watchCtx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			size, err := s.workspace.ResultSize(task.ID)
+			if err != nil {
+				slog.Warn("failed to check task disk usage", "task_id", task.ID, "error", err)
+				continue
+			}
+			if size <= limit {
+				continue
+			}
+
+			exceeded.Store(true)
+			slog.Info("task exceeded its disk limit, killing it", "task_id", task.ID, "size", size, "limit", limit)
+			if err := s.manager.StopContainer(watchCtx, task.ContainerID, 0); err != nil {
+				slog.Error("failed to kill task over its disk limit", "task_id", task.ID, "error", err)
+				continue
+			}
+			return
+		}
+	})
+
+	return func() bool {
+		cancel()
+		wg.Wait()
+		if exceeded.Load() {
+			return true
+		}
+
+		// the result may outgrow the limit between two checks
+		size, err := s.workspace.ResultSize(task.ID)
+		return err == nil && size > limit
+	}
+}
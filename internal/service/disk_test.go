@@ -0,0 +1,90 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"pinn-connect-service/internal/domain"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// New tasks are rejected once the disk is nearly full.
+func TestSaveInput_DiskFull(t *testing.T) {
+	tests := []struct {
+		name        string
+		free, total uint64
+	}{
+		{"below min free", 512 << 20, 200 << 30},
+		{"below min free percent", 5 << 30, 200 << 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _, _, ws := defaultSvc()
+			ws.freeFunc = func() (uint64, uint64, error) { return tt.free, tt.total, nil }
+			ws.prepFunc = func(uuid.UUID) error {
+				t.Fatal("expected the workspace not to be prepared")
+				return nil
+			}
+
+			_, err := svc.SaveInput(uuid.New(), "input.txt", bytes.NewBufferString("hello"))
+			if !errors.Is(err, domain.ErrDiskFull) {
+				t.Fatalf("expected ErrDiskFull, got %v", err)
+			}
+		})
+	}
+}
+
+// A task whose result outgrows its disk limit is killed and failed.
+func TestWaitAndSaveTask_DiskQuotaExceeded(t *testing.T) {
+	svc, _, mgr, ws := defaultSvc()
+	ws.sizeFunc = func(uuid.UUID) (int64, error) { return 2 << 20, nil }
+	killed := make(chan struct{})
+	mgr.stopFunc = func(_ context.Context, _ string, timeout time.Duration) error {
+		if timeout != 0 {
+			t.Errorf("expected the container to be killed, got stop timeout %v", timeout)
+		}
+		close(killed)
+		return nil
+	}
+	mgr.waitFunc = func(context.Context, string) (*domain.ContainerExit, error) {
+		<-killed
+		return &domain.ContainerExit{ExitCode: 137, Signal: "SIGKILL"}, nil
+	}
+
+	task := &domain.Task{ID: uuid.New(), ContainerID: "ctr-1", DiskLim: 1}
+	if err := svc.waitAndSaveTask(context.Background(), task); err == nil {
+		t.Fatal("expected error for a task over its disk limit, got nil")
+	}
+	if task.FailureReason != domain.FailureDiskQuota || !strings.Contains(task.ErrorLog, "1 MB") {
+		t.Errorf("expected a disk quota failure, got %q / %q", task.FailureReason, task.ErrorLog)
+	}
+}
+
+// The result is checked once more after the container exited.
+func TestWaitAndSaveTask_DiskQuotaExceededAtExit(t *testing.T) {
+	svc, _, _, ws := defaultSvc()
+	svc.config.Disk.CheckInterval = time.Hour
+	ws.sizeFunc = func(uuid.UUID) (int64, error) { return 2 << 20, nil }
+
+	task := &domain.Task{ID: uuid.New(), ContainerID: "ctr-1", DiskLim: 1}
+	if err := svc.waitAndSaveTask(context.Background(), task); err == nil {
+		t.Fatal("expected error for a result over the disk limit, got nil")
+	}
+	if task.FailureReason != domain.FailureDiskQuota || task.ResultPath != "" {
+		t.Errorf("expected a disk quota failure without upload, got %q / %q", task.FailureReason, task.ResultPath)
+	}
+}
+
+func TestWaitAndSaveTask_WithinDiskLimit(t *testing.T) {
+	svc, _, _, ws := defaultSvc()
+	ws.sizeFunc = func(uuid.UUID) (int64, error) { return 1 << 20, nil }
+
+	task := &domain.Task{ID: uuid.New(), ContainerID: "ctr-1", DiskLim: 1}
+	if err := svc.waitAndSaveTask(context.Background(), task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
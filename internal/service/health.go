@@ -1,6 +1,10 @@
 package service
 
-import "context"
+import (
+	"context"
+	"log/slog"
+	"pinn-connect-service/internal/domain"
+)
 
 type ContainerSystemPinger interface {
 	CheckStatus(context.Context) error
@@ -14,17 +18,32 @@ type DatabasePinger interface {
 	CheckStatus(context.Context) error
 }
 
+type ContainerHosts interface {
+	Hosts() []domain.DockerHost
+}
+
+type Leadership interface {
+	InstanceID() string
+	IsLeader() bool
+	Leader(context.Context) (string, error)
+}
+
 type HealthService struct {
 	containerSystemPinger ContainerSystemPinger
 	storagePinger         StoragePinger
 	databasePinger        DatabasePinger
+	leadership            Leadership
+	containerHosts        ContainerHosts
 }
 
-func NewHealthService(containerSystemPinger ContainerSystemPinger, storagePinger StoragePinger, databasePinger DatabasePinger) *HealthService {
+func NewHealthService(containerSystemPinger ContainerSystemPinger, storagePinger StoragePinger,
+	databasePinger DatabasePinger, leadership Leadership, containerHosts ContainerHosts) *HealthService {
 	return &HealthService{
 		containerSystemPinger: containerSystemPinger,
 		storagePinger:         storagePinger,
 		databasePinger:        databasePinger,
+		leadership:            leadership,
+		containerHosts:        containerHosts,
 	}
 }
 
@@ -43,3 +62,35 @@ func (s *HealthService) CheckStatus(ctx context.Context) error {
 
 	return nil
 }
+
+// LeaderInfo returns the instance running the singleton jobs, or nil without
+// leader election.
+func (s *HealthService) LeaderInfo(ctx context.Context) *domain.LeaderInfo {
+	if s.leadership == nil {
+		return nil
+	}
+
+	info := &domain.LeaderInfo{
+		InstanceID: s.leadership.InstanceID(),
+		IsLeader:   s.leadership.IsLeader(),
+	}
+	if info.IsLeader {
+		info.LeaderID = info.InstanceID
+		return info
+	}
+
+	leader, err := s.leadership.Leader(ctx)
+	if err != nil {
+		slog.Error("health: looking up leader", "error", err)
+	}
+	info.LeaderID = leader
+	return info
+}
+
+// DockerHosts returns the status of the Docker hosts as of their last check.
+func (s *HealthService) DockerHosts() []domain.DockerHost {
+	if s.containerHosts == nil {
+		return nil
+	}
+	return s.containerHosts.Hosts()
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"pinn-connect-service/internal/domain"
 	"testing"
 )
 
@@ -19,6 +20,19 @@ func (m *mockPinger) CheckStatus(ctx context.Context) error {
 	return nil
 }
 
+type mockLeadership struct {
+	instanceID string
+	isLeader   bool
+	leader     string
+	err        error
+}
+
+func (m *mockLeadership) InstanceID() string { return m.instanceID }
+
+func (m *mockLeadership) IsLeader() bool { return m.isLeader }
+
+func (m *mockLeadership) Leader(context.Context) (string, error) { return m.leader, m.err }
+
 // --- TESTS ---
 
 func TestNewHealthService(t *testing.T) {
@@ -26,7 +40,7 @@ func TestNewHealthService(t *testing.T) {
 	storage := &mockPinger{}
 	database := &mockPinger{}
 
-	svc := NewHealthService(container, storage, database)
+	svc := NewHealthService(container, storage, database, nil, nil)
 	if svc == nil {
 		t.Fatal("NewHealthService returned nil")
 	}
@@ -72,7 +86,7 @@ func TestHealthService_CheckStatus(t *testing.T) {
 			storage := &mockPinger{checkStatusFunc: func(ctx context.Context) error { return tt.storageErr }}
 			database := &mockPinger{checkStatusFunc: func(ctx context.Context) error { return tt.databaseErr }}
 
-			svc := NewHealthService(container, storage, database)
+			svc := NewHealthService(container, storage, database, nil, nil)
 			err := svc.CheckStatus(context.Background())
 
 			if !errors.Is(err, tt.wantErr) {
@@ -81,3 +95,46 @@ func TestHealthService_CheckStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestHealthService_LeaderInfo(t *testing.T) {
+	tests := []struct {
+		name       string
+		leadership *mockLeadership
+		want       domain.LeaderInfo
+	}{
+		{
+			name:       "Leader",
+			leadership: &mockLeadership{instanceID: "a", isLeader: true, leader: "ignored"},
+			want:       domain.LeaderInfo{InstanceID: "a", LeaderID: "a", IsLeader: true},
+		},
+		{
+			name:       "Follower",
+			leadership: &mockLeadership{instanceID: "a", leader: "b"},
+			want:       domain.LeaderInfo{InstanceID: "a", LeaderID: "b"},
+		},
+		{
+			name:       "Lookup Failure",
+			leadership: &mockLeadership{instanceID: "a", err: errors.New("db down")},
+			want:       domain.LeaderInfo{InstanceID: "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewHealthService(&mockPinger{}, &mockPinger{}, &mockPinger{}, tt.leadership, nil)
+
+			got := svc.LeaderInfo(context.Background())
+			if got == nil || *got != tt.want {
+				t.Errorf("LeaderInfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthService_LeaderInfo_WithoutElection(t *testing.T) {
+	svc := NewHealthService(&mockPinger{}, &mockPinger{}, &mockPinger{}, nil, nil)
+
+	if got := svc.LeaderInfo(context.Background()); got != nil {
+		t.Errorf("expected no leader info, got %+v", got)
+	}
+}
@@ -15,6 +15,8 @@ type ModelRepository interface {
 	ListModels(context.Context) ([]domain.Model, error)
 	UpdateModel(ctx context.Context, modelID string, newContainerImage string) error
 	Exists(ctx context.Context, id string) (bool, error)
+	SetNetworkMode(ctx context.Context, modelID string, mode domain.NetworkMode) error
+	SetSecurity(ctx context.Context, modelID string, security *domain.ModelSecurity) error
 }
 
 type ModelManager interface {
@@ -76,6 +78,15 @@ func (s *ModelService) RebuildModel(ctx context.Context, modelID string, archive
 	return nil
 }
 
+// GetModel returns the model, nil if it doesn't exist.
+func (s *ModelService) GetModel(ctx context.Context, modelID string) (*domain.Model, error) {
+	model, err := s.repository.GetModelByID(ctx, modelID)
+	if err != nil {
+		return nil, fmt.Errorf("getting model from repo: %w", err)
+	}
+	return model, nil
+}
+
 func (s *ModelService) GetImageByID(ctx context.Context, modelID string) (string, error) {
 	model, err := s.repository.GetModelByID(ctx, modelID)
 	if err != nil {
@@ -156,3 +167,24 @@ func (s *ModelService) UpdateModel(ctx context.Context, modelID string, newConta
 
 	return nil
 }
+
+// SetNetworkMode sets the network mode of the tasks of a model created from
+// now on, an empty mode falls back to the default of the service.
+func (s *ModelService) SetNetworkMode(ctx context.Context, modelID string, mode domain.NetworkMode) error {
+	if err := s.repository.SetNetworkMode(ctx, modelID, mode); err != nil {
+		return fmt.Errorf("setting model network mode: %w", err)
+	}
+	return nil
+}
+
+// SetSecurity sets the security overrides of the tasks of a model started from
+// now on, empty overrides fall back to the profile of the service.
+func (s *ModelService) SetSecurity(ctx context.Context, modelID string, security *domain.ModelSecurity) error {
+	if err := security.Validate(); err != nil {
+		return err
+	}
+	if err := s.repository.SetSecurity(ctx, modelID, security); err != nil {
+		return fmt.Errorf("setting model security: %w", err)
+	}
+	return nil
+}
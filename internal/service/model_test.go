@@ -731,3 +731,23 @@ func TestUpdateModel_RepoError(t *testing.T) {
 		t.Errorf("unexpected error message: %v", err)
 	}
 }
+
+// ─────────────────────────────────────────────
+// SetSecurity
+// ─────────────────────────────────────────────
+
+func TestSetSecurity_Invalid(t *testing.T) {
+	svc := NewModelService(&fakeModelRepo{}, nil)
+	pids := int64(-1)
+
+	tests := []*domain.ModelSecurity{
+		{CapAdd: []string{"ALL"}},
+		{CapAdd: []string{"net_admin"}},
+		{PidsLimit: &pids},
+	}
+	for _, sec := range tests {
+		if err := svc.SetSecurity(context.Background(), "m1", sec); !errors.Is(err, domain.ErrInvalidSecurity) {
+			t.Errorf("expected ErrInvalidSecurity for %+v, got %v", sec, err)
+		}
+	}
+}
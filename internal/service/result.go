@@ -0,0 +1,201 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"pinn-connect-service/internal/domain"
+	"pinn-connect-service/internal/storage"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ListResultFiles returns all result objects of a completed task.
+func (s *TaskService) ListResultFiles(ctx context.Context, id uuid.UUID) ([]domain.ResultFile, error) {
+	task, err := s.getCompletedTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.listResultFiles(ctx, storage.ResultPrefix(task.ResultPath))
+}
+
+func (s *TaskService) listResultFiles(ctx context.Context, prefix string) ([]domain.ResultFile, error) {
+	artifacts, err := s.storage.ListArtifacts(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("listing result artifacts: %w", err)
+	}
+
+	recorded, err := s.repository.ListArtifactChecksums(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	// listings report stored sizes, the recorded ones are the sizes of the
+	// content even for encrypted objects
+	checksums := make(map[string]domain.Artifact, len(recorded))
+	for _, r := range recorded {
+		checksums[r.Key] = r
+	}
+
+	files := make([]domain.ResultFile, 0, len(artifacts))
+	for _, a := range artifacts {
+		size := a.Size
+		if r, ok := checksums[a.Key]; ok {
+			size = r.Size
+		}
+		files = append(files, domain.ResultFile{
+			Path:         strings.TrimPrefix(a.Key, prefix),
+			Size:         size,
+			ContentType:  a.ContentType,
+			LastModified: a.LastModified,
+			SHA256:       checksums[a.Key].SHA256,
+		})
+	}
+
+	return files, nil
+}
+
+// OpenResultFile opens a single result object of a completed task.
+// The caller must close the returned reader.
+func (s *TaskService) OpenResultFile(ctx context.Context, id uuid.UUID, name string) (io.ReadSeekCloser, *domain.ResultFile, error) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return nil, nil, domain.ErrArtifactNotFound
+	}
+
+	task, err := s.getCompletedTask(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rc, artifact, err := s.storage.OpenArtifact(ctx, storage.ResultPrefix(task.ResultPath)+name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening result artifact: %w", err)
+	}
+
+	return rc, &domain.ResultFile{
+		Path:         name,
+		Size:         artifact.Size,
+		ContentType:  artifact.ContentType,
+		LastModified: artifact.LastModified,
+		SHA256:       artifact.SHA256,
+	}, nil
+}
+
+// OpenResultArchive lists the result objects of a completed task and returns
+// a func streaming them into w as an archive of the given format. Errors
+// about the task are returned before anything is written. Nothing is
+// buffered on disk.
+func (s *TaskService) OpenResultArchive(ctx context.Context, id uuid.UUID, format domain.ArchiveFormat) (func(w io.Writer) error, error) {
+	task, err := s.getCompletedTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := storage.ResultPrefix(task.ResultPath)
+
+	files, err := s.listResultFiles(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case domain.ArchiveZip:
+		return func(w io.Writer) error { return s.writeZip(ctx, prefix, files, w) }, nil
+	case domain.ArchiveTarGz:
+		return func(w io.Writer) error { return s.writeTarGz(ctx, prefix, files, w) }, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive format: %s", format)
+	}
+}
+
+func (s *TaskService) writeZip(ctx context.Context, prefix string, files []domain.ResultFile, w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	for _, f := range files {
+		entry, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.Path,
+			Method:   zip.Deflate,
+			Modified: f.LastModified,
+		})
+		if err != nil {
+			return fmt.Errorf("creating zip entry: %w", err)
+		}
+
+		if err := s.copyArtifact(ctx, prefix+f.Path, entry); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("closing zip writer: %w", err)
+	}
+
+	return nil
+}
+
+func (s *TaskService) writeTarGz(ctx context.Context, prefix string, files []domain.ResultFile, w io.Writer) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	for _, f := range files {
+		err := tw.WriteHeader(&tar.Header{
+			Name:    f.Path,
+			Mode:    0o644,
+			Size:    f.Size,
+			ModTime: f.LastModified,
+		})
+		if err != nil {
+			return fmt.Errorf("writing tar header: %w", err)
+		}
+
+		if err := s.copyArtifact(ctx, prefix+f.Path, tw); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("closing tar writer: %w", err)
+	}
+	if err := gw.Close(); err != nil {
+		return fmt.Errorf("closing gzip writer: %w", err)
+	}
+
+	return nil
+}
+
+func (s *TaskService) copyArtifact(ctx context.Context, objectKey string, w io.Writer) error {
+	rc, _, err := s.storage.OpenArtifact(ctx, objectKey)
+	if err != nil {
+		return fmt.Errorf("opening artifact %s: %w", objectKey, err)
+	}
+	defer rc.Close()
+
+	if _, err := io.Copy(w, rc); err != nil {
+		return fmt.Errorf("copying artifact %s: %w", objectKey, err)
+	}
+
+	return nil
+}
+
+func (s *TaskService) getCompletedTask(ctx context.Context, id uuid.UUID) (*domain.Task, error) {
+	task, err := s.repository.GetTaskById(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting task from repo: %w", err)
+	}
+
+	if task == nil {
+		return nil, domain.ErrTaskNotFound
+	}
+
+	if task.Status != domain.TaskCompleted || storage.ResultPrefix(task.ResultPath) == "" {
+		return nil, domain.ErrResultNotReady
+	}
+
+	return task, nil
+}
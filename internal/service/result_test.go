@@ -0,0 +1,262 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"pinn-connect-service/internal/domain"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// ─────────────────────────────────────────────
+// HELPERS
+// ─────────────────────────────────────────────
+
+type nopSeekCloser struct{ io.ReadSeeker }
+
+func (nopSeekCloser) Close() error { return nil }
+
+// resultSvc builds a service whose storage holds the given objects and whose
+// repository returns a completed task pointing into "tasks/<owner>/".
+func resultSvc(owner uuid.UUID, objects map[string]string) (*TaskService, *mockRepository) {
+	svc, repo, _, _ := defaultSvc()
+	repo.getByIdFunc = func(_ context.Context, id uuid.UUID) (*domain.Task, error) {
+		return &domain.Task{ID: id, Status: domain.TaskCompleted, ResultPath: "tasks/" + owner.String() + "/result.txt"}, nil
+	}
+	svc.storage = &mockArtifactStorage{
+		listFunc: func(_ context.Context, prefix string) ([]domain.Artifact, error) {
+			var res []domain.Artifact
+			for k, v := range objects {
+				if strings.HasPrefix(k, prefix) {
+					res = append(res, domain.Artifact{Key: k, Size: int64(len(v))})
+				}
+			}
+			return res, nil
+		},
+		openFunc: func(_ context.Context, key string) (io.ReadSeekCloser, *domain.Artifact, error) {
+			v, ok := objects[key]
+			if !ok {
+				return nil, nil, domain.ErrArtifactNotFound
+			}
+			return nopSeekCloser{strings.NewReader(v)}, &domain.Artifact{Key: key, Size: int64(len(v))}, nil
+		},
+	}
+	return svc, repo
+}
+
+// ─────────────────────────────────────────────
+// ListResultFiles
+// ─────────────────────────────────────────────
+
+// Paths must be relative to the result prefix of the task that produced the
+// result, which differs from the requested task for cached results.
+func TestListResultFiles_CachedTask_UsesOwnerPrefix(t *testing.T) {
+	owner := uuid.New()
+	svc, _ := resultSvc(owner, map[string]string{
+		"tasks/" + owner.String() + "/result.txt":  "a",
+		"tasks/" + owner.String() + "/sub/log.txt": "bb",
+	})
+
+	files, err := svc.ListResultFiles(context.Background(), uuid.New())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+	for _, f := range files {
+		if strings.HasPrefix(f.Path, "tasks/") {
+			t.Errorf("expected relative path, got %q", f.Path)
+		}
+	}
+}
+
+func TestListResultFiles_TaskNotFound(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	repo.getByIdFunc = func(context.Context, uuid.UUID) (*domain.Task, error) { return nil, nil }
+
+	if _, err := svc.ListResultFiles(context.Background(), uuid.New()); !errors.Is(err, domain.ErrTaskNotFound) {
+		t.Fatalf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestListResultFiles_NotCompleted(t *testing.T) {
+	svc, _, _, _ := defaultSvc() // default repo returns a queued task
+
+	if _, err := svc.ListResultFiles(context.Background(), uuid.New()); !errors.Is(err, domain.ErrResultNotReady) {
+		t.Fatalf("expected ErrResultNotReady, got %v", err)
+	}
+}
+
+func TestListResultFiles_StorageError(t *testing.T) {
+	svc, _ := resultSvc(uuid.New(), nil)
+	svc.storage = &mockArtifactStorage{
+		listFunc: func(context.Context, string) ([]domain.Artifact, error) { return nil, errors.New("list error") },
+	}
+
+	if _, err := svc.ListResultFiles(context.Background(), uuid.New()); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestListResultFiles_IncludesChecksums(t *testing.T) {
+	owner := uuid.New()
+	prefix := "tasks/" + owner.String() + "/"
+	svc, repo := resultSvc(owner, map[string]string{prefix + "result.txt": "a"})
+	withChecksums(repo, map[string]string{prefix + "result.txt": "abc"})
+
+	files, err := svc.ListResultFiles(context.Background(), uuid.New())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 1 || files[0].SHA256 != "abc" {
+		t.Errorf("expected the recorded checksum, got %+v", files)
+	}
+}
+
+// Encrypted objects are listed with their stored size, the recorded size of
+// the content is reported instead.
+func TestListResultFiles_UsesRecordedSize(t *testing.T) {
+	owner := uuid.New()
+	prefix := "tasks/" + owner.String() + "/"
+	svc, repo := resultSvc(owner, map[string]string{prefix + "result.txt": "ciphertext"})
+	repo.listSumsFunc = func(context.Context, string) ([]domain.Artifact, error) {
+		return []domain.Artifact{{Key: prefix + "result.txt", Size: 4, SHA256: "abc"}}, nil
+	}
+
+	files, err := svc.ListResultFiles(context.Background(), uuid.New())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 1 || files[0].Size != 4 {
+		t.Errorf("expected the recorded size, got %+v", files)
+	}
+}
+
+// ─────────────────────────────────────────────
+// OpenResultFile
+// ─────────────────────────────────────────────
+
+func TestOpenResultFile_Success(t *testing.T) {
+	owner := uuid.New()
+	svc, _ := resultSvc(owner, map[string]string{"tasks/" + owner.String() + "/sub/log.txt": "log"})
+
+	rc, file, err := svc.OpenResultFile(context.Background(), uuid.New(), "sub/log.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+
+	data, _ := io.ReadAll(rc)
+	if string(data) != "log" || file.Path != "sub/log.txt" {
+		t.Errorf("unexpected file %+v with content %q", file, data)
+	}
+}
+
+// Paths escaping the result prefix must be confined to it.
+func TestOpenResultFile_PathTraversal(t *testing.T) {
+	owner := uuid.New()
+	var gotKey string
+	svc, _ := resultSvc(owner, nil)
+	svc.storage = &mockArtifactStorage{
+		openFunc: func(_ context.Context, key string) (io.ReadSeekCloser, *domain.Artifact, error) {
+			gotKey = key
+			return nil, nil, domain.ErrArtifactNotFound
+		},
+	}
+
+	_, _, err := svc.OpenResultFile(context.Background(), uuid.New(), "../../other/secret.txt")
+	if !errors.Is(err, domain.ErrArtifactNotFound) {
+		t.Fatalf("expected ErrArtifactNotFound, got %v", err)
+	}
+	if gotKey != "tasks/"+owner.String()+"/other/secret.txt" {
+		t.Errorf("expected key confined to result prefix, got %q", gotKey)
+	}
+}
+
+func TestOpenResultFile_EmptyName(t *testing.T) {
+	svc, _ := resultSvc(uuid.New(), nil)
+
+	if _, _, err := svc.OpenResultFile(context.Background(), uuid.New(), "/"); !errors.Is(err, domain.ErrArtifactNotFound) {
+		t.Fatalf("expected ErrArtifactNotFound, got %v", err)
+	}
+}
+
+// ─────────────────────────────────────────────
+// OpenResultArchive
+// ─────────────────────────────────────────────
+
+func TestOpenResultArchive_Zip(t *testing.T) {
+	owner := uuid.New()
+	svc, _ := resultSvc(owner, map[string]string{
+		"tasks/" + owner.String() + "/result.txt":  "result",
+		"tasks/" + owner.String() + "/sub/log.txt": "log",
+	})
+
+	write, err := svc.OpenResultArchive(context.Background(), uuid.New(), domain.ArchiveZip)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("reading zip: %v", err)
+	}
+	got := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		got[f.Name] = string(data)
+	}
+	if got["result.txt"] != "result" || got["sub/log.txt"] != "log" {
+		t.Errorf("unexpected zip content: %v", got)
+	}
+}
+
+func TestOpenResultArchive_TarGz(t *testing.T) {
+	owner := uuid.New()
+	svc, _ := resultSvc(owner, map[string]string{"tasks/" + owner.String() + "/result.txt": "result"})
+
+	write, err := svc.OpenResultArchive(context.Background(), uuid.New(), domain.ArchiveTarGz)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	gr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("reading gzip: %v", err)
+	}
+	tr := tar.NewReader(gr)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatalf("reading tar: %v", err)
+	}
+	data, _ := io.ReadAll(tr)
+	if hdr.Name != "result.txt" || string(data) != "result" {
+		t.Errorf("unexpected tar entry %q with content %q", hdr.Name, data)
+	}
+}
+
+func TestOpenResultArchive_UnsupportedFormat(t *testing.T) {
+	svc, _ := resultSvc(uuid.New(), nil)
+
+	if _, err := svc.OpenResultArchive(context.Background(), uuid.New(), "rar"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
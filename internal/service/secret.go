@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"pinn-connect-service/internal/domain"
+	"sort"
+)
+
+type SecretRepository interface {
+	ListSecrets(ctx context.Context) ([]domain.Secret, error)
+	GetSecrets(ctx context.Context, names []string) ([]domain.SealedSecret, error)
+	CreateSecret(ctx context.Context, secret domain.SealedSecret) error
+	UpdateSecret(ctx context.Context, secret domain.SealedSecret) error
+	DeleteSecret(ctx context.Context, name string) error
+}
+
+// SecretCipher encrypts the values of secrets before they are stored.
+type SecretCipher interface {
+	Seal(plaintext, ad []byte) (keyID string, sealed []byte, err error)
+	Open(keyID string, sealed, ad []byte) ([]byte, error)
+}
+
+// SecretService stores secrets encrypted and resolves the secrets of a task
+// when its container starts. Without a cipher secrets are disabled.
+type SecretService struct {
+	repository SecretRepository
+	cipher     SecretCipher
+}
+
+func NewSecretService(repository SecretRepository, cipher SecretCipher) *SecretService {
+	return &SecretService{
+		repository: repository,
+		cipher:     cipher,
+	}
+}
+
+func (s *SecretService) ListSecrets(ctx context.Context) ([]domain.Secret, error) {
+	if s.cipher == nil {
+		return nil, domain.ErrSecretsDisabled
+	}
+	return s.repository.ListSecrets(ctx)
+}
+
+func (s *SecretService) CreateSecret(ctx context.Context, name, value string) error {
+	secret, err := s.seal(name, value)
+	if err != nil {
+		return err
+	}
+	return s.repository.CreateSecret(ctx, secret)
+}
+
+func (s *SecretService) UpdateSecret(ctx context.Context, name, value string) error {
+	secret, err := s.seal(name, value)
+	if err != nil {
+		return err
+	}
+	return s.repository.UpdateSecret(ctx, secret)
+}
+
+func (s *SecretService) DeleteSecret(ctx context.Context, name string) error {
+	if s.cipher == nil {
+		return domain.ErrSecretsDisabled
+	}
+	return s.repository.DeleteSecret(ctx, name)
+}
+
+// CheckSecrets verifies that the secrets referenced by a task exist, so a
+// task with a typo fails at creation rather than when its container starts.
+func (s *SecretService) CheckSecrets(ctx context.Context, refs map[string]string) error {
+	_, err := s.getSecrets(ctx, refs)
+	return err
+}
+
+// ResolveSecrets returns the secrets of a task as environment variables in
+// the KEY=value form, sorted by name.
+func (s *SecretService) ResolveSecrets(ctx context.Context, refs map[string]string) ([]string, error) {
+	secrets, err := s.getSecrets(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	envs := make([]string, 0, len(refs))
+	for env, name := range refs {
+		secret := secrets[name]
+		value, err := s.cipher.Open(secret.KeyID, secret.Value, []byte(name))
+		if err != nil {
+			return nil, fmt.Errorf("decrypting secret %q: %w", name, err)
+		}
+		envs = append(envs, env+"="+string(value))
+	}
+	sort.Strings(envs)
+
+	return envs, nil
+}
+
+func (s *SecretService) getSecrets(ctx context.Context, refs map[string]string) (map[string]domain.SealedSecret, error) {
+	if s.cipher == nil {
+		return nil, domain.ErrSecretsDisabled
+	}
+	if err := domain.ValidateSecretRefs(refs); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(refs))
+	for _, name := range refs {
+		names = append(names, name)
+	}
+
+	found, err := s.repository.GetSecrets(ctx, names)
+	if err != nil {
+		return nil, fmt.Errorf("getting secrets: %w", err)
+	}
+
+	secrets := make(map[string]domain.SealedSecret, len(found))
+	for _, secret := range found {
+		secrets[secret.Name] = secret
+	}
+	for _, name := range names {
+		if _, ok := secrets[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", domain.ErrSecretNotFound, name)
+		}
+	}
+
+	return secrets, nil
+}
+
+// seal encrypts the value of a secret. The name is bound to the ciphertext,
+// a value copied to another secret in the database doesn't decrypt.
+func (s *SecretService) seal(name, value string) (domain.SealedSecret, error) {
+	if s.cipher == nil {
+		return domain.SealedSecret{}, domain.ErrSecretsDisabled
+	}
+	if err := domain.ValidateSecretName(name); err != nil {
+		return domain.SealedSecret{}, err
+	}
+
+	keyID, sealed, err := s.cipher.Seal([]byte(value), []byte(name))
+	if err != nil {
+		return domain.SealedSecret{}, fmt.Errorf("encrypting secret: %w", err)
+	}
+
+	return domain.SealedSecret{Name: name, KeyID: keyID, Value: sealed}, nil
+}
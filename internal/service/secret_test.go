@@ -0,0 +1,218 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"pinn-connect-service/internal/domain"
+	"pinn-connect-service/internal/envelope"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// mockSecretRepo keeps the sealed secrets in memory.
+type mockSecretRepo struct {
+	secrets map[string]domain.SealedSecret
+}
+
+func (m *mockSecretRepo) ListSecrets(context.Context) ([]domain.Secret, error) {
+	var result []domain.Secret
+	for name := range m.secrets {
+		result = append(result, domain.Secret{Name: name})
+	}
+	return result, nil
+}
+
+func (m *mockSecretRepo) GetSecrets(_ context.Context, names []string) ([]domain.SealedSecret, error) {
+	var result []domain.SealedSecret
+	for _, name := range names {
+		if secret, ok := m.secrets[name]; ok {
+			result = append(result, secret)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockSecretRepo) CreateSecret(_ context.Context, secret domain.SealedSecret) error {
+	if _, ok := m.secrets[secret.Name]; ok {
+		return domain.ErrSecretExists
+	}
+	m.secrets[secret.Name] = secret
+	return nil
+}
+
+func (m *mockSecretRepo) UpdateSecret(_ context.Context, secret domain.SealedSecret) error {
+	if _, ok := m.secrets[secret.Name]; !ok {
+		return domain.ErrSecretNotFound
+	}
+	m.secrets[secret.Name] = secret
+	return nil
+}
+
+func (m *mockSecretRepo) DeleteSecret(_ context.Context, name string) error {
+	if _, ok := m.secrets[name]; !ok {
+		return domain.ErrSecretNotFound
+	}
+	delete(m.secrets, name)
+	return nil
+}
+
+func newSecretSvc(t *testing.T) (*SecretService, *mockSecretRepo) {
+	t.Helper()
+	keyring, err := envelope.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, envelope.KeySize)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &mockSecretRepo{secrets: map[string]domain.SealedSecret{}}
+	return NewSecretService(repo, keyring), repo
+}
+
+func TestSecretService_StoresValuesEncrypted(t *testing.T) {
+	svc, repo := newSecretSvc(t)
+	ctx := context.Background()
+
+	if err := svc.CreateSecret(ctx, "api-key", "s3cr3t"); err != nil {
+		t.Fatalf("CreateSecret: %v", err)
+	}
+	if stored := repo.secrets["api-key"]; stored.KeyID != "k1" || bytes.Contains(stored.Value, []byte("s3cr3t")) {
+		t.Errorf("expected the value to be sealed by k1, got %+v", stored)
+	}
+	if err := svc.UpdateSecret(ctx, "api-key", "n3w"); err != nil {
+		t.Fatalf("UpdateSecret: %v", err)
+	}
+
+	envs, err := svc.ResolveSecrets(ctx, map[string]string{"API_KEY": "api-key", "TOKEN": "api-key"})
+	if err != nil {
+		t.Fatalf("ResolveSecrets: %v", err)
+	}
+	if want := []string{"API_KEY=n3w", "TOKEN=n3w"}; !slices.Equal(envs, want) {
+		t.Errorf("expected %v, got %v", want, envs)
+	}
+}
+
+// A value copied to another secret in the database must not decrypt.
+func TestSecretService_ValueBoundToName(t *testing.T) {
+	svc, repo := newSecretSvc(t)
+	ctx := context.Background()
+
+	if err := svc.CreateSecret(ctx, "a", "value-a"); err != nil {
+		t.Fatal(err)
+	}
+	copied := repo.secrets["a"]
+	copied.Name = "b"
+	repo.secrets["b"] = copied
+
+	if _, err := svc.ResolveSecrets(ctx, map[string]string{"B": "b"}); !errors.Is(err, envelope.ErrAuthFailed) {
+		t.Errorf("expected ErrAuthFailed, got %v", err)
+	}
+}
+
+func TestSecretService_Errors(t *testing.T) {
+	svc, _ := newSecretSvc(t)
+	ctx := context.Background()
+
+	if err := svc.CreateSecret(ctx, "bad name", "x"); !errors.Is(err, domain.ErrInvalidSecretName) {
+		t.Errorf("expected ErrInvalidSecretName, got %v", err)
+	}
+	if err := svc.CheckSecrets(ctx, map[string]string{"API_KEY": "missing"}); !errors.Is(err, domain.ErrSecretNotFound) {
+		t.Errorf("expected ErrSecretNotFound, got %v", err)
+	}
+	if err := svc.CheckSecrets(ctx, map[string]string{"1BAD": "api-key"}); !errors.Is(err, domain.ErrInvalidSecretRef) {
+		t.Errorf("expected ErrInvalidSecretRef, got %v", err)
+	}
+
+	disabled := NewSecretService(&mockSecretRepo{}, nil)
+	if err := disabled.CreateSecret(ctx, "api-key", "x"); !errors.Is(err, domain.ErrSecretsDisabled) {
+		t.Errorf("expected ErrSecretsDisabled, got %v", err)
+	}
+	if _, err := disabled.ResolveSecrets(ctx, map[string]string{"API_KEY": "api-key"}); !errors.Is(err, domain.ErrSecretsDisabled) {
+		t.Errorf("expected ErrSecretsDisabled, got %v", err)
+	}
+}
+
+func TestCreateTask_Secrets(t *testing.T) {
+	secrets, _ := newSecretSvc(t)
+	if err := secrets.CreateSecret(context.Background(), "api-key", "s3cr3t"); err != nil {
+		t.Fatal(err)
+	}
+	svc, _, _, _ := defaultSvc()
+	svc.secretService = secrets
+
+	plain := &domain.Task{ModelID: "m1"}
+	if err := svc.CreateTask(context.Background(), plain, []byte("hash")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	task := &domain.Task{ModelID: "m1", Secrets: map[string]string{"API_KEY": "api-key"}}
+	if err := svc.CreateTask(context.Background(), task, []byte("hash")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// secrets don't change the signature, the task shares the cache
+	if task.Signature != plain.Signature {
+		t.Errorf("expected the signature of the task without secrets, got %q and %q", task.Signature, plain.Signature)
+	}
+
+	missing := &domain.Task{ModelID: "m1", Secrets: map[string]string{"API_KEY": "missing"}}
+	if err := svc.CreateTask(context.Background(), missing, []byte("hash")); !errors.Is(err, domain.ErrSecretNotFound) {
+		t.Errorf("expected ErrSecretNotFound, got %v", err)
+	}
+}
+
+func TestProcessTask_InjectsSecrets(t *testing.T) {
+	secrets, _ := newSecretSvc(t)
+	if err := secrets.CreateSecret(context.Background(), "api-key", "s3cr3t"); err != nil {
+		t.Fatal(err)
+	}
+	svc, _, mgr, _ := defaultSvc()
+	svc.secretService = secrets
+
+	var envs []string
+	mgr.startFunc = func(_ context.Context, c *domain.ContainerConfig) (string, error) {
+		envs = c.Envs
+		return "cid", nil
+	}
+
+	task := &domain.Task{
+		ID:             uuid.New(),
+		ContainerImage: "img:latest",
+		ContainerEnvs:  []string{"MODE=fast"},
+		Secrets:        map[string]string{"API_KEY": "api-key"},
+	}
+	if err := svc.processTask(context.Background(), task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"MODE=fast", "API_KEY=s3cr3t"}; !slices.Equal(envs, want) {
+		t.Errorf("expected container envs %v, got %v", want, envs)
+	}
+	if len(task.ContainerEnvs) != 1 {
+		t.Errorf("secret values leaked into the task: %v", task.ContainerEnvs)
+	}
+}
+
+func TestProcessTask_MissingSecret_FailsTask(t *testing.T) {
+	secrets, _ := newSecretSvc(t)
+	svc, repo, mgr, _ := defaultSvc()
+	svc.secretService = secrets
+
+	mgr.startFunc = func(context.Context, *domain.ContainerConfig) (string, error) {
+		t.Error("container must not start without its secrets")
+		return "", nil
+	}
+	var failed *domain.Task
+	repo.markFunc = func(_ context.Context, task *domain.Task, s domain.TaskStatus) error {
+		if s == domain.TaskFailed {
+			failed = task
+		}
+		return nil
+	}
+
+	task := &domain.Task{ID: uuid.New(), ContainerImage: "img:latest", Secrets: map[string]string{"API_KEY": "deleted"}}
+	err := svc.processTask(context.Background(), task)
+	if !errors.Is(err, domain.ErrSecretNotFound) {
+		t.Fatalf("expected ErrSecretNotFound, got %v", err)
+	}
+	if failed == nil || failed.FailureReason != domain.FailureContainerStart {
+		t.Errorf("expected a container start failure, got %+v", failed)
+	}
+}
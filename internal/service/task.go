@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -12,7 +13,9 @@ import (
 	"path/filepath"
 	"pinn-connect-service/internal/config"
 	"pinn-connect-service/internal/domain"
+	"pinn-connect-service/internal/storage"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -26,26 +29,40 @@ type ContainerManager interface {
 	GetContainerLogs(ctx context.Context, containerID string, follow bool) (io.ReadCloser, error)
 	RemoveContainer(context.Context, string) error
 	StopContainer(ctx context.Context, id string, timeout time.Duration) error
-	WaitContainer(context.Context, string) (int64, error)
+	WaitContainer(context.Context, string) (*domain.ContainerExit, error)
 	GetContainerState(context.Context, string) (*domain.ContainerState, error)
+	ContainerStats(context.Context, string) (*domain.ContainerStats, error)
+	StreamContainerStats(ctx context.Context, id string, fn func(*domain.ContainerStats) error) error
 }
 
 type ArtifactStorage interface {
-	UploadToStorage(ctx context.Context, taskID uuid.UUID, resultDir string) (string, error)
-	GetDownloadURL(ctx context.Context, objectKey string) (string, error)
+	UploadToStorage(ctx context.Context, taskID uuid.UUID, resultDir string, progress domain.UploadProgressFunc) (*domain.UploadResult, error)
+	GetDownloadURL(ctx context.Context, objectKey string, expiry time.Duration) (string, error)
 	DeleteArtifacts(ctx context.Context, taskID uuid.UUID) error
+	ListArtifacts(ctx context.Context, prefix string) ([]domain.Artifact, error)
+	OpenArtifact(ctx context.Context, objectKey string) (io.ReadSeekCloser, *domain.Artifact, error)
 }
 
 type TaskRepository interface {
-	Create(context.Context, *domain.Task) error
+	Create(context.Context, *domain.Task, domain.StatusChange) error
 	GetTaskById(context.Context, uuid.UUID) (*domain.Task, error)
 	FindCachedTask(context.Context, string) (string, error)
-	Mark(context.Context, *domain.Task, domain.TaskStatus) error
-	GetNextQueuedTask(context.Context) (*domain.Task, error)
-	GetScheduledTasks(context.Context, time.Time) ([]domain.Task, error)
-	GetTasksPaginated(context.Context, int32, int32) ([]domain.Task, error)
-	GetTasksCount(context.Context) (int64, error)
+	Mark(context.Context, *domain.Task, domain.TaskStatus, domain.StatusChange) error
+	GetNextQueuedTask(ctx context.Context, nodeID string, lease time.Duration) (*domain.Task, error)
+	RenewLease(ctx context.Context, id uuid.UUID, nodeID string, lease time.Duration) (bool, error)
+	GetTasksPaginated(context.Context, int32, int32, domain.TaskFilter) ([]domain.Task, error)
+	GetTasksCount(context.Context, domain.TaskFilter) (int64, error)
 	DeleteTask(context.Context, uuid.UUID) error
+	SetPinned(ctx context.Context, id uuid.UUID, pinned bool) (*domain.Task, error)
+	SetUploadProgress(ctx context.Context, id uuid.UUID, total, done int64) error
+	SaveArtifactChecksums(ctx context.Context, taskID uuid.UUID, objects []domain.Artifact) error
+	ListArtifactChecksums(ctx context.Context, prefix string) ([]domain.Artifact, error)
+	DeleteArtifactChecksums(ctx context.Context, prefix string) error
+	CountResultRefs(ctx context.Context, id uuid.UUID, prefix string) (int64, error)
+	SetResultCorrupted(ctx context.Context, prefix string, corrupted bool) error
+	ListTaskEvents(ctx context.Context, taskID uuid.UUID) ([]domain.TaskEvent, error)
+	SaveTaskUsage(ctx context.Context, id uuid.UUID, usage domain.ResourceUsage) error
+	UsageReport(ctx context.Context, from, to time.Time) ([]domain.UsageReportRow, error)
 }
 
 type Workspace interface {
@@ -53,8 +70,19 @@ type Workspace interface {
 	ResultDir(uuid.UUID) string
 	Cleanup(uuid.UUID) error
 	SaveInput(taskID uuid.UUID, filename string, r io.Reader) error
+	ResultSize(uuid.UUID) (int64, error)
+	FreeSpace() (free, total uint64, err error)
 }
 
+// errLeaseLost cancels the processing of a task reclaimed by another node.
+// The new owner takes care of the container and the workspace.
+var errLeaseLost = errors.New("task lease lost")
+
+// errShutdown ends the processing of a running task when the node shuts down.
+// The task is left running, its lease expires and the GC of a node reclaims
+// it with the container and the workspace.
+var errShutdown = errors.New("node is shutting down")
+
 type TaskService struct {
 	manager          ContainerManager
 	config           *config.Config
@@ -62,8 +90,14 @@ type TaskService struct {
 	repository       TaskRepository
 	workspace        Workspace
 	modelService     *ModelService
+	secretService    *SecretService
 	recoverTaskQueue []*domain.Task
+	uploadQueue      []*domain.Task
 	recoverMu        sync.Mutex
+	processing       map[uuid.UUID]struct{}
+	processingMu     sync.Mutex
+	// wakeCh wakes up the worker when a slot is freed or a task is recovered
+	wakeCh chan struct{}
 }
 
 func NewTaskService(
@@ -72,7 +106,8 @@ func NewTaskService(
 	config *config.Config,
 	repository TaskRepository,
 	workspace Workspace,
-	modelService *ModelService) *TaskService {
+	modelService *ModelService,
+	secretService *SecretService) *TaskService {
 	return &TaskService{
 		manager:          manager,
 		storage:          storage,
@@ -80,12 +115,20 @@ func NewTaskService(
 		repository:       repository,
 		workspace:        workspace,
 		modelService:     modelService,
+		secretService:    secretService,
 		recoverTaskQueue: make([]*domain.Task, 0),
+		uploadQueue:      make([]*domain.Task, 0),
 		recoverMu:        sync.Mutex{},
+		processing:       make(map[uuid.UUID]struct{}),
+		wakeCh:           make(chan struct{}, 1),
 	}
 }
 
 func (s *TaskService) SaveInput(taskID uuid.UUID, filename string, r io.Reader) ([]byte, error) {
+	if err := s.checkFreeSpace(); err != nil {
+		return nil, err
+	}
+
 	if err := s.workspace.Prepare(taskID); err != nil {
 		return nil, fmt.Errorf("preparing task workspace: %w", err)
 	}
@@ -100,6 +143,65 @@ func (s *TaskService) SaveInput(taskID uuid.UUID, filename string, r io.Reader)
 	return hasher.Sum(nil), nil
 }
 
+// SaveParams writes the params of a task to its input dir and returns them in
+// canonical form: compact, with the keys of every object sorted, so the same
+// params always give the same signature. A literal null is no params, nothing
+// is written for it.
+func (s *TaskService) SaveParams(taskID uuid.UUID, params []byte) ([]byte, error) {
+	canonical, err := canonicalParams(params)
+	if err != nil || canonical == nil {
+		return nil, err
+	}
+
+	if err := s.checkFreeSpace(); err != nil {
+		return nil, err
+	}
+
+	if err := s.workspace.Prepare(taskID); err != nil {
+		return nil, fmt.Errorf("preparing task workspace: %w", err)
+	}
+
+	if err := s.workspace.SaveInput(taskID, domain.ParamsFilename, bytes.NewReader(canonical)); err != nil {
+		return nil, fmt.Errorf("saving params in task workspace: %w", err)
+	}
+
+	return canonical, nil
+}
+
+// DiscardInput removes the input saved for a task that is rejected before it
+// is created.
+func (s *TaskService) DiscardInput(taskID uuid.UUID) error {
+	return s.cleanupWorkspace(taskID)
+}
+
+func canonicalParams(params []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(params))
+	// numbers are kept as written, not rounded through float64
+	dec.UseNumber()
+
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidParams, err)
+	}
+	if dec.More() {
+		return nil, domain.ErrInvalidParams
+	}
+	if obj == nil {
+		// a map decodes null without an error, anything else is rejected above
+		return nil, nil
+	}
+
+	// maps are encoded with sorted keys, strings are kept as written
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(obj); err != nil {
+		return nil, fmt.Errorf("encoding params: %w", err)
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
 func (s *TaskService) CreateTask(ctx context.Context, task *domain.Task, fileHash []byte) (err error) {
 	defer func() {
 		if err != nil {
@@ -109,33 +211,51 @@ func (s *TaskService) CreateTask(ctx context.Context, task *domain.Task, fileHas
 		}
 	}()
 
-	contImg, err := s.modelService.GetImageByID(ctx, task.ModelID)
+	model, err := s.modelService.GetModel(ctx, task.ModelID)
 	if err != nil {
-		return fmt.Errorf("getting container image by model id: %w", err)
+		return fmt.Errorf("getting model: %w", err)
 	}
 
-	if contImg == "" {
+	if model == nil || model.ContainerImage == "" {
 		err = domain.ErrModelNotFound
 		return err
 	}
 
-	task.ContainerImage = contImg
+	task.ContainerImage = model.ContainerImage
 
+	// the task overrides the model, the model overrides the default
+	if task.NetworkMode == "" {
+		task.NetworkMode = model.NetworkMode
+	}
+	if task.NetworkMode == "" {
+		task.NetworkMode = domain.NetworkMode(s.config.Docker.NetworkMode)
+	}
+	if !s.config.Docker.AllowsNetworkMode(string(task.NetworkMode)) {
+		err = fmt.Errorf("%w: %s", domain.ErrNetworkModeNotAllowed, task.NetworkMode)
+		return err
+	}
+
+	if len(task.Secrets) > 0 {
+		if err = s.secretService.CheckSecrets(ctx, task.Secrets); err != nil {
+			return fmt.Errorf("checking task secrets: %w", err)
+		}
+	}
+
+	// secrets are not part of the signature, their values never leave the container
 	var signature string
 	signature, err = getFinalHash(task, fileHash)
 	if err != nil {
 		return fmt.Errorf("hashing task meta: %w", err)
 	}
 
-	slog.Info("DEBUG: Task Signature",
+	slog.Debug("task signature",
 		"task_id", task.ID,
 		"signature", signature,
 		"file_hash", hex.EncodeToString(fileHash),
-		"envs", task.ContainerEnvs,
-		"cmd", task.ContainerCmd,
 	)
 
 	task.Signature = signature
+	task.InputSHA256 = hex.EncodeToString(fileHash)
 
 	var resultPath string
 	resultPath, err = s.findCachedTask(ctx, task.Signature)
@@ -146,7 +266,7 @@ func (s *TaskService) CreateTask(ctx context.Context, task *domain.Task, fileHas
 		task.ResultPath = resultPath
 		task.Status = domain.TaskCompleted
 
-		if err = s.initTask(ctx, task); err != nil {
+		if err = s.initTask(ctx, task, "result found in cache"); err != nil {
 			return fmt.Errorf("saving task using task service: %w", err)
 		}
 
@@ -162,7 +282,7 @@ func (s *TaskService) CreateTask(ctx context.Context, task *domain.Task, fileHas
 		task.Status = domain.TaskScheduled
 	}
 
-	return s.initTask(ctx, task)
+	return s.initTask(ctx, task, "")
 }
 
 func (s *TaskService) StopTask(ctx context.Context, taskID uuid.UUID, timeout time.Duration) error {
@@ -170,13 +290,31 @@ func (s *TaskService) StopTask(ctx context.Context, taskID uuid.UUID, timeout ti
 	if err != nil {
 		return fmt.Errorf("getting task by id: %w", err)
 	}
+	if task == nil {
+		return domain.ErrTaskNotFound
+	}
 
+	waiting := task.Status == domain.TaskQueued || task.Status == domain.TaskScheduled
+
+	task.FailureReason = domain.FailureUserCancelled
+	if err := s.repository.Mark(ctx, task, domain.TaskStopped, s.change(domain.ActorAPI, "stopped through the API")); err != nil {
+		return fmt.Errorf("marking task stopped: %w", err)
+	}
+
+	// a task waiting in the queue is cancelled, no worker has its input yet
+	if waiting {
+		return s.cleanupWorkspace(taskID)
+	}
+	// a task taken by a worker that hasn't started its container yet is
+	// dropped by the worker once it sees the status
 	if task.ContainerID == "" {
-		return fmt.Errorf("task container id is empty")
+		return nil
 	}
 
-	if err := s.repository.Mark(ctx, task, domain.TaskStopped); err != nil {
-		return fmt.Errorf("marking task stopped: %w", err)
+	// the container of another node is stopped by that node once it is
+	// notified about the status change
+	if task.NodeID != "" && task.NodeID != s.config.InstanceID {
+		return nil
 	}
 
 	if err := s.manager.StopContainer(ctx, task.ContainerID, timeout); err != nil {
@@ -186,53 +324,6 @@ func (s *TaskService) StopTask(ctx context.Context, taskID uuid.UUID, timeout ti
 	return nil
 }
 
-func (s *TaskService) StartScheduler(ctx context.Context, wg *sync.WaitGroup) {
-	ticker := time.NewTicker(s.config.Scheduler.Interval)
-	var mu sync.Mutex
-
-	scheduled := make(map[uuid.UUID]struct{})
-
-	wg.Go(func() {
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				tasks, err := s.repository.GetScheduledTasks(ctx, time.Now().Add(s.config.Scheduler.TaskExpires))
-				if err != nil {
-					slog.Error("error getting scheduled task")
-					continue
-				}
-
-				for _, task := range tasks {
-					mu.Lock()
-
-					if _, ok := scheduled[task.ID]; ok {
-						mu.Unlock()
-						continue
-					}
-
-					scheduled[task.ID] = struct{}{}
-					mu.Unlock()
-
-					delay := time.Until(*task.ScheduledAt)
-					time.AfterFunc(delay, func() {
-						t, err := s.repository.GetTaskById(ctx, task.ID)
-						if err != nil || t.Status != domain.TaskScheduled {
-							return
-						}
-						_ = s.repository.Mark(ctx, &task, domain.TaskQueued)
-						mu.Lock()
-						delete(scheduled, task.ID)
-						mu.Unlock()
-					})
-				}
-			}
-		}
-	})
-}
-
 func (s *TaskService) GetTask(ctx context.Context, id uuid.UUID) (*domain.Task, error) {
 	result, err := s.repository.GetTaskById(ctx, id)
 	if err != nil {
@@ -242,7 +333,25 @@ func (s *TaskService) GetTask(ctx context.Context, id uuid.UUID) (*domain.Task,
 	return result, nil
 }
 
-func (s *TaskService) ListTasks(ctx context.Context, page, pageSize int) ([]domain.Task, int64, error) {
+// ListTaskEvents returns the status history of the task.
+func (s *TaskService) ListTaskEvents(ctx context.Context, id uuid.UUID) ([]domain.TaskEvent, error) {
+	task, err := s.repository.GetTaskById(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting task by id: %w", err)
+	}
+	if task == nil {
+		return nil, domain.ErrTaskNotFound
+	}
+
+	events, err := s.repository.ListTaskEvents(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting task events from repo: %w", err)
+	}
+
+	return events, nil
+}
+
+func (s *TaskService) ListTasks(ctx context.Context, page, pageSize int, filter domain.TaskFilter) ([]domain.Task, int64, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -252,12 +361,12 @@ func (s *TaskService) ListTasks(ctx context.Context, page, pageSize int) ([]doma
 
 	offset := (page - 1) * pageSize
 
-	tasks, err := s.repository.GetTasksPaginated(ctx, int32(pageSize), int32(offset))
+	tasks, err := s.repository.GetTasksPaginated(ctx, int32(pageSize), int32(offset), filter)
 	if err != nil {
 		return nil, 0, fmt.Errorf("getting paginated tasks from repo: %w", err)
 	}
 
-	total, err := s.repository.GetTasksCount(ctx)
+	total, err := s.repository.GetTasksCount(ctx, filter)
 	if err != nil {
 		return nil, 0, fmt.Errorf("getting total tasks count: %w", err)
 	}
@@ -265,9 +374,25 @@ func (s *TaskService) ListTasks(ctx context.Context, page, pageSize int) ([]doma
 	return tasks, total, nil
 }
 
+// DeleteTask deletes the task with its workspace. Its stored result and the
+// checksums are kept while tasks taken from the cache still point at them.
 func (s *TaskService) DeleteTask(ctx context.Context, id uuid.UUID) error {
-	if err := s.storage.DeleteArtifacts(ctx, id); err != nil {
-		return fmt.Errorf("deleting artifacts: %w", err)
+	task, err := s.repository.GetTaskById(ctx, id)
+	if err != nil {
+		return fmt.Errorf("getting task from repo: %w", err)
+	}
+
+	// a task taken from the cache points into the prefix of another task
+	prefixes := []string{storage.TaskPrefix(id)}
+	if task != nil {
+		if prefix := storage.ResultPrefix(task.ResultPath); prefix != "" && prefix != prefixes[0] {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	for _, prefix := range prefixes {
+		if err := s.deleteUnusedResult(ctx, id, prefix); err != nil {
+			return err
+		}
 	}
 
 	if err := s.workspace.Cleanup(id); err != nil {
@@ -277,17 +402,67 @@ func (s *TaskService) DeleteTask(ctx context.Context, id uuid.UUID) error {
 	return s.repository.DeleteTask(ctx, id)
 }
 
-func (s *TaskService) GetResultURL(ctx context.Context, id uuid.UUID) (string, error) {
+// deleteUnusedResult deletes the objects and the checksums under the prefix
+// unless a task other than the deleted one still uses them.
+func (s *TaskService) deleteUnusedResult(ctx context.Context, id uuid.UUID, prefix string) error {
+	refs, err := s.repository.CountResultRefs(ctx, id, prefix)
+	if err != nil {
+		return fmt.Errorf("checking result refs: %w", err)
+	}
+	owner, ok := storage.PrefixOwner(prefix)
+	if refs > 0 || !ok {
+		return nil
+	}
+
+	if err := s.storage.DeleteArtifacts(ctx, owner); err != nil {
+		return fmt.Errorf("deleting artifacts: %w", err)
+	}
+
+	if err := s.repository.DeleteArtifactChecksums(ctx, prefix); err != nil {
+		return fmt.Errorf("deleting artifact checksums: %w", err)
+	}
+
+	return nil
+}
+
+// SetPinned pins or unpins the task. Pinned tasks are never removed by retention.
+func (s *TaskService) SetPinned(ctx context.Context, id uuid.UUID, pinned bool) (*domain.Task, error) {
+	task, err := s.repository.SetPinned(ctx, id, pinned)
+	if err != nil {
+		return nil, fmt.Errorf("setting task pinned: %w", err)
+	}
+
+	if task == nil {
+		return nil, domain.ErrTaskNotFound
+	}
+
+	return task, nil
+}
+
+// GetResultURL returns a download URL of the task result valid for expiry.
+// A zero expiry means STORAGE_PRESIGN_EXPIRY.
+func (s *TaskService) GetResultURL(ctx context.Context, id uuid.UUID, expiry time.Duration) (string, error) {
+	if expiry == 0 {
+		expiry = s.config.Storage.PresignExpiry
+	}
+	if expiry < 0 || expiry > s.config.Storage.MaxPresignExpiry {
+		return "", fmt.Errorf("%w: must be within %s", domain.ErrInvalidExpiry, s.config.Storage.MaxPresignExpiry)
+	}
+
 	task, err := s.repository.GetTaskById(ctx, id)
 	if err != nil {
 		return "", fmt.Errorf("getting task from repo: %w", err)
 	}
 
+	if task == nil {
+		return "", domain.ErrTaskNotFound
+	}
+
 	if task.Status != domain.TaskCompleted {
 		return "", nil
 	}
 
-	result, err := s.storage.GetDownloadURL(ctx, task.ResultPath)
+	result, err := s.storage.GetDownloadURL(ctx, task.ResultPath, expiry)
 	if err != nil {
 		return "", fmt.Errorf("getting storage download link: %w", err)
 	}
@@ -295,15 +470,83 @@ func (s *TaskService) GetResultURL(ctx context.Context, id uuid.UUID) (string, e
 	return result, nil
 }
 
+// RecoverTask queues a task whose container is still running so that a worker
+// waits for it and saves its result. Tasks already processed are ignored.
 func (s *TaskService) RecoverTask(task *domain.Task) {
+	if !s.startProcessing(task.ID) {
+		return
+	}
+
 	s.recoverMu.Lock()
 	s.recoverTaskQueue = append(s.recoverTaskQueue, task)
 	s.recoverMu.Unlock()
+
+	s.wakeUp()
 }
 
-func (s *TaskService) StartWorker(ctx context.Context, wg *sync.WaitGroup) {
+// RetryUpload queues the upload of the result of a task whose container
+// succeeded but whose upload failed. The result is taken from the workspace
+// kept by the failed attempt.
+func (s *TaskService) RetryUpload(ctx context.Context, id uuid.UUID) error {
+	task, err := s.repository.GetTaskById(ctx, id)
+	if err != nil {
+		return fmt.Errorf("getting task from repo: %w", err)
+	}
+
+	if task == nil {
+		return domain.ErrTaskNotFound
+	}
+
+	if task.Status != domain.TaskFailed || !task.UploadFailed {
+		return domain.ErrUploadNotFailed
+	}
+
+	if !s.startProcessing(task.ID) {
+		return domain.ErrTaskInProgress
+	}
+
+	s.recoverMu.Lock()
+	s.uploadQueue = append(s.uploadQueue, task)
+	s.recoverMu.Unlock()
+
+	s.wakeUp()
+
+	return nil
+}
+
+// IsProcessing reports whether the task is handled by a worker of this instance.
+func (s *TaskService) IsProcessing(id uuid.UUID) bool {
+	s.processingMu.Lock()
+	defer s.processingMu.Unlock()
+
+	_, ok := s.processing[id]
+	return ok
+}
+
+func (s *TaskService) startProcessing(id uuid.UUID) bool {
+	s.processingMu.Lock()
+	defer s.processingMu.Unlock()
+
+	if _, ok := s.processing[id]; ok {
+		return false
+	}
+	s.processing[id] = struct{}{}
+	return true
+}
+
+func (s *TaskService) finishProcessing(id uuid.UUID) {
+	s.processingMu.Lock()
+	delete(s.processing, id)
+	s.processingMu.Unlock()
+}
+
+// StartWorker processes queued tasks until ctx is done. The queue is checked
+// whenever queued receives, a worker slot is freed or a task is recovered,
+// and every configured interval as a fallback for lost notifications.
+func (s *TaskService) StartWorker(ctx context.Context, wg *sync.WaitGroup, queued <-chan struct{}) {
 	sem := make(chan struct{}, s.config.Worker.MaxWorkers)
 	ticker := time.NewTicker(s.config.Worker.Interval)
+	s.wakeUp()
 
 	wg.Go(func() {
 		defer ticker.Stop()
@@ -319,6 +562,10 @@ func (s *TaskService) StartWorker(ctx context.Context, wg *sync.WaitGroup) {
 				slog.Info("all active tasks finished")
 				return
 
+			case <-queued:
+				s.processQueue(ctx, sem, wg)
+			case <-s.wakeCh:
+				s.processQueue(ctx, sem, wg)
 			case <-ticker.C:
 				s.processQueue(ctx, sem, wg)
 			}
@@ -326,6 +573,60 @@ func (s *TaskService) StartWorker(ctx context.Context, wg *sync.WaitGroup) {
 	})
 }
 
+// StartStopWatcher stops the containers of tasks processed by this node that
+// were stopped through another instance. The processed tasks are checked
+// whenever stopped receives.
+func (s *TaskService) StartStopWatcher(ctx context.Context, wg *sync.WaitGroup, stopped <-chan struct{}) {
+	wg.Go(func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-stopped:
+				s.stopStoppedTasks(ctx)
+			}
+		}
+	})
+}
+
+func (s *TaskService) stopStoppedTasks(ctx context.Context) {
+	s.processingMu.Lock()
+	ids := make([]uuid.UUID, 0, len(s.processing))
+	for id := range s.processing {
+		ids = append(ids, id)
+	}
+	s.processingMu.Unlock()
+
+	for _, id := range ids {
+		task, err := s.repository.GetTaskById(ctx, id)
+		if err != nil {
+			slog.Error("getting processed task", "id", id, "error", err)
+			continue
+		}
+		if task == nil || task.Status != domain.TaskStopped || task.ContainerID == "" {
+			continue
+		}
+
+		if err := s.manager.StopContainer(ctx, task.ContainerID, s.config.Server.DefaultTaskStopTimeout); err != nil {
+			slog.Error("stopping container of stopped task", "id", id, "error", err)
+		}
+	}
+}
+
+// wakeUp makes the worker check the queue without waiting for the next tick.
+func (s *TaskService) wakeUp() {
+	select {
+	case s.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// release frees a worker slot and lets the worker take the next task.
+func (s *TaskService) release(sem chan struct{}) {
+	<-sem
+	s.wakeUp()
+}
+
 func (s *TaskService) getNextRecoverTask() *domain.Task {
 	s.recoverMu.Lock()
 	defer s.recoverMu.Unlock()
@@ -342,6 +643,20 @@ func (s *TaskService) getNextRecoverTask() *domain.Task {
 	return task
 }
 
+func (s *TaskService) getNextUploadTask() *domain.Task {
+	s.recoverMu.Lock()
+	defer s.recoverMu.Unlock()
+
+	if len(s.uploadQueue) == 0 {
+		return nil
+	}
+
+	task := s.uploadQueue[0]
+	s.uploadQueue = s.uploadQueue[1:]
+
+	return task
+}
+
 func (s *TaskService) processQueue(ctx context.Context, sem chan struct{}, wg *sync.WaitGroup) {
 	for {
 		select {
@@ -353,12 +668,36 @@ func (s *TaskService) processQueue(ctx context.Context, sem chan struct{}, wg *s
 
 		recTask := s.getNextRecoverTask()
 		if recTask != nil {
-			wg.Go(func() { s.waitAndSaveTask(ctx, recTask) })
+			wg.Go(func() {
+				defer s.finishProcessing(recTask.ID)
+
+				leaseCtx, unlease := s.holdLease(ctx, recTask.ID)
+				defer unlease()
+
+				s.waitAndSaveTask(leaseCtx, recTask)
+			})
 			<-sem
+			s.wakeUp()
 			return
 		}
 
-		task, err := s.repository.GetNextQueuedTask(ctx)
+		uploadTask := s.getNextUploadTask()
+		if uploadTask != nil {
+			wg.Go(func() {
+				defer s.release(sem)
+				defer s.finishProcessing(uploadTask.ID)
+
+				taskCtx, cancel := context.WithTimeout(ctx, time.Duration(uploadTask.TimeoutSec)*time.Second)
+				defer cancel()
+
+				if err := s.retryUpload(taskCtx, uploadTask); err != nil {
+					slog.Error("error while retrying upload", "id", uploadTask.ID, "error", err)
+				}
+			})
+			continue
+		}
+
+		task, err := s.repository.GetNextQueuedTask(ctx, s.config.InstanceID, s.config.Worker.LeaseDuration)
 		if err != nil {
 			slog.Error("failed to get next task", "error", err)
 			<-sem
@@ -370,34 +709,89 @@ func (s *TaskService) processQueue(ctx context.Context, sem chan struct{}, wg *s
 			return // no queued tasks, waiting for next call
 		}
 
-		resPath, err := s.repository.FindCachedTask(ctx, task.Signature)
+		resPath, err := s.findCachedTask(ctx, task.Signature)
 		if err != nil {
 			slog.Error("while finding task in cache", "error", err)
 		} else if resPath != "" {
 			task.ResultPath = resPath
-			s.mark(ctx, task, domain.TaskCompleted)
+			s.mark(ctx, task, domain.TaskCompleted, s.change(domain.ActorWorker, "result found in cache"))
 
 			<-sem
+			s.wakeUp()
 			return
 		}
 
+		s.startProcessing(task.ID)
+
 		wg.Go(func() {
-			defer func() { <-sem }()
+			defer s.release(sem)
+			defer s.finishProcessing(task.ID)
 
 			taskCtx, cancel := context.WithTimeout(ctx, time.Duration(task.TimeoutSec)*time.Second)
 			defer cancel()
 
-			if err := s.processTask(taskCtx, task); err != nil {
+			taskCtx, unlease := s.holdLease(taskCtx, task.ID)
+			defer unlease()
+
+			err := s.processTask(taskCtx, task)
+			if errors.Is(err, errShutdown) {
+				slog.Info("task left running until its lease expires", "id", task.ID)
+			} else if err != nil {
 				slog.Error("error while processing task", "id", task.ID, "error", err)
 			}
 		})
 	}
 }
 
+// holdLease renews the lease of the task until the returned func is called.
+// If another node reclaimed the task, the returned context is cancelled with
+// errLeaseLost.
+func (s *TaskService) holdLease(ctx context.Context, taskID uuid.UUID) (context.Context, func()) {
+	leaseCtx, cancel := context.WithCancelCause(ctx)
+	ticker := time.NewTicker(s.config.Worker.LeaseDuration / 3)
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-leaseCtx.Done():
+				return
+			case <-ticker.C:
+				ok, err := s.repository.RenewLease(leaseCtx, taskID, s.config.InstanceID, s.config.Worker.LeaseDuration)
+				if err != nil {
+					slog.Warn("failed to renew task lease", "task_id", taskID, "error", err)
+					continue
+				}
+				if !ok {
+					slog.Warn("task was reclaimed by another node", "task_id", taskID)
+					cancel(errLeaseLost)
+					return
+				}
+			}
+		}
+	})
+
+	return leaseCtx, func() {
+		cancel(nil)
+		wg.Wait()
+	}
+}
+
 // worker gouroutine should use it
 func (s *TaskService) processTask(ctx context.Context, task *domain.Task) (err error) {
 	start := time.Now()
+	// a reclaimed task, its container and its workspace belong to the new
+	// owner; a task left on shutdown is reclaimed the same way
+	leaseLost := func() bool { return errors.Is(context.Cause(ctx), errLeaseLost) || errors.Is(err, errShutdown) }
+	// a task queued again keeps its input for the next attempt
+	requeued := false
+
 	defer func() {
+		// the result is kept for a retry of the upload
+		if task.UploadFailed || leaseLost() || requeued {
+			return
+		}
 		if err := s.workspace.Cleanup(task.ID); err != nil {
 			slog.Error("Error while cleanup workspace", "error", err)
 		}
@@ -405,10 +799,15 @@ func (s *TaskService) processTask(ctx context.Context, task *domain.Task) (err e
 
 	// mark failed if err occurs while run
 	defer func() {
-		if err != nil {
+		// a task stopped on its timeout is already final
+		if err != nil && !leaseLost() && !requeued && task.Status != domain.TaskStopped {
+			// the steps of the task set the reason, the rest are failures of the service
+			if task.FailureReason == "" {
+				task.FailureReason = domain.FailureInfrastructure
+			}
 			markCtx, cancel := context.WithTimeout(context.Background(), s.config.Worker.ProcessTaskCleanupTimeout)
 			defer cancel()
-			s.repository.Mark(markCtx, task, domain.TaskFailed)
+			s.repository.Mark(markCtx, task, domain.TaskFailed, s.change(domain.ActorWorker, err.Error()))
 		}
 	}()
 
@@ -417,19 +816,62 @@ func (s *TaskService) processTask(ctx context.Context, task *domain.Task) (err e
 		return fmt.Errorf("checking is task stopped: %w", err)
 	}
 
+	envs, err := s.containerEnvs(ctx, task)
+	if err != nil {
+		task.FailureReason = domain.FailureContainerStart
+		return fmt.Errorf("preparing container environment: %w", err)
+	}
+
+	security, err := s.securityProfile(ctx, task.ModelID)
+	if err != nil {
+		task.FailureReason = domain.FailureContainerStart
+		return fmt.Errorf("resolving security profile: %w", err)
+	}
+
 	// start container
 	containerID, err := s.manager.StartContainer(ctx, &domain.ContainerConfig{
-		Image:  task.ContainerImage,
-		Mounts: createMounts(s.config.TmpDir, task.ID),
-		Cmd:    task.ContainerCmd,
-		Envs:   task.ContainerEnvs,
-		TaskID: task.ID,
+		Image:       task.ContainerImage,
+		Mounts:      createMounts(s.config.TmpDir, task.ID),
+		Cmd:         task.ContainerCmd,
+		Envs:        envs,
+		MemoryLimit: task.MemLim,
+		CPULimit:    task.CPULim,
+		GPU:         task.GPUEnabled,
+		Constraints: task.Constraints,
+		NetworkMode: task.NetworkMode,
+		Network:     s.containerNetwork(task.NetworkMode),
+		Security:    security,
+		TaskID:      task.ID,
+		NodeID:      s.config.InstanceID,
 	})
+	if errors.Is(err, domain.ErrHostsBusy) {
+		requeued = true
+		// only this task waits, the next ones may fit on other hosts
+		retryAt := time.Now().Add(s.config.Worker.Interval)
+		task.RetryAt = &retryAt
+		slog.Info("docker hosts are busy, queueing task again", "id", task.ID, "retry_at", retryAt, "error", err)
+		if err := s.repository.Mark(ctx, task, domain.TaskQueued, s.change(domain.ActorWorker, err.Error())); err != nil {
+			// the lease expires and the task is reclaimed
+			return fmt.Errorf("queueing task again: %w", err)
+		}
+		return nil
+	}
+	if errors.Is(err, domain.ErrNoMatchingHost) {
+		task.ErrorLog = err.Error()
+	}
 	if err != nil {
+		task.FailureReason = domain.FailureContainerStart
+		if errors.Is(err, domain.ErrImagePull) {
+			task.FailureReason = domain.FailureImagePull
+		}
 		return fmt.Errorf("starting container: %w", err)
 	}
 
 	defer func() {
+		if leaseLost() {
+			return
+		}
+
 		removeCtx, cancel := context.WithTimeout(context.Background(), s.config.Worker.ProcessTaskCleanupTimeout)
 		defer cancel()
 
@@ -441,7 +883,7 @@ func (s *TaskService) processTask(ctx context.Context, task *domain.Task) (err e
 	task.ContainerID = containerID
 
 	// mark as running
-	err = s.repository.Mark(ctx, task, domain.TaskRunning)
+	err = s.repository.Mark(ctx, task, domain.TaskRunning, s.change(domain.ActorWorker, "container started"))
 	if err != nil {
 		return fmt.Errorf("marking task running: %w", err)
 	}
@@ -452,16 +894,28 @@ func (s *TaskService) processTask(ctx context.Context, task *domain.Task) (err e
 		return fmt.Errorf("waiting and saving task: %w", err)
 	}
 
-	fmt.Println(time.Since(start))
+	slog.Debug("task processed", "task_id", task.ID, "duration", time.Since(start))
 
 	return nil
 }
 
 func (s *TaskService) waitAndSaveTask(ctx context.Context, task *domain.Task) error {
-	exitCode, err := s.manager.WaitContainer(ctx, task.ContainerID)
+	stopSampling := s.sampleUsage(ctx, task.ContainerID)
+	stopWatching := s.watchDisk(ctx, task)
+	exit, err := s.manager.WaitContainer(ctx, task.ContainerID)
+	usage := stopSampling()
+	diskExceeded := stopWatching()
+	if err != nil && errors.Is(context.Cause(ctx), errLeaseLost) {
+		return fmt.Errorf("waiting for container: %w", errLeaseLost)
+	}
+	if err != nil && errors.Is(context.Cause(ctx), context.Canceled) {
+		return fmt.Errorf("waiting for container: %w", errShutdown)
+	}
+	// saved before the status, so a finished task has its usage
+	s.saveUsage(ctx, task.ID, usage)
+
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-			origErr := err
 			stopCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
 
@@ -469,12 +923,19 @@ func (s *TaskService) waitAndSaveTask(ctx context.Context, task *domain.Task) er
 				slog.Error("failed to stop container during timeout cleanup", "error", err)
 			}
 
-			if err := s.repository.Mark(stopCtx, task, domain.TaskStopped); err != nil {
+			// a shutdown of the node is handled above, other cancellations
+			// are failures of the service
+			reason := "timed out"
+			task.FailureReason = domain.FailureTimeout
+			if errors.Is(err, context.Canceled) {
+				reason = "cancelled"
+				task.FailureReason = domain.FailureInfrastructure
+			}
+			if err := s.repository.Mark(stopCtx, task, domain.TaskStopped, s.change(domain.ActorWorker, reason)); err != nil {
 				slog.Error("failed to mark container stopped during timeout cleanup", "error", err)
 			}
 
-			err = nil
-			return fmt.Errorf("task timed out: %w", origErr) //TODO maybe return nil instead
+			return fmt.Errorf("task %s: %w", reason, err)
 		}
 
 		errorLog, logErr := s.getErrLogs(ctx, task.ContainerID)
@@ -488,12 +949,29 @@ func (s *TaskService) waitAndSaveTask(ctx context.Context, task *domain.Task) er
 	}
 
 	// check container status
+	exitCode := int(exit.ExitCode)
+	task.ExitCode = &exitCode
+
+	if diskExceeded {
+		task.FailureReason = domain.FailureDiskQuota
+		task.ErrorLog = fmt.Sprintf("result exceeded the disk limit of %d MB", task.DiskLim)
+		return fmt.Errorf("container killed: %s", task.ErrorLog)
+	}
+
 	var errorLog string
-	if exitCode != 0 {
+	if exit.ExitCode != 0 {
+		task.FailureReason = domain.FailureNonzeroExit
+		if exit.OOMKilled {
+			task.FailureReason = domain.FailureOOMKilled
+		}
+
 		errorLog, err = s.getErrLogs(ctx, task.ContainerID)
 		if err != nil {
 			return fmt.Errorf("getting container error logs: %w", err)
 		}
+		if exit.OOMKilled {
+			errorLog = fmt.Sprintf("container ran out of memory (limit %d MB)\n%s", task.MemLim, errorLog)
+		}
 
 		task.ErrorLog = errorLog
 
@@ -511,22 +989,165 @@ func (s *TaskService) waitAndSaveTask(ctx context.Context, task *domain.Task) er
 	}
 
 	// upload result to storage
-	resPath, err := s.storage.UploadToStorage(ctx, task.ID, s.workspace.ResultDir(task.ID))
+	resPath, err := s.uploadResult(ctx, task)
 	if err != nil {
-		return fmt.Errorf("upload to storage: %w", err)
+		return err
 	}
 
 	task.ResultPath = resPath
 
 	// mark as completed
-	err = s.repository.Mark(ctx, task, domain.TaskCompleted)
+	err = s.repository.Mark(ctx, task, domain.TaskCompleted, s.change(domain.ActorWorker, ""))
+	if errors.Is(err, domain.ErrInvalidTransition) {
+		// stopped while the result was uploaded
+		slog.Info("task was not completed", "id", task.ID, "reason", err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("marking task completed: %w", err)
+	}
+
+	return nil
+}
+
+// uploadResult uploads the result dir of the task, records the upload
+// progress on the task and the checksums of the uploaded objects. A failed
+// upload marks the task, so its workspace is kept and the upload can be retried.
+func (s *TaskService) uploadResult(ctx context.Context, task *domain.Task) (string, error) {
+	res, err := s.storage.UploadToStorage(ctx, task.ID, s.workspace.ResultDir(task.ID), s.uploadProgress(ctx, task.ID))
+	if err == nil {
+		err = s.repository.SaveArtifactChecksums(ctx, task.ID, res.Objects)
+	}
+	if err != nil {
+		task.UploadFailed = true
+		task.FailureReason = domain.FailureUpload
+		task.ErrorLog = fmt.Sprintf("uploading result: %v", err)
+		return "", fmt.Errorf("upload to storage: %w", err)
+	}
+
+	task.UploadFailed = false
+	return res.PrimaryKey, nil
+}
+
+// uploadProgress saves the upload progress of the task at most once per
+// UPLOAD_PROGRESS_INTERVAL, the final progress is always saved.
+func (s *TaskService) uploadProgress(ctx context.Context, taskID uuid.UUID) domain.UploadProgressFunc {
+	var last time.Time
+
+	return func(uploaded, total int64) {
+		if uploaded < total && time.Since(last) < s.config.Upload.ProgressInterval {
+			return
+		}
+		last = time.Now()
+
+		if err := s.repository.SetUploadProgress(ctx, taskID, total, uploaded); err != nil {
+			slog.Warn("failed to save upload progress", "task_id", taskID, "error", err)
+		}
+	}
+}
+
+// retryUpload uploads the kept result of an upload failed task and completes
+// the task. The task stays failed if the upload fails again.
+func (s *TaskService) retryUpload(ctx context.Context, task *domain.Task) error {
+	resPath, err := s.uploadResult(ctx, task)
 	if err != nil {
+		markCtx, cancel := context.WithTimeout(context.Background(), s.config.Worker.ProcessTaskCleanupTimeout)
+		defer cancel()
+
+		if markErr := s.repository.Mark(markCtx, task, domain.TaskFailed, s.change(domain.ActorWorker, err.Error())); markErr != nil {
+			slog.Error("failed to mark task failed", "task_id", task.ID, "error", markErr)
+		}
+		return err
+	}
+
+	task.ResultPath = resPath
+	if err := s.repository.Mark(ctx, task, domain.TaskCompleted, s.change(domain.ActorWorker, "result upload retried")); err != nil {
 		return fmt.Errorf("marking task completed: %w", err)
 	}
 
+	if err := s.cleanupWorkspace(task.ID); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// containerEnvs returns the environment of the container of a task, its
+// secrets are appended after the plain variables and override them. Egress
+// containers get the egress proxy, if any, in the proxy variables.
+func (s *TaskService) containerEnvs(ctx context.Context, task *domain.Task) ([]string, error) {
+	proxy := s.config.Docker.EgressProxy
+	if task.NetworkMode != domain.NetworkEgress {
+		proxy = ""
+	}
+	if len(task.Secrets) == 0 && proxy == "" {
+		return task.ContainerEnvs, nil
+	}
+
+	envs := make([]string, 0, len(task.ContainerEnvs)+len(task.Secrets)+4)
+	if proxy != "" {
+		envs = append(envs, "HTTP_PROXY="+proxy, "HTTPS_PROXY="+proxy, "http_proxy="+proxy, "https_proxy="+proxy)
+	}
+	envs = append(envs, task.ContainerEnvs...)
+
+	if len(task.Secrets) > 0 {
+		secrets, err := s.secretService.ResolveSecrets(ctx, task.Secrets)
+		if err != nil {
+			return nil, fmt.Errorf("resolving task secrets: %w", err)
+		}
+		envs = append(envs, secrets...)
+	}
+
+	return envs, nil
+}
+
+// securityProfile returns the hardening of the containers of a model, the
+// profile of the service with the overrides of the model.
+func (s *TaskService) securityProfile(ctx context.Context, modelID string) (domain.SecurityProfile, error) {
+	sec := s.config.Docker.Security
+	profile := domain.SecurityProfile{
+		User:             sec.User,
+		DropCapabilities: sec.DropCapabilities,
+		CapAdd:           sec.CapAdd,
+		NoNewPrivileges:  sec.NoNewPrivileges,
+		ReadOnlyRootfs:   sec.ReadOnlyRootfs,
+		TmpfsSizeMB:      sec.TmpfsSizeMB,
+		PidsLimit:        sec.PidsLimit,
+		Seccomp:          sec.SeccompProfile,
+		GroupAdd:         []string{strconv.Itoa(sec.WorkspaceGID)},
+	}
+	for name, v := range sec.Ulimits {
+		soft, hard, err := config.ParseUlimit(v)
+		if err != nil {
+			return domain.SecurityProfile{}, fmt.Errorf("parsing ulimit %s: %w", name, err)
+		}
+		profile.Ulimits = append(profile.Ulimits, domain.Ulimit{Name: name, Soft: soft, Hard: hard})
+	}
+	sort.Slice(profile.Ulimits, func(i, j int) bool { return profile.Ulimits[i].Name < profile.Ulimits[j].Name })
+
+	model, err := s.modelService.GetModel(ctx, modelID)
+	if err != nil {
+		return domain.SecurityProfile{}, fmt.Errorf("getting model: %w", err)
+	}
+	if model == nil {
+		return profile, nil
+	}
+	return profile.Apply(model.Security), nil
+}
+
+// containerNetwork returns the Docker network of the network mode, empty
+// for the modes without a network of their own.
+func (s *TaskService) containerNetwork(mode domain.NetworkMode) string {
+	switch mode {
+	case domain.NetworkInternal:
+		return s.config.Docker.InternalNetwork
+	case domain.NetworkEgress:
+		return s.config.Docker.EgressNetwork
+	default:
+		return ""
+	}
+}
+
 func getFinalHash(task *domain.Task, fileHash []byte) (string, error) {
 	sort.Strings(task.ContainerEnvs)
 	finalHasher := sha256.New()
@@ -538,29 +1159,63 @@ func getFinalHash(task *domain.Task, fileHash []byte) (string, error) {
 	if _, err := finalHasher.Write(fileHash); err != nil {
 		return "", fmt.Errorf("writing to hasher: %w", err)
 	}
+	// tasks without params keep their signature
+	if len(task.Params) > 0 {
+		if _, err := finalHasher.Write(append([]byte("|params|"), task.Params...)); err != nil {
+			return "", fmt.Errorf("writing to hasher: %w", err)
+		}
+	}
 
 	return hex.EncodeToString(finalHasher.Sum(nil)), nil
 }
 
-func (s *TaskService) initTask(ctx context.Context, task *domain.Task) error {
-	if err := s.repository.Create(ctx, task); err != nil {
+func (s *TaskService) initTask(ctx context.Context, task *domain.Task, reason string) error {
+	if err := s.repository.Create(ctx, task, s.change(domain.ActorAPI, reason)); err != nil {
 		return fmt.Errorf("creating task in repository: %w", err)
 	}
 	return nil
 }
 
-func (s *TaskService) mark(ctx context.Context, task *domain.Task, status domain.TaskStatus) error {
-	if err := s.repository.Mark(ctx, task, status); err != nil {
+// change describes a status change made by this instance.
+func (s *TaskService) change(actor domain.TaskActor, reason string) domain.StatusChange {
+	return domain.StatusChange{Actor: actor, NodeID: s.config.InstanceID, Reason: reason}
+}
+
+func (s *TaskService) mark(ctx context.Context, task *domain.Task, status domain.TaskStatus, change domain.StatusChange) error {
+	if err := s.repository.Mark(ctx, task, status, change); err != nil {
 		return fmt.Errorf("marking task: %w", err)
 	}
 	return nil
 }
 
+// findCachedTask returns the result path of a completed task with the same
+// signature. With STORAGE_VERIFY_CACHED the result is verified first, a
+// corrupted result is flagged and not reused.
 func (s *TaskService) findCachedTask(ctx context.Context, signature string) (string, error) {
 	resPath, err := s.repository.FindCachedTask(ctx, signature)
 	if err != nil {
 		return "", fmt.Errorf("finding cached task: %w", err)
 	}
+	if resPath == "" || !s.config.Storage.VerifyCached {
+		return resPath, nil
+	}
+
+	prefix := storage.ResultPrefix(resPath)
+	if prefix == "" {
+		return "", nil
+	}
+	report, err := s.verifyPrefix(ctx, prefix)
+	if err != nil {
+		return "", fmt.Errorf("verifying cached result: %w", err)
+	}
+	if !report.OK {
+		slog.Warn("cached result is corrupted, not reusing it", "result_path", resPath)
+		if err := s.repository.SetResultCorrupted(ctx, prefix, true); err != nil {
+			return "", err
+		}
+		return "", nil
+	}
+
 	return resPath, nil
 }
 
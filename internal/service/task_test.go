@@ -3,10 +3,16 @@ package service
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
 	"pinn-connect-service/internal/config"
 	"pinn-connect-service/internal/domain"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -20,10 +26,14 @@ import (
 
 type mockContainerManager struct {
 	ContainerManager
-	startFunc func(context.Context, *domain.ContainerConfig) (string, error)
-	waitFunc  func(context.Context, string) (int64, error)
-	stopFunc  func(context.Context, string, time.Duration) error
-	logsFunc  func(context.Context, string, bool) (io.ReadCloser, error)
+	startFunc  func(context.Context, *domain.ContainerConfig) (string, error)
+	waitFunc   func(context.Context, string) (*domain.ContainerExit, error)
+	stopFunc   func(context.Context, string, time.Duration) error
+	logsFunc   func(context.Context, string, bool) (io.ReadCloser, error)
+	statsFunc  func(context.Context, string) (*domain.ContainerStats, error)
+	streamFunc func(context.Context, string, func(*domain.ContainerStats) error) error
+	mu         sync.Mutex
+	removed    []string
 }
 
 func (m *mockContainerManager) StartContainer(ctx context.Context, c *domain.ContainerConfig) (string, error) {
@@ -32,11 +42,11 @@ func (m *mockContainerManager) StartContainer(ctx context.Context, c *domain.Con
 	}
 	return "ctr-1", nil
 }
-func (m *mockContainerManager) WaitContainer(ctx context.Context, id string) (int64, error) {
+func (m *mockContainerManager) WaitContainer(ctx context.Context, id string) (*domain.ContainerExit, error) {
 	if m.waitFunc != nil {
 		return m.waitFunc(ctx, id)
 	}
-	return 0, nil
+	return &domain.ContainerExit{}, nil
 }
 func (m *mockContainerManager) StopContainer(ctx context.Context, id string, t time.Duration) error {
 	if m.stopFunc != nil {
@@ -51,27 +61,59 @@ func (m *mockContainerManager) GetContainerLogs(ctx context.Context, id string,
 	// docker multiplexed stream: header (8 bytes) + payload
 	return io.NopCloser(bytes.NewReader([]byte{0x02, 0, 0, 0, 0, 0, 0, 2, 'e', 'r'})), nil
 }
-func (m *mockContainerManager) RemoveContainer(ctx context.Context, id string) error { return nil }
+func (m *mockContainerManager) RemoveContainer(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removed = append(m.removed, id)
+	return nil
+}
 func (m *mockContainerManager) GetContainerState(ctx context.Context, id string) (*domain.ContainerState, error) {
 	return nil, nil
 }
+func (m *mockContainerManager) ContainerStats(ctx context.Context, id string) (*domain.ContainerStats, error) {
+	if m.statsFunc != nil {
+		return m.statsFunc(ctx, id)
+	}
+	return &domain.ContainerStats{}, nil
+}
+func (m *mockContainerManager) StreamContainerStats(ctx context.Context, id string, fn func(*domain.ContainerStats) error) error {
+	if m.streamFunc != nil {
+		return m.streamFunc(ctx, id, fn)
+	}
+	return nil
+}
 
 // ─────────────────────────────────────────────
 
 type mockRepository struct {
 	TaskRepository
-	getByIdFunc      func(context.Context, uuid.UUID) (*domain.Task, error)
-	getNextFunc      func(context.Context) (*domain.Task, error)
-	getScheduledFunc func(context.Context, time.Time) ([]domain.Task, error)
-	markFunc         func(context.Context, *domain.Task, domain.TaskStatus) error
-	findCachedFunc   func(context.Context, string) (string, error)
-	countFunc        func(context.Context) (int64, error)
-	listFunc         func(context.Context, int32, int32) ([]domain.Task, error)
-	deleteFunc       func(context.Context, uuid.UUID) error
-	createFunc       func(context.Context, *domain.Task) error
-}
-
-func (m *mockRepository) Create(ctx context.Context, t *domain.Task) error {
+	getByIdFunc    func(context.Context, uuid.UUID) (*domain.Task, error)
+	getNextFunc    func(context.Context) (*domain.Task, error)
+	markFunc       func(context.Context, *domain.Task, domain.TaskStatus) error
+	findCachedFunc func(context.Context, string) (string, error)
+	countFunc      func(context.Context, domain.TaskFilter) (int64, error)
+	listFunc       func(context.Context, int32, int32, domain.TaskFilter) ([]domain.Task, error)
+	deleteFunc     func(context.Context, uuid.UUID) error
+	createFunc     func(context.Context, *domain.Task) error
+	setPinnedFunc  func(context.Context, uuid.UUID, bool) (*domain.Task, error)
+	progressFunc   func(context.Context, uuid.UUID, int64, int64) error
+	saveSumsFunc   func(context.Context, uuid.UUID, []domain.Artifact) error
+	listSumsFunc   func(context.Context, string) ([]domain.Artifact, error)
+	deleteSumsFunc func(context.Context, string) error
+	refsFunc       func(context.Context, uuid.UUID, string) (int64, error)
+	corruptedFunc  func(context.Context, string, bool) error
+	renewFunc      func(context.Context, uuid.UUID) (bool, error)
+	eventsFunc     func(context.Context, uuid.UUID) ([]domain.TaskEvent, error)
+	usageFunc      func(context.Context, uuid.UUID, domain.ResourceUsage) error
+	reportFunc     func(context.Context, time.Time, time.Time) ([]domain.UsageReportRow, error)
+	// changeFunc observes the status changes passed to Create and Mark
+	changeFunc func(domain.TaskStatus, domain.StatusChange)
+}
+
+func (m *mockRepository) Create(ctx context.Context, t *domain.Task, change domain.StatusChange) error {
+	if m.changeFunc != nil {
+		m.changeFunc(t.Status, change)
+	}
 	if m.createFunc != nil {
 		return m.createFunc(ctx, t)
 	}
@@ -83,19 +125,22 @@ func (m *mockRepository) GetTaskById(ctx context.Context, id uuid.UUID) (*domain
 	}
 	return &domain.Task{ID: id, Status: domain.TaskQueued}, nil
 }
-func (m *mockRepository) GetNextQueuedTask(ctx context.Context) (*domain.Task, error) {
+func (m *mockRepository) GetNextQueuedTask(ctx context.Context, _ string, _ time.Duration) (*domain.Task, error) {
 	if m.getNextFunc != nil {
 		return m.getNextFunc(ctx)
 	}
 	return nil, nil
 }
-func (m *mockRepository) GetScheduledTasks(ctx context.Context, t time.Time) ([]domain.Task, error) {
-	if m.getScheduledFunc != nil {
-		return m.getScheduledFunc(ctx, t)
+func (m *mockRepository) RenewLease(ctx context.Context, id uuid.UUID, _ string, _ time.Duration) (bool, error) {
+	if m.renewFunc != nil {
+		return m.renewFunc(ctx, id)
 	}
-	return nil, nil
+	return true, nil
 }
-func (m *mockRepository) Mark(ctx context.Context, t *domain.Task, s domain.TaskStatus) error {
+func (m *mockRepository) Mark(ctx context.Context, t *domain.Task, s domain.TaskStatus, change domain.StatusChange) error {
+	if m.changeFunc != nil {
+		m.changeFunc(s, change)
+	}
 	if m.markFunc != nil {
 		return m.markFunc(ctx, t, s)
 	}
@@ -113,34 +158,106 @@ func (m *mockRepository) DeleteTask(ctx context.Context, id uuid.UUID) error {
 	}
 	return nil
 }
-func (m *mockRepository) GetTasksCount(ctx context.Context) (int64, error) {
+func (m *mockRepository) SetPinned(ctx context.Context, id uuid.UUID, pinned bool) (*domain.Task, error) {
+	if m.setPinnedFunc != nil {
+		return m.setPinnedFunc(ctx, id, pinned)
+	}
+	return &domain.Task{ID: id, Pinned: pinned}, nil
+}
+func (m *mockRepository) SetUploadProgress(ctx context.Context, id uuid.UUID, total, done int64) error {
+	if m.progressFunc != nil {
+		return m.progressFunc(ctx, id, total, done)
+	}
+	return nil
+}
+func (m *mockRepository) SaveArtifactChecksums(ctx context.Context, id uuid.UUID, objects []domain.Artifact) error {
+	if m.saveSumsFunc != nil {
+		return m.saveSumsFunc(ctx, id, objects)
+	}
+	return nil
+}
+func (m *mockRepository) ListArtifactChecksums(ctx context.Context, prefix string) ([]domain.Artifact, error) {
+	if m.listSumsFunc != nil {
+		return m.listSumsFunc(ctx, prefix)
+	}
+	return nil, nil
+}
+func (m *mockRepository) DeleteArtifactChecksums(ctx context.Context, prefix string) error {
+	if m.deleteSumsFunc != nil {
+		return m.deleteSumsFunc(ctx, prefix)
+	}
+	return nil
+}
+func (m *mockRepository) CountResultRefs(ctx context.Context, id uuid.UUID, prefix string) (int64, error) {
+	if m.refsFunc != nil {
+		return m.refsFunc(ctx, id, prefix)
+	}
+	return 0, nil
+}
+func (m *mockRepository) SetResultCorrupted(ctx context.Context, prefix string, corrupted bool) error {
+	if m.corruptedFunc != nil {
+		return m.corruptedFunc(ctx, prefix, corrupted)
+	}
+	return nil
+}
+func (m *mockRepository) ListTaskEvents(ctx context.Context, id uuid.UUID) ([]domain.TaskEvent, error) {
+	if m.eventsFunc != nil {
+		return m.eventsFunc(ctx, id)
+	}
+	return nil, nil
+}
+func (m *mockRepository) SaveTaskUsage(ctx context.Context, id uuid.UUID, usage domain.ResourceUsage) error {
+	if m.usageFunc != nil {
+		return m.usageFunc(ctx, id, usage)
+	}
+	return nil
+}
+func (m *mockRepository) UsageReport(ctx context.Context, from, to time.Time) ([]domain.UsageReportRow, error) {
+	if m.reportFunc != nil {
+		return m.reportFunc(ctx, from, to)
+	}
+	return nil, nil
+}
+func (m *mockRepository) GetTasksCount(ctx context.Context, f domain.TaskFilter) (int64, error) {
 	if m.countFunc != nil {
-		return m.countFunc(ctx)
+		return m.countFunc(ctx, f)
 	}
 	return 5, nil
 }
-func (m *mockRepository) GetTasksPaginated(ctx context.Context, l, o int32) ([]domain.Task, error) {
+func (m *mockRepository) GetTasksPaginated(ctx context.Context, l, o int32, f domain.TaskFilter) ([]domain.Task, error) {
 	if m.listFunc != nil {
-		return m.listFunc(ctx, l, o)
+		return m.listFunc(ctx, l, o, f)
 	}
 	return []domain.Task{{ID: uuid.New()}}, nil
 }
 
 // ─────────────────────────────────────────────
 
-type mockArtifactStorage struct{ ArtifactStorage }
+type mockArtifactStorage struct {
+	ArtifactStorage
+	listFunc func(context.Context, string) ([]domain.Artifact, error)
+	openFunc func(context.Context, string) (io.ReadSeekCloser, *domain.Artifact, error)
+}
 
-func (m *mockArtifactStorage) UploadToStorage(ctx context.Context, id uuid.UUID, d string) (string, error) {
+func (m *mockArtifactStorage) UploadToStorage(ctx context.Context, id uuid.UUID, d string, progress domain.UploadProgressFunc) (*domain.UploadResult, error) {
 	if d == "fail" {
-		return "", errors.New("upload error")
+		return nil, errors.New("upload error")
+	}
+	if progress != nil {
+		progress(0, 10)
+		progress(5, 10)
+		progress(10, 10)
 	}
-	return "result-key", nil
+	return &domain.UploadResult{
+		PrimaryKey: "result-key",
+		Objects:    []domain.Artifact{{Key: "result-key", Size: 10, SHA256: "abc"}},
+	}, nil
 }
-func (m *mockArtifactStorage) GetDownloadURL(ctx context.Context, k string) (string, error) {
+func (m *mockArtifactStorage) GetDownloadURL(ctx context.Context, k string, expiry time.Duration) (string, error) {
 	if k == "fail" {
 		return "", errors.New("url error")
 	}
-	return "https://example.com/result", nil
+	return fmt.Sprintf("https://example.com/result?expires=%d", int(expiry.Seconds())), nil
 }
 func (m *mockArtifactStorage) DeleteArtifacts(ctx context.Context, id uuid.UUID) error {
 	// fixed UUID triggers artifact delete error
@@ -150,12 +267,28 @@ func (m *mockArtifactStorage) DeleteArtifacts(ctx context.Context, id uuid.UUID)
 	return nil
 }
 
+func (m *mockArtifactStorage) ListArtifacts(ctx context.Context, prefix string) ([]domain.Artifact, error) {
+	if m.listFunc != nil {
+		return m.listFunc(ctx, prefix)
+	}
+	return nil, nil
+}
+func (m *mockArtifactStorage) OpenArtifact(ctx context.Context, key string) (io.ReadSeekCloser, *domain.Artifact, error) {
+	if m.openFunc != nil {
+		return m.openFunc(ctx, key)
+	}
+	return nil, nil, errors.New("not found")
+}
+
 // ─────────────────────────────────────────────
 
 type mockWorkspace struct {
 	Workspace
 	prepFunc      func(uuid.UUID) error
 	saveInputFunc func(uuid.UUID, string, io.Reader) error
+	sizeFunc      func(uuid.UUID) (int64, error)
+	freeFunc      func() (uint64, uint64, error)
+	cleaned       []uuid.UUID
 }
 
 func (m *mockWorkspace) Prepare(id uuid.UUID) error {
@@ -172,6 +305,7 @@ func (m *mockWorkspace) ResultDir(id uuid.UUID) string {
 	return "/tmp/result"
 }
 func (m *mockWorkspace) Cleanup(id uuid.UUID) error {
+	m.cleaned = append(m.cleaned, id)
 	// fixed UUID triggers cleanup error
 	if id.String() == "00000000-0000-0000-0000-000000000003" {
 		return errors.New("cleanup error")
@@ -185,6 +319,18 @@ func (m *mockWorkspace) SaveInput(id uuid.UUID, n string, r io.Reader) error {
 	io.Copy(io.Discard, r)
 	return nil
 }
+func (m *mockWorkspace) ResultSize(id uuid.UUID) (int64, error) {
+	if m.sizeFunc != nil {
+		return m.sizeFunc(id)
+	}
+	return 0, nil
+}
+func (m *mockWorkspace) FreeSpace() (uint64, uint64, error) {
+	if m.freeFunc != nil {
+		return m.freeFunc()
+	}
+	return 100 << 30, 200 << 30, nil
+}
 
 // ─────────────────────────────────────────────
 
@@ -212,11 +358,27 @@ func defaultCfg() *config.Config {
 		Worker: config.WorkerConfig{
 			Interval:                  time.Millisecond,
 			MaxWorkers:                2,
+			LeaseDuration:             time.Minute,
 			ProcessTaskCleanupTimeout: time.Second,
+			StatsInterval:             time.Millisecond,
 		},
 		Scheduler: config.SchedulerConfig{
-			Interval:    time.Millisecond,
-			TaskExpires: time.Hour,
+			Interval: time.Millisecond,
+		},
+		Storage: config.StorageConfig{
+			PresignExpiry:    10 * time.Minute,
+			MaxPresignExpiry: time.Hour,
+		},
+		Disk: config.DiskConfig{
+			CheckInterval:  time.Millisecond,
+			MinFreeMB:      1024,
+			MinFreePercent: 5,
+		},
+		Docker: config.DockerConfig{
+			NetworkMode:     "none",
+			NetworkModes:    []string{"none", "internal", "egress"},
+			InternalNetwork: "pinn-internal",
+			EgressNetwork:   "pinn-egress",
 		},
 		TmpDir: "/tmp",
 	}
@@ -227,7 +389,7 @@ func buildSvc(repo *mockRepository, mgr *mockContainerManager, ws *mockWorkspace
 		modelRepo = &mockModelRepo{}
 	}
 	ms := NewModelService(modelRepo, nil)
-	return NewTaskService(mgr, &mockArtifactStorage{}, defaultCfg(), repo, ws, ms)
+	return NewTaskService(mgr, &mockArtifactStorage{}, defaultCfg(), repo, ws, ms, nil)
 }
 
 func defaultSvc() (*TaskService, *mockRepository, *mockContainerManager, *mockWorkspace) {
@@ -276,6 +438,89 @@ func TestSaveInput_Success(t *testing.T) {
 	}
 }
 
+// ─────────────────────────────────────────────
+// SaveParams
+// ─────────────────────────────────────────────
+
+func TestSaveParams_Canonical(t *testing.T) {
+	svc, _, _, ws := defaultSvc()
+	var filename, saved string
+	ws.saveInputFunc = func(_ uuid.UUID, name string, r io.Reader) error {
+		b, _ := io.ReadAll(r)
+		filename, saved = name, string(b)
+		return nil
+	}
+
+	p1, err := svc.SaveParams(uuid.New(), []byte(`{"epochs": 1000, "pde": {"nu": 0.01, "alpha": 1e-3}, "domain": [0, 1.0]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p2, err := svc.SaveParams(uuid.New(), []byte(`{"domain":[0,1.0],"pde":{"alpha":1e-3,"nu":0.01},"epochs":1000}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `{"domain":[0,1.0],"epochs":1000,"pde":{"alpha":1e-3,"nu":0.01}}`
+	if string(p1) != want || string(p2) != want {
+		t.Errorf("expected canonical params %s, got %s and %s", want, p1, p2)
+	}
+	if filename != domain.ParamsFilename || saved != want {
+		t.Errorf("expected params written to %s, got %q: %s", domain.ParamsFilename, filename, saved)
+	}
+}
+
+// Strings are written as given, not with the HTML escapes of json.Marshal.
+func TestSaveParams_KeepsStrings(t *testing.T) {
+	svc, _, _, _ := defaultSvc()
+
+	params, err := svc.SaveParams(uuid.New(), []byte(`{"u": "a<b && c>d"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `{"u":"a<b && c>d"}`; string(params) != want {
+		t.Errorf("expected params %s, got %s", want, params)
+	}
+}
+
+func TestSaveParams_Null(t *testing.T) {
+	svc, _, _, ws := defaultSvc()
+	ws.prepFunc = func(uuid.UUID) error {
+		t.Fatal("expected the workspace not to be prepared")
+		return nil
+	}
+
+	params, err := svc.SaveParams(uuid.New(), []byte(` null `))
+	if err != nil || params != nil {
+		t.Errorf("expected no params, got %q / %v", params, err)
+	}
+}
+
+func TestSaveParams_Invalid(t *testing.T) {
+	for _, params := range []string{`[1, 2]`, `42`, `{"a": 1} {}`, `{"a":`} {
+		svc, _, _, ws := defaultSvc()
+		ws.prepFunc = func(uuid.UUID) error {
+			t.Fatal("expected the workspace not to be prepared")
+			return nil
+		}
+
+		if _, err := svc.SaveParams(uuid.New(), []byte(params)); !errors.Is(err, domain.ErrInvalidParams) {
+			t.Errorf("expected ErrInvalidParams for %s, got %v", params, err)
+		}
+	}
+}
+
+func TestDiscardInput(t *testing.T) {
+	svc, _, _, ws := defaultSvc()
+	id := uuid.New()
+
+	if err := svc.DiscardInput(id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ws.cleaned) != 1 || ws.cleaned[0] != id {
+		t.Errorf("expected the workspace of %s to be removed, got %v", id, ws.cleaned)
+	}
+}
+
 // ─────────────────────────────────────────────
 // CreateTask
 // ─────────────────────────────────────────────
@@ -325,6 +570,18 @@ func TestCreateTask_CacheHit(t *testing.T) {
 	}
 }
 
+func TestCreateTask_RecordsInputChecksum(t *testing.T) {
+	svc, _, _, _ := defaultSvc()
+
+	task := &domain.Task{ModelID: "m1"}
+	if err := svc.CreateTask(context.Background(), task, []byte{0xab, 0xcd}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if task.InputSHA256 != "abcd" {
+		t.Errorf("expected input checksum abcd, got %q", task.InputSHA256)
+	}
+}
+
 func TestCreateTask_CacheError(t *testing.T) {
 	svc, repo, _, _ := defaultSvc()
 	repo.findCachedFunc = func(_ context.Context, _ string) (string, error) {
@@ -398,6 +655,52 @@ func TestCreateTask_InitError(t *testing.T) {
 	}
 }
 
+// The network mode of the task overrides the one of the model, which
+// overrides the default.
+func TestCreateTask_ResolvesNetworkMode(t *testing.T) {
+	tests := []struct {
+		name  string
+		task  domain.NetworkMode
+		model domain.NetworkMode
+		want  domain.NetworkMode
+	}{
+		{"default", "", "", domain.NetworkNone},
+		{"model", "", domain.NetworkInternal, domain.NetworkInternal},
+		{"task", domain.NetworkEgress, domain.NetworkInternal, domain.NetworkEgress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := &mockModelRepo{
+				getByIDFunc: func(_ context.Context, id string) (*domain.Model, error) {
+					return &domain.Model{ID: id, ContainerImage: "img:latest", NetworkMode: tt.model}, nil
+				},
+			}
+			svc := buildSvc(&mockRepository{}, &mockContainerManager{}, &mockWorkspace{}, mr)
+
+			task := &domain.Task{ModelID: "m1", NetworkMode: tt.task}
+			if err := svc.CreateTask(context.Background(), task, []byte("hash")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if task.NetworkMode != tt.want {
+				t.Errorf("expected network mode %q, got %q", tt.want, task.NetworkMode)
+			}
+		})
+	}
+}
+
+func TestCreateTask_NetworkModeNotAllowed(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	repo.createFunc = func(context.Context, *domain.Task) error {
+		t.Fatal("expected the task not to be created")
+		return nil
+	}
+
+	err := svc.CreateTask(context.Background(), &domain.Task{ModelID: "m1", NetworkMode: domain.NetworkBridge}, []byte("hash"))
+	if !errors.Is(err, domain.ErrNetworkModeNotAllowed) {
+		t.Fatalf("expected ErrNetworkModeNotAllowed, got %v", err)
+	}
+}
+
 func TestCreateTask_CacheHit_InitError(t *testing.T) {
 	svc, repo, _, _ := defaultSvc()
 	repo.findCachedFunc = func(_ context.Context, _ string) (string, error) { return "path", nil }
@@ -409,6 +712,39 @@ func TestCreateTask_CacheHit_InitError(t *testing.T) {
 	}
 }
 
+// ─────────────────────────────────────────────
+// ListTaskEvents
+// ─────────────────────────────────────────────
+
+func TestListTaskEvents_NotFound(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	repo.getByIdFunc = func(_ context.Context, _ uuid.UUID) (*domain.Task, error) {
+		return nil, nil
+	}
+
+	if _, err := svc.ListTaskEvents(context.Background(), uuid.New()); !errors.Is(err, domain.ErrTaskNotFound) {
+		t.Fatalf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestListTaskEvents_Success(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	repo.eventsFunc = func(_ context.Context, taskID uuid.UUID) ([]domain.TaskEvent, error) {
+		return []domain.TaskEvent{
+			{TaskID: taskID, To: domain.TaskQueued, Actor: domain.ActorAPI},
+			{TaskID: taskID, From: domain.TaskQueued, To: domain.TaskRunning, Actor: domain.ActorWorker},
+		}, nil
+	}
+
+	events, err := svc.ListTaskEvents(context.Background(), uuid.New())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 2 || events[1].To != domain.TaskRunning {
+		t.Errorf("unexpected events: %+v", events)
+	}
+}
+
 // ─────────────────────────────────────────────
 // StopTask
 // ─────────────────────────────────────────────
@@ -425,15 +761,64 @@ func TestStopTask_GetByIdError(t *testing.T) {
 	}
 }
 
-func TestStopTask_EmptyContainerID(t *testing.T) {
+func TestStopTask_NotFound(t *testing.T) {
 	svc, repo, _, _ := defaultSvc()
-	repo.getByIdFunc = func(_ context.Context, id uuid.UUID) (*domain.Task, error) {
-		return &domain.Task{ID: id, ContainerID: ""}, nil
+	repo.getByIdFunc = func(_ context.Context, _ uuid.UUID) (*domain.Task, error) {
+		return nil, nil
 	}
 
 	err := svc.StopTask(context.Background(), uuid.New(), time.Second)
-	if err == nil {
-		t.Fatal("expected error for empty container ID, got nil")
+	if !errors.Is(err, domain.ErrTaskNotFound) {
+		t.Fatalf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+// A queued task is cancelled straight from the queue.
+func TestStopTask_Queued(t *testing.T) {
+	svc, repo, mgr, ws := defaultSvc()
+	repo.getByIdFunc = func(_ context.Context, id uuid.UUID) (*domain.Task, error) {
+		return &domain.Task{ID: id, Status: domain.TaskQueued}, nil
+	}
+	var stopped *domain.Task
+	repo.markFunc = func(_ context.Context, task *domain.Task, s domain.TaskStatus) error {
+		if s == domain.TaskStopped {
+			stopped = task
+		}
+		return nil
+	}
+	mgr.stopFunc = func(context.Context, string, time.Duration) error {
+		t.Error("no container must be stopped")
+		return nil
+	}
+	id := uuid.New()
+
+	if err := svc.StopTask(context.Background(), id, time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stopped == nil || stopped.FailureReason != domain.FailureUserCancelled {
+		t.Fatalf("expected task to be stopped by the user, got %+v", stopped)
+	}
+	if len(ws.cleaned) != 1 || ws.cleaned[0] != id {
+		t.Errorf("expected workspace to be cleaned, got %v", ws.cleaned)
+	}
+}
+
+// A task whose container isn't started yet is left to its worker.
+func TestStopTask_NotStarted(t *testing.T) {
+	svc, repo, mgr, ws := defaultSvc()
+	repo.getByIdFunc = func(_ context.Context, id uuid.UUID) (*domain.Task, error) {
+		return &domain.Task{ID: id, Status: domain.TaskRunning}, nil
+	}
+	mgr.stopFunc = func(context.Context, string, time.Duration) error {
+		t.Error("no container must be stopped")
+		return nil
+	}
+
+	if err := svc.StopTask(context.Background(), uuid.New(), time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ws.cleaned) != 0 {
+		t.Errorf("expected workspace to be kept for the worker, cleaned %v", ws.cleaned)
 	}
 }
 
@@ -469,14 +854,74 @@ func TestStopTask_StopContainerError(t *testing.T) {
 
 func TestStopTask_Success(t *testing.T) {
 	svc, repo, _, _ := defaultSvc()
+	svc.config.InstanceID = "api-1"
+	var stopped *domain.Task
 	repo.getByIdFunc = func(_ context.Context, id uuid.UUID) (*domain.Task, error) {
-		return &domain.Task{ID: id, ContainerID: "ctr-1"}, nil
+		stopped = &domain.Task{ID: id, ContainerID: "ctr-1"}
+		return stopped, nil
 	}
+	var change domain.StatusChange
+	repo.changeFunc = func(_ domain.TaskStatus, c domain.StatusChange) { change = c }
 
 	err := svc.StopTask(context.Background(), uuid.New(), time.Second)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	if change.Actor != domain.ActorAPI || change.NodeID != "api-1" || change.Reason == "" {
+		t.Errorf("expected the stop to be recorded as made through the API, got %+v", change)
+	}
+	if stopped.FailureReason != domain.FailureUserCancelled {
+		t.Errorf("expected user_cancelled, got %q", stopped.FailureReason)
+	}
+}
+
+func TestStopTask_OtherNode_LeavesContainerToOwner(t *testing.T) {
+	svc, repo, mgr, _ := defaultSvc()
+	svc.config.InstanceID = "api"
+	repo.getByIdFunc = func(_ context.Context, id uuid.UUID) (*domain.Task, error) {
+		return &domain.Task{ID: id, ContainerID: "ctr-1", NodeID: "worker-1"}, nil
+	}
+	var marked domain.TaskStatus
+	repo.markFunc = func(_ context.Context, _ *domain.Task, status domain.TaskStatus) error {
+		marked = status
+		return nil
+	}
+	mgr.stopFunc = func(_ context.Context, _ string, _ time.Duration) error {
+		t.Error("container of another node must not be stopped locally")
+		return nil
+	}
+
+	if err := svc.StopTask(context.Background(), uuid.New(), time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if marked != domain.TaskStopped {
+		t.Errorf("expected task to be marked stopped, got %q", marked)
+	}
+}
+
+func TestStopStoppedTasks_StopsProcessedContainers(t *testing.T) {
+	svc, repo, mgr, _ := defaultSvc()
+	stoppedID, runningID := uuid.New(), uuid.New()
+	svc.startProcessing(stoppedID)
+	svc.startProcessing(runningID)
+
+	repo.getByIdFunc = func(_ context.Context, id uuid.UUID) (*domain.Task, error) {
+		if id == stoppedID {
+			return &domain.Task{ID: id, Status: domain.TaskStopped, ContainerID: "ctr-stopped"}, nil
+		}
+		return &domain.Task{ID: id, Status: domain.TaskRunning, ContainerID: "ctr-running"}, nil
+	}
+	var stopped []string
+	mgr.stopFunc = func(_ context.Context, id string, _ time.Duration) error {
+		stopped = append(stopped, id)
+		return nil
+	}
+
+	svc.stopStoppedTasks(context.Background())
+
+	if len(stopped) != 1 || stopped[0] != "ctr-stopped" {
+		t.Errorf("expected only the stopped task's container to be stopped, got %v", stopped)
+	}
 }
 
 // ─────────────────────────────────────────────
@@ -515,7 +960,7 @@ func TestGetTask_Success(t *testing.T) {
 func TestListTasks_Success(t *testing.T) {
 	svc, _, _, _ := defaultSvc()
 
-	tasks, total, err := svc.ListTasks(context.Background(), 1, 10)
+	tasks, total, err := svc.ListTasks(context.Background(), 1, 10, domain.TaskFilter{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -527,11 +972,32 @@ func TestListTasks_Success(t *testing.T) {
 	}
 }
 
+func TestListTasks_Filter(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	var listed, counted domain.TaskFilter
+	repo.listFunc = func(_ context.Context, _, _ int32, f domain.TaskFilter) ([]domain.Task, error) {
+		listed = f
+		return nil, nil
+	}
+	repo.countFunc = func(_ context.Context, f domain.TaskFilter) (int64, error) {
+		counted = f
+		return 0, nil
+	}
+
+	filter := domain.TaskFilter{FailureReason: domain.FailureOOMKilled}
+	if _, _, err := svc.ListTasks(context.Background(), 1, 10, filter); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if listed != filter || counted != filter {
+		t.Errorf("expected the filter to be passed to the repository, got %+v and %+v", listed, counted)
+	}
+}
+
 func TestListTasks_DefaultPagination(t *testing.T) {
 	svc, _, _, _ := defaultSvc()
 
 	// page=0 and pageSize=0 should be corrected to 1 and 10
-	_, _, err := svc.ListTasks(context.Background(), 0, 0)
+	_, _, err := svc.ListTasks(context.Background(), 0, 0, domain.TaskFilter{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -539,11 +1005,11 @@ func TestListTasks_DefaultPagination(t *testing.T) {
 
 func TestListTasks_PaginatedError(t *testing.T) {
 	svc, repo, _, _ := defaultSvc()
-	repo.listFunc = func(_ context.Context, _ int32, _ int32) ([]domain.Task, error) {
+	repo.listFunc = func(_ context.Context, _ int32, _ int32, _ domain.TaskFilter) ([]domain.Task, error) {
 		return nil, errors.New("db error")
 	}
 
-	_, _, err := svc.ListTasks(context.Background(), 1, 10)
+	_, _, err := svc.ListTasks(context.Background(), 1, 10, domain.TaskFilter{})
 	if err == nil {
 		t.Fatal("expected error from GetTasksPaginated, got nil")
 	}
@@ -551,16 +1017,41 @@ func TestListTasks_PaginatedError(t *testing.T) {
 
 func TestListTasks_CountError(t *testing.T) {
 	svc, repo, _, _ := defaultSvc()
-	repo.countFunc = func(_ context.Context) (int64, error) {
+	repo.countFunc = func(_ context.Context, _ domain.TaskFilter) (int64, error) {
 		return 0, errors.New("count error")
 	}
 
-	_, _, err := svc.ListTasks(context.Background(), 1, 10)
+	_, _, err := svc.ListTasks(context.Background(), 1, 10, domain.TaskFilter{})
 	if err == nil {
 		t.Fatal("expected error from GetTasksCount, got nil")
 	}
 }
 
+// ─────────────────────────────────────────────
+// SetPinned
+// ─────────────────────────────────────────────
+
+func TestSetPinned_Success(t *testing.T) {
+	svc, _, _, _ := defaultSvc()
+
+	task, err := svc.SetPinned(context.Background(), uuid.New(), true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !task.Pinned {
+		t.Error("expected task to be pinned")
+	}
+}
+
+func TestSetPinned_NotFound(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	repo.setPinnedFunc = func(context.Context, uuid.UUID, bool) (*domain.Task, error) { return nil, nil }
+
+	if _, err := svc.SetPinned(context.Background(), uuid.New(), true); !errors.Is(err, domain.ErrTaskNotFound) {
+		t.Fatalf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
 // ─────────────────────────────────────────────
 // DeleteTask
 // ─────────────────────────────────────────────
@@ -598,38 +1089,123 @@ func TestDeleteTask_RepoError(t *testing.T) {
 }
 
 func TestDeleteTask_Success(t *testing.T) {
-	svc, _, _, _ := defaultSvc()
+	svc, repo, _, _ := defaultSvc()
+	id := uuid.New()
 
-	err := svc.DeleteTask(context.Background(), uuid.New())
+	var deletedSums string
+	repo.deleteSumsFunc = func(_ context.Context, prefix string) error {
+		deletedSums = prefix
+		return nil
+	}
+
+	err := svc.DeleteTask(context.Background(), id)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	if want := "tasks/" + id.String() + "/"; deletedSums != want {
+		t.Errorf("expected checksums of %q to be deleted, got %q", want, deletedSums)
+	}
 }
 
-// ─────────────────────────────────────────────
-// GetResultURL
-// ─────────────────────────────────────────────
-
-func TestGetResultURL_GetByIdError(t *testing.T) {
+// The result of a task is kept while tasks taken from the cache use it.
+func TestDeleteTask_SharedResult_Kept(t *testing.T) {
 	svc, repo, _, _ := defaultSvc()
-	repo.getByIdFunc = func(_ context.Context, _ uuid.UUID) (*domain.Task, error) {
-		return nil, errors.New("db error")
+	id := uuid.New()
+
+	repo.refsFunc = func(_ context.Context, _ uuid.UUID, prefix string) (int64, error) {
+		return 1, nil
+	}
+	repo.deleteSumsFunc = func(_ context.Context, prefix string) error {
+		t.Errorf("expected the checksums of %s to be kept", prefix)
+		return nil
+	}
+	var deleted bool
+	repo.deleteFunc = func(context.Context, uuid.UUID) error {
+		deleted = true
+		return nil
 	}
 
-	_, err := svc.GetResultURL(context.Background(), uuid.New())
-	if err == nil {
-		t.Fatal("expected error, got nil")
+	if err := svc.DeleteTask(context.Background(), id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !deleted {
+		t.Error("expected the task to be deleted")
 	}
 }
 
-func TestGetResultURL_NotCompleted(t *testing.T) {
+// A task taken from the cache releases the result it points at.
+func TestDeleteTask_CachedTask_DeletesUnusedSource(t *testing.T) {
 	svc, repo, _, _ := defaultSvc()
+	id, source := uuid.New(), uuid.New()
+
 	repo.getByIdFunc = func(_ context.Context, id uuid.UUID) (*domain.Task, error) {
-		return &domain.Task{ID: id, Status: domain.TaskRunning}, nil
+		return &domain.Task{ID: id, Status: domain.TaskCompleted, ResultPath: "tasks/" + source.String() + "/result.txt"}, nil
+	}
+	var deletedSums []string
+	repo.deleteSumsFunc = func(_ context.Context, prefix string) error {
+		deletedSums = append(deletedSums, prefix)
+		return nil
 	}
 
-	url, err := svc.GetResultURL(context.Background(), uuid.New())
-	if err != nil {
+	if err := svc.DeleteTask(context.Background(), id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"tasks/" + id.String() + "/", "tasks/" + source.String() + "/"}
+	if !slices.Equal(deletedSums, want) {
+		t.Errorf("expected checksums of %v to be deleted, got %v", want, deletedSums)
+	}
+}
+
+func TestDeleteTask_RefsError(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	repo.refsFunc = func(context.Context, uuid.UUID, string) (int64, error) {
+		return 0, errors.New("db error")
+	}
+	repo.deleteFunc = func(context.Context, uuid.UUID) error {
+		t.Error("expected the task not to be deleted")
+		return nil
+	}
+
+	if err := svc.DeleteTask(context.Background(), uuid.New()); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+// ─────────────────────────────────────────────
+// GetResultURL
+// ─────────────────────────────────────────────
+
+func TestGetResultURL_GetByIdError(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	repo.getByIdFunc = func(_ context.Context, _ uuid.UUID) (*domain.Task, error) {
+		return nil, errors.New("db error")
+	}
+
+	_, err := svc.GetResultURL(context.Background(), uuid.New(), 0)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestGetResultURL_NotFound(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	repo.getByIdFunc = func(context.Context, uuid.UUID) (*domain.Task, error) {
+		return nil, nil
+	}
+
+	if _, err := svc.GetResultURL(context.Background(), uuid.New(), 0); !errors.Is(err, domain.ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestGetResultURL_NotCompleted(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	repo.getByIdFunc = func(_ context.Context, id uuid.UUID) (*domain.Task, error) {
+		return &domain.Task{ID: id, Status: domain.TaskRunning}, nil
+	}
+
+	url, err := svc.GetResultURL(context.Background(), uuid.New(), 0)
+	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if url != "" {
@@ -643,7 +1219,7 @@ func TestGetResultURL_StorageError(t *testing.T) {
 		return &domain.Task{ID: id, Status: domain.TaskCompleted, ResultPath: "fail"}, nil
 	}
 
-	_, err := svc.GetResultURL(context.Background(), uuid.New())
+	_, err := svc.GetResultURL(context.Background(), uuid.New(), 0)
 	if err == nil {
 		t.Fatal("expected storage error, got nil")
 	}
@@ -655,12 +1231,36 @@ func TestGetResultURL_Success(t *testing.T) {
 		return &domain.Task{ID: id, Status: domain.TaskCompleted, ResultPath: "some-key"}, nil
 	}
 
-	url, err := svc.GetResultURL(context.Background(), uuid.New())
+	url, err := svc.GetResultURL(context.Background(), uuid.New(), 0)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if url == "" {
-		t.Error("expected non-empty URL")
+	if url != "https://example.com/result?expires=600" {
+		t.Errorf("expected URL with default expiry, got %q", url)
+	}
+}
+
+func TestGetResultURL_CustomExpiry(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	repo.getByIdFunc = func(_ context.Context, id uuid.UUID) (*domain.Task, error) {
+		return &domain.Task{ID: id, Status: domain.TaskCompleted, ResultPath: "some-key"}, nil
+	}
+
+	url, err := svc.GetResultURL(context.Background(), uuid.New(), 30*time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if url != "https://example.com/result?expires=1800" {
+		t.Errorf("expected URL with requested expiry, got %q", url)
+	}
+}
+
+func TestGetResultURL_ExpiryOverMax(t *testing.T) {
+	svc, _, _, _ := defaultSvc()
+
+	_, err := svc.GetResultURL(context.Background(), uuid.New(), 2*time.Hour)
+	if !errors.Is(err, domain.ErrInvalidExpiry) {
+		t.Fatalf("expected ErrInvalidExpiry, got %v", err)
 	}
 }
 
@@ -679,6 +1279,31 @@ func TestRecoverTask_AddsToQueue(t *testing.T) {
 	}
 }
 
+// A task recovered twice, or already processed by a worker, must be queued once.
+func TestRecoverTask_Dedup(t *testing.T) {
+	svc, _, _, _ := defaultSvc()
+
+	task := &domain.Task{ID: uuid.New()}
+	svc.RecoverTask(task)
+	svc.RecoverTask(task)
+
+	processed := &domain.Task{ID: uuid.New()}
+	svc.startProcessing(processed.ID)
+	svc.RecoverTask(processed)
+
+	if len(svc.recoverTaskQueue) != 1 {
+		t.Fatalf("expected queue length 1, got %d", len(svc.recoverTaskQueue))
+	}
+	if !svc.IsProcessing(task.ID) {
+		t.Error("expected recovered task to be reported as processing")
+	}
+
+	svc.finishProcessing(task.ID)
+	if svc.IsProcessing(task.ID) {
+		t.Error("expected task to stop being processed")
+	}
+}
+
 func TestGetNextRecoverTask_EmptyQueue(t *testing.T) {
 	svc, _, _, _ := defaultSvc()
 
@@ -751,6 +1376,25 @@ func TestGetFinalHash_DifferentInputDifferentHash(t *testing.T) {
 	}
 }
 
+func TestGetFinalHash_Params(t *testing.T) {
+	task := &domain.Task{ModelID: "m1", ContainerEnvs: []string{"A=1"}}
+	plain, _ := getFinalHash(task, []byte("hash"))
+
+	// tasks without params keep the signature they had before params
+	want := sha256.Sum256([]byte("m1|A=1||hash"))
+	if plain != hex.EncodeToString(want[:]) {
+		t.Errorf("expected the signature of a task without params to be unchanged, got %s", plain)
+	}
+
+	task.Params = []byte(`{"epochs":1000}`)
+	h1, _ := getFinalHash(task, []byte("hash"))
+	task.Params = []byte(`{"epochs":2000}`)
+	h2, _ := getFinalHash(task, []byte("hash"))
+	if h1 == plain || h1 == h2 {
+		t.Error("expected params to change the signature")
+	}
+}
+
 func TestGetFinalHash_NonEmpty(t *testing.T) {
 	task := &domain.Task{ModelID: "m"}
 	hash, err := getFinalHash(task, []byte("h"))
@@ -767,35 +1411,68 @@ func TestGetFinalHash_NonEmpty(t *testing.T) {
 // ─────────────────────────────────────────────
 
 func TestWaitAndSaveTask_DeadlineExceeded(t *testing.T) {
-	svc, _, mgr, _ := defaultSvc()
-	mgr.waitFunc = func(_ context.Context, _ string) (int64, error) {
-		return 0, context.DeadlineExceeded
+	svc, repo, mgr, _ := defaultSvc()
+	mgr.waitFunc = func(_ context.Context, _ string) (*domain.ContainerExit, error) {
+		return nil, context.DeadlineExceeded
+	}
+	var marked domain.TaskStatus
+	repo.markFunc = func(_ context.Context, _ *domain.Task, s domain.TaskStatus) error {
+		marked = s
+		return nil
 	}
 
 	task := &domain.Task{ID: uuid.New(), ContainerID: "ctr-1"}
 	err := svc.waitAndSaveTask(context.Background(), task)
-	if err == nil {
-		t.Fatal("expected timeout error, got nil")
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if marked != domain.TaskStopped || task.FailureReason != domain.FailureTimeout {
+		t.Errorf("expected the task to be stopped for the timeout, got %s / %q", marked, task.FailureReason)
 	}
 }
 
 func TestWaitAndSaveTask_ContextCanceled(t *testing.T) {
 	svc, _, mgr, _ := defaultSvc()
-	mgr.waitFunc = func(_ context.Context, _ string) (int64, error) {
-		return 0, context.Canceled
+	mgr.waitFunc = func(_ context.Context, _ string) (*domain.ContainerExit, error) {
+		return nil, context.Canceled
 	}
 
 	task := &domain.Task{ID: uuid.New(), ContainerID: "ctr-1"}
 	err := svc.waitAndSaveTask(context.Background(), task)
-	if err == nil {
-		t.Fatal("expected canceled error, got nil")
+	if err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Fatalf("expected canceled error, got %v", err)
+	}
+	if task.FailureReason != domain.FailureInfrastructure {
+		t.Errorf("expected infrastructure failure, got %q", task.FailureReason)
+	}
+}
+
+// A task stopped on its timeout is final and is not marked failed afterwards.
+func TestProcessTask_Timeout_NotMarkedFailed(t *testing.T) {
+	svc, repo, mgr, _ := defaultSvc()
+	mgr.waitFunc = func(context.Context, string) (*domain.ContainerExit, error) {
+		return nil, context.DeadlineExceeded
+	}
+	var marked []domain.TaskStatus
+	repo.markFunc = func(_ context.Context, task *domain.Task, s domain.TaskStatus) error {
+		marked = append(marked, s)
+		task.Status = s
+		return nil
+	}
+
+	task := &domain.Task{ID: uuid.New(), ContainerImage: "img:latest"}
+	if err := svc.processTask(context.Background(), task); err == nil {
+		t.Fatal("expected timeout error, got nil")
+	}
+	if !slices.Equal(marked, []domain.TaskStatus{domain.TaskRunning, domain.TaskStopped}) {
+		t.Errorf("expected running and stopped marks only, got %v", marked)
 	}
 }
 
 func TestWaitAndSaveTask_WaitError_LogsError(t *testing.T) {
 	svc, _, mgr, _ := defaultSvc()
-	mgr.waitFunc = func(_ context.Context, _ string) (int64, error) {
-		return 0, errors.New("container crashed")
+	mgr.waitFunc = func(_ context.Context, _ string) (*domain.ContainerExit, error) {
+		return nil, errors.New("container crashed")
 	}
 	mgr.logsFunc = func(_ context.Context, _ string, _ bool) (io.ReadCloser, error) {
 		return nil, errors.New("logs unavailable")
@@ -810,8 +1487,8 @@ func TestWaitAndSaveTask_WaitError_LogsError(t *testing.T) {
 
 func TestWaitAndSaveTask_WaitError_WithLogs(t *testing.T) {
 	svc, _, mgr, _ := defaultSvc()
-	mgr.waitFunc = func(_ context.Context, _ string) (int64, error) {
-		return 0, errors.New("container crashed")
+	mgr.waitFunc = func(_ context.Context, _ string) (*domain.ContainerExit, error) {
+		return nil, errors.New("container crashed")
 	}
 	// logs returns valid docker multiplexed stream (stderr stream type = 0x02)
 	mgr.logsFunc = func(_ context.Context, _ string, _ bool) (io.ReadCloser, error) {
@@ -832,7 +1509,9 @@ func TestWaitAndSaveTask_WaitError_WithLogs(t *testing.T) {
 
 func TestWaitAndSaveTask_NonZeroExitCode_LogError(t *testing.T) {
 	svc, _, mgr, _ := defaultSvc()
-	mgr.waitFunc = func(_ context.Context, _ string) (int64, error) { return 1, nil }
+	mgr.waitFunc = func(_ context.Context, _ string) (*domain.ContainerExit, error) {
+		return &domain.ContainerExit{ExitCode: 1}, nil
+	}
 	mgr.logsFunc = func(_ context.Context, _ string, _ bool) (io.ReadCloser, error) {
 		return nil, errors.New("logs error")
 	}
@@ -846,13 +1525,39 @@ func TestWaitAndSaveTask_NonZeroExitCode_LogError(t *testing.T) {
 
 func TestWaitAndSaveTask_NonZeroExitCode(t *testing.T) {
 	svc, _, mgr, _ := defaultSvc()
-	mgr.waitFunc = func(_ context.Context, _ string) (int64, error) { return 2, nil }
+	mgr.waitFunc = func(_ context.Context, _ string) (*domain.ContainerExit, error) {
+		return &domain.ContainerExit{ExitCode: 2}, nil
+	}
 
 	task := &domain.Task{ID: uuid.New(), ContainerID: "ctr-1"}
 	err := svc.waitAndSaveTask(context.Background(), task)
 	if err == nil {
 		t.Fatal("expected error for non-zero exit code, got nil")
 	}
+	if task.FailureReason != domain.FailureNonzeroExit || task.ExitCode == nil || *task.ExitCode != 2 {
+		t.Errorf("expected nonzero_exit with code 2, got %q / %v", task.FailureReason, task.ExitCode)
+	}
+}
+
+func TestWaitAndSaveTask_OOMKilled(t *testing.T) {
+	svc, _, mgr, _ := defaultSvc()
+	mgr.waitFunc = func(_ context.Context, _ string) (*domain.ContainerExit, error) {
+		return &domain.ContainerExit{ExitCode: 137, OOMKilled: true, Signal: "9"}, nil
+	}
+	mgr.logsFunc = func(_ context.Context, _ string, _ bool) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	task := &domain.Task{ID: uuid.New(), ContainerID: "ctr-1", MemLim: 512}
+	if err := svc.waitAndSaveTask(context.Background(), task); err == nil {
+		t.Fatal("expected error for an OOM killed container, got nil")
+	}
+	if !strings.Contains(task.ErrorLog, "out of memory") {
+		t.Errorf("expected the OOM kill in the error log, got %q", task.ErrorLog)
+	}
+	if task.FailureReason != domain.FailureOOMKilled || task.ExitCode == nil || *task.ExitCode != 137 {
+		t.Errorf("expected oom_killed with code 137, got %q / %v", task.FailureReason, task.ExitCode)
+	}
 }
 
 func TestWaitAndSaveTask_GetTaskByIdError(t *testing.T) {
@@ -891,6 +1596,66 @@ func TestWaitAndSaveTask_UploadError(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected upload error, got nil")
 	}
+	if !task.UploadFailed || task.ErrorLog == "" {
+		t.Errorf("expected task to be marked as upload failed, got %+v", task)
+	}
+	if task.FailureReason != domain.FailureUpload {
+		t.Errorf("expected upload_failed, got %q", task.FailureReason)
+	}
+}
+
+func TestWaitAndSaveTask_SavesChecksums(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	var saved []domain.Artifact
+	repo.saveSumsFunc = func(_ context.Context, _ uuid.UUID, objects []domain.Artifact) error {
+		saved = objects
+		return nil
+	}
+
+	task := &domain.Task{ID: uuid.New(), ContainerID: "ctr-1"}
+	if err := svc.waitAndSaveTask(context.Background(), task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(saved) != 1 || saved[0].Key != "result-key" || saved[0].SHA256 != "abc" {
+		t.Errorf("unexpected saved checksums %+v", saved)
+	}
+}
+
+// A result whose checksums couldn't be recorded is handled like a failed upload.
+func TestWaitAndSaveTask_SaveChecksumsError(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	repo.saveSumsFunc = func(context.Context, uuid.UUID, []domain.Artifact) error {
+		return errors.New("db down")
+	}
+
+	task := &domain.Task{ID: uuid.New(), ContainerID: "ctr-1"}
+	if err := svc.waitAndSaveTask(context.Background(), task); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !task.UploadFailed || task.ResultPath != "" {
+		t.Errorf("expected task to be marked as upload failed, got %+v", task)
+	}
+}
+
+// Progress is saved throttled, but the first and the final progress always are.
+func TestWaitAndSaveTask_SavesUploadProgress(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	svc.config.Upload.ProgressInterval = time.Hour
+	var saved [][2]int64
+	repo.progressFunc = func(_ context.Context, _ uuid.UUID, total, done int64) error {
+		saved = append(saved, [2]int64{done, total})
+		return nil
+	}
+
+	task := &domain.Task{ID: uuid.New(), ContainerID: "ctr-1"}
+	if err := svc.waitAndSaveTask(context.Background(), task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(saved) != 2 || saved[0] != [2]int64{0, 10} || saved[1] != [2]int64{10, 10} {
+		t.Errorf("unexpected saved progress %v", saved)
+	}
 }
 
 func TestWaitAndSaveTask_MarkCompletedError(t *testing.T) {
@@ -911,6 +1676,18 @@ func TestWaitAndSaveTask_MarkCompletedError(t *testing.T) {
 	}
 }
 
+func TestWaitAndSaveTask_StoppedWhileUploading(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	repo.markFunc = func(_ context.Context, task *domain.Task, s domain.TaskStatus) error {
+		return &domain.TransitionError{TaskID: task.ID, From: domain.TaskStopped, To: s, Err: domain.ErrInvalidTransition}
+	}
+
+	task := &domain.Task{ID: uuid.New(), ContainerID: "ctr-1"}
+	if err := svc.waitAndSaveTask(context.Background(), task); err != nil {
+		t.Fatalf("expected a task stopped meanwhile to be left stopped, got %v", err)
+	}
+}
+
 func TestWaitAndSaveTask_Success(t *testing.T) {
 	svc, _, _, _ := defaultSvc()
 
@@ -954,9 +1731,48 @@ func TestProcessTask_StartContainerError(t *testing.T) {
 	}
 }
 
+func TestProcessTask_StartFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want domain.FailureReason
+	}{
+		{"image pull", fmt.Errorf("pre-pulling image: %w: not found", domain.ErrImagePull), domain.FailureImagePull},
+		{"container start", errors.New("cannot start container"), domain.FailureContainerStart},
+		{"no matching host", domain.ErrNoMatchingHost, domain.FailureContainerStart},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo, mgr, _ := defaultSvc()
+			mgr.startFunc = func(context.Context, *domain.ContainerConfig) (string, error) {
+				return "", tt.err
+			}
+			var failed *domain.Task
+			repo.markFunc = func(_ context.Context, task *domain.Task, s domain.TaskStatus) error {
+				if s == domain.TaskFailed {
+					failed = task
+				}
+				return nil
+			}
+
+			task := &domain.Task{ID: uuid.New(), ContainerImage: "img:latest"}
+			if err := svc.processTask(context.Background(), task); err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if failed == nil || failed.FailureReason != tt.want {
+				t.Errorf("expected the task to be failed with %q, got %+v", tt.want, failed)
+			}
+		})
+	}
+}
+
 func TestProcessTask_MarkRunningError(t *testing.T) {
 	svc, repo, _, _ := defaultSvc()
-	repo.markFunc = func(_ context.Context, _ *domain.Task, s domain.TaskStatus) error {
+	var failed *domain.Task
+	repo.markFunc = func(_ context.Context, task *domain.Task, s domain.TaskStatus) error {
+		if s == domain.TaskFailed {
+			failed = task
+		}
 		if s == domain.TaskRunning {
 			return errors.New("mark running failed")
 		}
@@ -968,6 +1784,10 @@ func TestProcessTask_MarkRunningError(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error from Mark(running), got nil")
 	}
+	// a failure outside of the steps of the task is blamed on the service
+	if failed == nil || failed.FailureReason != domain.FailureInfrastructure {
+		t.Errorf("expected an infrastructure failure, got %+v", failed)
+	}
 }
 
 func TestProcessTask_Success(t *testing.T) {
@@ -978,6 +1798,400 @@ func TestProcessTask_Success(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	if task.ExitCode == nil || *task.ExitCode != 0 || task.FailureReason != "" {
+		t.Errorf("expected exit code 0 and no failure reason, got %v / %q", task.ExitCode, task.FailureReason)
+	}
+}
+
+// A failed upload keeps the workspace and marks the task as upload failed.
+func TestProcessTask_UploadFailedKeepsWorkspace(t *testing.T) {
+	svc, repo, _, ws := defaultSvc()
+	var failed *domain.Task
+	repo.markFunc = func(_ context.Context, task *domain.Task, s domain.TaskStatus) error {
+		if s == domain.TaskFailed {
+			failed = task
+		}
+		return nil
+	}
+
+	task := &domain.Task{ID: mustParseUUID("00000000-0000-0000-0000-000000000001"), ContainerImage: "img:latest"}
+	if err := svc.processTask(context.Background(), task); err == nil {
+		t.Fatal("expected upload error, got nil")
+	}
+
+	if failed == nil || !failed.UploadFailed {
+		t.Fatalf("expected task marked failed with UploadFailed, got %+v", failed)
+	}
+	if len(ws.cleaned) != 0 {
+		t.Errorf("expected workspace to be kept, cleaned %v", ws.cleaned)
+	}
+}
+
+// A task whose hosts are busy is queued again with its input and isn't taken
+// again for a worker interval, the other queued tasks are.
+func TestProcessTask_HostsBusy_QueuesTaskAgain(t *testing.T) {
+	svc, repo, mgr, ws := defaultSvc()
+	svc.config.Worker.Interval = time.Hour
+	mgr.startFunc = func(context.Context, *domain.ContainerConfig) (string, error) {
+		return "", fmt.Errorf("placing container: %w", domain.ErrHostsBusy)
+	}
+	var marked []domain.TaskStatus
+	repo.markFunc = func(_ context.Context, _ *domain.Task, s domain.TaskStatus) error {
+		marked = append(marked, s)
+		return nil
+	}
+	var reason string
+	repo.changeFunc = func(_ domain.TaskStatus, c domain.StatusChange) { reason = c.Reason }
+
+	task := &domain.Task{ID: uuid.New(), ContainerImage: "img:latest"}
+	if err := svc.processTask(context.Background(), task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(marked) != 1 || marked[0] != domain.TaskQueued {
+		t.Errorf("expected the task to be queued again, got %v", marked)
+	}
+	if !strings.Contains(reason, domain.ErrHostsBusy.Error()) {
+		t.Errorf("expected the busy hosts to be recorded as the reason, got %q", reason)
+	}
+	if len(ws.cleaned) != 0 {
+		t.Errorf("expected the workspace to be kept, cleaned %v", ws.cleaned)
+	}
+	if task.RetryAt == nil || time.Until(*task.RetryAt) < 59*time.Minute {
+		t.Errorf("expected the task to be retried after a worker interval, got %v", task.RetryAt)
+	}
+}
+
+func TestProcessTask_NoMatchingHost_FailsTask(t *testing.T) {
+	svc, repo, mgr, _ := defaultSvc()
+	var got *domain.ContainerConfig
+	mgr.startFunc = func(_ context.Context, c *domain.ContainerConfig) (string, error) {
+		got = c
+		return "", domain.ErrNoMatchingHost
+	}
+	var failed *domain.Task
+	repo.markFunc = func(_ context.Context, task *domain.Task, s domain.TaskStatus) error {
+		if s == domain.TaskFailed {
+			failed = task
+		}
+		return nil
+	}
+
+	task := &domain.Task{
+		ID: uuid.New(), ContainerImage: "img:latest", CPULim: 200, MemLim: 1024,
+		GPUEnabled: true, Constraints: map[string]string{"arch": "arm64"},
+	}
+	if err := svc.processTask(context.Background(), task); !errors.Is(err, domain.ErrNoMatchingHost) {
+		t.Fatalf("expected ErrNoMatchingHost, got %v", err)
+	}
+	if got.CPULimit != 200 || got.MemoryLimit != 1024 || !got.GPU || got.Constraints["arch"] != "arm64" {
+		t.Errorf("expected the task resources in the container config, got %+v", got)
+	}
+	if failed == nil || failed.ErrorLog == "" {
+		t.Errorf("expected the task to be failed with the reason, got %+v", failed)
+	}
+}
+
+func TestProcessTask_NetworkConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      domain.NetworkMode
+		network   string
+		wantProxy bool
+	}{
+		{"none", domain.NetworkNone, "", false},
+		{"internal", domain.NetworkInternal, "pinn-internal", false},
+		{"egress", domain.NetworkEgress, "pinn-egress", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _, mgr, _ := defaultSvc()
+			svc.config.Docker.EgressProxy = "http://proxy:3128"
+			var got *domain.ContainerConfig
+			mgr.startFunc = func(_ context.Context, c *domain.ContainerConfig) (string, error) {
+				got = c
+				return "", domain.ErrNoMatchingHost
+			}
+
+			task := &domain.Task{ID: uuid.New(), ContainerImage: "img:latest", NetworkMode: tt.mode, ContainerEnvs: []string{"A=1"}}
+			_ = svc.processTask(context.Background(), task)
+
+			if got.NetworkMode != tt.mode || got.Network != tt.network {
+				t.Errorf("expected network %q/%q, got %q/%q", tt.mode, tt.network, got.NetworkMode, got.Network)
+			}
+			if slices.Contains(got.Envs, "HTTPS_PROXY=http://proxy:3128") != tt.wantProxy {
+				t.Errorf("unexpected proxy variables in %v", got.Envs)
+			}
+			if !slices.Contains(got.Envs, "A=1") {
+				t.Errorf("expected the task envs to be kept, got %v", got.Envs)
+			}
+		})
+	}
+}
+
+// The security profile of the service is overridden by the model.
+func TestProcessTask_SecurityProfile(t *testing.T) {
+	user := ""
+	readOnly := false
+	mr := &mockModelRepo{
+		getByIDFunc: func(_ context.Context, id string) (*domain.Model, error) {
+			return &domain.Model{ID: id, ContainerImage: "img:latest", Security: &domain.ModelSecurity{
+				User: &user, CapAdd: []string{"NET_ADMIN"}, ReadOnlyRootfs: &readOnly,
+			}}, nil
+		},
+	}
+	mgr := &mockContainerManager{}
+	var got *domain.ContainerConfig
+	mgr.startFunc = func(_ context.Context, c *domain.ContainerConfig) (string, error) {
+		got = c
+		return "", domain.ErrNoMatchingHost
+	}
+	svc := buildSvc(&mockRepository{}, mgr, &mockWorkspace{}, mr)
+	svc.config.Docker.Security = config.SecurityConfig{
+		User:             "65534:65534",
+		DropCapabilities: true,
+		CapAdd:           []string{"CHOWN"},
+		NoNewPrivileges:  true,
+		ReadOnlyRootfs:   true,
+		TmpfsSizeMB:      64,
+		PidsLimit:        256,
+		Ulimits:          map[string]string{"nofile": "1024:4096", "core": "0"},
+		WorkspaceGID:     65534,
+	}
+
+	task := &domain.Task{ID: uuid.New(), ModelID: "m1", ContainerImage: "img:latest"}
+	_ = svc.processTask(context.Background(), task)
+
+	want := domain.SecurityProfile{
+		User:             "",
+		DropCapabilities: true,
+		CapAdd:           []string{"CHOWN", "NET_ADMIN"},
+		NoNewPrivileges:  true,
+		ReadOnlyRootfs:   false,
+		TmpfsSizeMB:      64,
+		PidsLimit:        256,
+		Ulimits:          []domain.Ulimit{{Name: "core"}, {Name: "nofile", Soft: 1024, Hard: 4096}},
+		GroupAdd:         []string{"65534"},
+	}
+	if got == nil || !reflect.DeepEqual(got.Security, want) {
+		t.Errorf("expected security profile %+v, got %+v", want, got)
+	}
+}
+
+// ─────────────────────────────────────────────
+// holdLease
+// ─────────────────────────────────────────────
+
+func TestHoldLease_RenewsUntilReleased(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	svc.config.Worker.LeaseDuration = 3 * time.Millisecond
+	var mu sync.Mutex
+	renewed := 0
+	repo.renewFunc = func(context.Context, uuid.UUID) (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		renewed++
+		return true, nil
+	}
+
+	ctx, unlease := svc.holdLease(context.Background(), uuid.New())
+	time.Sleep(20 * time.Millisecond)
+	unlease()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if renewed == 0 {
+		t.Error("expected the lease to be renewed")
+	}
+	if errors.Is(context.Cause(ctx), errLeaseLost) {
+		t.Error("expected the lease to be kept")
+	}
+}
+
+// A task reclaimed by another node is left to it: the container keeps running
+// and the task is neither marked nor cleaned up.
+func TestProcessTask_LeaseLost_LeavesTaskToNewOwner(t *testing.T) {
+	svc, repo, mgr, ws := defaultSvc()
+	svc.config.Worker.LeaseDuration = 3 * time.Millisecond
+	repo.renewFunc = func(context.Context, uuid.UUID) (bool, error) { return false, nil }
+	var marked []domain.TaskStatus
+	repo.markFunc = func(_ context.Context, _ *domain.Task, s domain.TaskStatus) error {
+		marked = append(marked, s)
+		return nil
+	}
+	mgr.waitFunc = func(ctx context.Context, _ string) (*domain.ContainerExit, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	stopped := false
+	mgr.stopFunc = func(context.Context, string, time.Duration) error {
+		stopped = true
+		return nil
+	}
+	usageSaved := false
+	repo.usageFunc = func(context.Context, uuid.UUID, domain.ResourceUsage) error {
+		usageSaved = true
+		return nil
+	}
+
+	task := &domain.Task{ID: uuid.New(), ContainerImage: "img:latest"}
+	ctx, unlease := svc.holdLease(context.Background(), task.ID)
+	defer unlease()
+
+	if err := svc.processTask(ctx, task); !errors.Is(err, errLeaseLost) {
+		t.Fatalf("expected errLeaseLost, got %v", err)
+	}
+	if stopped || len(mgr.removed) != 0 {
+		t.Errorf("expected the container to be left running, stopped=%v removed=%v", stopped, mgr.removed)
+	}
+	if len(marked) != 1 || marked[0] != domain.TaskRunning {
+		t.Errorf("expected only the running mark, got %v", marked)
+	}
+	if usageSaved {
+		t.Error("expected the usage to be left to the new owner")
+	}
+	if len(ws.cleaned) != 0 {
+		t.Errorf("expected the workspace to be kept, cleaned %v", ws.cleaned)
+	}
+}
+
+// On a shutdown of the node a running task is left for the GC to reclaim
+// instead of being stopped for good.
+func TestProcessTask_Shutdown_LeavesTaskRunning(t *testing.T) {
+	svc, repo, mgr, ws := defaultSvc()
+	var marked []domain.TaskStatus
+	repo.markFunc = func(_ context.Context, _ *domain.Task, s domain.TaskStatus) error {
+		marked = append(marked, s)
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	mgr.waitFunc = func(waitCtx context.Context, _ string) (*domain.ContainerExit, error) {
+		cancel()
+		<-waitCtx.Done()
+		return nil, waitCtx.Err()
+	}
+	stopped := false
+	mgr.stopFunc = func(context.Context, string, time.Duration) error {
+		stopped = true
+		return nil
+	}
+
+	task := &domain.Task{ID: uuid.New(), ContainerImage: "img:latest"}
+	leaseCtx, unlease := svc.holdLease(ctx, task.ID)
+	defer unlease()
+
+	if err := svc.processTask(leaseCtx, task); !errors.Is(err, errShutdown) {
+		t.Fatalf("expected errShutdown, got %v", err)
+	}
+	if slices.Contains(marked, domain.TaskStopped) || len(marked) != 1 || marked[0] != domain.TaskRunning {
+		t.Errorf("expected the task to be left running, got marks %v", marked)
+	}
+	if stopped || len(mgr.removed) != 0 {
+		t.Errorf("expected the container to be left running, stopped=%v removed=%v", stopped, mgr.removed)
+	}
+	if len(ws.cleaned) != 0 {
+		t.Errorf("expected the workspace to be kept, cleaned %v", ws.cleaned)
+	}
+}
+
+// ─────────────────────────────────────────────
+// RetryUpload
+// ─────────────────────────────────────────────
+
+func uploadFailedRepo(repo *mockRepository) {
+	repo.getByIdFunc = func(_ context.Context, id uuid.UUID) (*domain.Task, error) {
+		return &domain.Task{ID: id, Status: domain.TaskFailed, UploadFailed: true, TimeoutSec: 1}, nil
+	}
+}
+
+func TestRetryUpload_NotFound(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	repo.getByIdFunc = func(_ context.Context, _ uuid.UUID) (*domain.Task, error) { return nil, nil }
+
+	if err := svc.RetryUpload(context.Background(), uuid.New()); !errors.Is(err, domain.ErrTaskNotFound) {
+		t.Fatalf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestRetryUpload_NotUploadFailed(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	repo.getByIdFunc = func(_ context.Context, id uuid.UUID) (*domain.Task, error) {
+		return &domain.Task{ID: id, Status: domain.TaskFailed}, nil
+	}
+
+	if err := svc.RetryUpload(context.Background(), uuid.New()); !errors.Is(err, domain.ErrUploadNotFailed) {
+		t.Fatalf("expected ErrUploadNotFailed, got %v", err)
+	}
+}
+
+func TestRetryUpload_AlreadyQueued(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	uploadFailedRepo(repo)
+	id := uuid.New()
+
+	if err := svc.RetryUpload(context.Background(), id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.RetryUpload(context.Background(), id); !errors.Is(err, domain.ErrTaskInProgress) {
+		t.Fatalf("expected ErrTaskInProgress, got %v", err)
+	}
+	if len(svc.uploadQueue) != 1 {
+		t.Errorf("expected queue length 1, got %d", len(svc.uploadQueue))
+	}
+}
+
+func TestProcessQueue_RetriesUpload(t *testing.T) {
+	svc, repo, _, ws := defaultSvc()
+	uploadFailedRepo(repo)
+	var completed *domain.Task
+	repo.markFunc = func(_ context.Context, task *domain.Task, s domain.TaskStatus) error {
+		if s == domain.TaskCompleted {
+			completed = task
+		}
+		return nil
+	}
+	id := uuid.New()
+
+	if err := svc.RetryUpload(context.Background(), id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sem := make(chan struct{}, 1)
+	wg := &sync.WaitGroup{}
+	svc.processQueue(context.Background(), sem, wg)
+	wg.Wait()
+
+	if completed == nil || completed.UploadFailed || completed.ResultPath != "result-key" {
+		t.Fatalf("expected task to be completed, got %+v", completed)
+	}
+	if len(ws.cleaned) != 1 || ws.cleaned[0] != id {
+		t.Errorf("expected workspace to be cleaned, got %v", ws.cleaned)
+	}
+	if svc.IsProcessing(id) {
+		t.Error("expected task to stop being processed")
+	}
+}
+
+func TestRetryUploadTask_FailsAgain(t *testing.T) {
+	svc, repo, _, ws := defaultSvc()
+	var failed *domain.Task
+	repo.markFunc = func(_ context.Context, task *domain.Task, s domain.TaskStatus) error {
+		if s == domain.TaskFailed {
+			failed = task
+		}
+		return nil
+	}
+
+	task := &domain.Task{ID: mustParseUUID("00000000-0000-0000-0000-000000000001"), Status: domain.TaskFailed, UploadFailed: true}
+	if err := svc.retryUpload(context.Background(), task); err == nil {
+		t.Fatal("expected upload error, got nil")
+	}
+
+	if failed == nil || !failed.UploadFailed {
+		t.Errorf("expected task to stay upload failed, got %+v", failed)
+	}
+	if len(ws.cleaned) != 0 {
+		t.Errorf("expected workspace to be kept, cleaned %v", ws.cleaned)
+	}
 }
 
 // ─────────────────────────────────────────────
@@ -1000,11 +2214,18 @@ func TestProcessQueue_RecoverTask(t *testing.T) {
 
 	task := &domain.Task{ID: uuid.New(), ContainerID: "ctr-1"}
 	svc.RecoverTask(task)
+	<-svc.wakeCh
 
 	sem := make(chan struct{}, 1)
 	wg := &sync.WaitGroup{}
 	svc.processQueue(context.Background(), sem, wg)
 	wg.Wait()
+
+	select {
+	case <-svc.wakeCh:
+	default:
+		t.Error("expected the worker to be woken up")
+	}
 }
 
 func TestProcessQueue_NoQueuedTask(t *testing.T) {
@@ -1039,6 +2260,13 @@ func TestProcessQueue_CacheHit(t *testing.T) {
 	sem := make(chan struct{}, 1)
 	wg := &sync.WaitGroup{}
 	svc.processQueue(context.Background(), sem, wg)
+
+	// the next queued task is taken without waiting for the next tick
+	select {
+	case <-svc.wakeCh:
+	default:
+		t.Error("expected the worker to be woken up")
+	}
 }
 
 func TestProcessQueue_CacheLookupError(t *testing.T) {
@@ -1107,7 +2335,7 @@ func TestMark_Error(t *testing.T) {
 		return errors.New("mark error")
 	}
 
-	err := svc.mark(context.Background(), &domain.Task{}, domain.TaskCompleted)
+	err := svc.mark(context.Background(), &domain.Task{}, domain.TaskCompleted, domain.StatusChange{Actor: domain.ActorWorker})
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -1116,7 +2344,7 @@ func TestMark_Error(t *testing.T) {
 func TestMark_Success(t *testing.T) {
 	svc, _, _, _ := defaultSvc()
 
-	err := svc.mark(context.Background(), &domain.Task{}, domain.TaskCompleted)
+	err := svc.mark(context.Background(), &domain.Task{}, domain.TaskCompleted, domain.StatusChange{Actor: domain.ActorWorker})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1169,7 +2397,7 @@ func TestCleanupWorkspace_Success(t *testing.T) {
 }
 
 // ─────────────────────────────────────────────
-// StartWorker / StartScheduler (integration smoke)
+// StartWorker (integration smoke)
 // ─────────────────────────────────────────────
 
 func TestStartWorker_LifeCycle(t *testing.T) {
@@ -1178,7 +2406,7 @@ func TestStartWorker_LifeCycle(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	wg := &sync.WaitGroup{}
-	svc.StartWorker(ctx, wg)
+	svc.StartWorker(ctx, wg, nil)
 
 	time.Sleep(20 * time.Millisecond)
 	cancel()
@@ -1186,61 +2414,49 @@ func TestStartWorker_LifeCycle(t *testing.T) {
 	time.Sleep(20 * time.Millisecond)
 }
 
-func TestStartScheduler_LifeCycle(t *testing.T) {
+func TestStartWorker_WakesOnQueued(t *testing.T) {
 	svc, repo, _, _ := defaultSvc()
-	repo.getScheduledFunc = func(_ context.Context, _ time.Time) ([]domain.Task, error) {
+	svc.config.Worker.Interval = time.Hour
+
+	var mu sync.Mutex
+	polls := 0
+	repo.getNextFunc = func(_ context.Context) (*domain.Task, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		polls++
 		return nil, nil
 	}
-
-	ctx, cancel := context.WithCancel(context.Background())
-	wg := &sync.WaitGroup{}
-	svc.StartScheduler(ctx, wg)
-
-	time.Sleep(20 * time.Millisecond)
-	cancel()
-	time.Sleep(20 * time.Millisecond)
-}
-
-func TestStartScheduler_WithScheduledTask(t *testing.T) {
-	svc, repo, _, _ := defaultSvc()
-
-	taskID := uuid.New()
-	scheduledAt := time.Now().Add(10 * time.Millisecond)
-	called := false
-
-	repo.getScheduledFunc = func(_ context.Context, _ time.Time) ([]domain.Task, error) {
-		if called {
-			return nil, nil
-		}
-		called = true
-		return []domain.Task{{ID: taskID, ScheduledAt: &scheduledAt, Status: domain.TaskScheduled}}, nil
+	pollCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return polls
 	}
-	repo.getByIdFunc = func(_ context.Context, id uuid.UUID) (*domain.Task, error) {
-		return &domain.Task{ID: id, Status: domain.TaskScheduled}, nil
+	waitPolls := func(n int) {
+		deadline := time.Now().Add(time.Second)
+		for pollCount() < n && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
 	}
 
+	queued := make(chan struct{})
 	ctx, cancel := context.WithCancel(context.Background())
 	wg := &sync.WaitGroup{}
-	svc.StartScheduler(ctx, wg)
+	svc.StartWorker(ctx, wg, queued)
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
 
-	time.Sleep(100 * time.Millisecond)
-	cancel()
-	time.Sleep(20 * time.Millisecond)
-}
-
-func TestStartScheduler_ScheduledError(t *testing.T) {
-	svc, repo, _, _ := defaultSvc()
-	repo.getScheduledFunc = func(_ context.Context, _ time.Time) ([]domain.Task, error) {
-		return nil, errors.New("scheduler db error")
+	waitPolls(1)
+	if pollCount() != 1 {
+		t.Fatalf("expected the queue to be checked on start, got %d polls", pollCount())
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	wg := &sync.WaitGroup{}
-	svc.StartScheduler(ctx, wg)
-
-	time.Sleep(20 * time.Millisecond)
-	cancel()
-	time.Sleep(20 * time.Millisecond)
+	queued <- struct{}{}
+	waitPolls(2)
+	if pollCount() != 2 {
+		t.Errorf("expected the queue to be checked on notification, got %d polls", pollCount())
+	}
 }
 
 // ─────────────────────────────────────────────
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"pinn-connect-service/internal/domain"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// usageCollector aggregates the stats samples of a container.
+type usageCollector struct {
+	usage  domain.ResourceUsage
+	memSum float64
+}
+
+func (c *usageCollector) add(stats *domain.ContainerStats) {
+	c.usage.Samples++
+	mem := int64(stats.MemoryBytes)
+	c.usage.MemPeakBytes = max(c.usage.MemPeakBytes, mem)
+	c.memSum += float64(mem)
+	c.usage.MemAvgBytes = int64(c.memSum / float64(c.usage.Samples))
+
+	// the counters are cumulative, a sample taken while the container exits
+	// may come back lower
+	c.usage.CPUSeconds = max(c.usage.CPUSeconds, float64(stats.CPUNanos)/float64(time.Second))
+	c.usage.BlockReadBytes = max(c.usage.BlockReadBytes, int64(stats.BlockReadBytes))
+	c.usage.BlockWriteBytes = max(c.usage.BlockWriteBytes, int64(stats.BlockWriteBytes))
+	c.usage.NetRxBytes = max(c.usage.NetRxBytes, int64(stats.NetRxBytes))
+	c.usage.NetTxBytes = max(c.usage.NetTxBytes, int64(stats.NetTxBytes))
+}
+
+// sampleUsage samples the stats of the container every WORKER_STATS_INTERVAL
+// until the returned func is called, the func returns the aggregated usage.
+func (s *TaskService) sampleUsage(ctx context.Context, containerID string) func() domain.ResourceUsage {
+	sampleCtx, cancel := context.WithCancel(ctx)
+	ticker := time.NewTicker(s.config.Worker.StatsInterval)
+
+	var c usageCollector
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		defer ticker.Stop()
+		for {
+			stats, err := s.manager.ContainerStats(sampleCtx, containerID)
+			switch {
+			case err != nil:
+				if sampleCtx.Err() == nil {
+					slog.Debug("failed to sample container stats", "container_id", containerID, "error", err)
+				}
+			case !stats.ReadAt.IsZero():
+				// an exited container reports empty stats
+				c.add(stats)
+			}
+
+			select {
+			case <-sampleCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+
+	return func() domain.ResourceUsage {
+		cancel()
+		wg.Wait()
+		return c.usage
+	}
+}
+
+// saveUsage records the usage of the task, a failure is only logged as the
+// usage is not needed to finish the task.
+func (s *TaskService) saveUsage(ctx context.Context, taskID uuid.UUID, usage domain.ResourceUsage) {
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.config.Worker.ProcessTaskCleanupTimeout)
+	defer cancel()
+
+	if err := s.repository.SaveTaskUsage(saveCtx, taskID, usage); err != nil {
+		slog.Warn("failed to save task usage", "task_id", taskID, "error", err)
+	}
+}
+
+// UsageReport aggregates the resource usage of the tasks recorded in
+// [from, to) per model and day.
+func (s *TaskService) UsageReport(ctx context.Context, from, to time.Time) ([]domain.UsageReportRow, error) {
+	report, err := s.repository.UsageReport(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("getting usage report: %w", err)
+	}
+
+	return report, nil
+}
+
+// StreamTaskStats calls send with live stats of the running task until its
+// container exits, ctx is done or send returns an error.
+func (s *TaskService) StreamTaskStats(ctx context.Context, id uuid.UUID, send func(domain.TaskStats) error) error {
+	task, err := s.repository.GetTaskById(ctx, id)
+	if err != nil {
+		return fmt.Errorf("getting task by id: %w", err)
+	}
+	if task == nil {
+		return domain.ErrTaskNotFound
+	}
+	if task.Status != domain.TaskRunning || task.ContainerID == "" {
+		return domain.ErrTaskNotRunning
+	}
+
+	err = s.manager.StreamContainerStats(ctx, task.ContainerID, func(stats *domain.ContainerStats) error {
+		return send(toTaskStats(task, stats))
+	})
+	if errors.Is(err, domain.ErrContainerNotFound) {
+		// the container exited and was removed
+		return domain.ErrTaskNotRunning
+	}
+	if err != nil {
+		return fmt.Errorf("streaming container stats: %w", err)
+	}
+
+	return nil
+}
+
+func toTaskStats(task *domain.Task, stats *domain.ContainerStats) domain.TaskStats {
+	out := domain.TaskStats{
+		ReadAt:           stats.ReadAt,
+		CPUPercent:       stats.CPUPercent,
+		CPULimitPercent:  task.CPULim,
+		MemoryBytes:      stats.MemoryBytes,
+		MemoryLimitBytes: stats.MemoryLimitBytes,
+		BlockReadBytes:   stats.BlockReadBytes,
+		BlockWriteBytes:  stats.BlockWriteBytes,
+		NetRxBytes:       stats.NetRxBytes,
+		NetTxBytes:       stats.NetTxBytes,
+	}
+	if stats.MemoryLimitBytes > 0 {
+		out.MemoryPercent = float64(stats.MemoryBytes) / float64(stats.MemoryLimitBytes) * 100
+	}
+	return out
+}
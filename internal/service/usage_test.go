@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"pinn-connect-service/internal/domain"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestUsageCollector_Aggregates(t *testing.T) {
+	var c usageCollector
+	c.add(&domain.ContainerStats{MemoryBytes: 100, CPUNanos: 1e9, BlockReadBytes: 10, NetTxBytes: 5})
+	c.add(&domain.ContainerStats{MemoryBytes: 300, CPUNanos: 3e9, BlockReadBytes: 30, NetTxBytes: 7})
+	// a sample of an exiting container
+	c.add(&domain.ContainerStats{MemoryBytes: 200, CPUNanos: 2e9})
+
+	want := domain.ResourceUsage{
+		MemPeakBytes:   300,
+		MemAvgBytes:    200,
+		CPUSeconds:     3,
+		BlockReadBytes: 30,
+		NetTxBytes:     7,
+		Samples:        3,
+	}
+	if c.usage != want {
+		t.Errorf("usage = %+v, want %+v", c.usage, want)
+	}
+}
+
+func TestWaitAndSaveTask_SavesUsageBeforeCompleting(t *testing.T) {
+	svc, repo, mgr, _ := defaultSvc()
+
+	var mu sync.Mutex
+	var samples int
+	sampled := make(chan struct{})
+	mgr.statsFunc = func(context.Context, string) (*domain.ContainerStats, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		samples++
+		switch {
+		case samples == 2:
+			close(sampled)
+		case samples > 2:
+			// the container has exited
+			return nil, errors.New("no such container")
+		}
+		return &domain.ContainerStats{ReadAt: time.Now(), MemoryBytes: uint64(samples) * 100, CPUNanos: 5e8}, nil
+	}
+	mgr.waitFunc = func(context.Context, string) (*domain.ContainerExit, error) {
+		<-sampled
+		return &domain.ContainerExit{}, nil
+	}
+
+	var order []string
+	var saved domain.ResourceUsage
+	repo.usageFunc = func(_ context.Context, _ uuid.UUID, usage domain.ResourceUsage) error {
+		order = append(order, "usage")
+		saved = usage
+		return nil
+	}
+	repo.markFunc = func(_ context.Context, _ *domain.Task, s domain.TaskStatus) error {
+		order = append(order, string(s))
+		return nil
+	}
+
+	task := &domain.Task{ID: uuid.New(), ContainerID: "ctr-1"}
+	if err := svc.waitAndSaveTask(context.Background(), task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(order) != 2 || order[0] != "usage" || order[1] != string(domain.TaskCompleted) {
+		t.Errorf("expected the usage to be saved before completing, got %v", order)
+	}
+	if saved.Samples != 2 || saved.MemPeakBytes != 200 || saved.MemAvgBytes != 150 || saved.CPUSeconds != 0.5 {
+		t.Errorf("unexpected usage %+v", saved)
+	}
+}
+
+func TestWaitAndSaveTask_UsageError_Ignored(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	repo.usageFunc = func(context.Context, uuid.UUID, domain.ResourceUsage) error {
+		return errors.New("db error")
+	}
+	var marked domain.TaskStatus
+	repo.markFunc = func(_ context.Context, _ *domain.Task, s domain.TaskStatus) error {
+		marked = s
+		return nil
+	}
+
+	task := &domain.Task{ID: uuid.New(), ContainerID: "ctr-1"}
+	if err := svc.waitAndSaveTask(context.Background(), task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if marked != domain.TaskCompleted {
+		t.Errorf("expected TaskCompleted, got %v", marked)
+	}
+}
+
+func TestUsageReport(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	repo.reportFunc = func(_ context.Context, f, tt time.Time) ([]domain.UsageReportRow, error) {
+		if !f.Equal(from) || !tt.Equal(to) {
+			t.Errorf("unexpected range %v - %v", f, tt)
+		}
+		return []domain.UsageReportRow{{ModelID: "m1", Day: from, Tasks: 3}}, nil
+	}
+
+	report, err := svc.UsageReport(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report) != 1 || report[0].ModelID != "m1" {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestUsageReport_Error(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	repo.reportFunc = func(context.Context, time.Time, time.Time) ([]domain.UsageReportRow, error) {
+		return nil, errors.New("db error")
+	}
+
+	if _, err := svc.UsageReport(context.Background(), time.Now().Add(-time.Hour), time.Now()); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestStreamTaskStats(t *testing.T) {
+	svc, repo, mgr, _ := defaultSvc()
+	id := uuid.New()
+	repo.getByIdFunc = func(context.Context, uuid.UUID) (*domain.Task, error) {
+		return &domain.Task{ID: id, Status: domain.TaskRunning, ContainerID: "ctr-1", CPULim: 200}, nil
+	}
+	mgr.streamFunc = func(_ context.Context, containerID string, fn func(*domain.ContainerStats) error) error {
+		if containerID != "ctr-1" {
+			t.Errorf("unexpected container %q", containerID)
+		}
+		return fn(&domain.ContainerStats{ReadAt: time.Now(), CPUPercent: 150, MemoryBytes: 256, MemoryLimitBytes: 1024, NetRxBytes: 7})
+	}
+
+	var got []domain.TaskStats
+	err := svc.StreamTaskStats(context.Background(), id, func(s domain.TaskStats) error {
+		got = append(got, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 sample, got %d", len(got))
+	}
+	if got[0].CPUPercent != 150 || got[0].CPULimitPercent != 200 || got[0].MemoryPercent != 25 || got[0].NetRxBytes != 7 {
+		t.Errorf("unexpected stats %+v", got[0])
+	}
+}
+
+func TestStreamTaskStats_Errors(t *testing.T) {
+	running := &domain.Task{Status: domain.TaskRunning, ContainerID: "ctr-1"}
+	cases := []struct {
+		name   string
+		task   *domain.Task
+		stream error
+		want   error
+	}{
+		{"not found", nil, nil, domain.ErrTaskNotFound},
+		{"queued", &domain.Task{Status: domain.TaskQueued}, nil, domain.ErrTaskNotRunning},
+		{"container gone", running, domain.ErrContainerNotFound, domain.ErrTaskNotRunning},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, repo, mgr, _ := defaultSvc()
+			repo.getByIdFunc = func(context.Context, uuid.UUID) (*domain.Task, error) { return tc.task, nil }
+			mgr.streamFunc = func(context.Context, string, func(*domain.ContainerStats) error) error { return tc.stream }
+
+			err := svc.StreamTaskStats(context.Background(), uuid.New(), func(domain.TaskStats) error { return nil })
+			if !errors.Is(err, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"pinn-connect-service/internal/domain"
+	"pinn-connect-service/internal/storage"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VerifyResult re-reads the stored result of a completed task and checks
+// every object against the checksum recorded when it was uploaded. The
+// result is flagged as corrupted, or the flag is cleared, according to the
+// outcome, so the cache doesn't reuse a corrupted result.
+func (s *TaskService) VerifyResult(ctx context.Context, id uuid.UUID) (*domain.VerifyReport, error) {
+	task, err := s.getCompletedTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := storage.ResultPrefix(task.ResultPath)
+
+	report, err := s.verifyPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	report.TaskID = id
+
+	if report.OK == task.ResultCorrupted {
+		if err := s.repository.SetResultCorrupted(ctx, prefix, !report.OK); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// verifyPrefix checks the objects under the prefix against their recorded
+// checksums. Objects without a recorded checksum are reported, but don't
+// fail the verification.
+func (s *TaskService) verifyPrefix(ctx context.Context, prefix string) (*domain.VerifyReport, error) {
+	recorded, err := s.repository.ListArtifactChecksums(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.storage.ListArtifacts(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("listing result artifacts: %w", err)
+	}
+
+	report := &domain.VerifyReport{
+		CheckedAt: time.Now(),
+		OK:        true,
+		Files:     make([]domain.FileVerification, 0, len(stored)),
+	}
+
+	known := make(map[string]bool, len(recorded))
+	for _, r := range recorded {
+		known[r.Key] = true
+
+		file := domain.FileVerification{
+			Path:     strings.TrimPrefix(r.Key, prefix),
+			Size:     r.Size,
+			Expected: r.SHA256,
+		}
+
+		sum, err := s.hashArtifact(ctx, r.Key)
+		switch {
+		case errors.Is(err, domain.ErrArtifactNotFound):
+			file.Status = domain.ChecksumMissing
+		case errors.Is(err, domain.ErrArtifactCorrupted):
+			file.Status = domain.ChecksumMismatch
+		case err != nil:
+			return nil, err
+		case sum != r.SHA256:
+			file.Status = domain.ChecksumMismatch
+		default:
+			file.Status = domain.ChecksumOK
+		}
+		file.Actual = sum
+
+		if file.Status != domain.ChecksumOK {
+			report.OK = false
+		}
+		report.Files = append(report.Files, file)
+	}
+
+	for _, a := range stored {
+		if known[a.Key] {
+			continue
+		}
+		report.Files = append(report.Files, domain.FileVerification{
+			Path:   strings.TrimPrefix(a.Key, prefix),
+			Size:   a.Size,
+			Status: domain.ChecksumUnrecorded,
+		})
+	}
+
+	sort.Slice(report.Files, func(i, j int) bool { return report.Files[i].Path < report.Files[j].Path })
+
+	return report, nil
+}
+
+// hashArtifact reads the whole object and returns its hex encoded sha256.
+func (s *TaskService) hashArtifact(ctx context.Context, objectKey string) (string, error) {
+	rc, _, err := s.storage.OpenArtifact(ctx, objectKey)
+	if err != nil {
+		if errors.Is(err, domain.ErrArtifactNotFound) || errors.Is(err, domain.ErrArtifactCorrupted) {
+			return "", err
+		}
+		return "", fmt.Errorf("opening artifact %s: %w", objectKey, err)
+	}
+	defer rc.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, rc); err != nil {
+		return "", fmt.Errorf("reading artifact %s: %w", objectKey, err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
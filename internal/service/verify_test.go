@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"pinn-connect-service/internal/domain"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// ─────────────────────────────────────────────
+// HELPERS
+// ─────────────────────────────────────────────
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// withChecksums makes the repository return the recorded checksums and
+// collects the corrupted flags set by the service.
+func withChecksums(repo *mockRepository, recorded map[string]string) map[string]bool {
+	repo.listSumsFunc = func(_ context.Context, prefix string) ([]domain.Artifact, error) {
+		var res []domain.Artifact
+		for k, v := range recorded {
+			if strings.HasPrefix(k, prefix) {
+				res = append(res, domain.Artifact{Key: k, SHA256: v})
+			}
+		}
+		return res, nil
+	}
+
+	flags := make(map[string]bool)
+	repo.corruptedFunc = func(_ context.Context, prefix string, corrupted bool) error {
+		flags[prefix] = corrupted
+		return nil
+	}
+	return flags
+}
+
+// ─────────────────────────────────────────────
+// VerifyResult
+// ─────────────────────────────────────────────
+
+func TestVerifyResult_OK(t *testing.T) {
+	owner := uuid.New()
+	prefix := "tasks/" + owner.String() + "/"
+	svc, repo := resultSvc(owner, map[string]string{
+		prefix + "result.txt": "result",
+		prefix + "legacy.txt": "old",
+	})
+	flags := withChecksums(repo, map[string]string{prefix + "result.txt": sha256Hex("result")})
+
+	report, err := svc.VerifyResult(context.Background(), uuid.New())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !report.OK || len(report.Files) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if report.Files[0].Path != "legacy.txt" || report.Files[0].Status != domain.ChecksumUnrecorded {
+		t.Errorf("expected legacy.txt to be unrecorded, got %+v", report.Files[0])
+	}
+	if report.Files[1].Path != "result.txt" || report.Files[1].Status != domain.ChecksumOK {
+		t.Errorf("expected result.txt to be ok, got %+v", report.Files[1])
+	}
+	if len(flags) != 0 {
+		t.Errorf("expected no flag change, got %v", flags)
+	}
+}
+
+func TestVerifyResult_MismatchAndMissing(t *testing.T) {
+	owner := uuid.New()
+	prefix := "tasks/" + owner.String() + "/"
+	svc, repo := resultSvc(owner, map[string]string{prefix + "result.txt": "rotten"})
+	flags := withChecksums(repo, map[string]string{
+		prefix + "result.txt":  sha256Hex("result"),
+		prefix + "sub/log.txt": sha256Hex("log"),
+	})
+
+	report, err := svc.VerifyResult(context.Background(), uuid.New())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if report.OK || len(report.Files) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if f := report.Files[0]; f.Status != domain.ChecksumMismatch || f.Actual != sha256Hex("rotten") {
+		t.Errorf("expected result.txt to mismatch, got %+v", f)
+	}
+	if f := report.Files[1]; f.Status != domain.ChecksumMissing {
+		t.Errorf("expected sub/log.txt to be missing, got %+v", f)
+	}
+	if corrupted, ok := flags[prefix]; !ok || !corrupted {
+		t.Errorf("expected the result to be flagged corrupted, got %v", flags)
+	}
+}
+
+// An encrypted object failing authentication is reported as a mismatch.
+func TestVerifyResult_CorruptedCiphertext(t *testing.T) {
+	owner := uuid.New()
+	prefix := "tasks/" + owner.String() + "/"
+	svc, repo := resultSvc(owner, map[string]string{prefix + "result.txt": "result"})
+	flags := withChecksums(repo, map[string]string{prefix + "result.txt": sha256Hex("result")})
+	svc.storage.(*mockArtifactStorage).openFunc = func(context.Context, string) (io.ReadSeekCloser, *domain.Artifact, error) {
+		return nil, nil, domain.ErrArtifactCorrupted
+	}
+
+	report, err := svc.VerifyResult(context.Background(), uuid.New())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.OK || report.Files[0].Status != domain.ChecksumMismatch || report.Files[0].Actual != "" {
+		t.Errorf("expected result.txt to mismatch, got %+v", report)
+	}
+	if !flags[prefix] {
+		t.Errorf("expected the result to be flagged corrupted, got %v", flags)
+	}
+}
+
+// A result repaired since the last verification is no longer flagged.
+func TestVerifyResult_ClearsCorruptedFlag(t *testing.T) {
+	owner := uuid.New()
+	prefix := "tasks/" + owner.String() + "/"
+	svc, repo := resultSvc(owner, map[string]string{prefix + "result.txt": "result"})
+	flags := withChecksums(repo, map[string]string{prefix + "result.txt": sha256Hex("result")})
+	repo.getByIdFunc = func(_ context.Context, id uuid.UUID) (*domain.Task, error) {
+		return &domain.Task{ID: id, Status: domain.TaskCompleted, ResultPath: prefix + "result.txt", ResultCorrupted: true}, nil
+	}
+
+	if _, err := svc.VerifyResult(context.Background(), uuid.New()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if corrupted, ok := flags[prefix]; !ok || corrupted {
+		t.Errorf("expected the corrupted flag to be cleared, got %v", flags)
+	}
+}
+
+func TestVerifyResult_NotReady(t *testing.T) {
+	svc, repo, _, _ := defaultSvc()
+	repo.getByIdFunc = func(_ context.Context, id uuid.UUID) (*domain.Task, error) {
+		return &domain.Task{ID: id, Status: domain.TaskRunning}, nil
+	}
+
+	if _, err := svc.VerifyResult(context.Background(), uuid.New()); !errors.Is(err, domain.ErrResultNotReady) {
+		t.Fatalf("expected ErrResultNotReady, got %v", err)
+	}
+}
+
+// ─────────────────────────────────────────────
+// Cache
+// ─────────────────────────────────────────────
+
+func TestFindCachedTask_CorruptedResultNotReused(t *testing.T) {
+	owner := uuid.New()
+	prefix := "tasks/" + owner.String() + "/"
+	svc, repo := resultSvc(owner, map[string]string{prefix + "result.txt": "rotten"})
+	svc.config.Storage.VerifyCached = true
+	flags := withChecksums(repo, map[string]string{prefix + "result.txt": sha256Hex("result")})
+	repo.findCachedFunc = func(context.Context, string) (string, error) { return prefix + "result.txt", nil }
+
+	resPath, err := svc.findCachedTask(context.Background(), "sig")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resPath != "" {
+		t.Errorf("expected a cache miss, got %q", resPath)
+	}
+	if !flags[prefix] {
+		t.Errorf("expected the result to be flagged corrupted, got %v", flags)
+	}
+}
+
+func TestFindCachedTask_VerifiedResultReused(t *testing.T) {
+	owner := uuid.New()
+	prefix := "tasks/" + owner.String() + "/"
+	svc, repo := resultSvc(owner, map[string]string{prefix + "result.txt": "result"})
+	svc.config.Storage.VerifyCached = true
+	withChecksums(repo, map[string]string{prefix + "result.txt": sha256Hex("result")})
+	repo.findCachedFunc = func(context.Context, string) (string, error) { return prefix + "result.txt", nil }
+
+	resPath, err := svc.findCachedTask(context.Background(), "sig")
+	if err != nil || resPath != prefix+"result.txt" {
+		t.Errorf("expected the cached result to be reused, got %q / %v", resPath, err)
+	}
+}
+
+func TestFindCachedTask_VerifyDisabled(t *testing.T) {
+	owner := uuid.New()
+	prefix := "tasks/" + owner.String() + "/"
+	svc, repo := resultSvc(owner, map[string]string{prefix + "result.txt": "rotten"})
+	svc.config.Storage.VerifyCached = false
+	repo.listSumsFunc = func(context.Context, string) ([]domain.Artifact, error) {
+		t.Fatal("checksums must not be read with verification disabled")
+		return nil, nil
+	}
+	repo.findCachedFunc = func(context.Context, string) (string, error) { return prefix + "result.txt", nil }
+
+	if resPath, err := svc.findCachedTask(context.Background(), "sig"); err != nil || resPath == "" {
+		t.Errorf("expected the cached result to be reused, got %q / %v", resPath, err)
+	}
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"pinn-connect-service/internal/domain"
+	"pinn-connect-service/internal/envelope"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// User metadata of client-side encrypted objects. The plain checksum is not
+// stored with an encrypted object, only its fingerprint under the master key.
+const (
+	encKeyIDMetaKey       = "Enc-Key-Id"
+	encDataKeyMetaKey     = "Enc-Data-Key"
+	encNonceMetaKey       = "Enc-Nonce"
+	encFingerprintMetaKey = "Enc-Fingerprint"
+)
+
+// objectBody is the content of an object as it is stored, with its metadata.
+type objectBody struct {
+	io.ReaderAt
+	size int64
+	meta map[string]string
+	// resumable is false when the stored content changes between attempts,
+	// so the parts of an earlier upload can't be reused.
+	resumable bool
+}
+
+// objectBody returns the stored form of the file. Without a keyring the file
+// is stored as is, otherwise it is encrypted with the data key.
+func (m *MinIOStorage) objectBody(objectKey string, file io.ReaderAt, size int64, sum string, dataKey *envelope.DataKey) (objectBody, error) {
+	if m.keyring == nil {
+		return objectBody{ReaderAt: file, size: size, meta: map[string]string{checksumMetaKey: sum}, resumable: true}, nil
+	}
+
+	enc, err := dataKey.NewEncryptor(objectKey, file, size)
+	if err != nil {
+		return objectBody{}, fmt.Errorf("encrypting %s: %w", objectKey, err)
+	}
+
+	fingerprint, err := m.keyring.Fingerprint(dataKey.KeyID, sum)
+	if err != nil {
+		return objectBody{}, err
+	}
+
+	return objectBody{
+		ReaderAt: enc,
+		size:     enc.Size(),
+		meta: map[string]string{
+			encKeyIDMetaKey:       dataKey.KeyID,
+			encDataKeyMetaKey:     base64.StdEncoding.EncodeToString(dataKey.Wrapped),
+			encNonceMetaKey:       base64.StdEncoding.EncodeToString(enc.Nonce()),
+			encFingerprintMetaKey: fingerprint,
+		},
+	}, nil
+}
+
+// isStored reports whether the object already holds a file of the given size
+// and checksum in the form the storage would write it.
+func (m *MinIOStorage) isStored(info minio.ObjectInfo, size int64, sum string) bool {
+	if m.keyring == nil {
+		return info.Size == size && info.UserMetadata[checksumMetaKey] == sum
+	}
+
+	keyID := info.UserMetadata[encKeyIDMetaKey]
+	if keyID == "" || info.Size != envelope.CiphertextSize(size) {
+		return false
+	}
+	fingerprint, err := m.keyring.Fingerprint(keyID, sum)
+	return err == nil && info.UserMetadata[encFingerprintMetaKey] == fingerprint
+}
+
+// storedSize returns the size a file of the given size takes in the bucket.
+func (m *MinIOStorage) storedSize(size int64) int64 {
+	if m.keyring == nil {
+		return size
+	}
+	return envelope.CiphertextSize(size)
+}
+
+// openEncrypted returns a reader decrypting the object on the fly.
+func (m *MinIOStorage) openEncrypted(obj *minio.Object, info minio.ObjectInfo) (*decryptedObject, error) {
+	if m.keyring == nil {
+		return nil, fmt.Errorf("object %s is encrypted, but no keyring is configured", info.Key)
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(info.UserMetadata[encDataKeyMetaKey])
+	if err != nil {
+		return nil, domain.ErrArtifactCorrupted
+	}
+	nonce, err := base64.StdEncoding.DecodeString(info.UserMetadata[encNonceMetaKey])
+	if err != nil {
+		return nil, domain.ErrArtifactCorrupted
+	}
+
+	dataKey, err := m.keyring.Unwrap(info.UserMetadata[encKeyIDMetaKey], wrapped)
+	if err != nil {
+		return nil, decryptionError(fmt.Errorf("unwrapping data key of %s: %w", info.Key, err))
+	}
+
+	dec, err := dataKey.NewDecryptor(info.Key, nonce, obj, info.Size)
+	if err != nil {
+		return nil, decryptionError(fmt.Errorf("decrypting %s: %w", info.Key, err))
+	}
+
+	return &decryptedObject{Decryptor: dec, obj: obj}, nil
+}
+
+// decryptedObject is the plaintext of an encrypted object.
+type decryptedObject struct {
+	*envelope.Decryptor
+	obj *minio.Object
+}
+
+func (d *decryptedObject) Read(p []byte) (int, error) {
+	n, err := d.Decryptor.Read(p)
+	return n, decryptionError(err)
+}
+
+func (d *decryptedObject) ReadAt(p []byte, off int64) (int, error) {
+	n, err := d.Decryptor.ReadAt(p, off)
+	return n, decryptionError(err)
+}
+
+func (d *decryptedObject) Close() error {
+	return d.obj.Close()
+}
+
+// decryptionError reports a ciphertext or a wrapped key that fails
+// authentication as a corrupted artifact.
+func decryptionError(err error) error {
+	if errors.Is(err, envelope.ErrAuthFailed) || errors.Is(err, envelope.ErrInvalidKey) || errors.Is(err, envelope.ErrInvalidSize) {
+		return fmt.Errorf("%w: %w", domain.ErrArtifactCorrupted, err)
+	}
+	return err
+}
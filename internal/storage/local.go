@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/domain"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// tmpFilePrefix marks files that are being written, they are never listed.
+const tmpFilePrefix = ".upload-"
+
+// LocalStorage keeps task results in a directory on the local filesystem.
+// Objects are stored under their keys, downloads are served by the service
+// through signed URLs.
+type LocalStorage struct {
+	*urlSigner
+
+	dir     string
+	dirPerm os.FileMode
+}
+
+func NewLocalStorage(config *config.Config) (*LocalStorage, error) {
+	cfg := config.Storage.Local
+
+	if err := os.MkdirAll(cfg.Dir, config.WorkspaceDirsPerm); err != nil {
+		return nil, fmt.Errorf("creating storage dir: %w", err)
+	}
+
+	signer, err := newURLSigner(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalStorage{
+		urlSigner: signer,
+		dir:       cfg.Dir,
+		dirPerm:   config.WorkspaceDirsPerm,
+	}, nil
+}
+
+// UploadToStorage copies every file of the result directory under the
+// "tasks/<id>/" prefix, keeping the relative layout of the directory.
+// It returns the key of the primary result file and the stored objects with
+// their checksums. progress may be nil.
+//
+// The filesystem has no object metadata, checksums are only returned to the
+// caller.
+func (l *LocalStorage) UploadToStorage(ctx context.Context, taskID uuid.UUID, resultDir string, progress domain.UploadProgressFunc) (*domain.UploadResult, error) {
+	files, err := listResultFiles(resultDir)
+	if err != nil {
+		return nil, fmt.Errorf("listing result files: %w", err)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no result file found in directory")
+	}
+
+	total, err := resultSize(resultDir, files, nil)
+	if err != nil {
+		return nil, err
+	}
+	tracker := newUploadProgress(total, progress)
+
+	objects := make([]domain.Artifact, 0, len(files))
+	for _, relPath := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		objectKey := fmt.Sprintf("tasks/%s/%s", taskID, relPath)
+		object, err := l.copyFile(objectKey, filepath.Join(resultDir, filepath.FromSlash(relPath)), tracker)
+		if err != nil {
+			return nil, fmt.Errorf("saving to local storage: %w", err)
+		}
+		objects = append(objects, object)
+	}
+
+	return &domain.UploadResult{
+		PrimaryKey: fmt.Sprintf("tasks/%s/%s", taskID, primaryResultFile(files)),
+		Objects:    objects,
+	}, nil
+}
+
+// copyFile writes the object through a temporary file, so a partially
+// written object is never visible under its key. The content is hashed
+// while it is copied.
+func (l *LocalStorage) copyFile(objectKey, filePath string, progress *uploadProgress) (domain.Artifact, error) {
+	src, err := os.Open(filePath)
+	if err != nil {
+		return domain.Artifact{}, fmt.Errorf("opening result file: %w", err)
+	}
+	defer src.Close()
+
+	dst := l.objectPath(objectKey)
+	if err := os.MkdirAll(filepath.Dir(dst), l.dirPerm); err != nil {
+		return domain.Artifact{}, fmt.Errorf("creating object dir: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), tmpFilePrefix+"*")
+	if err != nil {
+		return domain.Artifact{}, fmt.Errorf("creating object file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, progress, hasher), src)
+	if err != nil {
+		tmp.Close()
+		return domain.Artifact{}, fmt.Errorf("copying object: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return domain.Artifact{}, fmt.Errorf("closing object file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return domain.Artifact{}, fmt.Errorf("renaming object file: %w", err)
+	}
+
+	return domain.Artifact{
+		Key:         objectKey,
+		Size:        size,
+		ContentType: contentTypeByKey(objectKey),
+		SHA256:      hex.EncodeToString(hasher.Sum(nil)),
+	}, nil
+}
+
+func (l *LocalStorage) DeleteArtifacts(ctx context.Context, taskID uuid.UUID) error {
+	if err := os.RemoveAll(l.objectPath(fmt.Sprintf("tasks/%s", taskID))); err != nil {
+		return fmt.Errorf("removing task objects: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteArtifact removes a single object. Removing a missing object is not an error.
+func (l *LocalStorage) DeleteArtifact(ctx context.Context, objectKey string) error {
+	if err := os.Remove(l.objectPath(objectKey)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove object %s: %w", objectKey, err)
+	}
+
+	return nil
+}
+
+// ListArtifacts returns all objects stored under the given prefix.
+func (l *LocalStorage) ListArtifacts(ctx context.Context, prefix string) ([]domain.Artifact, error) {
+	// only the directory holding the prefix has to be walked
+	root := l.objectPath(path.Dir(prefix + "x"))
+
+	artifacts := []domain.Artifact{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), tmpFilePrefix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.dir, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		artifacts = append(artifacts, domain.Artifact{
+			Key:          key,
+			Size:         info.Size(),
+			ContentType:  contentTypeByKey(key),
+			LastModified: info.ModTime(),
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing objects: %w", err)
+	}
+
+	return artifacts, nil
+}
+
+// OpenArtifact opens the object for reading.
+func (l *LocalStorage) OpenArtifact(ctx context.Context, objectKey string) (io.ReadSeekCloser, *domain.Artifact, error) {
+	file, err := os.Open(l.objectPath(objectKey))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, domain.ErrArtifactNotFound
+		}
+		return nil, nil, fmt.Errorf("opening object: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("getting object info: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		file.Close()
+		return nil, nil, domain.ErrArtifactNotFound
+	}
+
+	return file, &domain.Artifact{
+		Key:          objectKey,
+		Size:         info.Size(),
+		ContentType:  contentTypeByKey(objectKey),
+		LastModified: info.ModTime(),
+	}, nil
+}
+
+// GetDownloadURL returns a URL of the service's /storage endpoint signed for expiry.
+func (l *LocalStorage) GetDownloadURL(ctx context.Context, objectKey string, expiry time.Duration) (string, error) {
+	return l.urlSigner.GetDownloadURL(objectKey, expiry), nil
+}
+
+func (l *LocalStorage) CheckStatus(ctx context.Context) error {
+	info, err := os.Stat(l.dir)
+	if err != nil {
+		return fmt.Errorf("checking storage dir: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("storage path %s is not a directory", l.dir)
+	}
+
+	return nil
+}
+
+// objectPath maps the key to a path inside the storage dir. Keys can't
+// escape the dir.
+func (l *LocalStorage) objectPath(objectKey string) string {
+	return filepath.Join(l.dir, filepath.FromSlash(path.Clean("/"+objectKey)))
+}
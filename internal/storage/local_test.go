@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/domain"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ─────────────────────────────────────────────
+// HELPERS
+// ─────────────────────────────────────────────
+
+func newTestLocalStorage(t *testing.T) *LocalStorage {
+	t.Helper()
+	l, err := NewLocalStorage(&config.Config{
+		Storage: config.StorageConfig{Local: config.LocalStorageConfig{
+			Dir:        t.TempDir(),
+			PublicURL:  "http://pinn.local/",
+			SigningKey: "secret",
+		}},
+		WorkspaceDirsPerm: 0o755,
+	})
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	return l
+}
+
+func writeResultDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// ─────────────────────────────────────────────
+// NewLocalStorage
+// ─────────────────────────────────────────────
+
+// Without a shared key the urls signed by one instance fail on the others.
+func TestNewLocalStorage_RequiresSigningKey(t *testing.T) {
+	_, err := NewLocalStorage(&config.Config{
+		Storage: config.StorageConfig{Local: config.LocalStorageConfig{
+			Dir:       t.TempDir(),
+			PublicURL: "http://pinn.local/",
+		}},
+		WorkspaceDirsPerm: 0o755,
+	})
+	if err == nil {
+		t.Fatal("expected error without a signing key, got nil")
+	}
+}
+
+// ─────────────────────────────────────────────
+// Upload / List / Open
+// ─────────────────────────────────────────────
+
+func TestLocalStorage_UploadListOpen(t *testing.T) {
+	l := newTestLocalStorage(t)
+	id := uuid.New()
+	ctx := context.Background()
+
+	var uploaded, total int64
+	res, err := l.UploadToStorage(ctx, id, writeResultDir(t, map[string]string{
+		"result.txt":  "result",
+		"sub/log.txt": "log",
+	}), func(u, t int64) { uploaded, total = u, t })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	key := res.PrimaryKey
+	if uploaded != 9 || total != 9 {
+		t.Errorf("expected progress 9/9, got %d/%d", uploaded, total)
+	}
+	if key != "tasks/"+id.String()+"/result.txt" {
+		t.Errorf("unexpected primary key %q", key)
+	}
+	if len(res.Objects) != 2 || res.Objects[0].SHA256 != sha256Hex("result") || res.Objects[1].SHA256 != sha256Hex("log") {
+		t.Errorf("unexpected uploaded objects %+v", res.Objects)
+	}
+
+	artifacts, err := l.ListArtifacts(ctx, "tasks/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(artifacts) != 2 {
+		t.Fatalf("expected 2 artifacts, got %+v", artifacts)
+	}
+
+	rc, artifact, err := l.OpenArtifact(ctx, "tasks/"+id.String()+"/sub/log.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+	data, _ := io.ReadAll(rc)
+	if string(data) != "log" || artifact.Size != 3 || artifact.ContentType != "text/plain; charset=utf-8" {
+		t.Errorf("unexpected artifact %+v with content %q", artifact, data)
+	}
+}
+
+// Listing a prefix must not return objects of other tasks or files being written.
+func TestLocalStorage_ListArtifacts_FiltersPrefix(t *testing.T) {
+	l := newTestLocalStorage(t)
+	a, b := uuid.New(), uuid.New()
+	ctx := context.Background()
+
+	for _, id := range []uuid.UUID{a, b} {
+		if _, err := l.UploadToStorage(ctx, id, writeResultDir(t, map[string]string{"result.txt": "x"}), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tmp := filepath.Join(l.dir, "tasks", a.String(), tmpFilePrefix+"123")
+	if err := os.WriteFile(tmp, []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	artifacts, err := l.ListArtifacts(ctx, "tasks/"+a.String()+"/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(artifacts) != 1 || artifacts[0].Key != "tasks/"+a.String()+"/result.txt" {
+		t.Errorf("unexpected artifacts %+v", artifacts)
+	}
+
+	if artifacts, err := l.ListArtifacts(ctx, "tasks/"+uuid.NewString()+"/"); err != nil || len(artifacts) != 0 {
+		t.Errorf("expected no artifacts for a missing prefix, got %+v / %v", artifacts, err)
+	}
+}
+
+func TestLocalStorage_OpenArtifact_NotFound(t *testing.T) {
+	l := newTestLocalStorage(t)
+
+	if _, _, err := l.OpenArtifact(context.Background(), "tasks/missing/result.txt"); !errors.Is(err, domain.ErrArtifactNotFound) {
+		t.Fatalf("expected ErrArtifactNotFound, got %v", err)
+	}
+}
+
+// Keys must not be able to reach files outside of the storage dir.
+func TestLocalStorage_ObjectPath_Confined(t *testing.T) {
+	l := newTestLocalStorage(t)
+
+	p := l.objectPath("../../etc/passwd")
+	if !strings.HasPrefix(p, l.dir+string(filepath.Separator)) {
+		t.Errorf("expected path inside %s, got %s", l.dir, p)
+	}
+}
+
+// ─────────────────────────────────────────────
+// Delete
+// ─────────────────────────────────────────────
+
+func TestLocalStorage_Delete(t *testing.T) {
+	l := newTestLocalStorage(t)
+	a, b := uuid.New(), uuid.New()
+	ctx := context.Background()
+
+	for _, id := range []uuid.UUID{a, b} {
+		if _, err := l.UploadToStorage(ctx, id, writeResultDir(t, map[string]string{"result.txt": "x", "log.txt": "y"}), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := l.DeleteArtifacts(ctx, a); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := l.DeleteArtifact(ctx, "tasks/"+b.String()+"/log.txt"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := l.DeleteArtifact(ctx, "tasks/"+b.String()+"/log.txt"); err != nil {
+		t.Fatalf("deleting a missing object must not fail, got %v", err)
+	}
+
+	artifacts, _ := l.ListArtifacts(ctx, "tasks/")
+	if len(artifacts) != 1 || artifacts[0].Key != "tasks/"+b.String()+"/result.txt" {
+		t.Errorf("unexpected artifacts after delete %+v", artifacts)
+	}
+}
+
+// ─────────────────────────────────────────────
+// Signed URLs
+// ─────────────────────────────────────────────
+
+func signedQuery(t *testing.T, l *LocalStorage, key string, expiry time.Duration) (string, url.Values) {
+	t.Helper()
+	raw, err := l.GetDownloadURL(context.Background(), key, expiry)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("invalid url %q: %v", raw, err)
+	}
+	return u.Path, u.Query()
+}
+
+func TestLocalStorage_SignedURL_Valid(t *testing.T) {
+	l := newTestLocalStorage(t)
+
+	p, q := signedQuery(t, l, "tasks/abc/my result.txt", time.Minute)
+
+	if p != "/storage/tasks/abc/my result.txt" {
+		t.Errorf("unexpected path %q", p)
+	}
+	if err := l.VerifySignedURL("tasks/abc/my result.txt", q); err != nil {
+		t.Errorf("expected valid signature, got %v", err)
+	}
+}
+
+func TestLocalStorage_SignedURL_Rejected(t *testing.T) {
+	l := newTestLocalStorage(t)
+	_, q := signedQuery(t, l, "tasks/abc/result.txt", time.Minute)
+
+	if err := l.VerifySignedURL("tasks/other/result.txt", q); !errors.Is(err, domain.ErrInvalidSignature) {
+		t.Errorf("expected signature to be bound to the key, got %v", err)
+	}
+
+	tampered := url.Values{"expires": {"99999999999"}, "signature": q["signature"]}
+	if err := l.VerifySignedURL("tasks/abc/result.txt", tampered); !errors.Is(err, domain.ErrInvalidSignature) {
+		t.Errorf("expected signature to be bound to the expiry, got %v", err)
+	}
+
+	l.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if err := l.VerifySignedURL("tasks/abc/result.txt", q); !errors.Is(err, domain.ErrInvalidSignature) {
+		t.Errorf("expected expired url to be rejected, got %v", err)
+	}
+}
@@ -4,63 +4,143 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"mime"
 	"net/url"
 	"os"
 	"path/filepath"
 	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/domain"
+	"pinn-connect-service/internal/envelope"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
 )
 
 type MinIOStorage struct {
 	Client         *minio.Client
 	clientExternal *minio.Client
+	core           *minio.Core
 	bucket         string
+	sse            encrypt.ServerSide
+	uploadCfg      config.UploadConfig
+
+	// keyring is set when objects are encrypted on the client side. The
+	// bucket can't serve them then, downloads go through the signer's URLs.
+	keyring *envelope.Keyring
+	signer  *urlSigner
 }
 
 func NewMinIOStorage(ctx context.Context, config *config.Config) (*MinIOStorage, error) {
-	client, err := minio.New(config.MinIO.Endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(config.MinIO.AccessKey, config.MinIO.SecretKey, ""),
-		Secure: config.MinIO.SSLUse,
-	})
+	cfg := config.MinIO
+
+	client, err := minio.New(cfg.Endpoint, minioOptions(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("creating minio client: %w", err)
 	}
 
-	clientExternal, err := minio.New(config.MinIO.ExternalEndpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(config.MinIO.AccessKey, config.MinIO.SecretKey, ""),
-		Secure: config.MinIO.SSLUse,
-		Region: "us-east-1",
-	})
+	// presigning doesn't reach the external endpoint as long as the region is known
+	clientExternal, err := minio.New(cfg.ExternalEndpoint, minioOptions(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("creating minio client: %w", err)
 	}
 
-	exists, err := client.BucketExists(ctx, config.MinIO.Bucket)
+	sse, err := serverSideEncryption(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("checking bucket exists: %w", err)
+		return nil, err
 	}
 
-	if !exists {
-		err := client.MakeBucket(ctx, config.MinIO.Bucket, minio.MakeBucketOptions{})
+	if cfg.CreateBucket {
+		exists, err := client.BucketExists(ctx, cfg.Bucket)
 		if err != nil {
-			return nil, fmt.Errorf("creating bucket: %w", err)
+			return nil, fmt.Errorf("checking bucket exists: %w", err)
+		}
+
+		if !exists {
+			err := client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{Region: cfg.Region})
+			if err != nil {
+				return nil, fmt.Errorf("creating bucket: %w", err)
+			}
 		}
 	}
 
-	return &MinIOStorage{
+	storage := &MinIOStorage{
 		Client:         client,
-		bucket:         config.MinIO.Bucket,
+		bucket:         cfg.Bucket,
 		clientExternal: clientExternal,
-	}, nil
+		core:           &minio.Core{Client: client},
+		sse:            sse,
+		uploadCfg:      config.Upload,
+	}
+
+	if cfg.EncryptionKeyring != "" {
+		storage.keyring, err = envelope.LoadKeyring(cfg.EncryptionKeyring)
+		if err != nil {
+			return nil, err
+		}
+		storage.signer, err = newURLSigner(config.Storage.Local)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return storage, nil
+}
+
+// Encrypted reports whether objects are encrypted on the client side.
+func (m *MinIOStorage) Encrypted() bool {
+	return m.keyring != nil
+}
+
+func minioOptions(cfg config.MinIOConfig) *minio.Options {
+	creds := credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, "")
+	if cfg.AccessKey == "" {
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.FileAWSCredentials{},
+			&credentials.IAM{},
+		})
+	}
+
+	lookup := minio.BucketLookupAuto
+	switch cfg.Addressing {
+	case "path":
+		lookup = minio.BucketLookupPath
+	case "virtual":
+		lookup = minio.BucketLookupDNS
+	}
+
+	return &minio.Options{
+		Creds:        creds,
+		Secure:       cfg.SSLUse,
+		Region:       cfg.Region,
+		BucketLookup: lookup,
+	}
+}
+
+func serverSideEncryption(cfg config.MinIOConfig) (encrypt.ServerSide, error) {
+	switch cfg.SSE {
+	case "s3":
+		return encrypt.NewSSE(), nil
+	case "kms":
+		sse, err := encrypt.NewSSEKMS(cfg.SSEKMSKeyID, nil)
+		if err != nil {
+			return nil, fmt.Errorf("creating kms encryption: %w", err)
+		}
+		return sse, nil
+	default:
+		return nil, nil
+	}
 }
 
 func (m *MinIOStorage) DeleteArtifacts(ctx context.Context, taskID uuid.UUID) error {
-	prefix := fmt.Sprintf("tasks/%s/", taskID)
+	prefix := TaskPrefix(taskID)
+
+	if err := m.abortMultipartUploads(ctx, prefix); err != nil {
+		return err
+	}
 
 	objectsCh := m.Client.ListObjects(ctx, m.bucket, minio.ListObjectsOptions{
 		Prefix:    prefix,
@@ -77,55 +157,120 @@ func (m *MinIOStorage) DeleteArtifacts(ctx context.Context, taskID uuid.UUID) er
 	return nil
 }
 
-func (m *MinIOStorage) UploadToStorage(ctx context.Context, taskID uuid.UUID, resultDir string) (string, error) {
-	entries, err := os.ReadDir(resultDir)
+// DeleteArtifact removes a single object. Removing a missing object is not an error.
+func (m *MinIOStorage) DeleteArtifact(ctx context.Context, objectKey string) error {
+	if err := m.Client.RemoveObject(ctx, m.bucket, objectKey, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to remove object %s: %w", objectKey, err)
+	}
+
+	return nil
+}
+
+// UploadToStorage uploads every file of the result directory under the
+// "tasks/<id>/" prefix, keeping the relative layout of the directory.
+// It returns the key of the primary result file and the stored objects with
+// their checksums. progress may be nil.
+//
+// Files already stored with the same checksum are skipped and large files
+// resume their incomplete multipart upload, so a failed upload can be retried
+// cheaply. With a keyring the files are encrypted under a data key generated
+// for the upload.
+func (m *MinIOStorage) UploadToStorage(ctx context.Context, taskID uuid.UUID, resultDir string, progress domain.UploadProgressFunc) (*domain.UploadResult, error) {
+	files, err := listResultFiles(resultDir)
+	if err != nil {
+		return nil, fmt.Errorf("listing result files: %w", err)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no result file found in directory")
+	}
+
+	total, err := resultSize(resultDir, files, m.storedSize)
 	if err != nil {
-		return "", fmt.Errorf("reading result dir: %w", err)
+		return nil, err
 	}
+	tracker := newUploadProgress(total, progress)
 
-	var resultFileName string
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			resultFileName = entry.Name()
-			break
+	var dataKey *envelope.DataKey
+	if m.keyring != nil {
+		if dataKey, err = m.keyring.NewDataKey(); err != nil {
+			return nil, err
 		}
 	}
 
-	if resultFileName == "" {
-		return "", fmt.Errorf("no result file found in directory")
+	objects := make([]domain.Artifact, 0, len(files))
+	for _, relPath := range files {
+		objectKey := fmt.Sprintf("tasks/%s/%s", taskID, relPath)
+
+		object, err := m.uploadFile(ctx, objectKey, filepath.Join(resultDir, filepath.FromSlash(relPath)), dataKey, tracker)
+		if err != nil {
+			return nil, fmt.Errorf("saving to S3 storage: %w", err)
+		}
+		objects = append(objects, object)
 	}
 
-	resultFilePath := filepath.Join(resultDir, resultFileName)
+	return &domain.UploadResult{
+		PrimaryKey: fmt.Sprintf("tasks/%s/%s", taskID, primaryResultFile(files)),
+		Objects:    objects,
+	}, nil
+}
 
-	file, err := os.Open(resultFilePath)
+// uploadFile stores the file with its sha256 in the object metadata.
+func (m *MinIOStorage) uploadFile(ctx context.Context, objectKey string, filePath string, dataKey *envelope.DataKey, progress *uploadProgress) (domain.Artifact, error) {
+	file, err := os.Open(filePath)
 	if err != nil {
-		return "", fmt.Errorf("opening result file: %w", err)
+		return domain.Artifact{}, fmt.Errorf("opening result file: %w", err)
 	}
 	defer file.Close()
 
 	stat, err := file.Stat()
 	if err != nil {
-		return "", fmt.Errorf("getting file stat: %w", err)
+		return domain.Artifact{}, fmt.Errorf("getting file stat: %w", err)
 	}
+	size := stat.Size()
 
-	objectKey := fmt.Sprintf("tasks/%s/%s", taskID, resultFileName)
-	_, err = m.upload(ctx, objectKey, file, stat.Size())
+	sum, err := fileSHA256(io.NewSectionReader(file, 0, size))
 	if err != nil {
-		return "", fmt.Errorf("saving to S3 storage: %w", err)
+		return domain.Artifact{}, err
 	}
 
-	return objectKey, nil
-}
+	object := domain.Artifact{
+		Key:         objectKey,
+		Size:        size,
+		ContentType: contentTypeByKey(objectKey),
+		SHA256:      sum,
+	}
 
-func (m *MinIOStorage) upload(ctx context.Context, objectKey string, r io.Reader, size int64) (string, error) {
-	contentType := mime.TypeByExtension(filepath.Ext(objectKey))
-	if contentType == "" {
-		contentType = "application/octet-stream"
+	// uploaded by a previous attempt
+	if info, err := m.Client.StatObject(ctx, m.bucket, objectKey, minio.StatObjectOptions{}); err == nil &&
+		m.isStored(info, size, sum) {
+		progress.add(info.Size)
+		return object, nil
+	}
+
+	body, err := m.objectBody(objectKey, file, size, sum, dataKey)
+	if err != nil {
+		return domain.Artifact{}, err
+	}
+
+	if m.uploadCfg.PartSize > 0 && body.size > m.uploadCfg.PartSize {
+		return object, m.uploadMultipart(ctx, objectKey, body, progress)
 	}
 
-	_, err := m.Client.PutObject(ctx, m.bucket, objectKey, r, size, minio.PutObjectOptions{
-		ContentType: contentType,
+	err = m.retry(ctx, func() error {
+		_, err := m.upload(ctx, objectKey, io.NewSectionReader(body, 0, body.size), body.size, body.meta)
+		return err
 	})
+	if err != nil {
+		return domain.Artifact{}, err
+	}
+	progress.add(body.size)
+
+	return object, nil
+}
+
+func (m *MinIOStorage) upload(ctx context.Context, objectKey string, r io.Reader, size int64, meta map[string]string) (string, error) {
+	_, err := m.Client.PutObject(ctx, m.bucket, objectKey, r, size, m.putOptions(objectKey, meta))
 	if err != nil {
 		return "", fmt.Errorf("uploading into minio: %w", err)
 	}
@@ -133,8 +278,93 @@ func (m *MinIOStorage) upload(ctx context.Context, objectKey string, r io.Reader
 	return objectKey, nil
 }
 
-func (m *MinIOStorage) GetDownloadURL(ctx context.Context, objectKey string) (string, error) {
-	expiry := 10 * time.Minute
+// putOptions returns the options of a new object with the given user metadata.
+func (m *MinIOStorage) putOptions(objectKey string, meta map[string]string) minio.PutObjectOptions {
+	return minio.PutObjectOptions{
+		ContentType:          contentTypeByKey(objectKey),
+		ServerSideEncryption: m.sse,
+		UserMetadata:         meta,
+	}
+}
+
+// ListArtifacts returns all objects stored under the given prefix with
+// their stored sizes.
+func (m *MinIOStorage) ListArtifacts(ctx context.Context, prefix string) ([]domain.Artifact, error) {
+	objectsCh := m.Client.ListObjects(ctx, m.bucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	})
+
+	artifacts := []domain.Artifact{}
+	for obj := range objectsCh {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("listing objects: %w", obj.Err)
+		}
+
+		artifacts = append(artifacts, domain.Artifact{
+			Key:          obj.Key,
+			Size:         obj.Size,
+			ContentType:  contentTypeByKey(obj.Key),
+			LastModified: obj.LastModified,
+		})
+	}
+
+	return artifacts, nil
+}
+
+// OpenArtifact opens the object for reading. The returned reader supports
+// seeking, so it can be used to serve range requests. Encrypted objects are
+// decrypted on the fly, content failing authentication is reported as
+// domain.ErrArtifactCorrupted.
+func (m *MinIOStorage) OpenArtifact(ctx context.Context, objectKey string) (io.ReadSeekCloser, *domain.Artifact, error) {
+	obj, err := m.Client.GetObject(ctx, m.bucket, objectKey, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting object: %w", err)
+	}
+
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, nil, domain.ErrArtifactNotFound
+		}
+		return nil, nil, fmt.Errorf("getting object info: %w", err)
+	}
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = contentTypeByKey(objectKey)
+	}
+
+	artifact := &domain.Artifact{
+		Key:          info.Key,
+		Size:         info.Size,
+		ContentType:  contentType,
+		LastModified: info.LastModified,
+		SHA256:       info.UserMetadata[checksumMetaKey],
+	}
+
+	if info.UserMetadata[encKeyIDMetaKey] == "" {
+		return obj, artifact, nil
+	}
+
+	dec, err := m.openEncrypted(obj, info)
+	if err != nil {
+		obj.Close()
+		return nil, nil, err
+	}
+	artifact.Size = dec.Size()
+
+	return dec, artifact, nil
+}
+
+// GetDownloadURL returns a presigned URL of the object valid for expiry.
+// Encrypted objects are only readable through the service, a URL of its
+// /storage endpoint is returned for them.
+func (m *MinIOStorage) GetDownloadURL(ctx context.Context, objectKey string, expiry time.Duration) (string, error) {
+	if m.signer != nil {
+		return m.signer.GetDownloadURL(objectKey, expiry), nil
+	}
 
 	reqParams := make(url.Values)
 
@@ -146,6 +376,15 @@ func (m *MinIOStorage) GetDownloadURL(ctx context.Context, objectKey string) (st
 	return presignedUrl.String(), nil
 }
 
+// VerifySignedURL checks a download URL produced by GetDownloadURL for an
+// encrypted object.
+func (m *MinIOStorage) VerifySignedURL(objectKey string, query url.Values) error {
+	if m.signer == nil {
+		return domain.ErrInvalidSignature
+	}
+	return m.signer.VerifySignedURL(objectKey, query)
+}
+
 func (m *MinIOStorage) CheckStatus(ctx context.Context) error {
 	_, err := m.Client.BucketExists(ctx, m.bucket)
 	return err
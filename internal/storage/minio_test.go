@@ -10,12 +10,21 @@ package storage
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/domain"
+	"pinn-connect-service/internal/envelope"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/johannesboyne/gofakes3"
@@ -64,10 +73,40 @@ func newTestStorage(t *testing.T) *MinIOStorage {
 	return &MinIOStorage{
 		Client:         client,
 		clientExternal: client,
+		core:           &minio.Core{Client: client},
 		bucket:         testBucket,
 	}
 }
 
+// newTestMultipartStorage returns a storage uploading files larger than
+// partSize in parts.
+func newTestMultipartStorage(t *testing.T, partSize int64) *MinIOStorage {
+	t.Helper()
+	s := newTestStorage(t)
+	s.uploadCfg = config.UploadConfig{PartSize: partSize, Concurrency: 3}
+	return s
+}
+
+// objectContent reads the whole object.
+func objectContent(t *testing.T, s *MinIOStorage, key string) string {
+	t.Helper()
+	obj, err := s.Client.GetObject(context.Background(), s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		t.Fatalf("objectContent: %v", err)
+	}
+	defer obj.Close()
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		t.Fatalf("objectContent: %v", err)
+	}
+	return string(data)
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
 // tempDirWithFile creates a temp directory with one file and returns the dir path.
 func tempDirWithFile(t *testing.T, filename, content string) string {
 	t.Helper()
@@ -99,6 +138,52 @@ func objectExists(s *MinIOStorage, key string) bool {
 	return err == nil
 }
 
+// ─────────────────────────────────────────────
+// Options
+// ─────────────────────────────────────────────
+
+func TestMinIOOptions_Addressing(t *testing.T) {
+	cases := map[string]minio.BucketLookupType{
+		"auto":    minio.BucketLookupAuto,
+		"path":    minio.BucketLookupPath,
+		"virtual": minio.BucketLookupDNS,
+	}
+	for addressing, want := range cases {
+		opts := minioOptions(config.MinIOConfig{Addressing: addressing, Region: "eu-central-1", AccessKey: "a", SecretKey: "b"})
+		if opts.BucketLookup != want || opts.Region != "eu-central-1" {
+			t.Errorf("%s: unexpected options %+v", addressing, opts)
+		}
+	}
+}
+
+// Without static keys credentials must come from the environment chain.
+func TestMinIOOptions_CredentialsChain(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "env-access")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+
+	opts := minioOptions(config.MinIOConfig{})
+	v, err := opts.Creds.GetWithContext(&credentials.CredContext{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.AccessKeyID != "env-access" {
+		t.Errorf("expected credentials from env, got %q", v.AccessKeyID)
+	}
+}
+
+func TestServerSideEncryption(t *testing.T) {
+	if sse, err := serverSideEncryption(config.MinIOConfig{SSE: "none"}); err != nil || sse != nil {
+		t.Errorf("expected no encryption, got %v / %v", sse, err)
+	}
+	if sse, err := serverSideEncryption(config.MinIOConfig{SSE: "s3"}); err != nil || sse == nil {
+		t.Errorf("expected SSE-S3, got %v / %v", sse, err)
+	}
+	sse, err := serverSideEncryption(config.MinIOConfig{SSE: "kms", SSEKMSKeyID: "key-1"})
+	if err != nil || sse == nil || sse.Type() != "KMS" {
+		t.Errorf("expected SSE-KMS, got %v / %v", sse, err)
+	}
+}
+
 // ─────────────────────────────────────────────
 // CheckStatus
 // ─────────────────────────────────────────────
@@ -134,7 +219,7 @@ func TestMinIOStorage_Upload_Success_PlainText(t *testing.T) {
 	data := []byte("hello world")
 	key := "tasks/abc/result.txt"
 
-	got, err := s.upload(context.Background(), key, bytes.NewReader(data), int64(len(data)))
+	got, err := s.upload(context.Background(), key, bytes.NewReader(data), int64(len(data)), nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -151,7 +236,7 @@ func TestMinIOStorage_Upload_Success_JSON(t *testing.T) {
 	data := []byte(`{"status":"ok"}`)
 	key := "tasks/abc/output.json"
 
-	if _, err := s.upload(context.Background(), key, bytes.NewReader(data), int64(len(data))); err != nil {
+	if _, err := s.upload(context.Background(), key, bytes.NewReader(data), int64(len(data)), nil); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if !objectExists(s, key) {
@@ -165,7 +250,7 @@ func TestMinIOStorage_Upload_DefaultContentType_NoExtension(t *testing.T) {
 	data := []byte("binary data")
 	key := "tasks/abc/datafile" // no extension
 
-	if _, err := s.upload(context.Background(), key, bytes.NewReader(data), int64(len(data))); err != nil {
+	if _, err := s.upload(context.Background(), key, bytes.NewReader(data), int64(len(data)), nil); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if !objectExists(s, key) {
@@ -181,7 +266,7 @@ func TestMinIOStorage_Upload_ClientError(t *testing.T) {
 	})
 	s := &MinIOStorage{Client: client, bucket: testBucket}
 	data := []byte("data")
-	_, err := s.upload(context.Background(), "any/key.txt", bytes.NewReader(data), int64(len(data)))
+	_, err := s.upload(context.Background(), "any/key.txt", bytes.NewReader(data), int64(len(data)), nil)
 	if err == nil {
 		t.Fatal("expected error from unreachable server, got nil")
 	}
@@ -196,10 +281,11 @@ func TestMinIOStorage_UploadToStorage_Success(t *testing.T) {
 	id := uuid.New()
 	dir := tempDirWithFile(t, "result.csv", "col1,col2\nval1,val2")
 
-	key, err := s.UploadToStorage(context.Background(), id, dir)
+	res, err := s.UploadToStorage(context.Background(), id, dir, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	key := res.PrimaryKey
 	if !strings.Contains(key, id.String()) {
 		t.Errorf("expected key to contain task ID %v, got %q", id, key)
 	}
@@ -213,7 +299,7 @@ func TestMinIOStorage_UploadToStorage_Success(t *testing.T) {
 
 func TestMinIOStorage_UploadToStorage_DirNotFound(t *testing.T) {
 	s := newTestStorage(t)
-	_, err := s.UploadToStorage(context.Background(), uuid.New(), "/tmp/__nonexistent_dir_xyz_99999__")
+	_, err := s.UploadToStorage(context.Background(), uuid.New(), "/tmp/__nonexistent_dir_xyz_99999__", nil)
 	if err == nil {
 		t.Fatal("expected error for non-existent directory, got nil")
 	}
@@ -223,7 +309,7 @@ func TestMinIOStorage_UploadToStorage_EmptyDir(t *testing.T) {
 	s := newTestStorage(t)
 	dir := t.TempDir() // no files
 
-	_, err := s.UploadToStorage(context.Background(), uuid.New(), dir)
+	_, err := s.UploadToStorage(context.Background(), uuid.New(), dir, nil)
 	if err == nil {
 		t.Fatal("expected error for empty directory, got nil")
 	}
@@ -237,7 +323,7 @@ func TestMinIOStorage_UploadToStorage_OnlySubdirectories(t *testing.T) {
 		t.Fatalf("creating subdir: %v", err)
 	}
 
-	_, err := s.UploadToStorage(context.Background(), uuid.New(), dir)
+	_, err := s.UploadToStorage(context.Background(), uuid.New(), dir, nil)
 	if err == nil {
 		t.Fatal("expected error when no files in directory, got nil")
 	}
@@ -259,10 +345,11 @@ func TestMinIOStorage_UploadToStorage_SkipsLeadingSubdirectory(t *testing.T) {
 	}
 
 	id := uuid.New()
-	key, err := s.UploadToStorage(context.Background(), id, dir)
+	res, err := s.UploadToStorage(context.Background(), id, dir, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	key := res.PrimaryKey
 	if !strings.Contains(key, "b-result.txt") {
 		t.Errorf("expected key to contain 'b-result.txt', got %q", key)
 	}
@@ -274,10 +361,11 @@ func TestMinIOStorage_UploadToStorage_KeyFormat(t *testing.T) {
 	id := uuid.New()
 	dir := tempDirWithFile(t, "model_output.zip", "zip-content")
 
-	key, err := s.UploadToStorage(context.Background(), id, dir)
+	res, err := s.UploadToStorage(context.Background(), id, dir, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	key := res.PrimaryKey
 
 	expected := fmt.Sprintf("tasks/%s/model_output.zip", id)
 	if key != expected {
@@ -285,6 +373,108 @@ func TestMinIOStorage_UploadToStorage_KeyFormat(t *testing.T) {
 	}
 }
 
+// Nested files must be uploaded keeping their relative layout, while the
+// returned key still points at the top-level result file.
+func TestMinIOStorage_UploadToStorage_UploadsNestedFiles(t *testing.T) {
+	s := newTestStorage(t)
+	id := uuid.New()
+	dir := tempDirWithFile(t, "result.csv", "a,b")
+	if err := os.MkdirAll(filepath.Join(dir, "checkpoints"), 0o755); err != nil {
+		t.Fatalf("creating subdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "checkpoints", "epoch1.pt"), []byte("weights"), 0o644); err != nil {
+		t.Fatalf("creating nested file: %v", err)
+	}
+
+	res, err := s.UploadToStorage(context.Background(), id, dir, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	key := res.PrimaryKey
+	if key != fmt.Sprintf("tasks/%s/result.csv", id) {
+		t.Errorf("expected primary key to be result.csv, got %q", key)
+	}
+	if !objectExists(s, fmt.Sprintf("tasks/%s/checkpoints/epoch1.pt", id)) {
+		t.Error("expected nested file to be uploaded")
+	}
+}
+
+// ─────────────────────────────────────────────
+// ListArtifacts
+// ─────────────────────────────────────────────
+
+func TestMinIOStorage_ListArtifacts_ReturnsPrefixObjects(t *testing.T) {
+	s := newTestStorage(t)
+	id := uuid.New()
+	putObject(t, s, fmt.Sprintf("tasks/%s/result.json", id), "{}")
+	putObject(t, s, fmt.Sprintf("tasks/%s/sub/log.txt", id), "log")
+	putObject(t, s, fmt.Sprintf("tasks/%s/other.txt", uuid.New()), "other")
+
+	artifacts, err := s.ListArtifacts(context.Background(), fmt.Sprintf("tasks/%s/", id))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(artifacts) != 2 {
+		t.Fatalf("expected 2 artifacts, got %d", len(artifacts))
+	}
+	for _, a := range artifacts {
+		if strings.HasSuffix(a.Key, ".json") && a.ContentType != "application/json" {
+			t.Errorf("expected json content type, got %q", a.ContentType)
+		}
+	}
+}
+
+func TestMinIOStorage_ListArtifacts_Empty(t *testing.T) {
+	s := newTestStorage(t)
+
+	artifacts, err := s.ListArtifacts(context.Background(), "tasks/none/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(artifacts) != 0 {
+		t.Errorf("expected no artifacts, got %d", len(artifacts))
+	}
+}
+
+// ─────────────────────────────────────────────
+// OpenArtifact
+// ─────────────────────────────────────────────
+
+func TestMinIOStorage_OpenArtifact_ReadAndSeek(t *testing.T) {
+	s := newTestStorage(t)
+	key := "tasks/abc/result.txt"
+	putObject(t, s, key, "0123456789")
+
+	rc, info, err := s.OpenArtifact(context.Background(), key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+
+	if info.Size != 10 {
+		t.Errorf("expected size 10, got %d", info.Size)
+	}
+	if _, err := rc.Seek(5, io.SeekStart); err != nil {
+		t.Fatalf("seek: %v", err)
+	}
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(data) != "56789" {
+		t.Errorf("expected '56789', got %q", data)
+	}
+}
+
+func TestMinIOStorage_OpenArtifact_NotFound(t *testing.T) {
+	s := newTestStorage(t)
+
+	_, _, err := s.OpenArtifact(context.Background(), "tasks/abc/missing.txt")
+	if !errors.Is(err, domain.ErrArtifactNotFound) {
+		t.Fatalf("expected ErrArtifactNotFound, got %v", err)
+	}
+}
+
 // ─────────────────────────────────────────────
 // DeleteArtifacts
 // ─────────────────────────────────────────────
@@ -371,6 +561,28 @@ func TestMinIOStorage_DeleteArtifacts_MultipleFiles(t *testing.T) {
 	}
 }
 
+func TestMinIOStorage_DeleteArtifact_KeepsOtherObjects(t *testing.T) {
+	s := newTestStorage(t)
+	id := uuid.New()
+	ctx := context.Background()
+
+	target := fmt.Sprintf("tasks/%s/orphan.txt", id)
+	other := fmt.Sprintf("tasks/%s/result.txt", id)
+	putObject(t, s, target, "orphan")
+	putObject(t, s, other, "result")
+
+	if err := s.DeleteArtifact(ctx, target); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if objectExists(s, target) {
+		t.Errorf("expected object %q to be deleted", target)
+	}
+	if !objectExists(s, other) {
+		t.Errorf("expected object %q to be kept", other)
+	}
+}
+
 // ─────────────────────────────────────────────
 // GetDownloadURL
 // ─────────────────────────────────────────────
@@ -380,7 +592,7 @@ func TestMinIOStorage_GetDownloadURL_Success(t *testing.T) {
 	id := uuid.New()
 	objectKey := fmt.Sprintf("tasks/%s/result.zip", id)
 
-	rawURL, err := s.GetDownloadURL(context.Background(), objectKey)
+	rawURL, err := s.GetDownloadURL(context.Background(), objectKey, 10*time.Minute)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -395,7 +607,7 @@ func TestMinIOStorage_GetDownloadURL_Success(t *testing.T) {
 // The returned URL must be a syntactically valid absolute URL.
 func TestMinIOStorage_GetDownloadURL_ReturnsValidURL(t *testing.T) {
 	s := newTestStorage(t)
-	rawURL, err := s.GetDownloadURL(context.Background(), "tasks/abc/file.txt")
+	rawURL, err := s.GetDownloadURL(context.Background(), "tasks/abc/file.txt", 10*time.Minute)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -408,7 +620,7 @@ func TestMinIOStorage_GetDownloadURL_ReturnsValidURL(t *testing.T) {
 // signing took place).
 func TestMinIOStorage_GetDownloadURL_ContainsSignature(t *testing.T) {
 	s := newTestStorage(t)
-	rawURL, err := s.GetDownloadURL(context.Background(), "tasks/abc/file.txt")
+	rawURL, err := s.GetDownloadURL(context.Background(), "tasks/abc/file.txt", 10*time.Minute)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -420,11 +632,11 @@ func TestMinIOStorage_GetDownloadURL_ContainsSignature(t *testing.T) {
 // Different object keys must produce different presigned URLs.
 func TestMinIOStorage_GetDownloadURL_DifferentKeysProduceDifferentURLs(t *testing.T) {
 	s := newTestStorage(t)
-	url1, err := s.GetDownloadURL(context.Background(), "tasks/aaa/file1.zip")
+	url1, err := s.GetDownloadURL(context.Background(), "tasks/aaa/file1.zip", 10*time.Minute)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	url2, err := s.GetDownloadURL(context.Background(), "tasks/bbb/file2.zip")
+	url2, err := s.GetDownloadURL(context.Background(), "tasks/bbb/file2.zip", 10*time.Minute)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -433,6 +645,18 @@ func TestMinIOStorage_GetDownloadURL_DifferentKeysProduceDifferentURLs(t *testin
 	}
 }
 
+// The expiry requested by the caller must be signed into the URL.
+func TestMinIOStorage_GetDownloadURL_UsesExpiry(t *testing.T) {
+	s := newTestStorage(t)
+	rawURL, err := s.GetDownloadURL(context.Background(), "tasks/abc/file.txt", 2*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(rawURL, "X-Amz-Expires=7200") {
+		t.Errorf("expected presigned URL to expire in 7200s, got %q", rawURL)
+	}
+}
+
 // An empty bucket name should cause PresignedGetObject to return an error.
 func TestMinIOStorage_GetDownloadURL_InvalidBucket_Error(t *testing.T) {
 	client := newFakeMinIOClient(t)
@@ -440,7 +664,394 @@ func TestMinIOStorage_GetDownloadURL_InvalidBucket_Error(t *testing.T) {
 		clientExternal: client,
 		bucket:         "", // invalid — empty bucket name
 	}
-	if _, err := s.GetDownloadURL(context.Background(), "tasks/abc/file.txt"); err == nil {
+	if _, err := s.GetDownloadURL(context.Background(), "tasks/abc/file.txt", 10*time.Minute); err == nil {
 		t.Fatal("expected error for empty bucket name, got nil")
 	}
 }
+
+// ─────────────────────────────────────────────
+// Multipart upload
+// ─────────────────────────────────────────────
+
+func TestMinIOStorage_UploadToStorage_Multipart(t *testing.T) {
+	s := newTestMultipartStorage(t, 10)
+	id := uuid.New()
+	content := strings.Repeat("0123456789", 9) + "abcde"
+	dir := tempDirWithFile(t, "model.bin", content)
+
+	var uploaded, total int64
+	res, err := s.UploadToStorage(context.Background(), id, dir, func(u, t int64) { uploaded, total = u, t })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	key := res.PrimaryKey
+
+	if got := objectContent(t, s, key); got != content {
+		t.Errorf("unexpected object content %q", got)
+	}
+	if uploaded != int64(len(content)) || total != int64(len(content)) {
+		t.Errorf("expected progress %d/%d, got %d/%d", len(content), len(content), uploaded, total)
+	}
+	if uploadID, _, err := s.findMultipartUpload(context.Background(), key); err != nil || uploadID != "" {
+		t.Errorf("expected no incomplete upload, got %q / %v", uploadID, err)
+	}
+}
+
+// Parts uploaded by a failed attempt are reused by the next one.
+func TestMinIOStorage_UploadToStorage_ResumesMultipart(t *testing.T) {
+	s := newTestMultipartStorage(t, 10)
+	ctx := context.Background()
+	id := uuid.New()
+	key := fmt.Sprintf("tasks/%s/model.bin", id)
+	dir := tempDirWithFile(t, "model.bin", "0123456789abcdefghij")
+
+	uploadID, err := s.core.NewMultipartUpload(ctx, s.bucket, key, minio.PutObjectOptions{})
+	if err != nil {
+		t.Fatalf("starting upload: %v", err)
+	}
+	// the content differs, so the final object shows which part was reused
+	if _, err := s.uploadPart(ctx, key, uploadID, 1, io.NewSectionReader(strings.NewReader("XXXXXXXXXX"), 0, 10)); err != nil {
+		t.Fatalf("uploading part: %v", err)
+	}
+
+	if _, err := s.UploadToStorage(ctx, id, dir, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := objectContent(t, s, key); got != "XXXXXXXXXXabcdefghij" {
+		t.Errorf("expected the uploaded part to be reused, got %q", got)
+	}
+}
+
+// An object already uploaded with the same checksum is not uploaded again.
+func TestMinIOStorage_UploadToStorage_SkipsUploadedFiles(t *testing.T) {
+	s := newTestStorage(t)
+	id := uuid.New()
+	key := fmt.Sprintf("tasks/%s/result.txt", id)
+	// the stored content differs, so the object shows whether it was replaced
+	if _, err := s.upload(context.Background(), key, strings.NewReader("XXXX"), 4, map[string]string{checksumMetaKey: sha256Hex("done")}); err != nil {
+		t.Fatalf("uploading object: %v", err)
+	}
+
+	var uploaded int64
+	_, err := s.UploadToStorage(context.Background(), id, tempDirWithFile(t, "result.txt", "done"),
+		func(u, _ int64) { uploaded = u })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := objectContent(t, s, key); got != "XXXX" || uploaded != 4 {
+		t.Errorf("expected the stored object to be kept, got %q (%d bytes reported)", got, uploaded)
+	}
+}
+
+// An object of the same size but without a matching checksum is replaced.
+func TestMinIOStorage_UploadToStorage_ReplacesChangedFiles(t *testing.T) {
+	s := newTestStorage(t)
+	id := uuid.New()
+	key := fmt.Sprintf("tasks/%s/result.txt", id)
+	putObject(t, s, key, "XXXX")
+
+	if _, err := s.UploadToStorage(context.Background(), id, tempDirWithFile(t, "result.txt", "done"), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := objectContent(t, s, key); got != "done" {
+		t.Errorf("expected the stored object to be replaced, got %q", got)
+	}
+}
+
+// Checksums are returned for every object and kept in the object metadata.
+func TestMinIOStorage_UploadToStorage_RecordsChecksums(t *testing.T) {
+	for name, partSize := range map[string]int64{"single": 0, "multipart": 10} {
+		t.Run(name, func(t *testing.T) {
+			s := newTestMultipartStorage(t, partSize)
+			id := uuid.New()
+			content := strings.Repeat("0123456789", 3)
+
+			res, err := s.UploadToStorage(context.Background(), id, tempDirWithFile(t, "model.bin", content), nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			want := sha256Hex(content)
+			if len(res.Objects) != 1 || res.Objects[0].SHA256 != want || res.Objects[0].Size != int64(len(content)) {
+				t.Fatalf("unexpected objects %+v", res.Objects)
+			}
+
+			rc, info, err := s.OpenArtifact(context.Background(), res.PrimaryKey)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			rc.Close()
+			if info.SHA256 != want {
+				t.Errorf("expected checksum %s in the metadata, got %q", want, info.SHA256)
+			}
+		})
+	}
+}
+
+func TestMinIOStorage_DeleteArtifacts_AbortsIncompleteUploads(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	id := uuid.New()
+	key := fmt.Sprintf("tasks/%s/model.bin", id)
+
+	if _, err := s.core.NewMultipartUpload(ctx, s.bucket, key, minio.PutObjectOptions{}); err != nil {
+		t.Fatalf("starting upload: %v", err)
+	}
+
+	if err := s.DeleteArtifacts(ctx, id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if uploadID, _, err := s.findMultipartUpload(ctx, key); err != nil || uploadID != "" {
+		t.Errorf("expected the upload to be aborted, got %q / %v", uploadID, err)
+	}
+}
+
+func TestMinIOStorage_Retry(t *testing.T) {
+	s := &MinIOStorage{uploadCfg: config.UploadConfig{PartRetries: 2}}
+
+	calls := 0
+	err := s.retry(context.Background(), func() error {
+		if calls++; calls < 3 {
+			return errors.New("temporary")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("expected success on the last retry, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = s.retry(context.Background(), func() error {
+		calls++
+		return errors.New("permanent")
+	})
+	if err == nil || calls != 3 {
+		t.Errorf("expected an error after 3 calls, got %v after %d calls", err, calls)
+	}
+}
+
+func TestMultipartPartSize(t *testing.T) {
+	if got := multipartPartSize(100<<20, 64<<20); got != 64<<20 {
+		t.Errorf("expected the configured part size, got %d", got)
+	}
+	// 1 TiB doesn't fit into 10000 parts of 64 MiB
+	got := multipartPartSize(1<<40, 64<<20)
+	if got%(1<<20) != 0 || (int64(1<<40)+got-1)/got > maxUploadParts {
+		t.Errorf("unexpected part size %d", got)
+	}
+}
+
+// ─────────────────────────────────────────────
+// Client-side encryption
+// ─────────────────────────────────────────────
+
+func newTestKeyring(t *testing.T, active string, ids ...string) *envelope.Keyring {
+	t.Helper()
+	keys := map[string][]byte{}
+	for i, id := range ids {
+		keys[id] = bytes.Repeat([]byte{byte(i + 1)}, envelope.KeySize)
+	}
+	k, err := envelope.NewKeyring(active, keys)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	return k
+}
+
+// newTestEncryptedStorage returns a storage encrypting objects with the k1
+// master key.
+func newTestEncryptedStorage(t *testing.T, partSize int64) *MinIOStorage {
+	t.Helper()
+	s := newTestMultipartStorage(t, partSize)
+	s.keyring = newTestKeyring(t, "k1", "k1")
+	s.signer = &urlSigner{publicURL: "http://pinn.local", signingKey: []byte("secret"), now: time.Now}
+	return s
+}
+
+// readArtifact reads the whole artifact through OpenArtifact.
+func readArtifact(s *MinIOStorage, key string) (string, *domain.Artifact, error) {
+	rc, info, err := s.OpenArtifact(context.Background(), key)
+	if err != nil {
+		return "", nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	return string(data), info, err
+}
+
+func TestMinIOStorage_Encrypted_RoundTrip(t *testing.T) {
+	for name, partSize := range map[string]int64{"single": 0, "multipart": 10} {
+		t.Run(name, func(t *testing.T) {
+			s := newTestEncryptedStorage(t, partSize)
+			content := strings.Repeat("secret input ", 10)
+
+			var uploaded, total int64
+			res, err := s.UploadToStorage(context.Background(), uuid.New(), tempDirWithFile(t, "model.bin", content),
+				func(u, t int64) { uploaded, total = u, t })
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			stored := objectContent(t, s, res.PrimaryKey)
+			if strings.Contains(stored, "secret") || int64(len(stored)) != envelope.CiphertextSize(int64(len(content))) {
+				t.Errorf("expected the object to be stored encrypted, got %d bytes", len(stored))
+			}
+			if uploaded != int64(len(stored)) || total != int64(len(stored)) {
+				t.Errorf("expected progress in stored bytes %d, got %d/%d", len(stored), uploaded, total)
+			}
+			if res.Objects[0].SHA256 != sha256Hex(content) || res.Objects[0].Size != int64(len(content)) {
+				t.Errorf("expected the plaintext size and checksum, got %+v", res.Objects[0])
+			}
+
+			got, info, err := readArtifact(s, res.PrimaryKey)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != content || info.Size != int64(len(content)) {
+				t.Errorf("expected the plaintext of %d bytes, got %q (%d)", len(content), got, info.Size)
+			}
+			if info.SHA256 != "" {
+				t.Errorf("expected no plain checksum next to the ciphertext, got %q", info.SHA256)
+			}
+		})
+	}
+}
+
+func TestMinIOStorage_Encrypted_SeekWithinObject(t *testing.T) {
+	s := newTestEncryptedStorage(t, 0)
+	res, err := s.UploadToStorage(context.Background(), uuid.New(), tempDirWithFile(t, "result.txt", "0123456789"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rc, _, err := s.OpenArtifact(context.Background(), res.PrimaryKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+
+	if _, err := rc.Seek(5, io.SeekStart); err != nil {
+		t.Fatalf("seek: %v", err)
+	}
+	data, err := io.ReadAll(rc)
+	if err != nil || string(data) != "56789" {
+		t.Errorf("expected '56789', got %q / %v", data, err)
+	}
+}
+
+// A modified ciphertext is reported as corrupted instead of being returned.
+func TestMinIOStorage_Encrypted_DetectsTampering(t *testing.T) {
+	s := newTestEncryptedStorage(t, 0)
+	ctx := context.Background()
+	res, err := s.UploadToStorage(ctx, uuid.New(), tempDirWithFile(t, "result.txt", "done"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info, err := s.Client.StatObject(ctx, s.bucket, res.PrimaryKey, minio.StatObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := []byte(objectContent(t, s, res.PrimaryKey))
+	tampered[0] ^= 1
+	if _, err := s.upload(ctx, res.PrimaryKey, bytes.NewReader(tampered), int64(len(tampered)), info.UserMetadata); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := readArtifact(s, res.PrimaryKey); !errors.Is(err, domain.ErrArtifactCorrupted) {
+		t.Errorf("expected ErrArtifactCorrupted, got %v", err)
+	}
+}
+
+// Objects written under an old master key stay readable after a rotation.
+func TestMinIOStorage_Encrypted_KeyRotation(t *testing.T) {
+	s := newTestEncryptedStorage(t, 0)
+	res, err := s.UploadToStorage(context.Background(), uuid.New(), tempDirWithFile(t, "result.txt", "done"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s.keyring = newTestKeyring(t, "k2", "k1", "k2")
+	if got, _, err := readArtifact(s, res.PrimaryKey); err != nil || got != "done" {
+		t.Errorf("expected 'done', got %q / %v", got, err)
+	}
+}
+
+// Plain objects are replaced by encrypted ones, encrypted objects with the
+// same content are kept.
+func TestMinIOStorage_Encrypted_SkipsOnlyEncryptedFiles(t *testing.T) {
+	s := newTestEncryptedStorage(t, 0)
+	ctx := context.Background()
+	id := uuid.New()
+	key := fmt.Sprintf("tasks/%s/result.txt", id)
+	if _, err := s.upload(ctx, key, strings.NewReader("done"), 4, map[string]string{checksumMetaKey: sha256Hex("done")}); err != nil {
+		t.Fatal(err)
+	}
+	dir := tempDirWithFile(t, "result.txt", "done")
+
+	if _, err := s.UploadToStorage(ctx, id, dir, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first := objectContent(t, s, key)
+	if first == "done" {
+		t.Fatal("expected the plain object to be replaced")
+	}
+
+	if _, err := s.UploadToStorage(ctx, id, dir, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if objectContent(t, s, key) != first {
+		t.Error("expected the encrypted object to be kept")
+	}
+}
+
+// Parts of an earlier attempt were sealed with another data key, the upload
+// starts over.
+func TestMinIOStorage_Encrypted_ReplacesIncompleteUpload(t *testing.T) {
+	s := newTestEncryptedStorage(t, 10)
+	ctx := context.Background()
+	id := uuid.New()
+	key := fmt.Sprintf("tasks/%s/model.bin", id)
+
+	uploadID, err := s.core.NewMultipartUpload(ctx, s.bucket, key, minio.PutObjectOptions{})
+	if err != nil {
+		t.Fatalf("starting upload: %v", err)
+	}
+	if _, err := s.uploadPart(ctx, key, uploadID, 1, io.NewSectionReader(strings.NewReader("XXXXXXXXXX"), 0, 10)); err != nil {
+		t.Fatalf("uploading part: %v", err)
+	}
+
+	if _, err := s.UploadToStorage(ctx, id, tempDirWithFile(t, "model.bin", "0123456789abcdefghij"), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, _, err := readArtifact(s, key); err != nil || got != "0123456789abcdefghij" {
+		t.Errorf("expected the whole file, got %q / %v", got, err)
+	}
+}
+
+func TestMinIOStorage_Encrypted_DownloadURL(t *testing.T) {
+	s := newTestEncryptedStorage(t, 0)
+	key := "tasks/abc/result.txt"
+
+	rawURL, err := s.GetDownloadURL(context.Background(), key, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(rawURL, "http://pinn.local/storage/"+key+"?") {
+		t.Fatalf("expected a service url, got %q", rawURL)
+	}
+
+	u, _ := url.Parse(rawURL)
+	if err := s.VerifySignedURL(key, u.Query()); err != nil {
+		t.Errorf("expected a valid signature, got %v", err)
+	}
+	if err := newTestStorage(t).VerifySignedURL(key, u.Query()); !errors.Is(err, domain.ErrInvalidSignature) {
+		t.Errorf("expected plain storage to reject signed urls, got %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// maxUploadParts is the largest number of parts S3 accepts for one object.
+const maxUploadParts = 10000
+
+// uploadMultipart uploads the body in parts of the configured size, several
+// parts at a time. An incomplete upload of the same key left by a previous
+// attempt is resumed: its parts of the expected size are not uploaded again.
+// A failed upload is not aborted, so it can be resumed later. Bodies that
+// aren't resumable replace the incomplete upload instead.
+func (m *MinIOStorage) uploadMultipart(ctx context.Context, objectKey string, body objectBody, progress *uploadProgress) error {
+	size := body.size
+	partSize := multipartPartSize(size, m.uploadCfg.PartSize)
+	partsCount := int((size + partSize - 1) / partSize)
+
+	uploadID, uploaded, err := m.findMultipartUpload(ctx, objectKey)
+	if err != nil {
+		return err
+	}
+
+	if uploadID != "" && !body.resumable {
+		if err := m.core.AbortMultipartUpload(ctx, m.bucket, objectKey, uploadID); err != nil {
+			return fmt.Errorf("aborting multipart upload: %w", err)
+		}
+		uploadID, uploaded = "", nil
+	}
+
+	if uploadID == "" {
+		uploadID, err = m.core.NewMultipartUpload(ctx, m.bucket, objectKey, m.putOptions(objectKey, body.meta))
+		if err != nil {
+			return fmt.Errorf("starting multipart upload: %w", err)
+		}
+	} else {
+		slog.Info("resuming multipart upload", "key", objectKey, "upload_id", uploadID, "uploaded_parts", len(uploaded))
+	}
+
+	var (
+		mu       sync.Mutex
+		complete = make([]minio.CompletePart, 0, partsCount)
+		pending  = make(chan int, partsCount)
+	)
+
+	for number := 1; number <= partsCount; number++ {
+		length := min(partSize, size-int64(number-1)*partSize)
+		if part, ok := uploaded[number]; ok && part.Size == length {
+			complete = append(complete, minio.CompletePart{PartNumber: number, ETag: part.ETag})
+			progress.add(length)
+			continue
+		}
+		pending <- number
+	}
+	close(pending)
+
+	partsCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	for range min(m.uploadCfg.Concurrency, len(pending)) {
+		wg.Go(func() {
+			for number := range pending {
+				offset := int64(number-1) * partSize
+				length := min(partSize, size-offset)
+
+				var part minio.ObjectPart
+				err := m.retry(partsCtx, func() (err error) {
+					part, err = m.uploadPart(partsCtx, objectKey, uploadID, number, io.NewSectionReader(body, offset, length))
+					return err
+				})
+				if err != nil {
+					cancel(fmt.Errorf("uploading part %d: %w", number, err))
+					return
+				}
+
+				mu.Lock()
+				complete = append(complete, minio.CompletePart{PartNumber: number, ETag: part.ETag})
+				mu.Unlock()
+				progress.add(length)
+			}
+		})
+	}
+	wg.Wait()
+
+	if err := context.Cause(partsCtx); err != nil {
+		return err
+	}
+
+	sort.Slice(complete, func(i, j int) bool { return complete[i].PartNumber < complete[j].PartNumber })
+
+	if _, err := m.core.CompleteMultipartUpload(ctx, m.bucket, objectKey, uploadID, complete, minio.PutObjectOptions{}); err != nil {
+		return fmt.Errorf("completing multipart upload: %w", err)
+	}
+
+	return nil
+}
+
+// uploadPart uploads a single part. The part is sent with its MD5, so the
+// storage rejects it if it was corrupted on the way.
+func (m *MinIOStorage) uploadPart(ctx context.Context, objectKey, uploadID string, number int, section *io.SectionReader) (minio.ObjectPart, error) {
+	hash := md5.New()
+	if _, err := io.Copy(hash, section); err != nil {
+		return minio.ObjectPart{}, fmt.Errorf("hashing part: %w", err)
+	}
+	if _, err := section.Seek(0, io.SeekStart); err != nil {
+		return minio.ObjectPart{}, fmt.Errorf("rewinding part: %w", err)
+	}
+
+	return m.core.PutObjectPart(ctx, m.bucket, objectKey, uploadID, number, section, section.Size(), minio.PutObjectPartOptions{
+		Md5Base64:            base64.StdEncoding.EncodeToString(hash.Sum(nil)),
+		DisableContentSha256: true,
+	})
+}
+
+// findMultipartUpload returns the most recent incomplete upload of the key
+// together with its uploaded parts. The upload ID is empty if there is none.
+func (m *MinIOStorage) findMultipartUpload(ctx context.Context, objectKey string) (string, map[int]minio.ObjectPart, error) {
+	uploads, err := m.core.ListMultipartUploads(ctx, m.bucket, objectKey, "", "", "", 1000)
+	if isNoSuchUpload(err) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("listing multipart uploads: %w", err)
+	}
+
+	var latest *minio.ObjectMultipartInfo
+	for i, upload := range uploads.Uploads {
+		if upload.Key != objectKey {
+			continue
+		}
+		if latest == nil || upload.Initiated.After(latest.Initiated) {
+			latest = &uploads.Uploads[i]
+		}
+	}
+	if latest == nil {
+		return "", nil, nil
+	}
+
+	parts := make(map[int]minio.ObjectPart)
+	marker := 0
+	for {
+		res, err := m.core.ListObjectParts(ctx, m.bucket, objectKey, latest.UploadID, marker, maxUploadParts)
+		if err != nil {
+			return "", nil, fmt.Errorf("listing uploaded parts: %w", err)
+		}
+		for _, part := range res.ObjectParts {
+			parts[part.PartNumber] = part
+		}
+		if !res.IsTruncated {
+			break
+		}
+		marker = res.NextPartNumberMarker
+	}
+
+	return latest.UploadID, parts, nil
+}
+
+// abortMultipartUploads drops incomplete uploads under the prefix, so their
+// parts don't stay in the bucket after the task is deleted.
+func (m *MinIOStorage) abortMultipartUploads(ctx context.Context, prefix string) error {
+	for upload := range m.Client.ListIncompleteUploads(ctx, m.bucket, prefix, true) {
+		if isNoSuchUpload(upload.Err) {
+			return nil
+		}
+		if upload.Err != nil {
+			return fmt.Errorf("listing multipart uploads: %w", upload.Err)
+		}
+		if err := m.Client.RemoveIncompleteUpload(ctx, m.bucket, upload.Key); err != nil {
+			return fmt.Errorf("aborting multipart upload of %s: %w", upload.Key, err)
+		}
+	}
+
+	return nil
+}
+
+// isNoSuchUpload reports the error some S3 implementations return instead of
+// an empty list when the bucket has no incomplete uploads.
+func isNoSuchUpload(err error) bool {
+	return err != nil && minio.ToErrorResponse(err).Code == "NoSuchUpload"
+}
+
+// retry calls f until it succeeds or the configured number of retries is
+// exhausted, waiting a growing backoff between the attempts.
+func (m *MinIOStorage) retry(ctx context.Context, f func() error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = f(); err == nil {
+			return nil
+		}
+		if attempt >= m.uploadCfg.PartRetries || ctx.Err() != nil {
+			return err
+		}
+
+		slog.Warn("upload attempt failed, retrying", "attempt", attempt+1, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(m.uploadCfg.RetryBackoff * time.Duration(attempt+1)):
+		}
+	}
+}
+
+// multipartPartSize grows the configured part size so the file fits into
+// maxUploadParts parts.
+func multipartPartSize(size, partSize int64) int64 {
+	if minSize := (size + maxUploadParts - 1) / maxUploadParts; partSize < minSize {
+		// keep parts aligned to MiB
+		partSize = (minSize + 1<<20 - 1) &^ (1<<20 - 1)
+	}
+	return partSize
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/domain"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// urlSigner produces and checks URLs of the service's /storage endpoint, for
+// backends whose downloads are served by the service itself.
+type urlSigner struct {
+	publicURL  string
+	signingKey []byte
+
+	now func() time.Time
+}
+
+func newURLSigner(cfg config.LocalStorageConfig) (*urlSigner, error) {
+	// every instance must check the urls signed by the others
+	if cfg.SigningKey == "" {
+		return nil, fmt.Errorf("STORAGE_LOCAL_SIGNING_KEY is required")
+	}
+
+	return &urlSigner{
+		publicURL:  strings.TrimSuffix(cfg.PublicURL, "/"),
+		signingKey: []byte(cfg.SigningKey),
+		now:        time.Now,
+	}, nil
+}
+
+// GetDownloadURL returns a URL of the service's /storage endpoint signed for expiry.
+func (s *urlSigner) GetDownloadURL(objectKey string, expiry time.Duration) string {
+	expires := strconv.FormatInt(s.now().Add(expiry).Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", s.sign(objectKey, expires))
+
+	return s.publicURL + "/storage/" + escapeKey(objectKey) + "?" + query.Encode()
+}
+
+// VerifySignedURL checks the signature and the expiry of a download URL
+// produced by GetDownloadURL.
+func (s *urlSigner) VerifySignedURL(objectKey string, query url.Values) error {
+	expires := query.Get("expires")
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || s.now().Unix() > unix {
+		return domain.ErrInvalidSignature
+	}
+
+	signature, err := hex.DecodeString(query.Get("signature"))
+	if err != nil {
+		return domain.ErrInvalidSignature
+	}
+
+	expected, _ := hex.DecodeString(s.sign(objectKey, expires))
+	if !hmac.Equal(signature, expected) {
+		return domain.ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func (s *urlSigner) sign(objectKey, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(objectKey + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func escapeKey(objectKey string) string {
+	parts := strings.Split(objectKey, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/domain"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Backend is implemented by every storage backend of task results.
+type Backend interface {
+	UploadToStorage(ctx context.Context, taskID uuid.UUID, resultDir string, progress domain.UploadProgressFunc) (*domain.UploadResult, error)
+	GetDownloadURL(ctx context.Context, objectKey string, expiry time.Duration) (string, error)
+	DeleteArtifacts(ctx context.Context, taskID uuid.UUID) error
+	DeleteArtifact(ctx context.Context, objectKey string) error
+	ListArtifacts(ctx context.Context, prefix string) ([]domain.Artifact, error)
+	OpenArtifact(ctx context.Context, objectKey string) (io.ReadSeekCloser, *domain.Artifact, error)
+	CheckStatus(ctx context.Context) error
+}
+
+// TasksPrefix is the key prefix all task results are stored under.
+const TasksPrefix = "tasks/"
+
+// TaskPrefix returns the "tasks/<id>/" prefix the results of a task are
+// uploaded to.
+func TaskPrefix(taskID uuid.UUID) string {
+	return TasksPrefix + taskID.String() + "/"
+}
+
+// PrefixOwner returns the task whose results are uploaded to a "tasks/<id>/"
+// prefix.
+func PrefixOwner(prefix string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(strings.TrimSuffix(strings.TrimPrefix(prefix, TasksPrefix), "/"))
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// ResultPrefix returns the "tasks/<id>/" prefix a result key or path belongs
+// to, or "" if the key isn't stored under a task prefix. Cached tasks point
+// into the prefix of the task that actually produced the result.
+func ResultPrefix(key string) string {
+	id, _, ok := strings.Cut(strings.TrimPrefix(key, TasksPrefix), "/")
+	if !strings.HasPrefix(key, TasksPrefix) || !ok || id == "" {
+		return ""
+	}
+
+	return TasksPrefix + id + "/"
+}
+
+// New creates the backend selected by STORAGE_BACKEND.
+func New(ctx context.Context, cfg *config.Config) (Backend, error) {
+	switch cfg.Storage.Backend {
+	case config.StorageLocal:
+		return NewLocalStorage(cfg)
+	default:
+		return NewMinIOStorage(ctx, cfg)
+	}
+}
+
+// listResultFiles walks the result directory and returns slash-separated
+// paths of all regular files relative to it, in lexical order.
+func listResultFiles(resultDir string) ([]string, error) {
+	if _, err := os.Stat(resultDir); err != nil {
+		return nil, fmt.Errorf("reading result dir: %w", err)
+	}
+
+	var files []string
+	err := filepath.WalkDir(resultDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(resultDir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking result dir: %w", err)
+	}
+
+	return files, nil
+}
+
+// checksumMetaKey is the user metadata key holding the hex encoded sha256 of
+// an object, as returned by the S3 client.
+const checksumMetaKey = "Sha256"
+
+// fileSHA256 returns the hex encoded sha256 of the content.
+func fileSHA256(r io.Reader) (string, error) {
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, r); err != nil {
+		return "", fmt.Errorf("hashing file: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// resultSize returns the total size the result files take in the storage.
+// storedSize maps the size of a file to its stored size, nil keeps it.
+func resultSize(resultDir string, files []string, storedSize func(int64) int64) (int64, error) {
+	var total int64
+	for _, relPath := range files {
+		info, err := os.Stat(filepath.Join(resultDir, filepath.FromSlash(relPath)))
+		if err != nil {
+			return 0, fmt.Errorf("getting file stat: %w", err)
+		}
+		if storedSize != nil {
+			total += storedSize(info.Size())
+		} else {
+			total += info.Size()
+		}
+	}
+	return total, nil
+}
+
+// uploadProgress sums the bytes uploaded by concurrent writers and reports
+// the sum to an optional callback.
+type uploadProgress struct {
+	mu       sync.Mutex
+	uploaded int64
+	total    int64
+	report   domain.UploadProgressFunc
+}
+
+func newUploadProgress(total int64, report domain.UploadProgressFunc) *uploadProgress {
+	p := &uploadProgress{total: total, report: report}
+	p.add(0)
+	return p
+}
+
+func (p *uploadProgress) add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.uploaded += n
+	if p.report != nil {
+		p.report(p.uploaded, p.total)
+	}
+}
+
+// Write counts the bytes written through it, so the progress can be used
+// with io.Copy.
+func (p *uploadProgress) Write(b []byte) (int, error) {
+	p.add(int64(len(b)))
+	return len(b), nil
+}
+
+// primaryResultFile picks the first file placed directly in the result
+// directory, falling back to the first nested one.
+func primaryResultFile(files []string) string {
+	for _, f := range files {
+		if !strings.Contains(f, "/") {
+			return f
+		}
+	}
+	return files[0]
+}
+
+func contentTypeByKey(objectKey string) string {
+	contentType := mime.TypeByExtension(path.Ext(objectKey))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return contentType
+}
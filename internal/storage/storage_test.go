@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestResultPrefix(t *testing.T) {
+	cases := map[string]string{
+		"tasks/abc/result.txt":     "tasks/abc/",
+		"tasks/abc/sub/result.txt": "tasks/abc/",
+		"tasks/abc":                "",
+		"tasks//result.txt":        "",
+		"result.txt":               "",
+		"":                         "",
+	}
+	for in, want := range cases {
+		if got := ResultPrefix(in); got != want {
+			t.Errorf("ResultPrefix(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTaskPrefix(t *testing.T) {
+	id := uuid.New()
+	if got, want := TaskPrefix(id), "tasks/"+id.String()+"/"; got != want {
+		t.Errorf("TaskPrefix() = %q, want %q", got, want)
+	}
+	if got := ResultPrefix(TaskPrefix(id) + "result.txt"); got != TaskPrefix(id) {
+		t.Errorf("ResultPrefix() = %q, want %q", got, TaskPrefix(id))
+	}
+	if owner, ok := PrefixOwner(TaskPrefix(id)); !ok || owner != id {
+		t.Errorf("PrefixOwner() = %v, %v, want %v", owner, ok, id)
+	}
+	if _, ok := PrefixOwner("tasks/abc/"); ok {
+		t.Error("expected no owner of a prefix without a task id")
+	}
+}
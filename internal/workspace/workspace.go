@@ -1,13 +1,17 @@
 package workspace
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"pinn-connect-service/internal/config"
+	"pinn-connect-service/internal/domain"
 
 	"github.com/google/uuid"
+	"github.com/shirou/gopsutil/v3/disk"
 )
 
 type LocalWorkspace struct {
@@ -29,6 +33,16 @@ func (w *LocalWorkspace) Prepare(taskID uuid.UUID) error {
 		return fmt.Errorf("creating result dir: %w", err)
 	}
 
+	// task containers may run as any user, all of them get the workspace
+	// group; new files inherit the group for the service to read them
+	resultDir := w.ResultDir(taskID)
+	if err := os.Chown(resultDir, -1, w.config.Docker.Security.WorkspaceGID); err != nil {
+		return fmt.Errorf("changing result dir group: %w", err)
+	}
+	if err := os.Chmod(resultDir, 0o770|os.ModeSetgid); err != nil {
+		return fmt.Errorf("making result dir writable: %w", err)
+	}
+
 	return nil
 }
 
@@ -59,6 +73,87 @@ func (w *LocalWorkspace) Cleanup(taskID uuid.UUID) error {
 	return nil
 }
 
+// List returns the task workspaces found in TmpDir. Entries that are not
+// task workspaces are skipped.
+func (w *LocalWorkspace) List() ([]domain.WorkspaceInfo, error) {
+	entries, err := os.ReadDir(w.config.TmpDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading workspaces dir: %w", err)
+	}
+
+	result := make([]domain.WorkspaceInfo, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		taskID, err := uuid.Parse(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("reading workspace info: %w", err)
+		}
+
+		result = append(result, domain.WorkspaceInfo{TaskID: taskID, ModifiedAt: info.ModTime()})
+	}
+
+	return result, nil
+}
+
+// ResultSize returns the size of the files in the result dir of a task.
+func (w *LocalWorkspace) ResultSize(taskID uuid.UUID) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(w.ResultDir(taskID), func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// the task may remove its files while they are walked
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("walking result dir: %w", err)
+	}
+
+	return size, nil
+}
+
+// FreeSpace returns the free and the total bytes of the disk of TmpDir.
+func (w *LocalWorkspace) FreeSpace() (free, total uint64, err error) {
+	if err := os.MkdirAll(w.config.TmpDir, w.config.WorkspaceDirsPerm); err != nil {
+		return 0, 0, fmt.Errorf("creating workspaces dir: %w", err)
+	}
+
+	usage, err := disk.Usage(w.config.TmpDir)
+	if err != nil {
+		return 0, 0, fmt.Errorf("getting disk usage: %w", err)
+	}
+
+	return usage.Free, usage.Total, nil
+}
+
 func (w *LocalWorkspace) ResultDir(taskID uuid.UUID) string {
 	return filepath.Join(w.config.TmpDir, taskID.String(), "result")
 }
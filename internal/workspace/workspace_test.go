@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"pinn-connect-service/internal/config"
+	"syscall"
 	"testing"
 
 	"github.com/google/uuid"
@@ -20,6 +21,7 @@ func setupTestWorkspace(t *testing.T) (*LocalWorkspace, string) {
 		TmpDir:            tmpDir,
 		WorkspaceDirsPerm: 0755,
 	}
+	cfg.Docker.Security.WorkspaceGID = os.Getgid()
 
 	return NewLocalWorkspace(cfg), tmpDir
 }
@@ -41,8 +43,15 @@ func TestLocalWorkspace_Prepare(t *testing.T) {
 		t.Errorf("input directory was not created: %s", inputDir)
 	}
 
-	if _, err := os.Stat(resultDir); os.IsNotExist(err) {
-		t.Errorf("result directory was not created: %s", resultDir)
+	info, err := os.Stat(resultDir)
+	if os.IsNotExist(err) {
+		t.Fatalf("result directory was not created: %s", resultDir)
+	}
+	if info.Mode().Perm() != 0o770 || info.Mode()&os.ModeSetgid == 0 {
+		t.Errorf("expected result directory to be writable by the workspace group, got %v", info.Mode())
+	}
+	if gid := info.Sys().(*syscall.Stat_t).Gid; int(gid) != os.Getgid() {
+		t.Errorf("expected result directory of group %d, got %d", os.Getgid(), gid)
 	}
 }
 
@@ -105,3 +114,79 @@ func TestLocalWorkspace_Paths(t *testing.T) {
 		t.Errorf("ResultDir() mismatch: expected %s, got %s", expectedResult, ws.ResultDir(taskID))
 	}
 }
+
+func TestLocalWorkspace_List(t *testing.T) {
+	ws, tmpDir := setupTestWorkspace(t)
+	defer os.RemoveAll(tmpDir)
+
+	taskID := uuid.New()
+	_ = ws.Prepare(taskID)
+	_ = os.Mkdir(filepath.Join(tmpDir, "not-a-task"), 0755)
+	_ = os.WriteFile(filepath.Join(tmpDir, uuid.New().String()), []byte("file"), 0644)
+
+	list, err := ws.List()
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+
+	if len(list) != 1 || list[0].TaskID != taskID {
+		t.Fatalf("expected only the task workspace, got %+v", list)
+	}
+	if list[0].ModifiedAt.IsZero() {
+		t.Error("expected modification time to be set")
+	}
+}
+
+func TestLocalWorkspace_List_MissingDir(t *testing.T) {
+	ws := NewLocalWorkspace(&config.Config{TmpDir: filepath.Join(os.TempDir(), uuid.New().String())})
+
+	list, err := ws.List()
+	if err != nil || len(list) != 0 {
+		t.Errorf("expected empty list for missing dir, got %v / %v", list, err)
+	}
+}
+
+func TestLocalWorkspace_ResultSize(t *testing.T) {
+	ws, tmpDir := setupTestWorkspace(t)
+	defer os.RemoveAll(tmpDir)
+
+	taskID := uuid.New()
+	_ = ws.Prepare(taskID)
+	resultDir := ws.ResultDir(taskID)
+	_ = os.MkdirAll(filepath.Join(resultDir, "nested"), 0755)
+	_ = os.WriteFile(filepath.Join(resultDir, "a.txt"), make([]byte, 100), 0644)
+	_ = os.WriteFile(filepath.Join(resultDir, "nested", "b.txt"), make([]byte, 50), 0644)
+	// the input doesn't count
+	_ = os.WriteFile(filepath.Join(ws.InputDir(taskID), "input.txt"), make([]byte, 1000), 0644)
+
+	size, err := ws.ResultSize(taskID)
+	if err != nil {
+		t.Fatalf("ResultSize() failed: %v", err)
+	}
+	if size != 150 {
+		t.Errorf("expected 150 bytes, got %d", size)
+	}
+}
+
+func TestLocalWorkspace_ResultSize_MissingDir(t *testing.T) {
+	ws, tmpDir := setupTestWorkspace(t)
+	defer os.RemoveAll(tmpDir)
+
+	size, err := ws.ResultSize(uuid.New())
+	if err != nil || size != 0 {
+		t.Errorf("expected 0 for a missing workspace, got %d / %v", size, err)
+	}
+}
+
+func TestLocalWorkspace_FreeSpace(t *testing.T) {
+	ws, tmpDir := setupTestWorkspace(t)
+	defer os.RemoveAll(tmpDir)
+
+	free, total, err := ws.FreeSpace()
+	if err != nil {
+		t.Fatalf("FreeSpace() failed: %v", err)
+	}
+	if total == 0 || free > total {
+		t.Errorf("expected free space within the disk size, got %d of %d", free, total)
+	}
+}
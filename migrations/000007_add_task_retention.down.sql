@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_tasks_finished;
+ALTER TABLE tasks DROP COLUMN keep_for_sec;
+ALTER TABLE tasks DROP COLUMN pinned;
@@ -0,0 +1,5 @@
+ALTER TABLE tasks ADD COLUMN pinned BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE tasks ADD COLUMN keep_for_sec INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_tasks_finished ON tasks (finished_at ASC)
+WHERE status IN ('completed', 'failed', 'stopped');
@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN result_missing BOOLEAN NOT NULL DEFAULT FALSE;
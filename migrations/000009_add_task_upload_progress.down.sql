@@ -0,0 +1,4 @@
+ALTER TABLE tasks
+    DROP COLUMN upload_failed,
+    DROP COLUMN upload_done_bytes,
+    DROP COLUMN upload_total_bytes;
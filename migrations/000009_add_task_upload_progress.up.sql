@@ -0,0 +1,4 @@
+ALTER TABLE tasks
+    ADD COLUMN upload_total_bytes BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN upload_done_bytes BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN upload_failed BOOLEAN NOT NULL DEFAULT FALSE;
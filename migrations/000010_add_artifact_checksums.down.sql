@@ -0,0 +1,5 @@
+DROP TABLE artifact_checksums;
+
+ALTER TABLE tasks
+    DROP COLUMN result_corrupted,
+    DROP COLUMN input_sha256;
@@ -0,0 +1,12 @@
+ALTER TABLE tasks ADD COLUMN input_sha256 VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN result_corrupted BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE artifact_checksums (
+    object_key TEXT PRIMARY KEY,
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    size BIGINT NOT NULL,
+    sha256 VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_artifact_checksums_task ON artifact_checksums(task_id);
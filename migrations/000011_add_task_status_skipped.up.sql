@@ -0,0 +1 @@
+ALTER TYPE task_status ADD VALUE 'skipped';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_tasks_finished;
+
+CREATE INDEX idx_tasks_finished ON tasks (finished_at ASC)
+WHERE status IN ('completed', 'failed', 'stopped');
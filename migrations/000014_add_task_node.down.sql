@@ -0,0 +1 @@
+ALTER TABLE tasks DROP COLUMN IF EXISTS node_id;
@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN node_id TEXT;
+
+CREATE INDEX idx_tasks_node ON tasks(node_id) WHERE node_id IS NOT NULL;
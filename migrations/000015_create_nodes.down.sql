@@ -0,0 +1,2 @@
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS tasks_node_id_fkey;
+DROP TABLE IF EXISTS nodes;
@@ -0,0 +1,16 @@
+CREATE TABLE nodes (
+    id TEXT PRIMARY KEY,
+    hostname TEXT NOT NULL,
+    capacity INTEGER NOT NULL,
+    labels JSONB NOT NULL DEFAULT '{}',
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    stopped_at TIMESTAMPTZ
+);
+
+-- instances that claimed tasks before nodes registered, they register again on start
+INSERT INTO nodes (id, hostname, capacity, stopped_at)
+SELECT DISTINCT node_id, node_id, 0, NOW() FROM tasks WHERE node_id IS NOT NULL;
+
+ALTER TABLE tasks ADD CONSTRAINT tasks_node_id_fkey
+    FOREIGN KEY (node_id) REFERENCES nodes(id) ON DELETE SET NULL;
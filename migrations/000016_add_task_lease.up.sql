@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN lease_expires_at TIMESTAMPTZ;
+
+CREATE INDEX idx_tasks_lease ON tasks(lease_expires_at) WHERE status = 'running';
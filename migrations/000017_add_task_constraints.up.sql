@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN constraints JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,12 @@
+CREATE TABLE task_events (
+    id BIGSERIAL PRIMARY KEY,
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    from_status task_status,
+    to_status task_status NOT NULL,
+    actor TEXT NOT NULL,
+    node_id TEXT,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_task_events_task ON task_events(task_id, id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_tasks_failure_reason;
+ALTER TABLE tasks DROP COLUMN IF EXISTS failure_reason;
+ALTER TABLE tasks DROP COLUMN IF EXISTS exit_code;
+DROP TYPE IF EXISTS failure_reason;
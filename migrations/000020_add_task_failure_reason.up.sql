@@ -0,0 +1,15 @@
+CREATE TYPE failure_reason AS ENUM (
+    'oom_killed',
+    'timeout',
+    'nonzero_exit',
+    'image_pull_failed',
+    'container_start_failed',
+    'upload_failed',
+    'user_cancelled',
+    'infrastructure'
+);
+
+ALTER TABLE tasks ADD COLUMN exit_code INTEGER;
+ALTER TABLE tasks ADD COLUMN failure_reason failure_reason;
+
+CREATE INDEX idx_tasks_failure_reason ON tasks(failure_reason) WHERE failure_reason IS NOT NULL;
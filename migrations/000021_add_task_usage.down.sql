@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_tasks_usage_recorded;
+ALTER TABLE tasks
+    DROP COLUMN IF EXISTS usage_recorded_at,
+    DROP COLUMN IF EXISTS run_seconds,
+    DROP COLUMN IF EXISTS queue_seconds,
+    DROP COLUMN IF EXISTS usage_samples,
+    DROP COLUMN IF EXISTS net_tx_bytes,
+    DROP COLUMN IF EXISTS net_rx_bytes,
+    DROP COLUMN IF EXISTS block_write_bytes,
+    DROP COLUMN IF EXISTS block_read_bytes,
+    DROP COLUMN IF EXISTS cpu_seconds,
+    DROP COLUMN IF EXISTS mem_avg_bytes,
+    DROP COLUMN IF EXISTS mem_peak_bytes;
@@ -0,0 +1,14 @@
+ALTER TABLE tasks
+    ADD COLUMN mem_peak_bytes BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN mem_avg_bytes BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN cpu_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN block_read_bytes BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN block_write_bytes BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN net_rx_bytes BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN net_tx_bytes BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN usage_samples INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN queue_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN run_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN usage_recorded_at TIMESTAMPTZ;
+
+CREATE INDEX idx_tasks_usage_recorded ON tasks(usage_recorded_at) WHERE usage_recorded_at IS NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE tasks DROP COLUMN IF EXISTS secret_envs;
+DROP TABLE IF EXISTS secrets;
@@ -0,0 +1,3 @@
+ALTER TABLE models DROP COLUMN IF EXISTS network_mode;
+ALTER TABLE tasks DROP COLUMN IF EXISTS network_mode;
+DROP TYPE IF EXISTS network_mode;
@@ -0,0 +1,12 @@
+CREATE TYPE network_mode AS ENUM (
+    'none',
+    'internal',
+    'egress',
+    'bridge'
+);
+
+-- tasks created before the network policy ran on the default bridge
+ALTER TABLE tasks ADD COLUMN network_mode network_mode NOT NULL DEFAULT 'bridge';
+ALTER TABLE tasks ALTER COLUMN network_mode DROP DEFAULT;
+
+ALTER TABLE models ADD COLUMN network_mode network_mode;
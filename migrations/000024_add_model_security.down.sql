@@ -0,0 +1 @@
+ALTER TABLE models DROP COLUMN IF EXISTS security;
@@ -0,0 +1,4 @@
+ALTER TYPE failure_reason ADD VALUE 'disk_quota_exceeded';
+
+-- zero is no limit, tasks created before the quota had none
+ALTER TABLE tasks ADD COLUMN disk_lim INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+DELETE FROM artifact_checksums WHERE task_id IS NULL;
+ALTER TABLE artifact_checksums DROP CONSTRAINT artifact_checksums_task_id_fkey;
+ALTER TABLE artifact_checksums ALTER COLUMN task_id SET NOT NULL;
+ALTER TABLE artifact_checksums ADD CONSTRAINT artifact_checksums_task_id_fkey
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE;
@@ -0,0 +1,6 @@
+-- results taken from cache share the prefix of the task that produced them,
+-- so checksums outlive that task and are removed with the prefix
+ALTER TABLE artifact_checksums DROP CONSTRAINT artifact_checksums_task_id_fkey;
+ALTER TABLE artifact_checksums ALTER COLUMN task_id DROP NOT NULL;
+ALTER TABLE artifact_checksums ADD CONSTRAINT artifact_checksums_task_id_fkey
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE SET NULL;
@@ -0,0 +1,20 @@
+ALTER TABLE tasks DROP COLUMN IF EXISTS attempts;
+
+-- enum values can't be dropped, the type is recreated without it
+UPDATE tasks SET failure_reason = 'infrastructure' WHERE failure_reason = 'attempts_exhausted';
+DROP INDEX IF EXISTS idx_tasks_failure_reason;
+ALTER TYPE failure_reason RENAME TO failure_reason_old;
+CREATE TYPE failure_reason AS ENUM (
+    'oom_killed',
+    'timeout',
+    'nonzero_exit',
+    'image_pull_failed',
+    'container_start_failed',
+    'upload_failed',
+    'user_cancelled',
+    'infrastructure',
+    'disk_quota_exceeded'
+);
+ALTER TABLE tasks ALTER COLUMN failure_reason TYPE failure_reason USING failure_reason::text::failure_reason;
+DROP TYPE failure_reason_old;
+CREATE INDEX idx_tasks_failure_reason ON tasks(failure_reason) WHERE failure_reason IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TYPE failure_reason ADD VALUE 'attempts_exhausted';
+
+ALTER TABLE tasks ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
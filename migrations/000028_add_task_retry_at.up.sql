@@ -0,0 +1,2 @@
+-- a queued task isn't taken before retry_at, set when its hosts were busy
+ALTER TABLE tasks ADD COLUMN retry_at TIMESTAMPTZ;
@@ -2,9 +2,9 @@
 INSERT INTO tasks (
     id, model_id, input_filename, signature, status, scheduled_at,
      container_image, container_envs, container_cmd, error_log, mem_lim,
-      cpu_lim, gpu_enable, result_path, timeout_sec
+      cpu_lim, gpu_enable, result_path, timeout_sec, keep_for_sec, input_sha256, constraints, secret_envs, network_mode, disk_lim
 ) VALUES (
-    $1, $2, $3, $4, sqlc.arg('status')::task_status, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+    $1, $2, $3, $4, sqlc.arg('status')::task_status, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20
 )
 RETURNING *;
 
@@ -14,11 +14,13 @@ WHERE id = $1 LIMIT 1;
 
 -- name: GetTasksPaginated :many
 SELECT * FROM tasks
+WHERE sqlc.narg('failure_reason')::failure_reason IS NULL OR failure_reason = sqlc.narg('failure_reason')
 ORDER BY created_at DESC
 LIMIT $1 OFFSET $2;
 
 -- name: GetTasksCount :one
-SELECT COUNT(*) FROM tasks;
+SELECT COUNT(*) FROM tasks
+WHERE sqlc.narg('failure_reason')::failure_reason IS NULL OR failure_reason = sqlc.narg('failure_reason');
 
 -- name: FindCachedTask :one
 SELECT result_path FROM tasks
@@ -26,17 +28,21 @@ WHERE signature = $1
     AND status = 'completed'::task_status
     AND result_path IS NOT NULL
     AND result_path != ''
+    AND NOT result_missing
+    AND NOT result_corrupted
 ORDER BY created_at DESC
 LIMIT 1;
 
 -- name: GetNextQueuedTask :one
 UPDATE tasks
-SET status = 'running', updated_at = NOW()
+SET status = 'running', node_id = $1,
+    lease_expires_at = NOW() + make_interval(secs => sqlc.arg('lease_sec')::float8), updated_at = NOW(), version = version + 1
 WHERE id = (
     SELECT id
     FROM tasks
     WHERE status = 'queued'
     AND (scheduled_at IS NULL OR scheduled_at <= NOW())
+    AND (retry_at IS NULL OR retry_at <= NOW())
     ORDER BY scheduled_at ASC
 LIMIT 1
 FOR UPDATE SKIP LOCKED
@@ -49,8 +55,9 @@ SET
     status = 'running',
     container_id = $2,
     started_at = NOW(),
-    updated_at = NOW()
-WHERE id = $1
+    updated_at = NOW(),
+    version = version + 1
+WHERE id = $1 AND status = sqlc.arg('from_status') AND version = sqlc.arg('version')
 RETURNING *;
 
 -- name: MarkTaskCompleted :one
@@ -58,17 +65,24 @@ UPDATE tasks
 SET 
     status = 'completed',
     result_path = $2,
+    upload_failed = FALSE,
+    exit_code = $3,
+    failure_reason = NULL,
     finished_at = NOW(),
-    updated_at = NOW()
-WHERE id = $1
+    updated_at = NOW(),
+    version = version + 1
+WHERE id = $1 AND status = sqlc.arg('from_status') AND version = sqlc.arg('version')
 RETURNING *;
 
 -- name: MarkTaskQueued :one
 UPDATE tasks
 SET 
     status = 'queued',
-    updated_at = NOW()
-WHERE id = $1
+    attempts = $2,
+    retry_at = $3,
+    updated_at = NOW(),
+    version = version + 1
+WHERE id = $1 AND status = sqlc.arg('from_status') AND version = sqlc.arg('version')
 RETURNING *;
 
 -- name: MarkTaskFailed :one
@@ -76,17 +90,22 @@ UPDATE tasks
 SET 
     status = 'failed',
     error_log = $2,
+    upload_failed = $3,
+    exit_code = $4,
+    failure_reason = $5,
     finished_at = NOW(),
-    updated_at = NOW()
-WHERE id = $1 AND status != 'stopped'
+    updated_at = NOW(),
+    version = version + 1
+WHERE id = $1 AND status = sqlc.arg('from_status') AND version = sqlc.arg('version')
 RETURNING *;
 
 -- name: MarkTaskInitializing :one
 UPDATE tasks
 SET 
     status = 'initializing',
-    updated_at = NOW()
-WHERE id = $1
+    updated_at = NOW(),
+    version = version + 1
+WHERE id = $1 AND status = sqlc.arg('from_status') AND version = sqlc.arg('version')
 RETURNING *;
 
 -- name: MarkTaskScheduled :one
@@ -94,17 +113,21 @@ UPDATE tasks
 SET 
     status = 'scheduled',
     updated_at = NOW(),
-    scheduled_at = $2
-WHERE id = $1
+    scheduled_at = $2,
+    version = version + 1
+WHERE id = $1 AND status = sqlc.arg('from_status') AND version = sqlc.arg('version')
 RETURNING *;
 
 -- name: MarkTaskStopped :one
 UPDATE tasks
 SET 
     status = 'stopped',
+    exit_code = $2,
+    failure_reason = $3,
     finished_at = NOW(),
-    updated_at = NOW()
-WHERE id = $1
+    updated_at = NOW(),
+    version = version + 1
+WHERE id = $1 AND status = sqlc.arg('from_status') AND version = sqlc.arg('version')
 RETURNING *;
 
 -- name: GetActiveTasks :many
@@ -118,12 +141,6 @@ WHERE status = 'running'
 SELECT * FROM tasks
 WHERE status = 'running' AND container_id IS NOT NULL;
 
--- name: GetUpcomingScheduledTasks :many
-SELECT * FROM tasks
-WHERE status = 'scheduled'
-AND scheduled_at <= $1
-ORDER BY scheduled_at ASC;
-
 -- name: GetModelByID :one
 SELECT * FROM models WHERE id = $1 LIMIT 1;
 
@@ -147,4 +164,213 @@ WHERE id = $1;
 SELECT EXISTS(SELECT 1 FROM models WHERE id = $1);
 
 -- name: DeleteTask :exec
-DELETE FROM tasks WHERE id = $1;
\ No newline at end of file
+DELETE FROM tasks WHERE id = $1;
+
+-- name: SetTaskPinned :one
+UPDATE tasks
+SET
+    pinned = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetFinishedTasks :many
+SELECT * FROM tasks
+WHERE status IN ('completed', 'failed', 'stopped', 'skipped')
+ORDER BY finished_at ASC NULLS FIRST;
+
+-- name: GetStaleTasks :many
+SELECT * FROM tasks
+WHERE status = sqlc.arg('status')::task_status
+    AND updated_at < sqlc.arg('updated_before')
+ORDER BY updated_at ASC;
+
+-- name: ListTaskResultRefs :many
+SELECT id, status, result_path, result_missing FROM tasks
+WHERE result_path IS NOT NULL AND result_path != '';
+
+-- name: CountResultRefs :one
+SELECT COUNT(*) FROM tasks
+WHERE id != sqlc.arg('id') AND starts_with(result_path, sqlc.arg('prefix')::text);
+
+-- name: SetTaskResultMissing :exec
+UPDATE tasks
+SET result_missing = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetTaskUploadProgress :exec
+UPDATE tasks
+SET upload_total_bytes = $2, upload_done_bytes = $3
+WHERE id = $1;
+
+-- name: GetUploadFailedTasks :many
+SELECT * FROM tasks
+WHERE status = 'failed' AND upload_failed;
+
+-- name: UpsertArtifactChecksum :exec
+INSERT INTO artifact_checksums (object_key, task_id, size, sha256)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (object_key) DO UPDATE
+SET task_id = EXCLUDED.task_id, size = EXCLUDED.size, sha256 = EXCLUDED.sha256, created_at = NOW();
+
+-- name: ListArtifactChecksums :many
+SELECT object_key, size, sha256 FROM artifact_checksums
+WHERE starts_with(object_key, sqlc.arg('prefix')::text)
+ORDER BY object_key;
+
+-- name: DeleteArtifactChecksums :exec
+DELETE FROM artifact_checksums
+WHERE starts_with(object_key, sqlc.arg('prefix')::text);
+
+-- name: SetResultCorrupted :exec
+UPDATE tasks
+SET result_corrupted = sqlc.arg('corrupted'), updated_at = NOW()
+WHERE starts_with(result_path, sqlc.arg('prefix')::text);
+
+-- name: PromoteScheduledTasks :many
+WITH due AS (
+    SELECT id,
+        sqlc.arg('policy')::text <> 'run'
+            AND scheduled_at < NOW() - make_interval(secs => sqlc.arg('misfire_threshold_sec')::float8) AS misfired
+    FROM tasks
+    WHERE status = 'scheduled' AND scheduled_at <= NOW()
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE tasks t
+SET
+    status = CASE
+        WHEN NOT due.misfired THEN 'queued'::task_status
+        WHEN sqlc.arg('policy')::text = 'skip' THEN 'skipped'::task_status
+        ELSE 'failed'::task_status
+    END,
+    error_log = CASE
+        WHEN due.misfired THEN format('missed its schedule by %s', date_trunc('second', NOW() - t.scheduled_at))
+        ELSE t.error_log
+    END,
+    finished_at = CASE WHEN due.misfired THEN NOW() ELSE t.finished_at END,
+    updated_at = NOW(),
+    version = t.version + 1
+FROM due
+WHERE t.id = due.id
+RETURNING t.*;
+
+-- name: GetNextScheduledAt :one
+SELECT MIN(scheduled_at)::timestamptz FROM tasks
+WHERE status = 'scheduled';
+
+-- name: RegisterNode :exec
+INSERT INTO nodes (id, hostname, capacity, labels)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (id) DO UPDATE
+SET hostname = EXCLUDED.hostname,
+    capacity = EXCLUDED.capacity,
+    labels = EXCLUDED.labels,
+    started_at = NOW(),
+    heartbeat_at = NOW(),
+    stopped_at = NULL;
+
+-- name: HeartbeatNode :execrows
+UPDATE nodes
+SET heartbeat_at = NOW()
+WHERE id = $1 AND stopped_at IS NULL;
+
+-- name: StopNode :exec
+UPDATE nodes
+SET stopped_at = NOW()
+WHERE id = $1;
+
+-- name: ListNodes :many
+SELECT n.id, n.hostname, n.capacity, n.labels, n.started_at, n.heartbeat_at, n.stopped_at,
+    (SELECT COUNT(*) FROM tasks t WHERE t.node_id = n.id AND t.status = 'running') AS running_tasks,
+    (n.stopped_at IS NULL AND n.heartbeat_at > NOW() - make_interval(secs => sqlc.arg('timeout_sec')::float8))::bool AS alive
+FROM nodes n
+ORDER BY n.id;
+
+-- name: RenewTaskLease :execrows
+UPDATE tasks
+SET node_id = $2, lease_expires_at = NOW() + make_interval(secs => sqlc.arg('lease_sec')::float8)
+WHERE id = $1 AND (node_id = $2 OR node_id IS NULL);
+
+-- name: ReclaimExpiredTasks :many
+UPDATE tasks
+SET node_id = $1, lease_expires_at = NOW() + make_interval(secs => sqlc.arg('lease_sec')::float8), updated_at = NOW(), version = version + 1
+WHERE id IN (
+    SELECT id
+    FROM tasks
+    WHERE status = 'running' AND lease_expires_at < NOW()
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CreateTaskEvent :exec
+INSERT INTO task_events (task_id, from_status, to_status, actor, node_id, reason)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ListTaskEvents :many
+SELECT * FROM task_events
+WHERE task_id = $1
+ORDER BY id;
+
+-- name: SaveTaskUsage :exec
+UPDATE tasks
+SET mem_peak_bytes = $2,
+    mem_avg_bytes = $3,
+    cpu_seconds = $4,
+    block_read_bytes = $5,
+    block_write_bytes = $6,
+    net_rx_bytes = $7,
+    net_tx_bytes = $8,
+    usage_samples = $9,
+    queue_seconds = COALESCE(EXTRACT(EPOCH FROM started_at - GREATEST(created_at, scheduled_at))::float8, 0),
+    run_seconds = COALESCE(EXTRACT(EPOCH FROM NOW() - started_at)::float8, 0),
+    usage_recorded_at = NOW()
+WHERE id = $1;
+
+-- name: GetUsageReport :many
+SELECT model_id,
+    date_trunc('day', usage_recorded_at, 'UTC')::timestamptz AS day,
+    COUNT(*) AS tasks,
+    MAX(mem_peak_bytes)::bigint AS mem_peak_bytes,
+    AVG(mem_avg_bytes)::bigint AS mem_avg_bytes,
+    SUM(cpu_seconds)::float8 AS cpu_seconds,
+    SUM(block_read_bytes)::bigint AS block_read_bytes,
+    SUM(block_write_bytes)::bigint AS block_write_bytes,
+    SUM(net_rx_bytes)::bigint AS net_rx_bytes,
+    SUM(net_tx_bytes)::bigint AS net_tx_bytes,
+    AVG(queue_seconds)::float8 AS avg_queue_seconds,
+    SUM(run_seconds)::float8 AS run_seconds
+FROM tasks
+WHERE usage_recorded_at >= sqlc.arg('since') AND usage_recorded_at < sqlc.arg('until')
+GROUP BY model_id, day
+ORDER BY day, model_id;
+
+-- name: CreateSecret :execrows
+INSERT INTO secrets (name, key_id, value)
+VALUES ($1, $2, $3)
+ON CONFLICT (name) DO NOTHING;
+
+-- name: UpdateSecret :execrows
+UPDATE secrets
+SET key_id = $2, value = $3, updated_at = NOW()
+WHERE name = $1;
+
+-- name: GetSecrets :many
+SELECT * FROM secrets
+WHERE name = ANY($1::text[]);
+
+-- name: ListSecrets :many
+SELECT name, created_at, updated_at FROM secrets
+ORDER BY name;
+
+-- name: DeleteSecret :execrows
+DELETE FROM secrets WHERE name = $1;
+
+-- name: SetModelNetworkMode :execrows
+UPDATE models
+SET network_mode = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetModelSecurity :execrows
+UPDATE models
+SET security = $2, updated_at = NOW()
+WHERE id = $1;
@@ -5,7 +5,38 @@ CREATE TYPE task_status AS ENUM (
     'running',
     'completed',
     'failed',
-    'stopped'
+    'stopped',
+    'skipped'
+);
+
+CREATE TYPE failure_reason AS ENUM (
+    'oom_killed',
+    'timeout',
+    'nonzero_exit',
+    'image_pull_failed',
+    'container_start_failed',
+    'upload_failed',
+    'user_cancelled',
+    'infrastructure',
+    'disk_quota_exceeded',
+    'attempts_exhausted'
+);
+
+CREATE TYPE network_mode AS ENUM (
+    'none',
+    'internal',
+    'egress',
+    'bridge'
+);
+
+CREATE TABLE nodes (
+    id TEXT PRIMARY KEY,
+    hostname TEXT NOT NULL,
+    capacity INTEGER NOT NULL,
+    labels JSONB NOT NULL DEFAULT '{}',
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    stopped_at TIMESTAMPTZ
 );
 
 CREATE TABLE tasks (
@@ -36,13 +67,80 @@ CREATE TABLE tasks (
     cpu_lim INTEGER,
     gpu_enable BOOLEAN,
 
-    timeout_sec INTEGER NOT NULL DEFAULT 0
+    timeout_sec INTEGER NOT NULL DEFAULT 0,
+
+    pinned BOOLEAN NOT NULL DEFAULT FALSE,
+    keep_for_sec INTEGER NOT NULL DEFAULT 0,
+
+    result_missing BOOLEAN NOT NULL DEFAULT FALSE,
+
+    upload_total_bytes BIGINT NOT NULL DEFAULT 0,
+    upload_done_bytes BIGINT NOT NULL DEFAULT 0,
+    upload_failed BOOLEAN NOT NULL DEFAULT FALSE,
+
+    input_sha256 VARCHAR(64) NOT NULL DEFAULT '',
+    result_corrupted BOOLEAN NOT NULL DEFAULT FALSE,
+
+    node_id TEXT REFERENCES nodes(id) ON DELETE SET NULL,
+    lease_expires_at TIMESTAMPTZ,
+    constraints JSONB NOT NULL DEFAULT '{}',
+    version INTEGER NOT NULL DEFAULT 0,
+    exit_code INTEGER,
+    failure_reason failure_reason,
+
+    mem_peak_bytes BIGINT NOT NULL DEFAULT 0,
+    mem_avg_bytes BIGINT NOT NULL DEFAULT 0,
+    cpu_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+    block_read_bytes BIGINT NOT NULL DEFAULT 0,
+    block_write_bytes BIGINT NOT NULL DEFAULT 0,
+    net_rx_bytes BIGINT NOT NULL DEFAULT 0,
+    net_tx_bytes BIGINT NOT NULL DEFAULT 0,
+    usage_samples INTEGER NOT NULL DEFAULT 0,
+    queue_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+    run_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+    usage_recorded_at TIMESTAMPTZ,
+
+    secret_envs JSONB NOT NULL DEFAULT '{}',
+
+    network_mode network_mode NOT NULL,
+    disk_lim INTEGER NOT NULL DEFAULT 0,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    retry_at TIMESTAMPTZ
+);
+
+CREATE TABLE task_events (
+    id BIGSERIAL PRIMARY KEY,
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    from_status task_status,
+    to_status task_status NOT NULL,
+    actor TEXT NOT NULL,
+    node_id TEXT,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE artifact_checksums (
+    object_key TEXT PRIMARY KEY,
+    task_id UUID REFERENCES tasks(id) ON DELETE SET NULL,
+    size BIGINT NOT NULL,
+    sha256 VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
 CREATE TABLE models (
     id TEXT PRIMARY KEY,
     container_image TEXT NOT NULL,
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    network_mode network_mode,
+    security JSONB
+);
+
+CREATE TABLE secrets (
+    name TEXT PRIMARY KEY,
+    key_id TEXT NOT NULL,
+    value BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
@@ -55,4 +153,28 @@ WHERE status = 'queued';
 
 CREATE INDEX idx_tasks_upcoming_scheduled
 ON tasks (scheduled_at)
-WHERE status = 'scheduled';
\ No newline at end of file
+WHERE status = 'scheduled';
+
+CREATE INDEX idx_tasks_finished ON tasks (finished_at ASC)
+WHERE status IN ('completed', 'failed', 'stopped', 'skipped');
+
+CREATE INDEX idx_artifact_checksums_task ON artifact_checksums(task_id);
+
+CREATE INDEX idx_tasks_node ON tasks(node_id) WHERE node_id IS NOT NULL;
+CREATE INDEX idx_tasks_lease ON tasks(lease_expires_at) WHERE status = 'running';
+CREATE INDEX idx_task_events_task ON task_events(task_id, id);
+CREATE INDEX idx_tasks_failure_reason ON tasks(failure_reason) WHERE failure_reason IS NOT NULL;
+CREATE INDEX idx_tasks_usage_recorded ON tasks(usage_recorded_at) WHERE usage_recorded_at IS NOT NULL;
+
+CREATE FUNCTION notify_task_change() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR OLD.status IS DISTINCT FROM NEW.status THEN
+        PERFORM pg_notify('task_changes', json_build_object('id', NEW.id, 'status', NEW.status)::text);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_notify_change
+    AFTER INSERT OR UPDATE OF status ON tasks
+    FOR EACH ROW EXECUTE FUNCTION notify_task_change();